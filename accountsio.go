@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining import and export of account books
+type ExportFormat int8
+
+const (
+	CsvExportFormat ExportFormat = iota
+	NdjsonExportFormat
+)
+
+// Helper function to find the account status by its name in any supported locale
+func parseAccountStatus(name string) (AccountStatus, bool) {
+	name = strings.TrimSpace(name)
+	for status, names := range accountStatusCodeToNameMap {
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return status, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Helper function to find the account type by its name in any supported locale
+func parseAccountType(name string) (AccountType, bool) {
+	name = strings.TrimSpace(name)
+	for accType, names := range accountTypeCodeToNameMap {
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return accType, true
+			}
+		}
+	}
+	return 0, false
+}
+
+type importedAccount struct {
+	iban    string
+	balance float64
+	status  AccountStatus
+}
+
+// Imports rows of "iban,balance,status,type" (an optional header row starting with "iban" is skipped), i.e.
+// BY04 9389 1967 9457 1425 6938 2035,150.50,Active,Ordinary
+// Any further columns, such as the ones written by ExportAccounts, are ignored
+// Either all rows are imported or none of them: every row is validated before the first account is registered
+// Only ordinary accounts can be imported since special accounts are created by the repository itself
+// Imported balances are accounted as emitted money, so that the books stay balanced, and the accounts start pending KYC verification
+// Returns the number of imported accounts
+func (r *InMemoryAccountRepository) ImportAccountsCSV(reader io.Reader) (int, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[AccountsImportError][locale()])
+	}
+	if len(rows) > 0 && strings.EqualFold(strings.TrimSpace(rows[0][0]), "iban") {
+		rows = rows[1:]
+	}
+
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	imported := []importedAccount{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if len(row) < 4 {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[AccountsImportError][locale()])
+		}
+		iban := NormalizeIban(row[0])
+		// Checking if the IBAN is valid and unique both within the file and the repository
+		if !IsValidIban(iban) {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale()])
+		}
+		if seen[iban] || r.accountExists(iban) {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[AccountAlreadyExistsError][locale()])
+		}
+		seen[iban] = true
+		// Balances of encrypted exports are decrypted by the encryptor of the repository
+		cell := strings.TrimSpace(row[1])
+		if IsEncryptedValue(cell) {
+			if r.Encryptor == nil {
+				return 0, fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+			}
+			if cell, err = r.Encryptor.Decrypt(cell); err != nil {
+				return 0, err
+			}
+		}
+		balance, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[AccountsImportError][locale()])
+		}
+		// Checking if the balance is not negative
+		if balance < 0 {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+		}
+		status, ok := parseAccountStatus(row[2])
+		if !ok {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[AccountsImportError][locale()])
+		}
+		// Checking if closed accounts do not hold any money
+		if status == Closed && balance != 0 {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[AccountsImportError][locale()])
+		}
+		if accType, ok := parseAccountType(row[3]); !ok || accType != Ordinary {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[AccountsImportError][locale()])
+		}
+		imported = append(imported, importedAccount{iban, balance, status})
+	}
+
+	for _, row := range imported {
+		acc, err := r.openAccount(DefaultCurrency, row.iban)
+		if err != nil {
+			return 0, err
+		}
+		acc.Status = row.status
+		r.Accounts.Put(acc)
+		tx := &Transaction{Type: ImportTransaction, Recipient: row.iban, Amount: row.balance, Currency: acc.Currency}
+		if err := r.post(tx, debitLedgerLine(IssuedLedgerAccount, acc.Currency, row.balance), creditLine(acc, row.balance)); err != nil {
+			return 0, err
+		}
+		r.TotalEmitted += row.balance
+	}
+	return len(imported), nil
+}
+
+// Column names of the CSV export, the first four match the columns expected by ImportAccountsCSV
+// The export as a whole does not round-trip: it lists system and other special accounts, which ImportAccountsCSV rejects,
+// so only rows of ordinary accounts can be imported, into a repository where their IBANs are not taken yet. Imported accounts
+// keep IBANs, balances and statuses, while fractions, holders, KYC status and products are not carried over
+var accountsCsvHeader = []string{"iban", "balance", "status", "type", "currency", "fractions", "customer_id", "kyc", "product"}
+
+// Writes all accounts to the writer as CSV with a header row or as newline-delimited JSON with one AccountDetails object per line
+// Holders and metadata (and balances if EncryptBalances is set) are encrypted if the encryptor of the repository is set
+// Accounts are streamed one by one rather than collected into one JSON array, so that large account books can be dumped
+func (r *InMemoryAccountRepository) ExportAccounts(writer io.Writer, format ExportFormat) error {
+	return r.exportAccounts(writer, format, r.ForEachAccount)
+}
+
+// Helper function to write the accounts iterated over by forEach in the format, see ExportAccounts
+func (r *InMemoryAccountRepository) exportAccounts(writer io.Writer, format ExportFormat, forEach func(callback func(Account) bool) error) error {
+	var write func(acc Account) error
+	var flush func() error
+	switch format {
+	case CsvExportFormat:
+		csvWriter := csv.NewWriter(writer)
+		if err := csvWriter.Write(accountsCsvHeader); err != nil {
+			return fmt.Errorf(errorCodesToMessagesMap[AccountsExportError][locale()])
+		}
+		write = func(acc Account) error {
+			d := acc.Details()
+			balance, fractions := strconv.FormatFloat(roundToCurrency(d.Balance, d.Currency), 'f', minorUnitsOf(d.Currency), 64), strconv.FormatFloat(d.Fractions, 'g', -1, 64)
+			customerID, err := r.Encryptor.encryptIfSet(d.CustomerID)
+			if err != nil {
+				return err
+			}
+			if r.EncryptBalances {
+				if balance, err = r.Encryptor.encryptIfSet(balance); err != nil {
+					return err
+				}
+				if fractions, err = r.Encryptor.encryptIfSet(fractions); err != nil {
+					return err
+				}
+			}
+			return csvWriter.Write([]string{d.Iban, balance, d.Status, d.Type, d.Currency, fractions, customerID, d.Kyc, d.Product})
+		}
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	case NdjsonExportFormat:
+		encoder := json.NewEncoder(writer)
+		write = func(acc Account) error {
+			sealed, err := r.Encryptor.sealDetails(acc.Details(), r.EncryptBalances)
+			if err != nil {
+				return err
+			}
+			return encoder.Encode(sealed)
+		}
+		flush = func() error {
+			return nil
+		}
+	default:
+		return fmt.Errorf(errorCodesToMessagesMap[AccountsExportError][locale()])
+	}
+
+	var writeErr error
+	if err := forEach(func(acc Account) bool {
+		writeErr = write(acc)
+		return writeErr == nil
+	}); err != nil {
+		return err
+	}
+	if writeErr != nil || flush() != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountsExportError][locale()])
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Import accounts from CSV and make sure invalid files are rejected as a whole
+func TestImportAccountsCSV(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+
+	first, _ := GenerateValidBelarusianIban()
+	second, _ := GenerateValidBelarusianIban()
+	csv := "iban,balance,status,type\n" +
+		first[:4] + " " + first[4:] + ",150.50,Active,Ordinary\n" +
+		second + ",0,Заблокированный,Обычный\n"
+	count, err := service.ImportAccountsCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 imported accounts, got %d", count)
+	}
+	if acc, err := service.GetAccount(first); err != nil || acc.Balance != 150.5 || acc.Status != Active {
+		t.Errorf("Unexpected imported account %+v (%v)", acc, err)
+	}
+	if acc, err := service.GetAccount(second); err != nil || acc.Status != Blocked {
+		t.Errorf("Unexpected imported account %+v (%v)", acc, err)
+	}
+	// Imported balances must be accounted as emitted money
+	if rec, err := service.ReconcileFractions(); err != nil || !rec.Balanced {
+		t.Errorf("Expected books to be balanced after import, got %+v (%v)", rec, err)
+	}
+
+	third, _ := GenerateValidBelarusianIban()
+	invalid := []string{
+		third + ",10,Active,Ordinary\n" + first + ",10,Active,Ordinary\n",
+		third + ",10,Active,Ordinary\n" + third + ",10,Active,Ordinary\n",
+		third + ",-10,Active,Ordinary\n",
+		third + ",ten,Active,Ordinary\n",
+		third + ",10,Frozen,Ordinary\n",
+		third + ",10,Closed,Ordinary\n",
+		third + ",10,Active,Monetary emission\n",
+		third + ",10,Active\n",
+		"BY12,10,Active,Ordinary\n",
+	}
+	for _, data := range invalid {
+		if _, err := service.ImportAccountsCSV(strings.NewReader(data)); err == nil {
+			t.Errorf("Import of %q failed to fail", data)
+		}
+	}
+	if _, err := service.GetAccount(third); err == nil {
+		t.Errorf("Expected rejected import to leave no accounts behind")
+	}
+}
+
+// Export accounts to CSV and NDJSON and import the CSV back into another repository
+func TestExportAccounts(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccount()
+	if err := service.EmitMoney(20.5); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(emission, acc.Iban, 20.5); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var csvOut strings.Builder
+	if err := service.ExportAccounts(&csvOut, CsvExportFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "iban,balance,status,type") {
+		t.Fatalf("Unexpected CSV export:\n%s", csvOut.String())
+	}
+	if !strings.Contains(csvOut.String(), acc.Iban+",20.50,Active,Ordinary,BYN") {
+		t.Errorf("Expected CSV export to contain account %s, got:\n%s", acc.Iban, csvOut.String())
+	}
+
+	var ndjsonOut strings.Builder
+	if err := service.ExportAccounts(&ndjsonOut, NdjsonExportFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(ndjsonOut.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "{\"iban\":") {
+		t.Errorf("Unexpected NDJSON export:\n%s", ndjsonOut.String())
+	}
+
+	if err := service.ExportAccounts(&ndjsonOut, ExportFormat(42)); err == nil {
+		t.Errorf("Export in unknown format failed to fail")
+	}
+
+	// The exported account can be imported elsewhere, rows of special accounts excluded
+	ordinaryRows := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(csvOut.String()), "\n") {
+		if strings.HasPrefix(line, acc.Iban) {
+			ordinaryRows = append(ordinaryRows, line)
+		}
+	}
+	other := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	if count, err := other.ImportAccountsCSV(strings.NewReader(strings.Join(ordinaryRows, "\n"))); err != nil || count != 1 {
+		t.Errorf("Expected exported account to be imported, got %d (%v)", count, err)
+	}
+}
+
+// Rows of ordinary accounts exported from one repository import into another one and export from it the same way,
+// the whole export is rejected because of special accounts and taken IBANs
+func TestExportImportRoundTrip(t *testing.T) {
+	emission, destruction := "BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, destruction))
+	for _, deposit := range []float64{10, 20.25, 0} {
+		service.OpenAccountWithInitialDeposit(deposit)
+	}
+	blocked, _ := service.OpenAccountWithInitialDeposit(5)
+	service.BlockAccount(blocked.Iban)
+
+	// Only the first four columns of ordinary accounts are compared, the rest is not carried over
+	ordinaryRows := func(service *AccountService) []string {
+		var out strings.Builder
+		if err := service.ExportAccounts(&out, CsvExportFormat); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		rows := []string{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+			if columns := strings.Split(line, ","); columns[3] == "Ordinary" {
+				rows = append(rows, strings.Join(columns[:4], ","))
+			}
+		}
+		sort.Strings(rows)
+		return rows
+	}
+	exported := ordinaryRows(service)
+	if len(exported) != 4 {
+		t.Fatalf("Expected 4 ordinary accounts to be exported, got %v", exported)
+	}
+
+	var whole strings.Builder
+	service.ExportAccounts(&whole, CsvExportFormat)
+	other := NewAccountService(NewInMemoryAccountRepository(emission, destruction))
+	if _, err := other.ImportAccountsCSV(strings.NewReader(whole.String())); err == nil {
+		t.Errorf("Importing the export with special accounts failed to fail")
+	}
+	if count, err := other.ImportAccountsCSV(strings.NewReader(strings.Join(exported, "\n"))); err != nil || count != 4 {
+		t.Fatalf("Expected exported accounts to be imported, got %d (%v)", count, err)
+	}
+	reexported := ordinaryRows(other)
+	if strings.Join(reexported, "\n") != strings.Join(exported, "\n") {
+		t.Errorf("Expected the same accounts after the round trip, got %v instead of %v", reexported, exported)
+	}
+	if _, err := other.ImportAccountsCSV(strings.NewReader(strings.Join(exported, "\n"))); err == nil {
+		t.Errorf("Importing taken IBANs failed to fail")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining manual adjustments: operators correct balances against the adjustments ledger account instead of emitting
+// and transferring money, so that corrections are visible as such in the journal and in the money supply
+type Adjustment struct {
+	TransferID string // ULID of the adjustment transaction
+	Iban       string
+	Delta      float64 // positive adjustments credit the account, negative ones debit it
+	Currency   string
+	Reason     string
+	Operator   string
+	Timestamp  time.Time
+}
+
+// Maximum length of the reason of the adjustment, the reason is also stored as the memo of the transaction
+const maxAdjustmentReasonLength = maxTransferMemoLength
+
+// Only ordinary and term deposit accounts are adjusted, negative adjustments cannot exceed the available balance
+func (r *InMemoryAccountRepository) AdjustBalance(iban string, delta float64, reason, operator string) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	reason = strings.Join(strings.Fields(reason), " ")
+	operator = strings.TrimSpace(operator)
+	// Checking if the adjustment is made by a known operator for a reason and the delta is valid
+	if operator == "" || reason == "" || len([]rune(reason)) > maxAdjustmentReasonLength || delta == 0 || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidAdjustmentError][locale()])
+	}
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is not closed
+	if acc.Status == Closed {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the account holds money of customers
+	if acc.Type != Ordinary && acc.Type != TermDepositAccount {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+
+	amount := roundToCurrency(math.Abs(delta), acc.Currency)
+	tx := &Transaction{Type: AdjustmentTransaction, Amount: amount, Currency: acc.Currency, Memo: reason}
+	var lines []LedgerLine
+	if delta > 0 {
+		tx.Recipient = acc.Iban
+		lines = []LedgerLine{debitLedgerLine(AdjustmentsLedgerAccount, acc.Currency, amount), creditLine(acc, amount)}
+	} else {
+		// Checking if the available balance is sufficient and the balance stays at or above the floor of the account
+		if acc.AvailableBalance() < amount {
+			return "", insufficientFundsError(acc)
+		}
+		if err := checkBalanceFloor(acc, amount); err != nil {
+			return "", err
+		}
+		tx.Sender = acc.Iban
+		lines = []LedgerLine{debitLine(acc, amount), creditLedgerLine(AdjustmentsLedgerAccount, acc.Currency, amount)}
+	}
+	if err := r.post(tx, lines...); err != nil {
+		return "", err
+	}
+	if delta < 0 {
+		amount = -amount
+	}
+	r.Adjustments = append(r.Adjustments, &Adjustment{tx.Ulid, acc.Iban, amount, acc.Currency, reason, operator, tx.Timestamp})
+	return tx.Ulid, nil
+}
+
+// Lists adjustments of the account (of all accounts if empty IBAN is passed), the oldest first
+func (r *InMemoryAccountRepository) ListAdjustments(iban string) ([]Adjustment, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	adjustments := []Adjustment{}
+	for _, adjustment := range r.Adjustments {
+		if iban == "" || adjustment.Iban == iban {
+			adjustments = append(adjustments, *adjustment)
+		}
+	}
+	return adjustments, nil
+}
+
+// Helper function to sum up money brought into circulation by adjustments, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) adjustedMoney(currency string) float64 {
+	return -r.LedgerBalances[ledgerBalanceKey(AdjustmentsLedgerAccount, currency)]
+}
+
+// Adjusts the balance on behalf of the principal of the service
+func (s *AccountService) AdjustBalance(iban string, delta float64, reason string) (string, error) {
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.auditedID(AdjustAction, iban, "", delta, "", err)
+	}
+	id, err := s.accountRepoImpl.AdjustBalance(iban, delta, reason, s.principal.ID)
+	return s.auditedID(AdjustAction, iban, "", delta, id, err)
+}
+
+func (s *AccountService) ListAdjustments(iban string) ([]Adjustment, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListAdjustments(iban)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+// Adjustments correct balances against the adjustments ledger account and keep the invariants
+func TestAdjustBalance(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	admin := service.As(Principal{ID: "admin", Role: AdminRole})
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+
+	if _, err := service.AdjustBalance(acc.Iban, 10, "Duplicate debit"); err == nil {
+		t.Errorf("Adjustment without a principal failed to fail")
+	}
+	if _, err := admin.AdjustBalance(acc.Iban, 10, " "); err == nil {
+		t.Errorf("Adjustment without a reason failed to fail")
+	}
+	if _, err := admin.AdjustBalance(acc.Iban, 0, "Nothing"); err == nil {
+		t.Errorf("Adjustment by zero failed to fail")
+	}
+	if _, err := admin.AdjustBalance(emission, 10, "Duplicate debit"); err == nil {
+		t.Errorf("Adjustment of the emission account failed to fail")
+	}
+	if _, err := admin.AdjustBalance(acc.Iban, -500, "Erroneous credit"); err == nil {
+		t.Errorf("Adjustment below the balance floor failed to fail")
+	}
+	credit, err := admin.AdjustBalance(acc.Iban, 25.5, "Duplicate debit")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := admin.AdjustBalance(acc.Iban, -5.5, "Erroneous credit"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Balance != 120 {
+		t.Errorf("Expected balance of 120, got %v", details.Balance)
+	}
+	if tx, _ := service.RetrieveTransaction(credit); tx.Type != AdjustmentTransaction || tx.Memo != "Duplicate debit" || tx.Recipient != acc.Iban {
+		t.Errorf("Unexpected adjustment transaction: %+v", tx)
+	}
+	if balance := service.RetrieveLedgerBalance(AdjustmentsLedgerAccount, DefaultCurrency); balance != -20 {
+		t.Errorf("Expected 20 brought into circulation by adjustments, got %v", balance)
+	}
+	adjustments, _ := service.ListAdjustments(acc.Iban)
+	if len(adjustments) != 2 || adjustments[0].Operator != "admin" || adjustments[1].Delta != -5.5 {
+		t.Errorf("Unexpected adjustments: %+v", adjustments)
+	}
+	if entries, _ := service.QueryAuditLog(AuditFilter{Actions: []AuditAction{AdjustAction}}); len(entries) != 7 || entries[5].Principal != "admin" || entries[5].ID != credit {
+		t.Errorf("Expected all adjustment attempts to be audited, got %+v", entries)
+	}
+
+	if _, err := service.VerifyInvariants(); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if _, err := service.ReconcileFractions(); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining proxy directory: phone numbers and email addresses registered as aliases of accounts,
+// so that money can be sent without knowing the IBAN of the recipient
+type AliasType int8
+
+const (
+	PhoneAlias AliasType = iota
+	EmailAlias
+)
+
+// Mapping alias type codes to alias type names considering locale
+var aliasTypeCodeToNameMap map[AliasType](map[LanguageCode]string) = map[AliasType](map[LanguageCode]string){
+	PhoneAlias: {
+		English: "Phone number",
+		Russian: "Номер телефона",
+	},
+	EmailAlias: {
+		English: "Email address",
+		Russian: "Адрес электронной почты",
+	},
+}
+
+// Helper function to bring the alias to its canonical form, phone numbers are expected in the international format
+// (+375 29 123-45-67 becomes +375291234567) and email addresses are matched regardless of case
+func normalizeAlias(alias string) (string, AliasType, bool) {
+	alias = strings.TrimSpace(alias)
+	if strings.Contains(alias, "@") {
+		alias = strings.ToLower(alias)
+		at := strings.Index(alias, "@")
+		local, domain := alias[:at], alias[at+1:]
+		valid := local != "" && len(alias) <= 254 && !strings.ContainsAny(alias, " \t\r\n") && !strings.Contains(domain, "@") &&
+			strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+		return alias, EmailAlias, valid
+	}
+	phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(alias)
+	// E.164 numbers have up to 15 digits including the country code
+	if !strings.HasPrefix(phone, "+") || len(phone) < 8 || len(phone) > 16 {
+		return "", PhoneAlias, false
+	}
+	for _, c := range phone[1:] {
+		if c < '0' || c > '9' {
+			return "", PhoneAlias, false
+		}
+	}
+	return phone, PhoneAlias, true
+}
+
+// Registers the alias for the ordinary account, an alias can point to a single account only
+// Registering the alias for the account it already points to does nothing
+func (r *InMemoryAccountRepository) RegisterAlias(alias, iban string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if the alias is a phone number or an email address
+	alias, _, ok := normalizeAlias(alias)
+	if !ok {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidAliasError][locale()])
+	}
+	// Checking if account associated with the given IBAN exists and can receive payments
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if acc.Type != Ordinary {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	if acc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the alias is not taken by another account
+	if registered, exists := r.Aliases[alias]; exists && registered != iban {
+		return fmt.Errorf(errorCodesToMessagesMap[AliasAlreadyRegisteredError][locale()])
+	}
+	r.Aliases[alias] = iban
+	return nil
+}
+
+// Returns the IBAN of the account the alias is registered for
+func (r *InMemoryAccountRepository) ResolveAlias(alias string) (string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	alias, _, ok := normalizeAlias(alias)
+	if !ok {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidAliasError][locale()])
+	}
+	iban, exists := r.Aliases[alias]
+	if !exists {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AliasDoesNotExistError][locale()])
+	}
+	return iban, nil
+}
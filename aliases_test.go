@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNormalizeAlias(t *testing.T) {
+	tests := []struct {
+		alias      string
+		normalized string
+		aliasType  AliasType
+		valid      bool
+	}{
+		{"+375 (29) 123-45-67", "+375291234567", PhoneAlias, true},
+		{"+1234567", "+1234567", PhoneAlias, true},
+		{"375291234567", "", PhoneAlias, false},
+		{"+37529123456789012", "", PhoneAlias, false},
+		{"+375 29 ABC", "", PhoneAlias, false},
+		{" John.Doe@Example.COM ", "john.doe@example.com", EmailAlias, true},
+		{"@example.com", "@example.com", EmailAlias, false},
+		{"john@localhost", "john@localhost", EmailAlias, false},
+		{"john@@example.com", "john@@example.com", EmailAlias, false},
+		{"john doe@example.com", "john doe@example.com", EmailAlias, false},
+	}
+	for _, test := range tests {
+		normalized, aliasType, valid := normalizeAlias(test.alias)
+		if valid != test.valid || aliasType != test.aliasType || (valid && normalized != test.normalized) {
+			t.Errorf("normalizeAlias(%q) = %q, %v, %v, expected %q, %v, %v", test.alias, normalized, aliasType, valid, test.normalized, test.aliasType, test.valid)
+		}
+	}
+}
+
+// Register aliases and send money to them
+func TestAliases(t *testing.T) {
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	other, _ := service.OpenAccount()
+	emission, _ := service.RetrieveEmissionAccountIban()
+
+	if err := service.RegisterAlias("+375 29 123-45-67", recipient.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.RegisterAlias("+375291234567", recipient.Iban); err != nil {
+		t.Errorf("Registering the same alias again failed: %v", err)
+	}
+	if err := service.RegisterAlias("Jane@Example.com", recipient.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.RegisterAlias("+375291234567", other.Iban); err == nil {
+		t.Errorf("Registering alias taken by another account failed to fail")
+	}
+	if err := service.RegisterAlias("+375297654321", emission); err == nil {
+		t.Errorf("Registering alias for system account failed to fail")
+	}
+	if err := service.RegisterAlias("not an alias", other.Iban); err == nil {
+		t.Errorf("Registering invalid alias failed to fail")
+	}
+	if iban, err := service.ResolveAlias("jane@example.com"); err != nil || iban != recipient.Iban {
+		t.Errorf("Expected alias to resolve to %s, got %s (%v)", recipient.Iban, iban, err)
+	}
+	if _, err := service.ResolveAlias("+375297654321"); err == nil {
+		t.Errorf("Resolving unregistered alias failed to fail")
+	}
+
+	if _, err := service.TransferMoneyJson(`{"sender": "` + sender.Iban + `", "recipient_alias": "+375 29 123 45 67", "amount": 30}`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoneyJson(`{"sender": "` + sender.Iban + `", "recipient": "` + other.Iban + `", "recipient_alias": "jane@example.com", "amount": 30}`); err == nil {
+		t.Errorf("Transfer naming both recipient and its alias failed to fail")
+	}
+	if _, err := service.TransferMoneyJson(`{"sender": "` + sender.Iban + `", "recipient_alias": "john@example.com", "amount": 30}`); err == nil {
+		t.Errorf("Transfer to unregistered alias failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 30 {
+		t.Errorf("Expected recipient balance of 30, got %v", details.Balance)
+	}
+	if details, _ := service.RetrieveAccount(sender.Iban); details.Balance != 70 {
+		t.Errorf("Expected sender balance of 70, got %v", details.Balance)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining anti-money laundering monitor raising alerts on large transfers for compliance users to review
+// The monitor listens to settled transfers on the event bus and publishes alerts back to it
+type AmlAlertKind int8
+
+const (
+	SingleTransferAmlAlert AmlAlertKind = iota // a single transfer exceeded the threshold
+	DailyAggregateAmlAlert                     // transfers from the account on one day (UTC) exceeded the threshold together
+)
+
+// Mapping AML alert kind codes to AML alert kind names considering locale
+var amlAlertKindCodeToNameMap map[AmlAlertKind](map[LanguageCode]string) = map[AmlAlertKind](map[LanguageCode]string){
+	SingleTransferAmlAlert: {
+		English: "Single transfer",
+		Russian: "Разовый перевод",
+	},
+	DailyAggregateAmlAlert: {
+		English: "Daily aggregate",
+		Russian: "Сумма за день",
+	},
+}
+
+// Thresholds are compared to amounts in the currency of the sender account, zero disables the threshold
+type AmlThresholds struct {
+	Single float64
+	Daily  float64
+}
+
+type AmlAlert struct {
+	ID         string
+	Kind       AmlAlertKind
+	Iban       string  // sender of the transfers
+	Amount     float64 // amount of the transfer or the daily sum that triggered the alert
+	Currency   string
+	TransferID string // transfer that triggered the alert
+	Resolved   bool
+	ResolvedBy string
+	Resolution string
+	CreatedAt  time.Time
+	ResolvedAt time.Time
+}
+
+type AmlMonitor struct {
+	Thresholds  AmlThresholds
+	bus         EventBus
+	alerts      map[string]*AmlAlert
+	daily       map[string]float64 // sums of transfers by sender and day
+	dailyAlerts map[string]bool    // senders and days already alerted, so that one day produces one aggregate alert
+	stop        func()
+	done        chan struct{}
+	mutex       sync.Mutex
+}
+
+// Starts processing settled transfers published on the bus in a background goroutine until Stop is called
+func NewAmlMonitor(bus EventBus, thresholds AmlThresholds) *AmlMonitor {
+	events, stop := bus.Subscribe(TransferSettledEvent)
+	m := &AmlMonitor{Thresholds: thresholds, bus: bus, alerts: map[string]*AmlAlert{}, daily: map[string]float64{}, dailyAlerts: map[string]bool{}, stop: stop, done: make(chan struct{})}
+	go func() {
+		defer close(m.done)
+		for event := range events {
+			if payload, ok := event.Payload.(TransferEventPayload); ok {
+				m.process(payload, event.Timestamp)
+			}
+		}
+	}()
+	return m
+}
+
+// Stops listening to the event bus, transfers published afterwards are not monitored
+func (m *AmlMonitor) Stop() {
+	m.stop()
+	<-m.done
+}
+
+func (m *AmlMonitor) process(tx TransferEventPayload, at time.Time) {
+	m.mutex.Lock()
+	raised := []AmlAlert{}
+	if m.Thresholds.Single > 0 && tx.Amount > m.Thresholds.Single {
+		raised = append(raised, m.raise(SingleTransferAmlAlert, tx, tx.Amount, at))
+	}
+	day := tx.Sender + "|" + at.Format(time.DateOnly)
+	m.daily[day] = roundToCurrency(m.daily[day]+tx.Amount, tx.Currency)
+	if m.Thresholds.Daily > 0 && m.daily[day] > m.Thresholds.Daily && !m.dailyAlerts[day] {
+		m.dailyAlerts[day] = true
+		raised = append(raised, m.raise(DailyAggregateAmlAlert, tx, m.daily[day], at))
+	}
+	m.mutex.Unlock()
+
+	for _, alert := range raised {
+		m.bus.Publish(AmlAlertEvent, alert)
+	}
+}
+
+// Helper function to store a new alert, expects the monitor mutex to be held by the caller
+func (m *AmlMonitor) raise(kind AmlAlertKind, tx TransferEventPayload, amount float64, at time.Time) AmlAlert {
+	alert := &AmlAlert{ID: NewUlid(at), Kind: kind, Iban: tx.Sender, Amount: amount, Currency: tx.Currency, TransferID: tx.TransferID, CreatedAt: at}
+	m.alerts[alert.ID] = alert
+	return *alert
+}
+
+// Lists alerts, the oldest first, optionally only the ones not resolved yet
+func (m *AmlMonitor) ListAlerts(unresolvedOnly bool) []AmlAlert {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	alerts := []AmlAlert{}
+	for _, alert := range m.alerts {
+		if !unresolvedOnly || !alert.Resolved {
+			alerts = append(alerts, *alert)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].CreatedAt.Before(alerts[j].CreatedAt) })
+	return alerts
+}
+
+// Marks the alert as reviewed by the compliance user with the outcome of the review
+func (m *AmlMonitor) ResolveAlert(id, principal, resolution string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	alert, exists := m.alerts[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || alert == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AlertDoesNotExistError][locale()])
+	}
+	if alert.Resolved {
+		return fmt.Errorf(errorCodesToMessagesMap[AlertAlreadyResolvedError][locale()])
+	}
+	principal = strings.TrimSpace(principal)
+	if principal == "" {
+		return fmt.Errorf(errorCodesToMessagesMap[PrincipalRequiredError][locale()])
+	}
+	alert.Resolved = true
+	alert.ResolvedBy = principal
+	alert.Resolution = strings.TrimSpace(resolution)
+	alert.ResolvedAt = clock.Now().UTC()
+	return nil
+}
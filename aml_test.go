@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Helper function to wait for an event of the given type or fail the test
+func waitForEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for an event")
+	}
+	return Event{}
+}
+
+// Raise AML alerts on large single and aggregated daily transfers and resolve them
+func TestAmlAlerts(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(900)
+	recipient, _ := service.OpenAccount()
+
+	bus := NewInMemoryEventBus()
+	inMemImpl.EventBus = bus
+	alerts, unsubscribe := bus.Subscribe(AmlAlertEvent)
+	defer unsubscribe()
+	monitor := NewAmlMonitor(bus, AmlThresholds{Single: 300, Daily: 500})
+	defer monitor.Stop()
+
+	id, err := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 350, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	single := waitForEvent(t, alerts).Payload.(AmlAlert)
+	if single.Kind != SingleTransferAmlAlert || single.TransferID != id || single.Iban != sender.Iban || single.Amount != 350 {
+		t.Errorf("Unexpected single transfer alert: %+v", single)
+	}
+
+	// Small transfers adding up to more than the daily threshold raise one aggregate alert
+	for i := 0; i < 3; i++ {
+		if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 100); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	daily := waitForEvent(t, alerts).Payload.(AmlAlert)
+	if daily.Kind != DailyAggregateAmlAlert || daily.Amount != 550 {
+		t.Errorf("Unexpected daily aggregate alert: %+v", daily)
+	}
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	select {
+	case event := <-alerts:
+		t.Errorf("Unexpected alert after the daily threshold was already reported: %+v", event.Payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if open := monitor.ListAlerts(true); len(open) != 2 || open[0].ID != single.ID {
+		t.Errorf("Unexpected open alerts: %+v", open)
+	}
+	if err := monitor.ResolveAlert(single.ID, "", "Known customer"); err == nil {
+		t.Errorf("Resolving alert without principal failed to fail")
+	}
+	if err := monitor.ResolveAlert(single.ID, "compliance", "Known customer"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := monitor.ResolveAlert(single.ID, "compliance", "Known customer"); err == nil {
+		t.Errorf("Resolving alert twice failed to fail")
+	}
+	if err := monitor.ResolveAlert("unknown", "compliance", ""); err == nil {
+		t.Errorf("Resolving unknown alert failed to fail")
+	}
+	if open, all := monitor.ListAlerts(true), monitor.ListAlerts(false); len(open) != 1 || len(all) != 2 || !all[0].Resolved || all[0].ResolvedBy != "compliance" {
+		t.Errorf("Unexpected alerts after resolution: %+v", all)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining API keys of the clients of the HTTP layer: only hashes of the secrets are stored, the secret is returned
+// once when the key is issued or rotated, every key carries the principal it acts for, its scopes and its rate limit
+type ApiKeyScope int8
+
+const (
+	ReadScope     ApiKeyScope = iota // reading balances, e.g. vostro balances of the peers
+	TransferScope                    // submitting transfers
+	AdminScope                       // any operation
+)
+
+// Mapping scope codes to scope names considering locale
+var apiKeyScopeCodeToNameMap map[ApiKeyScope](map[LanguageCode]string) = map[ApiKeyScope](map[LanguageCode]string){
+	ReadScope: {
+		English: "Read",
+		Russian: "Чтение",
+	},
+	TransferScope: {
+		English: "Transfer",
+		Russian: "Переводы",
+	},
+	AdminScope: {
+		English: "Admin",
+		Russian: "Администрирование",
+	},
+}
+
+type ApiKeyStatus int8
+
+const (
+	ApiKeyActive ApiKeyStatus = iota
+	ApiKeyRevoked
+)
+
+type ApiKey struct {
+	ID          string
+	Principal   Principal // principal the requests authenticated by the key act for
+	Scopes      []ApiKeyScope
+	RateLimit   int // requests per minute, zero disables the limit
+	SecretHash  string
+	Status      ApiKeyStatus
+	CreatedAt   time.Time
+	ExpiresAt   time.Time // set on rotation, the replaced key keeps working until then
+	RevokedAt   time.Time
+	ReplacedBy  string // ID of the key issued on rotation
+	windowStart time.Time
+	windowCount int
+}
+
+// Helper function to check if the key grants the scope, the admin scope grants any
+func (k *ApiKey) hasScope(scope ApiKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == AdminScope {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to hash the secret of the key, the secrets themselves are never stored
+func hashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Helper function to create the key and its token "<ID>.<secret>", expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) newApiKey(principal Principal, scopes []ApiKeyScope, rateLimit int, now time.Time) (*ApiKey, string) {
+	var secret [32]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		panic(err)
+	}
+	encoded := hex.EncodeToString(secret[:])
+	key := &ApiKey{ID: NewUlid(now), Principal: principal, Scopes: append([]ApiKeyScope{}, scopes...), RateLimit: rateLimit, SecretHash: hashApiKeySecret(encoded), Status: ApiKeyActive, CreatedAt: now}
+	r.ApiKeys[key.ID] = key
+	return key, key.ID + "." + encoded
+}
+
+// Returns the issued key along with its token, the token is not retrievable afterwards
+func (r *InMemoryAccountRepository) IssueApiKey(principal Principal, scopes []ApiKeyScope, rateLimit int) (*ApiKey, string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the key acts for a principal and grants known scopes
+	principal.ID = strings.TrimSpace(principal.ID)
+	if principal.ID == "" || len(scopes) == 0 || rateLimit < 0 {
+		return nil, "", fmt.Errorf(errorCodesToMessagesMap[InvalidApiKeyError][locale()])
+	}
+	for _, scope := range scopes {
+		if _, known := apiKeyScopeCodeToNameMap[scope]; !known {
+			return nil, "", fmt.Errorf(errorCodesToMessagesMap[InvalidApiKeyError][locale()])
+		}
+	}
+	key, token := r.newApiKey(principal, scopes, rateLimit, clock.Now().UTC())
+	copied := *key
+	return &copied, token, nil
+}
+
+// Helper function to find a key which has not been revoked, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) activeApiKey(id string, now time.Time) (*ApiKey, error) {
+	key, exists := r.ApiKeys[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || key == nil || key.Status != ApiKeyActive || (!key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt)) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidApiKeyError][locale()])
+	}
+	return key, nil
+}
+
+// Issues a key with the same principal, scopes and rate limit, the replaced key keeps working for the grace period,
+// zero grace period revokes it right away
+func (r *InMemoryAccountRepository) RotateApiKey(id string, grace time.Duration) (*ApiKey, string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	now := clock.Now().UTC()
+	old, err := r.activeApiKey(id, now)
+	if err != nil {
+		return nil, "", err
+	}
+	// Checking if the key has not been rotated already
+	if old.ReplacedBy != "" || grace < 0 {
+		return nil, "", fmt.Errorf(errorCodesToMessagesMap[InvalidApiKeyError][locale()])
+	}
+	key, token := r.newApiKey(old.Principal, old.Scopes, old.RateLimit, now)
+	old.ReplacedBy = key.ID
+	if grace == 0 {
+		old.Status = ApiKeyRevoked
+		old.RevokedAt = now
+	} else {
+		old.ExpiresAt = now.Add(grace)
+	}
+	copied := *key
+	return &copied, token, nil
+}
+
+func (r *InMemoryAccountRepository) RevokeApiKey(id string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	now := clock.Now().UTC()
+	key, err := r.activeApiKey(id, now)
+	if err != nil {
+		return err
+	}
+	key.Status = ApiKeyRevoked
+	key.RevokedAt = now
+	return nil
+}
+
+// Lists keys of the principal (all keys if empty ID is passed), the oldest first
+func (r *InMemoryAccountRepository) ListApiKeys(principalID string) ([]ApiKey, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	principalID = strings.TrimSpace(principalID)
+	keys := []ApiKey{}
+	for _, key := range r.ApiKeys {
+		if principalID == "" || strings.EqualFold(key.Principal.ID, principalID) {
+			keys = append(keys, *key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Checks the token, the scope and the rate limit of the key, every successful call counts towards the limit
+func (r *InMemoryAccountRepository) AuthenticateApiKey(token string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	id, secret, found := strings.Cut(strings.TrimSpace(token), ".")
+	if !found {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidApiKeyError][locale()])
+	}
+	key, err := r.activeApiKey(id, now)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashApiKeySecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidApiKeyError][locale()])
+	}
+	// Checking if the key grants the scope
+	if !key.hasScope(scope) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	// Checking if the key has requests left in the current minute, the default rate limit applies to keys without their own
+	rateLimit := key.RateLimit
+	if rateLimit == 0 {
+		rateLimit = r.ApiKeyRateLimit
+	}
+	if rateLimit > 0 {
+		if now.Sub(key.windowStart) >= time.Minute {
+			key.windowStart = now
+			key.windowCount = 0
+		}
+		if key.windowCount >= rateLimit {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[ApiKeyRateLimitExceededError][locale()])
+		}
+		key.windowCount++
+	}
+	copied := *key
+	return &copied, nil
+}
+
+// Checks if the key is still active and grants the scope, without its secret and without counting towards its rate limit,
+// i.e. for requests of sessions opened with the key
+func (r *InMemoryAccountRepository) CheckApiKey(id string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	key, err := r.activeApiKey(id, now)
+	if err != nil {
+		return nil, err
+	}
+	if !key.hasScope(scope) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	copied := *key
+	return &copied, nil
+}
+
+// Default rate limit applies to API keys issued without a rate limit of their own, i.e. set by the configuration
+func (r *InMemoryAccountRepository) SetApiKeyRateLimit(rateLimit int) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	if rateLimit < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	r.ApiKeyRateLimit = rateLimit
+	return nil
+}
+
+func (s *AccountService) IssueApiKey(principal Principal, scopes []ApiKeyScope, rateLimit int) (*ApiKey, string, error) {
+	if err := s.authorize(KeyPermission); err != nil {
+		return nil, "", err
+	}
+	return s.accountRepoImpl.IssueApiKey(principal, scopes, rateLimit)
+}
+
+func (s *AccountService) RotateApiKey(id string, grace time.Duration) (*ApiKey, string, error) {
+	if err := s.authorize(KeyPermission); err != nil {
+		return nil, "", err
+	}
+	return s.accountRepoImpl.RotateApiKey(id, grace)
+}
+
+func (s *AccountService) RevokeApiKey(id string) error {
+	if err := s.authorize(KeyPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RevokeApiKey(id)
+}
+
+func (s *AccountService) ListApiKeys(principalID string) ([]ApiKey, error) {
+	if err := s.authorize(KeyPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListApiKeys(principalID)
+}
+
+func (s *AccountService) AuthenticateApiKey(token string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	return s.accountRepoImpl.AuthenticateApiKey(token, scope, now)
+}
+
+func (s *AccountService) CheckApiKey(id string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	return s.accountRepoImpl.CheckApiKey(id, scope, now)
+}
+
+// Header the clients pass their tokens in, "Authorization: Bearer <token>" is accepted as well
+const apiKeyHeader = "X-API-Key"
+
+type principalContextKey struct{}
+
+// Returns the principal authenticated by the middleware, false if the request was not authenticated
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
+
+// Wraps the handler to reject requests without a valid key granting the scope of the request,
+// the principal of the key is passed to the handler in the request context, see PrincipalFromContext
+func ApiKeyMiddleware(service *AccountService, scopeOf func(*http.Request) ApiKeyScope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := req.Header.Get(apiKeyHeader)
+		if token == "" {
+			token = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		}
+		key, err := service.AuthenticateApiKey(token, scopeOf(req), clock.Now().UTC())
+		if err != nil {
+			status := http.StatusUnauthorized
+			switch err.Error() {
+			case errorCodesToMessagesMap[AccessDeniedError][locale()]:
+				status = http.StatusForbidden
+			case errorCodesToMessagesMap[ApiKeyRateLimitExceededError][locale()]:
+				status = http.StatusTooManyRequests
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), principalContextKey{}, key.Principal)))
+	})
+}
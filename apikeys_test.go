@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Keys are checked for their secrets, scopes and rate limits, rotated keys keep working for the grace period
+func TestApiKeys(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	teller := service.As(Principal{ID: "teller", Role: TellerRole})
+	principal := Principal{ID: "client", Role: TellerRole}
+
+	if _, _, err := teller.IssueApiKey(principal, []ApiKeyScope{ReadScope}, 0); err == nil {
+		t.Errorf("Issuance by the teller failed to fail")
+	}
+	if _, _, err := service.IssueApiKey(principal, nil, 0); err == nil {
+		t.Errorf("Issuance without scopes failed to fail")
+	}
+	key, token, err := service.IssueApiKey(principal, []ApiKeyScope{ReadScope}, 2)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if key.SecretHash == "" || key.SecretHash == token {
+		t.Errorf("Expected only the hash of the secret to be stored, got %+v", key)
+	}
+	now := time.Now()
+	if authenticated, err := service.AuthenticateApiKey(token, ReadScope, now); err != nil || authenticated.Principal.ID != "client" {
+		t.Errorf("Expected the key to authenticate the client, got %+v (%v)", authenticated, err)
+	}
+	if _, err := service.AuthenticateApiKey(key.ID+".secret", ReadScope, now); err == nil {
+		t.Errorf("Authentication with a wrong secret failed to fail")
+	}
+	if _, err := service.AuthenticateApiKey(token, TransferScope, now); err == nil {
+		t.Errorf("Authentication for a scope not granted failed to fail")
+	}
+	service.AuthenticateApiKey(token, ReadScope, now)
+	if _, err := service.AuthenticateApiKey(token, ReadScope, now); err == nil {
+		t.Errorf("Authentication exceeding the rate limit failed to fail")
+	}
+	if _, err := service.AuthenticateApiKey(token, ReadScope, now.Add(time.Minute)); err != nil {
+		t.Errorf("Expected the limit to be reset after a minute, got %v", err)
+	}
+
+	rotated, rotatedToken, err := service.RotateApiKey(key.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if rotated.RateLimit != 2 || len(rotated.Scopes) != 1 || rotated.Principal != principal {
+		t.Errorf("Expected the rotated key to keep the scopes and the limit, got %+v", rotated)
+	}
+	if _, _, err := service.RotateApiKey(key.ID, time.Hour); err == nil {
+		t.Errorf("Rotating the replaced key again failed to fail")
+	}
+	later := now.Add(2 * time.Minute)
+	if _, err := service.AuthenticateApiKey(token, ReadScope, later); err != nil {
+		t.Errorf("Expected the replaced key to work during the grace period, got %v", err)
+	}
+	if _, err := service.AuthenticateApiKey(token, ReadScope, now.Add(2*time.Hour)); err == nil {
+		t.Errorf("Authentication with the expired key failed to fail")
+	}
+	if err := service.RevokeApiKey(rotated.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.AuthenticateApiKey(rotatedToken, ReadScope, later); err == nil {
+		t.Errorf("Authentication with the revoked key failed to fail")
+	}
+	if keys, _ := service.ListApiKeys("CLIENT"); len(keys) != 2 || keys[0].ID != key.ID || keys[1].Status != ApiKeyRevoked {
+		t.Errorf("Unexpected keys: %+v", keys)
+	}
+}
+
+// Gateway requiring API keys rejects peers without a key granting the scope of the request
+func TestApiKeyMiddleware(t *testing.T) {
+	alfa, alfaGateway, alfaServer, sender := startInterbankInstance(t, "ALFA", 100)
+	defer alfaServer.Close()
+	beta, betaGateway, _, _ := startInterbankInstance(t, "BETA", 0)
+	betaGateway.RequireApiKeys, betaGateway.RequireSignatures = true, false
+	betaServer := httptest.NewServer(betaGateway.Handler())
+	defer betaServer.Close()
+	alfaGateway.AddPeer("BETA", betaServer.URL)
+	betaGateway.AddPeer("ALFA", alfaServer.URL)
+	recipient, _ := beta.OpenAccountWithIban(ibanWithBban("BETA00000000000000000042"))
+
+	resp, err := http.Get(betaServer.URL + interbankVostroPath + "?bank=ALFA")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected request without a key to be unauthorized, got %d", resp.StatusCode)
+	}
+
+	_, readToken, _ := beta.IssueApiKey(Principal{ID: "ALFA", Role: TellerRole}, []ApiKeyScope{ReadScope}, 0)
+	alfaGateway.SetPeerApiKey("BETA", readToken)
+	id, err := alfaGateway.SendTransfer(sender.Iban, recipient.Iban, 40, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := alfa.GetTransferStatus(id); status != TransferPending {
+		t.Errorf("Expected transfer to wait for delivery with a key lacking the scope, got %v", status)
+	}
+
+	_, transferToken, _ := beta.IssueApiKey(Principal{ID: "ALFA", Role: TellerRole}, []ApiKeyScope{ReadScope, TransferScope}, 0)
+	alfaGateway.SetPeerApiKey("BETA", transferToken)
+	if delivered := alfaGateway.RunRetries(time.Now().Add(time.Hour)); delivered != 1 {
+		t.Fatalf("Expected the queued transfer to be delivered, got %d", delivered)
+	}
+	if details, _ := beta.RetrieveAccount(recipient.Iban); details.Balance != 40 {
+		t.Errorf("Expected recipient balance of 40, got %v", details.Balance)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining maker-checker workflow: transfers above the approval threshold are not executed right away,
+// they wait until a principal other than the initiator approves or rejects them
+// Both the initiator and the checker are the principals the service is bound to, see As, transfers submitted by the service
+// not bound to any principal have no initiator and cannot be approved
+type ApprovalStatus int8
+
+const (
+	ApprovalPending ApprovalStatus = iota
+	ApprovalApproved
+	ApprovalRejected
+	ApprovalFailed // approved but the transfer could not be executed, i.e. because of insufficient balance at that time
+)
+
+// Mapping approval status codes to approval status names considering locale
+var approvalStatusCodeToNameMap map[ApprovalStatus](map[LanguageCode]string) = map[ApprovalStatus](map[LanguageCode]string){
+	ApprovalPending: {
+		English: "Pending approval",
+		Russian: "Ожидает подтверждения",
+	},
+	ApprovalApproved: {
+		English: "Approved",
+		Russian: "Подтверждён",
+	},
+	ApprovalRejected: {
+		English: "Rejected",
+		Russian: "Отклонён",
+	},
+	ApprovalFailed: {
+		English: "Failed",
+		Russian: "Не исполнен",
+	},
+}
+
+// ID of the approval is the ID of the transfer handed out to the initiator, it is kept by the transfer once executed
+type Approval struct {
+	ID        string
+	Sender    string
+	Recipient string
+	Amount    float64
+	Details   TransferDetails
+	Status    ApprovalStatus
+	Checker   string // principal who approved or rejected the transfer
+	Reason    string // reason of the rejection or the failure
+	CreatedAt time.Time
+	DecidedAt time.Time
+}
+
+// Helper function to either execute the transfer or put it aside for approval, expects the repository mutex to be held by the caller
+// Transfers are put aside if they exceed the approval threshold or velocity rules demand a delay,
+// transfers executed right away may need confirmation by a one-time code first, see otp.go
+// Returns the ID of the transfer in both cases
+func (r *InMemoryAccountRepository) submitTransfer(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	sender = NormalizeIban(sender)
+	// Recipient can be given by an alias from the address book of the sender
+	recipient = NormalizeIban(r.resolveBeneficiary(sender, recipient))
+
+	// Checking if velocity rules allow the transfer
+	action, rule := VelocityAllow, ""
+	if r.VelocityEngine != nil {
+		action, rule = r.VelocityEngine.Evaluate(sender, amount, clock.Now().UTC())
+	}
+	if action == VelocityBlock {
+		err := fmt.Errorf(errorCodesToMessagesMap[VelocityRuleViolationError][locale()])
+		return r.trackTransfer(err), err
+	}
+
+	if action != VelocityDelay && (r.ApprovalThreshold <= 0 || amount <= r.ApprovalThreshold) {
+		// Checking if the holder of the sender account has to confirm the transfer first
+		if r.transferNeedsConfirmation(sender, amount) {
+			return r.challengeTransfer(sender, recipient, amount, details)
+		}
+		err := r.transferMoney(sender, recipient, amount, details)
+		id := r.trackTransfer(err)
+		if err == nil && action == VelocityFlag {
+			r.VelocityEngine.flag(VelocityFlagRecord{id, sender, amount, rule, clock.Now().UTC()})
+		}
+		return id, err
+	}
+	// Checking what can be checked upfront, the rest is checked by the transfer itself once approved
+	details, ok := normalizeTransferDetails(details)
+	if !ok {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidTransferDetailsError][locale()])
+	}
+	if sAcc, exists := r.Accounts.Get(sender); !exists || sAcc == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if rAcc, exists := r.Accounts.Get(recipient); !exists || rAcc == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+
+	now := clock.Now().UTC()
+	approval := &Approval{ID: NewUlid(now), Sender: sender, Recipient: recipient, Amount: amount, Details: details, Status: ApprovalPending, CreatedAt: now}
+	r.Approvals[approval.ID] = approval
+	r.TransferStatuses[approval.ID] = TransferPending
+	return approval.ID, nil
+}
+
+// Helper function to keep the ID handed out to the initiator for the transfer executed last, along with its fees,
+// expects the repository mutex to be held by the caller
+// Fees are recorded right before the transfer they are charged on, see commitTransfer
+func (r *InMemoryAccountRepository) keepTransferID(id string) {
+	n := len(r.Transactions)
+	tx := r.Transactions[n-1]
+	for i := n - 2; i >= 0 && r.Transactions[i].FeeOf == tx.Ulid; i-- {
+		r.Transactions[i].FeeOf = id
+	}
+	tx.Ulid = id
+}
+
+// Helper function to find a pending approval and check the principal deciding on it, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) pendingApproval(id, principal string) (*Approval, error) {
+	approval, exists := r.Approvals[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || approval == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[ApprovalDoesNotExistError][locale()])
+	}
+	if approval.Status != ApprovalPending {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[ApprovalIsNotPendingError][locale()])
+	}
+	// Checking if both principals are known and the principal is not the initiator of the transfer
+	if principal == "" || approval.Details.Initiator == "" || strings.EqualFold(principal, approval.Details.Initiator) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[ApprovalPrincipalError][locale()])
+	}
+	return approval, nil
+}
+
+// If the transfer cannot be executed at the time of approval, the approval fails and has to be requested again
+func (r *InMemoryAccountRepository) ApproveTransfer(id, principal string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	principal = strings.TrimSpace(principal)
+	approval, err := r.pendingApproval(id, principal)
+	if err != nil {
+		return err
+	}
+	approval.Checker = principal
+	approval.DecidedAt = clock.Now().UTC()
+	if err := r.transferMoney(approval.Sender, approval.Recipient, approval.Amount, approval.Details); err != nil {
+		approval.Status = ApprovalFailed
+		approval.Reason = err.Error()
+		r.TransferStatuses[approval.ID] = TransferFailed
+		return err
+	}
+	r.keepTransferID(approval.ID)
+	approval.Status = ApprovalApproved
+	r.TransferStatuses[approval.ID] = TransferSettled
+	return nil
+}
+
+func (r *InMemoryAccountRepository) RejectTransfer(id, principal, reason string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	principal = strings.TrimSpace(principal)
+	approval, err := r.pendingApproval(id, principal)
+	if err != nil {
+		return err
+	}
+	approval.Checker = principal
+	approval.DecidedAt = clock.Now().UTC()
+	approval.Status = ApprovalRejected
+	approval.Reason = strings.TrimSpace(reason)
+	r.TransferStatuses[approval.ID] = TransferFailed
+	return nil
+}
+
+// Helper function to stamp the transfer with the principal of the service as its initiator, whatever the caller says
+func (s *AccountService) initiated(details TransferDetails) TransferDetails {
+	details.Initiator = s.principal.ID
+	return details
+}
+
+// Lists transfers awaiting approval, the oldest first
+func (r *InMemoryAccountRepository) ListPendingApprovals() ([]Approval, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	approvals := []Approval{}
+	for _, approval := range r.Approvals {
+		if approval.Status == ApprovalPending {
+			approvals = append(approvals, *approval)
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].CreatedAt.Before(approvals[j].CreatedAt) })
+	return approvals, nil
+}
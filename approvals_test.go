@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+)
+
+// Large transfers wait for approval by another principal
+func TestTransferApprovals(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(900)
+	recipient, _ := service.OpenAccount()
+	inMemImpl.ApprovalThreshold = 100
+	maker := service.As(Principal{ID: "maker", Role: TellerRole})
+	checker := service.As(Principal{ID: "checker", Role: TellerRole})
+
+	// Transfers up to the threshold are executed right away
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// The initiator is the principal submitting the transfer, not the one the details name
+	id, err := maker.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 500, TransferDetails{Initiator: "checker"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferPending {
+		t.Errorf("Expected transfer to be pending, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 100 {
+		t.Errorf("Expected pending transfer not to move money, recipient has %.2f", details.Balance)
+	}
+	rejected, _ := maker.TransferMoney(sender.Iban, recipient.Iban, 200)
+	if pending, _ := service.ListPendingApprovals(); len(pending) != 2 || pending[0].ID != id {
+		t.Errorf("Unexpected pending approvals: %+v", pending)
+	}
+
+	// The initiator cannot approve their own transfer
+	if inMemImpl.Approvals[id].Details.Initiator != "maker" || inMemImpl.Approvals[rejected].Details.Initiator != "maker" {
+		t.Errorf("Expected transfers to be initiated by maker, got %+v and %+v", inMemImpl.Approvals[id], inMemImpl.Approvals[rejected])
+	}
+	if err := service.As(Principal{ID: " Maker ", Role: TellerRole}).ApproveTransfer(id); err == nil {
+		t.Errorf("Approval by the initiator failed to fail")
+	}
+	if err := service.ApproveTransfer(id); err == nil {
+		t.Errorf("Approval without a principal failed to fail")
+	}
+	if err := checker.ApproveTransfer(id); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := checker.ApproveTransfer(id); err == nil {
+		t.Errorf("Approving a transfer twice failed to fail")
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferSettled {
+		t.Errorf("Expected approved transfer to be settled, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 600 {
+		t.Errorf("Expected recipient balance 600 after approval, got %.2f", details.Balance)
+	}
+
+	if err := checker.RejectTransfer(rejected, "Unknown beneficiary"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(rejected); status != TransferFailed {
+		t.Errorf("Expected rejected transfer to be failed, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+
+	// Approved transfer still has to pass the usual checks at the time of approval
+	tooLarge, err := maker.TransferMoneyJson(`{"sender":"` + sender.Iban + `","recipient":"` + recipient.Iban + `","amount":400,"initiator":"checker"}`)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := checker.ApproveTransfer(tooLarge); err == nil {
+		t.Errorf("Approving a transfer exceeding the balance failed to fail")
+	}
+	if approval := inMemImpl.Approvals[tooLarge]; approval.Status != ApprovalFailed || approval.Reason == "" {
+		t.Errorf("Expected approval to fail with a reason, got %+v", approval)
+	}
+	if pending, _ := service.ListPendingApprovals(); len(pending) != 0 {
+		t.Errorf("Expected no pending approvals, got %+v", pending)
+	}
+
+	// Transfers of the service not bound to any principal have no known initiator to differ from
+	unknown, _ := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 150, TransferDetails{Initiator: "maker"})
+	if err := checker.ApproveTransfer(unknown); err == nil {
+		t.Errorf("Approval of a transfer by an unknown initiator failed to fail")
+	}
+}
+
+// Approved transfer keeps the ID handed out to the initiator, both its fee and the acquiring fee of the merchant follow it
+func TestApprovedTransferFees(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.FeeSchedule = NewFeeSchedule()
+	inMemImpl.FeeSchedule.SetFee(TransferTransaction, FeeRule{Kind: FlatFee, Value: 1})
+	inMemImpl.FeeSchedule.SetFee(AcquiringFeeTransaction, FeeRule{Kind: PercentageFee, Value: 2})
+	service := NewAccountService(inMemImpl)
+	feeIncome, _ := service.OpenAccount()
+	service.SetFeeAccount(feeIncome.Iban)
+	sender, _ := service.OpenAccountWithInitialDeposit(900)
+	c, _ := service.CreateCustomer("Shop", "", "", "")
+	merchant, _ := service.OpenAccountWithOptions(AccountOptions{Product: MerchantProduct, CustomerID: c.ID})
+	inMemImpl.ApprovalThreshold = 100
+
+	id, _ := service.As(Principal{ID: "maker", Role: TellerRole}).TransferMoney(sender.Iban, merchant.Iban, 500)
+	if err := service.As(Principal{ID: "checker", Role: TellerRole}).ApproveTransfer(id); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	fees := 0
+	for _, tx := range inMemImpl.Transactions {
+		switch {
+		case tx.Ulid == id && tx.Type != TransferTransaction:
+			t.Errorf("Expected the ID to be kept by the transfer, got %+v", tx)
+		case tx.Type == FeeTransaction || tx.Type == AcquiringFeeTransaction:
+			if tx.FeeOf != id {
+				t.Errorf("Expected fee %+v to be linked to %s", tx, id)
+			}
+			fees++
+		}
+	}
+	if fees != 2 {
+		t.Errorf("Expected 2 fees, got %d", fees)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining the audit log: every opening, transfer, block, emission, destruction and adjustment requested through the service is recorded
+// with the principal of the service, failed operations and connections rejected by the IP filter are recorded as well,
+// entries are never modified or removed
+type AuditAction int8
+
+const (
+	OpenAction AuditAction = iota
+	TransferAction
+	BlockAction
+	ActivateAction
+	CloseAction
+	EmitAction
+	DestructAction
+	AdjustAction
+	ConnectAction // connection rejected by the IP filter, the address of the client is recorded as the counterparty
+	EraseAction   // erasure of personal data, the ID of the customer is recorded as the counterparty
+)
+
+// Mapping audit action codes to audit action names considering locale
+var auditActionCodeToNameMap map[AuditAction](map[LanguageCode]string) = map[AuditAction](map[LanguageCode]string){
+	OpenAction: {
+		English: "Open",
+		Russian: "Открытие",
+	},
+	TransferAction: {
+		English: "Transfer",
+		Russian: "Перевод",
+	},
+	BlockAction: {
+		English: "Block",
+		Russian: "Блокировка",
+	},
+	ActivateAction: {
+		English: "Activate",
+		Russian: "Активация",
+	},
+	CloseAction: {
+		English: "Close",
+		Russian: "Закрытие",
+	},
+	EmitAction: {
+		English: "Emit",
+		Russian: "Эмиссия",
+	},
+	DestructAction: {
+		English: "Destruct",
+		Russian: "Уничтожение",
+	},
+	AdjustAction: {
+		English: "Adjust",
+		Russian: "Корректировка",
+	},
+	ConnectAction: {
+		English: "Connect",
+		Russian: "Подключение",
+	},
+	EraseAction: {
+		English: "Erase",
+		Russian: "Удаление персональных данных",
+	},
+}
+
+type AuditEntry struct {
+	Sequence     uint64
+	Principal    string // empty if the service is not bound to a principal
+	Action       AuditAction
+	Iban         string // opened, blocked, debited or closed account
+	Counterparty string // recipient of the transfer or target of the sweep
+	Amount       float64
+	ID           string // ID of the transfer or the pending emission, if any
+	Error        string // empty if the operation succeeded
+	Timestamp    time.Time
+}
+
+// Zero values of the fields match any entry
+type AuditFilter struct {
+	Principal string
+	Actions   []AuditAction
+	Iban      string // matches both the account and the counterparty
+	From      time.Time
+	To        time.Time // exclusive
+}
+
+type AuditLog struct {
+	entries []AuditEntry
+	Mutex   sync.Mutex
+}
+
+func NewAuditLog() *AuditLog {
+	return &AuditLog{entries: []AuditEntry{}}
+}
+
+func (l *AuditLog) Append(entry AuditEntry) {
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+
+	entry.Sequence = uint64(len(l.entries) + 1)
+	entry.Timestamp = clock.Now().UTC()
+	l.entries = append(l.entries, entry)
+}
+
+// Returns matching entries in the order they were recorded
+func (l *AuditLog) Query(filter AuditFilter) []AuditEntry {
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+
+	iban := NormalizeIban(filter.Iban)
+	entries := []AuditEntry{}
+	for _, entry := range l.entries {
+		if filter.Principal != "" && !strings.EqualFold(entry.Principal, filter.Principal) {
+			continue
+		}
+		if iban != "" && entry.Iban != iban && entry.Counterparty != iban {
+			continue
+		}
+		if (!filter.From.IsZero() && entry.Timestamp.Before(filter.From)) || (!filter.To.IsZero() && !entry.Timestamp.Before(filter.To)) {
+			continue
+		}
+		if len(filter.Actions) > 0 && !containsAuditAction(filter.Actions, entry.Action) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func containsAuditAction(actions []AuditAction, action AuditAction) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the service acting on behalf of the principal, it shares the repositories and the audit log with the original service
+// Operations of the returned service are restricted by the role of the principal, see rbac.go
+func (s *AccountService) As(principal Principal) *AccountService {
+	bound := *s
+	principal.ID = strings.TrimSpace(principal.ID)
+	bound.principal = principal
+	return &bound
+}
+
+func (s *AccountService) QueryAuditLog(filter AuditFilter) ([]AuditEntry, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.auditLog.Query(filter), nil
+}
+
+// Helper functions to record the outcome of the operation and pass it through to the caller
+func (s *AccountService) audit(action AuditAction, iban, counterparty string, amount float64, id string, err error) {
+	entry := AuditEntry{Principal: s.principal.ID, Action: action, Iban: NormalizeIban(iban), Counterparty: NormalizeIban(counterparty), Amount: amount, ID: id}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	s.auditLog.Append(entry)
+	s.logAudited(entry, err)
+	s.traceAudited(entry, err)
+}
+
+func (s *AccountService) audited(action AuditAction, iban, counterparty string, amount float64, err error) error {
+	s.audit(action, iban, counterparty, amount, "", err)
+	return err
+}
+
+func (s *AccountService) auditedID(action AuditAction, iban, counterparty string, amount float64, id string, err error) (string, error) {
+	s.audit(action, iban, counterparty, amount, id, err)
+	return id, err
+}
+
+func (s *AccountService) auditedOpening(acc *Account, err error) (*Account, error) {
+	iban, amount := "", 0.0
+	if acc != nil {
+		iban, amount = acc.Iban, acc.Balance
+	}
+	s.audit(OpenAction, iban, "", amount, "", err)
+	return acc, err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Operations are recorded with the principal of the service, including failed ones
+func TestAuditLog(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	teller := service.As(Principal{ID: " teller ", Role: TellerRole})
+	admin := service.As(Principal{ID: "admin", Role: AdminRole})
+
+	acc, _ := teller.OpenAccountWithInitialDeposit(100)
+	other, _ := teller.OpenAccount()
+	if err := admin.EmitMoney(50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := teller.TransferMoneyWithDetails(acc.Iban, other.Iban, 30, TransferDetails{}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := teller.TransferMoney(acc.Iban, other.Iban, 1000); err == nil {
+		t.Fatalf("Transfer exceeding the balance failed to fail")
+	}
+	admin.BlockAccount(other.Iban)
+	admin.ActivateAccount(other.Iban)
+	admin.DestructMoney(acc.Iban, 10)
+	service.CloseAccount(other.Iban, acc.Iban)
+
+	entries, _ := service.QueryAuditLog(AuditFilter{})
+	expected := []struct {
+		principal string
+		action    AuditAction
+		failed    bool
+	}{
+		{"teller", OpenAction, false},
+		{"teller", OpenAction, false},
+		{"admin", EmitAction, false},
+		{"teller", TransferAction, false},
+		{"teller", TransferAction, true},
+		{"admin", BlockAction, false},
+		{"admin", ActivateAction, false},
+		{"admin", DestructAction, false},
+		{"", CloseAction, false},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, e := range expected {
+		if entry := entries[i]; entry.Principal != e.principal || entry.Action != e.action || (entry.Error != "") != e.failed || entry.Sequence != uint64(i+1) {
+			t.Errorf("Unexpected entry %d: %+v", i, entry)
+		}
+	}
+	if entries[0].Iban != acc.Iban || entries[0].Amount != 100 || entries[3].Counterparty != other.Iban || entries[3].ID == "" {
+		t.Errorf("Expected entries to name the accounts, amounts and transfers, got %+v", entries)
+	}
+
+	if byTeller, _ := service.QueryAuditLog(AuditFilter{Principal: "TELLER", Actions: []AuditAction{TransferAction}}); len(byTeller) != 2 {
+		t.Errorf("Expected 2 transfers by the teller, got %+v", byTeller)
+	}
+	if byAccount, _ := service.QueryAuditLog(AuditFilter{Iban: other.Iban}); len(byAccount) != 6 {
+		t.Errorf("Expected 6 entries naming the account, got %+v", byAccount)
+	}
+	if later, _ := service.QueryAuditLog(AuditFilter{From: time.Now().Add(time.Minute)}); len(later) != 0 {
+		t.Errorf("Expected no entries after now, got %+v", later)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --------------------------------------------------------
+// Defining address books of accounts: named beneficiaries let holders transfer money to an alias instead of a raw IBAN
+const maxBeneficiaryAliasLength = 35
+
+type Beneficiary struct {
+	Alias     string
+	Iban      string
+	CreatedAt time.Time
+}
+
+// Aliases are matched regardless of case and surrounding spaces
+func beneficiaryKey(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
+
+// Saves the beneficiary under the alias in the address book of the account, saving an existing alias replaces its IBAN
+// Aliases cannot be valid IBANs, otherwise transfers to them would be ambiguous
+func (r *InMemoryAccountRepository) SaveBeneficiary(iban, alias, beneficiaryIban string) (*Beneficiary, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	beneficiaryIban = NormalizeIban(beneficiaryIban)
+	alias = strings.TrimSpace(alias)
+	// Checking if both accounts exist
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if acc, exists := r.Accounts.Get(beneficiaryIban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the alias is printable, not too long and cannot be mistaken for an IBAN
+	valid := alias != "" && utf8.RuneCountInString(alias) <= maxBeneficiaryAliasLength && !IsValidIban(alias) && iban != beneficiaryIban
+	for _, c := range alias {
+		valid = valid && unicode.IsPrint(c)
+	}
+	if !valid {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidBeneficiaryError][locale()])
+	}
+
+	if r.Beneficiaries[iban] == nil {
+		r.Beneficiaries[iban] = map[string]*Beneficiary{}
+	}
+	beneficiary := &Beneficiary{Alias: alias, Iban: beneficiaryIban, CreatedAt: clock.Now().UTC()}
+	r.Beneficiaries[iban][beneficiaryKey(alias)] = beneficiary
+	copied := *beneficiary
+	return &copied, nil
+}
+
+// Lists beneficiaries of the account sorted by their aliases
+func (r *InMemoryAccountRepository) ListBeneficiaries(iban string) ([]Beneficiary, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	beneficiaries := []Beneficiary{}
+	for _, beneficiary := range r.Beneficiaries[iban] {
+		beneficiaries = append(beneficiaries, *beneficiary)
+	}
+	sort.Slice(beneficiaries, func(i, j int) bool {
+		return beneficiaryKey(beneficiaries[i].Alias) < beneficiaryKey(beneficiaries[j].Alias)
+	})
+	return beneficiaries, nil
+}
+
+func (r *InMemoryAccountRepository) DeleteBeneficiary(iban, alias string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if the beneficiary exists in the address book of the account
+	if _, exists := r.Beneficiaries[iban][beneficiaryKey(alias)]; !exists {
+		return fmt.Errorf(errorCodesToMessagesMap[BeneficiaryDoesNotExistError][locale()])
+	}
+	delete(r.Beneficiaries[iban], beneficiaryKey(alias))
+	return nil
+}
+
+// Helper function to resolve the recipient through the address book of the sender, expects the repository mutex to be held by the caller
+// Recipients which are not aliases of the sender beneficiaries are returned as they are
+func (r *InMemoryAccountRepository) resolveBeneficiary(sender, recipient string) string {
+	if beneficiary, exists := r.Beneficiaries[NormalizeIban(sender)][beneficiaryKey(recipient)]; exists {
+		return beneficiary.Iban
+	}
+	return recipient
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+// Save beneficiaries, transfer money to their aliases and delete them
+func TestBeneficiaries(t *testing.T) {
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	landlord, _ := service.OpenAccount()
+	friend, _ := service.OpenAccount()
+
+	if _, err := service.SaveBeneficiary(sender.Iban, "Myself", sender.Iban); err == nil {
+		t.Errorf("Saving the account itself as beneficiary failed to fail")
+	}
+	if _, err := service.SaveBeneficiary(sender.Iban, friend.Iban, landlord.Iban); err == nil {
+		t.Errorf("Saving IBAN as alias failed to fail")
+	}
+	if _, err := service.SaveBeneficiary(sender.Iban, "Landlord", "BY84 ALFA 1000 0000 0000 0000 9999"); err == nil {
+		t.Errorf("Saving nonexistent beneficiary failed to fail")
+	}
+	if _, err := service.SaveBeneficiary(sender.Iban, "Landlord", landlord.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Saving the same alias again replaces the IBAN
+	if _, err := service.SaveBeneficiary(sender.Iban, "Rent", friend.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.SaveBeneficiary(sender.Iban, " rent ", landlord.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	beneficiaries, err := service.ListBeneficiaries(sender.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(beneficiaries) != 2 || beneficiaries[0].Alias != "Landlord" || beneficiaries[1].Alias != "rent" || beneficiaries[1].Iban != landlord.Iban {
+		t.Errorf("Unexpected beneficiaries: %+v", beneficiaries)
+	}
+	if beneficiaries, _ := service.ListBeneficiaries(landlord.Iban); len(beneficiaries) != 0 {
+		t.Errorf("Expected empty address book of another account, got %+v", beneficiaries)
+	}
+
+	// Aliases are resolved through the address book of the sender only
+	if _, err := service.TransferMoney(sender.Iban, "RENT", 40); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(sender.Iban, friend.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(landlord.Iban, "Rent", 10); err == nil {
+		t.Errorf("Transfer to alias of another address book failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(landlord.Iban); details.Balance != 40 {
+		t.Errorf("Expected landlord balance of 40, got %v", details.Balance)
+	}
+
+	if err := service.DeleteBeneficiary(sender.Iban, "Rent"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.DeleteBeneficiary(sender.Iban, "Rent"); err == nil {
+		t.Errorf("Deleting nonexistent beneficiary failed to fail")
+	}
+	if _, err := service.TransferMoney(sender.Iban, "Rent", 10); err == nil {
+		t.Errorf("Transfer to deleted beneficiary failed to fail")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining reasons of account blocks and authorities allowed to lift them: blocks for fraud and by court order
+// can only be lifted by compliance and legal officers, blocks may expire automatically at the given time
+type BlockReason int8
+
+const (
+	UnspecifiedBlock BlockReason = iota
+	CustomerRequestBlock
+	FraudBlock
+	CourtOrderBlock
+)
+
+// Mapping block reason codes to block reason names considering locale
+var blockReasonCodeToNameMap map[BlockReason](map[LanguageCode]string) = map[BlockReason](map[LanguageCode]string){
+	UnspecifiedBlock: {
+		English: "Unspecified",
+		Russian: "Не указана",
+	},
+	CustomerRequestBlock: {
+		English: "Customer request",
+		Russian: "По заявлению клиента",
+	},
+	FraudBlock: {
+		English: "Fraud",
+		Russian: "Мошенничество",
+	},
+	CourtOrderBlock: {
+		English: "Court order",
+		Russian: "По решению суда",
+	},
+}
+
+type Authority int8
+
+const (
+	CustomerAuthority Authority = iota // holder of the account
+	OperatorAuthority
+	ComplianceAuthority
+	LegalAuthority
+)
+
+// Authorities allowed to lift blocks of every reason
+var blockLiftAuthorities map[BlockReason]map[Authority]bool = map[BlockReason]map[Authority]bool{
+	UnspecifiedBlock:     {OperatorAuthority: true, ComplianceAuthority: true, LegalAuthority: true},
+	CustomerRequestBlock: {CustomerAuthority: true, OperatorAuthority: true},
+	FraudBlock:           {ComplianceAuthority: true},
+	CourtOrderBlock:      {LegalAuthority: true},
+}
+
+// Blocking the account already blocked for the same reason replaces the expiry of the block
+func (r *InMemoryAccountRepository) BlockAccountWithReason(iban string, reason BlockReason, until time.Time) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the reason is known and the expiry is in the future
+	if _, ok := blockReasonCodeToNameMap[reason]; !ok || (!until.IsZero() && !until.After(clock.Now())) {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidBlockError][locale()])
+	}
+	return r.blockAccount(iban, reason, until.UTC())
+}
+
+func (r *InMemoryAccountRepository) ActivateAccountAs(iban string, authority Authority) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.activateAccount(iban, authority)
+}
+
+// Lifts blocks expired by the given time, returns the number of activated accounts
+func (r *InMemoryAccountRepository) ExpireBlocks(now time.Time) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	expired := 0
+	for _, acc := range r.Accounts.All() {
+		if acc.Status == Blocked && !acc.BlockedUntil.IsZero() && !now.Before(acc.BlockedUntil) {
+			acc.Activate()
+			r.Accounts.Put(acc)
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (s *TransferScheduler) RunBlockExpiry(now time.Time) int {
+	expired, _ := s.service.ExpireBlocks(now)
+	return expired
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Helper function to find the account in the listing of all accounts, blocked accounts are not returned by RetrieveAccount
+func findListedAccount(t *testing.T, service *AccountService, iban string) AccountDetails {
+	output, err := service.RetrieveAllAccountsAsJson()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	accounts := []AccountDetails{}
+	if err := json.Unmarshal([]byte(output), &accounts); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for _, details := range accounts {
+		if details.Iban == iban {
+			return details
+		}
+	}
+	t.Fatalf("Account %s is not listed", iban)
+	return AccountDetails{}
+}
+
+// Blocks can only be lifted by authorities allowed for their reason, expired blocks are lifted by the scheduler
+func TestBlockReasons(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccount()
+
+	if err := service.BlockAccountWithReason(acc.Iban, BlockReason(42), time.Time{}); err == nil {
+		t.Errorf("Block with unknown reason failed to fail")
+	}
+	if err := service.BlockAccountWithReason(acc.Iban, FraudBlock, time.Now().Add(-time.Hour)); err == nil {
+		t.Errorf("Block expiring in the past failed to fail")
+	}
+	if err := service.BlockAccountWithReason(acc.Iban, FraudBlock, time.Time{}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.BlockAccountWithReason(acc.Iban, CustomerRequestBlock, time.Time{}); err == nil {
+		t.Errorf("Replacing a fraud block with a customer request block failed to fail")
+	}
+	if details := findListedAccount(t, service, acc.Iban); details.BlockReason != blockReasonCodeToNameMap[FraudBlock][locale()] || details.BlockedUntil != nil {
+		t.Errorf("Expected listing to show the reason of the block, got %+v", details)
+	}
+	if err := service.ActivateAccount(acc.Iban); err == nil {
+		t.Errorf("Lifting a fraud block by an operator failed to fail")
+	}
+	if err := service.ActivateAccountAs(acc.Iban, LegalAuthority); err == nil {
+		t.Errorf("Lifting a fraud block by a legal officer failed to fail")
+	}
+	if err := service.ActivateAccountAs(acc.Iban, ComplianceAuthority); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Status != accountStatusCodeToNameMap[Active][locale()] || details.BlockReason != "" {
+		t.Errorf("Expected account to be active without a block reason, got %+v", details)
+	}
+
+	// Customer request blocks expire on their own
+	until := time.Now().Add(time.Hour)
+	if err := service.BlockAccountWithReason(acc.Iban, CustomerRequestBlock, until); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details := findListedAccount(t, service, acc.Iban); details.BlockedUntil == nil || !details.BlockedUntil.Equal(until) {
+		t.Errorf("Expected block to expire at %v, got %+v", until, details.BlockedUntil)
+	}
+	scheduler := NewTransferScheduler(service)
+	if expired := scheduler.RunBlockExpiry(time.Now()); expired != 0 {
+		t.Errorf("Expected no blocks to expire yet, got %d", expired)
+	}
+	if expired := scheduler.RunBlockExpiry(until); expired != 1 {
+		t.Errorf("Expected the block to expire, got %d", expired)
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Status != accountStatusCodeToNameMap[Active][locale()] {
+		t.Errorf("Expected account to be active after the block expired, got %s", details.Status)
+	}
+
+	// Court orders are only lifted by legal officers
+	service.BlockAccountWithReason(acc.Iban, CourtOrderBlock, time.Time{})
+	if err := service.ActivateAccountAs(acc.Iban, ComplianceAuthority); err == nil {
+		t.Errorf("Lifting a court order block by a compliance officer failed to fail")
+	}
+	if err := service.ActivateAccountAs(acc.Iban, LegalAuthority); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining payment cards issued to accounts: the card debits the linked account but is blocked and activated on its own,
+// i.e. the lost card is blocked while the account keeps working with other cards and transfers
+// The full card number is only generated to derive the masked one and is never stored
+type CardStatus int8
+
+const (
+	CardActive CardStatus = iota
+	CardBlocked
+)
+
+// Mapping card status codes to card status names considering locale
+var cardStatusCodeToNameMap map[CardStatus](map[LanguageCode]string) = map[CardStatus](map[LanguageCode]string){
+	CardActive: {
+		English: "Active",
+		Russian: "Активна",
+	},
+	CardBlocked: {
+		English: "Blocked",
+		Russian: "Заблокирована",
+	},
+}
+
+// Issuer identification number the card numbers of the bank start with and the years new cards are valid for
+const (
+	cardIssuerBin      = "427683"
+	cardNumberLength   = 16
+	cardValidityPeriod = 3
+)
+
+type Card struct {
+	ID        string
+	Iban      string
+	MaskedPan string    // first 6 and last 4 digits of the card number, i.e. 427683******1234
+	ExpiresAt time.Time // end of the month printed on the card, the card is valid until then
+	Status    CardStatus
+	CreatedAt time.Time
+}
+
+// Returns the expiry date as printed on the card, i.e. 03/27
+func (c Card) Expiry() string {
+	return c.ExpiresAt.Add(-time.Nanosecond).Format("01/06")
+}
+
+func (c Card) IsExpired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// Calculates the Luhn check digit of the card number without it
+func CalculateLuhnCheckDigit(number string) byte {
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		// Doubling every second digit starting from the rightmost one, the check digit is appended to the right of it
+		if (len(number)-i)%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return byte((10-sum%10)%10) + '0'
+}
+
+// Generates the card number of the bank with the valid Luhn check digit
+func GenerateCardNumber() (string, error) {
+	digits, err := GenerateSecureRandomDigits(cardNumberLength - len(cardIssuerBin) - 1)
+	if err != nil {
+		return "", err
+	}
+	number := cardIssuerBin + digits
+	return number + string(CalculateLuhnCheckDigit(number)), nil
+}
+
+func MaskCardNumber(number string) string {
+	return number[:6] + strings.Repeat("*", len(number)-10) + number[len(number)-4:]
+}
+
+// Cards can be issued to ordinary accounts which are not closed, blocked accounts get cards usable once they are activated
+func (r *InMemoryAccountRepository) IssueCard(iban string) (*Card, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is not closed
+	if acc.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the account is not a system account or a term deposit which are never debited by cards
+	if acc.Type != Ordinary {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CardNotAllowedError][locale()])
+	}
+
+	number, err := GenerateCardNumber()
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CardIssuanceError][locale()])
+	}
+	now := clock.Now().UTC()
+	// Cards expire at the end of the month of the last year of validity
+	expiresAt := time.Date(now.Year()+cardValidityPeriod, now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	card := &Card{ID: NewUlid(now), Iban: iban, MaskedPan: MaskCardNumber(number), ExpiresAt: expiresAt, Status: CardActive, CreatedAt: now}
+	r.Cards[card.ID] = card
+	copied := *card
+	return &copied, nil
+}
+
+// Helper function to find a card, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) findCard(cardID string) (*Card, error) {
+	card, exists := r.Cards[strings.ToUpper(strings.TrimSpace(cardID))]
+	if !exists || card == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CardDoesNotExistError][locale()])
+	}
+	return card, nil
+}
+
+// Helper function to find a card which can be used for debits, expects the repository mutex to be held by the caller
+// The status of the linked account is checked by the debit itself
+func (r *InMemoryAccountRepository) usableCard(cardID string) (*Card, error) {
+	card, err := r.findCard(cardID)
+	if err != nil {
+		return nil, err
+	}
+	// Checking if the card is neither blocked nor expired
+	if card.Status == CardBlocked {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CardIsBlockedError][locale()])
+	}
+	if card.IsExpired(clock.Now().UTC()) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CardIsExpiredError][locale()])
+	}
+	return card, nil
+}
+
+func (r *InMemoryAccountRepository) BlockCard(cardID string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	card, err := r.findCard(cardID)
+	if err != nil {
+		return err
+	}
+	card.Status = CardBlocked
+	return nil
+}
+
+// Expired cards cannot be activated, a new card has to be issued instead
+func (r *InMemoryAccountRepository) ActivateCard(cardID string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	card, err := r.findCard(cardID)
+	if err != nil {
+		return err
+	}
+	if card.IsExpired(clock.Now().UTC()) {
+		return fmt.Errorf(errorCodesToMessagesMap[CardIsExpiredError][locale()])
+	}
+	card.Status = CardActive
+	return nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveCard(cardID string) (Card, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	card, err := r.findCard(cardID)
+	if err != nil {
+		return Card{}, err
+	}
+	return *card, nil
+}
+
+// Returns the cards of the account in the order they were issued
+func (r *InMemoryAccountRepository) ListCards(iban string) ([]Card, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	cards := []Card{}
+	for _, card := range r.Cards {
+		if card.Iban == iban {
+			cards = append(cards, *card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	return cards, nil
+}
+
+// Debits the account of the card in favor of the recipient, i.e. the merchant, the card is the reference of the transfer
+// The debit is subject to the same checks as transfers from the account in addition to the status of the card
+func (r *InMemoryAccountRepository) DebitCard(cardID, recipient string, amount float64) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	card, err := r.usableCard(cardID)
+	if err != nil {
+		return "", err
+	}
+	err = r.transferMoney(card.Iban, recipient, amount, TransferDetails{Reference: card.ID})
+	return r.trackTransfer(err), err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Issue cards, debit the account by them and block them without blocking the account
+func TestCards(t *testing.T) {
+	fake := NewFakeClock(time.Date(2024, time.December, 15, 12, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	merchant, _ := service.OpenAccount()
+
+	card, err := service.IssueCard(acc.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(card.MaskedPan) != 16 || card.MaskedPan[:6] != cardIssuerBin || card.MaskedPan[6:12] != "******" {
+		t.Errorf("Unexpected masked card number %s", card.MaskedPan)
+	}
+	if card.Expiry() != "12/27" || card.Status != CardActive {
+		t.Errorf("Unexpected card %+v expiring %s", card, card.Expiry())
+	}
+	if _, err := service.IssueCard(emission); err == nil {
+		t.Errorf("Issuing card to system account failed to fail")
+	}
+	second, _ := service.IssueCard(acc.Iban)
+
+	id, err := service.DebitCard(card.ID, merchant.Iban, 30)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferSettled {
+		t.Errorf("Expected card debit to be settled")
+	}
+	if details, _ := service.RetrieveAccount(merchant.Iban); details.Balance != 30 {
+		t.Errorf("Expected merchant to be credited, got %v", details.Balance)
+	}
+	if _, err := service.DebitCard(card.ID, merchant.Iban, 100); err == nil {
+		t.Errorf("Card debit exceeding the balance failed to fail")
+	}
+
+	// Blocked card cannot be used while the account and other cards keep working
+	if err := service.BlockCard(card.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.DebitCard(card.ID, merchant.Iban, 10); err == nil {
+		t.Errorf("Debit by blocked card failed to fail")
+	}
+	if _, err := service.DebitCard(second.ID, merchant.Iban, 10); err != nil {
+		t.Errorf("Expected other card to work, got %v", err)
+	}
+	if _, err := service.TransferMoney(acc.Iban, merchant.Iban, 10); err != nil {
+		t.Errorf("Expected account to work with blocked card, got %v", err)
+	}
+	if err := service.ActivateCard(card.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.DebitCard(card.ID, merchant.Iban, 10); err != nil {
+		t.Errorf("Expected activated card to work, got %v", err)
+	}
+
+	// Blocked account cannot be debited by active cards
+	service.BlockAccount(acc.Iban)
+	if _, err := service.DebitCard(card.ID, merchant.Iban, 10); err == nil {
+		t.Errorf("Card debit from blocked account failed to fail")
+	}
+	service.ActivateAccount(acc.Iban)
+
+	// Expired card cannot be used nor activated
+	fake.Set(time.Date(2028, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if _, err := service.DebitCard(card.ID, merchant.Iban, 10); err == nil {
+		t.Errorf("Debit by expired card failed to fail")
+	}
+	if err := service.ActivateCard(card.ID); err == nil {
+		t.Errorf("Activating expired card failed to fail")
+	}
+
+	cards, err := service.ListCards(acc.Iban)
+	if err != nil || len(cards) != 2 {
+		t.Errorf("Expected 2 cards of the account, got %d and %v", len(cards), err)
+	}
+	if _, err := service.RetrieveCard("unknown"); err == nil {
+		t.Errorf("Retrieving unknown card failed to fail")
+	}
+}
+
+// Calculate check digits of known card numbers
+func TestLuhnCheckDigit(t *testing.T) {
+	for number, digit := range map[string]byte{"7992739871": '3', "453201511283036": '6', "400000000000000": '2'} {
+		if got := CalculateLuhnCheckDigit(number); got != digit {
+			t.Errorf("Expected check digit %c of %s, got %c", digit, number, got)
+		}
+	}
+	number, err := GenerateCardNumber()
+	if err != nil || len(number) != cardNumberLength || CalculateLuhnCheckDigit(number[:len(number)-1]) != number[len(number)-1] {
+		t.Errorf("Expected valid card number, got %s and %v", number, err)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining cash operations at ATMs and tills: withdrawals move the money from the account to the vault system account
+// and deposits move it back, so the balance of the vault is the money the bank has paid out in cash and not taken back yet
+// Cash operations are limited by cash limits of the repository instead of transfer limits of the accounts and are not
+// charged with transfer fees
+type CashLimits struct {
+	PerWithdrawal   float64 // maximum amount of a single withdrawal
+	DailyWithdrawal float64 // maximum sum of withdrawals from an account per calendar day (UTC)
+	PerDeposit      float64 // maximum amount of a single deposit
+}
+
+// Helper function to get the sum of cash withdrawals made on the day of the given time
+func (acc *Account) cashWithdrawnOn(t time.Time) float64 {
+	if acc.DailyCashWithdrawalDate != t.Format(time.DateOnly) {
+		return 0
+	}
+	return acc.DailyCashWithdrawn
+}
+
+// Helper function to find the vault and the account cash is withdrawn from or deposited to, expects the repository mutex
+// to be held by the caller
+func (r *InMemoryAccountRepository) cashAccounts(iban string) (*Account, *Account, error) {
+	iban = NormalizeIban(iban)
+
+	// Checking if the vault is declared in the chart of accounts
+	vault, exists := r.SystemAccounts[VaultRole]
+	if !exists || vault == nil {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[CashVaultNotDeclaredError][locale()])
+	}
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is neither blocked nor closed
+	if acc.Status == Blocked {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	if acc.Status == Closed {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the account is an ordinary one other than the vault and holds money in the currency of the vault
+	if acc.Type != Ordinary || acc == vault {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	if acc.Currency != vault.Currency {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+	return acc, vault, nil
+}
+
+// Pays out the amount in cash from the account, returns the ID of the transaction
+func (r *InMemoryAccountRepository) CashWithdraw(iban string, amount float64) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	acc, vault, err := r.cashAccounts(iban)
+	if err != nil {
+		return "", err
+	}
+	// Checking if money amount to withdraw is not negative
+	if amount < 0 {
+		return "", fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if debits from the account are not frozen
+	if err := checkDebitRestriction(acc); err != nil {
+		return "", err
+	}
+	// Checking if KYC status of the account allows the debit
+	if err := checkKycDebit(acc, amount); err != nil {
+		return "", err
+	}
+	amount = roundToCurrency(amount, acc.Currency)
+	// Checking if the withdrawal fits into the cash limits
+	now := clock.Now().UTC()
+	if limits := r.CashLimits; (limits.PerWithdrawal > 0 && amount > limits.PerWithdrawal) ||
+		(limits.DailyWithdrawal > 0 && roundToCurrency(acc.cashWithdrawnOn(now)+amount, acc.Currency) > limits.DailyWithdrawal) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[CashLimitExceededError][locale()])
+	}
+	// Checking if the account has sufficient available balance and the balance stays at or above the floor
+	if acc.AvailableBalance() < amount {
+		return "", insufficientFundsError(acc)
+	}
+	if err := checkBalanceFloor(acc, amount); err != nil {
+		return "", err
+	}
+
+	tx := &Transaction{Type: CashWithdrawalTransaction, Sender: acc.Iban, Recipient: vault.Iban, Amount: amount, Currency: acc.Currency}
+	if err := r.post(tx, transferLines(acc, vault, amount)...); err != nil {
+		return "", err
+	}
+	acc.DailyCashWithdrawn = roundToCurrency(acc.cashWithdrawnOn(now)+amount, acc.Currency)
+	acc.DailyCashWithdrawalDate = now.Format(time.DateOnly)
+	return tx.Ulid, nil
+}
+
+// Credits the account with the amount deposited in cash, returns the ID of the transaction
+// The vault has to hold the amount, cash paid out by other banks is brought into the vault by emission
+func (r *InMemoryAccountRepository) CashDeposit(iban string, amount float64) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	acc, vault, err := r.cashAccounts(iban)
+	if err != nil {
+		return "", err
+	}
+	// Checking if money amount to deposit is not negative
+	if amount < 0 {
+		return "", fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if credits to the account are not frozen
+	if err := checkCreditRestriction(acc); err != nil {
+		return "", err
+	}
+	amount = roundToCurrency(amount, acc.Currency)
+	// Checking if the deposit fits into the cash limits
+	if r.CashLimits.PerDeposit > 0 && amount > r.CashLimits.PerDeposit {
+		return "", fmt.Errorf(errorCodesToMessagesMap[CashLimitExceededError][locale()])
+	}
+	// Checking if the vault holds the amount and the balance of the account stays at or below the ceiling
+	if vault.AvailableBalance() < amount {
+		return "", fmt.Errorf(errorCodesToMessagesMap[CashVaultShortageError][locale()])
+	}
+	if err := checkBalanceCeiling(acc, amount); err != nil {
+		return "", err
+	}
+
+	tx := &Transaction{Type: CashDepositTransaction, Sender: vault.Iban, Recipient: acc.Iban, Amount: amount, Currency: acc.Currency}
+	if err := r.post(tx, transferLines(vault, acc, amount)...); err != nil {
+		return "", err
+	}
+	return tx.Ulid, nil
+}
+
+func (r *InMemoryAccountRepository) SetCashLimits(limits CashLimits) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the limits are not negative
+	if limits.PerWithdrawal < 0 || limits.DailyWithdrawal < 0 || limits.PerDeposit < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	r.CashLimits = limits
+	return nil
+}
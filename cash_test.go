@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Withdraw cash to the vault and deposit it back within the cash limits
+func TestCashOperations(t *testing.T) {
+	fake := NewFakeClock(time.Date(2024, time.May, 10, 18, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+
+	chart := DefaultChartOfAccounts()
+	inMemImpl, err := NewInMemoryAccountRepositoryWithChart(chart)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service := NewAccountService(inMemImpl)
+	vault := NormalizeIban(chart[VaultRole])
+	acc, _ := service.OpenAccountWithInitialDeposit(1000)
+	service.SetAccountLimits(acc.Iban, AccountLimits{PerTransaction: 50})
+	if err := service.SetCashLimits(CashLimits{PerWithdrawal: 300, DailyWithdrawal: 500, PerDeposit: 400}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Cash limits apply instead of the transfer limits of the account
+	id, err := service.CashWithdraw(acc.Iban, 300)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.CashWithdraw(acc.Iban, 300.01); err == nil {
+		t.Errorf("Withdrawing more than the limit of a withdrawal failed to fail")
+	}
+	if _, err := service.CashWithdraw(acc.Iban, 200); err != nil {
+		t.Errorf("Expected withdrawal within the daily limit to succeed, got %v", err)
+	}
+	if _, err := service.CashWithdraw(acc.Iban, 1); err == nil {
+		t.Errorf("Exceeding the daily cash limit failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(vault); details.Balance != 500 {
+		t.Errorf("Expected the vault to hold the withdrawn cash, got %v", details.Balance)
+	}
+	transactions, _ := service.RetrieveAccountTransactions(acc.Iban, 2)
+	if len(transactions) != 2 || transactions[0].Type != CashWithdrawalTransaction || transactions[1].Ulid != id {
+		t.Errorf("Unexpected transactions %+v", transactions)
+	}
+
+	if _, err := service.CashDeposit(acc.Iban, 450); err == nil {
+		t.Errorf("Depositing more than the limit of a deposit failed to fail")
+	}
+	if _, err := service.CashDeposit(acc.Iban, 400); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.CashDeposit(acc.Iban, 150); err == nil {
+		t.Errorf("Depositing more than the vault holds failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Balance != 900 {
+		t.Errorf("Expected balance 900 after the cash operations, got %v", details.Balance)
+	}
+
+	// The daily cash limit starts over the next day
+	fake.Advance(6 * time.Hour)
+	if _, err := service.CashWithdraw(acc.Iban, 100); err != nil {
+		t.Errorf("Expected the daily cash limit to be reset, got %v", err)
+	}
+	service.BlockAccount(acc.Iban)
+	if _, err := service.CashWithdraw(acc.Iban, 10); err == nil {
+		t.Errorf("Withdrawing from blocked account failed to fail")
+	}
+	if _, err := service.CashDeposit(vault, 10); err == nil {
+		t.Errorf("Depositing cash to the vault failed to fail")
+	}
+	if err := service.SetCashLimits(CashLimits{PerDeposit: -1}); err == nil {
+		t.Errorf("Setting negative cash limit failed to fail")
+	}
+	if _, err := service.VerifyInvariants(); err != nil {
+		t.Errorf("Expected invariants to hold after the cash operations, got %v", err)
+	}
+
+	// Repositories without the vault cannot operate cash
+	other := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	otherAcc, _ := other.OpenAccountWithInitialDeposit(100)
+	if _, err := other.CashWithdraw(otherAcc.Iban, 10); err == nil {
+		t.Errorf("Withdrawing without the vault failed to fail")
+	}
+}
+
+// Load the cash limits from the flags along with the other reloadable settings
+func TestCashLimitsConfig(t *testing.T) {
+	config, err := LoadConfig([]string{"-cash-per-withdrawal", "300", "-cash-daily-withdrawal", "1000", "-cash-per-deposit", "5000"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if config.CashLimits != (CashLimits{PerWithdrawal: 300, DailyWithdrawal: 1000, PerDeposit: 5000}) {
+		t.Errorf("Unexpected cash limits %+v", config.CashLimits)
+	}
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	if err := config.ApplyTo(inMemImpl); err != nil || inMemImpl.CashLimits != config.CashLimits {
+		t.Errorf("Expected the cash limits to be applied, got %+v and %v", inMemImpl.CashLimits, err)
+	}
+	if _, err := LoadConfig([]string{"-cash-per-deposit", "-5"}); err == nil {
+		t.Errorf("Loading config with negative cash limit failed to fail")
+	}
+}
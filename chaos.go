@@ -0,0 +1,1187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining fault injection into repository calls to test how the service, the queues and the schedulers behave when the
+// backend is slow or failing: calls are delayed, fail before reaching the repository or fail after the repository has
+// executed them, the latter being the lost response which leaves the caller unaware that the operation succeeded
+// Injected errors do not come from the error map, so that the breaker and the retries take them for failures of the
+// backend
+type FaultConfig struct {
+	Latency            time.Duration // delay added to every call
+	Jitter             time.Duration // random delay of up to the jitter added on top of the latency
+	ErrorRate          float64       // share of calls failing without reaching the repository, from 0 to 1
+	PartialFailureRate float64       // share of calls failing after the repository has executed them, from 0 to 1
+	Operations         []string      // names of the methods faults are injected into, i.e. "TransferMoney", all if empty
+}
+
+// Checks if any fault is configured
+func (c FaultConfig) Enabled() bool {
+	return c.Latency > 0 || c.Jitter > 0 || c.ErrorRate > 0 || c.PartialFailureRate > 0
+}
+
+type FaultInjectingAccountRepository struct {
+	AccountRepository
+	config   FaultConfig
+	random   *rand.Rand
+	sleep    func(time.Duration)
+	Injected map[string]uint64 // faults injected per kind, "latency", "error" and "partial"
+	Mutex    sync.Mutex
+}
+
+func NewFaultInjectingAccountRepository(r AccountRepository, config FaultConfig) *FaultInjectingAccountRepository {
+	return &FaultInjectingAccountRepository{AccountRepository: r, config: config, random: rand.New(rand.NewSource(random.Int63())),
+		sleep: time.Sleep, Injected: map[string]uint64{}}
+}
+
+// Replaces the configuration, i.e. to stop injecting faults in the middle of the test
+func (r *FaultInjectingAccountRepository) Configure(config FaultConfig) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	r.config = config
+}
+
+// Helper function to call the repository with the faults of the configuration injected
+func (r *FaultInjectingAccountRepository) inject(operation string, call func() error) error {
+	r.Mutex.Lock()
+	config := r.config
+	targeted := len(config.Operations) == 0
+	for _, name := range config.Operations {
+		if strings.EqualFold(strings.TrimSpace(name), operation) {
+			targeted = true
+		}
+	}
+	if !targeted {
+		r.Mutex.Unlock()
+		return call()
+	}
+	delay := config.Latency
+	if config.Jitter > 0 {
+		delay += time.Duration(r.random.Int63n(int64(config.Jitter) + 1))
+	}
+	failBefore := r.random.Float64() < config.ErrorRate
+	failAfter := !failBefore && r.random.Float64() < config.PartialFailureRate
+	if delay > 0 {
+		r.Injected["latency"]++
+	}
+	if failBefore {
+		r.Injected["error"]++
+	}
+	r.Mutex.Unlock()
+
+	if delay > 0 {
+		r.sleep(delay)
+	}
+	if failBefore {
+		return fmt.Errorf("injected fault: %s failed before reaching the repository", operation)
+	}
+	err := call()
+	if failAfter && err == nil {
+		r.Mutex.Lock()
+		r.Injected["partial"]++
+		r.Mutex.Unlock()
+		return fmt.Errorf("injected fault: %s succeeded but the response was lost", operation)
+	}
+	return err
+}
+
+// Faulty methods of the account repository
+func (r *FaultInjectingAccountRepository) RetrieveEmissionAccountIban() (string, error) {
+	var result string
+	err := r.inject("RetrieveEmissionAccountIban", func() (err error) {
+		result, err = r.AccountRepository.RetrieveEmissionAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveDestructionAccountIban() (string, error) {
+	var result string
+	err := r.inject("RetrieveDestructionAccountIban", func() (err error) {
+		result, err = r.AccountRepository.RetrieveDestructionAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) EmitMoney(amount float64) error {
+	return r.inject("EmitMoney", func() error { return r.AccountRepository.EmitMoney(amount) })
+}
+
+func (r *FaultInjectingAccountRepository) DestructMoney(iban string, amount float64) error {
+	return r.inject("DestructMoney", func() error { return r.AccountRepository.DestructMoney(iban, amount) })
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccount() (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccount", func() (err error) {
+		result, err = r.AccountRepository.OpenAccount()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	var result string
+	err := r.inject("TransferMoney", func() (err error) {
+		result, err = r.AccountRepository.TransferMoney(sender, recipient, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	var result string
+	err := r.inject("TransferMoneyWithDetails", func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyWithDetails(sender, recipient, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) GetTransferStatus(id string) (TransferStatus, error) {
+	var result TransferStatus
+	err := r.inject("GetTransferStatus", func() (err error) {
+		result, err = r.AccountRepository.GetTransferStatus(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveTransferParties(id string) (string, string, error) {
+	var result string
+	var second string
+	err := r.inject("RetrieveTransferParties", func() (err error) {
+		result, second, err = r.AccountRepository.RetrieveTransferParties(id)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *FaultInjectingAccountRepository) ReverseTransfer(id, reason string) (string, error) {
+	var result string
+	err := r.inject("ReverseTransfer", func() (err error) {
+		result, err = r.AccountRepository.ReverseTransfer(id, reason)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ApproveTransfer(id, principal string) error {
+	return r.inject("ApproveTransfer", func() error { return r.AccountRepository.ApproveTransfer(id, principal) })
+}
+
+func (r *FaultInjectingAccountRepository) RejectTransfer(id, principal, reason string) error {
+	return r.inject("RejectTransfer", func() error { return r.AccountRepository.RejectTransfer(id, principal, reason) })
+}
+
+func (r *FaultInjectingAccountRepository) ListPendingApprovals() ([]Approval, error) {
+	var result []Approval
+	err := r.inject("ListPendingApprovals", func() (err error) {
+		result, err = r.AccountRepository.ListPendingApprovals()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SetAccountLimits(iban string, limits AccountLimits) error {
+	return r.inject("SetAccountLimits", func() error { return r.AccountRepository.SetAccountLimits(iban, limits) })
+}
+
+func (r *FaultInjectingAccountRepository) SetOverdraftLimit(iban string, limit float64) error {
+	return r.inject("SetOverdraftLimit", func() error { return r.AccountRepository.SetOverdraftLimit(iban, limit) })
+}
+
+func (r *FaultInjectingAccountRepository) UpdateAccountSettings(iban string, settings AccountSettings) error {
+	return r.inject("UpdateAccountSettings", func() error { return r.AccountRepository.UpdateAccountSettings(iban, settings) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAccountSettings(iban string) (AccountSettings, error) {
+	var result AccountSettings
+	err := r.inject("RetrieveAccountSettings", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountSettings(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SetFeeAccount(iban string) error {
+	return r.inject("SetFeeAccount", func() error { return r.AccountRepository.SetFeeAccount(iban) })
+}
+
+func (r *FaultInjectingAccountRepository) SetInterestRate(product ProductType, rate float64) error {
+	return r.inject("SetInterestRate", func() error { return r.AccountRepository.SetInterestRate(product, rate) })
+}
+
+func (r *FaultInjectingAccountRepository) AccrueInterest(date time.Time) (int, error) {
+	var result int
+	err := r.inject("AccrueInterest", func() (err error) {
+		result, err = r.AccountRepository.AccrueInterest(date)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) PostInterest(until time.Time) ([]InterestPosting, error) {
+	var result []InterestPosting
+	err := r.inject("PostInterest", func() (err error) {
+		result, err = r.AccountRepository.PostInterest(until)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListInterestAccruals(iban string) ([]InterestAccrual, error) {
+	var result []InterestAccrual
+	err := r.inject("ListInterestAccruals", func() (err error) {
+		result, err = r.AccountRepository.ListInterestAccruals(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListInterestPostings(iban string) ([]InterestPosting, error) {
+	var result []InterestPosting
+	err := r.inject("ListInterestPostings", func() (err error) {
+		result, err = r.AccountRepository.ListInterestPostings(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenLoan(linkedIban string, principal, annualRate float64, months int, firstDue time.Time) (*Loan, error) {
+	var result *Loan
+	err := r.inject("OpenLoan", func() (err error) {
+		result, err = r.AccountRepository.OpenLoan(linkedIban, principal, annualRate, months, firstDue)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveLoan(iban string) (Loan, error) {
+	var result Loan
+	err := r.inject("RetrieveLoan", func() (err error) {
+		result, err = r.AccountRepository.RetrieveLoan(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) CollectDueInstallments(now time.Time) (int, error) {
+	var result int
+	err := r.inject("CollectDueInstallments", func() (err error) {
+		result, err = r.AccountRepository.CollectDueInstallments(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenTermDeposit(linkedIban string, amount, annualRate, penaltyRate float64, maturesAt time.Time) (*TermDeposit, error) {
+	var result *TermDeposit
+	err := r.inject("OpenTermDeposit", func() (err error) {
+		result, err = r.AccountRepository.OpenTermDeposit(linkedIban, amount, annualRate, penaltyRate, maturesAt)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveTermDeposit(iban string) (TermDeposit, error) {
+	var result TermDeposit
+	err := r.inject("RetrieveTermDeposit", func() (err error) {
+		result, err = r.AccountRepository.RetrieveTermDeposit(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) MatureTermDeposits(now time.Time) (int, error) {
+	var result int
+	err := r.inject("MatureTermDeposits", func() (err error) {
+		result, err = r.AccountRepository.MatureTermDeposits(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) WithdrawTermDeposit(iban string) (float64, error) {
+	var result float64
+	err := r.inject("WithdrawTermDeposit", func() (err error) {
+		result, err = r.AccountRepository.WithdrawTermDeposit(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) EmitMoneyIdempotent(key string, amount float64) error {
+	return r.inject("EmitMoneyIdempotent", func() error { return r.AccountRepository.EmitMoneyIdempotent(key, amount) })
+}
+
+func (r *FaultInjectingAccountRepository) DestructMoneyIdempotent(key, iban string, amount float64) error {
+	return r.inject("DestructMoneyIdempotent", func() error { return r.AccountRepository.DestructMoneyIdempotent(key, iban, amount) })
+}
+
+func (r *FaultInjectingAccountRepository) TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	var result string
+	err := r.inject("TransferMoneyIdempotent", func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyIdempotent(key, sender, recipient, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SplitTransfer(sender string, amount float64, shares []SplitShare) ([]string, error) {
+	var result []string
+	err := r.inject("SplitTransfer", func() (err error) {
+		result, err = r.AccountRepository.SplitTransfer(sender, amount, shares)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) HoldFunds(iban string, amount float64) (*Hold, error) {
+	var result *Hold
+	err := r.inject("HoldFunds", func() (err error) {
+		result, err = r.AccountRepository.HoldFunds(iban, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) CaptureHold(holdID, recipient string, amount float64) (string, error) {
+	var result string
+	err := r.inject("CaptureHold", func() (err error) {
+		result, err = r.AccountRepository.CaptureHold(holdID, recipient, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ReleaseHold(holdID string) error {
+	return r.inject("ReleaseHold", func() error { return r.AccountRepository.ReleaseHold(holdID) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveHold(holdID string) (Hold, error) {
+	var result Hold
+	err := r.inject("RetrieveHold", func() (err error) {
+		result, err = r.AccountRepository.RetrieveHold(holdID)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) IssueCard(iban string) (*Card, error) {
+	var result *Card
+	err := r.inject("IssueCard", func() (err error) {
+		result, err = r.AccountRepository.IssueCard(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) BlockCard(cardID string) error {
+	return r.inject("BlockCard", func() error { return r.AccountRepository.BlockCard(cardID) })
+}
+
+func (r *FaultInjectingAccountRepository) ActivateCard(cardID string) error {
+	return r.inject("ActivateCard", func() error { return r.AccountRepository.ActivateCard(cardID) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveCard(cardID string) (Card, error) {
+	var result Card
+	err := r.inject("RetrieveCard", func() (err error) {
+		result, err = r.AccountRepository.RetrieveCard(cardID)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListCards(iban string) ([]Card, error) {
+	var result []Card
+	err := r.inject("ListCards", func() (err error) {
+		result, err = r.AccountRepository.ListCards(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) DebitCard(cardID, recipient string, amount float64) (string, error) {
+	var result string
+	err := r.inject("DebitCard", func() (err error) {
+		result, err = r.AccountRepository.DebitCard(cardID, recipient, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) AuthorizePurchase(cardID string, amount float64, merchant string) (*Authorization, error) {
+	var result *Authorization
+	err := r.inject("AuthorizePurchase", func() (err error) {
+		result, err = r.AccountRepository.AuthorizePurchase(cardID, amount, merchant)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) CapturePurchase(authorizationID string, amount float64) (string, error) {
+	var result string
+	err := r.inject("CapturePurchase", func() (err error) {
+		result, err = r.AccountRepository.CapturePurchase(authorizationID, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) VoidAuthorization(authorizationID string) error {
+	return r.inject("VoidAuthorization", func() error { return r.AccountRepository.VoidAuthorization(authorizationID) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAuthorization(authorizationID string) (Authorization, error) {
+	var result Authorization
+	err := r.inject("RetrieveAuthorization", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAuthorization(authorizationID)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ExpireAuthorizations(now time.Time) (int, error) {
+	var result int
+	err := r.inject("ExpireAuthorizations", func() (err error) {
+		result, err = r.AccountRepository.ExpireAuthorizations(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) CashWithdraw(iban string, amount float64) (string, error) {
+	var result string
+	err := r.inject("CashWithdraw", func() (err error) {
+		result, err = r.AccountRepository.CashWithdraw(iban, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) CashDeposit(iban string, amount float64) (string, error) {
+	var result string
+	err := r.inject("CashDeposit", func() (err error) {
+		result, err = r.AccountRepository.CashDeposit(iban, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SetCashLimits(limits CashLimits) error {
+	return r.inject("SetCashLimits", func() error { return r.AccountRepository.SetCashLimits(limits) })
+}
+
+func (r *FaultInjectingAccountRepository) CreatePaymentRequest(requester, payer string, amount float64, dueDate time.Time, memo string) (*PaymentRequest, error) {
+	var result *PaymentRequest
+	err := r.inject("CreatePaymentRequest", func() (err error) {
+		result, err = r.AccountRepository.CreatePaymentRequest(requester, payer, amount, dueDate, memo)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListIncomingPaymentRequests(payer string) ([]PaymentRequest, error) {
+	var result []PaymentRequest
+	err := r.inject("ListIncomingPaymentRequests", func() (err error) {
+		result, err = r.AccountRepository.ListIncomingPaymentRequests(payer)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) AcceptPaymentRequest(id, payer string) (string, error) {
+	var result string
+	err := r.inject("AcceptPaymentRequest", func() (err error) {
+		result, err = r.AccountRepository.AcceptPaymentRequest(id, payer)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) DeclinePaymentRequest(id, payer string) error {
+	return r.inject("DeclinePaymentRequest", func() error { return r.AccountRepository.DeclinePaymentRequest(id, payer) })
+}
+
+func (r *FaultInjectingAccountRepository) ExpirePaymentRequests(now time.Time) (int, error) {
+	var result int
+	err := r.inject("ExpirePaymentRequests", func() (err error) {
+		result, err = r.AccountRepository.ExpirePaymentRequests(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SaveBeneficiary(iban, alias, beneficiaryIban string) (*Beneficiary, error) {
+	var result *Beneficiary
+	err := r.inject("SaveBeneficiary", func() (err error) {
+		result, err = r.AccountRepository.SaveBeneficiary(iban, alias, beneficiaryIban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListBeneficiaries(iban string) ([]Beneficiary, error) {
+	var result []Beneficiary
+	err := r.inject("ListBeneficiaries", func() (err error) {
+		result, err = r.AccountRepository.ListBeneficiaries(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) DeleteBeneficiary(iban, alias string) error {
+	return r.inject("DeleteBeneficiary", func() error { return r.AccountRepository.DeleteBeneficiary(iban, alias) })
+}
+
+func (r *FaultInjectingAccountRepository) RegisterAlias(alias, iban string) error {
+	return r.inject("RegisterAlias", func() error { return r.AccountRepository.RegisterAlias(alias, iban) })
+}
+
+func (r *FaultInjectingAccountRepository) ResolveAlias(alias string) (string, error) {
+	var result string
+	err := r.inject("ResolveAlias", func() (err error) {
+		result, err = r.AccountRepository.ResolveAlias(alias)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) InitiateInterbankTransfer(sender, recipient, peerBank string, amount float64, details TransferDetails) (*InterbankTransfer, error) {
+	var result *InterbankTransfer
+	err := r.inject("InitiateInterbankTransfer", func() (err error) {
+		result, err = r.AccountRepository.InitiateInterbankTransfer(sender, recipient, peerBank, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SettleInterbankTransfer(correlationID string) error {
+	return r.inject("SettleInterbankTransfer", func() error { return r.AccountRepository.SettleInterbankTransfer(correlationID) })
+}
+
+func (r *FaultInjectingAccountRepository) RefundInterbankTransfer(correlationID, reason string) error {
+	return r.inject("RefundInterbankTransfer", func() error { return r.AccountRepository.RefundInterbankTransfer(correlationID, reason) })
+}
+
+func (r *FaultInjectingAccountRepository) ReceiveInterbankTransfer(msg InterbankMessage) (InterbankAck, error) {
+	var result InterbankAck
+	err := r.inject("ReceiveInterbankTransfer", func() (err error) {
+		result, err = r.AccountRepository.ReceiveInterbankTransfer(msg)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveInterbankTransfer(correlationID string) (InterbankTransfer, error) {
+	var result InterbankTransfer
+	err := r.inject("RetrieveInterbankTransfer", func() (err error) {
+		result, err = r.AccountRepository.RetrieveInterbankTransfer(correlationID)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenNostroAccount(bank, iban string, balance float64) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenNostroAccount", func() (err error) {
+		result, err = r.AccountRepository.OpenNostroAccount(bank, iban, balance)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenVostroAccount(bank, iban string) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenVostroAccount", func() (err error) {
+		result, err = r.AccountRepository.OpenVostroAccount(bank, iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveVostroBalance(bank string) (float64, string, error) {
+	var result float64
+	var second string
+	err := r.inject("RetrieveVostroBalance", func() (err error) {
+		result, second, err = r.AccountRepository.RetrieveVostroBalance(bank)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *FaultInjectingAccountRepository) ReconcileNostro(bank string, reportedBalance float64) (*NostroReconciliation, error) {
+	var result *NostroReconciliation
+	err := r.inject("ReconcileNostro", func() (err error) {
+		result, err = r.AccountRepository.ReconcileNostro(bank, reportedBalance)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SetEmissionPolicy(policy EmissionPolicy) error {
+	return r.inject("SetEmissionPolicy", func() error { return r.AccountRepository.SetEmissionPolicy(policy) })
+}
+
+func (r *FaultInjectingAccountRepository) EmitMoneyWithReason(amount float64, operator string, reason SupplyReason) error {
+	return r.inject("EmitMoneyWithReason", func() error { return r.AccountRepository.EmitMoneyWithReason(amount, operator, reason) })
+}
+
+func (r *FaultInjectingAccountRepository) DestructMoneyWithReason(iban string, amount float64, operator string, reason SupplyReason) error {
+	return r.inject("DestructMoneyWithReason", func() error { return r.AccountRepository.DestructMoneyWithReason(iban, amount, operator, reason) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveSupplyRegister(from, to time.Time) ([]SupplyRecord, error) {
+	var result []SupplyRecord
+	err := r.inject("RetrieveSupplyRegister", func() (err error) {
+		result, err = r.AccountRepository.RetrieveSupplyRegister(from, to)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RequestEmission(amount float64, initiator string, reason SupplyReason) (string, error) {
+	var result string
+	err := r.inject("RequestEmission", func() (err error) {
+		result, err = r.AccountRepository.RequestEmission(amount, initiator, reason)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ApproveEmission(id, principal string) error {
+	return r.inject("ApproveEmission", func() error { return r.AccountRepository.ApproveEmission(id, principal) })
+}
+
+func (r *FaultInjectingAccountRepository) RejectEmission(id, principal, reason string) error {
+	return r.inject("RejectEmission", func() error { return r.AccountRepository.RejectEmission(id, principal, reason) })
+}
+
+func (r *FaultInjectingAccountRepository) ListPendingEmissions() ([]EmissionApproval, error) {
+	var result []EmissionApproval
+	err := r.inject("ListPendingEmissions", func() (err error) {
+		result, err = r.AccountRepository.ListPendingEmissions()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) MoneySupply() (*MoneySupply, error) {
+	var result *MoneySupply
+	err := r.inject("MoneySupply", func() (err error) {
+		result, err = r.AccountRepository.MoneySupply()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) TransferMoneyJson(jsonStr string) (string, error) {
+	var result string
+	err := r.inject("TransferMoneyJson", func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyJson(jsonStr)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccountWithInitialDeposit(amount float64) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccountWithInitialDeposit", func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithInitialDeposit(amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAllAccountsAsJson() (string, error) {
+	var result string
+	err := r.inject("RetrieveAllAccountsAsJson", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAllAccountsAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) WriteAllAccountsJson(writer io.Writer) error {
+	return r.inject("WriteAllAccountsJson", func() error { return r.AccountRepository.WriteAllAccountsJson(writer) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAccount(iban string) (*AccountDetails, error) {
+	var result *AccountDetails
+	err := r.inject("RetrieveAccount", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccount(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAccountAsJson(iban string) (string, error) {
+	var result string
+	err := r.inject("RetrieveAccountAsJson", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountAsJson(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) GetAccount(iban string) (Account, error) {
+	var result Account
+	err := r.inject("GetAccount", func() (err error) {
+		result, err = r.AccountRepository.GetAccount(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ForEachAccount(callback func(Account) bool) error {
+	return r.inject("ForEachAccount", func() error { return r.AccountRepository.ForEachAccount(callback) })
+}
+
+func (r *FaultInjectingAccountRepository) FindAccounts(query AccountQuery) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.inject("FindAccounts", func() (err error) {
+		result, err = r.AccountRepository.FindAccounts(query)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveSnapshot(ibans ...string) (*RepositorySnapshot, error) {
+	var result *RepositorySnapshot
+	err := r.inject("RetrieveSnapshot", func() (err error) {
+		result, err = r.AccountRepository.RetrieveSnapshot(ibans...)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListBlockedAccounts() ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.inject("ListBlockedAccounts", func() (err error) {
+		result, err = r.AccountRepository.ListBlockedAccounts()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListAccountsByType(accType AccountType) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.inject("ListAccountsByType", func() (err error) {
+		result, err = r.AccountRepository.ListAccountsByType(accType)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListDormantAccounts(since time.Time) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.inject("ListDormantAccounts", func() (err error) {
+		result, err = r.AccountRepository.ListDormantAccounts(since)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) BlockAccount(iban string) error {
+	return r.inject("BlockAccount", func() error { return r.AccountRepository.BlockAccount(iban) })
+}
+
+func (r *FaultInjectingAccountRepository) ActivateAccount(iban string) error {
+	return r.inject("ActivateAccount", func() error { return r.AccountRepository.ActivateAccount(iban) })
+}
+
+func (r *FaultInjectingAccountRepository) BlockAccountWithReason(iban string, reason BlockReason, until time.Time) error {
+	return r.inject("BlockAccountWithReason", func() error { return r.AccountRepository.BlockAccountWithReason(iban, reason, until) })
+}
+
+func (r *FaultInjectingAccountRepository) ActivateAccountAs(iban string, authority Authority) error {
+	return r.inject("ActivateAccountAs", func() error { return r.AccountRepository.ActivateAccountAs(iban, authority) })
+}
+
+func (r *FaultInjectingAccountRepository) ExpireBlocks(now time.Time) (int, error) {
+	var result int
+	err := r.inject("ExpireBlocks", func() (err error) {
+		result, err = r.AccountRepository.ExpireBlocks(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RestrictAccount(iban string, restriction AccountRestriction) error {
+	return r.inject("RestrictAccount", func() error { return r.AccountRepository.RestrictAccount(iban, restriction) })
+}
+
+func (r *FaultInjectingAccountRepository) RegisterFreeze(iban, caseNumber, authority string, limit float64) (*LegalFreeze, error) {
+	var result *LegalFreeze
+	err := r.inject("RegisterFreeze", func() (err error) {
+		result, err = r.AccountRepository.RegisterFreeze(iban, caseNumber, authority, limit)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ReleaseFreeze(id, caseNumber string) error {
+	return r.inject("ReleaseFreeze", func() error { return r.AccountRepository.ReleaseFreeze(id, caseNumber) })
+}
+
+func (r *FaultInjectingAccountRepository) ListFreezes(iban string) ([]LegalFreeze, error) {
+	var result []LegalFreeze
+	err := r.inject("ListFreezes", func() (err error) {
+		result, err = r.AccountRepository.ListFreezes(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) AdjustBalance(iban string, delta float64, reason, operator string) (string, error) {
+	var result string
+	err := r.inject("AdjustBalance", func() (err error) {
+		result, err = r.AccountRepository.AdjustBalance(iban, delta, reason, operator)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListAdjustments(iban string) ([]Adjustment, error) {
+	var result []Adjustment
+	err := r.inject("ListAdjustments", func() (err error) {
+		result, err = r.AccountRepository.ListAdjustments(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) IssueApiKey(principal Principal, scopes []ApiKeyScope, rateLimit int) (*ApiKey, string, error) {
+	var result *ApiKey
+	var second string
+	err := r.inject("IssueApiKey", func() (err error) {
+		result, second, err = r.AccountRepository.IssueApiKey(principal, scopes, rateLimit)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *FaultInjectingAccountRepository) RotateApiKey(id string, grace time.Duration) (*ApiKey, string, error) {
+	var result *ApiKey
+	var second string
+	err := r.inject("RotateApiKey", func() (err error) {
+		result, second, err = r.AccountRepository.RotateApiKey(id, grace)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *FaultInjectingAccountRepository) RevokeApiKey(id string) error {
+	return r.inject("RevokeApiKey", func() error { return r.AccountRepository.RevokeApiKey(id) })
+}
+
+func (r *FaultInjectingAccountRepository) ListApiKeys(principalID string) ([]ApiKey, error) {
+	var result []ApiKey
+	err := r.inject("ListApiKeys", func() (err error) {
+		result, err = r.AccountRepository.ListApiKeys(principalID)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) AuthenticateApiKey(token string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	var result *ApiKey
+	err := r.inject("AuthenticateApiKey", func() (err error) {
+		result, err = r.AccountRepository.AuthenticateApiKey(token, scope, now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) CheckApiKey(id string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	var result *ApiKey
+	err := r.inject("CheckApiKey", func() (err error) {
+		result, err = r.AccountRepository.CheckApiKey(id, scope, now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) IssueConsent(clientID string, ibans []string, scopes []ConsentScope, paymentLimit float64, days int) (*Consent, error) {
+	var result *Consent
+	err := r.inject("IssueConsent", func() (err error) {
+		result, err = r.AccountRepository.IssueConsent(clientID, ibans, scopes, paymentLimit, days)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) IntrospectConsent(id string) (ConsentIntrospection, error) {
+	var result ConsentIntrospection
+	err := r.inject("IntrospectConsent", func() (err error) {
+		result, err = r.AccountRepository.IntrospectConsent(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RevokeConsent(id string) error {
+	return r.inject("RevokeConsent", func() error { return r.AccountRepository.RevokeConsent(id) })
+}
+
+func (r *FaultInjectingAccountRepository) ListConsents(iban string) ([]Consent, error) {
+	var result []Consent
+	err := r.inject("ListConsents", func() (err error) {
+		result, err = r.AccountRepository.ListConsents(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAccountByConsent(id, clientID, iban string) (*AccountDetails, error) {
+	var result *AccountDetails
+	err := r.inject("RetrieveAccountByConsent", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountByConsent(id, clientID, iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) TransferMoneyByConsent(id, clientID, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	var result string
+	err := r.inject("TransferMoneyByConsent", func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyByConsent(id, clientID, sender, recipient, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SetOtpThreshold(iban string, threshold float64) error {
+	return r.inject("SetOtpThreshold", func() error { return r.AccountRepository.SetOtpThreshold(iban, threshold) })
+}
+
+func (r *FaultInjectingAccountRepository) ConfirmTransfer(challengeID, code string) error {
+	return r.inject("ConfirmTransfer", func() error { return r.AccountRepository.ConfirmTransfer(challengeID, code) })
+}
+
+func (r *FaultInjectingAccountRepository) ExpireChallenges(now time.Time) (int, error) {
+	var result int
+	err := r.inject("ExpireChallenges", func() (err error) {
+		result, err = r.AccountRepository.ExpireChallenges(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ListPendingChallenges(iban string) ([]Challenge, error) {
+	var result []Challenge
+	err := r.inject("ListPendingChallenges", func() (err error) {
+		result, err = r.AccountRepository.ListPendingChallenges(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) AnonymizeCustomerAccounts(customerID string) (int, error) {
+	var result int
+	err := r.inject("AnonymizeCustomerAccounts", func() (err error) {
+		result, err = r.AccountRepository.AnonymizeCustomerAccounts(customerID)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) CloseAccount(iban, sweepTargetIban string) error {
+	return r.inject("CloseAccount", func() error { return r.AccountRepository.CloseAccount(iban, sweepTargetIban) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveRemainderAccountIban() (string, error) {
+	var result string
+	err := r.inject("RetrieveRemainderAccountIban", func() (err error) {
+		result, err = r.AccountRepository.RetrieveRemainderAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveSystemAccountIban(role SystemAccountRole) (string, error) {
+	var result string
+	err := r.inject("RetrieveSystemAccountIban", func() (err error) {
+		result, err = r.AccountRepository.RetrieveSystemAccountIban(role)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ReconcileFractions() (*FractionsReconciliation, error) {
+	var result *FractionsReconciliation
+	err := r.inject("ReconcileFractions", func() (err error) {
+		result, err = r.AccountRepository.ReconcileFractions()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccountInCurrency(currency string) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccountInCurrency", func() (err error) {
+		result, err = r.AccountRepository.OpenAccountInCurrency(currency)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccountWithMetadata(metadata map[string]string, tags []string) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccountWithMetadata", func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithMetadata(metadata, tags)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccountWithOptions(opts AccountOptions) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccountWithOptions", func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithOptions(opts)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) UpdateAccountMetadata(iban string, metadata map[string]string, tags []string) error {
+	return r.inject("UpdateAccountMetadata", func() error { return r.AccountRepository.UpdateAccountMetadata(iban, metadata, tags) })
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccountWithReference(reference string) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccountWithReference", func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithReference(reference)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccountWithIban(iban string) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccountWithIban", func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithIban(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ImportAccountsCSV(reader io.Reader) (int, error) {
+	var result int
+	err := r.inject("ImportAccountsCSV", func() (err error) {
+		result, err = r.AccountRepository.ImportAccountsCSV(reader)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) ExportAccounts(writer io.Writer, format ExportFormat) error {
+	return r.inject("ExportAccounts", func() error { return r.AccountRepository.ExportAccounts(writer, format) })
+}
+
+func (r *FaultInjectingAccountRepository) OpenAccountForCustomer(customerID string) (*Account, error) {
+	var result *Account
+	err := r.inject("OpenAccountForCustomer", func() (err error) {
+		result, err = r.AccountRepository.OpenAccountForCustomer(customerID)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) AttachAccountToCustomer(iban, customerID string) error {
+	return r.inject("AttachAccountToCustomer", func() error { return r.AccountRepository.AttachAccountToCustomer(iban, customerID) })
+}
+
+func (r *FaultInjectingAccountRepository) InternalMoveMoney(customerID, sender, recipient string, amount float64) error {
+	return r.inject("InternalMoveMoney", func() error { return r.AccountRepository.InternalMoveMoney(customerID, sender, recipient, amount) })
+}
+
+func (r *FaultInjectingAccountRepository) VerifyKyc(iban string) error {
+	return r.inject("VerifyKyc", func() error { return r.AccountRepository.VerifyKyc(iban) })
+}
+
+func (r *FaultInjectingAccountRepository) RejectKyc(iban string) error {
+	return r.inject("RejectKyc", func() error { return r.AccountRepository.RejectKyc(iban) })
+}
+
+func (r *FaultInjectingAccountRepository) ConvertAndTransfer(sender, recipient string, amount float64) error {
+	return r.inject("ConvertAndTransfer", func() error { return r.AccountRepository.ConvertAndTransfer(sender, recipient, amount) })
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAllTransactionsAsJson() (string, error) {
+	var result string
+	err := r.inject("RetrieveAllTransactionsAsJson", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAllTransactionsAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveAccountTransactions(iban string, limit int) ([]Transaction, error) {
+	var result []Transaction
+	err := r.inject("RetrieveAccountTransactions", func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountTransactions(iban, limit)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveTransaction(id string) (Transaction, error) {
+	var result Transaction
+	err := r.inject("RetrieveTransaction", func() (err error) {
+		result, err = r.AccountRepository.RetrieveTransaction(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveTransactionsBetween(from, to time.Time) ([]Transaction, error) {
+	var result []Transaction
+	err := r.inject("RetrieveTransactionsBetween", func() (err error) {
+		result, err = r.AccountRepository.RetrieveTransactionsBetween(from, to)
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) RetrieveJournalAsJson() (string, error) {
+	var result string
+	err := r.inject("RetrieveJournalAsJson", func() (err error) {
+		result, err = r.AccountRepository.RetrieveJournalAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) VerifyInvariants() (*InvariantReport, error) {
+	var result *InvariantReport
+	err := r.inject("VerifyInvariants", func() (err error) {
+		result, err = r.AccountRepository.VerifyInvariants()
+		return err
+	})
+	return result, err
+}
+
+func (r *FaultInjectingAccountRepository) SetHotAccount(iban string, hot bool) error {
+	return r.inject("SetHotAccount", func() error { return r.AccountRepository.SetHotAccount(iban, hot) })
+}
+
+func (r *FaultInjectingAccountRepository) ReconcileHotAccounts() (int, error) {
+	var result int
+	err := r.inject("ReconcileHotAccounts", func() (err error) {
+		result, err = r.AccountRepository.ReconcileHotAccounts()
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Delay calls, fail them before and after the repository executes them and leave operations outside of the list alone
+func TestFaultInjection(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	chaos := NewFaultInjectingAccountRepository(inMemImpl, FaultConfig{ErrorRate: 1, Operations: []string{"emitmoney"}})
+	delays := []time.Duration{}
+	chaos.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+	service := NewAccountService(chaos)
+	acc, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Expected operations outside of the list to succeed, got %v", err)
+	}
+
+	if err := service.EmitMoney(100); err == nil || !isRetryableError(err) {
+		t.Errorf("Expected the injected error to look like a failure of the backend, got %v", err)
+	}
+	if supply, _ := inMemImpl.MoneySupply(); supply.Emitted != 0 {
+		t.Errorf("Expected the failed call not to reach the repository, got %v emitted", supply.Emitted)
+	}
+
+	chaos.Configure(FaultConfig{PartialFailureRate: 1, Latency: 20 * time.Millisecond, Jitter: 10 * time.Millisecond})
+	if err := service.EmitMoney(100); err == nil {
+		t.Errorf("Expected the response to be lost")
+	}
+	if supply, _ := inMemImpl.MoneySupply(); supply.Emitted != 100 {
+		t.Errorf("Expected the call to be executed before failing, got %v emitted", supply.Emitted)
+	}
+	if len(delays) != 1 || delays[0] < 20*time.Millisecond || delays[0] > 30*time.Millisecond {
+		t.Errorf("Unexpected latency %v", delays)
+	}
+	if _, err := service.TransferMoney(acc.Iban, emission, 1000); err == nil || isRetryableError(err) {
+		t.Errorf("Expected errors of the repository to be returned as they are, got %v", err)
+	}
+	if chaos.Injected["error"] != 1 || chaos.Injected["partial"] != 1 || chaos.Injected["latency"] != 2 {
+		t.Errorf("Unexpected faults injected %v", chaos.Injected)
+	}
+}
+
+// Survive lost responses of transfers by retrying them with the generated idempotency key
+func TestFaultInjectionWithRetries(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.EmitMoney(1000)
+	acc, _ := inMemImpl.OpenAccount()
+	chaos := NewFaultInjectingAccountRepository(inMemImpl, FaultConfig{PartialFailureRate: 0.5})
+	repo := NewRetryingAccountRepository(chaos, RetryPolicy{MaxAttempts: 50})
+	service := NewAccountService(repo)
+	for i := 0; i < 20; i++ {
+		if _, err := service.TransferMoney(emission, acc.Iban, 10); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if account, _ := inMemImpl.RetrieveAccount(acc.Iban); account.Balance != 200 {
+		t.Errorf("Expected every transfer to be executed once, got balance %v after %d lost responses", account.Balance, chaos.Injected["partial"])
+	}
+}
+
+// Load the faults from the flags and reject shares out of range
+func TestFaultConfig(t *testing.T) {
+	config, err := LoadConfig([]string{"-chaos-latency", "50ms", "-chaos-error-rate", "0.1", "-chaos-operations", "TransferMoney, EmitMoney"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !config.Chaos.Enabled() || config.Chaos.Latency != 50*time.Millisecond || config.Chaos.ErrorRate != 0.1 || strings.Join(config.Chaos.Operations, ",") != "TransferMoney,EmitMoney" {
+		t.Errorf("Unexpected faults %+v", config.Chaos)
+	}
+	if DefaultConfig().Chaos.Enabled() {
+		t.Errorf("Expected no faults to be injected by default")
+	}
+	for _, args := range [][]string{{"-chaos-error-rate", "1.5"}, {"-chaos-partial-failure-rate", "-0.1"}, {"-chaos-latency", "-1s"}} {
+		if _, err := LoadConfig(args); err == nil {
+			t.Errorf("Loading config with %v failed to fail", args)
+		}
+	}
+}
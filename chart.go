@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining chart of accounts: system accounts are declared by their roles in configuration instead of hardcoded IBANs
+type SystemAccountRole int8
+
+const (
+	EmissionRole SystemAccountRole = iota
+	DestructionRole
+	FeeIncomeRole
+	SuspenseRole
+	InterestExpenseRole
+	RemainderRole
+	VaultRole // cash paid out at ATMs and tills, see cash.go
+)
+
+// Mapping system account role codes to the keys used in configuration files
+var systemAccountRoleCodeToNameMap map[SystemAccountRole]string = map[SystemAccountRole]string{
+	EmissionRole:        "emission",
+	DestructionRole:     "destruction",
+	FeeIncomeRole:       "fee_income",
+	SuspenseRole:        "suspense",
+	InterestExpenseRole: "interest_expense",
+	RemainderRole:       "remainder",
+	VaultRole:           "vault",
+}
+
+// Roles the repository cannot work without, the rest of the accounts are opened only if declared
+var requiredSystemAccountRoles = []SystemAccountRole{EmissionRole, DestructionRole, RemainderRole}
+
+// IBANs of system accounts by their roles
+type ChartOfAccounts map[SystemAccountRole]string
+
+// Chart used by the demo, emission, destruction and remainder IBANs match the ones used before the chart was introduced
+func DefaultChartOfAccounts() ChartOfAccounts {
+	return ChartOfAccounts{
+		EmissionRole:        "BY84 ALFA 1000 0000 0000 0000 0000",
+		DestructionRole:     "BY84 ALFA 1000 0000 0000 0000 0001",
+		RemainderRole:       RemainderAccountIban,
+		FeeIncomeRole:       "BY84 ALFA 1000 0000 0000 0000 0003",
+		SuspenseRole:        "BY84 ALFA 1000 0000 0000 0000 0004",
+		InterestExpenseRole: "BY84 ALFA 1000 0000 0000 0000 0005",
+		VaultRole:           "BY84 ALFA 1000 0000 0000 0000 0006",
+	}
+}
+
+// Reads the chart from a JSON file of the form {"emission": "BY84...", "destruction": "BY84...", "remainder": "BY84...", "fee_income": "BY84..."}
+func LoadChartOfAccounts(path string) (ChartOfAccounts, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidChartOfAccountsError][locale()])
+	}
+	entries := map[string]string{}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidChartOfAccountsError][locale()])
+	}
+	chart := ChartOfAccounts{}
+	for name, iban := range entries {
+		role, ok := parseSystemAccountRole(name)
+		if !ok {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidChartOfAccountsError][locale()])
+		}
+		chart[role] = iban
+	}
+	if err := chart.Validate(); err != nil {
+		return nil, err
+	}
+	return chart, nil
+}
+
+func parseSystemAccountRole(name string) (SystemAccountRole, bool) {
+	for role, roleName := range systemAccountRoleCodeToNameMap {
+		if strings.EqualFold(roleName, strings.TrimSpace(name)) {
+			return role, true
+		}
+	}
+	return -1, false
+}
+
+// Checks that required roles are declared, roles are known and no IBAN is assigned to more than one role
+func (c ChartOfAccounts) Validate() error {
+	for _, role := range requiredSystemAccountRoles {
+		if strings.TrimSpace(c[role]) == "" {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidChartOfAccountsError][locale()])
+		}
+	}
+	seen := map[string]bool{}
+	for role, iban := range c {
+		iban = NormalizeIban(iban)
+		if _, ok := systemAccountRoleCodeToNameMap[role]; !ok || iban == "" || seen[iban] {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidChartOfAccountsError][locale()])
+		}
+		seen[iban] = true
+	}
+	return nil
+}
+
+// Opens the system accounts declared in the chart, fee income, suspense, interest expense and vault accounts are ordinary ones,
+// so that money can be moved out of them like out of any other account
+func NewInMemoryAccountRepositoryWithChart(chart ChartOfAccounts) (*InMemoryAccountRepository, error) {
+	if err := chart.Validate(); err != nil {
+		return nil, err
+	}
+	r := newInMemoryAccountRepository()
+	r.openSystemAccounts(chart)
+	return r, nil
+}
+
+// Helper function to open accounts of the chart and assign them to their roles
+func (r *InMemoryAccountRepository) openSystemAccounts(chart ChartOfAccounts) {
+	accountTypes := map[SystemAccountRole]AccountType{EmissionRole: MonetaryEmission, DestructionRole: MonetaryDestruction, RemainderRole: MonetaryRemainder}
+	for role, iban := range chart {
+		iban = NormalizeIban(iban)
+		acc := NewAccount(iban, Active, accountTypes[role], 0)
+		r.Accounts.Put(acc)
+		r.SystemAccounts[role] = acc
+	}
+	r.EmissionAccount = r.SystemAccounts[EmissionRole]
+	r.DestructionAccount = r.SystemAccounts[DestructionRole]
+	r.RemainderAccount = r.SystemAccounts[RemainderRole]
+	r.FeeAccount = r.SystemAccounts[FeeIncomeRole]
+}
+
+func (r *InMemoryAccountRepository) RetrieveSystemAccountIban(role SystemAccountRole) (string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	acc, exists := r.SystemAccounts[role]
+	if !exists || acc == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	return acc.Iban, nil
+}
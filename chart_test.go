@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// System accounts are opened from the chart loaded from configuration and retrievable by their roles
+func TestChartOfAccounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chart.json")
+	content := `{"emission": "BY84 ALFA 2000 0000 0000 0000 0000", "destruction": "BY84 ALFA 2000 0000 0000 0000 0001", "remainder": "BY84 ALFA 2000 0000 0000 0000 0002", "fee_income": "BY84 ALFA 2000 0000 0000 0000 0003"}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	chart, err := LoadChartOfAccounts(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	inMemImpl, err := NewInMemoryAccountRepositoryWithChart(chart)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service := NewAccountService(inMemImpl)
+	if iban, _ := service.RetrieveEmissionAccountIban(); iban != "BY84ALFA20000000000000000000" {
+		t.Errorf("Unexpected emission account %s", iban)
+	}
+	if iban, _ := service.RetrieveSystemAccountIban(FeeIncomeRole); iban != "BY84ALFA20000000000000000003" {
+		t.Errorf("Unexpected fee income account %s", iban)
+	}
+	if _, err := service.RetrieveSystemAccountIban(SuspenseRole); err == nil {
+		t.Errorf("Retrieving undeclared suspense account failed to fail")
+	}
+
+	// Fees are credited to the fee income account of the chart without calling SetFeeAccount
+	inMemImpl.FeeSchedule = NewFeeSchedule()
+	inMemImpl.FeeSchedule.SetFee(TransferTransaction, FeeRule{Kind: FlatFee, Value: 1})
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount("BY84ALFA20000000000000000003"); details.Balance != 1 {
+		t.Errorf("Expected fee income of 1, got %v", details.Balance)
+	}
+
+	// Charts missing required roles or reusing IBANs are rejected
+	invalid := []ChartOfAccounts{
+		{EmissionRole: "BY84 ALFA 2000 0000 0000 0000 0000", DestructionRole: "BY84 ALFA 2000 0000 0000 0000 0001"},
+		{EmissionRole: "BY84 ALFA 2000 0000 0000 0000 0000", DestructionRole: "BY84ALFA20000000000000000000", RemainderRole: "BY84 ALFA 2000 0000 0000 0000 0002"},
+	}
+	for _, chart := range invalid {
+		_, err := NewInMemoryAccountRepositoryWithChart(chart)
+		if err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[InvalidChartOfAccountsError][locale()]) {
+			t.Errorf("Expected invalid chart of accounts, got %v", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(`{"emission": "BY84 ALFA 2000 0000 0000 0000 0000", "treasury": "BY84 ALFA 2000 0000 0000 0000 0009"}`), 0600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := LoadChartOfAccounts(path); err == nil {
+		t.Errorf("Loading chart with unknown role failed to fail")
+	}
+}
@@ -0,0 +1,1251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining circuit breaker around remote backends of the repositories: after repeated failures of the backend the circuit
+// opens and calls fail fast with BackendUnavailableError instead of waiting for the backend, once the open timeout passes
+// a single probe is let through and the circuit closes again if the probe succeeds
+// Only failures of the backend itself count, that is errors which do not come from the error map and calls slower than
+// the slow call threshold, business errors such as insufficient funds mean the backend is healthy
+type CircuitState int8
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+type CircuitBreaker struct {
+	FailureThreshold  int           // consecutive failures opening the circuit
+	OpenTimeout       time.Duration // time the circuit stays open before the probe is let through
+	SlowCallThreshold time.Duration // calls taking longer count as failures even if they succeed, not checked if 0
+	state             CircuitState
+	failures          int
+	openedAt          time.Time
+	probing           bool   // the probe of the half-open circuit is in progress
+	Opened            uint64 // times the circuit has opened since the start
+	Rejected          uint64 // calls failed fast while the circuit was open
+	now               func() time.Time
+	Mutex             sync.Mutex
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout, slowCallThreshold time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{FailureThreshold: failureThreshold, OpenTimeout: openTimeout, SlowCallThreshold: slowCallThreshold, now: func() time.Time { return clock.Now() }}
+}
+
+// Returns the state of the circuit, the open circuit is reported as half-open once the open timeout has passed
+func (b *CircuitBreaker) State() CircuitState {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	if b.state == CircuitOpen && !b.now().Before(b.openedAt.Add(b.OpenTimeout)) {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Helper function to check if the error is a failure of the backend rather than a business error
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	code, ok := errorCodeOf(err)
+	return !ok || code == BackendUnavailableError
+}
+
+// Calls the backend unless the circuit is open, the outcome of the call is recorded to decide if the circuit opens
+func (b *CircuitBreaker) Call(call func() error) error {
+	b.Mutex.Lock()
+	// Checking if the circuit is open and no probe can be let through yet
+	if b.state == CircuitOpen && !b.now().Before(b.openedAt.Add(b.OpenTimeout)) {
+		b.state = CircuitHalfOpen
+	}
+	if b.state == CircuitOpen || (b.state == CircuitHalfOpen && b.probing) {
+		b.Rejected++
+		b.Mutex.Unlock()
+		return fmt.Errorf(errorCodesToMessagesMap[BackendUnavailableError][locale()])
+	}
+	probe := b.state == CircuitHalfOpen
+	b.probing = probe
+	b.Mutex.Unlock()
+
+	start := b.now()
+	err := call()
+	failed := isBackendFailure(err) || (b.SlowCallThreshold > 0 && b.now().Sub(start) > b.SlowCallThreshold)
+
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if !failed {
+		b.state, b.failures = CircuitClosed, 0
+		return err
+	}
+	b.failures++
+	// Checking if the probe failed or failures have piled up, both open the circuit
+	if probe || (b.state == CircuitClosed && b.failures >= b.FailureThreshold) {
+		b.state, b.openedAt = CircuitOpen, b.now()
+		b.Opened++
+	}
+	return err
+}
+
+// Decorates the account repository of a remote backend, every method returning an error is guarded by the breaker
+type CircuitBreakerAccountRepository struct {
+	AccountRepository
+	breaker *CircuitBreaker
+}
+
+func NewCircuitBreakerAccountRepository(r AccountRepository, breaker *CircuitBreaker) *CircuitBreakerAccountRepository {
+	return &CircuitBreakerAccountRepository{r, breaker}
+}
+
+// Decorates the customer repository of a remote backend, the breaker may be shared with the account repository if both
+// are kept by the same backend
+type CircuitBreakerCustomerRepository struct {
+	CustomerRepository
+	breaker *CircuitBreaker
+}
+
+func NewCircuitBreakerCustomerRepository(r CustomerRepository, breaker *CircuitBreaker) *CircuitBreakerCustomerRepository {
+	return &CircuitBreakerCustomerRepository{r, breaker}
+}
+
+// Guarded methods of the account repository
+func (r *CircuitBreakerAccountRepository) RetrieveEmissionAccountIban() (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveEmissionAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveDestructionAccountIban() (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveDestructionAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) EmitMoney(amount float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.EmitMoney(amount) })
+}
+
+func (r *CircuitBreakerAccountRepository) DestructMoney(iban string, amount float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.DestructMoney(iban, amount) })
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccount() (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccount()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.TransferMoney(sender, recipient, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyWithDetails(sender, recipient, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) GetTransferStatus(id string) (TransferStatus, error) {
+	var result TransferStatus
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.GetTransferStatus(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveTransferParties(id string) (string, string, error) {
+	var result string
+	var second string
+	err := r.breaker.Call(func() (err error) {
+		result, second, err = r.AccountRepository.RetrieveTransferParties(id)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *CircuitBreakerAccountRepository) ReverseTransfer(id, reason string) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ReverseTransfer(id, reason)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ApproveTransfer(id, principal string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ApproveTransfer(id, principal) })
+}
+
+func (r *CircuitBreakerAccountRepository) RejectTransfer(id, principal, reason string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RejectTransfer(id, principal, reason) })
+}
+
+func (r *CircuitBreakerAccountRepository) ListPendingApprovals() ([]Approval, error) {
+	var result []Approval
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListPendingApprovals()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SetAccountLimits(iban string, limits AccountLimits) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetAccountLimits(iban, limits) })
+}
+
+func (r *CircuitBreakerAccountRepository) SetOverdraftLimit(iban string, limit float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetOverdraftLimit(iban, limit) })
+}
+
+func (r *CircuitBreakerAccountRepository) UpdateAccountSettings(iban string, settings AccountSettings) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.UpdateAccountSettings(iban, settings) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAccountSettings(iban string) (AccountSettings, error) {
+	var result AccountSettings
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountSettings(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SetFeeAccount(iban string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetFeeAccount(iban) })
+}
+
+func (r *CircuitBreakerAccountRepository) SetInterestRate(product ProductType, rate float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetInterestRate(product, rate) })
+}
+
+func (r *CircuitBreakerAccountRepository) AccrueInterest(date time.Time) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.AccrueInterest(date)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) PostInterest(until time.Time) ([]InterestPosting, error) {
+	var result []InterestPosting
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.PostInterest(until)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListInterestAccruals(iban string) ([]InterestAccrual, error) {
+	var result []InterestAccrual
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListInterestAccruals(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListInterestPostings(iban string) ([]InterestPosting, error) {
+	var result []InterestPosting
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListInterestPostings(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenLoan(linkedIban string, principal, annualRate float64, months int, firstDue time.Time) (*Loan, error) {
+	var result *Loan
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenLoan(linkedIban, principal, annualRate, months, firstDue)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveLoan(iban string) (Loan, error) {
+	var result Loan
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveLoan(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) CollectDueInstallments(now time.Time) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.CollectDueInstallments(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenTermDeposit(linkedIban string, amount, annualRate, penaltyRate float64, maturesAt time.Time) (*TermDeposit, error) {
+	var result *TermDeposit
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenTermDeposit(linkedIban, amount, annualRate, penaltyRate, maturesAt)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveTermDeposit(iban string) (TermDeposit, error) {
+	var result TermDeposit
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveTermDeposit(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) MatureTermDeposits(now time.Time) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.MatureTermDeposits(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) WithdrawTermDeposit(iban string) (float64, error) {
+	var result float64
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.WithdrawTermDeposit(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) EmitMoneyIdempotent(key string, amount float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.EmitMoneyIdempotent(key, amount) })
+}
+
+func (r *CircuitBreakerAccountRepository) DestructMoneyIdempotent(key, iban string, amount float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.DestructMoneyIdempotent(key, iban, amount) })
+}
+
+func (r *CircuitBreakerAccountRepository) TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyIdempotent(key, sender, recipient, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SplitTransfer(sender string, amount float64, shares []SplitShare) ([]string, error) {
+	var result []string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.SplitTransfer(sender, amount, shares)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) HoldFunds(iban string, amount float64) (*Hold, error) {
+	var result *Hold
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.HoldFunds(iban, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) CaptureHold(holdID, recipient string, amount float64) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.CaptureHold(holdID, recipient, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ReleaseHold(holdID string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ReleaseHold(holdID) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveHold(holdID string) (Hold, error) {
+	var result Hold
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveHold(holdID)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) IssueCard(iban string) (*Card, error) {
+	var result *Card
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.IssueCard(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) BlockCard(cardID string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.BlockCard(cardID) })
+}
+
+func (r *CircuitBreakerAccountRepository) ActivateCard(cardID string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ActivateCard(cardID) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveCard(cardID string) (Card, error) {
+	var result Card
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveCard(cardID)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListCards(iban string) ([]Card, error) {
+	var result []Card
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListCards(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) DebitCard(cardID, recipient string, amount float64) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.DebitCard(cardID, recipient, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) AuthorizePurchase(cardID string, amount float64, merchant string) (*Authorization, error) {
+	var result *Authorization
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.AuthorizePurchase(cardID, amount, merchant)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) CapturePurchase(authorizationID string, amount float64) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.CapturePurchase(authorizationID, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) VoidAuthorization(authorizationID string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.VoidAuthorization(authorizationID) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAuthorization(authorizationID string) (Authorization, error) {
+	var result Authorization
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAuthorization(authorizationID)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ExpireAuthorizations(now time.Time) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ExpireAuthorizations(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) CashWithdraw(iban string, amount float64) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.CashWithdraw(iban, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) CashDeposit(iban string, amount float64) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.CashDeposit(iban, amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SetCashLimits(limits CashLimits) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetCashLimits(limits) })
+}
+
+func (r *CircuitBreakerAccountRepository) CreatePaymentRequest(requester, payer string, amount float64, dueDate time.Time, memo string) (*PaymentRequest, error) {
+	var result *PaymentRequest
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.CreatePaymentRequest(requester, payer, amount, dueDate, memo)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListIncomingPaymentRequests(payer string) ([]PaymentRequest, error) {
+	var result []PaymentRequest
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListIncomingPaymentRequests(payer)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) AcceptPaymentRequest(id, payer string) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.AcceptPaymentRequest(id, payer)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) DeclinePaymentRequest(id, payer string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.DeclinePaymentRequest(id, payer) })
+}
+
+func (r *CircuitBreakerAccountRepository) ExpirePaymentRequests(now time.Time) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ExpirePaymentRequests(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SaveBeneficiary(iban, alias, beneficiaryIban string) (*Beneficiary, error) {
+	var result *Beneficiary
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.SaveBeneficiary(iban, alias, beneficiaryIban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListBeneficiaries(iban string) ([]Beneficiary, error) {
+	var result []Beneficiary
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListBeneficiaries(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) DeleteBeneficiary(iban, alias string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.DeleteBeneficiary(iban, alias) })
+}
+
+func (r *CircuitBreakerAccountRepository) RegisterAlias(alias, iban string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RegisterAlias(alias, iban) })
+}
+
+func (r *CircuitBreakerAccountRepository) ResolveAlias(alias string) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ResolveAlias(alias)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) InitiateInterbankTransfer(sender, recipient, peerBank string, amount float64, details TransferDetails) (*InterbankTransfer, error) {
+	var result *InterbankTransfer
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.InitiateInterbankTransfer(sender, recipient, peerBank, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SettleInterbankTransfer(correlationID string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SettleInterbankTransfer(correlationID) })
+}
+
+func (r *CircuitBreakerAccountRepository) RefundInterbankTransfer(correlationID, reason string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RefundInterbankTransfer(correlationID, reason) })
+}
+
+func (r *CircuitBreakerAccountRepository) ReceiveInterbankTransfer(msg InterbankMessage) (InterbankAck, error) {
+	var result InterbankAck
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ReceiveInterbankTransfer(msg)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveInterbankTransfer(correlationID string) (InterbankTransfer, error) {
+	var result InterbankTransfer
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveInterbankTransfer(correlationID)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenNostroAccount(bank, iban string, balance float64) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenNostroAccount(bank, iban, balance)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenVostroAccount(bank, iban string) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenVostroAccount(bank, iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveVostroBalance(bank string) (float64, string, error) {
+	var result float64
+	var second string
+	err := r.breaker.Call(func() (err error) {
+		result, second, err = r.AccountRepository.RetrieveVostroBalance(bank)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *CircuitBreakerAccountRepository) ReconcileNostro(bank string, reportedBalance float64) (*NostroReconciliation, error) {
+	var result *NostroReconciliation
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ReconcileNostro(bank, reportedBalance)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SetEmissionPolicy(policy EmissionPolicy) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetEmissionPolicy(policy) })
+}
+
+func (r *CircuitBreakerAccountRepository) EmitMoneyWithReason(amount float64, operator string, reason SupplyReason) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.EmitMoneyWithReason(amount, operator, reason) })
+}
+
+func (r *CircuitBreakerAccountRepository) DestructMoneyWithReason(iban string, amount float64, operator string, reason SupplyReason) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.DestructMoneyWithReason(iban, amount, operator, reason) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveSupplyRegister(from, to time.Time) ([]SupplyRecord, error) {
+	var result []SupplyRecord
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveSupplyRegister(from, to)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RequestEmission(amount float64, initiator string, reason SupplyReason) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RequestEmission(amount, initiator, reason)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ApproveEmission(id, principal string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ApproveEmission(id, principal) })
+}
+
+func (r *CircuitBreakerAccountRepository) RejectEmission(id, principal, reason string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RejectEmission(id, principal, reason) })
+}
+
+func (r *CircuitBreakerAccountRepository) ListPendingEmissions() ([]EmissionApproval, error) {
+	var result []EmissionApproval
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListPendingEmissions()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) MoneySupply() (*MoneySupply, error) {
+	var result *MoneySupply
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.MoneySupply()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) TransferMoneyJson(jsonStr string) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyJson(jsonStr)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccountWithInitialDeposit(amount float64) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithInitialDeposit(amount)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAllAccountsAsJson() (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAllAccountsAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) WriteAllAccountsJson(writer io.Writer) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.WriteAllAccountsJson(writer) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAccount(iban string) (*AccountDetails, error) {
+	var result *AccountDetails
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccount(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAccountAsJson(iban string) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountAsJson(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) GetAccount(iban string) (Account, error) {
+	var result Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.GetAccount(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ForEachAccount(callback func(Account) bool) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ForEachAccount(callback) })
+}
+
+func (r *CircuitBreakerAccountRepository) FindAccounts(query AccountQuery) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.FindAccounts(query)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveSnapshot(ibans ...string) (*RepositorySnapshot, error) {
+	var result *RepositorySnapshot
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveSnapshot(ibans...)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListBlockedAccounts() ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListBlockedAccounts()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListAccountsByType(accType AccountType) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListAccountsByType(accType)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListDormantAccounts(since time.Time) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListDormantAccounts(since)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) BlockAccount(iban string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.BlockAccount(iban) })
+}
+
+func (r *CircuitBreakerAccountRepository) ActivateAccount(iban string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ActivateAccount(iban) })
+}
+
+func (r *CircuitBreakerAccountRepository) BlockAccountWithReason(iban string, reason BlockReason, until time.Time) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.BlockAccountWithReason(iban, reason, until) })
+}
+
+func (r *CircuitBreakerAccountRepository) ActivateAccountAs(iban string, authority Authority) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ActivateAccountAs(iban, authority) })
+}
+
+func (r *CircuitBreakerAccountRepository) ExpireBlocks(now time.Time) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ExpireBlocks(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RestrictAccount(iban string, restriction AccountRestriction) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RestrictAccount(iban, restriction) })
+}
+
+func (r *CircuitBreakerAccountRepository) RegisterFreeze(iban, caseNumber, authority string, limit float64) (*LegalFreeze, error) {
+	var result *LegalFreeze
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RegisterFreeze(iban, caseNumber, authority, limit)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ReleaseFreeze(id, caseNumber string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ReleaseFreeze(id, caseNumber) })
+}
+
+func (r *CircuitBreakerAccountRepository) ListFreezes(iban string) ([]LegalFreeze, error) {
+	var result []LegalFreeze
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListFreezes(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) AdjustBalance(iban string, delta float64, reason, operator string) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.AdjustBalance(iban, delta, reason, operator)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListAdjustments(iban string) ([]Adjustment, error) {
+	var result []Adjustment
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListAdjustments(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) IssueApiKey(principal Principal, scopes []ApiKeyScope, rateLimit int) (*ApiKey, string, error) {
+	var result *ApiKey
+	var second string
+	err := r.breaker.Call(func() (err error) {
+		result, second, err = r.AccountRepository.IssueApiKey(principal, scopes, rateLimit)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *CircuitBreakerAccountRepository) RotateApiKey(id string, grace time.Duration) (*ApiKey, string, error) {
+	var result *ApiKey
+	var second string
+	err := r.breaker.Call(func() (err error) {
+		result, second, err = r.AccountRepository.RotateApiKey(id, grace)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *CircuitBreakerAccountRepository) RevokeApiKey(id string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RevokeApiKey(id) })
+}
+
+func (r *CircuitBreakerAccountRepository) ListApiKeys(principalID string) ([]ApiKey, error) {
+	var result []ApiKey
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListApiKeys(principalID)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) AuthenticateApiKey(token string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	var result *ApiKey
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.AuthenticateApiKey(token, scope, now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) CheckApiKey(id string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	var result *ApiKey
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.CheckApiKey(id, scope, now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) IssueConsent(clientID string, ibans []string, scopes []ConsentScope, paymentLimit float64, days int) (*Consent, error) {
+	var result *Consent
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.IssueConsent(clientID, ibans, scopes, paymentLimit, days)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) IntrospectConsent(id string) (ConsentIntrospection, error) {
+	var result ConsentIntrospection
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.IntrospectConsent(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RevokeConsent(id string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RevokeConsent(id) })
+}
+
+func (r *CircuitBreakerAccountRepository) ListConsents(iban string) ([]Consent, error) {
+	var result []Consent
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListConsents(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAccountByConsent(id, clientID, iban string) (*AccountDetails, error) {
+	var result *AccountDetails
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountByConsent(id, clientID, iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) TransferMoneyByConsent(id, clientID, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.TransferMoneyByConsent(id, clientID, sender, recipient, amount, details)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SetOtpThreshold(iban string, threshold float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetOtpThreshold(iban, threshold) })
+}
+
+func (r *CircuitBreakerAccountRepository) ConfirmTransfer(challengeID, code string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ConfirmTransfer(challengeID, code) })
+}
+
+func (r *CircuitBreakerAccountRepository) ExpireChallenges(now time.Time) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ExpireChallenges(now)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ListPendingChallenges(iban string) ([]Challenge, error) {
+	var result []Challenge
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ListPendingChallenges(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) AnonymizeCustomerAccounts(customerID string) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.AnonymizeCustomerAccounts(customerID)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) CloseAccount(iban, sweepTargetIban string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.CloseAccount(iban, sweepTargetIban) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveRemainderAccountIban() (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveRemainderAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveSystemAccountIban(role SystemAccountRole) (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveSystemAccountIban(role)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ReconcileFractions() (*FractionsReconciliation, error) {
+	var result *FractionsReconciliation
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ReconcileFractions()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccountInCurrency(currency string) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccountInCurrency(currency)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccountWithMetadata(metadata map[string]string, tags []string) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithMetadata(metadata, tags)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccountWithOptions(opts AccountOptions) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithOptions(opts)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) UpdateAccountMetadata(iban string, metadata map[string]string, tags []string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.UpdateAccountMetadata(iban, metadata, tags) })
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccountWithReference(reference string) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithReference(reference)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccountWithIban(iban string) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccountWithIban(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ImportAccountsCSV(reader io.Reader) (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ImportAccountsCSV(reader)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) ExportAccounts(writer io.Writer, format ExportFormat) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ExportAccounts(writer, format) })
+}
+
+func (r *CircuitBreakerAccountRepository) OpenAccountForCustomer(customerID string) (*Account, error) {
+	var result *Account
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.OpenAccountForCustomer(customerID)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) AttachAccountToCustomer(iban, customerID string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.AttachAccountToCustomer(iban, customerID) })
+}
+
+func (r *CircuitBreakerAccountRepository) InternalMoveMoney(customerID, sender, recipient string, amount float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.InternalMoveMoney(customerID, sender, recipient, amount) })
+}
+
+func (r *CircuitBreakerAccountRepository) VerifyKyc(iban string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.VerifyKyc(iban) })
+}
+
+func (r *CircuitBreakerAccountRepository) RejectKyc(iban string) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.RejectKyc(iban) })
+}
+
+func (r *CircuitBreakerAccountRepository) ConvertAndTransfer(sender, recipient string, amount float64) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.ConvertAndTransfer(sender, recipient, amount) })
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAllTransactionsAsJson() (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAllTransactionsAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveAccountTransactions(iban string, limit int) ([]Transaction, error) {
+	var result []Transaction
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountTransactions(iban, limit)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveTransaction(id string) (Transaction, error) {
+	var result Transaction
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveTransaction(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveTransactionsBetween(from, to time.Time) ([]Transaction, error) {
+	var result []Transaction
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveTransactionsBetween(from, to)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) RetrieveJournalAsJson() (string, error) {
+	var result string
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.RetrieveJournalAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) VerifyInvariants() (*InvariantReport, error) {
+	var result *InvariantReport
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.VerifyInvariants()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerAccountRepository) SetHotAccount(iban string, hot bool) error {
+	return r.breaker.Call(func() error { return r.AccountRepository.SetHotAccount(iban, hot) })
+}
+
+func (r *CircuitBreakerAccountRepository) ReconcileHotAccounts() (int, error) {
+	var result int
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.AccountRepository.ReconcileHotAccounts()
+		return err
+	})
+	return result, err
+}
+
+// Guarded methods of the customer repository
+func (r *CircuitBreakerCustomerRepository) CreateCustomer(name, email, phone, address string) (*Customer, error) {
+	var result *Customer
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.CustomerRepository.CreateCustomer(name, email, phone, address)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerCustomerRepository) RetrieveCustomer(id string) (Customer, error) {
+	var result Customer
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.CustomerRepository.RetrieveCustomer(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerCustomerRepository) ListCustomers() ([]Customer, error) {
+	var result []Customer
+	err := r.breaker.Call(func() (err error) {
+		result, err = r.CustomerRepository.ListCustomers()
+		return err
+	})
+	return result, err
+}
+
+func (r *CircuitBreakerCustomerRepository) SetPrimaryAccount(id, iban string) error {
+	return r.breaker.Call(func() error { return r.CustomerRepository.SetPrimaryAccount(id, iban) })
+}
+
+func (r *CircuitBreakerCustomerRepository) ExportCustomers(writer io.Writer) error {
+	return r.breaker.Call(func() error { return r.CustomerRepository.ExportCustomers(writer) })
+}
+
+func (r *CircuitBreakerCustomerRepository) EraseCustomer(id string) error {
+	return r.breaker.Call(func() error { return r.CustomerRepository.EraseCustomer(id) })
+}
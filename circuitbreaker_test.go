@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Backend failing with errors of the connection while it is down
+type flakyAccountRepository struct {
+	AccountRepository
+	down  bool
+	calls int
+}
+
+func (r *flakyAccountRepository) EmitMoney(amount float64) error {
+	r.calls++
+	if r.down {
+		return errors.New("connection refused")
+	}
+	return r.AccountRepository.EmitMoney(amount)
+}
+
+// Open the circuit after repeated failures, fail fast while it is open and close it once the probe succeeds
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(3, time.Minute, 0)
+	breaker.now = func() time.Time { return now }
+	backend := &flakyAccountRepository{AccountRepository: NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")}
+	service := NewAccountService(NewCircuitBreakerAccountRepository(backend, breaker))
+	unavailable := errorCodesToMessagesMap[BackendUnavailableError][locale()]
+
+	// Business errors mean the backend is healthy and do not open the circuit
+	for i := 0; i < 5; i++ {
+		if err := service.EmitMoney(-1); err == nil {
+			t.Fatalf("Emission of negative amount failed to fail")
+		}
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected business errors to keep the circuit closed, got %d", breaker.State())
+	}
+
+	backend.down, backend.calls = true, 0
+	for i := 0; i < 3; i++ {
+		if err := service.EmitMoney(10); err == nil || err.Error() == unavailable {
+			t.Fatalf("Expected the failure of the backend to be returned, got %v", err)
+		}
+	}
+	if breaker.State() != CircuitOpen || breaker.Opened != 1 {
+		t.Fatalf("Expected the circuit to open after 3 failures, got %d", breaker.State())
+	}
+	if err := service.EmitMoney(10); err == nil || err.Error() != unavailable || backend.calls != 3 {
+		t.Errorf("Expected the open circuit to fail fast without calling the backend, got %v and %d calls", err, backend.calls)
+	}
+
+	// The failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	if breaker.State() != CircuitHalfOpen {
+		t.Errorf("Expected the circuit to be half-open after the timeout, got %d", breaker.State())
+	}
+	if err := service.EmitMoney(10); err == nil || err.Error() == unavailable || backend.calls != 4 {
+		t.Errorf("Expected the probe to reach the backend, got %v", err)
+	}
+	if breaker.State() != CircuitOpen || breaker.Opened != 2 {
+		t.Errorf("Expected the failed probe to open the circuit, got %d", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	backend.down = false
+	if err := service.EmitMoney(10); err != nil {
+		t.Errorf("Expected the probe to succeed, got %v", err)
+	}
+	if breaker.State() != CircuitClosed || breaker.Rejected != 1 {
+		t.Errorf("Expected the succeeded probe to close the circuit, got %d", breaker.State())
+	}
+}
+
+// Let a single probe through while the circuit is half-open and count slow calls as failures
+func TestCircuitBreakerProbe(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Minute, time.Second)
+	breaker.now = func() time.Time { return now }
+	slow := func() error {
+		now = now.Add(2 * time.Second)
+		return nil
+	}
+	if err := breaker.Call(slow); err != nil {
+		t.Errorf("Expected the result of the slow call to be returned, got %v", err)
+	}
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected the slow call to open the circuit, got %d", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	var concurrent error
+	breaker.Call(func() error {
+		concurrent = breaker.Call(func() error { return nil })
+		return nil
+	})
+	if concurrent == nil || concurrent.Error() != errorCodesToMessagesMap[BackendUnavailableError][locale()] {
+		t.Errorf("Expected calls during the probe to fail fast, got %v", concurrent)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected the probe to close the circuit, got %d", breaker.State())
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining clearing of interbank obligations: obligations between participants (bank codes) are accumulated
+// during the settlement window, at window close only net positions are settled, so that far less money moves
+// between the banks than the gross flows of the window amount to
+type ClearingObligation struct {
+	ID          string
+	Payer       string // bank code of the participant owing the money
+	Payee       string
+	Amount      float64
+	Currency    string
+	SubmittedAt time.Time
+}
+
+// Net position of the participant in the currency, positive if the participant receives money at window close
+type NetPosition struct {
+	Participant string  `json:"participant"`
+	Currency    string  `json:"currency"`
+	Sent        float64 `json:"sent"`
+	Received    float64 `json:"received"`
+	Net         float64 `json:"net"`
+}
+
+// Payment settling net positions, Error is set if the settlement callback failed to execute it
+type NetSettlement struct {
+	Payer    string  `json:"payer"`
+	Payee    string  `json:"payee"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Gross amounts are sums of all obligations of the window, net amounts are sums of the settlements, both by currency
+type ClearingReport struct {
+	WindowID     string             `json:"window_id"`
+	OpenedAt     time.Time          `json:"opened_at"`
+	ClosedAt     time.Time          `json:"closed_at"`
+	Obligations  int                `json:"obligations"`
+	GrossAmounts map[string]float64 `json:"gross_amounts"`
+	NetAmounts   map[string]float64 `json:"net_amounts"`
+	Positions    []NetPosition      `json:"positions"`
+	Settlements  []NetSettlement    `json:"settlements"`
+}
+
+type clearingWindow struct {
+	id          string
+	openedAt    time.Time
+	obligations []ClearingObligation
+}
+
+type ClearingHouse struct {
+	Settle  func(NetSettlement) error // executes settlements at window close, settlements are only reported if not set
+	Reports []ClearingReport          // reports of closed windows, the oldest first
+	window  *clearingWindow
+	Mutex   sync.Mutex
+}
+
+func NewClearingHouse() *ClearingHouse {
+	now := clock.Now().UTC()
+	return &ClearingHouse{Reports: []ClearingReport{}, window: &clearingWindow{NewUlid(now), now, []ClearingObligation{}}}
+}
+
+// Accumulates the obligation in the current window, returns the ID of the obligation
+func (c *ClearingHouse) Submit(payer, payee string, amount float64, currency string) (string, error) {
+	payer = strings.ToUpper(strings.TrimSpace(payer))
+	payee = strings.ToUpper(strings.TrimSpace(payee))
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	// Checking if the obligation is owed by one participant to another one
+	if payer == "" || payee == "" || payer == payee || currency == "" || !(amount > 0) || math.IsInf(amount, 0) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidClearingObligationError][locale()])
+	}
+
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	now := clock.Now().UTC()
+	obligation := ClearingObligation{NewUlid(now), payer, payee, roundToCurrency(amount, currency), currency, now}
+	c.window.obligations = append(c.window.obligations, obligation)
+	return obligation.ID, nil
+}
+
+// Closes the current window, settles net positions through the settlement callback and opens the next window
+func (c *ClearingHouse) CloseWindow(now time.Time) ClearingReport {
+	c.Mutex.Lock()
+	window := c.window
+	c.window = &clearingWindow{NewUlid(now), now.UTC(), []ClearingObligation{}}
+	settle := c.Settle
+	c.Mutex.Unlock()
+
+	report := netObligations(window.obligations)
+	report.WindowID, report.OpenedAt, report.ClosedAt = window.id, window.openedAt, now.UTC()
+	if settle != nil {
+		for i := range report.Settlements {
+			if err := settle(report.Settlements[i]); err != nil {
+				report.Settlements[i].Error = err.Error()
+			}
+		}
+	}
+
+	c.Mutex.Lock()
+	c.Reports = append(c.Reports, report)
+	c.Mutex.Unlock()
+	return report
+}
+
+// Helper function to compute net positions of the obligations and the settlements of the positions,
+// every participant owing money pays participants owed money, the largest amounts first, so that
+// there are at most as many settlements as participants less one in every currency
+func netObligations(obligations []ClearingObligation) ClearingReport {
+	report := ClearingReport{Obligations: len(obligations), GrossAmounts: map[string]float64{}, NetAmounts: map[string]float64{}, Positions: []NetPosition{}, Settlements: []NetSettlement{}}
+	positions := map[string]*NetPosition{}
+	position := func(participant, currency string) *NetPosition {
+		key := currency + "|" + participant
+		if positions[key] == nil {
+			positions[key] = &NetPosition{Participant: participant, Currency: currency}
+		}
+		return positions[key]
+	}
+	for _, o := range obligations {
+		report.GrossAmounts[o.Currency] = roundToCurrency(report.GrossAmounts[o.Currency]+o.Amount, o.Currency)
+		payer, payee := position(o.Payer, o.Currency), position(o.Payee, o.Currency)
+		payer.Sent = roundToCurrency(payer.Sent+o.Amount, o.Currency)
+		payee.Received = roundToCurrency(payee.Received+o.Amount, o.Currency)
+	}
+	for _, p := range positions {
+		p.Net = roundToCurrency(p.Received-p.Sent, p.Currency)
+		report.Positions = append(report.Positions, *p)
+	}
+	sort.Slice(report.Positions, func(i, j int) bool {
+		a, b := report.Positions[i], report.Positions[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.Participant < b.Participant
+	})
+
+	byCurrency := map[string][]NetPosition{}
+	currencies := []string{}
+	for _, p := range report.Positions {
+		if _, seen := byCurrency[p.Currency]; !seen {
+			currencies = append(currencies, p.Currency)
+		}
+		byCurrency[p.Currency] = append(byCurrency[p.Currency], p)
+	}
+	for _, currency := range currencies {
+		debtors, creditors := []NetPosition{}, []NetPosition{}
+		for _, p := range byCurrency[currency] {
+			if p.Net < 0 {
+				debtors = append(debtors, p)
+			} else if p.Net > 0 {
+				creditors = append(creditors, p)
+			}
+		}
+		sort.SliceStable(debtors, func(i, j int) bool { return debtors[i].Net < debtors[j].Net })
+		sort.SliceStable(creditors, func(i, j int) bool { return creditors[i].Net > creditors[j].Net })
+		for d, cr := 0, 0; d < len(debtors) && cr < len(creditors); {
+			amount := math.Min(-debtors[d].Net, creditors[cr].Net)
+			report.Settlements = append(report.Settlements, NetSettlement{Payer: debtors[d].Participant, Payee: creditors[cr].Participant, Amount: amount, Currency: currency})
+			report.NetAmounts[currency] = roundToCurrency(report.NetAmounts[currency]+amount, currency)
+			debtors[d].Net = roundToCurrency(debtors[d].Net+amount, currency)
+			creditors[cr].Net = roundToCurrency(creditors[cr].Net-amount, currency)
+			if debtors[d].Net == 0 {
+				d++
+			}
+			if creditors[cr].Net == 0 {
+				cr++
+			}
+		}
+	}
+	return report
+}
+
+// Lists obligations accumulated in the current window, the oldest first
+func (c *ClearingHouse) ListOpenObligations() []ClearingObligation {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	return append([]ClearingObligation{}, c.window.obligations...)
+}
+
+// Closes the window every interval in a background goroutine, the callback receives reports of closed windows
+// Calling the returned function stops closing windows, the window being closed, if any, is closed first
+func (c *ClearingHouse) Start(interval time.Duration, callback func(ClearingReport)) func() {
+	ticker := time.NewTicker(interval)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				now := clock.Now()
+				report := c.CloseWindow(now)
+				if callback != nil {
+					callback(report)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Obligations of the window are settled by net positions only
+func TestClearingHouse(t *testing.T) {
+	clearing := NewClearingHouse()
+	settled := []NetSettlement{}
+	clearing.Settle = func(s NetSettlement) error {
+		if s.Payee == "GAMA" {
+			return fmt.Errorf("settlement account of GAMA is closed")
+		}
+		settled = append(settled, s)
+		return nil
+	}
+
+	if _, err := clearing.Submit("ALFA", "alfa", 10, "BYN"); err == nil {
+		t.Errorf("Obligation of a participant to itself failed to fail")
+	}
+	if _, err := clearing.Submit("ALFA", "BETA", 0, "BYN"); err == nil {
+		t.Errorf("Obligation of zero amount failed to fail")
+	}
+	obligations := []struct {
+		payer, payee string
+		amount       float64
+		currency     string
+	}{
+		{"ALFA", "BETA", 100, "BYN"},
+		{"BETA", "ALFA", 70, "BYN"},
+		{"BETA", "GAMA", 50, "BYN"},
+		{"GAMA", "ALFA", 10, "BYN"},
+		{"ALFA", "BETA", 5, "EUR"},
+	}
+	for _, o := range obligations {
+		if _, err := clearing.Submit(o.payer, o.payee, o.amount, o.currency); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if open := clearing.ListOpenObligations(); len(open) != len(obligations) {
+		t.Errorf("Expected %d open obligations, got %d", len(obligations), len(open))
+	}
+
+	report := clearing.CloseWindow(time.Now())
+	if report.Obligations != 5 || report.GrossAmounts["BYN"] != 230 || report.GrossAmounts["EUR"] != 5 {
+		t.Errorf("Unexpected gross flows: %+v", report)
+	}
+	// ALFA: -100 +70 +10 = -20, BETA: +100 -70 -50 = -20, GAMA: +50 -10 = +40
+	expectedNet := map[string]float64{"BYN|ALFA": -20, "BYN|BETA": -20, "BYN|GAMA": 40, "EUR|ALFA": -5, "EUR|BETA": 5}
+	if len(report.Positions) != len(expectedNet) {
+		t.Errorf("Expected %d positions, got %+v", len(expectedNet), report.Positions)
+	}
+	for _, p := range report.Positions {
+		if net := expectedNet[p.Currency+"|"+p.Participant]; p.Net != net {
+			t.Errorf("Expected net position %v of %s in %s, got %v", net, p.Participant, p.Currency, p.Net)
+		}
+	}
+	if report.NetAmounts["BYN"] != 40 || report.NetAmounts["EUR"] != 5 {
+		t.Errorf("Unexpected net flows: %v", report.NetAmounts)
+	}
+	if len(report.Settlements) != 3 {
+		t.Fatalf("Expected 3 settlements, got %+v", report.Settlements)
+	}
+	failed := 0
+	for _, s := range report.Settlements {
+		if s.Error != "" {
+			failed++
+		}
+	}
+	if failed != 2 || len(settled) != 1 || settled[0] != (NetSettlement{Payer: "ALFA", Payee: "BETA", Amount: 5, Currency: "EUR"}) {
+		t.Errorf("Expected settlements to GAMA to fail and the EUR one to be settled, got %+v", report.Settlements)
+	}
+
+	// The next window starts empty
+	if open := clearing.ListOpenObligations(); len(open) != 0 {
+		t.Errorf("Expected no open obligations after window close, got %d", len(open))
+	}
+	if empty := clearing.CloseWindow(time.Now()); empty.Obligations != 0 || len(empty.Settlements) != 0 {
+		t.Errorf("Expected empty window to settle nothing, got %+v", empty)
+	}
+	if len(clearing.Reports) != 2 || clearing.Reports[0].WindowID != report.WindowID {
+		t.Errorf("Expected reports of both windows to be kept")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining the clock the time of operations is taken from: timestamps of accounts and transactions, expiry of holds,
+// consents and payment requests, dormancy and daily limits, scheduled transfers and interest accrual all read the clock
+// of the package, so that tests and simulations can move time forward with the fake clock instead of waiting
+// So do authentication of tokens, keys and sessions, signatures exchanged with peers, caches of rates and the circuit
+// breaker, only durations measured for the log, the metrics and the traces keep using the system time
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+var clock Clock = SystemClock{}
+
+// Replaces the clock of the package, returns the function restoring the previous clock
+func UseClock(c Clock) func() {
+	previous := clock
+	clock = c
+	return func() { clock = previous }
+}
+
+// Clock standing still until it is moved explicitly
+type FakeClock struct {
+	now   time.Time
+	Mutex sync.Mutex
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	return c.now
+}
+
+// Moves the clock forward by the duration and returns the new time
+func (c *FakeClock) Advance(d time.Duration) time.Time {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// Moves the clock to the time, the time may be in the past
+func (c *FakeClock) Set(now time.Time) {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	c.now = now
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Stamp transactions and reset daily limits by the fake clock
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, time.March, 31, 23, 0, 0, 0, time.UTC)
+	fake := NewFakeClock(start)
+	defer UseClock(fake)()
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccountWithInitialDeposit(500)
+	other, _ := service.OpenAccount()
+	if account, _ := inMemImpl.GetAccount(acc.Iban); !account.OpenedAt.Equal(start) {
+		t.Errorf("Expected the account to be opened at %v, got %v", start, account.OpenedAt)
+	}
+	service.SetAccountLimits(acc.Iban, AccountLimits{Daily: 100})
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 1); err == nil {
+		t.Errorf("Exceeding the daily limit failed to fail")
+	}
+
+	fake.Advance(2 * time.Hour)
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 1); err != nil {
+		t.Errorf("Expected the daily limit to be reset the next day, got %v", err)
+	}
+	transactions, _ := service.RetrieveAccountTransactions(acc.Iban, 1)
+	if len(transactions) != 1 || !transactions[0].Timestamp.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("Expected the transaction to be stamped by the fake clock, got %+v", transactions)
+	}
+}
+
+// Execute the scheduled transfer once the fake clock reaches it rather than the system time
+func TestSchedulerWithFakeClock(t *testing.T) {
+	fake := NewFakeClock(time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	other, _ := service.OpenAccount()
+	scheduler := NewTransferScheduler(service)
+	st, err := scheduler.ScheduleTransfer(acc.Iban, other.Iban, 40, fake.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	stop := scheduler.Start(5 * time.Millisecond)
+	defer stop()
+
+	time.Sleep(30 * time.Millisecond)
+	if pending := scheduler.ListPendingScheduledTransfers(); len(pending) != 1 {
+		t.Fatalf("Expected the transfer to wait for the fake clock, got %d pending", len(pending))
+	}
+	fake.Advance(24 * time.Hour)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(scheduler.ListPendingScheduledTransfers()) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if details, _ := service.RetrieveAccount(other.Iban); details.Balance != 40 {
+		t.Errorf("Expected transfer %s to be executed once the clock reached it, got balance %v", st.ID, details.Balance)
+	}
+}
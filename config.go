@@ -0,0 +1,635 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining configuration of the program: settings are taken from the defaults, then from the YAML file, then from
+// the environment and finally from the command-line flags, so that every next source overrides the previous ones
+//
+// Every setting is known by its key in the YAML file, i.e. "tls.cert_file", the environment variable is the key in upper
+// case prefixed with PAYMENTS_ (PAYMENTS_TLS_CERT_FILE) and the flag is the key with dashes (-tls-cert-file)
+type Config struct {
+	Locale                    LanguageCode
+	EmissionIban              string
+	DestructionIban           string
+	ChartFile                 string // JSON chart of accounts, see LoadChartOfAccounts, takes precedence over the IBANs above
+	MaxIbanGenerationAttempts int    // attempts to generate an IBAN not taken by another account before giving up
+	SecureIbanGeneration      bool   // account numbers are generated with crypto/rand, so that they cannot be predicted
+	BankDirectoryFile         string // JSON directory of known banks IBANs of other banks are checked against, see LoadBankDirectory
+	HttpAddr                  string // address the HTTP API is served at, i.e. ":8080", the API is not served if empty
+	BankCode                  string // code of the bank in IBANs and interbank messages
+	Tls                       TlsConfig
+	Secrets                   SecretsConfig
+	Interbank                 InterbankConfig
+	Jwt                       JwtConfig     // verification of bearer tokens, keys are taken from the secrets store, see WithSecrets
+	LogFormat                 string        // "text" or "json", see NewLogger
+	LogLevel                  slog.Level    // messages below the level are not logged
+	Tracing                   bool          // spans are written to the standard error as JSON lines if set, see tracing.go
+	ShutdownTimeout           time.Duration // time given to the shutdown to drain requests, events and background jobs
+	SnapshotDir               string        // directory accounts and the journal are written to on shutdown, nothing is written if empty
+	Chaos                     FaultConfig   // faults injected into the repository for resilience testing, see chaos.go
+	RandomSeed                int64         // seed of the random source making runs reproducible, seeded by the start time if 0
+	File                      string        // YAML file the configuration was loaded from, if any
+	// Settings below can be changed without restarting the program, see ConfigReloader
+	Fees            map[TransactionType]FeeRule // fees of the fee schedule of the repository, the schedule is left as it is if nil
+	DefaultLimits   AccountLimits               // limits of ordinary accounts which do not have limits of their own
+	CashLimits      CashLimits                  // limits of cash withdrawals and deposits
+	ApiKeyRateLimit int                         // requests per minute of API keys issued without a rate limit of their own
+}
+
+// Path of the YAML file is taken from the -config flag or from the PAYMENTS_CONFIG_FILE variable
+const (
+	configFileFlag  = "config"
+	configEnvPrefix = "PAYMENTS_"
+)
+
+func DefaultConfig() Config {
+	chart := DefaultChartOfAccounts()
+	return Config{Locale: English, EmissionIban: chart[EmissionRole], DestructionIban: chart[DestructionRole], MaxIbanGenerationAttempts: 1000000, BankCode: "ALFA", LogFormat: "text", LogLevel: slog.LevelInfo, ShutdownTimeout: 30 * time.Second,
+		Secrets: SecretsConfig{Provider: "env", Dir: "/run/secrets", VaultMount: "secret", VaultPath: "payments"}, Interbank: InterbankConfig{RequireSignatures: true}}
+}
+
+type configSetting struct {
+	key   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// Helper function to parse the boolean setting
+func parseConfigBool(value string, target *bool) error {
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	*target = parsed
+	return nil
+}
+
+// Helper function to parse the amount of the setting, amounts cannot be negative
+func parseConfigAmount(value string, target *float64) error {
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	*target = parsed
+	return nil
+}
+
+// Helper function to parse the duration of the setting, durations cannot be negative
+func parseConfigDuration(value string, target *time.Duration) error {
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || parsed < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	*target = parsed
+	return nil
+}
+
+// Helper function to parse the share of the setting, shares are between 0 and 1
+func parseConfigRate(value string, target *float64) error {
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || parsed < 0 || parsed > 1 {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	*target = parsed
+	return nil
+}
+
+// Helper function to parse the fee rule of the form "flat:<amount>" or "percentage:<percent>[:<minimum>[:<maximum>]]",
+// i.e. "percentage:1.5:0.5:10" for 1.5% but no less than 0.50 and no more than 10.00
+func parseConfigFee(c *Config, txType TransactionType, value string) error {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	rule := FeeRule{}
+	switch strings.ToLower(parts[0]) {
+	case "flat":
+		if len(parts) != 2 {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+	case "percentage":
+		if len(parts) < 2 || len(parts) > 4 {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		rule.Kind = PercentageFee
+	default:
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	for i, target := range []*float64{&rule.Value, &rule.Minimum, &rule.Maximum}[:len(parts)-1] {
+		if err := parseConfigAmount(parts[i+1], target); err != nil {
+			return err
+		}
+	}
+	// Checking if the rule is well-formed
+	if err := NewFeeSchedule().SetFee(txType, rule); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	if c.Fees == nil {
+		c.Fees = map[TransactionType]FeeRule{}
+	}
+	c.Fees[txType] = rule
+	return nil
+}
+
+// Helper function to parse the locale by its short code ("en", "ru") or by the name of the language in English
+func parseLocale(value string) (LanguageCode, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "en", "english":
+		return English, true
+	case "ru", "russian":
+		return Russian, true
+	}
+	return English, false
+}
+
+var configSettings = []configSetting{
+	{"locale", "language of messages, en or ru", func(c *Config, value string) error {
+		parsed, ok := parseLocale(value)
+		if !ok {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		c.Locale = parsed
+		return nil
+	}},
+	{"emission_iban", "IBAN of the emission account", func(c *Config, value string) error {
+		c.EmissionIban = strings.TrimSpace(value)
+		return nil
+	}},
+	{"destruction_iban", "IBAN of the destruction account", func(c *Config, value string) error {
+		c.DestructionIban = strings.TrimSpace(value)
+		return nil
+	}},
+	{"chart_file", "JSON file with the chart of accounts", func(c *Config, value string) error {
+		c.ChartFile = strings.TrimSpace(value)
+		return nil
+	}},
+	{"max_iban_generation_attempts", "attempts to generate an IBAN not taken by another account", func(c *Config, value string) error {
+		attempts, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		c.MaxIbanGenerationAttempts = attempts
+		return nil
+	}},
+	{"secure_iban_generation", "generate account numbers with crypto/rand rather than the seedable random source", func(c *Config, value string) error {
+		return parseConfigBool(value, &c.SecureIbanGeneration)
+	}},
+	{"bank_directory_file", "JSON directory of known banks by country", func(c *Config, value string) error {
+		c.BankDirectoryFile = strings.TrimSpace(value)
+		return nil
+	}},
+	{"http_addr", "address the HTTP API is served at, i.e. :8080", func(c *Config, value string) error {
+		c.HttpAddr = strings.TrimSpace(value)
+		return nil
+	}},
+	{"bank_code", "code of the bank", func(c *Config, value string) error {
+		c.BankCode = strings.ToUpper(strings.TrimSpace(value))
+		return nil
+	}},
+	{"tls.cert_file", "PEM certificate of the server", func(c *Config, value string) error {
+		c.Tls.CertFile = strings.TrimSpace(value)
+		return nil
+	}},
+	{"tls.key_file", "PEM private key of the certificate", func(c *Config, value string) error {
+		c.Tls.KeyFile = strings.TrimSpace(value)
+		return nil
+	}},
+	{"tls.client_ca_file", "PEM CA of the client certificates", func(c *Config, value string) error {
+		c.Tls.ClientCAFile = strings.TrimSpace(value)
+		return nil
+	}},
+	{"tls.require_client_cert", "refuse clients without a certificate", func(c *Config, value string) error {
+		return parseConfigBool(value, &c.Tls.RequireClientCert)
+	}},
+	{"tls.root_ca_file", "PEM CA of the certificates of the peers", func(c *Config, value string) error {
+		c.Tls.RootCAFile = strings.TrimSpace(value)
+		return nil
+	}},
+	{"interbank.peers", "comma-separated peers, i.e. BETA=https://beta:8443", func(c *Config, value string) error {
+		c.Interbank.Peers = map[string]string{}
+		for _, peer := range strings.Split(value, ",") {
+			if peer = strings.TrimSpace(peer); peer == "" {
+				continue
+			}
+			bankCode, baseUrl, found := strings.Cut(peer, "=")
+			if !found || strings.TrimSpace(baseUrl) == "" {
+				return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+			}
+			c.Interbank.Peers[strings.ToUpper(strings.TrimSpace(bankCode))] = strings.TrimSpace(baseUrl)
+		}
+		return nil
+	}},
+	{"interbank.require_signatures", "accept only transfers signed with secrets shared with the peers", func(c *Config, value string) error {
+		return parseConfigBool(value, &c.Interbank.RequireSignatures)
+	}},
+	{"interbank.require_api_keys", "accept only requests with API keys issued to the peers", func(c *Config, value string) error {
+		return parseConfigBool(value, &c.Interbank.RequireApiKeys)
+	}},
+	{"interbank.require_jwt", "accept only requests with bearer tokens", func(c *Config, value string) error {
+		return parseConfigBool(value, &c.Interbank.RequireJwt)
+	}},
+	{"jwt.issuer", "expected issuer of bearer tokens, not checked if empty", func(c *Config, value string) error {
+		c.Jwt.Issuer = strings.TrimSpace(value)
+		return nil
+	}},
+	{"jwt.audience", "expected audience of bearer tokens, not checked if empty", func(c *Config, value string) error {
+		c.Jwt.Audience = strings.TrimSpace(value)
+		return nil
+	}},
+	{"jwt.leeway", "allowed clock skew when checking expiry of bearer tokens, i.e. 30s", func(c *Config, value string) error {
+		return parseConfigDuration(value, &c.Jwt.Leeway)
+	}},
+	{"fees.transfer", "fee of transfers, i.e. flat:0.50 or percentage:1.5:0.5:10", func(c *Config, value string) error {
+		return parseConfigFee(c, TransferTransaction, value)
+	}},
+	{"fees.internal_move", "fee of moves between accounts of one customer", func(c *Config, value string) error {
+		return parseConfigFee(c, InternalMoveTransaction, value)
+	}},
+	{"fees.acquiring", "fee deducted from payments to merchant accounts, i.e. percentage:1.8", func(c *Config, value string) error {
+		return parseConfigFee(c, AcquiringFeeTransaction, value)
+	}},
+	{"limits.per_transaction", "default maximum amount of a single debit", func(c *Config, value string) error {
+		return parseConfigAmount(value, &c.DefaultLimits.PerTransaction)
+	}},
+	{"limits.daily", "default maximum sum of debits per day", func(c *Config, value string) error {
+		return parseConfigAmount(value, &c.DefaultLimits.Daily)
+	}},
+	{"cash.per_withdrawal", "maximum amount of a single cash withdrawal", func(c *Config, value string) error {
+		return parseConfigAmount(value, &c.CashLimits.PerWithdrawal)
+	}},
+	{"cash.daily_withdrawal", "maximum sum of cash withdrawals from an account per day", func(c *Config, value string) error {
+		return parseConfigAmount(value, &c.CashLimits.DailyWithdrawal)
+	}},
+	{"cash.per_deposit", "maximum amount of a single cash deposit", func(c *Config, value string) error {
+		return parseConfigAmount(value, &c.CashLimits.PerDeposit)
+	}},
+	{"api_keys.rate_limit", "default requests per minute of API keys", func(c *Config, value string) error {
+		rateLimit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || rateLimit < 0 {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		c.ApiKeyRateLimit = rateLimit
+		return nil
+	}},
+	{"log.format", "format of the log, text or json", func(c *Config, value string) error {
+		format := strings.ToLower(strings.TrimSpace(value))
+		if format != "text" && format != "json" {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		c.LogFormat = format
+		return nil
+	}},
+	{"log.level", "minimum level of the log, debug, info, warn or error", func(c *Config, value string) error {
+		if err := c.LogLevel.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		return nil
+	}},
+	{"tracing.enabled", "trace operations and write the spans to the standard error", func(c *Config, value string) error {
+		return parseConfigBool(value, &c.Tracing)
+	}},
+	{"shutdown.timeout", "time given to the graceful shutdown, i.e. 30s", func(c *Config, value string) error {
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		c.ShutdownTimeout = timeout
+		return nil
+	}},
+	{"shutdown.snapshot_dir", "directory accounts and the journal are written to on shutdown", func(c *Config, value string) error {
+		c.SnapshotDir = strings.TrimSpace(value)
+		return nil
+	}},
+	{"random.seed", "seed of the random source to reproduce generated accounts and transfers, any if 0", func(c *Config, value string) error {
+		seed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		c.RandomSeed = seed
+		return nil
+	}},
+	{"chaos.latency", "delay added to every call of the repository, i.e. 100ms", func(c *Config, value string) error {
+		return parseConfigDuration(value, &c.Chaos.Latency)
+	}},
+	{"chaos.jitter", "random delay of up to the jitter added on top of the latency", func(c *Config, value string) error {
+		return parseConfigDuration(value, &c.Chaos.Jitter)
+	}},
+	{"chaos.error_rate", "share of calls of the repository failing, from 0 to 1", func(c *Config, value string) error {
+		return parseConfigRate(value, &c.Chaos.ErrorRate)
+	}},
+	{"chaos.partial_failure_rate", "share of calls of the repository failing after being executed, from 0 to 1", func(c *Config, value string) error {
+		return parseConfigRate(value, &c.Chaos.PartialFailureRate)
+	}},
+	{"chaos.operations", "comma-separated methods of the repository faults are injected into, all if empty", func(c *Config, value string) error {
+		c.Chaos.Operations = nil
+		for _, operation := range strings.Split(value, ",") {
+			if operation = strings.TrimSpace(operation); operation != "" {
+				c.Chaos.Operations = append(c.Chaos.Operations, operation)
+			}
+		}
+		return nil
+	}},
+	{"secrets.provider", "store of the secrets, env, file or vault", func(c *Config, value string) error {
+		c.Secrets.Provider = strings.ToLower(strings.TrimSpace(value))
+		return nil
+	}},
+	{"secrets.dir", "directory of the file store", func(c *Config, value string) error {
+		c.Secrets.Dir = strings.TrimSpace(value)
+		return nil
+	}},
+	{"secrets.vault_addr", "address of Vault, i.e. https://vault:8200", func(c *Config, value string) error {
+		c.Secrets.VaultAddr = strings.TrimSpace(value)
+		return nil
+	}},
+	{"secrets.vault_mount", "mount of the key-value engine of Vault", func(c *Config, value string) error {
+		c.Secrets.VaultMount = strings.TrimSpace(value)
+		return nil
+	}},
+	{"secrets.vault_path", "path of the Vault secret holding the secrets", func(c *Config, value string) error {
+		c.Secrets.VaultPath = strings.TrimSpace(value)
+		return nil
+	}},
+}
+
+func configEnvName(key string) string {
+	return configEnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_").Replace(key))
+}
+
+func configFlagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
+
+// Helper function to find the setting by its key
+func findConfigSetting(key string) (configSetting, bool) {
+	for _, setting := range configSettings {
+		if setting.key == key {
+			return setting, true
+		}
+	}
+	return configSetting{}, false
+}
+
+// Reads the subset of YAML used by config files: "key: value" pairs, optionally grouped into sections by indentation,
+// values may be quoted and lines may end with comments
+//
+//	locale: ru
+//	tls:
+//	  cert_file: "/etc/payments/server.pem"
+func parseConfigYaml(reader io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		key, value := strings.TrimSpace(trimmed[:colon]), strings.TrimSpace(trimmed[colon+1:])
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		if !indented {
+			section = ""
+		}
+		// Checking if the line opens a section, nested sections are not supported
+		if value == "" {
+			if indented {
+				return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+			}
+			section = key + "."
+			continue
+		}
+		if indented && section == "" {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		if quote := value[0]; quote == '"' || quote == '\'' {
+			end := strings.IndexByte(value[1:], quote)
+			if end < 0 {
+				return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+			}
+			value = value[1 : end+1]
+		} else if comment := strings.Index(value, " #"); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+		values[section+key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	return values, nil
+}
+
+// Loads the configuration from the YAML file, the environment and the command-line arguments (without the program name)
+// and validates it, settings which are not known are rejected rather than ignored
+func LoadConfig(args []string) (Config, error) {
+	config := DefaultConfig()
+
+	flags := flag.NewFlagSet("payments", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	path := flags.String(configFileFlag, os.Getenv(configEnvPrefix+"CONFIG_FILE"), "YAML file with the configuration")
+	flagSettings := map[string]configSetting{}
+	for _, setting := range configSettings {
+		flagSettings[configFlagName(setting.key)] = setting
+		flags.String(configFlagName(setting.key), "", setting.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+
+	config.File = *path
+	if *path != "" {
+		file, err := os.Open(*path)
+		if err != nil {
+			return Config{}, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+		defer file.Close()
+		values, err := parseConfigYaml(file)
+		if err != nil {
+			return Config{}, err
+		}
+		for key, value := range values {
+			setting, known := findConfigSetting(key)
+			if !known {
+				return Config{}, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+			}
+			if err := setting.set(&config, value); err != nil {
+				return Config{}, err
+			}
+		}
+	}
+
+	// Chart of accounts used to be set by CHART_OF_ACCOUNTS only, the variable is still honoured
+	if value, set := os.LookupEnv("CHART_OF_ACCOUNTS"); set {
+		config.ChartFile = strings.TrimSpace(value)
+	}
+	for _, setting := range configSettings {
+		if value, set := os.LookupEnv(configEnvName(setting.key)); set {
+			if err := setting.set(&config, value); err != nil {
+				return Config{}, err
+			}
+		}
+	}
+
+	// Only flags given explicitly override the settings, so that empty defaults of the flags do not reset them
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if setting, known := flagSettings[f.Name]; known && err == nil {
+			err = setting.set(&config, f.Value.String())
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+func (c Config) Validate() error {
+	if _, err := c.Chart(); err != nil {
+		return err
+	}
+	if c.MaxIbanGenerationAttempts <= 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	if c.BankDirectoryFile != "" {
+		if _, err := LoadBankDirectory(c.BankDirectoryFile); err != nil {
+			return err
+		}
+	}
+	if c.HttpAddr != "" {
+		if _, _, err := net.SplitHostPort(c.HttpAddr); err != nil {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+	}
+	// Checking if the bank code is 4 letters long as in Belarusian IBANs
+	if len(c.BankCode) != 4 || strings.Trim(c.BankCode, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	// Checking if the peers are known by valid bank codes and the served API authenticates them
+	for bankCode := range c.Interbank.Peers {
+		if len(bankCode) != 4 || strings.Trim(bankCode, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+		}
+	}
+	if c.HttpAddr != "" && !c.Interbank.RequireSignatures && !c.Interbank.RequireApiKeys && !c.Interbank.RequireJwt {
+		return fmt.Errorf(errorCodesToMessagesMap[InterbankAuthenticationRequiredError][locale()])
+	}
+	// Checking if the certificate comes with its key and client certificates can be verified if required
+	if (c.Tls.CertFile == "") != (c.Tls.KeyFile == "") || (c.Tls.RequireClientCert && c.Tls.ClientCAFile == "") {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	return c.Secrets.Validate()
+}
+
+// Returns the chart of accounts loaded from the chart file if set, the default chart with the configured emission and
+// destruction IBANs otherwise
+func (c Config) Chart() (ChartOfAccounts, error) {
+	if c.ChartFile != "" {
+		return LoadChartOfAccounts(c.ChartFile)
+	}
+	chart := DefaultChartOfAccounts()
+	chart[EmissionRole], chart[DestructionRole] = c.EmissionIban, c.DestructionIban
+	// Checking if the IBANs are 28 characters long as in Belarus, check digits are not verified since the default
+	// IBANs of system accounts do not have valid ones
+	for _, role := range []SystemAccountRole{EmissionRole, DestructionRole} {
+		if iban := NormalizeIban(chart[role]); len(iban) != 28 || !strings.HasPrefix(strings.ToUpper(iban), "BY") {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidChartOfAccountsError][locale()])
+		}
+	}
+	if err := chart.Validate(); err != nil {
+		return nil, err
+	}
+	return chart, nil
+}
+
+// Applies settings used by the package as a whole, i.e. the locale of messages, expected to be called once on start
+// before requests are served, reloads apply only the reloadable settings, see ApplyReloadable
+func (c Config) Apply() {
+	c.ApplyReloadable()
+	maxIbanGenerationAttempts = c.MaxIbanGenerationAttempts
+	secureIbanGeneration = c.SecureIbanGeneration
+	var directory BankDirectory
+	if c.BankDirectoryFile != "" {
+		// The file has been read by Validate already
+		directory, _ = LoadBankDirectory(c.BankDirectoryFile)
+	}
+	bankDirectory = directory
+	if c.RandomSeed != 0 {
+		UseRandomSource(rand.NewSource(c.RandomSeed))
+	}
+}
+
+// Applies package-wide settings which can be changed while requests are served, i.e. the locale of messages
+func (c Config) ApplyReloadable() {
+	setLocale(c.Locale)
+}
+
+// Creates the gateway of the served interbank API with the configured peers, their secrets and API keys are taken from
+// the secrets store, so are the keys of bearer tokens if tokens are required
+func (c Config) InterbankGateway(service *AccountService) (*InterbankGateway, error) {
+	gateway := NewInterbankGateway(service, c.BankCode)
+	gateway.RequireSignatures, gateway.RequireApiKeys = c.Interbank.RequireSignatures, c.Interbank.RequireApiKeys
+	if err := gateway.UseTls(c.Tls); err != nil {
+		return nil, err
+	}
+	provider, err := c.Secrets.SecretProvider()
+	if err != nil {
+		return nil, err
+	}
+	bankCodes := []string{}
+	for bankCode, baseUrl := range c.Interbank.Peers {
+		gateway.AddPeer(bankCode, baseUrl)
+		bankCodes = append(bankCodes, bankCode)
+	}
+	if err := gateway.LoadPeerSecrets(provider, bankCodes...); err != nil {
+		return nil, err
+	}
+	if c.Interbank.RequireJwt {
+		jwtConfig, err := c.Jwt.WithSecrets(provider)
+		if err != nil {
+			return nil, err
+		}
+		if gateway.Jwt, err = NewJwtVerifier(jwtConfig); err != nil {
+			return nil, err
+		}
+	}
+	return gateway, nil
+}
+
+// Starts serving the handler at the configured address in a background goroutine, over TLS if the certificate is configured,
+// returns the server to be shut down by the caller along with the address it listens at
+func (c Config) StartServer(handler http.Handler) (*http.Server, net.Addr, error) {
+	listener, err := net.Listen("tcp", c.HttpAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Tls.CertFile != "" {
+		if listener, err = c.Tls.Listener(listener); err != nil {
+			return nil, nil, err
+		}
+	}
+	server := &http.Server{Handler: handler}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("serving HTTP API", errorAttrs(err)...)
+		}
+	}()
+	return server, listener.Addr(), nil
+}
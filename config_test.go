@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Load the configuration from the file, the environment and the flags and make sure later sources override earlier ones
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if config.EmissionIban != "BY84 ALFA 1000 0000 0000 0000 0000" || config.Locale != English || config.MaxIbanGenerationAttempts != 1000000 || config.HttpAddr != "" {
+		t.Errorf("Unexpected default config %+v", config)
+	}
+
+	path := filepath.Join(t.TempDir(), "payments.yaml")
+	yaml := "# configuration of the demo\n" +
+		"locale: ru\n" +
+		"http_addr: \":8080\" # served over plain HTTP\n" +
+		"max_iban_generation_attempts: 100\n" +
+		"tls:\n" +
+		"  root_ca_file: '/etc/ca.pem'\n" +
+		"bank_code: beta\n"
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	t.Setenv("PAYMENTS_CONFIG_FILE", path)
+	t.Setenv("PAYMENTS_MAX_IBAN_GENERATION_ATTEMPTS", "200")
+	t.Setenv("PAYMENTS_TLS_ROOT_CA_FILE", "/etc/other-ca.pem")
+	config, err = LoadConfig([]string{"-max-iban-generation-attempts", "300", "-destruction-iban", "BY84 ALFA 1000 0000 0000 0000 0009"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if config.Locale != Russian || config.HttpAddr != ":8080" || config.BankCode != "BETA" {
+		t.Errorf("Expected settings of the file, got %+v", config)
+	}
+	if config.Tls.RootCAFile != "/etc/other-ca.pem" {
+		t.Errorf("Expected the environment to override the file, got %q", config.Tls.RootCAFile)
+	}
+	if config.MaxIbanGenerationAttempts != 300 || config.DestructionIban != "BY84 ALFA 1000 0000 0000 0000 0009" {
+		t.Errorf("Expected flags to override the environment, got %+v", config)
+	}
+	chart, err := config.Chart()
+	if err != nil || chart[DestructionRole] != config.DestructionIban {
+		t.Errorf("Unexpected chart %v (%v)", chart, err)
+	}
+
+	// Apply sets the package-wide settings
+	defer func(l LanguageCode, attempts int) { setLocale(l); maxIbanGenerationAttempts = attempts }(locale(), maxIbanGenerationAttempts)
+	config.Apply()
+	if locale() != Russian || maxIbanGenerationAttempts != 300 {
+		t.Errorf("Expected config to be applied, got locale %d and %d attempts", locale(), maxIbanGenerationAttempts)
+	}
+}
+
+// Make sure invalid settings are rejected at startup
+func TestInvalidConfig(t *testing.T) {
+	invalid := [][]string{
+		{"-locale", "de"},
+		{"-emission-iban", "BY12"},
+		{"-emission-iban", "BY84 ALFA 1000 0000 0000 0000 0001"},
+		{"-max-iban-generation-attempts", "0"},
+		{"-max-iban-generation-attempts", "many"},
+		{"-http-addr", "8080"},
+		{"-bank-code", "AB1"},
+		{"-tls-cert-file", "server.pem"},
+		{"-tls-require-client-cert", "true"},
+		{"-tls-require-client-cert", "maybe"},
+		{"-chart-file", "missing.json"},
+		{"-http-addr", ":8080", "-interbank-require-signatures", "false"},
+		{"-interbank-peers", "BETA"},
+		{"-interbank-peers", "B1=https://beta:8443"},
+		{"-unknown", "value"},
+		{"-config", "missing.yaml"},
+	}
+	for _, args := range invalid {
+		if _, err := LoadConfig(args); err == nil {
+			t.Errorf("Loading config with %v failed to fail", args)
+		}
+	}
+
+	t.Setenv("PAYMENTS_LOCALE", "fr")
+	if _, err := LoadConfig(nil); err == nil {
+		t.Errorf("Loading config with invalid environment failed to fail")
+	}
+	os.Unsetenv("PAYMENTS_LOCALE")
+
+	invalidYaml := []string{
+		"unknown_setting: 1\n",
+		"locale ru\n",
+		"  locale: ru\n",
+		"tls:\n  inner:\n    cert_file: x\n",
+		"http_addr: \":8080\n",
+	}
+	for _, yaml := range invalidYaml {
+		path := filepath.Join(t.TempDir(), "payments.yaml")
+		os.WriteFile(path, []byte(yaml), 0600)
+		if _, err := LoadConfig([]string{"-config", path}); err == nil {
+			t.Errorf("Loading config file %q failed to fail", yaml)
+		}
+	}
+}
+
+// Peers and authentication of the interbank API are configured, their secrets are taken from the store
+func TestInterbankConfig(t *testing.T) {
+	t.Setenv("PAYMENTS_SECRET_INTERBANK_SECRET_BETA", "shared")
+	t.Setenv("PAYMENTS_SECRET_JWT_HMAC_SECRET", "jwt-secret")
+	config, err := LoadConfig([]string{"-http-addr", ":8080", "-interbank-peers", "beta=https://beta:8443, GAMA=https://gama:8443", "-interbank-require-jwt", "true", "-jwt-issuer", "idp"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !config.Interbank.RequireSignatures || !config.Interbank.RequireJwt || len(config.Interbank.Peers) != 2 || config.Interbank.Peers["BETA"] != "https://beta:8443" {
+		t.Errorf("Unexpected interbank config %+v", config.Interbank)
+	}
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	gateway, err := config.InterbankGateway(NewAccountService(inMemImpl))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !gateway.RequireSignatures || gateway.Jwt == nil || gateway.Peers["GAMA"] != "https://gama:8443" {
+		t.Errorf("Unexpected gateway %+v", gateway)
+	}
+	if secret, known := gateway.peerSecret("BETA"); !known || secret != "shared" {
+		t.Errorf("Expected peer secret from the store, got %q", secret)
+	}
+	if !gateway.isPeer("gama") || gateway.isPeer("EVIL") {
+		t.Errorf("Expected only the configured banks to be peers")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining consents of account holders granting third-party clients scoped access to their accounts for a number of days,
+// clients use the ID of the consent to read balances or initiate payments which are capped by the limit of the consent
+type ConsentScope int8
+
+const (
+	BalancesConsentScope ConsentScope = iota // reading balances of the accounts
+	PaymentsConsentScope                     // initiating payments from the accounts up to the limit
+)
+
+// Mapping scope codes to scope names used by introspection
+var consentScopeCodeToNameMap map[ConsentScope]string = map[ConsentScope]string{
+	BalancesConsentScope: "balances",
+	PaymentsConsentScope: "payments",
+}
+
+type ConsentStatus int8
+
+const (
+	ConsentActive ConsentStatus = iota
+	ConsentRevoked
+)
+
+// Consents are valid for 90 days at most
+const maxConsentDays = 90
+
+type Consent struct {
+	ID           string
+	ClientID     string // third-party client the access is granted to
+	Ibans        []string
+	Scopes       []ConsentScope
+	PaymentLimit float64 // total amount of payments the client can initiate, only used with the payments scope
+	Spent        float64 // amount of payments initiated so far
+	Status       ConsentStatus
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	RevokedAt    time.Time
+}
+
+// Outcome of the introspection in the manner of OAuth 2.0 token introspection, inactive consents only report that
+type ConsentIntrospection struct {
+	Active         bool      `json:"active"`
+	ClientID       string    `json:"client_id,omitempty"`
+	Scope          string    `json:"scope,omitempty"` // space separated names of the scopes
+	Ibans          []string  `json:"ibans,omitempty"`
+	RemainingLimit float64   `json:"remaining_limit,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at,omitempty"`
+}
+
+// Helper function to check if the consent grants the scope for the account
+func (c *Consent) grants(scope ConsentScope, iban string) bool {
+	granted := false
+	for _, s := range c.Scopes {
+		granted = granted || s == scope
+	}
+	if !granted {
+		return false
+	}
+	for _, i := range c.Ibans {
+		if i == iban {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryAccountRepository) IssueConsent(clientID string, ibans []string, scopes []ConsentScope, paymentLimit float64, days int) (*Consent, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the client, the accounts, the scopes and the validity are given
+	clientID = strings.TrimSpace(clientID)
+	if clientID == "" || len(ibans) == 0 || len(scopes) == 0 || days <= 0 || days > maxConsentDays {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConsentError][locale()])
+	}
+	payments := false
+	for _, scope := range scopes {
+		if _, known := consentScopeCodeToNameMap[scope]; !known {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConsentError][locale()])
+		}
+		payments = payments || scope == PaymentsConsentScope
+	}
+	// Checking if the limit is given for payments only
+	if paymentLimit < 0 || math.IsNaN(paymentLimit) || math.IsInf(paymentLimit, 0) || payments != (paymentLimit > 0) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConsentError][locale()])
+	}
+	normalized := []string{}
+	for _, iban := range ibans {
+		iban = NormalizeIban(iban)
+		acc, exists := r.Accounts.Get(iban)
+		if !exists || acc == nil || acc.Type != Ordinary {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+		}
+		normalized = append(normalized, iban)
+	}
+
+	now := clock.Now().UTC()
+	consent := &Consent{ID: NewUlid(now), ClientID: clientID, Ibans: normalized, Scopes: append([]ConsentScope{}, scopes...), PaymentLimit: paymentLimit, Status: ConsentActive, CreatedAt: now, ExpiresAt: now.AddDate(0, 0, days)}
+	r.Consents[consent.ID] = consent
+	copied := *consent
+	return &copied, nil
+}
+
+// Helper function to find a consent which is neither revoked nor expired, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) activeConsent(id string, now time.Time) (*Consent, error) {
+	consent, exists := r.Consents[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || consent == nil || consent.Status != ConsentActive || !now.Before(consent.ExpiresAt) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConsentError][locale()])
+	}
+	return consent, nil
+}
+
+func (r *InMemoryAccountRepository) IntrospectConsent(id string) (ConsentIntrospection, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	consent, err := r.activeConsent(id, clock.Now().UTC())
+	if err != nil {
+		return ConsentIntrospection{Active: false}, nil
+	}
+	scopes := []string{}
+	for _, scope := range consent.Scopes {
+		scopes = append(scopes, consentScopeCodeToNameMap[scope])
+	}
+	return ConsentIntrospection{true, consent.ClientID, strings.Join(scopes, " "), append([]string{}, consent.Ibans...), roundToCurrency(consent.PaymentLimit-consent.Spent, DefaultCurrency), consent.ExpiresAt}, nil
+}
+
+func (r *InMemoryAccountRepository) RevokeConsent(id string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	now := clock.Now().UTC()
+	consent, err := r.activeConsent(id, now)
+	if err != nil {
+		return err
+	}
+	consent.Status = ConsentRevoked
+	consent.RevokedAt = now
+	return nil
+}
+
+// Lists consents covering the account, the oldest first
+func (r *InMemoryAccountRepository) ListConsents(iban string) ([]Consent, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	consents := []Consent{}
+	for _, consent := range r.Consents {
+		for _, i := range consent.Ibans {
+			if i == iban {
+				consents = append(consents, *consent)
+				break
+			}
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool { return consents[i].CreatedAt.Before(consents[j].CreatedAt) })
+	return consents, nil
+}
+
+// Returns the account of the consent with the balances scope to its client
+func (r *InMemoryAccountRepository) RetrieveAccountByConsent(id, clientID, iban string) (*AccountDetails, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	consent, err := r.activeConsent(id, clock.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	// Checking if the consent is granted to the client for the account
+	if !strings.EqualFold(consent.ClientID, strings.TrimSpace(clientID)) || !consent.grants(BalancesConsentScope, iban) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	details := acc.Details()
+	return &details, nil
+}
+
+// Initiates the payment from the account of the consent with the payments scope, returns the ID of the transfer
+// like TransferMoneyWithDetails does, payments waiting for approval count towards the limit as well
+func (r *InMemoryAccountRepository) TransferMoneyByConsent(id, clientID, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	sender = NormalizeIban(sender)
+	consent, err := r.activeConsent(id, clock.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	// Checking if the consent is granted to the client for the account
+	if !strings.EqualFold(consent.ClientID, strings.TrimSpace(clientID)) || !consent.grants(PaymentsConsentScope, sender) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	// Checking if the payment fits into the remaining limit
+	if amount > 0 && consent.Spent+amount > consent.PaymentLimit+reconciliationTolerance {
+		return "", fmt.Errorf(errorCodesToMessagesMap[ConsentLimitExceededError][locale()])
+	}
+	transferID, err := r.submitTransfer(sender, recipient, amount, details)
+	if err != nil {
+		return transferID, err
+	}
+	consent.Spent += amount
+	return transferID, nil
+}
+
+// Helper function to check if the principal of the service is the client the consent is granted to, the consent itself
+// is checked by the repository, clients act for themselves and never anonymously
+func (s *AccountService) authorizeClient(permission Permission, clientID string) error {
+	if s.principal.ID == "" {
+		return nil
+	}
+	if !rolePermissions[s.principal.Role][permission] || s.principal == anonymousPrincipal || !strings.EqualFold(s.principal.ID, strings.TrimSpace(clientID)) {
+		return fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	return nil
+}
+
+// Account holders grant consents for their own accounts only, payments can be granted only by those who may transfer
+// money from the accounts themselves
+func (s *AccountService) IssueConsent(clientID string, ibans []string, scopes []ConsentScope, paymentLimit float64, days int) (*Consent, error) {
+	permission := ReadPermission
+	for _, scope := range scopes {
+		if scope == PaymentsConsentScope {
+			permission = TransferPermission
+		}
+	}
+	if err := s.authorize(permission, ibans...); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.IssueConsent(clientID, ibans, scopes, paymentLimit, days)
+}
+
+func (s *AccountService) IntrospectConsent(id string) (ConsentIntrospection, error) {
+	return s.accountRepoImpl.IntrospectConsent(id)
+}
+
+func (s *AccountService) RevokeConsent(id string) error {
+	introspection, err := s.accountRepoImpl.IntrospectConsent(id)
+	if err != nil {
+		return err
+	}
+	// Inactive consents do not name their accounts, so only staff and the system are told they cannot be revoked
+	if err := s.authorize(ReadPermission, introspection.Ibans...); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RevokeConsent(id)
+}
+
+func (s *AccountService) ListConsents(iban string) ([]Consent, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListConsents(iban)
+}
+
+func (s *AccountService) RetrieveAccountByConsent(id, clientID, iban string) (*AccountDetails, error) {
+	if err := s.authorizeClient(ReadPermission, clientID); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.RetrieveAccountByConsent(id, clientID, iban)
+}
+
+func (s *AccountService) TransferMoneyByConsent(id, clientID, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	if err := s.authorizeClient(TransferPermission, clientID); err != nil {
+		return "", err
+	}
+	transferID, err := s.accountRepoImpl.TransferMoneyByConsent(id, clientID, sender, recipient, amount, s.initiated(details))
+	return s.auditedID(TransferAction, sender, recipient, amount, transferID, err)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Clients read balances and initiate payments within the accounts, scopes and limit of the consent
+func TestConsents(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccountWithInitialDeposit(500)
+	other, _ := service.OpenAccountWithInitialDeposit(500)
+	recipient, _ := service.OpenAccount()
+	service.VerifyKyc(acc.Iban)
+
+	if _, err := service.IssueConsent("fintech", []string{acc.Iban}, []ConsentScope{PaymentsConsentScope}, 0, 30); err == nil {
+		t.Errorf("Payments consent without a limit failed to fail")
+	}
+	if _, err := service.IssueConsent("fintech", []string{acc.Iban}, []ConsentScope{BalancesConsentScope}, 0, 365); err == nil {
+		t.Errorf("Consent exceeding the maximum validity failed to fail")
+	}
+	if _, err := service.IssueConsent("fintech", []string{emission}, []ConsentScope{BalancesConsentScope}, 0, 30); err == nil {
+		t.Errorf("Consent for the emission account failed to fail")
+	}
+	consent, err := service.IssueConsent("fintech", []string{acc.Iban}, []ConsentScope{BalancesConsentScope, PaymentsConsentScope}, 100, 30)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if details, err := service.RetrieveAccountByConsent(consent.ID, "fintech", acc.Iban); err != nil || details.Balance != 500 {
+		t.Errorf("Expected balance of 500, got %+v (%v)", details, err)
+	}
+	if _, err := service.RetrieveAccountByConsent(consent.ID, "fintech", other.Iban); err == nil {
+		t.Errorf("Reading an account outside the consent failed to fail")
+	}
+	if _, err := service.RetrieveAccountByConsent(consent.ID, "another", acc.Iban); err == nil {
+		t.Errorf("Reading by another client failed to fail")
+	}
+	if _, err := service.TransferMoneyByConsent(consent.ID, "fintech", acc.Iban, recipient.Iban, 60, TransferDetails{}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoneyByConsent(consent.ID, "fintech", acc.Iban, recipient.Iban, 50, TransferDetails{}); err == nil {
+		t.Errorf("Payment exceeding the limit failed to fail")
+	}
+	// Failed payments do not count towards the limit
+	if _, err := service.TransferMoneyByConsent(consent.ID, "fintech", acc.Iban, "BY00 NONE 0000 0000 0000 0000 0000", 40, TransferDetails{}); err == nil {
+		t.Errorf("Payment to a nonexistent account failed to fail")
+	}
+	introspection, _ := service.IntrospectConsent(consent.ID)
+	if !introspection.Active || introspection.ClientID != "fintech" || introspection.Scope != "balances payments" || introspection.RemainingLimit != 40 {
+		t.Errorf("Unexpected introspection: %+v", introspection)
+	}
+
+	if err := service.RevokeConsent(consent.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if introspection, _ := service.IntrospectConsent(consent.ID); introspection.Active || introspection.ClientID != "" {
+		t.Errorf("Expected revoked consent to be inactive, got %+v", introspection)
+	}
+	if _, err := service.TransferMoneyByConsent(consent.ID, "fintech", acc.Iban, recipient.Iban, 10, TransferDetails{}); err == nil {
+		t.Errorf("Payment by a revoked consent failed to fail")
+	}
+	if consents, _ := service.ListConsents(acc.Iban); len(consents) != 1 || consents[0].Status != ConsentRevoked || consents[0].Spent != 60 {
+		t.Errorf("Unexpected consents: %+v", consents)
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 60 {
+		t.Errorf("Expected recipient balance of 60, got %v", details.Balance)
+	}
+}
+
+// Customers grant consents for their own accounts only
+func TestConsentOwnership(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	customer, _ := service.CreateCustomer("Ivan Ivanov", "ivan@example.com", "+375291234567", "Minsk")
+	own, _ := service.OpenAccountForCustomer(customer.ID)
+	other, _ := service.OpenAccount()
+	holder := service.As(Principal{ID: "ivan", Role: CustomerRole, CustomerID: customer.ID})
+
+	if _, err := holder.IssueConsent("fintech", []string{own.Iban, other.Iban}, []ConsentScope{BalancesConsentScope}, 0, 30); err == nil {
+		t.Errorf("Consent for another's account failed to fail")
+	}
+	consent, err := holder.IssueConsent("fintech", []string{own.Iban}, []ConsentScope{BalancesConsentScope}, 0, 30)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Payments are granted only by those who may transfer money from the accounts
+	auditor := service.As(Principal{ID: "audit", Role: AuditorRole})
+	if _, err := auditor.IssueConsent("fintech", []string{own.Iban}, []ConsentScope{PaymentsConsentScope}, 100, 30); err == nil {
+		t.Errorf("Payments consent issued by an auditor failed to fail")
+	}
+	payments, err := holder.IssueConsent("fintech", []string{own.Iban}, []ConsentScope{PaymentsConsentScope}, 100, 30)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Clients use consents granted to themselves only
+	service.EmitMoney(100)
+	service.TransferMoney(emission, own.Iban, 100)
+	for _, principal := range []Principal{anonymousPrincipal, {ID: "another", Role: CustomerRole}, {ID: "fintech", Role: AuditorRole}} {
+		if _, err := service.As(principal).TransferMoneyByConsent(payments.ID, "fintech", own.Iban, other.Iban, 10, TransferDetails{}); err == nil {
+			t.Errorf("Payment by consent as %+v failed to fail", principal)
+		}
+	}
+	if _, err := service.As(anonymousPrincipal).RetrieveAccountByConsent(consent.ID, "fintech", own.Iban); err == nil {
+		t.Errorf("Anonymous read by consent failed to fail")
+	}
+	client := service.As(Principal{ID: "fintech", Role: CustomerRole})
+	if _, err := client.TransferMoneyByConsent(payments.ID, "fintech", own.Iban, other.Iban, 10, TransferDetails{}); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	// Revoking requires access to the accounts even if the consent is no longer active
+	service.RevokeConsent(payments.ID)
+	for _, id := range []string{consent.ID, payments.ID} {
+		if err := service.As(anonymousPrincipal).RevokeConsent(id); err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[AccessDeniedError][locale()]) {
+			t.Errorf("Anonymous revocation failed to be denied, got %v", err)
+		}
+	}
+	if err := holder.RevokeConsent(consent.ID); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining correspondent banking with peer banks: the nostro account mirrors our money held at the peer,
+// the vostro account holds the money of the peer with us, transfers crossing the banks move both of them
+// Nostro accounts mirror balances kept elsewhere against the nostro ledger account, so they are not part of the money supply
+const NostroLedgerAccount = "NOSTRO" // counterpart of money mirrored by nostro accounts
+
+type Correspondent struct {
+	Bank   string   // bank code of the peer
+	Nostro *Account // our account at the peer, debited by outbound transfers to the peer
+	Vostro *Account // account of the peer with us, debited by inbound transfers from the peer
+}
+
+// Outcome of comparing our nostro balance with the vostro balance reported by the peer
+// Transfers awaiting acknowledgement may have been debited by the peer but not yet by us, so they may explain the difference up to their amount
+type NostroReconciliation struct {
+	Bank            string    `json:"bank"`
+	Currency        string    `json:"currency"`
+	NostroBalance   float64   `json:"nostro_balance"`
+	ReportedBalance float64   `json:"reported_balance"`
+	InTransit       float64   `json:"in_transit"`
+	Difference      float64   `json:"difference"` // nostro balance less reported balance
+	Matched         bool      `json:"matched"`
+	ReconciledAt    time.Time `json:"reconciled_at"`
+}
+
+// Helper function to find the correspondent record of the peer creating it if needed, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) correspondent(bank string) *Correspondent {
+	bank = strings.ToUpper(strings.TrimSpace(bank))
+	if r.Correspondents[bank] == nil {
+		r.Correspondents[bank] = &Correspondent{Bank: bank}
+	}
+	return r.Correspondents[bank]
+}
+
+// Opens the mirror of our account at the peer with the balance the peer holds for us, in the default currency
+func (r *InMemoryAccountRepository) OpenNostroAccount(bank, iban string, balance float64) (*Account, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the nostro account has not been opened yet and the opening balance is valid
+	if bank = strings.ToUpper(strings.TrimSpace(bank)); bank == "" || (r.Correspondents[bank] != nil && r.Correspondents[bank].Nostro != nil) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CorrespondentAccountExistsError][locale()])
+	}
+	if balance < 0 || math.IsNaN(balance) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	acc, err := r.openAccount(DefaultCurrency, iban)
+	if err != nil {
+		return nil, err
+	}
+	acc.Type = NostroAccount
+	acc.Kyc = KycVerified
+	r.Accounts.Put(acc)
+	balance = roundToCurrency(balance, acc.Currency)
+	txCount := len(r.Transactions)
+	tx := &Transaction{Type: InterbankTransaction, Recipient: acc.Iban, Amount: balance, Currency: acc.Currency, Reference: bank}
+	if err := r.post(tx, debitLedgerLine(NostroLedgerAccount, acc.Currency, balance), creditLine(acc, balance)); err != nil {
+		r.rollbackTo(txCount)
+		r.Accounts.Delete(acc.Iban)
+		return nil, err
+	}
+	r.correspondent(bank).Nostro = acc
+	return acc, nil
+}
+
+// Opens the account holding the money of the peer with us, the peer funds it like any other account
+func (r *InMemoryAccountRepository) OpenVostroAccount(bank, iban string) (*Account, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the vostro account has not been opened yet
+	if bank = strings.ToUpper(strings.TrimSpace(bank)); bank == "" || (r.Correspondents[bank] != nil && r.Correspondents[bank].Vostro != nil) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CorrespondentAccountExistsError][locale()])
+	}
+	acc, err := r.openAccount(DefaultCurrency, iban)
+	if err != nil {
+		return nil, err
+	}
+	acc.Type = VostroAccount
+	acc.Kyc = KycVerified
+	r.Accounts.Put(acc)
+	r.correspondent(bank).Vostro = acc
+	return acc, nil
+}
+
+// Returns the balance of the vostro account of the peer, i.e. for the peer to reconcile its nostro account
+func (r *InMemoryAccountRepository) RetrieveVostroBalance(bank string) (float64, string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	c, exists := r.Correspondents[strings.ToUpper(strings.TrimSpace(bank))]
+	if !exists || c == nil || c.Vostro == nil {
+		return 0, "", fmt.Errorf(errorCodesToMessagesMap[CorrespondentAccountDoesNotExistError][locale()])
+	}
+	return c.Vostro.Balance, c.Vostro.Currency, nil
+}
+
+// Compares our nostro balance with the balance of our vostro account reported by the peer
+func (r *InMemoryAccountRepository) ReconcileNostro(bank string, reportedBalance float64) (*NostroReconciliation, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	bank = strings.ToUpper(strings.TrimSpace(bank))
+	c, exists := r.Correspondents[bank]
+	if !exists || c == nil || c.Nostro == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CorrespondentAccountDoesNotExistError][locale()])
+	}
+	res := &NostroReconciliation{Bank: bank, Currency: c.Nostro.Currency, NostroBalance: c.Nostro.Balance, ReportedBalance: reportedBalance, ReconciledAt: clock.Now().UTC()}
+	for _, transfer := range r.InterbankTransfers {
+		if transfer.Direction == OutboundInterbankTransfer && transfer.Status == InterbankPending && transfer.PeerBank == bank && transfer.Currency == res.Currency {
+			res.InTransit = roundToCurrency(res.InTransit+transfer.Amount, res.Currency)
+		}
+	}
+	res.Difference = roundToCurrency(res.NostroBalance-reportedBalance, res.Currency)
+	res.Matched = res.Difference == 0
+	return res, nil
+}
+
+// Helper functions to build the lines mirroring the transfer on the correspondent accounts of the peer,
+// expect the repository mutex to be held by the caller, no lines are built if the peer has no such account
+func (r *InMemoryAccountRepository) nostroLines(bank, currency string, amount float64) []LedgerLine {
+	c, exists := r.Correspondents[bank]
+	if !exists || c == nil || c.Nostro == nil || c.Nostro.Currency != currency {
+		return nil
+	}
+	return []LedgerLine{debitLine(c.Nostro, amount), creditLedgerLine(NostroLedgerAccount, currency, amount)}
+}
+
+func (r *InMemoryAccountRepository) vostroAccount(bank, currency string) *Account {
+	c, exists := r.Correspondents[bank]
+	if !exists || c == nil || c.Vostro == nil || c.Vostro.Currency != currency {
+		return nil
+	}
+	return c.Vostro
+}
+
+// Fetches the vostro balance the peer keeps for us and compares it with our nostro balance
+func (g *InterbankGateway) ReconcileNostro(bank string) (*NostroReconciliation, error) {
+	bank = strings.ToUpper(strings.TrimSpace(bank))
+	g.Mutex.Lock()
+	baseUrl, isPeer := g.Peers[bank]
+	g.Mutex.Unlock()
+	if !isPeer {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[UnknownPeerBankError][locale()])
+	}
+	req, err := http.NewRequest(http.MethodGet, baseUrl+interbankVostroPath+"?bank="+url.QueryEscape(g.BankCode), nil)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InterbankDeliveryError][locale()])
+	}
+	g.authenticate(req, bank)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InterbankDeliveryError][locale()])
+	}
+	defer resp.Body.Close()
+	var report vostroBalanceReport
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&report) != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InterbankDeliveryError][locale()])
+	}
+	return g.service.ReconcileNostro(bank, report.Balance)
+}
+
+// Path the peers report vostro balances at, relative to their base URLs
+const interbankVostroPath = "/interbank/vostro"
+
+type vostroBalanceReport struct {
+	Bank     string  `json:"bank"`
+	Balance  float64 `json:"balance"`
+	Currency string  `json:"currency"`
+}
+
+// Helper function to serve vostro balances of the peers
+func (g *InterbankGateway) serveVostroBalance(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	bank := strings.ToUpper(req.URL.Query().Get("bank"))
+	balance, currency, err := g.service.RetrieveVostroBalance(bank)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vostroBalanceReport{bank, balance, currency})
+}
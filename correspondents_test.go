@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+)
+
+// Transfers crossing the banks move the nostro account of the sending bank and the vostro account at the receiving bank
+func TestCorrespondentAccounts(t *testing.T) {
+	alfa, alfaGateway, alfaServer, sender := startInterbankInstance(t, "ALFA", 100)
+	defer alfaServer.Close()
+	beta, betaGateway, betaServer, _ := startInterbankInstance(t, "BETA", 0)
+	defer betaServer.Close()
+	alfaGateway.AddPeer("BETA", betaServer.URL)
+	betaGateway.AddPeer("ALFA", alfaServer.URL)
+	alfaGateway.SetPeerSecret("BETA", "shared")
+	betaGateway.SetPeerSecret("ALFA", "shared")
+	recipient, _ := beta.OpenAccountWithIban(ibanWithBban("BETA00000000000000000042"))
+
+	nostro, err := alfa.OpenNostroAccount("BETA", "", 500)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := alfa.OpenNostroAccount("beta", "", 500); err == nil {
+		t.Errorf("Opening second nostro account at the same bank failed to fail")
+	}
+	vostro, err := beta.OpenVostroAccount("ALFA", "")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Moving money to or from the nostro mirror is not allowed
+	if _, err := alfa.TransferMoney(sender.Iban, nostro.Iban, 10); err == nil {
+		t.Errorf("Transfer to nostro account failed to fail")
+	}
+
+	// Vostro account is not funded yet, so the transfer is rejected and refunded
+	rejected, err := alfaGateway.SendTransfer(sender.Iban, recipient.Iban, 40, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := alfa.GetTransferStatus(rejected); status != TransferReversed {
+		t.Errorf("Expected transfer exceeding vostro balance to be reversed, got %v", status)
+	}
+
+	emission, _ := beta.RetrieveEmissionAccountIban()
+	if err := beta.EmitMoney(500); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := beta.TransferMoney(emission, vostro.Iban, 500); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	id, err := alfaGateway.SendTransfer(sender.Iban, recipient.Iban, 40, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := alfa.GetTransferStatus(id); status != TransferSettled {
+		t.Fatalf("Expected transfer to be settled, got %v", status)
+	}
+	if details, _ := alfa.RetrieveAccount(nostro.Iban); details.Balance != 460 {
+		t.Errorf("Expected nostro balance of 460, got %v", details.Balance)
+	}
+	if balance, _, _ := beta.RetrieveVostroBalance("ALFA"); balance != 460 {
+		t.Errorf("Expected vostro balance of 460, got %v", balance)
+	}
+	if details, _ := beta.RetrieveAccount(recipient.Iban); details.Balance != 40 {
+		t.Errorf("Expected recipient balance of 40, got %v", details.Balance)
+	}
+
+	res, err := alfaGateway.ReconcileNostro("BETA")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !res.Matched || res.NostroBalance != 460 || res.ReportedBalance != 460 || res.InTransit != 0 {
+		t.Errorf("Expected nostro to match reported vostro balance, got %+v", res)
+	}
+	if res, _ := alfa.ReconcileNostro("BETA", 450); res.Matched || res.Difference != 10 {
+		t.Errorf("Expected difference of 10, got %+v", res)
+	}
+	if _, err := betaGateway.ReconcileNostro("ALFA"); err == nil {
+		t.Errorf("Reconciling nonexistent nostro account failed to fail")
+	}
+
+	// Money received through the vostro account stays in circulation at the receiving bank
+	for _, service := range []*AccountService{alfa, beta} {
+		if _, err := service.VerifyInvariants(); err != nil {
+			t.Errorf("Error: %v", err)
+		}
+	}
+	if balance := beta.RetrieveLedgerBalance(InterbankLedgerAccount, DefaultCurrency); balance != 0 {
+		t.Errorf("Expected nothing received through the interbank ledger account, got %v", balance)
+	}
+}
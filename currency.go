@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining ISO 4217 currency registry so that rounding and formatting respect the number of minor units of each currency
+type Currency struct {
+	Code       string
+	Numeric    int
+	MinorUnits int // number of digits after the decimal separator, i.e. 2 for BYN (kopecks), 0 for JPY, 3 for BHD (fils)
+	Name       string
+}
+
+// Registry can be augmented with other currencies or loaded from the official ISO 4217 list
+var currencyRegistry map[string]Currency = map[string]Currency{
+	"BYN": {"BYN", 933, 2, "Belarusian ruble"},
+	"RUB": {"RUB", 643, 2, "Russian ruble"},
+	"USD": {"USD", 840, 2, "US dollar"},
+	"EUR": {"EUR", 978, 2, "Euro"},
+	"GBP": {"GBP", 826, 2, "Pound sterling"},
+	"CHF": {"CHF", 756, 2, "Swiss franc"},
+	"PLN": {"PLN", 985, 2, "Polish zloty"},
+	"UAH": {"UAH", 980, 2, "Ukrainian hryvnia"},
+	"CNY": {"CNY", 156, 2, "Renminbi"},
+	"JPY": {"JPY", 392, 0, "Japanese yen"},
+	"KRW": {"KRW", 410, 0, "South Korean won"},
+	"BHD": {"BHD", 48, 3, "Bahraini dinar"},
+	"KWD": {"KWD", 414, 3, "Kuwaiti dinar"},
+	"OMR": {"OMR", 512, 3, "Omani rial"},
+	"JOD": {"JOD", 400, 3, "Jordanian dinar"},
+	"TND": {"TND", 788, 3, "Tunisian dinar"},
+}
+
+// Minor units assumed for currencies missing from the registry
+const defaultMinorUnits = 2
+
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := currencyRegistry[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+func IsValidCurrency(code string) bool {
+	_, ok := LookupCurrency(code)
+	return ok
+}
+
+func minorUnitsOf(code string) int {
+	if c, ok := LookupCurrency(code); ok {
+		return c.MinorUnits
+	}
+	return defaultMinorUnits
+}
+
+// Rounds the amount to the smallest unit of the given currency
+func roundToCurrency(amount float64, code string) float64 {
+	factor := math.Pow10(minorUnitsOf(code))
+	return math.Round(amount*factor) / factor
+}
+
+// Rounds the amount to the smallest unit of the given currency and returns the residue below it
+func roundAndExtractFractionsInCurrency(amount float64, code string) (float64, float64) {
+	rounded := roundToCurrency(amount, code)
+	fractions := amount - rounded
+	return rounded, fractions
+}
+
+// Truncates the amount towards zero to the smallest unit of the given currency
+func truncToCurrency(amount float64, code string) float64 {
+	factor := math.Pow10(minorUnitsOf(code))
+	return math.Trunc(amount*factor) / factor
+}
+
+// Formats the amount with as many decimals as the currency has minor units, i.e. "10.50 BYN", "1050 JPY", "10.500 BHD"
+func FormatAmount(amount float64, code string) string {
+	return fmt.Sprintf("%.*f %s", minorUnitsOf(code), roundToCurrency(amount, code), strings.ToUpper(code))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+// Round and format amounts according to the minor units of each currency
+func TestCurrencyMinorUnits(t *testing.T) {
+	cases := []struct {
+		amount    float64
+		currency  string
+		rounded   float64
+		formatted string
+	}{
+		{10.456, "BYN", 10.46, "10.46 BYN"},
+		{10.456, "jpy", 10, "10 JPY"},
+		{10.4567, "BHD", 10.457, "10.457 BHD"},
+		{10.456, "XXX", 10.46, "10.46 XXX"},
+	}
+	for _, c := range cases {
+		if rounded := roundToCurrency(c.amount, c.currency); rounded != c.rounded {
+			t.Errorf("Rounding %f %s: expected %f, got %f", c.amount, c.currency, c.rounded, rounded)
+		}
+		if formatted := FormatAmount(c.amount, c.currency); formatted != c.formatted {
+			t.Errorf("Formatting %f %s: expected %s, got %s", c.amount, c.currency, c.formatted, formatted)
+		}
+	}
+}
+
+// Keep balances of accounts in currencies without minor units whole
+func TestAccountRoundingInCurrency(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.RateProvider = NewFixedRateProvider(map[string]float64{"JPY/BYN": 0.0217})
+	service := NewAccountService(inMemImpl)
+
+	if _, err := service.OpenAccountInCurrency("ABC"); err == nil {
+		t.Errorf("Opening an account in unknown currency failed to fail")
+	}
+	acc, err := service.OpenAccountInCurrency("JPY")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.EmitMoney(10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.ConvertAndTransfer(inMemImpl.EmissionAccount.Iban, acc.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// 10 / 0.0217 = 460.829... yen
+	if converted, _ := service.GetAccount(acc.Iban); converted.Balance != 461 || converted.Fractions >= 0 {
+		t.Errorf("Unexpected JPY balance %f and fractions %f", converted.Balance, converted.Fractions)
+	}
+}
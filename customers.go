@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining customer (account holder) entity
+type Customer struct {
+	ID          string
+	Name        string
+	Email       string
+	Phone       string
+	Address     string
+	PrimaryIban string // account used by default for the customer, i.e. to receive payments addressed to the customer
+	CreatedAt   time.Time
+	ErasedAt    time.Time // set once name and contact details are erased on request of the customer, see EraseCustomer
+	// can be augmented with identity documents, date of birth and so on
+}
+
+// Helper function to validate customer details, name is mandatory while contact details are optional
+func isValidCustomerDetails(name, email, phone string) bool {
+	if strings.TrimSpace(name) == "" {
+		return false
+	}
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return false
+		}
+	}
+	for _, char := range phone {
+		if !(char >= '0' && char <= '9') && !strings.ContainsRune("+-() ", char) {
+			return false
+		}
+	}
+	return true
+}
+
+// --------------------------------------------------------
+// Defining implementation agnostic interface that contains methods to manipulate customers
+type CustomerRepository interface {
+	CreateCustomer(name, email, phone, address string) (*Customer, error)
+	RetrieveCustomer(id string) (Customer, error)
+	ListCustomers() ([]Customer, error)
+	SetPrimaryAccount(id, iban string) error
+	ExportCustomers(writer io.Writer) error
+	EraseCustomer(id string) error
+}
+
+// --------------------------------------------------------
+// Defining in-memory implementation of customer repository interface methods
+type InMemoryCustomerRepository struct {
+	Customers map[string]*Customer
+	Sequence  uint64          // used to generate sequential customer IDs
+	Encryptor *FieldEncryptor // encrypts personal details of exported customers, exports are written in plaintext if not set
+	Mutex     sync.Mutex
+}
+
+func NewInMemoryCustomerRepository() *InMemoryCustomerRepository {
+	return &InMemoryCustomerRepository{Customers: map[string]*Customer{}}
+}
+
+func (r *InMemoryCustomerRepository) CreateCustomer(name, email, phone, address string) (*Customer, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	name = strings.TrimSpace(name)
+	email = strings.TrimSpace(email)
+	phone = strings.TrimSpace(phone)
+	// Checking if customer details are valid
+	if !isValidCustomerDetails(name, email, phone) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidCustomerDetailsError][locale()])
+	}
+
+	r.Sequence++
+	c := &Customer{ID: fmt.Sprintf("CUST-%08d", r.Sequence), Name: name, Email: email, Phone: phone, Address: strings.TrimSpace(address), CreatedAt: clock.Now().UTC()}
+	r.Customers[c.ID] = c
+	copied := *c
+	return &copied, nil
+}
+
+func (r *InMemoryCustomerRepository) RetrieveCustomer(id string) (Customer, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if customer with the given ID exists
+	c, exists := r.Customers[strings.TrimSpace(id)]
+	if !exists || c == nil {
+		return Customer{}, fmt.Errorf(errorCodesToMessagesMap[CustomerDoesNotExistError][locale()])
+	}
+	return *c, nil
+}
+
+func (r *InMemoryCustomerRepository) ListCustomers() ([]Customer, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	customers := make([]Customer, 0, len(r.Customers))
+	for _, c := range r.Customers {
+		customers = append(customers, *c)
+	}
+	return customers, nil
+}
+
+// Linking account to the customer is verified by the service layer, repository only stores the designation
+func (r *InMemoryCustomerRepository) SetPrimaryAccount(id, iban string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if customer with the given ID exists
+	c, exists := r.Customers[strings.TrimSpace(id)]
+	if !exists || c == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[CustomerDoesNotExistError][locale()])
+	}
+	c.PrimaryIban = NormalizeIban(iban)
+	return nil
+}
+
+// Writes all customers to the writer as newline-delimited JSON with one Customer object per line, oldest customers first
+// Name and contact details are encrypted if the encryptor of the repository is set
+func (r *InMemoryCustomerRepository) ExportCustomers(writer io.Writer) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	customers := make([]Customer, 0, len(r.Customers))
+	for _, c := range r.Customers {
+		customers = append(customers, *c)
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+	encoder := json.NewEncoder(writer)
+	for _, c := range customers {
+		for _, field := range []*string{&c.Name, &c.Email, &c.Phone, &c.Address} {
+			var err error
+			if *field, err = r.Encryptor.encryptIfSet(*field); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --------------------------------------------------------
+// Defining in-memory implementation of linking accounts to customers
+func (r *InMemoryAccountRepository) OpenAccountForCustomer(customerID string) (*Account, error) {
+	// Checking if customer ID is set, existence of the customer is checked by the service layer
+	if strings.TrimSpace(customerID) == "" {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CustomerDoesNotExistError][locale()])
+	}
+	return r.OpenAccountWithOptions(AccountOptions{CustomerID: customerID})
+}
+
+// Links an existing ordinary account to the customer, accounts already held by another customer cannot be reassigned
+func (r *InMemoryAccountRepository) AttachAccountToCustomer(iban, customerID string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	customerID = strings.TrimSpace(customerID)
+
+	// Checking if customer ID is set, existence of the customer is checked by the service layer
+	if customerID == "" {
+		return fmt.Errorf(errorCodesToMessagesMap[CustomerDoesNotExistError][locale()])
+	}
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Ensuring that we indeed got the correct account object
+	if acc.Iban != iban {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if the account is an ordinary one, special accounts do not belong to customers
+	if acc.Type != Ordinary {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if the account is not held by another customer
+	if acc.CustomerID != "" && acc.CustomerID != customerID {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountCustomerMismatchError][locale()])
+	}
+
+	acc.CustomerID = customerID
+	return nil
+}
+
+// Moves money between two accounts of the same customer
+// Validation is relaxed compared to TransferMoney: the recipient account may be blocked since money does not leave the customer
+func (r *InMemoryAccountRepository) InternalMoveMoney(customerID, sender, recipient string, amount float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	sender = NormalizeIban(sender)
+	recipient = NormalizeIban(recipient)
+	customerID = strings.TrimSpace(customerID)
+
+	// Checking if both accounts exist
+	sAcc, sExists := r.Accounts.Get(sender)
+	rAcc, rExists := r.Accounts.Get(recipient)
+	if !sExists || sAcc == nil || !rExists || rAcc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Ensuring that we indeed got the correct account objects
+	if sAcc.Iban != sender || rAcc.Iban != recipient {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if both accounts are held by the given customer
+	if customerID == "" || sAcc.CustomerID != customerID || rAcc.CustomerID != customerID {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountCustomerMismatchError][locale()])
+	}
+	// Checking if sender account is not blocked
+	if sAcc.Status == Blocked {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if neither of the accounts is closed
+	if sAcc.Status == Closed || rAcc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if debits from sender account and credits to recipient account are not frozen
+	if err := checkDebitRestriction(sAcc); err != nil {
+		return err
+	}
+	if err := checkCreditRestriction(rAcc); err != nil {
+		return err
+	}
+	// Checking if money amount to move is not negative
+	if amount < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if neither of the accounts is a term deposit, a loan or a nostro account
+	if sAcc.Type == TermDepositAccount {
+		return fmt.Errorf(errorCodesToMessagesMap[TermDepositLockedError][locale()])
+	}
+	if sAcc.Type == LoanAccount || rAcc.Type == LoanAccount || rAcc.Type == TermDepositAccount || sAcc.Type == NostroAccount || rAcc.Type == NostroAccount {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if both accounts hold money in the same currency
+	if sAcc.Currency != rAcc.Currency {
+		return fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+	// Checking if sender has sufficient balance to move the amount
+	if r, _ := roundAndExtractFractionsInCurrency(amount, sAcc.Currency); sAcc.AvailableBalance() < r {
+		return insufficientFundsError(sAcc)
+	}
+
+	// Checking if sender can pay the fee for the move
+	fee, _, err := r.feeFor(sAcc, InternalMoveTransaction, amount, FeeBearerOur)
+	if err != nil {
+		return err
+	}
+	// Checking if both balances stay within the floor and the ceiling of the accounts
+	if err := checkBalanceFloor(sAcc, roundToCurrency(amount, sAcc.Currency)+fee); err != nil {
+		return err
+	}
+	if err := checkBalanceCeiling(rAcc, roundToCurrency(amount, rAcc.Currency)); err != nil {
+		return err
+	}
+
+	txCount := len(r.Transactions)
+	feeTx, err := r.chargeFee(sAcc, fee)
+	if err != nil {
+		return err
+	}
+	tx := &Transaction{Type: InternalMoveTransaction, Sender: sender, Recipient: recipient, Amount: amount, Currency: sAcc.Currency}
+	if err := r.post(tx, transferLines(sAcc, rAcc, amount)...); err != nil {
+		r.rollbackTo(txCount)
+		return err
+	}
+	linkFee(feeTx, tx)
+	return nil
+}
+
+// --------------------------------------------------------
+// Defining service methods combining customer and account repositories
+func (s *AccountService) CreateCustomer(name, email, phone, address string) (*Customer, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return nil, err
+	}
+	return s.customerRepoImpl.CreateCustomer(name, email, phone, address)
+}
+
+func (s *AccountService) RetrieveCustomer(id string) (Customer, error) {
+	if err := s.authorizeCustomer(ReadPermission, id); err != nil {
+		return Customer{}, err
+	}
+	return s.customerRepoImpl.RetrieveCustomer(id)
+}
+
+func (s *AccountService) ListCustomers() ([]Customer, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.customerRepoImpl.ListCustomers()
+}
+
+func (s *AccountService) ExportCustomers(writer io.Writer) error {
+	if err := s.authorize(ReadPermission); err != nil {
+		return err
+	}
+	return s.customerRepoImpl.ExportCustomers(writer)
+}
+
+// The first account of the customer becomes their primary account
+func (s *AccountService) OpenAccountForCustomer(customerID string) (*Account, error) {
+	if strings.TrimSpace(customerID) == "" {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CustomerDoesNotExistError][locale()])
+	}
+	return s.OpenAccountWithOptions(AccountOptions{CustomerID: customerID})
+}
+
+func (s *AccountService) AttachAccountToCustomer(iban, customerID string) error {
+	if err := s.authorize(OpenPermission); err != nil {
+		return err
+	}
+	c, err := s.customerRepoImpl.RetrieveCustomer(customerID)
+	if err != nil {
+		return err
+	}
+	if err := s.accountRepoImpl.AttachAccountToCustomer(iban, c.ID); err != nil {
+		return err
+	}
+	if c.PrimaryIban == "" {
+		return s.customerRepoImpl.SetPrimaryAccount(c.ID, iban)
+	}
+	return nil
+}
+
+func (s *AccountService) SetPrimaryAccount(customerID, iban string) error {
+	if err := s.authorizeCustomer(OpenPermission, customerID); err != nil {
+		return err
+	}
+	c, err := s.customerRepoImpl.RetrieveCustomer(customerID)
+	if err != nil {
+		return err
+	}
+	// Checking if the account is held by the customer
+	acc, err := s.accountRepoImpl.GetAccount(iban)
+	if err != nil {
+		return err
+	}
+	if acc.CustomerID != c.ID {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountCustomerMismatchError][locale()])
+	}
+	return s.customerRepoImpl.SetPrimaryAccount(c.ID, acc.Iban)
+}
+
+// Helper function to find out the name and the address of the account holder for payment messages,
+// falls back to the account type for accounts without a customer
+func (s *AccountService) accountHolder(iban string) (string, string) {
+	acc, err := s.accountRepoImpl.GetAccount(iban)
+	if err != nil {
+		return "", ""
+	}
+	if acc.CustomerID != "" {
+		if c, err := s.customerRepoImpl.RetrieveCustomer(acc.CustomerID); err == nil {
+			return c.Name, c.Address
+		}
+	}
+	return accountTypeCodeToNameMap[acc.Type][English], ""
+}
+
+func (s *AccountService) RetrievePrimaryAccount(customerID string) (*AccountDetails, error) {
+	if err := s.authorizeCustomer(ReadPermission, customerID); err != nil {
+		return nil, err
+	}
+	c, err := s.customerRepoImpl.RetrieveCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+	if c.PrimaryIban == "" {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	return s.accountRepoImpl.RetrieveAccount(c.PrimaryIban)
+}
+
+// Moves money between two accounts of the same customer, recorded as an internal move rather than a transfer
+func (s *AccountService) InternalMoveMoney(customerID, sender, recipient string, amount float64) error {
+	if err := s.authorizeCustomer(TransferPermission, customerID); err != nil {
+		return err
+	}
+	c, err := s.customerRepoImpl.RetrieveCustomer(customerID)
+	if err != nil {
+		return err
+	}
+	return s.accountRepoImpl.InternalMoveMoney(c.ID, sender, recipient, amount)
+}
+
+func (s *AccountService) ListAccountsByCustomer(customerID string) ([]AccountDetails, error) {
+	if err := s.authorizeCustomer(ReadPermission, customerID); err != nil {
+		return nil, err
+	}
+	c, err := s.customerRepoImpl.RetrieveCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.FindAccounts(AccountQuery{CustomerID: c.ID})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+)
+
+// Create a customer, open accounts on their behalf and list them
+func TestCustomerAccounts(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+
+	if _, err := service.CreateCustomer(" ", "", "", ""); err == nil {
+		t.Errorf("Creating a customer without name failed to fail")
+	}
+	if _, err := service.CreateCustomer("Ivan Ivanov", "not an email", "", ""); err == nil {
+		t.Errorf("Creating a customer with invalid email failed to fail")
+	}
+	c, err := service.CreateCustomer("Ivan Ivanov", "ivan@example.com", "+375 (29) 123-45-67", "Minsk")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.OpenAccountForCustomer("CUST-UNKNOWN"); err == nil {
+		t.Errorf("Opening an account for unknown customer failed to fail")
+	}
+
+	opened := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		acc, err := service.OpenAccountForCustomer(c.ID)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if acc.CustomerID != c.ID {
+			t.Errorf("Account %s is not linked to customer %s", acc.Iban, c.ID)
+		}
+		opened[acc.Iban] = true
+	}
+	if _, err := service.OpenAccount(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	accounts, err := service.ListAccountsByCustomer(c.ID)
+	if err != nil || len(accounts) != 2 {
+		t.Fatalf("Expected 2 accounts of customer %s, got %+v (%v)", c.ID, accounts, err)
+	}
+	for _, acc := range accounts {
+		if !opened[acc.Iban] {
+			t.Errorf("Unexpected account %s listed for customer %s", acc.Iban, c.ID)
+		}
+	}
+	if customers, err := service.ListCustomers(); err != nil || len(customers) != 1 {
+		t.Errorf("Unexpected customers %+v (%v)", customers, err)
+	}
+}
+
+// Attach several accounts to one customer, designate the primary one and move money between them
+func TestCustomerInternalMove(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+
+	c, err := service.CreateCustomer("Ivan Ivanov", "", "", "")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	other, err := service.CreateCustomer("Petr Petrov", "", "", "")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	first, err := service.OpenAccountForCustomer(c.ID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	second, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.AttachAccountToCustomer(second.Iban, c.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.AttachAccountToCustomer(second.Iban, other.ID); err == nil {
+		t.Errorf("Attaching an account held by another customer failed to fail")
+	}
+	if err := service.AttachAccountToCustomer(emission, c.ID); err == nil {
+		t.Errorf("Attaching a special account failed to fail")
+	}
+
+	if primary, err := service.RetrievePrimaryAccount(c.ID); err != nil || primary.Iban != first.Iban {
+		t.Errorf("Expected first account to be primary, got %+v (%v)", primary, err)
+	}
+	if err := service.SetPrimaryAccount(other.ID, second.Iban); err == nil {
+		t.Errorf("Designating an account of another customer as primary failed to fail")
+	}
+	if err := service.SetPrimaryAccount(c.ID, second.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if primary, err := service.RetrievePrimaryAccount(c.ID); err != nil || primary.Iban != second.Iban {
+		t.Errorf("Expected second account to be primary, got %+v (%v)", primary, err)
+	}
+
+	if err := service.EmitMoney(100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(emission, first.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Internal moves are allowed into a blocked account of the same customer
+	if err := service.BlockAccount(second.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.InternalMoveMoney(c.ID, first.Iban, second.Iban, 40); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.InternalMoveMoney(other.ID, first.Iban, second.Iban, 10); err == nil {
+		t.Errorf("Moving money between accounts of another customer failed to fail")
+	}
+	if err := service.InternalMoveMoney(c.ID, first.Iban, emission, 10); err == nil {
+		t.Errorf("Moving money to an account outside of the customer failed to fail")
+	}
+	if acc, _ := service.GetAccount(second.Iban); acc.Balance != 40 {
+		t.Errorf("Expected 40 on the second account, got %.2f", acc.Balance)
+	}
+	if last := inMemImpl.Transactions[len(inMemImpl.Transactions)-1]; last.Type != InternalMoveTransaction {
+		t.Errorf("Expected internal move transaction to be recorded, got %+v", last)
+	}
+}
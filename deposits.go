@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining term deposits: funds are moved from a linked account to a deposit account and locked until maturity,
+// at maturity interest is emitted and the whole balance is paid out to the linked account
+type TermDepositStatus int8
+
+const (
+	TermDepositActive TermDepositStatus = iota
+	TermDepositMatured
+	TermDepositWithdrawn // withdrawn before maturity
+)
+
+// Mapping term deposit status codes to term deposit status names considering locale
+var termDepositStatusCodeToNameMap map[TermDepositStatus](map[LanguageCode]string) = map[TermDepositStatus](map[LanguageCode]string){
+	TermDepositActive: {
+		English: "Active",
+		Russian: "Действующий",
+	},
+	TermDepositMatured: {
+		English: "Matured",
+		Russian: "Завершён",
+	},
+	TermDepositWithdrawn: {
+		English: "Withdrawn early",
+		Russian: "Досрочно расторгнут",
+	},
+}
+
+type TermDeposit struct {
+	Iban        string // IBAN of the deposit account
+	LinkedIban  string // IBAN of the account the deposit is funded from and paid out to
+	Amount      float64
+	AnnualRate  float64 // in percent
+	PenaltyRate float64 // percent of the amount charged on early withdrawal, interest is forfeited as well
+	Status      TermDepositStatus
+	Interest    float64 // interest credited at maturity
+	Penalty     float64 // penalty charged on early withdrawal
+	OpenedAt    time.Time
+	MaturesAt   time.Time
+}
+
+// Interest for the whole term on the actual number of days, see interestDayCount
+func (d TermDeposit) maturityInterest() float64 {
+	days := d.MaturesAt.Sub(d.OpenedAt).Hours() / 24
+	return d.Amount * d.AnnualRate / 100 * days / interestDayCount
+}
+
+// Locks the amount on a new deposit account until the given maturity, the amount is moved as a regular transfer from the linked account
+func (r *InMemoryAccountRepository) OpenTermDeposit(linkedIban string, amount, annualRate, penaltyRate float64, maturesAt time.Time) (*TermDeposit, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	linkedIban = NormalizeIban(linkedIban)
+
+	// Checking if the linked account exists and is an ordinary one
+	linked, exists := r.Accounts.Get(linkedIban)
+	if !exists || linked == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if linked.Type != Ordinary {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if the terms of the deposit are valid finite numbers
+	now := clock.Now().UTC()
+	amount = roundToCurrency(amount, linked.Currency)
+	for _, term := range []float64{amount, annualRate, penaltyRate} {
+		if math.IsNaN(term) || math.IsInf(term, 0) {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidDepositTermsError][locale()])
+		}
+	}
+	if amount <= 0 || annualRate < 0 || penaltyRate < 0 || penaltyRate > 100 || !maturesAt.After(now) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidDepositTermsError][locale()])
+	}
+
+	acc, err := r.openAccount(linked.Currency, "")
+	if err != nil {
+		return nil, err
+	}
+	acc.Kyc = KycVerified
+	acc.CustomerID = linked.CustomerID
+	// Funding the account while it is still an ordinary one, so that it goes through all checks of transfers
+	if err := r.transferMoney(linked.Iban, acc.Iban, amount, TransferDetails{Reference: "DEPOSIT"}); err != nil {
+		r.Accounts.Delete(acc.Iban)
+		return nil, err
+	}
+	acc.Type = TermDepositAccount
+	r.Accounts.Put(acc)
+
+	deposit := &TermDeposit{Iban: acc.Iban, LinkedIban: linked.Iban, Amount: amount, AnnualRate: annualRate, PenaltyRate: penaltyRate, Status: TermDepositActive, OpenedAt: now, MaturesAt: maturesAt.UTC()}
+	r.TermDeposits[acc.Iban] = deposit
+	copied := *deposit
+	return &copied, nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveTermDeposit(iban string) (TermDeposit, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists and is a deposit account
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return TermDeposit{}, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	deposit, exists := r.TermDeposits[iban]
+	if !exists || deposit == nil {
+		return TermDeposit{}, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	return *deposit, nil
+}
+
+// Helper function to pay out the balance of the deposit account to the linked account and close it, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) payOutTermDeposit(deposit *TermDeposit, status TermDepositStatus) error {
+	acc, _ := r.Accounts.Get(deposit.Iban)
+	linked, _ := r.Accounts.Get(deposit.LinkedIban)
+	if balance := acc.Balance; balance != 0 {
+		tx := &Transaction{Type: TransferTransaction, Sender: acc.Iban, Recipient: linked.Iban, Amount: balance, Currency: acc.Currency, Reference: "DEPOSIT"}
+		if err := r.post(tx, transferLines(acc, linked, balance)...); err != nil {
+			return err
+		}
+	}
+	acc.Status = Closed
+	r.Accounts.Put(acc)
+	deposit.Status = status
+	return nil
+}
+
+// Credits interest to deposits matured at the given time and pays them out, returns the number of matured deposits
+// Deposits linked to closed accounts stay active until the linked account is available again
+func (r *InMemoryAccountRepository) MatureTermDeposits(now time.Time) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	ibans := make([]string, 0, len(r.TermDeposits))
+	for iban, deposit := range r.TermDeposits {
+		if deposit.Status == TermDepositActive && !deposit.MaturesAt.After(now) {
+			ibans = append(ibans, iban)
+		}
+	}
+	sort.Strings(ibans)
+
+	matured := 0
+	for _, iban := range ibans {
+		deposit := r.TermDeposits[iban]
+		acc, _ := r.Accounts.Get(iban)
+		linked, exists := r.Accounts.Get(deposit.LinkedIban)
+		if acc == nil || !exists || linked == nil || linked.Status == Closed {
+			continue
+		}
+		// Interest is emitted like interest on savings, see PostInterest
+		if interest := deposit.maturityInterest(); interest > 0 && r.EmissionAccount != nil && r.EmissionAccount.Currency == acc.Currency {
+			if err := r.emitMoney(interest, "", InterestReason); err != nil {
+				return matured, err
+			}
+			tx := &Transaction{Type: InterestTransaction, Sender: r.EmissionAccount.Iban, Recipient: iban, Amount: interest, Currency: acc.Currency}
+			if err := r.post(tx, transferLines(r.EmissionAccount, acc, interest)...); err != nil {
+				return matured, err
+			}
+			deposit.Interest = interest
+		}
+		if err := r.payOutTermDeposit(deposit, TermDepositMatured); err != nil {
+			return matured, err
+		}
+		matured++
+	}
+	return matured, nil
+}
+
+// Withdraws the deposit before maturity: the interest is forfeited and the penalty is charged to the emission account,
+// the rest is paid out to the linked account, returns the amount paid out
+func (r *InMemoryAccountRepository) WithdrawTermDeposit(iban string) (float64, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	deposit, exists := r.TermDeposits[iban]
+	if !exists || deposit == nil {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the deposit is still locked
+	if deposit.Status != TermDepositActive {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	acc, _ := r.Accounts.Get(iban)
+	linked, exists := r.Accounts.Get(deposit.LinkedIban)
+	if !exists || linked == nil || linked.Status == Closed {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+
+	if penalty := roundToCurrency(deposit.Amount*deposit.PenaltyRate/100, acc.Currency); penalty > 0 && r.EmissionAccount != nil && r.EmissionAccount.Currency == acc.Currency {
+		tx := &Transaction{Type: FeeTransaction, Sender: iban, Recipient: r.EmissionAccount.Iban, Amount: penalty, Currency: acc.Currency, Reference: "DEPOSIT"}
+		if err := r.post(tx, transferLines(acc, r.EmissionAccount, penalty)...); err != nil {
+			return 0, err
+		}
+		deposit.Penalty = penalty
+	}
+	paidOut := acc.Balance
+	if err := r.payOutTermDeposit(deposit, TermDepositWithdrawn); err != nil {
+		return 0, err
+	}
+	return paidOut, nil
+}
+
+// Matures due term deposits along with the scheduled transfers, returns the number of matured deposits
+func (s *TransferScheduler) RunMaturedDeposits(now time.Time) int {
+	matured, _ := s.service.MatureTermDeposits(now)
+	return matured
+}
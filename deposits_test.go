@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Deposited funds are locked until maturity and paid out with interest
+func TestTermDeposits(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	scheduler := NewTransferScheduler(service)
+	current, _ := service.OpenAccountWithInitialDeposit(500)
+	if _, err := service.OpenTermDeposit(current.Iban, 100, 10, 1, time.Now().Add(-time.Hour)); err == nil {
+		t.Errorf("Opening deposit maturing in the past failed to fail")
+	}
+	maturesAt := time.Now().UTC().AddDate(0, 0, 73)
+	for _, terms := range [][3]float64{{math.NaN(), 10, 1}, {100, math.Inf(1), 1}, {100, math.NaN(), 1}, {100, 10, math.NaN()}} {
+		if _, err := service.OpenTermDeposit(current.Iban, terms[0], terms[1], terms[2], maturesAt); err == nil {
+			t.Errorf("Opening deposit with terms %v failed to fail", terms)
+		}
+	}
+	deposit, err := service.OpenTermDeposit(current.Iban, 365, 10, 2, maturesAt)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(current.Iban); details.Balance != 135 {
+		t.Errorf("Expected deposited amount to leave the linked account, got %.2f", details.Balance)
+	}
+	_, err = service.TransferMoney(deposit.Iban, current.Iban, 10)
+	if err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[TermDepositLockedError][locale()]) {
+		t.Errorf("Expected deposit to be locked, got %v", err)
+	}
+	if err := service.DestructMoney(deposit.Iban, 10); err == nil {
+		t.Errorf("Destruction from deposit failed to fail")
+	}
+	if _, err := service.TransferMoney(current.Iban, deposit.Iban, 10); err == nil {
+		t.Errorf("Top-up of deposit failed to fail")
+	}
+
+	if matured := scheduler.RunMaturedDeposits(time.Now()); matured != 0 {
+		t.Errorf("Expected no deposits to mature yet, got %d", matured)
+	}
+	if matured := scheduler.RunMaturedDeposits(maturesAt); matured != 1 {
+		t.Fatalf("Expected deposit to mature, got %d", matured)
+	}
+	// 365 at 10% for 73 days earns 7.30
+	matured, _ := service.RetrieveTermDeposit(deposit.Iban)
+	if matured.Status != TermDepositMatured || math.Abs(matured.Interest-7.3) > 0.01 {
+		t.Errorf("Unexpected matured deposit: %+v", matured)
+	}
+	if details, _ := service.RetrieveAccount(current.Iban); math.Abs(details.Balance-507.3) > 0.01 {
+		t.Errorf("Expected deposit with interest to be paid out, got %.2f", details.Balance)
+	}
+
+	// Early withdrawal forfeits the interest and charges the penalty
+	early, _ := service.OpenTermDeposit(current.Iban, 100, 10, 2, maturesAt)
+	if paidOut, err := service.WithdrawTermDeposit(early.Iban); err != nil || paidOut != 98 {
+		t.Errorf("Expected 98.00 to be paid out, got %.2f (%v)", paidOut, err)
+	}
+	if _, err := service.WithdrawTermDeposit(early.Iban); err == nil {
+		t.Errorf("Withdrawing deposit twice failed to fail")
+	}
+	if res, err := service.ReconcileFractions(); err != nil || !res.Balanced {
+		t.Errorf("Unexpected reconciliation: %+v (%v)", res, err)
+	}
+}
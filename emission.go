@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining limits of the money supply: caps on emissions are enforced on every emission, including emissions
+// of initial deposits and loan principal, large emissions are only made once a second principal approves them
+type EmissionPolicy struct {
+	MaxTotalSupply    float64 // emitted less destroyed money cannot exceed it, zero disables the cap
+	MaxPerOperation   float64 // zero disables the limit
+	ApprovalThreshold float64 // emissions of larger amounts wait for approval, zero disables approvals
+}
+
+type EmissionApproval struct {
+	ID           string
+	Amount       float64
+	Initiator    string
+	SupplyReason SupplyReason // reason of the emission recorded in the money supply register
+	Status       ApprovalStatus
+	Checker      string // principal who approved or rejected the emission
+	Reason       string // reason of the rejection or the failure
+	CreatedAt    time.Time
+	DecidedAt    time.Time
+}
+
+// Reasons of emissions and destructions recorded in the money supply register, the last three are only used by the system itself
+type SupplyReason int8
+
+const (
+	UnspecifiedReason SupplyReason = iota
+	MonetaryPolicyReason
+	CashCirculationReason // cash brought into or taken out of circulation
+	CorrectionReason      // correction of an erroneous emission or destruction
+	InitialDepositReason
+	LoanReason
+	InterestReason
+)
+
+// Mapping reason codes to reason names considering locale
+var supplyReasonCodeToNameMap map[SupplyReason](map[LanguageCode]string) = map[SupplyReason](map[LanguageCode]string){
+	UnspecifiedReason: {
+		English: "Unspecified",
+		Russian: "Не указана",
+	},
+	MonetaryPolicyReason: {
+		English: "Monetary policy",
+		Russian: "Денежно-кредитная политика",
+	},
+	CashCirculationReason: {
+		English: "Cash circulation",
+		Russian: "Налично-денежное обращение",
+	},
+	CorrectionReason: {
+		English: "Correction",
+		Russian: "Исправление ошибки",
+	},
+	InitialDepositReason: {
+		English: "Initial deposit",
+		Russian: "Первоначальный взнос",
+	},
+	LoanReason: {
+		English: "Loan disbursement",
+		Russian: "Выдача кредита",
+	},
+	InterestReason: {
+		English: "Interest payment",
+		Russian: "Выплата процентов",
+	},
+}
+
+// Entry of the money supply register, one per emission or destruction transaction
+type SupplyRecord struct {
+	TransactionID uint64
+	TransferID    string          // ULID of the transaction
+	Type          TransactionType // EmissionTransaction or DestructionTransaction
+	Account       string          // emission account or the account the money was destroyed from
+	Amount        float64
+	Currency      string
+	Operator      string // principal who made the operation, empty for operations made by the system
+	Approver      string // principal who approved the emission, if it needed approval
+	Reason        SupplyReason
+	Timestamp     time.Time
+}
+
+// Helper function to add the posted transaction to the register, expects the repository mutex to be held by the caller
+// Entries are removed along with their transactions by rollbackTo
+func (r *InMemoryAccountRepository) recordSupplyChange(tx *Transaction, iban, operator string, reason SupplyReason) {
+	r.SupplyRegister = append(r.SupplyRegister, &SupplyRecord{tx.ID, tx.Ulid, tx.Type, iban, tx.Amount, tx.Currency, operator, "", reason, tx.Timestamp})
+}
+
+// Helper function to check the operator and the reason given by the client, reasons used by the system cannot be given
+func checkSupplyOperator(operator string, reason SupplyReason) error {
+	if strings.TrimSpace(operator) == "" || reason < MonetaryPolicyReason || reason > CorrectionReason {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidSupplyReasonError][locale()])
+	}
+	return nil
+}
+
+func (r *InMemoryAccountRepository) EmitMoneyWithReason(amount float64, operator string, reason SupplyReason) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the operator and the reason are given
+	if err := checkSupplyOperator(operator, reason); err != nil {
+		return err
+	}
+	// Checking if the amount does not need approval, such emissions are requested with RequestEmission
+	if r.emissionNeedsApproval(amount) {
+		return fmt.Errorf(errorCodesToMessagesMap[EmissionApprovalRequiredError][locale()])
+	}
+	return r.emitMoney(amount, strings.TrimSpace(operator), reason)
+}
+
+func (r *InMemoryAccountRepository) DestructMoneyWithReason(iban string, amount float64, operator string, reason SupplyReason) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the operator and the reason are given
+	if err := checkSupplyOperator(operator, reason); err != nil {
+		return err
+	}
+	return r.destructMoney(iban, amount, strings.TrimSpace(operator), reason)
+}
+
+// Returns emissions and destructions made in [from, to), the oldest first
+func (r *InMemoryAccountRepository) RetrieveSupplyRegister(from, to time.Time) ([]SupplyRecord, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	records := []SupplyRecord{}
+	for _, record := range r.SupplyRegister {
+		if !record.Timestamp.Before(from) && record.Timestamp.Before(to) {
+			records = append(records, *record)
+		}
+	}
+	return records, nil
+}
+
+// Amounts in the currency of the emission account, circulating money is held by accounts other than the emission,
+// destruction, loan and nostro ones
+type MoneySupply struct {
+	Currency      string  `json:"currency"`
+	Emitted       float64 `json:"emitted"`
+	Destroyed     float64 `json:"destroyed"`
+	Undistributed float64 `json:"undistributed"` // emitted money still held by the emission account
+	Circulating   float64 `json:"circulating"`
+}
+
+func (r *InMemoryAccountRepository) SetEmissionPolicy(policy EmissionPolicy) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if none of the limits is negative
+	if policy.MaxTotalSupply < 0 || policy.MaxPerOperation < 0 || policy.ApprovalThreshold < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	r.EmissionPolicy = &policy
+	return nil
+}
+
+// Helper function to check the emission against the caps, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) checkEmissionCaps(amount float64) error {
+	policy := r.EmissionPolicy
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxPerOperation > 0 && amount > policy.MaxPerOperation {
+		return fmt.Errorf(errorCodesToMessagesMap[EmissionLimitExceededError][locale()])
+	}
+	if policy.MaxTotalSupply > 0 && r.TotalEmitted-r.destroyedMoney()+amount > policy.MaxTotalSupply+reconciliationTolerance {
+		return fmt.Errorf(errorCodesToMessagesMap[EmissionLimitExceededError][locale()])
+	}
+	return nil
+}
+
+// Helper function to check if the emission requested by a client waits for approval, expects the repository mutex to be held by the caller
+// Emissions made internally (initial deposits, loan principal, interest) are only subject to the caps
+func (r *InMemoryAccountRepository) emissionNeedsApproval(amount float64) bool {
+	return r.EmissionPolicy != nil && r.EmissionPolicy.ApprovalThreshold > 0 && amount > r.EmissionPolicy.ApprovalThreshold
+}
+
+// Helper function to sum up destroyed money, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) destroyedMoney() float64 {
+	if r.DestructionAccount == nil {
+		return 0
+	}
+	return r.DestructionAccount.BookedBalance() + r.DestructionAccount.Fractions
+}
+
+// Emits the amount right away if it does not exceed the approval threshold, returns an empty ID in that case,
+// otherwise returns the ID of the emission awaiting approval by a principal other than the initiator
+func (r *InMemoryAccountRepository) RequestEmission(amount float64, initiator string, reason SupplyReason) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the initiator and the reason are given
+	if err := checkSupplyOperator(initiator, reason); err != nil {
+		return "", err
+	}
+	initiator = strings.TrimSpace(initiator)
+	if !r.emissionNeedsApproval(amount) {
+		return "", r.emitMoney(amount, initiator, reason)
+	}
+	// Checking if the emission could be made at all before it waits for approval
+	if err := r.checkEmissionCaps(amount); err != nil {
+		return "", err
+	}
+	now := clock.Now().UTC()
+	approval := &EmissionApproval{ID: NewUlid(now), Amount: amount, Initiator: initiator, SupplyReason: reason, Status: ApprovalPending, CreatedAt: now}
+	r.EmissionApprovals[approval.ID] = approval
+	return approval.ID, nil
+}
+
+// Helper function to find a pending emission and check the principal deciding on it, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) pendingEmission(id, principal string) (*EmissionApproval, error) {
+	approval, exists := r.EmissionApprovals[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || approval == nil || approval.Status != ApprovalPending {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[EmissionApprovalDoesNotExistError][locale()])
+	}
+	// Checking if the principal is known and is not the initiator of the emission
+	if principal == "" || strings.EqualFold(principal, approval.Initiator) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[EmissionPrincipalError][locale()])
+	}
+	return approval, nil
+}
+
+// Caps are checked again at the time of approval, the approval fails if the emission would exceed them by then
+func (r *InMemoryAccountRepository) ApproveEmission(id, principal string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	principal = strings.TrimSpace(principal)
+	approval, err := r.pendingEmission(id, principal)
+	if err != nil {
+		return err
+	}
+	approval.Checker = principal
+	approval.DecidedAt = clock.Now().UTC()
+	if err := r.emitMoney(approval.Amount, approval.Initiator, approval.SupplyReason); err != nil {
+		approval.Status = ApprovalFailed
+		approval.Reason = err.Error()
+		return err
+	}
+	r.SupplyRegister[len(r.SupplyRegister)-1].Approver = principal
+	approval.Status = ApprovalApproved
+	return nil
+}
+
+func (r *InMemoryAccountRepository) RejectEmission(id, principal, reason string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	principal = strings.TrimSpace(principal)
+	approval, err := r.pendingEmission(id, principal)
+	if err != nil {
+		return err
+	}
+	approval.Checker = principal
+	approval.DecidedAt = clock.Now().UTC()
+	approval.Status = ApprovalRejected
+	approval.Reason = strings.TrimSpace(reason)
+	return nil
+}
+
+// Lists emissions awaiting approval, the oldest first
+func (r *InMemoryAccountRepository) ListPendingEmissions() ([]EmissionApproval, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	approvals := []EmissionApproval{}
+	for _, approval := range r.EmissionApprovals {
+		if approval.Status == ApprovalPending {
+			approvals = append(approvals, *approval)
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].CreatedAt.Before(approvals[j].CreatedAt) })
+	return approvals, nil
+}
+
+func (r *InMemoryAccountRepository) MoneySupply() (*MoneySupply, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if emission account is set
+	if r.EmissionAccount == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	supply := &MoneySupply{Currency: r.EmissionAccount.Currency, Emitted: r.TotalEmitted, Destroyed: r.destroyedMoney(), Undistributed: r.EmissionAccount.BookedBalance() + r.EmissionAccount.Fractions}
+	for _, acc := range r.Accounts.All() {
+		if acc == r.EmissionAccount || acc == r.DestructionAccount || acc.Type == LoanAccount || acc.Type == NostroAccount || acc.Currency != supply.Currency {
+			continue
+		}
+		supply.Circulating += acc.BookedBalance() + acc.Fractions
+	}
+	return supply, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Emissions are capped by the policy, large ones wait for approval by another principal
+func TestEmissionPolicy(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	holder, _ := service.OpenAccountWithInitialDeposit(100)
+	maker := service.As(Principal{ID: "maker", Role: AdminRole})
+	checker := service.As(Principal{ID: "checker", Role: AdminRole})
+
+	if err := service.SetEmissionPolicy(EmissionPolicy{MaxTotalSupply: -1}); err == nil {
+		t.Errorf("Negative cap failed to fail")
+	}
+	if err := service.SetEmissionPolicy(EmissionPolicy{MaxTotalSupply: 1000, MaxPerOperation: 600, ApprovalThreshold: 200}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.EmitMoney(700); err == nil {
+		t.Errorf("Emission exceeding the per-operation limit failed to fail")
+	}
+	if err := service.EmitMoney(300); err == nil {
+		t.Errorf("Emission above the approval threshold failed to fail")
+	}
+	if id, err := maker.RequestEmission(150, MonetaryPolicyReason); err != nil || id != "" {
+		t.Errorf("Expected emission up to the threshold to be made right away, got %q (%v)", id, err)
+	}
+	id, err := maker.RequestEmission(500, MonetaryPolicyReason)
+	if err != nil || id == "" {
+		t.Fatalf("Expected emission to wait for approval, got %q (%v)", id, err)
+	}
+	rejected, _ := maker.RequestEmission(400, MonetaryPolicyReason)
+	if pending, _ := service.ListPendingEmissions(); len(pending) != 2 || pending[0].ID != id {
+		t.Errorf("Unexpected pending emissions: %+v", pending)
+	}
+	if err := service.As(Principal{ID: "Maker", Role: AdminRole}).ApproveEmission(id); err == nil {
+		t.Errorf("Approval by the initiator failed to fail")
+	}
+	if err := service.ApproveEmission(id); err == nil {
+		t.Errorf("Approval without a principal failed to fail")
+	}
+	if err := checker.RejectEmission(rejected, "not needed"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := checker.ApproveEmission(rejected); err == nil {
+		t.Errorf("Approval of a rejected emission failed to fail")
+	}
+	if err := checker.ApproveEmission(id); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// 100 + 150 + 500 are outstanding, so the cap leaves room for 250 only
+	if _, err := maker.RequestEmission(260, MonetaryPolicyReason); err == nil {
+		t.Errorf("Emission exceeding the total supply failed to fail")
+	}
+	if _, err := service.OpenAccountWithInitialDeposit(260); err == nil {
+		t.Errorf("Initial deposit exceeding the total supply failed to fail")
+	}
+	// Destroyed money is no longer outstanding
+	if err := service.DestructMoney(holder.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.EmitMoney(200); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	supply, err := service.MoneySupply()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if supply.Emitted != 950 || supply.Destroyed != 50 || supply.Circulating != 50 || supply.Undistributed != 850 {
+		t.Errorf("Unexpected money supply: %+v", supply)
+	}
+	if _, err := service.VerifyInvariants(); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
+
+// Emissions and destructions are recorded with their operators and reasons
+func TestSupplyRegister(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	from := time.Now().Add(-time.Minute)
+	service.SetEmissionPolicy(EmissionPolicy{ApprovalThreshold: 500})
+	// The operator is the principal of the service
+	operator := service.As(Principal{ID: "operator", Role: AdminRole})
+
+	if err := service.EmitMoneyWithReason(100, MonetaryPolicyReason); err == nil {
+		t.Errorf("Emission without an operator failed to fail")
+	}
+	if err := operator.EmitMoneyWithReason(100, LoanReason); err == nil {
+		t.Errorf("Emission with a reason reserved for the system failed to fail")
+	}
+	if err := operator.EmitMoneyWithReason(100, MonetaryPolicyReason); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	id, _ := service.As(Principal{ID: "maker", Role: AdminRole}).RequestEmission(600, CashCirculationReason)
+	if err := service.As(Principal{ID: "checker", Role: AdminRole}).ApproveEmission(id); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	acc, _ := service.OpenAccountWithInitialDeposit(50)
+	if err := operator.DestructMoneyWithReason(acc.Iban, 20, CorrectionReason); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Rolled back emission leaves no record
+	txCount := len(inMemImpl.Transactions)
+	inMemImpl.Mutex.Lock()
+	inMemImpl.emitMoney(30, "", InitialDepositReason)
+	inMemImpl.TotalEmitted -= 30
+	inMemImpl.rollbackTo(txCount)
+	inMemImpl.Mutex.Unlock()
+
+	records, err := service.RetrieveSupplyRegister(from, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expected := []SupplyRecord{
+		{Type: EmissionTransaction, Amount: 100, Operator: "operator", Reason: MonetaryPolicyReason},
+		{Type: EmissionTransaction, Amount: 600, Operator: "maker", Approver: "checker", Reason: CashCirculationReason},
+		{Type: EmissionTransaction, Amount: 50, Reason: InitialDepositReason},
+		{Type: DestructionTransaction, Amount: 20, Operator: "operator", Reason: CorrectionReason},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %+v", len(expected), records)
+	}
+	for i, e := range expected {
+		if r := records[i]; r.Type != e.Type || r.Amount != e.Amount || r.Operator != e.Operator || r.Approver != e.Approver || r.Reason != e.Reason || r.TransferID == "" {
+			t.Errorf("Expected record %+v, got %+v", e, r)
+		}
+	}
+	if records[3].Account != acc.Iban {
+		t.Errorf("Expected destruction to name the debited account, got %s", records[3].Account)
+	}
+	if earlier, _ := service.RetrieveSupplyRegister(from.Add(-time.Hour), from); len(earlier) != 0 {
+		t.Errorf("Expected no records before the period, got %+v", earlier)
+	}
+}
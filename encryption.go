@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining envelope encryption of sensitive fields written to exports and dumps: every value is encrypted with its own
+// data key, the data key is encrypted with the key encryption key of the provider and stored along with the value,
+// so that keys of the provider can be rotated without re-encrypting older dumps as long as the provider keeps the old keys
+type KeyProvider interface {
+	CurrentKeyID() string // ID of the key new values are encrypted with
+	Key(id string) ([]byte, error)
+}
+
+// Keeps 256-bit key encryption keys in memory, i.e. loaded from the configuration or a secrets store
+type StaticKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+func NewStaticKeyProvider(current string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	p := &StaticKeyProvider{current: current, keys: map[string][]byte{}}
+	for id, key := range keys {
+		// Checking if the key is 256 bits long and its ID can be stored along with the values
+		if len(key) != 32 || id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+		}
+		p.keys[id] = append([]byte{}, key...)
+	}
+	if _, exists := p.keys[current]; !exists {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	return p, nil
+}
+
+func (p *StaticKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+func (p *StaticKeyProvider) Key(id string) ([]byte, error) {
+	key, exists := p.keys[id]
+	if !exists {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	return key, nil
+}
+
+// Prefix of encrypted values, the rest is "<key ID>:<encrypted data key>:<encrypted value>" with both parts base64 encoded
+const encryptedValuePrefix = "enc:v1:"
+
+type FieldEncryptor struct {
+	Provider KeyProvider
+}
+
+func NewFieldEncryptor(provider KeyProvider) *FieldEncryptor {
+	return &FieldEncryptor{provider}
+}
+
+// Helper function to seal the plaintext with AES-256-GCM, the nonce is prepended to the ciphertext
+func sealAesGcm(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Helper function to open the ciphertext sealed by sealAesGcm
+func openAesGcm(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func IsEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+func (e *FieldEncryptor) Encrypt(plaintext string) (string, error) {
+	keyID := e.Provider.CurrentKeyID()
+	kek, err := e.Provider.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	wrapped, err := sealAesGcm(kek, dek)
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	sealed, err := sealAesGcm(dek, []byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	return encryptedValuePrefix + keyID + ":" + base64.RawStdEncoding.EncodeToString(wrapped) + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Values which are not encrypted are returned as they are, i.e. values of dumps made before encryption was enabled
+func (e *FieldEncryptor) Decrypt(value string) (string, error) {
+	if !IsEncryptedValue(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, encryptedValuePrefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	kek, err := e.Provider.Key(parts[0])
+	if err != nil {
+		return "", err
+	}
+	wrapped, err1 := base64.RawStdEncoding.DecodeString(parts[1])
+	sealed, err2 := base64.RawStdEncoding.DecodeString(parts[2])
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	dek, err := openAesGcm(kek, wrapped)
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	plaintext, err := openAesGcm(dek, sealed)
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[EncryptionError][locale()])
+	}
+	return string(plaintext), nil
+}
+
+// Helper function to encrypt the value if the encryptor is set, nil encryptor leaves values in plaintext
+func (e *FieldEncryptor) encryptIfSet(value string) (string, error) {
+	if e == nil || value == "" {
+		return value, nil
+	}
+	return e.Encrypt(value)
+}
+
+// Representation of the account in encrypted NDJSON exports, balances are replaced by one encrypted JSON object
+// {"balance", "held", "available", "fractions"} if balances are encrypted
+type sealedAccountDetails struct {
+	AccountDetails
+	Balance           *float64 `json:"balance,omitempty"`
+	Held              *float64 `json:"held,omitempty"`
+	Available         *float64 `json:"available,omitempty"`
+	Fractions         *float64 `json:"fractions,omitempty"`
+	EncryptedBalances string   `json:"encrypted_balances,omitempty"`
+}
+
+// Helper function to encrypt the holder and the metadata (and the balances if requested) of the account for the export
+func (e *FieldEncryptor) sealDetails(d AccountDetails, balances bool) (interface{}, error) {
+	if e == nil {
+		return d, nil
+	}
+	var err error
+	if d.CustomerID, err = e.encryptIfSet(d.CustomerID); err != nil {
+		return nil, err
+	}
+	for key, value := range d.Metadata {
+		if d.Metadata[key], err = e.encryptIfSet(value); err != nil {
+			return nil, err
+		}
+	}
+	if !balances {
+		return d, nil
+	}
+	amounts, _ := json.Marshal(map[string]float64{"balance": d.Balance, "held": d.Held, "available": d.Available, "fractions": d.Fractions})
+	sealed := sealedAccountDetails{AccountDetails: d}
+	if sealed.EncryptedBalances, err = e.Encrypt(string(amounts)); err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
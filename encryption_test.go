@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Encrypt values with one key, rotate to another one and make sure older values can still be decrypted
+func TestFieldEncryptor(t *testing.T) {
+	provider, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	encryptor := NewFieldEncryptor(provider)
+	first, err := encryptor.Encrypt("John Smith")
+	if err != nil || !IsEncryptedValue(first) || strings.Contains(first, "John") {
+		t.Fatalf("Unexpected encrypted value %q (%v)", first, err)
+	}
+	// Every value is encrypted with its own data key, so equal values must not look equal
+	if second, _ := encryptor.Encrypt("John Smith"); second == first {
+		t.Errorf("Expected equal values to be encrypted differently")
+	}
+	if value, err := encryptor.Decrypt(first); err != nil || value != "John Smith" {
+		t.Errorf("Expected decrypted value, got %q (%v)", value, err)
+	}
+	if value, err := encryptor.Decrypt("plain"); err != nil || value != "plain" {
+		t.Errorf("Expected plaintext to be returned as it is, got %q (%v)", value, err)
+	}
+
+	rotated, _ := NewStaticKeyProvider("k2", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32), "k2": bytes.Repeat([]byte{2}, 32)})
+	encryptor.Provider = rotated
+	if value, err := encryptor.Decrypt(first); err != nil || value != "John Smith" {
+		t.Errorf("Expected value encrypted with the old key to be decrypted, got %q (%v)", value, err)
+	}
+	third, _ := encryptor.Encrypt("John Smith")
+	if !strings.HasPrefix(third, encryptedValuePrefix+"k2:") {
+		t.Errorf("Expected new values to be encrypted with the current key, got %q", third)
+	}
+
+	// Values must not be decrypted with other keys or once tampered with
+	other, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{3}, 32)})
+	if _, err := NewFieldEncryptor(other).Decrypt(first); err == nil {
+		t.Errorf("Decryption with another key failed to fail")
+	}
+	if _, err := encryptor.Decrypt(first[:len(first)-2] + "AA"); err == nil {
+		t.Errorf("Decryption of tampered value failed to fail")
+	}
+
+	invalid := []map[string][]byte{
+		{"k1": bytes.Repeat([]byte{1}, 16)},
+		{"k:1": bytes.Repeat([]byte{1}, 32)},
+		{"k2": bytes.Repeat([]byte{1}, 32)},
+	}
+	for _, keys := range invalid {
+		if _, err := NewStaticKeyProvider("k1", keys); err == nil {
+			t.Errorf("Creation of key provider with %v failed to fail", keys)
+		}
+	}
+}
+
+// Export accounts and customers with encryption enabled and make sure no plaintext leaks while balances can be imported back
+func TestEncryptedExports(t *testing.T) {
+	provider, _ := NewStaticKeyProvider("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.Encryptor = NewFieldEncryptor(provider)
+	inMemImpl.EncryptBalances = true
+	customers := NewInMemoryCustomerRepository()
+	customers.Encryptor = NewFieldEncryptor(provider)
+	service := NewAccountServiceWithCustomers(inMemImpl, customers)
+
+	customer, err := service.CreateCustomer("John Smith", "john@example.com", "+375 29 000 00 00", "Minsk")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	acc, err := service.OpenAccountForCustomer(customer.ID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.EmitMoney(123.45); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney("BY84 ALFA 1000 0000 0000 0000 0000", acc.Iban, 123.45); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var csvDump bytes.Buffer
+	if err := service.ExportAccounts(&csvDump, CsvExportFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if strings.Contains(csvDump.String(), customer.ID) || strings.Contains(csvDump.String(), "123.45") {
+		t.Errorf("Expected holder and balance to be encrypted, got %q", csvDump.String())
+	}
+
+	// Encrypted balances must be rejected by repositories without the encryptor
+	rows := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(csvDump.String()), "\n") {
+		if strings.Contains(line, acc.Iban) {
+			rows = append(rows, line)
+		}
+	}
+	other := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	if _, err := other.ImportAccountsCSV(strings.NewReader(strings.Join(rows, "\n"))); err == nil {
+		t.Errorf("Import of encrypted balances without the encryptor failed to fail")
+	}
+	other.Encryptor = NewFieldEncryptor(provider)
+	if count, err := other.ImportAccountsCSV(strings.NewReader(strings.Join(rows, "\n"))); err != nil || count != 1 {
+		t.Fatalf("Expected 1 imported account, got %d (%v)", count, err)
+	}
+	if imported, err := other.RetrieveAccount(acc.Iban); err != nil || imported.Balance != 123.45 {
+		t.Errorf("Expected decrypted balance after import, got %+v (%v)", imported, err)
+	}
+
+	var ndjsonDump bytes.Buffer
+	if err := service.ExportAccounts(&ndjsonDump, NdjsonExportFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(ndjsonDump.String()), "\n") {
+		var row map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if _, exists := row["balance"]; exists {
+			t.Errorf("Expected balance to be encrypted, got %q", line)
+		}
+		if sealed, _ := row["encrypted_balances"].(string); !IsEncryptedValue(sealed) {
+			t.Errorf("Expected encrypted balances, got %q", line)
+		}
+	}
+
+	var customersDump bytes.Buffer
+	if err := service.ExportCustomers(&customersDump); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for _, detail := range []string{"John Smith", "john@example.com", "+375 29 000 00 00", "Minsk"} {
+		if strings.Contains(customersDump.String(), detail) {
+			t.Errorf("Expected personal details to be encrypted, got %q", customersDump.String())
+		}
+	}
+	var exported Customer
+	json.Unmarshal(customersDump.Bytes(), &exported)
+	if name, err := customers.Encryptor.Decrypt(exported.Name); err != nil || name != "John Smith" || exported.ID != customer.ID {
+		t.Errorf("Expected decrypted name of the exported customer, got %q (%v)", name, err)
+	}
+}
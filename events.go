@@ -0,0 +1,161 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining event bus used to notify other components about what happens in the repository without coupling them to it
+// Events are delivered asynchronously through buffered channels, so that publishing never blocks the repository
+
+const (
+	TransferSettledEvent = "transfer.settled" // payload is TransferEventPayload
+	AmlAlertEvent        = "aml.alert"        // payload is AmlAlert
+)
+
+type Event struct {
+	Type      string
+	Payload   interface{}
+	Timestamp time.Time
+}
+
+type TransferEventPayload struct {
+	TransferID  string
+	Sender      string
+	Recipient   string
+	Amount      float64
+	Currency    string
+	TraceParent string // trace context of the transfer, empty if it was not traced
+}
+
+type EventBus interface {
+	Publish(eventType string, payload interface{})
+	// Subscribes to events of the given types (all events if none given), calling the returned function unsubscribes
+	Subscribe(eventTypes ...string) (<-chan Event, func())
+}
+
+// Number of events a subscriber may lag behind before further events are dropped for it
+const eventSubscriptionBuffer = 1024
+
+type eventSubscription struct {
+	types  map[string]bool
+	events chan Event
+}
+
+type InMemoryEventBus struct {
+	subscriptions map[*eventSubscription]bool
+	Dropped       uint64 // number of events dropped because of slow subscribers or published after the bus was closed
+	closed        bool
+	mutex         sync.Mutex
+}
+
+func NewInMemoryEventBus() *InMemoryEventBus {
+	return &InMemoryEventBus{subscriptions: map[*eventSubscription]bool{}}
+}
+
+func (b *InMemoryEventBus) Publish(eventType string, payload interface{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		b.Dropped++
+		return
+	}
+	event := Event{eventType, payload, clock.Now().UTC()}
+	for sub := range b.subscriptions {
+		if len(sub.types) > 0 && !sub.types[eventType] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.Dropped++
+		}
+	}
+}
+
+func (b *InMemoryEventBus) Subscribe(eventTypes ...string) (<-chan Event, func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sub := &eventSubscription{map[string]bool{}, make(chan Event, eventSubscriptionBuffer)}
+	for _, t := range eventTypes {
+		sub.types[t] = true
+	}
+	if b.closed {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+	b.subscriptions[sub] = true
+	once := sync.Once{}
+	return sub.events, func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+			// Checking if the channel has not been closed by Close already
+			if b.subscriptions[sub] {
+				delete(b.subscriptions, sub)
+				close(sub.events)
+			}
+		})
+	}
+}
+
+// Stops accepting events and closes the channels of all subscribers, so that they receive the events already published
+// and then stop, subscribing to the closed bus returns a closed channel
+func (b *InMemoryEventBus) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.closed = true
+	for sub := range b.subscriptions {
+		delete(b.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// Event held back until the operation publishing it completes, see holdEvents
+type heldEvent struct {
+	eventType string
+	payload   interface{}
+	txCount   int // number of transactions recorded when the event was published, the event is dropped if any of them is rolled back
+}
+
+// Helper function to publish an event if the event bus is set, expects the log to be owned exclusively by the caller,
+// see locks.go
+func (r *InMemoryAccountRepository) publish(eventType string, payload interface{}) {
+	if r.EventBus == nil {
+		return
+	}
+	if r.holdingEvents > 0 {
+		r.heldEvents = append(r.heldEvents, heldEvent{eventType, payload, len(r.Transactions)})
+		return
+	}
+	r.EventBus.Publish(eventType, payload)
+}
+
+// Helper function to hold back events of an operation made of several steps which rolls back the steps made so far on
+// failure, i.e. SplitTransfer, expects the log to be owned exclusively by the caller until the returned function is called
+// Events of the transactions rolled back are dropped by rollbackTo, the returned function publishes the rest once the
+// outermost operation holding events completes
+func (r *InMemoryAccountRepository) holdEvents() func() {
+	r.holdingEvents++
+	return func() {
+		if r.holdingEvents--; r.holdingEvents > 0 {
+			return
+		}
+		held := r.heldEvents
+		r.heldEvents = nil
+		for _, event := range held {
+			r.EventBus.Publish(event.eventType, event.payload)
+		}
+	}
+}
+
+// Helper function to drop held events of the transactions rolled back to the given number, expects the log to be owned
+// exclusively by the caller
+func (r *InMemoryAccountRepository) dropHeldEvents(count int) {
+	n := len(r.heldEvents)
+	for n > 0 && r.heldEvents[n-1].txCount > count {
+		n--
+	}
+	r.heldEvents = r.heldEvents[:n]
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// --------------------------------------------------------
+// Defining fees charged on top of money movements and credited to the fee income account
+type FeeKind int8
+
+const (
+	FlatFee       FeeKind = iota // fixed amount in the currency of the sender
+	PercentageFee                // percentage of the amount, i.e. 1.5 for 1.5%
+)
+
+// Mapping fee kind codes to fee kind names considering locale
+var feeKindCodeToNameMap map[FeeKind](map[LanguageCode]string) = map[FeeKind](map[LanguageCode]string){
+	FlatFee: {
+		English: "Flat",
+		Russian: "Фиксированная",
+	},
+	PercentageFee: {
+		English: "Percentage",
+		Russian: "Процентная",
+	},
+}
+
+// Party bearing the fee of a transfer, named after the charge codes of SWIFT messages
+type FeeBearer int8
+
+const (
+	FeeBearerOur FeeBearer = iota // sender pays the fee on top of the amount
+	FeeBearerSha                  // fee is split, sender pays the larger half on top and the rest is deducted from the credited amount
+	FeeBearerBen                  // fee is deducted from the credited amount
+)
+
+// Mapping fee bearer codes to their charge codes, the codes are the same for all locales
+var feeBearerCodeToNameMap map[FeeBearer]string = map[FeeBearer]string{
+	FeeBearerOur: "OUR",
+	FeeBearerSha: "SHA",
+	FeeBearerBen: "BEN",
+}
+
+// Unknown charge codes are mapped to an invalid fee bearer rejected by transfer details validation, empty code means OUR
+func parseFeeBearer(code string) FeeBearer {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return FeeBearerOur
+	}
+	for bearer, name := range feeBearerCodeToNameMap {
+		if name == code {
+			return bearer
+		}
+	}
+	return -1
+}
+
+// Minimum and Maximum bound percentage fees, zero means no bound
+type FeeRule struct {
+	Kind    FeeKind
+	Value   float64
+	Minimum float64
+	Maximum float64
+}
+
+// Transaction types money movements of which can be charged with a fee
+var feeTransactionTypes map[TransactionType]bool = map[TransactionType]bool{
+	TransferTransaction:     true,
+	InternalMoveTransaction: true,
+	AcquiringFeeTransaction: true, // charged to merchants on payments they receive, see merchants.go
+}
+
+// Fees by transaction type, transactions of types without a rule are free of charge
+type FeeSchedule struct {
+	rules map[TransactionType]FeeRule
+	mutex sync.Mutex
+}
+
+func NewFeeSchedule() *FeeSchedule {
+	return &FeeSchedule{rules: map[TransactionType]FeeRule{}}
+}
+
+func (s *FeeSchedule) SetFee(txType TransactionType, rule FeeRule) error {
+	// Checking if the rule is well-formed
+	if !feeTransactionTypes[txType] || rule.Value < 0 || rule.Minimum < 0 || rule.Maximum < 0 || (rule.Maximum > 0 && rule.Maximum < rule.Minimum) {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidFeeRuleError][locale()])
+	}
+	if _, ok := feeKindCodeToNameMap[rule.Kind]; !ok {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidFeeRuleError][locale()])
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rules[txType] = rule
+	return nil
+}
+
+// Replaces all rules of the schedule at once, the schedule is left as it is if any of the rules is not well-formed
+func (s *FeeSchedule) ReplaceFees(rules map[TransactionType]FeeRule) error {
+	replacement := NewFeeSchedule()
+	for txType, rule := range rules {
+		if err := replacement.SetFee(txType, rule); err != nil {
+			return err
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rules = replacement.rules
+	return nil
+}
+
+func (s *FeeSchedule) RemoveFee(txType TransactionType) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.rules, txType)
+}
+
+func (s *FeeSchedule) Fees() map[TransactionType]FeeRule {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fees := make(map[TransactionType]FeeRule, len(s.rules))
+	for txType, rule := range s.rules {
+		fees[txType] = rule
+	}
+	return fees
+}
+
+// Returns the fee applicable to the amount, rounded to the smallest unit of the currency
+func (s *FeeSchedule) Quote(txType TransactionType, amount float64, currency string) float64 {
+	s.mutex.Lock()
+	rule, exists := s.rules[txType]
+	s.mutex.Unlock()
+	if !exists {
+		return 0
+	}
+	fee := rule.Value
+	if rule.Kind == PercentageFee {
+		fee = amount * rule.Value / 100
+		fee = math.Max(fee, rule.Minimum)
+		if rule.Maximum > 0 {
+			fee = math.Min(fee, rule.Maximum)
+		}
+	}
+	return roundToCurrency(fee, currency)
+}
+
+// Designates an active ordinary account to receive the fees
+func (r *InMemoryAccountRepository) SetFeeAccount(iban string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account can receive money
+	if acc.Type != Ordinary || acc.Status != Active {
+		return fmt.Errorf(errorCodesToMessagesMap[FeeAccountUnavailableError][locale()])
+	}
+	r.FeeAccount = acc
+	return nil
+}
+
+// Helper function to find out the fee of the movement and the part of it deducted from the credited amount according to the fee bearer,
+// expects the repository mutex to be held by the caller
+// Only ordinary accounts are charged, so that emissions and system movements stay free of charge
+func (r *InMemoryAccountRepository) feeFor(sAcc *Account, txType TransactionType, amount float64, bearer FeeBearer) (float64, float64, error) {
+	if r.FeeSchedule == nil || sAcc.Type != Ordinary {
+		return 0, 0, nil
+	}
+	fee := r.FeeSchedule.Quote(txType, amount, sAcc.Currency)
+	if fee == 0 {
+		return 0, 0, nil
+	}
+	// Checking if there is an account to credit the fee to in the currency of the sender
+	if r.FeeAccount == nil || r.FeeAccount.Status != Active || r.FeeAccount.Currency != sAcc.Currency || r.FeeAccount == sAcc {
+		return 0, 0, fmt.Errorf(errorCodesToMessagesMap[FeeAccountUnavailableError][locale()])
+	}
+	deducted := 0.0
+	switch bearer {
+	case FeeBearerSha:
+		deducted = truncToCurrency(fee/2, sAcc.Currency)
+	case FeeBearerBen:
+		deducted = fee
+	}
+	rounded, _ := roundAndExtractFractionsInCurrency(amount, sAcc.Currency)
+	// Checking if the credited amount covers its part of the fee
+	if deducted > rounded {
+		return 0, 0, fmt.Errorf(errorCodesToMessagesMap[FeeExceedsAmountError][locale()])
+	}
+	// Checking if sender has sufficient balance to pay its part of the fee on top of the amount
+	if sAcc.AvailableBalance() < rounded+fee-deducted {
+		return 0, 0, insufficientFundsError(sAcc)
+	}
+	return fee, deducted, nil
+}
+
+// Helper function to move the fee to the fee income account, expects the repository mutex to be held by the caller
+// The fee is always debited from the sender, the part borne by the recipient is withheld from the credited amount instead
+// The fee is recorded before the charged transaction, so that the charged transaction stays the last one in the log
+func (r *InMemoryAccountRepository) chargeFee(sAcc *Account, fee float64) (*Transaction, error) {
+	if fee == 0 {
+		return nil, nil
+	}
+	feeTx := &Transaction{Type: FeeTransaction, Sender: sAcc.Iban, Recipient: r.FeeAccount.Iban, Amount: fee, Currency: sAcc.Currency}
+	if err := r.post(feeTx, transferLines(sAcc, r.FeeAccount, fee)...); err != nil {
+		return nil, err
+	}
+	return feeTx, nil
+}
+
+// Helper function to link the fee to the charged transaction once it is recorded
+func linkFee(feeTx, tx *Transaction) {
+	if feeTx != nil {
+		feeTx.FeeOf = tx.Ulid
+		tx.Fee = feeTx.Amount
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+)
+
+// Fees are debited on top of the amount and credited to the fee income account
+func TestTransferFees(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	feeIncome, _ := service.OpenAccount()
+
+	schedule := NewFeeSchedule()
+	if err := schedule.SetFee(EmissionTransaction, FeeRule{Kind: FlatFee, Value: 1}); err == nil {
+		t.Errorf("Setting fee for emission failed to fail")
+	}
+	if err := schedule.SetFee(TransferTransaction, FeeRule{Kind: PercentageFee, Value: 1, Minimum: 0.5, Maximum: 5}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for amount, expected := range map[float64]float64{10: 0.5, 120: 1.2, 1000: 5} {
+		if fee := schedule.Quote(TransferTransaction, amount, "BYN"); fee != expected {
+			t.Errorf("Expected fee %.2f for %.2f, got %.2f", expected, amount, fee)
+		}
+	}
+	if fee := schedule.Quote(InternalMoveTransaction, 10, "BYN"); fee != 0 {
+		t.Errorf("Expected no fee for internal moves, got %.2f", fee)
+	}
+	inMemImpl.FeeSchedule = schedule
+
+	// Charging fees requires a fee income account
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 10); err == nil {
+		t.Errorf("Transfer without fee income account failed to fail")
+	}
+	if err := service.SetFeeAccount(emission); err == nil {
+		t.Errorf("Setting emission account as fee income account failed to fail")
+	}
+	if err := service.SetFeeAccount(feeIncome.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	id, err := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 50, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for iban, expected := range map[string]float64{sender.Iban: 49.5, recipient.Iban: 50, feeIncome.Iban: 0.5} {
+		if details, _ := service.RetrieveAccount(iban); details.Balance != expected {
+			t.Errorf("Expected balance %.2f of %s, got %.2f", expected, iban, details.Balance)
+		}
+	}
+	txs, _ := service.RetrieveAccountTransactions(sender.Iban, 2)
+	if len(txs) != 2 || txs[0].Ulid != id || txs[0].Fee != 0.5 || txs[1].Type != FeeTransaction || txs[1].FeeOf != id {
+		t.Errorf("Unexpected transactions: %+v", txs)
+	}
+
+	// The sender has to afford the fee on top of the amount
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 49.5); err == nil {
+		t.Errorf("Transfer without funds for the fee failed to fail")
+	}
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 49); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(sender.Iban); details.Balance != 0 {
+		t.Errorf("Expected sender balance 0, got %.2f", details.Balance)
+	}
+}
+
+// Fee is borne by the sender, the recipient or split between them
+func TestFeeBearers(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	feeIncome, _ := service.OpenAccount()
+	inMemImpl.FeeSchedule = NewFeeSchedule()
+	inMemImpl.FeeSchedule.SetFee(TransferTransaction, FeeRule{Kind: FlatFee, Value: 1.01})
+	service.SetFeeAccount(feeIncome.Iban)
+
+	if _, err := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 10, TransferDetails{FeeBearer: FeeBearerSha}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoneyJson(`{"sender": "` + sender.Iban + `", "recipient": "` + recipient.Iban + `", "amount": 10, "fee_bearer": "ben"}`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoneyJson(`{"sender": "` + sender.Iban + `", "recipient": "` + recipient.Iban + `", "amount": 10, "fee_bearer": "XYZ"}`); err == nil {
+		t.Errorf("Transfer with unknown fee bearer failed to fail")
+	}
+	if _, err := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 1, TransferDetails{FeeBearer: FeeBearerBen}); err == nil {
+		t.Errorf("Transfer of amount below the fee borne by the recipient failed to fail")
+	}
+	// SHA: sender pays 10 + 0.51, recipient gets 10 - 0.50; BEN: sender pays 10, recipient gets 10 - 1.01
+	for iban, expected := range map[string]float64{sender.Iban: 79.49, recipient.Iban: 18.49, feeIncome.Iban: 2.02} {
+		if details, _ := service.RetrieveAccount(iban); details.Balance != expected {
+			t.Errorf("Expected balance %.2f of %s, got %.2f", expected, iban, details.Balance)
+		}
+	}
+	txs, _ := service.RetrieveAccountTransactions(recipient.Iban, 0)
+	if len(txs) != 2 || txs[0].FeeBearer != FeeBearerBen || txs[1].FeeBearer != FeeBearerSha || txs[1].Fee != 1.01 {
+		t.Errorf("Unexpected transactions: %+v", txs)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining pluggable fraud scoring of transfers
+// Context of a transfer passed to the scorer, History holds the most recent transactions of the sender, the most recent first
+type FraudContext struct {
+	Sender       string
+	Recipient    string
+	Amount       float64
+	Currency     string
+	Details      TransferDetails
+	SenderAge    time.Duration // time since the sender account was opened
+	RecipientAge time.Duration
+	History      []Transaction
+}
+
+// Returns the risk of the transfer being fraudulent from 0 (no risk) to 1 (certainly fraudulent)
+type FraudScorer interface {
+	Score(ctx FraudContext) (float64, error)
+}
+
+// Scorer adding up the weights of simple risk indicators, weights are chosen so that the sum does not exceed 1
+type HeuristicFraudScorer struct {
+	NewAccountAge time.Duration // accounts younger than that are considered new
+	AmountFactor  float64       // amounts above the average debit of the sender multiplied by the factor are considered unusual
+}
+
+func NewHeuristicFraudScorer() *HeuristicFraudScorer {
+	return &HeuristicFraudScorer{NewAccountAge: 24 * time.Hour, AmountFactor: 5}
+}
+
+func (s *HeuristicFraudScorer) Score(ctx FraudContext) (float64, error) {
+	score := 0.0
+	if ctx.SenderAge < s.NewAccountAge {
+		score += 0.3
+	}
+	if ctx.RecipientAge < s.NewAccountAge {
+		score += 0.2
+	}
+	debits, total, knownRecipient := 0, 0.0, false
+	for _, tx := range ctx.History {
+		if tx.Sender == ctx.Sender {
+			debits++
+			total += tx.Amount
+			knownRecipient = knownRecipient || tx.Recipient == ctx.Recipient
+		}
+	}
+	// Checking if the amount is unusual for the sender, the first debit is considered unusual
+	if debits == 0 || ctx.Amount > total/float64(debits)*s.AmountFactor {
+		score += 0.3
+	}
+	if !knownRecipient {
+		score += 0.2
+	}
+	return score, nil
+}
+
+type FraudReviewStatus int8
+
+const (
+	FraudReviewPending FraudReviewStatus = iota
+	FraudReviewReleased
+	FraudReviewDismissed
+	FraudReviewFailed // released but the transfer could not be executed
+)
+
+// Mapping fraud review status codes to fraud review status names considering locale
+var fraudReviewStatusCodeToNameMap map[FraudReviewStatus](map[LanguageCode]string) = map[FraudReviewStatus](map[LanguageCode]string){
+	FraudReviewPending: {
+		English: "Pending review",
+		Russian: "Ожидает проверки",
+	},
+	FraudReviewReleased: {
+		English: "Released",
+		Russian: "Пропущен",
+	},
+	FraudReviewDismissed: {
+		English: "Dismissed",
+		Russian: "Отклонён",
+	},
+	FraudReviewFailed: {
+		English: "Failed",
+		Russian: "Не исполнен",
+	},
+}
+
+// Transfer put aside because of its fraud score, TransferID is filled in once the transfer is released and executed
+type FraudReview struct {
+	ID             string
+	Sender         string
+	Recipient      string
+	Amount         float64
+	Details        TransferDetails
+	IdempotencyKey string
+	Score          float64
+	Status         FraudReviewStatus
+	Reviewer       string
+	Reason         string
+	TransferID     string
+	CreatedAt      time.Time
+	DecidedAt      time.Time
+}
+
+// Number of recent transactions of the sender passed to the scorer
+const fraudHistorySize = 50
+
+// Decorator scoring transfers before passing them to the wrapped repository, transfers scored above the threshold
+// are put into the review queue instead of being executed, all other methods are passed through as is
+type FraudScoringRepository struct {
+	AccountRepository
+	Scorer    FraudScorer
+	Threshold float64
+	Reviews   map[string]*FraudReview
+	keys      map[string]string // idempotency keys of transfers under review or released mapped to review IDs
+	mutex     sync.Mutex
+}
+
+func NewFraudScoringRepository(r AccountRepository, scorer FraudScorer, threshold float64) *FraudScoringRepository {
+	return &FraudScoringRepository{AccountRepository: r, Scorer: scorer, Threshold: threshold, Reviews: map[string]*FraudReview{}, keys: map[string]string{}}
+}
+
+// Helper function to collect the context of the transfer and score it
+// Errors of looking up the accounts are left to the wrapped repository to report, such transfers are not scored
+func (f *FraudScoringRepository) score(sender, recipient string, amount float64, details TransferDetails) (float64, bool, error) {
+	if f.Scorer == nil {
+		return 0, false, nil
+	}
+	sAcc, err := f.AccountRepository.GetAccount(sender)
+	if err != nil {
+		return 0, false, nil
+	}
+	rAcc, err := f.AccountRepository.GetAccount(recipient)
+	if err != nil {
+		return 0, false, nil
+	}
+	history, err := f.AccountRepository.RetrieveAccountTransactions(sender, fraudHistorySize)
+	if err != nil {
+		return 0, false, nil
+	}
+	now := clock.Now().UTC()
+	score, err := f.Scorer.Score(FraudContext{sAcc.Iban, rAcc.Iban, amount, sAcc.Currency, details, now.Sub(sAcc.OpenedAt), now.Sub(rAcc.OpenedAt), history})
+	if err != nil {
+		return 0, false, fmt.Errorf(errorCodesToMessagesMap[FraudScoringError][locale()])
+	}
+	return score, true, nil
+}
+
+// Helper function to either pass the transfer to the wrapped repository or put it into the review queue
+// Returns the ID of the transfer or the ID of the review respectively
+func (f *FraudScoringRepository) submit(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	sender = NormalizeIban(sender)
+	recipient = NormalizeIban(recipient)
+	key = strings.TrimSpace(key)
+
+	// Checking if the same request is already under review, released requests are answered by the wrapped repository
+	f.mutex.Lock()
+	if id, exists := f.keys[key]; key != "" && exists {
+		released := f.Reviews[id].Status == FraudReviewReleased
+		f.mutex.Unlock()
+		if released {
+			return f.AccountRepository.TransferMoneyIdempotent(key, sender, recipient, amount, details)
+		}
+		return id, nil
+	}
+	f.mutex.Unlock()
+
+	score, scored, err := f.score(sender, recipient, amount, details)
+	if err != nil {
+		return "", err
+	}
+	if !scored || score <= f.Threshold {
+		return f.AccountRepository.TransferMoneyIdempotent(key, sender, recipient, amount, details)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := clock.Now().UTC()
+	review := &FraudReview{ID: NewUlid(now), Sender: sender, Recipient: recipient, Amount: amount, Details: details, IdempotencyKey: key, Score: score, Status: FraudReviewPending, CreatedAt: now}
+	f.Reviews[review.ID] = review
+	if key != "" {
+		f.keys[key] = review.ID
+	}
+	return review.ID, nil
+}
+
+func (f *FraudScoringRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	return f.submit("", sender, recipient, amount, TransferDetails{})
+}
+
+func (f *FraudScoringRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	return f.submit("", sender, recipient, amount, details)
+}
+
+func (f *FraudScoringRepository) TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	return f.submit(key, sender, recipient, amount, details)
+}
+
+func (f *FraudScoringRepository) TransferMoneyJson(jsonStr string) (string, error) {
+	req, err := decodeMoneyTransferReq(jsonStr)
+	if err != nil {
+		return "", err
+	}
+	return f.submit(req.IdempotencyKey, req.Sender, req.Recipient, req.Amount, req.details())
+}
+
+// Transfers under review are reported as pending, released ones are reported by the wrapped repository
+func (f *FraudScoringRepository) GetTransferStatus(id string) (TransferStatus, error) {
+	f.mutex.Lock()
+	review, exists := f.Reviews[strings.ToUpper(strings.TrimSpace(id))]
+	var status FraudReviewStatus
+	transferID := ""
+	if exists {
+		status = review.Status
+		transferID = review.TransferID
+	}
+	f.mutex.Unlock()
+
+	switch {
+	case !exists:
+		return f.AccountRepository.GetTransferStatus(id)
+	case status == FraudReviewPending:
+		return TransferPending, nil
+	case status == FraudReviewReleased:
+		return f.AccountRepository.GetTransferStatus(transferID)
+	default:
+		return TransferFailed, nil
+	}
+}
+
+// Helper function to find a pending review and mark it as decided by the principal, expects the mutex to be held by the caller
+func (f *FraudScoringRepository) decide(id, principal string, status FraudReviewStatus) (*FraudReview, error) {
+	principal = strings.TrimSpace(principal)
+	if principal == "" {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[PrincipalRequiredError][locale()])
+	}
+	review, exists := f.Reviews[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || review == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[FraudReviewDoesNotExistError][locale()])
+	}
+	if review.Status != FraudReviewPending {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[FraudReviewIsNotPendingError][locale()])
+	}
+	review.Status = status
+	review.Reviewer = principal
+	review.DecidedAt = clock.Now().UTC()
+	return review, nil
+}
+
+// Executes the transfer under review, returns the ID of the executed transfer
+// The transfer is passed to the wrapped repository as is, so it is still subject to its checks and approvals
+func (f *FraudScoringRepository) ReleaseTransfer(id, principal string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	review, err := f.decide(id, principal, FraudReviewReleased)
+	if err != nil {
+		return "", err
+	}
+	transferID, err := f.AccountRepository.TransferMoneyIdempotent(review.IdempotencyKey, review.Sender, review.Recipient, review.Amount, review.Details)
+	if err != nil {
+		review.Status = FraudReviewFailed
+		review.Reason = err.Error()
+		delete(f.keys, review.IdempotencyKey)
+		return "", err
+	}
+	review.TransferID = transferID
+	return transferID, nil
+}
+
+func (f *FraudScoringRepository) DismissTransfer(id, principal, reason string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	review, err := f.decide(id, principal, FraudReviewDismissed)
+	if err != nil {
+		return err
+	}
+	review.Reason = strings.TrimSpace(reason)
+	delete(f.keys, review.IdempotencyKey)
+	return nil
+}
+
+// Lists transfers awaiting review, the oldest first
+func (f *FraudScoringRepository) ListPendingReviews() []FraudReview {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	reviews := []FraudReview{}
+	for _, review := range f.Reviews {
+		if review.Status == FraudReviewPending {
+			reviews = append(reviews, *review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].CreatedAt.Before(reviews[j].CreatedAt) })
+	return reviews
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Scorer returning the same score for every transfer
+type fixedFraudScorer struct {
+	score float64
+}
+
+func (s *fixedFraudScorer) Score(ctx FraudContext) (float64, error) {
+	return s.score, nil
+}
+
+// Transfers scored above the threshold wait in the review queue
+func TestFraudScoring(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	scorer := &fixedFraudScorer{0.2}
+	fraudImpl := NewFraudScoringRepository(inMemImpl, scorer, 0.5)
+	service := NewAccountService(fraudImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	scorer.score = 0.9
+	id, err := service.TransferMoneyIdempotent("key-1", sender.Iban, recipient.Iban, 20, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if again, _ := service.TransferMoneyIdempotent("key-1", sender.Iban, recipient.Iban, 20, TransferDetails{}); again != id {
+		t.Errorf("Expected the same review for a repeated request, got %s and %s", id, again)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferPending {
+		t.Errorf("Expected transfer under review to be pending, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+	if _, err := service.TransferMoneyJson(`{"sender": "` + sender.Iban + `", "recipient": "` + recipient.Iban + `", "amount": 30}`); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 10 {
+		t.Errorf("Expected transfers under review not to move money, recipient has %.2f", details.Balance)
+	}
+	pending := fraudImpl.ListPendingReviews()
+	if len(pending) != 2 || pending[0].ID != id || pending[0].Score != 0.9 {
+		t.Fatalf("Unexpected pending reviews: %+v", pending)
+	}
+
+	if _, err := fraudImpl.ReleaseTransfer(id, ""); err == nil {
+		t.Errorf("Release without a principal failed to fail")
+	}
+	transferID, err := fraudImpl.ReleaseTransfer(id, "analyst")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferSettled {
+		t.Errorf("Expected released transfer to be settled, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+	// Repeating the request after release returns the executed transfer
+	if again, _ := service.TransferMoneyIdempotent("key-1", sender.Iban, recipient.Iban, 20, TransferDetails{}); again != transferID {
+		t.Errorf("Expected repeated request to return transfer %s, got %s", transferID, again)
+	}
+	if err := fraudImpl.DismissTransfer(pending[1].ID, "analyst", "Account takeover"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := fraudImpl.ReleaseTransfer(pending[1].ID, "analyst"); err == nil {
+		t.Errorf("Releasing a dismissed transfer failed to fail")
+	}
+	if status, _ := service.GetTransferStatus(pending[1].ID); status != TransferFailed {
+		t.Errorf("Expected dismissed transfer to be failed, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 30 {
+		t.Errorf("Expected recipient balance 30, got %.2f", details.Balance)
+	}
+}
+
+func TestHeuristicFraudScorer(t *testing.T) {
+	scorer := NewHeuristicFraudScorer()
+	history := []Transaction{{Sender: "A", Recipient: "B", Amount: 10}, {Sender: "A", Recipient: "B", Amount: 30}}
+	ctx := FraudContext{Sender: "A", Recipient: "B", Amount: 20, SenderAge: 48 * time.Hour, RecipientAge: 48 * time.Hour, History: history}
+	if score, _ := scorer.Score(ctx); score != 0 {
+		t.Errorf("Expected usual transfer to score 0, got %.2f", score)
+	}
+	ctx.Recipient, ctx.Amount, ctx.SenderAge, ctx.RecipientAge = "C", 500, time.Hour, time.Hour
+	if score, _ := scorer.Score(ctx); score < 0.99 {
+		t.Errorf("Expected unusual transfer between new accounts to score 1, got %.2f", score)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining legal freezes: a court or another authority freezes the whole available balance of the account or a part of it,
+// the frozen money is reserved by a hold that can only be released together with the freeze, referencing the same case
+// Only money available at registration is frozen, later credits are not frozen
+type FreezeStatus int8
+
+const (
+	FreezeActive FreezeStatus = iota
+	FreezeReleased
+)
+
+// Mapping freeze status codes to freeze status names considering locale
+var freezeStatusCodeToNameMap map[FreezeStatus](map[LanguageCode]string) = map[FreezeStatus](map[LanguageCode]string){
+	FreezeActive: {
+		English: "Active",
+		Russian: "Действует",
+	},
+	FreezeReleased: {
+		English: "Released",
+		Russian: "Снят",
+	},
+}
+
+type LegalFreeze struct {
+	ID         string
+	CaseNumber string
+	Authority  string // court or agency that ordered the freeze
+	Iban       string
+	Limit      float64 // zero means the whole available balance is frozen
+	Amount     float64 // frozen amount, may be less than the limit if the account held less at registration
+	HoldID     string
+	Status     FreezeStatus
+	CreatedAt  time.Time
+	ReleasedAt time.Time
+}
+
+// Helper function to compare case numbers ignoring case and surrounding spaces
+func sameCase(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// Zero limit freezes the whole available balance, money reserved by other holds is not frozen again
+func (r *InMemoryAccountRepository) RegisterFreeze(iban, caseNumber, authority string, limit float64) (*LegalFreeze, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	caseNumber = strings.TrimSpace(caseNumber)
+	authority = strings.TrimSpace(authority)
+	// Checking if the case is identified and the limit is valid
+	if caseNumber == "" || authority == "" || limit < 0 || math.IsNaN(limit) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidFreezeError][locale()])
+	}
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is not closed
+	if acc.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the case has not frozen the account yet
+	for _, freeze := range r.Freezes {
+		if freeze.Iban == iban && freeze.Status == FreezeActive && sameCase(freeze.CaseNumber, caseNumber) {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidFreezeError][locale()])
+		}
+	}
+
+	amount := math.Max(acc.AvailableBalance(), 0)
+	if limit > 0 {
+		amount = math.Min(amount, roundToCurrency(limit, acc.Currency))
+	}
+	now := clock.Now().UTC()
+	freeze := &LegalFreeze{ID: NewUlid(now), CaseNumber: caseNumber, Authority: authority, Iban: iban, Limit: limit, Amount: amount, Status: FreezeActive, CreatedAt: now}
+	hold := &Hold{ID: NewUlid(now), Iban: iban, Amount: amount, Status: HoldActive, FreezeID: freeze.ID, CreatedAt: now}
+	acc.Held = roundToCurrency(acc.Held+amount, acc.Currency)
+	r.Holds[hold.ID] = hold
+	freeze.HoldID = hold.ID
+	r.Freezes[freeze.ID] = freeze
+	copied := *freeze
+	return &copied, nil
+}
+
+// Releases the frozen money, the release has to reference the case of the freeze
+func (r *InMemoryAccountRepository) ReleaseFreeze(id, caseNumber string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	freeze, exists := r.Freezes[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || freeze == nil || freeze.Status != FreezeActive {
+		return fmt.Errorf(errorCodesToMessagesMap[FreezeDoesNotExistError][locale()])
+	}
+	// Checking if the release references the same case
+	if !sameCase(freeze.CaseNumber, caseNumber) {
+		return fmt.Errorf(errorCodesToMessagesMap[FreezeCaseMismatchError][locale()])
+	}
+	hold := r.Holds[freeze.HoldID]
+	if acc, _ := r.Accounts.Get(freeze.Iban); hold != nil && acc != nil && hold.Status == HoldActive {
+		acc.Held = roundToCurrency(acc.Held-hold.Amount, acc.Currency)
+		hold.Status = HoldReleased
+	}
+	freeze.Status = FreezeReleased
+	freeze.ReleasedAt = clock.Now().UTC()
+	return nil
+}
+
+// Lists freezes of the account, the oldest first
+func (r *InMemoryAccountRepository) ListFreezes(iban string) ([]LegalFreeze, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	freezes := []LegalFreeze{}
+	for _, freeze := range r.Freezes {
+		if freeze.Iban == iban {
+			freezes = append(freezes, *freeze)
+		}
+	}
+	sort.Slice(freezes, func(i, j int) bool { return freezes[i].CreatedAt.Before(freezes[j].CreatedAt) })
+	return freezes, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+)
+
+// Frozen portion of the balance cannot be debited until the freeze is released referencing its case
+func TestLegalFreezes(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	other, _ := service.OpenAccount()
+
+	if _, err := service.RegisterFreeze(acc.Iban, " ", "District court", 10); err == nil {
+		t.Errorf("Freeze without a case number failed to fail")
+	}
+	if _, err := service.RegisterFreeze(acc.Iban, "2-17/2026", "District court", -10); err == nil {
+		t.Errorf("Freeze with negative limit failed to fail")
+	}
+	partial, err := service.RegisterFreeze(acc.Iban, "2-17/2026", "District court", 30)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.RegisterFreeze(acc.Iban, "2-17/2026 ", "District court", 10); err == nil {
+		t.Errorf("Second freeze of the same case failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Held != 30 || details.Available != 70 {
+		t.Errorf("Expected 30 to be frozen, got %+v", details)
+	}
+	// Money beyond the frozen portion can still be moved
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 80); err == nil {
+		t.Errorf("Transfer of frozen money failed to fail")
+	}
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// The hold of the freeze is not captured or released on its own
+	if _, err := service.CaptureHold(partial.HoldID, other.Iban, 0); err == nil {
+		t.Errorf("Capture of the hold of a freeze failed to fail")
+	}
+	if err := service.ReleaseHold(partial.HoldID); err == nil {
+		t.Errorf("Release of the hold of a freeze failed to fail")
+	}
+
+	// Full freeze takes the rest of the available balance
+	full, err := service.RegisterFreeze(acc.Iban, "CASE-9", "Tax authority", 0)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if full.Amount != 20 {
+		t.Errorf("Expected the rest of 20 to be frozen, got %v", full.Amount)
+	}
+	if freezes, _ := service.ListFreezes(acc.Iban); len(freezes) != 2 || freezes[0].ID != partial.ID {
+		t.Errorf("Unexpected freezes: %+v", freezes)
+	}
+
+	if err := service.ReleaseFreeze(partial.ID, "CASE-9"); err == nil {
+		t.Errorf("Release referencing another case failed to fail")
+	}
+	if err := service.ReleaseFreeze(partial.ID, "2-17/2026"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.ReleaseFreeze(partial.ID, "2-17/2026"); err == nil {
+		t.Errorf("Second release of the freeze failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Held != 20 || details.Available != 30 {
+		t.Errorf("Expected 20 to stay frozen, got %+v", details)
+	}
+	if hold, _ := service.RetrieveHold(partial.HoldID); hold.Status != HoldReleased {
+		t.Errorf("Expected the hold of the released freeze to be released, got %v", hold.Status)
+	}
+}
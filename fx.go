@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining implementation agnostic interface that quotes exchange rates for currency conversions
+// Rate returns how many units of the "to" currency are credited for one unit of the "from" currency
+type RateProvider interface {
+	Rate(from, to string) (float64, error)
+}
+
+// Helper function to look up a rate in a table keyed as "FROM/TO", falling back to the inverse of "TO/FROM"
+func lookupRate(rates map[string]float64, from, to string) (float64, bool) {
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+	if from == to {
+		return 1, true
+	}
+	if rate, ok := rates[from+"/"+to]; ok && rate > 0 {
+		return rate, true
+	}
+	if rate, ok := rates[to+"/"+from]; ok && rate > 0 {
+		return 1 / rate, true
+	}
+	return 0, false
+}
+
+// Rate provider backed by a hardcoded table, e.g. {"USD/BYN": 3.27, "EUR/BYN": 3.55}
+type FixedRateProvider struct {
+	Rates map[string]float64
+}
+
+func NewFixedRateProvider(rates map[string]float64) *FixedRateProvider {
+	return &FixedRateProvider{rates}
+}
+
+func (p *FixedRateProvider) Rate(from, to string) (float64, error) {
+	rate, ok := lookupRate(p.Rates, from, to)
+	if !ok {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	return rate, nil
+}
+
+// Rate provider reading a JSON table of the same shape as FixedRateProvider from a file
+// The file is read again whenever its modification time changes, so rates can be updated without a restart
+type FileRateProvider struct {
+	Path    string
+	rates   map[string]float64
+	modTime time.Time
+	mutex   sync.Mutex
+}
+
+func NewFileRateProvider(path string) *FileRateProvider {
+	return &FileRateProvider{Path: path}
+}
+
+func (p *FileRateProvider) Rate(from, to string) (float64, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	// Reloading the table only if the file has been modified since the last read
+	if p.rates == nil || !info.ModTime().Equal(p.modTime) {
+		content, err := os.ReadFile(p.Path)
+		if err != nil {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+		}
+		rates := map[string]float64{}
+		if err := json.Unmarshal(content, &rates); err != nil {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+		}
+		p.rates = rates
+		p.modTime = info.ModTime()
+	}
+
+	rate, ok := lookupRate(p.rates, from, to)
+	if !ok {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	return rate, nil
+}
+
+// Rate provider querying an HTTP feed as GET <Url>?base=USD and expecting {"base": "USD", "rates": {"BYN": 3.27}} in response
+// Responses are cached per base currency for Ttl to avoid hitting the feed on every conversion
+type HttpRateProvider struct {
+	Url    string
+	Ttl    time.Duration
+	Client *http.Client
+	cache  map[string]httpRatesEntry
+	mutex  sync.Mutex
+}
+
+type httpRatesEntry struct {
+	rates     map[string]float64
+	fetchedAt time.Time
+}
+
+func NewHttpRateProvider(feedUrl string, ttl time.Duration) *HttpRateProvider {
+	return &HttpRateProvider{Url: feedUrl, Ttl: ttl, Client: &http.Client{Timeout: 5 * time.Second}, cache: map[string]httpRatesEntry{}}
+}
+
+func (p *HttpRateProvider) Rate(from, to string) (float64, error) {
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+
+	p.mutex.Lock()
+	entry, cached := p.cache[from]
+	p.mutex.Unlock()
+
+	if !cached || clock.Now().Sub(entry.fetchedAt) > p.Ttl {
+		rates, err := p.fetch(from)
+		if err != nil {
+			return 0, err
+		}
+		entry = httpRatesEntry{rates, clock.Now()}
+		p.mutex.Lock()
+		p.cache[from] = entry
+		p.mutex.Unlock()
+	}
+
+	rate, ok := entry.rates[to]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	return rate, nil
+}
+
+func (p *HttpRateProvider) fetch(base string) (map[string]float64, error) {
+	resp, err := p.Client.Get(p.Url + "?base=" + url.QueryEscape(base))
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	var feed struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&feed); err != nil || !strings.EqualFold(feed.Base, base) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	rates := map[string]float64{}
+	for k, v := range feed.Rates {
+		rates[strings.ToUpper(k)] = v
+	}
+	return rates, nil
+}
+
+// --------------------------------------------------------
+// Defining in-memory implementation of currency conversion between accounts
+func (r *InMemoryAccountRepository) ConvertAndTransfer(sender, recipient string, amount float64) error {
+	sender = NormalizeIban(sender)
+	recipient = NormalizeIban(recipient)
+
+	// Looking up account currencies first, so that the rate provider (possibly a remote one) is not called under the lock
+	r.Mutex.Lock()
+	sAcc, sExists := r.Accounts.Get(sender)
+	rAcc, rExists := r.Accounts.Get(recipient)
+	r.Mutex.Unlock()
+	if !sExists || sAcc == nil || !rExists || rAcc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if r.RateProvider == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[ExchangeRateUnavailableError][locale()])
+	}
+	rate, err := r.RateProvider.Rate(sAcc.Currency, rAcc.Currency)
+	if err != nil {
+		return err
+	}
+
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Ensuring that we indeed got the correct account objects
+	if sAcc.Iban != sender || rAcc.Iban != recipient {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if neither of the accounts is blocked
+	if sAcc.Status == Blocked || rAcc.Status == Blocked {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if neither of the accounts is closed
+	if sAcc.Status == Closed || rAcc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if neither of the accounts is a term deposit, a loan or a nostro account
+	if sAcc.Type == TermDepositAccount {
+		return fmt.Errorf(errorCodesToMessagesMap[TermDepositLockedError][locale()])
+	}
+	if sAcc.Type == LoanAccount || rAcc.Type == LoanAccount || rAcc.Type == TermDepositAccount || sAcc.Type == NostroAccount || rAcc.Type == NostroAccount {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if money amount to convert is not negative
+	if amount < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if product rules of sender account allow the transfer
+	if err := checkProductTransfer(sAcc, rAcc); err != nil {
+		return err
+	}
+	// Checking if debits from sender account and credits to recipient account are not frozen
+	if err := checkDebitRestriction(sAcc); err != nil {
+		return err
+	}
+	if err := checkCreditRestriction(rAcc); err != nil {
+		return err
+	}
+	// Checking if KYC status of sender account allows the debit
+	if err := checkKycDebit(sAcc, amount); err != nil {
+		return err
+	}
+	// Checking if the debit fits into the limits of sender account
+	if err := r.checkDebitLimits(sAcc, amount); err != nil {
+		return err
+	}
+	// Checking if sender has sufficient balance to convert the amount
+	if r, _ := roundAndExtractFractionsInCurrency(amount, sAcc.Currency); sAcc.AvailableBalance() < r {
+		return insufficientFundsError(sAcc)
+	}
+	credited := amount * rate
+	// Checking if both balances stay within the floor and the ceiling of the accounts
+	if err := checkBalanceFloor(sAcc, roundToCurrency(amount, sAcc.Currency)); err != nil {
+		return err
+	}
+	if err := checkBalanceCeiling(rAcc, roundToCurrency(credited, rAcc.Currency)); err != nil {
+		return err
+	}
+	// Checking if neither of the counterparties matches sanctions lists
+	outcome, entry := r.screenTransfer(sAcc, rAcc)
+	if outcome == ScreeningReject {
+		return fmt.Errorf(errorCodesToMessagesMap[SanctionsMatchError][locale()])
+	}
+
+	// Conversion is posted through the currency position, so that debits and credits balance in each of the currencies
+	tx := &Transaction{Type: ConversionTransaction, Sender: sender, Recipient: recipient, Amount: amount, Currency: sAcc.Currency, CreditedAmount: credited, CreditedCurrency: rAcc.Currency, Rate: rate}
+	if err := r.post(tx, debitLine(sAcc, amount), creditLedgerLine(FxPositionLedgerAccount, sAcc.Currency, amount), debitLedgerLine(FxPositionLedgerAccount, rAcc.Currency, credited), creditLine(rAcc, credited)); err != nil {
+		return err
+	}
+	sAcc.recordDebit(amount)
+	r.ConvertedBalances[sAcc.Currency] -= amount
+	r.ConvertedBalances[rAcc.Currency] += credited
+	if outcome == ScreeningFlag {
+		hit := ScreeningHit{tx.Ulid, sender, recipient, entry, tx.Timestamp}
+		r.ScreeningHits = append(r.ScreeningHits, hit)
+		r.publish(ScreeningHitEvent, hit)
+	}
+
+	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Convert money between accounts in different currencies using a fixed rate table
+func TestConvertAndTransfer(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	inMemImpl.RateProvider = NewFixedRateProvider(map[string]float64{"USD/BYN": 3.2})
+	service := NewAccountService(inMemImpl)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Use Case 14: converting money between accounts in different currencies\n")
+	usdAcc, err := service.OpenAccountInCurrency("usd")
+	if err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	if err := service.EmitMoney(100); err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	// Plain transfers between different currencies must be rejected
+	if _, err := service.TransferMoney(emission, usdAcc.Iban, 10); err == nil {
+		fmt.Fprintf(&builder, "Error: transfer between different currencies failed to fail\n")
+		t.Errorf(builder.String())
+		return
+	}
+	if err := service.ConvertAndTransfer(emission, usdAcc.Iban, 32); err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	if converted, _ := service.GetAccount(usdAcc.Iban); converted.Balance != 10 || inMemImpl.EmissionAccount.Balance != 68 {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: unexpected balances %.2f USD and %.2f BYN\n", converted.Balance, inMemImpl.EmissionAccount.Balance))
+		t.Errorf(builder.String())
+		return
+	}
+	// Converted money leaves BYN circulation, so the books must still be balanced
+	if res, err := service.ReconcileFractions(); err != nil || !res.Balanced {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v (%+v)\n", err, res))
+		t.Errorf(builder.String())
+		return
+	}
+
+	str, err := service.RetrieveAllTransactionsAsJson()
+	if err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	var transactions []struct {
+		Type string  `json:"type"`
+		Rate float64 `json:"rate"`
+	}
+	if err := json.Unmarshal([]byte(str), &transactions); err != nil || len(transactions) != 2 || transactions[1].Type != "Conversion" || math.Abs(transactions[1].Rate-1/3.2) > 1e-9 {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: applied rate is not recorded in the transaction log: %s\n", str))
+		t.Errorf(builder.String())
+		return
+	}
+	fmt.Fprintf(&builder, str)
+	fmt.Println(builder.String())
+}
+
+// Conversions count towards the limits of the sender account and are screened like transfers
+func TestConversionLimitsAndScreening(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.RateProvider = NewFixedRateProvider(map[string]float64{"USD/BYN": 3.2})
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	usdAcc, _ := service.OpenAccountInCurrency("usd")
+	if err := service.SetAccountLimits(sender.Iban, AccountLimits{PerTransaction: 40, Daily: 50}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.ConvertAndTransfer(sender.Iban, usdAcc.Iban, 41); err == nil {
+		t.Errorf("Conversion exceeding the per-transaction limit failed to fail")
+	}
+	if err := service.ConvertAndTransfer(sender.Iban, usdAcc.Iban, 32); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(sender.Iban, emission, 20); err == nil {
+		t.Errorf("Transfer exceeding the daily limit together with the conversion failed to fail")
+	}
+
+	sanctioned, _ := service.OpenAccountInCurrency("usd")
+	inMemImpl.ScreeningProvider = NewListScreeningProvider(ScreeningReject, []string{sanctioned.Iban}, nil)
+	if err := service.ConvertAndTransfer(sender.Iban, sanctioned.Iban, 10); err == nil {
+		t.Errorf("Conversion to sanctioned account failed to fail")
+	}
+	inMemImpl.ScreeningProvider = NewListScreeningProvider(ScreeningFlag, []string{sanctioned.Iban}, nil)
+	if err := service.ConvertAndTransfer(sender.Iban, sanctioned.Iban, 16); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(inMemImpl.ScreeningHits) != 1 || inMemImpl.ScreeningHits[0].Recipient != sanctioned.Iban {
+		t.Errorf("Expected the flagged conversion to be reported, got %+v", inMemImpl.ScreeningHits)
+	}
+}
+
+// Read rates from a JSON file and pick up changes made to it
+func TestFileRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"EUR/BYN": 3.5}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := NewFileRateProvider(path)
+	if rate, err := provider.Rate("BYN", "EUR"); err != nil || math.Abs(rate-1/3.5) > 1e-9 {
+		t.Errorf("Unexpected rate %f (%v)", rate, err)
+	}
+	if _, err := provider.Rate("USD", "BYN"); err == nil {
+		t.Errorf("Missing rate failed to fail")
+	}
+	if err := os.WriteFile(path, []byte(`{"EUR/BYN": 3.6}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if rate, err := provider.Rate("EUR", "BYN"); err != nil || rate != 3.6 {
+		t.Errorf("Updated rate was not picked up: %f (%v)", rate, err)
+	}
+}
+
+// Query rates from an HTTP feed and cache them per base currency
+func TestHttpRateProvider(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"base": "%s", "rates": {"BYN": 3.27}}`, req.URL.Query().Get("base"))
+	}))
+	defer server.Close()
+
+	provider := NewHttpRateProvider(server.URL, time.Minute)
+	for i := 0; i < 3; i++ {
+		if rate, err := provider.Rate("usd", "BYN"); err != nil || rate != 3.27 {
+			t.Errorf("Unexpected rate %f (%v)", rate, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected rates to be cached, feed was called %d times", calls)
+	}
+	if _, err := provider.Rate("USD", "JPY"); err == nil {
+		t.Errorf("Missing rate failed to fail")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining data subject requests of customers: the export gathers everything kept about the customer in one document,
+// the erasure removes personal data once all accounts of the customer are closed
+// Transactions and ledger entries are kept as they are since they refer to accounts by IBANs only and have to be retained
+// for record-keeping, so that balances of the books stay the same after the erasure
+type CustomerDataExport struct {
+	Customer      Customer                 `json:"customer"`
+	Accounts      []AccountDetails         `json:"accounts"`
+	Transactions  []Transaction            `json:"transactions"`  // transactions of all accounts of the customer, oldest first
+	Beneficiaries map[string][]Beneficiary `json:"beneficiaries"` // address books by IBANs of the accounts
+	ExportedAt    time.Time                `json:"exported_at"`
+}
+
+// Removes metadata, tags, saved beneficiaries and registered aliases of closed accounts of the customer,
+// returns the number of anonymized accounts, accounts stay linked to the customer ID which does not identify the person
+func (r *InMemoryAccountRepository) AnonymizeCustomerAccounts(customerID string) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	customerID = strings.TrimSpace(customerID)
+	// Checking if customer ID is set, existence of the customer is checked by the service layer
+	if customerID == "" {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[CustomerDoesNotExistError][locale()])
+	}
+	accounts := []*Account{}
+	for _, acc := range r.Accounts.All() {
+		if acc != nil && acc.CustomerID == customerID {
+			accounts = append(accounts, acc)
+		}
+	}
+	// Checking if all accounts of the customer are closed, otherwise the data is still needed to serve them
+	for _, acc := range accounts {
+		if acc.Status != Closed {
+			return 0, fmt.Errorf(errorCodesToMessagesMap[CustomerHasOpenAccountsError][locale()])
+		}
+	}
+
+	for _, acc := range accounts {
+		acc.Metadata = map[string]string{}
+		acc.Tags = []string{}
+		delete(r.Beneficiaries, acc.Iban)
+		for alias, iban := range r.Aliases {
+			if iban == acc.Iban {
+				delete(r.Aliases, alias)
+			}
+		}
+	}
+	return len(accounts), nil
+}
+
+// Replaces the name and the contact details of the customer, the customer ID stays valid for the records referring to it
+func (r *InMemoryCustomerRepository) EraseCustomer(id string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if customer with the given ID exists
+	c, exists := r.Customers[strings.TrimSpace(id)]
+	if !exists || c == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[CustomerDoesNotExistError][locale()])
+	}
+	c.Name, c.Email, c.Phone, c.Address = "", "", "", ""
+	if c.ErasedAt.IsZero() {
+		c.ErasedAt = clock.Now().UTC()
+	}
+	return nil
+}
+
+// Customers can request the export of their own data, see authorizeCustomer
+func (s *AccountService) ExportCustomerData(customerID string) (*CustomerDataExport, error) {
+	if err := s.authorizeCustomer(ReadPermission, customerID); err != nil {
+		return nil, err
+	}
+	c, err := s.customerRepoImpl.RetrieveCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.accountRepoImpl.FindAccounts(AccountQuery{CustomerID: c.ID})
+	if err != nil {
+		return nil, err
+	}
+	export := &CustomerDataExport{Customer: c, Accounts: accounts, Transactions: []Transaction{}, Beneficiaries: map[string][]Beneficiary{}, ExportedAt: clock.Now().UTC()}
+	seen := map[uint64]bool{}
+	for _, acc := range accounts {
+		transactions, err := s.accountRepoImpl.RetrieveAccountTransactions(acc.Iban, 0)
+		if err != nil {
+			return nil, err
+		}
+		// Transactions between accounts of the customer are listed once
+		for _, tx := range transactions {
+			if !seen[tx.ID] {
+				seen[tx.ID] = true
+				export.Transactions = append(export.Transactions, tx)
+			}
+		}
+		beneficiaries, err := s.accountRepoImpl.ListBeneficiaries(acc.Iban)
+		if err != nil {
+			return nil, err
+		}
+		if len(beneficiaries) > 0 {
+			export.Beneficiaries[acc.Iban] = beneficiaries
+		}
+	}
+	sort.Slice(export.Transactions, func(i, j int) bool { return export.Transactions[i].ID < export.Transactions[j].ID })
+	return export, nil
+}
+
+// Erases personal data of the customer, all accounts of the customer have to be closed beforehand
+func (s *AccountService) EraseCustomer(customerID string) error {
+	if err := s.authorize(ErasePermission); err != nil {
+		return s.audited(EraseAction, "", strings.TrimSpace(customerID), 0, err)
+	}
+	c, err := s.customerRepoImpl.RetrieveCustomer(customerID)
+	if err == nil {
+		if _, err = s.accountRepoImpl.AnonymizeCustomerAccounts(c.ID); err == nil {
+			err = s.customerRepoImpl.EraseCustomer(c.ID)
+		}
+	}
+	return s.audited(EraseAction, "", strings.TrimSpace(customerID), 0, err)
+}
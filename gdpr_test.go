@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+)
+
+// Export all data of a customer, then erase their personal data once their accounts are closed
+func TestCustomerDataExportAndErasure(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+
+	c, err := service.CreateCustomer("Ivan Ivanov", "ivan@example.com", "+375 (29) 123-45-67", "Minsk")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	first, _ := service.OpenAccountForCustomer(c.ID)
+	second, _ := service.OpenAccountForCustomer(c.ID)
+	other, _ := service.OpenAccount()
+	if err := service.UpdateAccountMetadata(first.Iban, map[string]string{"nickname": "Ivan's salary"}, []string{"salary"}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.RegisterAlias("ivan@example.com", first.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.SaveBeneficiary(first.Iban, "Mother", other.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service.EmitMoney(100)
+	service.TransferMoney(emission, first.Iban, 100)
+	service.InternalMoveMoney(c.ID, first.Iban, second.Iban, 40)
+	service.TransferMoney(second.Iban, other.Iban, 40)
+
+	export, err := service.ExportCustomerData(c.ID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if export.Customer.Name != "Ivan Ivanov" || len(export.Accounts) != 2 || len(export.Beneficiaries[first.Iban]) != 1 {
+		t.Errorf("Unexpected export %+v", export)
+	}
+	// The internal move is shared by both accounts and has to be listed once
+	if len(export.Transactions) != 3 {
+		t.Errorf("Expected 3 transactions, got %+v", export.Transactions)
+	}
+	for i := 1; i < len(export.Transactions); i++ {
+		if export.Transactions[i-1].ID > export.Transactions[i].ID {
+			t.Errorf("Expected transactions to be listed oldest first, got %+v", export.Transactions)
+		}
+	}
+
+	// Customers can export their own data only and cannot erase it themselves
+	customer := service.As(Principal{ID: "ivan", Role: CustomerRole, CustomerID: c.ID})
+	if _, err := customer.ExportCustomerData(c.ID); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if _, err := customer.ExportCustomerData("CUST-99999999"); err == nil {
+		t.Errorf("Export of data of another customer failed to fail")
+	}
+	if err := customer.EraseCustomer(c.ID); err == nil {
+		t.Errorf("Erasure by the customer failed to fail")
+	}
+
+	// Erasure has to wait until all accounts are closed
+	if err := service.EraseCustomer(c.ID); err == nil {
+		t.Errorf("Erasure of customer with open accounts failed to fail")
+	}
+	if err := service.CloseAccount(first.Iban, other.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.CloseAccount(second.Iban, ""); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	transactions := len(inMemImpl.Transactions)
+	if err := service.EraseCustomer(c.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	erased, err := service.RetrieveCustomer(c.ID)
+	if err != nil || erased.Name != "" || erased.Email != "" || erased.Phone != "" || erased.Address != "" || erased.ErasedAt.IsZero() {
+		t.Errorf("Expected personal details to be erased, got %+v (%v)", erased, err)
+	}
+	if acc, err := service.RetrieveAccount(first.Iban); err != nil || len(acc.Metadata) != 0 || len(acc.Tags) != 0 || acc.CustomerID != c.ID {
+		t.Errorf("Expected anonymized account linked to the customer, got %+v (%v)", acc, err)
+	}
+	if _, err := service.ResolveAlias("ivan@example.com"); err == nil {
+		t.Errorf("Expected alias of the erased customer to be removed")
+	}
+	if beneficiaries, _ := service.ListBeneficiaries(first.Iban); len(beneficiaries) != 0 {
+		t.Errorf("Expected beneficiaries of the erased customer to be removed, got %+v", beneficiaries)
+	}
+	// Ledger stays intact after the erasure
+	if len(inMemImpl.Transactions) != transactions {
+		t.Errorf("Expected %d transactions after erasure, got %d", transactions, len(inMemImpl.Transactions))
+	}
+	if rec, err := service.ReconcileFractions(); err != nil || !rec.Balanced {
+		t.Errorf("Expected books to be balanced after erasure, got %+v (%v)", rec, err)
+	}
+	if entries, _ := service.QueryAuditLog(AuditFilter{Actions: []AuditAction{EraseAction}}); len(entries) != 3 {
+		t.Errorf("Expected 3 audited erasures, got %+v", entries)
+	}
+	if err := service.EraseCustomer("CUST-99999999"); err == nil {
+		t.Errorf("Erasure of unknown customer failed to fail")
+	}
+}
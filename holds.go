@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining two-phase holds: money is reserved first (authorization) and debited later (capture) or made available again (release)
+// Held money stays on the booked balance of the account but is excluded from its available balance
+type HoldStatus int8
+
+const (
+	HoldActive HoldStatus = iota
+	HoldCaptured
+	HoldReleased
+)
+
+// Mapping hold status codes to hold status names considering locale
+var holdStatusCodeToNameMap map[HoldStatus](map[LanguageCode]string) = map[HoldStatus](map[LanguageCode]string){
+	HoldActive: {
+		English: "Active",
+		Russian: "Активная",
+	},
+	HoldCaptured: {
+		English: "Captured",
+		Russian: "Списана",
+	},
+	HoldReleased: {
+		English: "Released",
+		Russian: "Снята",
+	},
+}
+
+type Hold struct {
+	ID              string
+	Iban            string
+	Amount          float64
+	CapturedAmount  float64
+	Status          HoldStatus
+	TransferID      string // ID of the transfer made on capture
+	FreezeID        string // legal freeze reserving money by the hold, such holds are only released with ReleaseFreeze
+	AuthorizationID string // card authorization reserving money by the hold, such holds are captured or voided with it
+	CreatedAt       time.Time
+}
+
+// Holds can only be placed on active accounts and are subject to the same KYC limits as debits
+func (r *InMemoryAccountRepository) HoldFunds(iban string, amount float64) (*Hold, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	hold, err := r.holdFunds(iban, amount)
+	if err != nil {
+		return nil, err
+	}
+	copied := *hold
+	return &copied, nil
+}
+
+// Helper function to place a hold, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) holdFunds(iban string, amount float64) (*Hold, error) {
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is neither blocked nor closed
+	if acc.Status == Blocked {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	if acc.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the account is not a term deposit locked until maturity
+	if acc.Type == TermDepositAccount {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[TermDepositLockedError][locale()])
+	}
+	// Checking if money amount to hold is not negative
+	if amount < 0 {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if debits from the account are not frozen, held money is meant to be debited
+	if err := checkDebitRestriction(acc); err != nil {
+		return nil, err
+	}
+	// Checking if KYC status of the account allows the debit
+	if err := checkKycDebit(acc, amount); err != nil {
+		return nil, err
+	}
+	amount = roundToCurrency(amount, acc.Currency)
+	// Checking if the account has sufficient available balance
+	if acc.AvailableBalance() < amount {
+		return nil, insufficientFundsError(acc)
+	}
+	// Checking if the balance less holds stays at or above the floor, so that the hold can be captured
+	if err := checkBalanceFloor(acc, amount); err != nil {
+		return nil, err
+	}
+
+	acc.Held = roundToCurrency(acc.Held+amount, acc.Currency)
+	now := clock.Now().UTC()
+	hold := &Hold{ID: NewUlid(now), Iban: iban, Amount: amount, Status: HoldActive, CreatedAt: now}
+	r.Holds[hold.ID] = hold
+	return hold, nil
+}
+
+// Helper function to find an active hold and its account, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) activeHold(holdID string) (*Hold, *Account, error) {
+	hold, exists := r.Holds[strings.ToUpper(strings.TrimSpace(holdID))]
+	if !exists || hold == nil {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[HoldDoesNotExistError][locale()])
+	}
+	if hold.Status != HoldActive {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[HoldIsNotActiveError][locale()])
+	}
+	// Checking if the hold does not reserve money frozen by a legal freeze
+	if hold.FreezeID != "" {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[FreezeCaseMismatchError][locale()])
+	}
+	acc, exists := r.Accounts.Get(hold.Iban)
+	if !exists || acc == nil {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	return hold, acc, nil
+}
+
+// Captures the amount (the whole hold if zero) and releases the rest of the hold
+func (r *InMemoryAccountRepository) CaptureHold(holdID, recipient string, amount float64) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	hold, acc, err := r.activeHold(holdID)
+	if err != nil {
+		return "", err
+	}
+	// Checking if the hold is not reserved by a card authorization, such holds are only settled with the authorization
+	if hold.AuthorizationID != "" {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AuthorizationHoldError][locale()])
+	}
+	return r.captureHold(hold, acc, recipient, amount)
+}
+
+// Helper function to capture the active hold, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) captureHold(hold *Hold, acc *Account, recipient string, amount float64) (string, error) {
+	if amount == 0 {
+		amount = hold.Amount
+	}
+	// Checking if captured amount is positive and does not exceed the hold
+	if amount < 0 {
+		return "", fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	if roundToCurrency(amount, acc.Currency) > hold.Amount {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InsufficientAccountBalanceError][locale()])
+	}
+
+	// Lifting the hold before the transfer, so that the held money becomes available for it, and restoring it if the transfer fails
+	acc.Held = roundToCurrency(acc.Held-hold.Amount, acc.Currency)
+	err := r.transferMoney(hold.Iban, recipient, amount, TransferDetails{Reference: hold.ID})
+	id := r.trackTransfer(err)
+	if err != nil {
+		acc.Held = roundToCurrency(acc.Held+hold.Amount, acc.Currency)
+		return id, err
+	}
+	hold.Status = HoldCaptured
+	hold.CapturedAmount = roundToCurrency(amount, acc.Currency)
+	hold.TransferID = id
+	return id, nil
+}
+
+func (r *InMemoryAccountRepository) ReleaseHold(holdID string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	hold, acc, err := r.activeHold(holdID)
+	if err != nil {
+		return err
+	}
+	if hold.AuthorizationID != "" {
+		return fmt.Errorf(errorCodesToMessagesMap[AuthorizationHoldError][locale()])
+	}
+	r.releaseHold(hold, acc)
+	return nil
+}
+
+// Helper function to release the active hold, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) releaseHold(hold *Hold, acc *Account) {
+	acc.Held = roundToCurrency(acc.Held-hold.Amount, acc.Currency)
+	hold.Status = HoldReleased
+}
+
+func (r *InMemoryAccountRepository) RetrieveHold(holdID string) (Hold, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	hold, exists := r.Holds[strings.ToUpper(strings.TrimSpace(holdID))]
+	if !exists || hold == nil {
+		return Hold{}, fmt.Errorf(errorCodesToMessagesMap[HoldDoesNotExistError][locale()])
+	}
+	return *hold, nil
+}
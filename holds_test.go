@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+// Hold part of the balance, then capture or release it
+func TestHolds(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	card, _ := service.OpenAccountWithInitialDeposit(100)
+	merchant, _ := service.OpenAccount()
+
+	first, err := service.HoldFunds(card.Iban, 60)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(card.Iban); details.Balance != 100 || details.Held != 60 || details.Available != 40 {
+		t.Errorf("Unexpected balances with active hold: %+v", details)
+	}
+	// Held money cannot be spent or held again
+	if _, err := service.TransferMoney(card.Iban, merchant.Iban, 50); err == nil {
+		t.Errorf("Transfer of held money failed to fail")
+	}
+	if _, err := service.HoldFunds(card.Iban, 50); err == nil {
+		t.Errorf("Holding more than available balance failed to fail")
+	}
+	if err := service.CloseAccount(card.Iban, ""); err == nil {
+		t.Errorf("Closing account with active hold failed to fail")
+	}
+	second, err := service.HoldFunds(card.Iban, 30)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Partial capture releases the rest of the hold
+	if _, err := service.CaptureHold(first.ID, merchant.Iban, 70); err == nil {
+		t.Errorf("Capturing more than held failed to fail")
+	}
+	id, err := service.CaptureHold(first.ID, merchant.Iban, 45.5)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferSettled {
+		t.Errorf("Expected capture transfer to be settled")
+	}
+	if details, _ := service.RetrieveAccount(card.Iban); details.Balance != 54.5 || details.Held != 30 || details.Available != 24.5 {
+		t.Errorf("Unexpected balances after capture: %+v", details)
+	}
+	if _, err := service.CaptureHold(first.ID, merchant.Iban, 0); err == nil {
+		t.Errorf("Capturing already captured hold failed to fail")
+	}
+
+	if err := service.ReleaseHold(second.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.ReleaseHold(second.ID); err == nil {
+		t.Errorf("Releasing already released hold failed to fail")
+	}
+	if hold, _ := service.RetrieveHold(second.ID); hold.Status != HoldReleased {
+		t.Errorf("Expected hold to be released, got %s", holdStatusCodeToNameMap[hold.Status][locale()])
+	}
+	if details, _ := service.RetrieveAccount(card.Iban); details.Held != 0 || details.Available != 54.5 {
+		t.Errorf("Unexpected balances after release: %+v", details)
+	}
+
+	// Failed capture keeps the hold active
+	third, _ := service.HoldFunds(card.Iban, 10)
+	if err := service.BlockAccount(merchant.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.CaptureHold(third.ID, merchant.Iban, 0); err == nil {
+		t.Errorf("Capturing to a blocked account failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(card.Iban); details.Held != 10 {
+		t.Errorf("Expected hold to stay active after failed capture: %+v", details)
+	}
+	if _, err := service.RetrieveHold("unknown"); err == nil {
+		t.Errorf("Retrieving unknown hold failed to fail")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// --------------------------------------------------------
+// Defining hot accounts: system accounts most transfers go through, i.e. the emission account paying out initial deposits or
+// the fee income account, keep movements of their balances in an atomic counter of minor units instead of the balance itself,
+// so that transfers from and to them run in parallel without locking them, see locks.go
+// Movements are folded into the balance by the reconciliation holding the repository mutex exclusively, the scheduler runs
+// it periodically, until then the booked balance of the account is its balance plus the counter, see BookedBalance
+// Fractions of hot accounts are still kept as they are, they are only changed under the journal mutex
+
+type hotBalance struct {
+	pending atomic.Int64 // movements not reconciled yet, in minor units of the currency of the account
+}
+
+// Helper function to convert the amount rounded to the currency into its minor units
+func toMinorUnits(amount float64, currency string) int64 {
+	return int64(math.Round(amount * math.Pow10(minorUnitsOf(currency))))
+}
+
+func fromMinorUnits(units int64, currency string) float64 {
+	return roundToCurrency(float64(units)/math.Pow10(minorUnitsOf(currency)), currency)
+}
+
+// Balance including movements of hot accounts not reconciled yet, the same as the balance for other accounts
+func (acc *Account) BookedBalance() float64 {
+	if acc.hot == nil {
+		return acc.Balance
+	}
+	return roundToCurrency(acc.Balance+fromMinorUnits(acc.hot.pending.Load(), acc.Currency), acc.Currency)
+}
+
+// Helper function to fold movements of the hot account into its balance, expects the repository mutex to be held exclusively
+// by the caller, returns whether there was anything to fold
+func (acc *Account) reconcileHotBalance() bool {
+	units := acc.hot.pending.Swap(0)
+	acc.Balance = roundToCurrency(acc.Balance+fromMinorUnits(units, acc.Currency), acc.Currency)
+	return units != 0
+}
+
+// Only system accounts can be hot, ordinary accounts are subject to limits counted along with their balances
+// Turning the account cold reconciles its movements first
+func (r *InMemoryAccountRepository) SetHotAccount(iban string, hot bool) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is a system account
+	if acc.Type == Ordinary {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	switch {
+	case hot && acc.hot == nil:
+		acc.hot = &hotBalance{}
+	case !hot && acc.hot != nil:
+		acc.reconcileHotBalance()
+		acc.hot = nil
+	}
+	return nil
+}
+
+// Folds movements of all hot accounts into their balances, returns the number of accounts which had movements to fold
+func (r *InMemoryAccountRepository) ReconcileHotAccounts() (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	reconciled := 0
+	for _, acc := range r.Accounts.All() {
+		if acc.hot != nil && acc.reconcileHotBalance() {
+			reconciled++
+		}
+	}
+	return reconciled, nil
+}
+
+// Helper function to leave hot accounts out of the accounts to lock, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) lockedAccounts(ibans ...string) []string {
+	locked := []string{}
+	for _, iban := range ibans {
+		if acc, exists := r.Accounts.Get(NormalizeIban(iban)); !exists || acc.hot == nil {
+			locked = append(locked, iban)
+		}
+	}
+	return locked
+}
+
+// Helper function to check the balances of hot accounts of the prepared transfer again, since they are not locked and other
+// transfers may have moved them after the checks, expects the journal mutex to be held by the caller
+func (r *InMemoryAccountRepository) recheckHotAccounts(t *preparedTransfer) error {
+	rounded := roundToCurrency(t.amount, t.sAcc.Currency)
+	if t.sAcc.hot != nil {
+		if t.sAcc.AvailableBalance() < rounded {
+			return insufficientFundsError(t.sAcc)
+		}
+		if err := checkBalanceFloor(t.sAcc, rounded+t.fee-t.deducted); err != nil {
+			return err
+		}
+	}
+	if t.rAcc.hot != nil {
+		return checkBalanceCeiling(t.rAcc, rounded-t.deducted)
+	}
+	return nil
+}
+
+// Reconciles hot accounts along with the scheduled transfers, returns the number of accounts which had movements to fold
+func (s *TransferScheduler) RunHotAccountReconciliation() int {
+	reconciled, _ := s.service.ReconcileHotAccounts()
+	return reconciled
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Pay out of the hot emission account in parallel, it is never overdrawn and balances add up before and after reconciliation
+func TestHotAccounts(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	ibans := openLockTestAccounts(t, service, 4, 0)
+	if err := service.SetHotAccount(ibans[0], true); err == nil {
+		t.Errorf("Making ordinary account hot failed to fail")
+	}
+	if err := service.SetHotAccount(inMemImpl.EmissionAccount.Iban, true); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.EmitMoney(100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Transfers from the hot account do not wait for its lock
+	unlock := inMemImpl.AccountLocks.Lock(inMemImpl.EmissionAccount.Iban)
+	if _, err := service.TransferMoney(inMemImpl.EmissionAccount.Iban, ibans[0], 0.5); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	unlock()
+
+	wg := sync.WaitGroup{}
+	failed := make(chan error, 150)
+	for i := 0; i < 150; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := service.TransferMoney(inMemImpl.EmissionAccount.Iban, ibans[i%len(ibans)], 1); err != nil {
+				failed <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(failed) != 51 {
+		t.Errorf("Expected 51 transfers to fail for insufficient funds, got %d", len(failed))
+	}
+	if details, _ := service.RetrieveAccount(inMemImpl.EmissionAccount.Iban); details.Balance != 0.5 || inMemImpl.EmissionAccount.Balance != 0 {
+		t.Errorf("Expected booked balance 0.5 before reconciliation, got %v and %v", details.Balance, inMemImpl.EmissionAccount.Balance)
+	}
+	if _, err := service.VerifyInvariants(); err != nil {
+		t.Errorf("Expected invariants to hold before reconciliation, got %v", err)
+	}
+
+	if reconciled, err := service.ReconcileHotAccounts(); err != nil || reconciled != 1 {
+		t.Errorf("Expected one account to be reconciled, got %d, %v", reconciled, err)
+	}
+	if inMemImpl.EmissionAccount.Balance != 0.5 {
+		t.Errorf("Expected balance 0.5 after reconciliation, got %v", inMemImpl.EmissionAccount.Balance)
+	}
+	if reconciled, _ := service.ReconcileHotAccounts(); reconciled != 0 {
+		t.Errorf("Expected nothing to reconcile, got %d", reconciled)
+	}
+	if supply, _ := service.MoneySupply(); supply.Undistributed != 0.5 || supply.Circulating != 99.5 {
+		t.Errorf("Unexpected money supply %+v", supply)
+	}
+}
+
+// Turning the account cold reconciles it, the scheduler reconciles hot accounts periodically
+func TestHotAccountReconciliation(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	emission := inMemImpl.EmissionAccount.Iban
+	service.SetHotAccount(emission, true)
+	service.EmitMoney(10)
+	if err := service.SetHotAccount(emission, false); err != nil || inMemImpl.EmissionAccount.Balance != 10 {
+		t.Errorf("Expected balance 10 after turning the account cold, got %v, %v", inMemImpl.EmissionAccount.Balance, err)
+	}
+
+	service.SetHotAccount(emission, true)
+	service.EmitMoney(5)
+	stop := NewTransferScheduler(service).Start(time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if snapshot, _ := service.GetAccount(emission); snapshot.Balance != 15 {
+			t.Fatalf("Expected booked balance 15, got %v", snapshot.Balance)
+		}
+		inMemImpl.Mutex.RLock()
+		balance := inMemImpl.EmissionAccount.Balance
+		inMemImpl.Mutex.RUnlock()
+		if balance == 15 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	if inMemImpl.EmissionAccount.Balance != 15 {
+		t.Errorf("Expected the scheduler to reconcile the account, got %v", inMemImpl.EmissionAccount.Balance)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// --------------------------------------------------------
+// Defining the registry of IBAN formats by country as published by SWIFT: the length of the IBAN and the structure of the
+// BBAN in the notation of the registry, i.e. "4!c4!n16!c" for Belarus, where every field is the count of characters
+// followed by the kind of them, n for digits, a for upper case letters and c for both, ! marking the length as fixed
+type IbanFormat struct {
+	Country          string
+	Length           int    // length of the whole IBAN including the country code and the check digits
+	Bban             string // structure of the BBAN
+	BankCodeLength   int    // leading characters of the BBAN identifying the bank
+	BranchCodeLength int    // characters following the bank code which identify the branch, if the country has branch codes
+	// Returns the BBAN with the national check digits of the country recalculated, countries without them have none
+	NationalCheck func(bban string) string
+}
+
+var ibanRegistry map[string]IbanFormat = map[string]IbanFormat{
+	"BY": {"BY", 28, "4!c4!n16!c", 4, 0, nil},
+	"DE": {"DE", 22, "8!n10!n", 8, 0, nil},
+	"FR": {"FR", 27, "5!n5!n11!c2!n", 5, 5, frenchNationalCheck},
+	"GB": {"GB", 22, "4!a6!n8!n", 4, 6, nil},
+	"PL": {"PL", 28, "8!n16!n", 8, 0, polishNationalCheck},
+	"UA": {"UA", 29, "6!n19!c", 6, 0, nil},
+	"LT": {"LT", 20, "5!n11!n", 5, 0, nil},
+	"LV": {"LV", 21, "4!a13!c", 4, 0, nil},
+}
+
+type ibanField struct {
+	length int
+	kind   byte
+}
+
+// Helper function to split the structure of the BBAN into fields, i.e. "4!a6!n" into 4 letters and 6 digits
+func ibanFields(structure string) []ibanField {
+	fields := []ibanField{}
+	for _, part := range strings.SplitAfter(structure, "!") {
+		if part == "" {
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].kind = part[0]
+			part = part[1:]
+		}
+		length, _ := strconv.Atoi(strings.TrimSuffix(part, "!"))
+		if length > 0 {
+			fields = append(fields, ibanField{length: length})
+		}
+	}
+	return fields
+}
+
+// Helper function to check if the characters are of the kind of the field
+func matchesIbanField(value string, kind byte) bool {
+	for _, char := range value {
+		digit, letter := char >= '0' && char <= '9', char >= 'A' && char <= 'Z'
+		if (kind == 'n' && !digit) || (kind == 'a' && !letter) || (kind == 'c' && !digit && !letter) {
+			return false
+		}
+	}
+	return true
+}
+
+// Recalculates the key of the RIB, the last 2 digits, from the bank code, the branch code and the account number with
+// letters of the account number replaced by digits as the French standard requires
+func frenchNationalCheck(bban string) string {
+	account := []byte(bban[10:21])
+	for i, char := range account {
+		if char >= 'A' && char <= 'Z' {
+			account[i] = "12345678912345678923456789"[char-'A']
+		}
+	}
+	bank, _ := strconv.ParseInt(bban[:5], 10, 64)
+	branch, _ := strconv.ParseInt(bban[5:10], 10, 64)
+	number, _ := strconv.ParseInt(string(account), 10, 64)
+	return bban[:21] + fmt.Sprintf("%02d", 97-(89*bank+15*branch+3*number)%97)
+}
+
+// Recalculates the last digit of the 8-digit sort code from the first 7 digits weighted by 3, 9, 7, 1, 3, 9 and 7
+func polishNationalCheck(bban string) string {
+	weights, sum := []int{3, 9, 7, 1, 3, 9, 7}, 0
+	for i, weight := range weights {
+		sum += int(bban[i]-'0') * weight
+	}
+	return bban[:7] + strconv.Itoa((10-sum%10)%10) + bban[8:]
+}
+
+// Checks if the BBAN has the structure of the country
+func (f IbanFormat) MatchesBban(bban string) bool {
+	if len(bban) != f.Length-4 {
+		return false
+	}
+	offset := 0
+	for _, field := range ibanFields(f.Bban) {
+		if offset+field.length > len(bban) || !matchesIbanField(bban[offset:offset+field.length], field.kind) {
+			return false
+		}
+		offset += field.length
+	}
+	return offset == len(bban) && (f.NationalCheck == nil || f.NationalCheck(bban) == bban)
+}
+
+// Returns the format of the country, countries missing in the registry do not use IBANs as far as the bank is concerned
+func LookupIbanFormat(countryCode string) (IbanFormat, bool) {
+	format, ok := ibanRegistry[strings.ToUpper(strings.TrimSpace(countryCode))]
+	return format, ok
+}
+
+// Calculates the check digits of the IBAN of the country with the BBAN, the country code and zero check digits are moved
+// behind the BBAN before the mod-97 operation as ISO 13616 requires
+func CalculateCheckDigits(countryCode, bban string) (string, error) {
+	numeric, err := ConvertIbanToNumericForm(bban + countryCode + "00")
+	if err != nil {
+		return "", err
+	}
+	return CalculateIbanCheckDigits(numeric), nil
+}
+
+// Helper function to pick the random index below n, with crypto/rand if secure generation is configured
+func randomIbanIndex(n int) (int, error) {
+	if !secureIbanGeneration {
+		return random.Intn(n), nil
+	}
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(index.Int64()), nil
+}
+
+// Generates the IBAN of the bank in the country with the random account number, the bank code has to fit the leading
+// fields of the BBAN of the country, i.e. 8 digits of the Bankleitzahl for Germany
+func GenerateIban(countryCode, bankCode string) (string, error) {
+	format, ok := LookupIbanFormat(countryCode)
+	if !ok {
+		return "", fmt.Errorf(errorCodesToMessagesMap[UnsupportedIbanCountryError][locale()])
+	}
+	bankCode = strings.ToUpper(strings.TrimSpace(bankCode))
+	if len(bankCode) != format.BankCodeLength {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidBankCodeError][locale()])
+	}
+
+	var bban strings.Builder
+	for _, field := range ibanFields(format.Bban) {
+		for i := 0; i < field.length; i++ {
+			// Taking the bank code for the leading characters and generating the rest
+			if bban.Len() < len(bankCode) {
+				bban.WriteByte(bankCode[bban.Len()])
+				continue
+			}
+			alphabet := "0123456789"
+			if field.kind == 'a' {
+				alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+			}
+			index, err := randomIbanIndex(len(alphabet))
+			if err != nil {
+				return "", err
+			}
+			bban.WriteByte(alphabet[index])
+		}
+	}
+	generated := bban.String()
+	if format.NationalCheck != nil {
+		generated = format.NationalCheck(generated)
+	}
+	// Checking if the bank code is intact after the national check digits have been recalculated
+	if !format.MatchesBban(generated) || !strings.HasPrefix(generated, bankCode) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidBankCodeError][locale()])
+	}
+	checkDigits, err := CalculateCheckDigits(format.Country, generated)
+	if err != nil {
+		return "", err
+	}
+	return format.Country + checkDigits + generated, nil
+}
+
+// Components of the IBAN by the format of the country, the account number is the rest of the BBAN after the bank and the
+// branch codes, national check digits included
+type IbanComponents struct {
+	CountryCode   string
+	CheckDigits   string
+	BankCode      string
+	BranchCode    string
+	AccountNumber string
+}
+
+// Splits the valid IBAN into components, spaces are ignored
+func ParseIban(iban string) (IbanComponents, error) {
+	iban = NormalizeIban(iban)
+	if len(iban) < 4 {
+		return IbanComponents{}, fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale()])
+	}
+	format, ok := LookupIbanFormat(iban[:2])
+	if !ok {
+		return IbanComponents{}, fmt.Errorf(errorCodesToMessagesMap[UnsupportedIbanCountryError][locale()])
+	}
+	if !IsValidIban(iban) {
+		return IbanComponents{}, fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale()])
+	}
+	bban := iban[4:]
+	branchEnd := format.BankCodeLength + format.BranchCodeLength
+	return IbanComponents{CountryCode: iban[:2], CheckDigits: iban[2:4], BankCode: bban[:format.BankCodeLength],
+		BranchCode: bban[format.BankCodeLength:branchEnd], AccountNumber: bban[branchEnd:]}, nil
+}
+
+// Directory of the banks known by country, bank codes map to the names of the banks
+type BankDirectory map[string]map[string]string
+
+// Banks IBANs of the countries of the directory have to belong to, IBANs of other countries are checked by the structure
+// only, the directory is not used if nil, see Config
+var bankDirectory BankDirectory
+
+// Reads the directory from the JSON file of the form {"DE": {"37040044": "Commerzbank"}}
+func LoadBankDirectory(path string) (BankDirectory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	directory := BankDirectory{}
+	if err := json.Unmarshal(data, &directory); err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+	}
+	normalized := BankDirectory{}
+	for country, banks := range directory {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		normalized[country] = map[string]string{}
+		for code, name := range banks {
+			normalized[country][strings.ToUpper(strings.TrimSpace(code))] = name
+		}
+	}
+	return normalized, nil
+}
+
+// Validates the IBAN of another bank, i.e. the recipient of the interbank transfer, beyond IsValidIban the bank has to be
+// in the directory if the directory lists banks of the country
+func ValidateIban(iban string) error {
+	components, err := ParseIban(iban)
+	if err != nil {
+		return err
+	}
+	if banks, listed := bankDirectory[components.CountryCode]; listed {
+		if _, known := banks[components.BankCode]; !known {
+			return fmt.Errorf(errorCodesToMessagesMap[UnknownBankCodeError][locale()])
+		}
+	}
+	return nil
+}
+
+// Strips whitespace of any kind, non-breaking and zero-width spaces included since IBANs are often copied from documents,
+// and upper cases the IBAN, so that the same account is always looked up by the same key
+func NormalizeIban(iban string) string {
+	return strings.ToUpper(strings.Map(func(char rune) rune {
+		if unicode.IsSpace(char) || char == '\u200b' || char == '\ufeff' {
+			return -1
+		}
+		return char
+	}, iban))
+}
+
+// Formats the IBAN for people in groups of four characters separated by spaces, i.e. BY84 ALFA 1000 0000 0000 0000 0000
+func FormatIban(iban string) string {
+	iban = NormalizeIban(iban)
+	var formatted strings.Builder
+	for i := 0; i < len(iban); i += 4 {
+		if i > 0 {
+			formatted.WriteByte(' ')
+		}
+		formatted.WriteString(iban[i:min(i+4, len(iban))])
+	}
+	return formatted.String()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Validate published sample IBANs of the countries of the registry and reject the ones broken in structure or checksum
+func TestIbanRegistry(t *testing.T) {
+	valid := []string{
+		"DE89 3704 0044 0532 0130 00",
+		"FR14 2004 1010 0505 0001 3M02 606",
+		"GB29 NWBK 6016 1331 9268 19",
+		"PL61 1090 1014 0000 0712 1981 2874",
+		"UA21 3223 1300 0002 6007 2335 6600 1",
+		"LT12 1000 0111 0100 1000",
+		"LV80 BANK 0000 4351 9500 1",
+		"BY13 NBRB 3600 9000 0000 2Z00 AB00",
+	}
+	for _, iban := range valid {
+		if !IsValidIban(iban) {
+			t.Errorf("Expected %s to be valid", iban)
+		}
+	}
+	invalid := []string{
+		"DE88 3704 0044 0532 0130 00", // check digits
+		"DE89 3704 0044 0532 0130 0",  // length
+		"GB82 1234 5698 7654 3210 00", // digits where the bank code has letters
+		"NO93 8601 1117 947",          // country missing in the registry
+		"BY13",
+	}
+	for _, iban := range invalid {
+		if IsValidIban(iban) {
+			t.Errorf("Expected %s to be invalid", iban)
+		}
+	}
+}
+
+// Generate IBANs of the bank in every country of the registry
+func TestIbanGeneration(t *testing.T) {
+	bankCodes := map[string]string{"BY": "ALFA", "DE": "37040044", "FR": "20041", "GB": "NWBK", "PL": "10901014", "UA": "322313", "LT": "10000", "LV": "BANK"}
+	for country, bankCode := range bankCodes {
+		iban, err := GenerateIban(country, bankCode)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if format, _ := LookupIbanFormat(country); !IsValidIban(iban) || len(iban) != format.Length || !strings.HasPrefix(iban[4:], bankCode) {
+			t.Errorf("Expected valid IBAN of bank %s in %s, got %s", bankCode, country, iban)
+		}
+	}
+	if _, err := GenerateIban("NO", "8601"); err == nil || err.Error() != errorCodesToMessagesMap[UnsupportedIbanCountryError][locale()] {
+		t.Errorf("Generation for the country missing in the registry failed to fail, got %v", err)
+	}
+	for country, bankCode := range map[string]string{"DE": "3704", "GB": "1234", "BY": "AL-A"} {
+		if _, err := GenerateIban(country, bankCode); err == nil || err.Error() != errorCodesToMessagesMap[InvalidBankCodeError][locale()] {
+			t.Errorf("Generation with bank code %s in %s failed to fail, got %v", bankCode, country, err)
+		}
+	}
+}
+
+// Split IBANs into components by the format of the country and route them by the bank code
+func TestIbanParsing(t *testing.T) {
+	expected := map[string]IbanComponents{
+		"DE89 3704 0044 0532 0130 00":       {"DE", "89", "37040044", "", "0532013000"},
+		"fr14 2004 1010 0505 0001 3m02 606": {"FR", "14", "20041", "01005", "0500013M02606"},
+		"GB29NWBK60161331926819":            {"GB", "29", "NWBK", "601613", "31926819"},
+	}
+	for iban, components := range expected {
+		if parsed, err := ParseIban(iban); err != nil || parsed != components {
+			t.Errorf("Expected %s to be parsed into %+v, got %+v and %v", iban, components, parsed, err)
+		}
+	}
+	if _, err := ParseIban("NO93 8601 1117 947"); err == nil || err.Error() != errorCodesToMessagesMap[UnsupportedIbanCountryError][locale()] {
+		t.Errorf("Parsing IBAN of the country missing in the registry failed to fail, got %v", err)
+	}
+	if _, err := ParseIban("DE88 3704 0044 0532 0130 00"); err == nil || err.Error() != errorCodesToMessagesMap[InvalidIbanError][locale()] {
+		t.Errorf("Parsing invalid IBAN failed to fail, got %v", err)
+	}
+	if bankCodeOfIban("DE89 3704 0044 0532 0130 00") != "37040044" || bankCodeOfIban("BY84 ALFA 1000 0000 0000 0000 0000") != "ALFA" {
+		t.Errorf("Unexpected bank codes %s and %s", bankCodeOfIban("DE89 3704 0044 0532 0130 00"), bankCodeOfIban("BY84 ALFA 1000 0000 0000 0000 0000"))
+	}
+}
+
+// Reject IBANs with valid checksums but impossible national check digits and banks missing in the directory
+func TestBbanValidation(t *testing.T) {
+	// Check digits of the IBANs below are recalculated, so that only the national check digits are wrong
+	for country, bban := range map[string]string{"FR": "20041010050500013M02607", "PL": "109010150000071219812874"} {
+		checkDigits, _ := CalculateCheckDigits(country, bban)
+		if iban := country + checkDigits + bban; IsValidIban(iban) {
+			t.Errorf("Expected %s with wrong national check digits to be invalid", iban)
+		}
+	}
+	if _, err := GenerateIban("PL", "10901015"); err == nil || err.Error() != errorCodesToMessagesMap[InvalidBankCodeError][locale()] {
+		t.Errorf("Generation with the sort code of wrong check digit failed to fail, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "banks.json")
+	os.WriteFile(path, []byte(`{"de": {"37040044": "Commerzbank"}}`), 0600)
+	config, err := LoadConfig([]string{"-bank-directory-file", path})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer func(directory BankDirectory) { bankDirectory = directory }(bankDirectory)
+	config.Apply()
+	if err := ValidateIban("DE89 3704 0044 0532 0130 00"); err != nil {
+		t.Errorf("Expected the IBAN of the known bank to be valid, got %v", err)
+	}
+	unknown, _ := GenerateIban("DE", "10010010")
+	if err := ValidateIban(unknown); err == nil || err.Error() != errorCodesToMessagesMap[UnknownBankCodeError][locale()] {
+		t.Errorf("Expected the IBAN of the unknown bank to be rejected, got %v", err)
+	}
+	if err := ValidateIban("GB29 NWBK 6016 1331 9268 19"); err != nil {
+		t.Errorf("Expected IBANs of countries missing in the directory to be checked by the structure only, got %v", err)
+	}
+	if _, err := LoadConfig([]string{"-bank-directory-file", filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Errorf("Loading config with missing bank directory failed to fail")
+	}
+}
+
+// Normalize IBANs copied from documents and format them for people
+func TestIbanNormalization(t *testing.T) {
+	normalized := map[string]string{
+		" by84 alfa 1000 0000 0000 0000 0000 ":     "BY84ALFA10000000000000000000",
+		"DE89 3704 0044 0532\t0130\n00":            "DE89370400440532013000",
+		"\ufeffGB29\u200bNWBK6016\u00a01331926819": "GB29NWBK60161331926819",
+	}
+	for iban, expected := range normalized {
+		if NormalizeIban(iban) != expected {
+			t.Errorf("Expected %q to be normalized to %s, got %s", iban, expected, NormalizeIban(iban))
+		}
+	}
+	if formatted := FormatIban("de89370400440532013000"); formatted != "DE89 3704 0044 0532 0130 00" {
+		t.Errorf("Unexpected formatted IBAN %q", formatted)
+	}
+
+	// Accounts are found whatever spaces the IBAN comes with
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccount()
+	if _, err := service.RetrieveAccount(strings.ToLower(FormatIban(acc.Iban)) + " "); err != nil {
+		t.Errorf("Expected the account to be found by the formatted IBAN, got %v", err)
+	}
+}
+
+// Generate valid IBANs in one pass and give up on collisions after the configured attempts
+func TestDirectIbanGeneration(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		if iban, err := GenerateBelarusianIban(); err != nil || !IsValidIban(iban) {
+			t.Fatalf("Expected every generated IBAN to be valid, got %s and %v", iban, err)
+		}
+	}
+
+	defer func(attempts int) { maxIbanGenerationAttempts = attempts }(maxIbanGenerationAttempts)
+	defer UseRandomSource(rand.NewSource(7))()
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccount()
+	maxIbanGenerationAttempts = 1
+	UseRandomSource(rand.NewSource(7))
+	if _, err := service.OpenAccount(); err == nil || err.Error() != errorCodesToMessagesMap[AccountCreationError][locale()] {
+		t.Errorf("Expected opening to give up once generated IBAN %s is taken, got %v", acc.Iban, err)
+	}
+	maxIbanGenerationAttempts = 2
+	UseRandomSource(rand.NewSource(7))
+	if other, err := service.OpenAccount(); err != nil || other.Iban == acc.Iban {
+		t.Errorf("Expected the second attempt to generate another IBAN, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining deduplication of money movements retried by clients, i.e. after a network timeout
+// A client generates a unique key per intended operation and sends it with every retry of that operation,
+// the operation is executed once and the retries return the result of the first successful execution
+// Failed operations are not remembered since they did not move any money and can be safely retried
+
+// Keys are forgotten after this period, clients are not expected to retry operations for longer
+const idempotencyKeyTtl = 24 * time.Hour
+
+const maxIdempotencyKeyLength = 255
+
+type idempotencyRecord struct {
+	fingerprint string // operation and its parameters, the same key cannot be reused for another operation
+	transferID  string
+	storedAt    time.Time
+}
+
+// Helper function to look up a previous execution of the operation with the given key, expects the repository mutex to be held by the caller
+// Returns true if the operation has already been executed and must not be executed again
+func (r *InMemoryAccountRepository) checkIdempotencyKey(key, fingerprint string) (*idempotencyRecord, bool, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, false, fmt.Errorf(errorCodesToMessagesMap[InvalidIdempotencyKeyError][locale()])
+	}
+	record, exists := r.IdempotencyKeys[key]
+	if !exists {
+		return nil, false, nil
+	}
+	if clock.Now().Sub(record.storedAt) > idempotencyKeyTtl {
+		delete(r.IdempotencyKeys, key)
+		return nil, false, nil
+	}
+	if record.fingerprint != fingerprint {
+		return nil, false, fmt.Errorf(errorCodesToMessagesMap[IdempotencyKeyConflictError][locale()])
+	}
+	return record, true, nil
+}
+
+// Helper function to remember a successful execution, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) storeIdempotencyKey(key, fingerprint, transferID string) {
+	r.IdempotencyKeys[key] = &idempotencyRecord{fingerprint, transferID, clock.Now()}
+	// Purging expired keys once in a while rather than on every call to keep the store bounded
+	if len(r.IdempotencyKeys)%1000 == 0 {
+		for k, record := range r.IdempotencyKeys {
+			if clock.Now().Sub(record.storedAt) > idempotencyKeyTtl {
+				delete(r.IdempotencyKeys, k)
+			}
+		}
+	}
+}
+
+// Empty key means the client does not need deduplication and the operation is always executed
+func (r *InMemoryAccountRepository) EmitMoneyIdempotent(key string, amount float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	if r.emissionNeedsApproval(amount) {
+		return fmt.Errorf(errorCodesToMessagesMap[EmissionApprovalRequiredError][locale()])
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return r.emitMoney(amount, "", UnspecifiedReason)
+	}
+	fingerprint := fmt.Sprintf("emit|%v", amount)
+	if _, done, err := r.checkIdempotencyKey(key, fingerprint); err != nil || done {
+		return err
+	}
+	if err := r.emitMoney(amount, "", UnspecifiedReason); err != nil {
+		return err
+	}
+	r.storeIdempotencyKey(key, fingerprint, "")
+	return nil
+}
+
+func (r *InMemoryAccountRepository) DestructMoneyIdempotent(key, iban string, amount float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return r.destructMoney(iban, amount, "", UnspecifiedReason)
+	}
+	fingerprint := fmt.Sprintf("destruct|%s|%v", NormalizeIban(iban), amount)
+	if _, done, err := r.checkIdempotencyKey(key, fingerprint); err != nil || done {
+		return err
+	}
+	if err := r.destructMoney(iban, amount, "", UnspecifiedReason); err != nil {
+		return err
+	}
+	r.storeIdempotencyKey(key, fingerprint, "")
+	return nil
+}
+
+// Retries return the ID of the transfer made by the first successful execution, including transfers awaiting approval
+func (r *InMemoryAccountRepository) TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return r.submitTransfer(sender, recipient, amount, details)
+	}
+	normalized, _ := normalizeTransferDetails(details)
+	fingerprint := fmt.Sprintf("transfer|%s|%s|%v|%+v", NormalizeIban(sender), NormalizeIban(recipient), amount, normalized)
+	if record, done, err := r.checkIdempotencyKey(key, fingerprint); err != nil || done {
+		if err != nil {
+			return "", err
+		}
+		return record.transferID, nil
+	}
+	id, err := r.submitTransfer(sender, recipient, amount, details)
+	if err != nil {
+		return id, err
+	}
+	r.storeIdempotencyKey(key, fingerprint, id)
+	return id, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Retry money movements with the same idempotency key and make sure they are executed once
+func TestIdempotentMoneyMovements(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccount()
+
+	for i := 0; i < 3; i++ {
+		if err := service.EmitMoneyIdempotent("emit-1", 100); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if inMemImpl.TotalEmitted != 100 {
+		t.Errorf("Expected 100 emitted, got %.2f", inMemImpl.TotalEmitted)
+	}
+
+	first, err := service.TransferMoneyIdempotent("transfer-1", emission, acc.Iban, 30, TransferDetails{Memo: "top-up"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	retried, err := service.TransferMoneyIdempotent("transfer-1", strings.Replace(emission, " ", "", -1), acc.Iban, 30, TransferDetails{Memo: " top-up "})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if first != retried {
+		t.Errorf("Expected retried transfer to return ID %s, got %s", first, retried)
+	}
+	// JSON variant shares the deduplication store
+	req := `{"sender": "` + emission + `", "recipient": "` + acc.Iban + `", "amount": 30, "memo": "top-up", "idempotency_key": "transfer-1"}`
+	if _, err := service.TransferMoneyJson(req); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.DestructMoneyIdempotent("destruct-1", acc.Iban, 10); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Balance != 20 {
+		t.Errorf("Expected balance 20 after deduplicated operations, got %.2f", details.Balance)
+	}
+
+	// The same key cannot be reused for another operation
+	if _, err := service.TransferMoneyIdempotent("transfer-1", emission, acc.Iban, 31, TransferDetails{}); err == nil {
+		t.Errorf("Reusing idempotency key with another amount failed to fail")
+	}
+	if err := service.EmitMoneyIdempotent("transfer-1", 30); err == nil {
+		t.Errorf("Reusing idempotency key for another operation failed to fail")
+	}
+	if err := service.EmitMoneyIdempotent(strings.Repeat("k", 256), 1); err == nil {
+		t.Errorf("Emission with too long idempotency key failed to fail")
+	}
+
+	// Failed operations are not remembered and can be retried once the cause is fixed
+	if _, err := service.TransferMoneyIdempotent("transfer-2", acc.Iban, emission, 50, TransferDetails{}); err == nil {
+		t.Fatalf("Transfer exceeding the balance failed to fail")
+	}
+	if err := service.VerifyKyc(acc.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoneyIdempotent("transfer-2", acc.Iban, emission, 20, TransferDetails{}); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	// Expired keys are forgotten
+	inMemImpl.IdempotencyKeys["emit-1"].storedAt = clock.Now().Add(-idempotencyKeyTtl - time.Minute)
+	if err := service.EmitMoneyIdempotent("emit-1", 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if inMemImpl.TotalEmitted != 200 {
+		t.Errorf("Expected emission with expired key to be executed again, got %.2f emitted", inMemImpl.TotalEmitted)
+	}
+}
+
+// Keys expire by the package clock, so retries are deduplicated under a fake clock as well
+func TestIdempotencyKeysFollowClock(t *testing.T) {
+	fake := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	for i := 0; i < 3; i++ {
+		if err := service.EmitMoneyIdempotent("k", 100); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if inMemImpl.TotalEmitted != 100 {
+		t.Errorf("Expected 100 emitted, got %.2f", inMemImpl.TotalEmitted)
+	}
+	fake.Advance(idempotencyKeyTtl + time.Minute)
+	if err := service.EmitMoneyIdempotent("k", 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if inMemImpl.TotalEmitted != 200 {
+		t.Errorf("Expected emission with expired key to be executed again, got %.2f emitted", inMemImpl.TotalEmitted)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining secondary indexes of stored accounts by status, by type and by the time of the last activity, maintained by the
+// account storage whenever accounts are stored, removed or posted to, so that blocked accounts, accounts of a type or dormant
+// accounts are found without scanning all accounts
+// Accounts changed in place have to be stored again for the indexes to follow their status and type, see AccountShards.Put
+
+type accountIndexes struct {
+	byStatus map[AccountStatus]map[string]bool
+	byType   map[AccountType]map[string]bool
+	indexed  map[string]indexedAccount
+	activity *list.List // activities of accounts, the least recent first
+	mutex    sync.Mutex
+}
+
+// Status and type the account is indexed under along with its place in the activity list
+type indexedAccount struct {
+	status   AccountStatus
+	accType  AccountType
+	activity *list.Element
+}
+
+type accountActivity struct {
+	iban string
+	at   time.Time
+}
+
+// Helper function to index the account under its current status and type, accounts indexed for the first time are active
+// as of their opening
+func (x *accountIndexes) update(acc *Account) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	entry, exists := x.indexed[acc.Iban]
+	if exists {
+		delete(x.byStatus[entry.status], acc.Iban)
+		delete(x.byType[entry.accType], acc.Iban)
+	} else {
+		entry.activity = x.place(accountActivity{acc.Iban, acc.OpenedAt})
+	}
+	entry.status, entry.accType = acc.Status, acc.Type
+	if x.byStatus[entry.status] == nil {
+		x.byStatus[entry.status] = map[string]bool{}
+	}
+	x.byStatus[entry.status][acc.Iban] = true
+	if x.byType[entry.accType] == nil {
+		x.byType[entry.accType] = map[string]bool{}
+	}
+	x.byType[entry.accType][acc.Iban] = true
+	x.indexed[acc.Iban] = entry
+}
+
+func (x *accountIndexes) remove(iban string) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if entry, exists := x.indexed[iban]; exists {
+		delete(x.byStatus[entry.status], iban)
+		delete(x.byType[entry.accType], iban)
+		x.activity.Remove(entry.activity)
+		delete(x.indexed, iban)
+	}
+}
+
+// Helper function to insert the activity into the list keeping it ordered, expects the mutex of the indexes to be held by the caller
+// Activities mostly come in order, so the place is looked for from the end
+func (x *accountIndexes) place(activity accountActivity) *list.Element {
+	for e := x.activity.Back(); e != nil; e = e.Prev() {
+		if !e.Value.(accountActivity).at.After(activity.at) {
+			return x.activity.InsertAfter(activity, e)
+		}
+	}
+	return x.activity.PushFront(activity)
+}
+
+// Records the activity of the indexed account at the given time, earlier activities than the recorded one are ignored
+func (m *AccountShards) RecordActivity(iban string, at time.Time) {
+	x := &m.indexes
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	entry, exists := x.indexed[iban]
+	if !exists || entry.activity.Value.(accountActivity).at.After(at) {
+		return
+	}
+	x.activity.Remove(entry.activity)
+	entry.activity = x.place(accountActivity{iban, at})
+	x.indexed[iban] = entry
+}
+
+// Returns IBANs of the accounts with the given status in ascending order
+func (m *AccountShards) WithStatus(status AccountStatus) []string {
+	m.indexes.mutex.Lock()
+	defer m.indexes.mutex.Unlock()
+	return sortedIbans(m.indexes.byStatus[status])
+}
+
+// Returns IBANs of the accounts of the given type in ascending order
+func (m *AccountShards) OfType(accType AccountType) []string {
+	m.indexes.mutex.Lock()
+	defer m.indexes.mutex.Unlock()
+	return sortedIbans(m.indexes.byType[accType])
+}
+
+// Returns IBANs of the accounts with no activity since the given time, the least recently active first
+func (m *AccountShards) InactiveSince(since time.Time) []string {
+	m.indexes.mutex.Lock()
+	defer m.indexes.mutex.Unlock()
+
+	ibans := []string{}
+	for e := m.indexes.activity.Front(); e != nil && e.Value.(accountActivity).at.Before(since); e = e.Next() {
+		ibans = append(ibans, e.Value.(accountActivity).iban)
+	}
+	return ibans
+}
+
+func sortedIbans(set map[string]bool) []string {
+	ibans := make([]string, 0, len(set))
+	for iban := range set {
+		ibans = append(ibans, iban)
+	}
+	sort.Strings(ibans)
+	return ibans
+}
+
+// Helper function to take details of the accounts in the given order, expects the repository mutex and the journal mutex
+// to be held shared by the caller
+func (r *InMemoryAccountRepository) detailsOf(ibans []string) []AccountDetails {
+	found := []AccountDetails{}
+	for _, iban := range ibans {
+		if acc, exists := r.Accounts.Get(iban); exists && acc != nil {
+			found = append(found, acc.Details())
+		}
+	}
+	return found
+}
+
+// Helper function to search only the accounts with the status or of the type the query asks for, in ascending order of IBANs
+func (r *InMemoryAccountRepository) findIndexed(query AccountQuery) []AccountDetails {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	var ibans []string
+	if query.Status != nil {
+		ibans = r.Accounts.WithStatus(*query.Status)
+	} else {
+		ibans = r.Accounts.OfType(*query.Type)
+	}
+	found := []AccountDetails{}
+	for _, iban := range ibans {
+		if acc, exists := r.Accounts.Get(iban); exists && acc != nil {
+			if snapshot := acc.Snapshot(); query.matches(&snapshot) {
+				found = append(found, snapshot.Details())
+			}
+		}
+	}
+	return found
+}
+
+func (r *InMemoryAccountRepository) ListBlockedAccounts() ([]AccountDetails, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+	return r.detailsOf(r.Accounts.WithStatus(Blocked)), nil
+}
+
+func (r *InMemoryAccountRepository) ListAccountsByType(accType AccountType) ([]AccountDetails, error) {
+	// Checking if the account type is known
+	if _, ok := accountTypeCodeToNameMap[accType]; !ok {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+	return r.detailsOf(r.Accounts.OfType(accType)), nil
+}
+
+// Dormant accounts are the ones neither opened nor posted to since the given time, closed accounts are not dormant
+// Activity of transactions rolled back is not reverted, such accounts merely look active for a while longer
+func (r *InMemoryAccountRepository) ListDormantAccounts(since time.Time) ([]AccountDetails, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	dormant := []string{}
+	for _, iban := range r.Accounts.InactiveSince(since) {
+		if acc, exists := r.Accounts.Get(iban); exists && acc != nil && acc.Status != Closed {
+			dormant = append(dormant, iban)
+		}
+	}
+	return r.detailsOf(dormant), nil
+}
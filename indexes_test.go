@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// List blocked accounts and accounts of a type while accounts are blocked, activated and closed
+func TestAccountIndexes(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	ibans := openLockTestAccounts(t, service, 4, 10)
+
+	service.BlockAccount(ibans[1])
+	service.BlockAccount(ibans[2])
+	service.ActivateAccount(ibans[2])
+	if err := service.CloseAccount(ibans[3], ibans[0]); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	blocked, err := service.ListBlockedAccounts()
+	if err != nil || len(blocked) != 1 || blocked[0].Iban != ibans[1] {
+		t.Errorf("Expected only %s to be blocked, got %+v, %v", ibans[1], blocked, err)
+	}
+	status := Closed
+	if closed, _ := service.FindAccounts(AccountQuery{Status: &status}); len(closed) != 1 || closed[0].Iban != ibans[3] {
+		t.Errorf("Expected only %s to be closed, got %+v", ibans[3], closed)
+	}
+
+	ordinary, _ := service.ListAccountsByType(Ordinary)
+	if len(ordinary) != 4 {
+		t.Fatalf("Expected 4 ordinary accounts, got %d", len(ordinary))
+	}
+	for i := 1; i < len(ordinary); i++ {
+		if ordinary[i-1].Iban >= ordinary[i].Iban {
+			t.Errorf("Expected accounts in ascending order of IBANs, got %+v", ordinary)
+		}
+	}
+	if emission, _ := service.ListAccountsByType(MonetaryEmission); len(emission) != 1 || emission[0].Iban != inMemImpl.EmissionAccount.Iban {
+		t.Errorf("Expected the emission account, got %+v", emission)
+	}
+	if _, err := service.ListAccountsByType(AccountType(42)); err == nil {
+		t.Errorf("Listing accounts of unknown type failed to fail")
+	}
+}
+
+// List accounts neither opened nor posted to since a time, the least recently active first
+func TestDormantAccounts(t *testing.T) {
+	fake := NewFakeClock(time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	ibans := openLockTestAccounts(t, service, 3, 0)
+	fake.Advance(24 * time.Hour)
+	service.EmitMoney(100)
+	service.TransferMoney(inMemImpl.EmissionAccount.Iban, ibans[2], 10)
+	fake.Advance(24 * time.Hour)
+	service.TransferMoney(inMemImpl.EmissionAccount.Iban, ibans[0], 10)
+
+	dormant, err := service.ListDormantAccounts(fake.Now())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// The destruction and remainder accounts have not been posted to either
+	found := map[string]int{}
+	for i, details := range dormant {
+		found[details.Iban] = i
+	}
+	if _, ok := found[ibans[1]]; !ok || len(dormant) != 4 {
+		t.Fatalf("Unexpected dormant accounts %+v", dormant)
+	}
+	if found[ibans[1]] >= found[ibans[2]] {
+		t.Errorf("Expected the least recently active account first, got %+v", dormant)
+	}
+	if dormant, _ := service.ListDormantAccounts(fake.Now().Add(-36 * time.Hour)); len(dormant) != 3 {
+		t.Errorf("Expected 3 accounts dormant since the opening day, got %+v", dormant)
+	}
+
+	service.CloseAccount(ibans[1], ibans[2])
+	if dormant, _ := service.ListDormantAccounts(fake.Now().Add(-36 * time.Hour)); len(dormant) != 2 {
+		t.Errorf("Expected closed account not to be dormant, got %+v", dormant)
+	}
+}
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining interbank transfers between federated instances of the system: transfers to IBANs of peer banks are debited
+// to the suspense account and forwarded to the peer over HTTP, the peer credits the recipient and acknowledges the transfer
+// Acknowledged transfers leave circulation through the INTERBANK ledger account, rejected ones are refunded to the sender
+const InterbankLedgerAccount = "INTERBANK" // net amount sent to (positive) or received from (negative) peer banks
+
+// Path the peers accept transfers at, relative to their base URLs
+const interbankTransfersPath = "/interbank/transfers"
+
+type InterbankDirection int8
+
+const (
+	OutboundInterbankTransfer InterbankDirection = iota
+	InboundInterbankTransfer
+)
+
+type InterbankTransferStatus int8
+
+const (
+	InterbankPending InterbankTransferStatus = iota // debited but not acknowledged by the peer yet
+	InterbankSettled
+	InterbankRejected
+)
+
+// Mapping interbank transfer status codes to interbank transfer status names considering locale
+var interbankTransferStatusCodeToNameMap map[InterbankTransferStatus](map[LanguageCode]string) = map[InterbankTransferStatus](map[LanguageCode]string){
+	InterbankPending: {
+		English: "Awaiting acknowledgement",
+		Russian: "Ожидает подтверждения банком получателя",
+	},
+	InterbankSettled: {
+		English: "Settled",
+		Russian: "Исполнен",
+	},
+	InterbankRejected: {
+		English: "Rejected",
+		Russian: "Отклонён",
+	},
+}
+
+// Correlation ID is the ID of the transfer at the sending bank, both instances refer to the transfer by it
+type InterbankTransfer struct {
+	CorrelationID string
+	Direction     InterbankDirection
+	TransferID    string // ID of the local transaction: debit of the sender or credit of the recipient
+	PeerBank      string // bank code of the other side
+	Sender        string
+	Recipient     string
+	Amount        float64
+	Currency      string
+	Reference     string
+	Memo          string
+	Status        InterbankTransferStatus
+	Reason        string // reason of the rejection given by the recipient bank
+	TraceParent   string // trace context the transfer was initiated or received in
+	CreatedAt     time.Time
+	SettledAt     time.Time
+}
+
+// Peers and authentication of the interbank API served by main, transfers are refused if none of signatures, API keys or
+// bearer tokens are required, see InterbankGateway.Handler
+type InterbankConfig struct {
+	Peers             map[string]string // base URLs of peer instances by their bank codes, their secrets are taken from the store
+	RequireSignatures bool              // transfers are signed with secrets shared with the peers, see SignatureMiddleware
+	RequireApiKeys    bool              // requests carry API keys issued to the peers, see ApiKeyMiddleware
+	RequireJwt        bool              // requests carry bearer tokens verified with the JWT settings, see JwtMiddleware
+}
+
+// Transfer forwarded to the peer bank
+type InterbankMessage struct {
+	CorrelationID string  `json:"correlation_id"`
+	SenderBank    string  `json:"sender_bank"`
+	Sender        string  `json:"sender"`
+	Recipient     string  `json:"recipient"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	Reference     string  `json:"reference,omitempty"`
+	Memo          string  `json:"memo,omitempty"`
+	TraceParent   string  `json:"trace_parent,omitempty"` // also sent as the traceparent header
+}
+
+// Acknowledgement of the peer bank, rejections are final while failed deliveries are retried
+type InterbankAck struct {
+	CorrelationID string `json:"correlation_id"`
+	Accepted      bool   `json:"accepted"`
+	TransferID    string `json:"transfer_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Helper function to find the suspense account which keeps money in transit, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) suspenseAccount() (*Account, error) {
+	acc, exists := r.SystemAccounts[SuspenseRole]
+	if !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InterbankUnavailableError][locale()])
+	}
+	return acc, nil
+}
+
+// Debits the sender to the suspense account until the peer bank acknowledges the transfer,
+// the transfer is subject to the same checks and fees as transfers between local accounts
+func (r *InMemoryAccountRepository) InitiateInterbankTransfer(sender, recipient, peerBank string, amount float64, details TransferDetails) (*InterbankTransfer, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	recipient = NormalizeIban(recipient)
+	suspense, err := r.suspenseAccount()
+	if err != nil {
+		return nil, err
+	}
+	// Checking if the recipient is a valid IBAN of a known bank which does not belong to this bank
+	if err := ValidateIban(recipient); err != nil {
+		return nil, err
+	}
+	if r.accountExists(recipient) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[UnknownPeerBankError][locale()])
+	}
+
+	if err := r.transferMoney(sender, suspense.Iban, amount, details); err != nil {
+		r.trackTransfer(err)
+		return nil, err
+	}
+	id := r.trackTransfer(nil)
+	// Transaction names the actual recipient, the journal entry keeps the suspense account as the credited one
+	tx := r.Transactions[len(r.Transactions)-1]
+	tx.Recipient = recipient
+	r.TransferStatuses[id] = TransferPending
+	transfer := &InterbankTransfer{CorrelationID: id, Direction: OutboundInterbankTransfer, TransferID: id, PeerBank: strings.ToUpper(peerBank), Sender: tx.Sender, Recipient: recipient,
+		Amount: tx.Amount, Currency: tx.Currency, Reference: tx.Reference, Memo: tx.Memo, Status: InterbankPending, TraceParent: details.TraceParent, CreatedAt: tx.Timestamp}
+	r.InterbankTransfers[id] = transfer
+	copied := *transfer
+	return &copied, nil
+}
+
+// Helper function to find a pending outbound transfer, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) pendingInterbankTransfer(correlationID string) (*InterbankTransfer, *Account, error) {
+	transfer, exists := r.InterbankTransfers[strings.ToUpper(strings.TrimSpace(correlationID))]
+	if !exists || transfer == nil || transfer.Direction != OutboundInterbankTransfer {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[InterbankTransferDoesNotExistError][locale()])
+	}
+	if transfer.Status != InterbankPending {
+		return nil, nil, fmt.Errorf(errorCodesToMessagesMap[InterbankTransferIsNotPendingError][locale()])
+	}
+	suspense, err := r.suspenseAccount()
+	if err != nil {
+		return nil, nil, err
+	}
+	return transfer, suspense, nil
+}
+
+// Moves the money of the acknowledged transfer out of the suspense account, it is held by the peer bank from now on
+func (r *InMemoryAccountRepository) SettleInterbankTransfer(correlationID string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	transfer, suspense, err := r.pendingInterbankTransfer(correlationID)
+	if err != nil {
+		return err
+	}
+	tx := &Transaction{Type: InterbankTransaction, Sender: suspense.Iban, Recipient: transfer.Recipient, Amount: transfer.Amount, Currency: transfer.Currency, Reference: transfer.CorrelationID}
+	// Our nostro account at the peer is debited along with the transfer, if there is one
+	lines := append([]LedgerLine{debitLine(suspense, transfer.Amount), creditLedgerLine(InterbankLedgerAccount, transfer.Currency, transfer.Amount)}, r.nostroLines(transfer.PeerBank, transfer.Currency, transfer.Amount)...)
+	if err := r.post(tx, lines...); err != nil {
+		return err
+	}
+	transfer.Status = InterbankSettled
+	transfer.SettledAt = tx.Timestamp
+	r.TransferStatuses[transfer.TransferID] = TransferSettled
+	r.publish(TransferSettledEvent, TransferEventPayload{transfer.TransferID, transfer.Sender, transfer.Recipient, transfer.Amount, transfer.Currency, transfer.TraceParent})
+	return nil
+}
+
+// Refunds the transfer rejected by the peer bank to the sender with a compensating transaction, fees are not refunded
+func (r *InMemoryAccountRepository) RefundInterbankTransfer(correlationID, reason string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	transfer, suspense, err := r.pendingInterbankTransfer(correlationID)
+	if err != nil {
+		return err
+	}
+	sAcc, exists := r.Accounts.Get(transfer.Sender)
+	if !exists || sAcc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	details, _ := normalizeTransferDetails(TransferDetails{Memo: strings.TrimSpace(reason)})
+	refund := &Transaction{Type: ReversalTransaction, Sender: suspense.Iban, Recipient: sAcc.Iban, Amount: transfer.Amount, Currency: transfer.Currency, Reference: transfer.Reference, Memo: details.Memo, ReversalOf: transfer.TransferID}
+	if err := r.post(refund, transferLines(suspense, sAcc, transfer.Amount)...); err != nil {
+		return err
+	}
+	for _, tx := range r.Transactions {
+		if tx.Ulid == transfer.TransferID {
+			tx.ReversedBy = refund.Ulid
+			break
+		}
+	}
+	transfer.Status = InterbankRejected
+	transfer.Reason = details.Memo
+	transfer.SettledAt = refund.Timestamp
+	r.TransferStatuses[transfer.TransferID] = TransferReversed
+	r.TransferStatuses[refund.Ulid] = TransferSettled
+	return nil
+}
+
+// Credits the recipient of the transfer forwarded by the peer bank, redelivered messages are acknowledged
+// with the outcome of the first delivery, so that retries of the peer never credit the recipient twice
+func (r *InMemoryAccountRepository) ReceiveInterbankTransfer(msg InterbankMessage) (InterbankAck, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	msg.CorrelationID = strings.ToUpper(strings.TrimSpace(msg.CorrelationID))
+	msg.Recipient = NormalizeIban(msg.Recipient)
+	msg.Currency = strings.ToUpper(strings.TrimSpace(msg.Currency))
+	// Checking if the message can be acknowledged at all
+	if msg.CorrelationID == "" || msg.SenderBank == "" || msg.Sender == "" || msg.Recipient == "" {
+		return InterbankAck{}, fmt.Errorf(errorCodesToMessagesMap[InvalidInterbankMessageError][locale()])
+	}
+	if known, exists := r.InterbankTransfers[msg.CorrelationID]; exists {
+		if known.Direction != InboundInterbankTransfer {
+			return InterbankAck{}, fmt.Errorf(errorCodesToMessagesMap[InvalidInterbankMessageError][locale()])
+		}
+		return InterbankAck{known.CorrelationID, known.Status == InterbankSettled, known.TransferID, known.Reason}, nil
+	}
+
+	transfer := &InterbankTransfer{CorrelationID: msg.CorrelationID, Direction: InboundInterbankTransfer, PeerBank: strings.ToUpper(msg.SenderBank), Sender: NormalizeIban(msg.Sender), Recipient: msg.Recipient,
+		Amount: msg.Amount, Currency: msg.Currency, Reference: msg.Reference, Memo: msg.Memo, TraceParent: msg.TraceParent, CreatedAt: clock.Now().UTC()}
+	reject := func(err error) (InterbankAck, error) {
+		transfer.Status = InterbankRejected
+		transfer.Reason = err.Error()
+		transfer.SettledAt = transfer.CreatedAt
+		r.InterbankTransfers[transfer.CorrelationID] = transfer
+		return InterbankAck{CorrelationID: transfer.CorrelationID, Reason: transfer.Reason}, nil
+	}
+	// Checking if the recipient can receive the money, the sender has been checked by its bank
+	details, ok := normalizeTransferDetails(TransferDetails{Reference: msg.Reference, Memo: msg.Memo})
+	if !ok {
+		return reject(fmt.Errorf(errorCodesToMessagesMap[InvalidTransferDetailsError][locale()]))
+	}
+	rAcc, exists := r.Accounts.Get(msg.Recipient)
+	if !exists || rAcc == nil {
+		return reject(fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()]))
+	}
+	if rAcc.Type != Ordinary {
+		return reject(fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()]))
+	}
+	if rAcc.Status == Blocked {
+		return reject(fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()]))
+	}
+	if rAcc.Status == Closed {
+		return reject(fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()]))
+	}
+	if rAcc.Currency != msg.Currency {
+		return reject(fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()]))
+	}
+	if msg.Amount <= 0 || roundToCurrency(msg.Amount, rAcc.Currency) != msg.Amount {
+		return reject(fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()]))
+	}
+	if err := checkBalanceCeiling(rAcc, msg.Amount); err != nil {
+		return reject(err)
+	}
+	// Money comes from the vostro account of the peer if there is one, so it stays in circulation
+	source := debitLedgerLine(InterbankLedgerAccount, rAcc.Currency, msg.Amount)
+	if vostro := r.vostroAccount(transfer.PeerBank, rAcc.Currency); vostro != nil {
+		if vostro.AvailableBalance() < msg.Amount {
+			return reject(insufficientFundsError(vostro))
+		}
+		source = debitLine(vostro, msg.Amount)
+	}
+
+	tx := &Transaction{Type: InterbankTransaction, Sender: transfer.Sender, Recipient: rAcc.Iban, Amount: msg.Amount, Currency: rAcc.Currency, Reference: details.Reference, Memo: details.Memo}
+	if err := r.post(tx, source, creditLine(rAcc, msg.Amount)); err != nil {
+		return reject(err)
+	}
+	transfer.TransferID = tx.Ulid
+	transfer.Status = InterbankSettled
+	transfer.SettledAt = tx.Timestamp
+	r.InterbankTransfers[transfer.CorrelationID] = transfer
+	r.TransferStatuses[tx.Ulid] = TransferSettled
+	r.publish(TransferSettledEvent, TransferEventPayload{tx.Ulid, transfer.Sender, rAcc.Iban, msg.Amount, rAcc.Currency, transfer.TraceParent})
+	return InterbankAck{transfer.CorrelationID, true, tx.Ulid, ""}, nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveInterbankTransfer(correlationID string) (InterbankTransfer, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	transfer, exists := r.InterbankTransfers[strings.ToUpper(strings.TrimSpace(correlationID))]
+	if !exists || transfer == nil {
+		return InterbankTransfer{}, fmt.Errorf(errorCodesToMessagesMap[InterbankTransferDoesNotExistError][locale()])
+	}
+	return *transfer, nil
+}
+
+// --------------------------------------------------------
+// Defining gateway which forwards transfers to peer instances and accepts transfers from them,
+// transfers which could not be delivered stay in the retry queue until the peer acknowledges them
+type InterbankGateway struct {
+	service           *AccountService
+	BankCode          string            // bank code this instance is known to its peers by
+	Peers             map[string]string // base URLs of peer instances by their bank codes
+	Client            *http.Client
+	RetryInterval     time.Duration     // delay before the next delivery attempt of an unacknowledged transfer
+	Clearing          *ClearingHouse    // accumulates obligations of acknowledged transfers for net settlement, nothing is cleared if not set
+	RequireApiKeys    bool              // requests of the peers are authenticated by API keys if set, see ApiKeyMiddleware
+	peerApiKeys       map[string]string // tokens the peers issued to us by their bank codes
+	Jwt               *JwtVerifier      // transfers of the peers are authenticated by bearer tokens if set, see JwtMiddleware
+	RequireSignatures bool              // transfers of the peers have to be signed by the secrets shared with them if set, see SetPeerSecret
+	peerSecrets       map[string]string // HMAC secrets shared with the peers by their bank codes
+	queue             map[string]*interbankDelivery
+	Mutex             sync.Mutex
+}
+
+type interbankDelivery struct {
+	message       InterbankMessage
+	attempts      int
+	nextAttemptAt time.Time
+}
+
+func NewInterbankGateway(service *AccountService, bankCode string) *InterbankGateway {
+	return &InterbankGateway{service: service, BankCode: strings.ToUpper(bankCode), Peers: map[string]string{}, Client: &http.Client{Timeout: 5 * time.Second},
+		RetryInterval: time.Minute, queue: map[string]*interbankDelivery{}, peerApiKeys: map[string]string{}, peerSecrets: map[string]string{}}
+}
+
+func (g *InterbankGateway) AddPeer(bankCode, baseUrl string) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	g.Peers[strings.ToUpper(bankCode)] = strings.TrimRight(baseUrl, "/")
+}
+
+// Sets the token of the API key the peer issued to us, it is passed with every request to the peer
+func (g *InterbankGateway) SetPeerApiKey(bankCode, token string) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	g.peerApiKeys[strings.ToUpper(bankCode)] = strings.TrimSpace(token)
+}
+
+// Sets the secret shared with the peer, transfers to the peer are signed with it and transfers of the peer are verified by it
+func (g *InterbankGateway) SetPeerSecret(bankCode, secret string) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	g.peerSecrets[strings.ToUpper(bankCode)] = secret
+}
+
+// Helper function to check if the bank is a peer, i.e. it has been added with its URL or shares a secret with us
+func (g *InterbankGateway) isPeer(bankCode string) bool {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	bankCode = strings.ToUpper(strings.TrimSpace(bankCode))
+	_, added := g.Peers[bankCode]
+	return bankCode != "" && (added || g.peerSecrets[bankCode] != "")
+}
+
+// Helper function to find the secret shared with the peer
+func (g *InterbankGateway) peerSecret(bankCode string) (string, bool) {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	secret, exists := g.peerSecrets[strings.ToUpper(strings.TrimSpace(bankCode))]
+	return secret, exists && secret != ""
+}
+
+// Helper function to authenticate the request to the peer if it issued us an API key
+func (g *InterbankGateway) authenticate(req *http.Request, bankCode string) {
+	g.Mutex.Lock()
+	token := g.peerApiKeys[bankCode]
+	g.Mutex.Unlock()
+	if token != "" {
+		req.Header.Set(apiKeyHeader, token)
+	}
+}
+
+// Transfers money to local accounts directly and forwards transfers to IBANs of peer banks,
+// returns the ID of the transfer like TransferMoneyWithDetails does, the ID of a forwarded transfer is its correlation ID
+// Forwarded transfers are pending until acknowledged, failed deliveries are retried without failing the transfer
+func (g *InterbankGateway) SendTransfer(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	bankCode := bankCodeOfIban(recipient)
+	g.Mutex.Lock()
+	_, isPeer := g.Peers[bankCode]
+	g.Mutex.Unlock()
+	if _, err := g.service.GetAccount(recipient); err == nil || !isPeer || bankCode == g.BankCode {
+		return g.service.TransferMoneyWithDetails(sender, recipient, amount, details)
+	}
+
+	transfer, err := g.service.InitiateInterbankTransfer(sender, recipient, bankCode, amount, details)
+	if err != nil {
+		return "", err
+	}
+	msg := InterbankMessage{transfer.CorrelationID, g.BankCode, transfer.Sender, transfer.Recipient, transfer.Amount, transfer.Currency, transfer.Reference, transfer.Memo, transfer.TraceParent}
+	g.Mutex.Lock()
+	g.queue[transfer.CorrelationID] = &interbankDelivery{message: msg}
+	g.Mutex.Unlock()
+	g.deliver(transfer.CorrelationID, clock.Now())
+	return transfer.CorrelationID, nil
+}
+
+// Helper function to attempt the delivery of the queued transfer, returns true once the transfer has been acknowledged
+func (g *InterbankGateway) deliver(correlationID string, now time.Time) bool {
+	g.Mutex.Lock()
+	delivery, queued := g.queue[correlationID]
+	if !queued {
+		g.Mutex.Unlock()
+		return false
+	}
+	delivery.attempts++
+	delivery.nextAttemptAt = now.Add(g.RetryInterval)
+	baseUrl := g.Peers[bankCodeOfIban(delivery.message.Recipient)]
+	msg := delivery.message
+	g.Mutex.Unlock()
+
+	ack, ok := g.post(baseUrl, msg)
+	if !ok {
+		return false
+	}
+	if ack.Accepted {
+		if g.service.SettleInterbankTransfer(correlationID) == nil && g.Clearing != nil {
+			g.Clearing.Submit(g.BankCode, bankCodeOfIban(msg.Recipient), msg.Amount, msg.Currency)
+		}
+	} else {
+		g.service.RefundInterbankTransfer(correlationID, ack.Reason)
+	}
+	g.Mutex.Lock()
+	delete(g.queue, correlationID)
+	g.Mutex.Unlock()
+	return true
+}
+
+// Helper function to send the message to the peer, any failure to get a matching acknowledgement counts as failed delivery
+func (g *InterbankGateway) post(baseUrl string, msg InterbankMessage) (InterbankAck, bool) {
+	body, err := json.Marshal(msg)
+	if err != nil || baseUrl == "" {
+		return InterbankAck{}, false
+	}
+	req, err := http.NewRequest(http.MethodPost, baseUrl+interbankTransfersPath, bytes.NewReader(body))
+	if err != nil {
+		return InterbankAck{}, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Correlation-ID", msg.CorrelationID)
+	if msg.TraceParent != "" {
+		req.Header.Set(traceParentHeader, msg.TraceParent)
+	}
+	g.authenticate(req, bankCodeOfIban(msg.Recipient))
+	if secret, shared := g.peerSecret(bankCodeOfIban(msg.Recipient)); shared {
+		signRequest(req, g.BankCode, secret, body, clock.Now())
+	}
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return InterbankAck{}, false
+	}
+	defer resp.Body.Close()
+	var ack InterbankAck
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&ack) != nil || ack.CorrelationID != msg.CorrelationID {
+		return InterbankAck{}, false
+	}
+	return ack, true
+}
+
+// Retries deliveries which are due by the given time, returns the number of transfers acknowledged by the peers
+func (g *InterbankGateway) RunRetries(now time.Time) int {
+	g.Mutex.Lock()
+	due := []string{}
+	for id, delivery := range g.queue {
+		if !now.Before(delivery.nextAttemptAt) {
+			due = append(due, id)
+		}
+	}
+	g.Mutex.Unlock()
+	sort.Strings(due)
+	acknowledged := 0
+	for _, id := range due {
+		if g.deliver(id, now) {
+			acknowledged++
+		}
+	}
+	return acknowledged
+}
+
+// Lists correlation IDs of transfers awaiting delivery, the oldest first
+func (g *InterbankGateway) ListQueuedTransfers() []string {
+	g.Mutex.Lock()
+	defer g.Mutex.Unlock()
+	queued := []string{}
+	for id := range g.queue {
+		queued = append(queued, id)
+	}
+	sort.Strings(queued)
+	return queued
+}
+
+// Retries due deliveries every interval in a background goroutine, calling the returned function stops retrying
+// once the retries in progress, if any, have finished
+func (g *InterbankGateway) Start(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				now := clock.Now()
+				g.RunRetries(now)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// Serves transfers forwarded by the peers and their vostro balances, business rejections of transfers are acknowledged
+// with 200 OK as well, so that the sending bank refunds the transfer instead of retrying it
+// Transfers are refused unless signatures, API keys or bearer tokens of the peers are required, since money received from
+// a peer without a vostro account is owed by the peer, and only transfers of known peers are accepted, signed transfers
+// only from the peer which signed them
+func (g *InterbankGateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	authenticated := g.RequireSignatures || g.RequireApiKeys || g.Jwt != nil
+	var transfers http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if !authenticated {
+			http.Error(w, errorCodesToMessagesMap[InterbankAuthenticationRequiredError][locale()], http.StatusUnauthorized)
+			return
+		}
+		var msg InterbankMessage
+		if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+			http.Error(w, errorCodesToMessagesMap[InvalidInterbankMessageError][locale()], http.StatusBadRequest)
+			return
+		}
+		if !g.isPeer(msg.SenderBank) || (g.RequireSignatures && !strings.EqualFold(req.Header.Get(signatureKeyHeader), strings.TrimSpace(msg.SenderBank))) {
+			http.Error(w, errorCodesToMessagesMap[UntrustedPeerBankError][locale()], http.StatusForbidden)
+			return
+		}
+		// Settlement of the transfer is traced in the span of the request rather than the one of the peer
+		if span, traced := SpanFromContext(req.Context()); traced {
+			msg.TraceParent = span.TraceParent()
+		}
+		ack, err := g.service.ForRequest(req).ReceiveInterbankTransfer(msg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Correlation-ID", ack.CorrelationID)
+		json.NewEncoder(w).Encode(ack)
+	})
+	if g.RequireSignatures {
+		transfers = SignatureMiddleware(g.peerSecret, transfers)
+	}
+	mux.Handle(interbankTransfersPath, transfers)
+	mux.HandleFunc(interbankVostroPath, g.serveVostroBalance)
+	var handler http.Handler = mux
+	if g.Jwt != nil {
+		handler = JwtMiddleware(g.Jwt, handler)
+	}
+	if !g.RequireApiKeys {
+		return handler
+	}
+	return ApiKeyMiddleware(g.service, func(req *http.Request) ApiKeyScope {
+		if req.URL.Path == interbankTransfersPath {
+			return TransferScope
+		}
+		return ReadScope
+	}, handler)
+}
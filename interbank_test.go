@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Helper function to start an instance with the default chart of accounts and an ordinary account holding the balance
+func startInterbankInstance(t *testing.T, bankCode string, balance float64) (*AccountService, *InterbankGateway, *httptest.Server, *Account) {
+	repo, err := NewInMemoryAccountRepositoryWithChart(DefaultChartOfAccounts())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service := NewAccountService(repo)
+	acc, err := service.OpenAccountWithInitialDeposit(balance)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	gateway := NewInterbankGateway(service, bankCode)
+	gateway.RequireSignatures = true
+	server := httptest.NewServer(gateway.Handler())
+	return service, gateway, server, acc
+}
+
+// Forward transfers between two instances, retry deliveries while the peer is unreachable
+func TestInterbankTransfers(t *testing.T) {
+	alfa, alfaGateway, alfaServer, sender := startInterbankInstance(t, "ALFA", 100)
+	defer alfaServer.Close()
+	beta, betaGateway, betaServer, _ := startInterbankInstance(t, "BETA", 0)
+	defer betaServer.Close()
+	recipient, _ := beta.OpenAccountWithIban(ibanWithBban("BETA00000000000000000042"))
+
+	// Peer is unreachable until it is switched on
+	reachable := int32(0)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&reachable) == 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		betaGateway.Handler().ServeHTTP(w, req)
+	}))
+	defer flaky.Close()
+	alfaGateway.AddPeer("BETA", flaky.URL)
+	alfaGateway.Clearing = NewClearingHouse()
+	betaGateway.AddPeer("ALFA", alfaServer.URL)
+	alfaGateway.SetPeerSecret("BETA", "shared")
+	betaGateway.SetPeerSecret("ALFA", "shared")
+
+	id, err := alfaGateway.SendTransfer(sender.Iban, recipient.Iban, 40, TransferDetails{Reference: "INV-1"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := alfa.GetTransferStatus(id); status != TransferPending {
+		t.Errorf("Expected undelivered transfer to be pending, got %v", status)
+	}
+	if queued := alfaGateway.ListQueuedTransfers(); len(queued) != 1 || queued[0] != id {
+		t.Errorf("Expected the transfer to be queued for retry, got %v", queued)
+	}
+	if details, _ := alfa.RetrieveAccount(sender.Iban); details.Balance != 60 {
+		t.Errorf("Expected sender to be debited right away, got balance %v", details.Balance)
+	}
+	if tx, _ := alfa.RetrieveTransaction(id); tx.Recipient != recipient.Iban {
+		t.Errorf("Expected transaction to name the actual recipient, got %s", tx.Recipient)
+	}
+	// Retries are not attempted before the retry interval elapses
+	if acknowledged := alfaGateway.RunRetries(time.Now()); acknowledged != 0 {
+		t.Errorf("Expected no retries before the interval, got %d", acknowledged)
+	}
+	atomic.StoreInt32(&reachable, 1)
+	if acknowledged := alfaGateway.RunRetries(time.Now().Add(time.Hour)); acknowledged != 1 {
+		t.Fatalf("Expected the transfer to be acknowledged on retry, got %d", acknowledged)
+	}
+	if status, _ := alfa.GetTransferStatus(id); status != TransferSettled {
+		t.Errorf("Expected acknowledged transfer to be settled, got %v", status)
+	}
+	if details, _ := beta.RetrieveAccount(recipient.Iban); details.Balance != 40 {
+		t.Errorf("Expected recipient balance of 40, got %v", details.Balance)
+	}
+	if open := alfaGateway.Clearing.ListOpenObligations(); len(open) != 1 || open[0].Payer != "ALFA" || open[0].Payee != "BETA" || open[0].Amount != 40 {
+		t.Errorf("Expected acknowledged transfer to be submitted for clearing, got %+v", open)
+	}
+	inbound, err := beta.RetrieveInterbankTransfer(id)
+	if err != nil || inbound.Direction != InboundInterbankTransfer || inbound.PeerBank != "ALFA" || inbound.Reference != "INV-1" {
+		t.Errorf("Unexpected inbound transfer: %+v (%v)", inbound, err)
+	}
+
+	// Redelivered message does not credit the recipient twice
+	ack, err := beta.ReceiveInterbankTransfer(InterbankMessage{CorrelationID: id, SenderBank: "ALFA", Sender: sender.Iban, Recipient: recipient.Iban, Amount: 40, Currency: DefaultCurrency})
+	if err != nil || !ack.Accepted || ack.TransferID != inbound.TransferID {
+		t.Errorf("Expected redelivery to be acknowledged with the first outcome, got %+v (%v)", ack, err)
+	}
+	if details, _ := beta.RetrieveAccount(recipient.Iban); details.Balance != 40 {
+		t.Errorf("Expected recipient balance of 40 after redelivery, got %v", details.Balance)
+	}
+
+	// Transfers rejected by the peer are refunded
+	unknown := ibanWithBban("BETA00000000000000000099")
+	rejected, err := alfaGateway.SendTransfer(sender.Iban, unknown, 15, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := alfa.GetTransferStatus(rejected); status != TransferReversed {
+		t.Errorf("Expected rejected transfer to be reversed, got %v", status)
+	}
+	if transfer, _ := alfa.RetrieveInterbankTransfer(rejected); transfer.Status != InterbankRejected || transfer.Reason == "" {
+		t.Errorf("Expected transfer to be rejected with a reason, got %+v", transfer)
+	}
+	if details, _ := alfa.RetrieveAccount(sender.Iban); details.Balance != 60 {
+		t.Errorf("Expected sender balance of 60 after refund, got %v", details.Balance)
+	}
+
+	// Both instances keep their invariants, money moved between them through the INTERBANK ledger account
+	for _, service := range []*AccountService{alfa, beta} {
+		if _, err := service.VerifyInvariants(); err != nil {
+			t.Errorf("Error: %v", err)
+		}
+	}
+	if balance := alfa.RetrieveLedgerBalance(InterbankLedgerAccount, DefaultCurrency); balance != 40 {
+		t.Errorf("Expected 40 sent to peer banks, got %v", balance)
+	}
+	if balance := beta.RetrieveLedgerBalance(InterbankLedgerAccount, DefaultCurrency); balance != -40 {
+		t.Errorf("Expected 40 received from peer banks, got %v", balance)
+	}
+
+	// Transfers between local accounts are not forwarded
+	local, _ := alfa.OpenAccount()
+	if _, err := alfaGateway.SendTransfer(sender.Iban, local.Iban, 10, TransferDetails{}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := alfa.RetrieveAccount(local.Iban); details.Balance != 10 {
+		t.Errorf("Expected local recipient balance of 10, got %v", details.Balance)
+	}
+}
+
+// Transfers are accepted only from authenticated peers, so that nobody can mint money by claiming to be a bank
+func TestUntrustedInterbankTransfers(t *testing.T) {
+	beta, betaGateway, betaServer, _ := startInterbankInstance(t, "BETA", 0)
+	defer betaServer.Close()
+	betaGateway.SetPeerSecret("ALFA", "shared")
+	recipient, _ := beta.OpenAccountWithIban(ibanWithBban("BETA00000000000000000042"))
+	post := func(url string, msg InterbankMessage, keyID, secret string) int {
+		body, _ := json.Marshal(msg)
+		req, _ := http.NewRequest(http.MethodPost, url+interbankTransfersPath, bytes.NewReader(body))
+		if secret != "" {
+			signRequest(req, keyID, secret, body, clock.Now())
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	msg := InterbankMessage{CorrelationID: "evil-1", SenderBank: "EVIL", Sender: ibanWithBban("EVIL00000000000000000001"), Recipient: recipient.Iban, Amount: 1000000, Currency: DefaultCurrency}
+
+	if status := post(betaServer.URL, msg, "", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected unsigned transfer to be unauthorized, got %d", status)
+	}
+	if status := post(betaServer.URL, msg, "EVIL", "guessed"); status != http.StatusUnauthorized {
+		t.Errorf("Expected transfer signed by an unknown bank to be unauthorized, got %d", status)
+	}
+	if status := post(betaServer.URL, msg, "ALFA", "shared"); status != http.StatusForbidden {
+		t.Errorf("Expected transfer signed by another peer to be forbidden, got %d", status)
+	}
+
+	// API keys do not tell the peer which sent the transfer, so only the peers are trusted
+	betaGateway.RequireApiKeys, betaGateway.RequireSignatures = true, false
+	keyed := httptest.NewServer(betaGateway.Handler())
+	defer keyed.Close()
+	_, token, _ := beta.IssueApiKey(Principal{ID: "ALFA", Role: TellerRole}, []ApiKeyScope{ReadScope, TransferScope}, 0)
+	body, _ := json.Marshal(msg)
+	req, _ := http.NewRequest(http.MethodPost, keyed.URL+interbankTransfersPath, bytes.NewReader(body))
+	req.Header.Set(apiKeyHeader, token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected transfer of an unknown bank to be forbidden, got %d", resp.StatusCode)
+	}
+
+	// Gateway authenticating nobody refuses transfers altogether
+	betaGateway.RequireApiKeys = false
+	open := httptest.NewServer(betaGateway.Handler())
+	defer open.Close()
+	msg.SenderBank = "ALFA"
+	if status := post(open.URL, msg, "", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected transfer to a gateway without authentication to be unauthorized, got %d", status)
+	}
+	if details, _ := beta.RetrieveAccount(recipient.Iban); details.Balance != 0 {
+		t.Errorf("Expected recipient not to be credited, got balance %v", details.Balance)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining interest accrued daily on balances of eligible accounts and posted (capitalized) separately
+// Accruals only record the interest earned for a day, money is moved by postings, so that every posting can be traced back to its accruals
+
+// Number of days in a year used to derive the daily rate from the annual one
+const interestDayCount = 365
+
+// Interest earned by the account for a single day, Amount is kept unrounded
+type InterestAccrual struct {
+	Iban      string
+	Date      string // UTC date in YYYY-MM-DD format
+	Balance   float64
+	Rate      float64 // annual rate in percent applied for the day
+	Amount    float64
+	PostingID string // ID of the posting the accrual was capitalized by, empty until posted
+}
+
+// Capitalization of accruals, TransactionID is the ULID of the transaction crediting the account
+type InterestPosting struct {
+	ID            string
+	Iban          string
+	Amount        float64
+	From          string // date of the earliest capitalized accrual
+	To            string // date of the latest capitalized accrual
+	Accruals      int
+	TransactionID string
+	PostedAt      time.Time
+}
+
+// Sets the annual interest rate in percent for accounts of the product, zero rate makes the product not eligible for interest
+func (r *InMemoryAccountRepository) SetInterestRate(product ProductType, rate float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the product is known and the rate is a finite number which is not negative
+	if _, ok := productTypeCodeToNameMap[product]; !ok || rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidInterestRateError][locale()])
+	}
+	if rate == 0 {
+		delete(r.InterestRates, product)
+		return nil
+	}
+	r.InterestRates[product] = rate
+	return nil
+}
+
+// Accrues interest for the day of the given date on balances of eligible accounts, returns the number of new accruals
+// Accounts that have already accrued interest for the day are skipped, so running the accrual twice for the same day does nothing
+// Interest is posted from the emission account, so only active ordinary accounts in its currency are eligible
+func (r *InMemoryAccountRepository) AccrueInterest(date time.Time) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if emission account is set
+	if r.EmissionAccount == nil {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	day := date.UTC().Format(time.DateOnly)
+	accrued := 0
+	for _, acc := range r.Accounts.All() {
+		rate, eligible := r.InterestRates[acc.Product]
+		if !eligible || acc.Type != Ordinary || acc.Status != Active || acc.Currency != r.EmissionAccount.Currency || acc.Balance <= 0 {
+			continue
+		}
+		key := acc.Iban + "|" + day
+		if _, exists := r.InterestAccruals[key]; exists {
+			continue
+		}
+		r.InterestAccruals[key] = &InterestAccrual{acc.Iban, day, acc.Balance, rate, acc.Balance * rate / 100 / interestDayCount, ""}
+		accrued++
+	}
+	return accrued, nil
+}
+
+// Posts accruals not posted yet up to the given date inclusive, one posting per account, i.e. at month-end
+// The interest is emitted and credited to the accounts from the emission account, sub-cent residues end up in account fractions
+func (r *InMemoryAccountRepository) PostInterest(until time.Time) ([]InterestPosting, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	last := until.UTC().Format(time.DateOnly)
+	pending := map[string][]*InterestAccrual{}
+	for _, accrual := range r.InterestAccruals {
+		if accrual.PostingID == "" && accrual.Date <= last {
+			pending[accrual.Iban] = append(pending[accrual.Iban], accrual)
+		}
+	}
+	ibans := make([]string, 0, len(pending))
+	for iban := range pending {
+		ibans = append(ibans, iban)
+	}
+	sort.Strings(ibans)
+
+	postings := []InterestPosting{}
+	for _, iban := range ibans {
+		acc, exists := r.Accounts.Get(iban)
+		// Accounts closed since the accrual keep their accruals unposted
+		if !exists || acc == nil || acc.Status == Closed {
+			continue
+		}
+		accruals := pending[iban]
+		amount := 0.0
+		for _, accrual := range accruals {
+			amount += accrual.Amount
+		}
+		// Accounts at their ceiling keep their accruals unposted until the balance goes down
+		if checkBalanceCeiling(acc, roundToCurrency(amount, acc.Currency)) != nil {
+			continue
+		}
+		sort.Slice(accruals, func(i, j int) bool { return accruals[i].Date < accruals[j].Date })
+		if err := r.emitMoney(amount, "", InterestReason); err != nil {
+			return postings, err
+		}
+		tx := &Transaction{Type: InterestTransaction, Sender: r.EmissionAccount.Iban, Recipient: iban, Amount: amount, Currency: acc.Currency}
+		if err := r.post(tx, transferLines(r.EmissionAccount, acc, amount)...); err != nil {
+			return postings, err
+		}
+
+		posting := &InterestPosting{NewUlid(tx.Timestamp), iban, amount, accruals[0].Date, accruals[len(accruals)-1].Date, len(accruals), tx.Ulid, tx.Timestamp}
+		for _, accrual := range accruals {
+			accrual.PostingID = posting.ID
+		}
+		r.InterestPostings = append(r.InterestPostings, posting)
+		postings = append(postings, *posting)
+	}
+	return postings, nil
+}
+
+// Lists accruals of the account, the earliest first
+func (r *InMemoryAccountRepository) ListInterestAccruals(iban string) ([]InterestAccrual, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	accruals := []InterestAccrual{}
+	for _, accrual := range r.InterestAccruals {
+		if accrual.Iban == iban {
+			accruals = append(accruals, *accrual)
+		}
+	}
+	sort.Slice(accruals, func(i, j int) bool { return accruals[i].Date < accruals[j].Date })
+	return accruals, nil
+}
+
+// Lists postings to the account, the earliest first
+func (r *InMemoryAccountRepository) ListInterestPostings(iban string) ([]InterestPosting, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	postings := []InterestPosting{}
+	for _, posting := range r.InterestPostings {
+		if posting.Iban == iban {
+			postings = append(postings, *posting)
+		}
+	}
+	return postings, nil
+}
+
+// Accrues interest for the current day every interval in a background goroutine and posts the accruals of the previous month
+// on the first run in a new month, each outcome is passed to the given callback
+// Calling the returned function stops the interest loop
+func (s *AccountService) StartInterestAccrual(interval time.Duration, callback func(accrued int, postings []InterestPosting, err error)) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		month := clock.Now().UTC().Format("2006-01")
+		for {
+			select {
+			case <-ticker.C:
+				now := clock.Now().UTC()
+				var postings []InterestPosting
+				var err error
+				// Capitalizing the accruals up to the last day of the previous month once the month changes
+				if now.Format("2006-01") != month {
+					month = now.Format("2006-01")
+					postings, err = s.PostInterest(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1))
+				}
+				accrued := 0
+				if err == nil {
+					accrued, err = s.AccrueInterest(now)
+				}
+				if callback != nil {
+					callback(accrued, postings, err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() { once.Do(func() { close(done) }) }
+}
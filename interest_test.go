@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Interest is accrued daily and credited only when posted
+func TestInterestAccrual(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	current, _ := service.OpenAccountWithInitialDeposit(2000)
+	savings, _ := service.OpenAccountWithOptions(AccountOptions{Product: SavingsProduct})
+	if _, err := service.TransferMoney(current.Iban, savings.Iban, 1000); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.SetInterestRate(SavingsProduct, -1); err == nil {
+		t.Errorf("Setting negative interest rate failed to fail")
+	}
+	for _, rate := range []float64{math.NaN(), math.Inf(1)} {
+		if err := service.SetInterestRate(SavingsProduct, rate); err == nil {
+			t.Errorf("Setting interest rate of %v failed to fail", rate)
+		}
+	}
+	if err := service.SetInterestRate(SavingsProduct, 3.65); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	day := time.Date(2024, time.January, 30, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if accrued, err := service.AccrueInterest(day.AddDate(0, 0, i)); err != nil || accrued != 1 {
+			t.Fatalf("Expected one accrual, got %d (%v)", accrued, err)
+		}
+	}
+	if accrued, _ := service.AccrueInterest(day); accrued != 0 {
+		t.Errorf("Expected repeated accrual for the same day to be skipped, got %d", accrued)
+	}
+	if details, _ := service.RetrieveAccount(savings.Iban); details.Balance != 1000 {
+		t.Errorf("Expected accruals not to move money, got %.2f", details.Balance)
+	}
+
+	// Month-end posting capitalizes January accruals only
+	postings, err := service.PostInterest(time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(postings) != 1 || postings[0].Accruals != 2 || postings[0].From != "2024-01-30" || postings[0].To != "2024-01-31" || math.Abs(postings[0].Amount-0.2) > 1e-9 {
+		t.Fatalf("Unexpected postings: %+v", postings)
+	}
+	if details, _ := service.RetrieveAccount(savings.Iban); details.Balance != 1000.2 {
+		t.Errorf("Expected balance 1000.20 after posting, got %.2f", details.Balance)
+	}
+	accruals, _ := service.ListInterestAccruals(savings.Iban)
+	if len(accruals) != 3 || accruals[0].PostingID != postings[0].ID || accruals[2].PostingID != "" {
+		t.Errorf("Unexpected accruals: %+v", accruals)
+	}
+	if again, _ := service.PostInterest(time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)); len(again) != 0 {
+		t.Errorf("Expected accruals to be posted once, got %+v", again)
+	}
+	if postings, _ := service.ListInterestPostings(current.Iban); len(postings) != 0 {
+		t.Errorf("Expected no interest for current account, got %+v", postings)
+	}
+	// Posted interest is emitted money, so the total in circulation still matches the emitted amount
+	if res, err := service.ReconcileFractions(); err != nil || math.Abs(res.Discrepancy) > 1e-9 {
+		t.Errorf("Unexpected reconciliation: %+v (%v)", res, err)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining invariants of the money supply which are verified on demand and in the background, unlike ReconcileFractions
+// verification does not change any balances, so it can be run as often as needed
+const (
+	MoneySupplyInvariant = "money_supply" // balances of all accounts except loan and nostro ones equal emitted plus converted money less money sent to peer banks
+	LedgerInvariant      = "ledger"       // balances of all accounts and off-balance ledger accounts sum up to zero
+	JournalInvariant     = "journal"      // every transaction has a journal entry with balanced debits and credits
+)
+
+// Drift of a single invariant in a single currency, Expected and Actual are exact (unrounded) sums
+type InvariantViolation struct {
+	Invariant string  `json:"invariant"`
+	Currency  string  `json:"currency"`
+	Expected  float64 `json:"expected"`
+	Actual    float64 `json:"actual"`
+	Drift     float64 `json:"drift"`
+}
+
+type InvariantReport struct {
+	Violations []InvariantViolation `json:"violations"`
+	CheckedAt  time.Time            `json:"checked_at"`
+}
+
+// Error returned along with the report when any of the invariants does not hold
+// Message is the localized one of IntegrityViolationError followed by the violations, see Violations for the details
+type IntegrityError struct {
+	Violations []InvariantViolation
+}
+
+func (e *IntegrityError) Error() string {
+	descriptions := []string{}
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, fmt.Sprintf("%s %s: expected %v, actual %v", v.Invariant, v.Currency, v.Expected, v.Actual))
+	}
+	return errorCodesToMessagesMap[IntegrityViolationError][locale()] + " (" + strings.Join(descriptions, "; ") + ")"
+}
+
+func (r *InMemoryAccountRepository) VerifyInvariants() (*InvariantReport, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	report := &InvariantReport{Violations: []InvariantViolation{}, CheckedAt: clock.Now().UTC()}
+	check := func(invariant, currency string, expected, actual float64) {
+		if drift := actual - expected; math.Abs(drift) >= reconciliationTolerance {
+			report.Violations = append(report.Violations, InvariantViolation{invariant, currency, expected, actual, drift})
+		}
+	}
+
+	// Summing up exact balances per currency, loan and nostro accounts mirror outstanding principal and money held by peers rather than hold money
+	supply := map[string]float64{}
+	ledger := map[string]float64{}
+	for _, acc := range r.Accounts.All() {
+		ledger[acc.Currency] += acc.BookedBalance() + acc.Fractions
+		if acc.Type != LoanAccount && acc.Type != NostroAccount {
+			supply[acc.Currency] += acc.BookedBalance() + acc.Fractions
+		}
+	}
+	for key, balance := range r.LedgerBalances {
+		currency := key[strings.LastIndex(key, "|")+1:]
+		ledger[currency] += balance
+	}
+	expected := map[string]float64{}
+	for currency, converted := range r.ConvertedBalances {
+		expected[currency] += converted
+	}
+	if r.EmissionAccount != nil {
+		expected[r.EmissionAccount.Currency] += r.TotalEmitted
+	}
+	// Money sent to peer banks has left circulation, money received from them and credited by adjustments has entered it
+	for key, balance := range r.LedgerBalances {
+		if strings.HasPrefix(key, InterbankLedgerAccount+"|") {
+			expected[key[len(InterbankLedgerAccount)+1:]] -= balance
+		}
+		if strings.HasPrefix(key, AdjustmentsLedgerAccount+"|") {
+			expected[key[len(AdjustmentsLedgerAccount)+1:]] -= balance
+		}
+	}
+	for currency := range expected {
+		if _, exists := supply[currency]; !exists {
+			supply[currency] = 0
+		}
+	}
+
+	currencies := []string{}
+	for currency := range supply {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		check(MoneySupplyInvariant, currency, expected[currency], supply[currency])
+	}
+	for _, currency := range currencies {
+		check(LedgerInvariant, currency, 0, ledger[currency])
+	}
+
+	// Checking if every transaction has been posted and every posting balances
+	if len(r.Journal) != len(r.Transactions) {
+		check(JournalInvariant, "", float64(len(r.Transactions)), float64(len(r.Journal)))
+	}
+	for _, entry := range r.Journal {
+		totals := map[string]float64{}
+		for _, line := range entry.Lines {
+			totals[line.Currency] += line.Debit - line.Credit
+		}
+		for currency, total := range totals {
+			check(JournalInvariant, currency, 0, total)
+		}
+	}
+
+	if len(report.Violations) > 0 {
+		return report, &IntegrityError{report.Violations}
+	}
+	return report, nil
+}
+
+// Verifies the invariants every interval until the returned function is called, the callback receives results of every run
+func (s *AccountService) StartInvariantChecks(interval time.Duration, callback func(*InvariantReport, error)) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				report, err := s.VerifyInvariants()
+				if callback != nil {
+					callback(report, err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() { once.Do(func() { close(done) }) }
+}
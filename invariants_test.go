@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// Invariants hold after regular operations and drift is reported with the affected invariant and currency
+func TestVerifyInvariants(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.RateProvider = NewFixedRateProvider(map[string]float64{"BYN/USD": 0.3})
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	dollars, _ := service.OpenAccountInCurrency("USD")
+	service.TransferMoney(sender.Iban, recipient.Iban, 10.005)
+	service.ConvertAndTransfer(sender.Iban, dollars.Iban, 33.333)
+	service.DestructMoney(recipient.Iban, 5)
+	if _, err := service.OpenLoan(recipient.Iban, 1000, 12, 12, time.Now().AddDate(0, 1, 0)); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if report, err := service.VerifyInvariants(); err != nil || len(report.Violations) != 0 {
+		t.Fatalf("Unexpected violations: %v", err)
+	}
+
+	// Simulating money appearing from nowhere
+	stored, _ := inMemImpl.Accounts.Get(sender.Iban)
+	stored.Balance += 0.01
+	report, err := service.VerifyInvariants()
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) || len(integrityErr.Violations) != 2 {
+		t.Fatalf("Expected integrity error, got %v", err)
+	}
+	violation := report.Violations[0]
+	if violation.Invariant != MoneySupplyInvariant || violation.Currency != "BYN" || math.Abs(violation.Expected-(1100-33.333)) > reconciliationTolerance || math.Abs(violation.Drift-0.01) > reconciliationTolerance {
+		t.Errorf("Unexpected violation: %+v", violation)
+	}
+	if report.Violations[1].Invariant != LedgerInvariant {
+		t.Errorf("Unexpected violation: %+v", report.Violations[1])
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining CIDR-based allow and deny lists of the servers: reads and mutations are checked against separate policies,
+// rejected connections are recorded in the audit log with the address of the client as the counterparty
+type IpPolicy struct {
+	Allow []*net.IPNet // clients outside of these networks are rejected, empty list allows any client
+	Deny  []*net.IPNet // clients inside of these networks are rejected even if allowed
+}
+
+// Accepts networks in CIDR notation and single addresses, i.e. "10.0.0.0/8" or "192.168.1.10"
+func NewIpPolicy(allow, deny []string) (*IpPolicy, error) {
+	policy := &IpPolicy{Allow: []*net.IPNet{}, Deny: []*net.IPNet{}}
+	for _, list := range []struct {
+		entries []string
+		target  *[]*net.IPNet
+	}{{allow, &policy.Allow}, {deny, &policy.Deny}} {
+		for _, entry := range list.entries {
+			entry = strings.TrimSpace(entry)
+			if !strings.Contains(entry, "/") {
+				ip := net.ParseIP(entry)
+				if ip == nil {
+					return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidIpPolicyError][locale()])
+				}
+				bits := 8 * net.IPv6len
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 8*net.IPv4len
+				}
+				*list.target = append(*list.target, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidIpPolicyError][locale()])
+			}
+			*list.target = append(*list.target, network)
+		}
+	}
+	return policy, nil
+}
+
+// Deny list takes precedence over the allow list, nil policy allows any client
+func (p *IpPolicy) Allows(ip net.IP) bool {
+	if p == nil {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.Deny {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, network := range p.Allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+type IpFilter struct {
+	Read     *IpPolicy // applies to GET, HEAD and OPTIONS requests, any client is allowed if not set
+	Mutation *IpPolicy // applies to other requests, any client is allowed if not set
+}
+
+// Helper function to take the address of the client, forwarding headers are not trusted since clients can set them
+func remoteIp(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return net.ParseIP(host)
+}
+
+// Helper function to record the rejected connection in the audit log of the service
+func (f *IpFilter) reject(service *AccountService, addr string) error {
+	return service.audited(ConnectAction, "", addr, 0, fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()]))
+}
+
+// Wraps the handler to reject clients not allowed by the policy of the request, reads and mutations are told by the method
+func IpFilterMiddleware(service *AccountService, filter *IpFilter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		policy := filter.Mutation
+		if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions {
+			policy = filter.Read
+		}
+		if !policy.Allows(remoteIp(req.RemoteAddr)) {
+			http.Error(w, filter.reject(service, req.RemoteAddr).Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+type ipFilterListener struct {
+	net.Listener
+	service *AccountService
+	filter  *IpFilter
+	policy  *IpPolicy
+}
+
+// Wraps the listener of a TCP server to close connections of clients not allowed by the policy right after accepting them,
+// the mutation policy applies if the server accepts mutations, the read policy applies otherwise
+func (f *IpFilter) Listener(service *AccountService, listener net.Listener, mutations bool) net.Listener {
+	policy := f.Read
+	if mutations {
+		policy = f.Mutation
+	}
+	return &ipFilterListener{listener, service, f, policy}
+}
+
+func (l *ipFilterListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.policy.Allows(remoteIp(conn.RemoteAddr().String())) {
+			return conn, nil
+		}
+		l.filter.reject(l.service, conn.RemoteAddr().String())
+		conn.Close()
+	}
+}
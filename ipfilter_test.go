@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Deny list takes precedence over the allow list, reads and mutations are checked against their own policies
+func TestIpFilter(t *testing.T) {
+	if _, err := NewIpPolicy([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Errorf("Invalid network failed to fail")
+	}
+	policy, err := NewIpPolicy([]string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"}, []string{"10.1.0.0/16"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for ip, allowed := range map[string]bool{"10.2.3.4": true, "10.1.2.3": false, "192.168.1.10": true, "192.168.1.11": false, "2001:db8::1": true, "2001:db9::1": false} {
+		if policy.Allows(net.ParseIP(ip)) != allowed {
+			t.Errorf("Expected %s to be allowed: %v", ip, allowed)
+		}
+	}
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	mutation, _ := NewIpPolicy([]string{"10.0.0.0/8"}, nil)
+	handler := IpFilterMiddleware(service, &IpFilter{Mutation: mutation}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	serve := func(method, addr string) int {
+		req := httptest.NewRequest(method, "/accounts", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if status := serve(http.MethodGet, "203.0.113.5:4000"); status != http.StatusOK {
+		t.Errorf("Expected read from any address to be allowed, got %d", status)
+	}
+	if status := serve(http.MethodPost, "203.0.113.5:4000"); status != http.StatusForbidden {
+		t.Errorf("Expected mutation from outside of the allow list to be rejected, got %d", status)
+	}
+	if status := serve(http.MethodPost, "10.0.0.5:4000"); status != http.StatusOK {
+		t.Errorf("Expected mutation from the allow list to be allowed, got %d", status)
+	}
+	entries, _ := service.QueryAuditLog(AuditFilter{Actions: []AuditAction{ConnectAction}})
+	if len(entries) != 1 || entries[0].Counterparty != "203.0.113.5:4000" || entries[0].Error == "" {
+		t.Errorf("Expected the rejected connection to be audited, got %+v", entries)
+	}
+}
+
+// Connections of clients not allowed are closed by the listener right after accepting them
+func TestIpFilterListener(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	deny, _ := NewIpPolicy(nil, []string{"127.0.0.0/8"})
+	filtered := (&IpFilter{Mutation: deny}).Listener(service, listener, true)
+	defer filtered.Close()
+
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := filtered.Accept()
+		accepted <- err
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err == nil {
+		t.Errorf("Expected the connection to be closed by the server")
+	}
+	conn.Close()
+	filtered.Close()
+	if err := <-accepted; err == nil {
+		t.Errorf("Expected no connection to be accepted")
+	}
+	if entries, _ := service.QueryAuditLog(AuditFilter{Actions: []AuditAction{ConnectAction}}); len(entries) != 1 {
+		t.Errorf("Expected the rejected connection to be audited, got %+v", entries)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining bearer token authentication: JWTs signed with HS256 or RS256 are verified against the keys of the config,
+// their claims are mapped to a principal, so that the service bound to it checks permissions and audits the operations
+type JwtConfig struct {
+	HmacSecret      string        // secret of HS256 tokens, such tokens are rejected if empty
+	RsaPublicKeyPem string        // PEM encoded public key of RS256 tokens, such tokens are rejected if empty
+	Issuer          string        // expected "iss" claim, not checked if empty
+	Audience        string        // expected "aud" claim, not checked if empty
+	Leeway          time.Duration // allowed clock skew when checking "exp" and "nbf"
+}
+
+type JwtVerifier struct {
+	config    JwtConfig
+	rsaPublic *rsa.PublicKey
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject    string          `json:"sub"`
+	Issuer     string          `json:"iss"`
+	Audience   json.RawMessage `json:"aud"` // either a string or an array of strings
+	ExpiresAt  float64         `json:"exp"`
+	NotBefore  float64         `json:"nbf"`
+	Role       string          `json:"role"`
+	Roles      []string        `json:"roles"`
+	CustomerID string          `json:"customer_id"`
+}
+
+func NewJwtVerifier(config JwtConfig) (*JwtVerifier, error) {
+	verifier := &JwtVerifier{config: config}
+	if strings.TrimSpace(config.RsaPublicKeyPem) != "" {
+		block, _ := pem.Decode([]byte(config.RsaPublicKeyPem))
+		if block == nil {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTokenError][locale()])
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTokenError][locale()])
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTokenError][locale()])
+		}
+		verifier.rsaPublic = rsaKey
+	}
+	// Checking if at least one kind of tokens can be verified
+	if config.HmacSecret == "" && verifier.rsaPublic == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTokenError][locale()])
+	}
+	return verifier, nil
+}
+
+// Helper function to find the role by its English name, case is ignored
+func roleByName(name string) (Role, bool) {
+	for role, names := range roleCodeToNameMap {
+		if strings.EqualFold(names[English], strings.TrimSpace(name)) {
+			return role, true
+		}
+	}
+	return CustomerRole, false
+}
+
+// Helper function to check if the "aud" claim names the audience
+func (claims *jwtClaims) hasAudience(audience string) bool {
+	var single string
+	if json.Unmarshal(claims.Audience, &single) == nil {
+		return single == audience
+	}
+	var multiple []string
+	if json.Unmarshal(claims.Audience, &multiple) == nil {
+		for _, a := range multiple {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Verifies the signature and the time and issuer claims of the token, returns the principal of its subject,
+// the most privileged of the roles named by the "role" and "roles" claims is taken
+func (v *JwtVerifier) Verify(token string, now time.Time) (Principal, error) {
+	invalid := fmt.Errorf(errorCodesToMessagesMap[InvalidTokenError][locale()])
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return Principal{}, invalid
+	}
+	var header jwtHeader
+	if !decodeJwtPart(parts[0], &header) {
+		return Principal{}, invalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, invalid
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	// Checking if the signature matches the key of the algorithm, "none" and other algorithms are rejected
+	switch header.Alg {
+	case "HS256":
+		if v.config.HmacSecret == "" {
+			return Principal{}, invalid
+		}
+		mac := hmac.New(sha256.New, []byte(v.config.HmacSecret))
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return Principal{}, invalid
+		}
+	case "RS256":
+		if v.rsaPublic == nil {
+			return Principal{}, invalid
+		}
+		digest := sha256.Sum256(signed)
+		if rsa.VerifyPKCS1v15(v.rsaPublic, crypto.SHA256, digest[:], signature) != nil {
+			return Principal{}, invalid
+		}
+	default:
+		return Principal{}, invalid
+	}
+
+	var claims jwtClaims
+	if !decodeJwtPart(parts[1], &claims) {
+		return Principal{}, invalid
+	}
+	// Checking if the token is valid at the moment, tokens without expiration are rejected
+	if claims.ExpiresAt == 0 || !now.Before(time.Unix(int64(claims.ExpiresAt), 0).Add(v.config.Leeway)) {
+		return Principal{}, invalid
+	}
+	if claims.NotBefore != 0 && now.Add(v.config.Leeway).Before(time.Unix(int64(claims.NotBefore), 0)) {
+		return Principal{}, invalid
+	}
+	if (v.config.Issuer != "" && claims.Issuer != v.config.Issuer) || (v.config.Audience != "" && !claims.hasAudience(v.config.Audience)) {
+		return Principal{}, invalid
+	}
+	if strings.TrimSpace(claims.Subject) == "" {
+		return Principal{}, invalid
+	}
+
+	principal := Principal{ID: strings.TrimSpace(claims.Subject), Role: CustomerRole, CustomerID: strings.TrimSpace(claims.CustomerID)}
+	for _, name := range append([]string{claims.Role}, claims.Roles...) {
+		if role, known := roleByName(name); known && role > principal.Role {
+			principal.Role = role
+		}
+	}
+	return principal, nil
+}
+
+// Helper function to decode the base64url encoded JSON part of the token
+func decodeJwtPart(part string, v interface{}) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(part)
+	return err == nil && json.Unmarshal(decoded, v) == nil
+}
+
+// Wraps the handler to authenticate requests by bearer tokens, requests other than GET, HEAD and OPTIONS are rejected
+// without a valid token, the principal of the token is passed to the handler in the request context, see PrincipalFromContext
+// GET, HEAD and OPTIONS requests without a token are passed on behalf of the anonymous principal, so that the service bound
+// to them by ForRequest denies whatever they ask for
+func JwtMiddleware(verifier *JwtVerifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization := req.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions {
+				next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), principalContextKey{}, anonymousPrincipal)))
+				return
+			}
+			http.Error(w, errorCodesToMessagesMap[InvalidTokenError][locale()], http.StatusUnauthorized)
+			return
+		}
+		principal, err := verifier.Verify(strings.TrimPrefix(authorization, "Bearer "), clock.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), principalContextKey{}, principal)))
+	})
+}
+
+// Returns the service acting on behalf of the principal authenticated by the middleware, requests let through by the
+// middleware without credentials are bound to the anonymous principal, the service itself if no middleware was involved
+func (s *AccountService) ForRequest(req *http.Request) *AccountService {
+	// Operations are traced in the span of the request if the request is traced, see TracingMiddleware
+	if span, traced := SpanFromContext(req.Context()); traced {
+		s = s.WithTrace(span)
+	}
+	if principal, ok := PrincipalFromContext(req.Context()); ok {
+		return s.As(principal)
+	}
+	return s
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper function to sign the claims with HS256 if the secret is given or with RS256 otherwise
+func signTestJwt(t *testing.T, alg string, claims map[string]interface{}, secret string, key *rsa.PrivateKey) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	var signature []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case "RS256":
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Tokens are verified against the configured keys and mapped to principals with roles
+func TestJwtVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	publicPem := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if _, err := NewJwtVerifier(JwtConfig{}); err == nil {
+		t.Errorf("Verifier without keys failed to fail")
+	}
+	verifier, err := NewJwtVerifier(JwtConfig{HmacSecret: "secret", RsaPublicKeyPem: publicPem, Issuer: "bank", Audience: "payments"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	now := time.Now()
+	claims := map[string]interface{}{"sub": "ivan", "iss": "bank", "aud": []string{"payments"}, "exp": now.Add(time.Hour).Unix(), "roles": []string{"teller", "Auditor"}}
+
+	principal, err := verifier.Verify(signTestJwt(t, "HS256", claims, "secret", nil), now)
+	if err != nil || principal.ID != "ivan" || principal.Role != TellerRole {
+		t.Errorf("Expected teller ivan, got %+v (%v)", principal, err)
+	}
+	if _, err := verifier.Verify(signTestJwt(t, "RS256", claims, "", rsaKey), now); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if _, err := verifier.Verify(signTestJwt(t, "HS256", claims, "other", nil), now); err == nil {
+		t.Errorf("Token signed with another secret failed to fail")
+	}
+	if _, err := verifier.Verify(strings.TrimRight(signTestJwt(t, "none", claims, "", nil), "."), now); err == nil {
+		t.Errorf("Unsigned token failed to fail")
+	}
+	if _, err := verifier.Verify(signTestJwt(t, "HS256", claims, "secret", nil), now.Add(2*time.Hour)); err == nil {
+		t.Errorf("Expired token failed to fail")
+	}
+	claims["iss"] = "other"
+	if _, err := verifier.Verify(signTestJwt(t, "HS256", claims, "secret", nil), now); err == nil {
+		t.Errorf("Token of another issuer failed to fail")
+	}
+	customer := map[string]interface{}{"sub": "petr", "iss": "bank", "aud": "payments", "exp": now.Add(time.Hour).Unix(), "customer_id": "C1"}
+	if principal, err := verifier.Verify(signTestJwt(t, "HS256", customer, "secret", nil), now); err != nil || principal.Role != CustomerRole || principal.CustomerID != "C1" {
+		t.Errorf("Expected customer petr, got %+v (%v)", principal, err)
+	}
+}
+
+// Mutation requests need a valid token, the principal of the token is recorded in the audit log
+func TestJwtMiddleware(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	verifier, _ := NewJwtVerifier(JwtConfig{HmacSecret: "secret"})
+	server := httptest.NewServer(JwtMiddleware(verifier, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := service.ForRequest(req).OpenAccount(); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		}
+	})))
+	defer server.Close()
+	do := func(method, token string) int {
+		req, _ := http.NewRequest(method, server.URL, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	post := func(token string) int { return do(http.MethodPost, token) }
+	exp := time.Now().Add(time.Hour).Unix()
+
+	if status := post(""); status != http.StatusUnauthorized {
+		t.Errorf("Expected unauthenticated request to be rejected, got %d", status)
+	}
+	if status := post("not.a.token"); status != http.StatusUnauthorized {
+		t.Errorf("Expected invalid token to be rejected, got %d", status)
+	}
+	if status := post(signTestJwt(t, "HS256", map[string]interface{}{"sub": "auditor", "exp": exp, "role": "auditor"}, "secret", nil)); status != http.StatusForbidden {
+		t.Errorf("Expected opening by the auditor to be denied, got %d", status)
+	}
+	if status := post(signTestJwt(t, "HS256", map[string]interface{}{"sub": "teller", "exp": exp, "role": "teller"}, "secret", nil)); status != http.StatusOK {
+		t.Errorf("Expected opening by the teller to succeed, got %d", status)
+	}
+	entries, _ := service.QueryAuditLog(AuditFilter{Actions: []AuditAction{OpenAction}})
+	if len(entries) != 2 || entries[0].Principal != "auditor" || entries[0].Error == "" || entries[1].Principal != "teller" || entries[1].Error != "" {
+		t.Errorf("Expected openings to be audited with the principals of the tokens, got %+v", entries)
+	}
+	// Requests without a token are let through on behalf of the anonymous principal, which is denied the opening
+	if status := do(http.MethodGet, ""); status != http.StatusForbidden {
+		t.Errorf("Expected opening by the anonymous principal to be denied, got %d", status)
+	}
+	if accounts, _ := service.ListAccountsByType(Ordinary); len(accounts) != 1 {
+		t.Errorf("Expected only the opening by the teller, got %+v", accounts)
+	}
+	// Tokens expire by the clock of the package
+	defer UseClock(NewFakeClock(time.Now().Add(2 * time.Hour)))()
+	if status := post(signTestJwt(t, "HS256", map[string]interface{}{"sub": "teller", "exp": exp, "role": "teller"}, "secret", nil)); status != http.StatusUnauthorized {
+		t.Errorf("Expected the token expired by the clock to be rejected, got %d", status)
+	}
+}
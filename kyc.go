@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+)
+
+// --------------------------------------------------------
+// Defining know-your-customer verification states of accounts
+type KycStatus int8
+
+const (
+	KycPending KycStatus = iota
+	KycVerified
+	KycRejected
+)
+
+// Mapping KYC status codes to KYC status names considering locale
+var kycStatusCodeToNameMap map[KycStatus](map[LanguageCode]string) = map[KycStatus](map[LanguageCode]string){
+	KycPending: {
+		English: "Pending",
+		Russian: "Ожидает проверки",
+	},
+	KycVerified: {
+		English: "Verified",
+		Russian: "Проверен",
+	},
+	KycRejected: {
+		English: "Rejected",
+		Russian: "Отклонен",
+	},
+}
+
+// Maximum amount a single debit from an account pending KYC verification can take
+// ideally this value should be parsed from environmental configuration
+const PendingKycDebitLimit float64 = 1000
+
+// Helper function to check if the account is allowed to be debited with the given amount considering its KYC status
+func checkKycDebit(acc *Account, amount float64) error {
+	switch acc.Kyc {
+	case KycRejected:
+		return fmt.Errorf(errorCodesToMessagesMap[KycRejectedError][locale()])
+	case KycPending:
+		if r, _ := roundAndExtractFractionsInCurrency(amount, acc.Currency); r > PendingKycDebitLimit {
+			return fmt.Errorf(errorCodesToMessagesMap[KycLimitExceededError][locale()])
+		}
+	}
+	return nil
+}
+
+// Promotes the account to verified state lifting the limits, previously rejected accounts can be verified after a review
+func (r *InMemoryAccountRepository) VerifyKyc(iban string) error {
+	return r.changeKycStatus(iban, KycVerified, KycPending, KycRejected)
+}
+
+// Rejects the account pending KYC verification, rejected accounts cannot be debited
+func (r *InMemoryAccountRepository) RejectKyc(iban string) error {
+	return r.changeKycStatus(iban, KycRejected, KycPending)
+}
+
+func (r *InMemoryAccountRepository) changeKycStatus(iban string, to KycStatus, allowedFrom ...KycStatus) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Ensuring that we indeed got the correct account object
+	if acc.Iban != iban {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if the account is in a state the transition is allowed from
+	for _, from := range allowedFrom {
+		if acc.Kyc == from {
+			acc.Kyc = to
+			return nil
+		}
+	}
+	return fmt.Errorf(errorCodesToMessagesMap[KycTransitionError][locale()])
+}
+
+func (s *AccountService) VerifyKyc(iban string) error {
+	if err := s.authorize(OpenPermission, iban); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.VerifyKyc(iban)
+}
+
+func (s *AccountService) RejectKyc(iban string) error {
+	if err := s.authorize(OpenPermission, iban); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RejectKyc(iban)
+}
+
+// Verifies all accounts of the customer pending verification at once
+func (s *AccountService) VerifyCustomerKyc(customerID string) error {
+	if err := s.authorizeCustomer(OpenPermission, customerID); err != nil {
+		return err
+	}
+	accounts, err := s.ListAccountsByCustomer(customerID)
+	if err != nil {
+		return err
+	}
+	for _, details := range accounts {
+		acc, err := s.accountRepoImpl.GetAccount(details.Iban)
+		if err != nil {
+			return err
+		}
+		if acc.Kyc != KycPending {
+			continue
+		}
+		if err := s.accountRepoImpl.VerifyKyc(acc.Iban); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+)
+
+// Limit debits of newly opened accounts until KYC verification
+func TestKycWorkflow(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+
+	sender, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	recipient, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if sender.Kyc != KycPending {
+		t.Errorf("Expected newly opened account to be pending KYC verification, got %v", sender.Kyc)
+	}
+	if err := service.EmitMoney(5000); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Special accounts are verified, so crediting pending accounts with large amounts is allowed
+	if _, err := service.TransferMoney(emission, sender.Iban, 5000); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, PendingKycDebitLimit+0.01); err == nil {
+		t.Errorf("Transfer over the pending KYC limit failed to fail")
+	}
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, PendingKycDebitLimit); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if err := service.VerifyKyc(sender.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 2000); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if err := service.RejectKyc(sender.Iban); err == nil {
+		t.Errorf("Rejecting verified account failed to fail")
+	}
+
+	if err := service.RejectKyc(recipient.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.DestructMoney(recipient.Iban, 1); err == nil {
+		t.Errorf("Debiting rejected account failed to fail")
+	}
+	if details, err := service.RetrieveAccount(recipient.Iban); err != nil || details.Kyc != "Rejected" {
+		t.Errorf("Unexpected account details %+v (%v)", details, err)
+	}
+}
+
+// Verify all accounts of a customer at once
+func TestCustomerKycVerification(t *testing.T) {
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	c, err := service.CreateCustomer("Ivan Ivanov", "", "", "")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := service.OpenAccountForCustomer(c.ID); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if err := service.VerifyCustomerKyc(c.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	accounts, _ := service.ListAccountsByCustomer(c.ID)
+	for _, acc := range accounts {
+		if acc.Kyc != "Verified" {
+			t.Errorf("Expected account %s to be verified, got %s", acc.Iban, acc.Kyc)
+		}
+	}
+}
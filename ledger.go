@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining double-entry general ledger: every money movement is posted as a journal entry of balanced debit and credit lines,
+// so money can only enter circulation through emission (against the issued money account) and leave it through destruction
+// Debits decrease and credits increase balances of customer and system IBAN accounts,
+// off-balance ledger accounts below carry the counterparts of money which is not held by any IBAN account
+const (
+	IssuedLedgerAccount      = "ISSUED"      // counterpart of emitted and imported money
+	FxPositionLedgerAccount  = "FX-POSITION" // open currency position of the bank resulting from conversions
+	LoansLedgerAccount       = "LOANS"       // counterpart of outstanding principal mirrored by loan accounts
+	AdjustmentsLedgerAccount = "ADJUSTMENTS" // counterpart of manual corrections of balances made by operators
+)
+
+// Either the IBAN account or the ledger account is set, the account name is the IBAN in the former case
+type LedgerLine struct {
+	Account  string
+	Currency string
+	Debit    float64
+	Credit   float64
+	acc      *Account
+}
+
+type JournalEntry struct {
+	ID            uint64
+	TransactionID uint64
+	Lines         []LedgerLine
+	Timestamp     time.Time
+}
+
+// Helper functions to build ledger lines against IBAN accounts
+func debitLine(acc *Account, amount float64) LedgerLine {
+	return LedgerLine{Account: acc.Iban, Currency: acc.Currency, Debit: amount, acc: acc}
+}
+
+func creditLine(acc *Account, amount float64) LedgerLine {
+	return LedgerLine{Account: acc.Iban, Currency: acc.Currency, Credit: amount, acc: acc}
+}
+
+// Helper functions to build ledger lines against off-balance ledger accounts
+func debitLedgerLine(account, currency string, amount float64) LedgerLine {
+	return LedgerLine{Account: account, Currency: currency, Debit: amount}
+}
+
+func creditLedgerLine(account, currency string, amount float64) LedgerLine {
+	return LedgerLine{Account: account, Currency: currency, Credit: amount}
+}
+
+// Helper function to build the lines of a plain movement from one account to another in the same currency
+func transferLines(from, to *Account, amount float64) []LedgerLine {
+	return []LedgerLine{debitLine(from, amount), creditLine(to, amount)}
+}
+
+// Key of an off-balance ledger account balance, balances are kept per currency
+func ledgerBalanceKey(account, currency string) string {
+	return account + "|" + currency
+}
+
+// Helper function to record the transaction along with its journal entry, expects the repository mutex to be held by the caller
+// Nothing is applied if debits and credits of the lines do not match in any of the currencies
+func (r *InMemoryAccountRepository) post(tx *Transaction, lines ...LedgerLine) error {
+	// Checking if debits equal credits in every currency of the entry
+	totals := map[string]float64{}
+	for _, line := range lines {
+		if line.Debit < 0 || line.Credit < 0 || math.IsNaN(line.Debit) || math.IsNaN(line.Credit) {
+			return fmt.Errorf(errorCodesToMessagesMap[LedgerImbalanceError][locale()])
+		}
+		totals[line.Currency] += line.Debit - line.Credit
+	}
+	for _, total := range totals {
+		if math.Abs(total) > reconciliationTolerance {
+			return fmt.Errorf(errorCodesToMessagesMap[LedgerImbalanceError][locale()])
+		}
+	}
+
+	for _, line := range lines {
+		r.applyLine(line, 1)
+	}
+	r.recordTransaction(tx)
+	r.Journal = append(r.Journal, &JournalEntry{uint64(len(r.Journal) + 1), tx.ID, lines, tx.Timestamp})
+	for _, line := range lines {
+		if line.acc != nil {
+			r.Accounts.RecordActivity(line.acc.Iban, tx.Timestamp)
+		}
+	}
+	return nil
+}
+
+// Helper function to apply the line to the balances, sign is -1 to revert the line, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) applyLine(line LedgerLine, sign float64) {
+	if line.acc != nil {
+		line.acc.Deduct(sign * line.Debit)
+		line.acc.Add(sign * line.Credit)
+		return
+	}
+	r.LedgerBalances[ledgerBalanceKey(line.Account, line.Currency)] += sign * (line.Credit - line.Debit)
+}
+
+// Helper function to revert transactions, journal entries and held events recorded after the first count transactions,
+// expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) rollbackTo(count int) {
+	for len(r.Transactions) > count {
+		tx := r.Transactions[len(r.Transactions)-1]
+		if n := len(r.Journal); n > 0 && r.Journal[n-1].TransactionID == tx.ID {
+			for _, line := range r.Journal[n-1].Lines {
+				r.applyLine(line, -1)
+			}
+			r.Journal = r.Journal[:n-1]
+		}
+		if n := len(r.SupplyRegister); n > 0 && r.SupplyRegister[n-1].TransactionID == tx.ID {
+			r.SupplyRegister = r.SupplyRegister[:n-1]
+		}
+		r.Transactions = r.Transactions[:len(r.Transactions)-1]
+	}
+	r.dropHeldEvents(count)
+}
+
+// Returns the balance of the off-balance ledger account in the given currency, i.e. ISSUED is the negated amount of emitted money
+func (r *InMemoryAccountRepository) RetrieveLedgerBalance(account, currency string) float64 {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	return r.LedgerBalances[ledgerBalanceKey(account, currency)]
+}
+
+func (r *InMemoryAccountRepository) RetrieveJournalAsJson() (string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+	return r.journalAsJson()
+}
+
+// Helper function to serialize the journal, expects the repository mutex and the journal mutex to be held shared by the caller
+func (r *InMemoryAccountRepository) journalAsJson() (string, error) {
+	type lineDetails struct {
+		Account  string  `json:"account"`
+		Currency string  `json:"currency"`
+		Debit    float64 `json:"debit,omitempty"`
+		Credit   float64 `json:"credit,omitempty"`
+	}
+	type entryDetails struct {
+		ID            uint64        `json:"id"`
+		TransactionID uint64        `json:"transaction_id"`
+		Lines         []lineDetails `json:"lines"`
+		Timestamp     time.Time     `json:"timestamp"`
+	}
+	allEntryDetails := []entryDetails{}
+	for _, entry := range r.Journal {
+		lines := []lineDetails{}
+		for _, line := range entry.Lines {
+			lines = append(lines, lineDetails{line.Account, line.Currency, roundToCurrency(line.Debit, line.Currency), roundToCurrency(line.Credit, line.Currency)})
+		}
+		allEntryDetails = append(allEntryDetails, entryDetails{entry.ID, entry.TransactionID, lines, entry.Timestamp})
+	}
+	output, err := json.Marshal(allEntryDetails)
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[TransactionsJsonError][locale()])
+	}
+	return string(output), nil
+}
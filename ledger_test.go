@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Every transaction gets a journal entry and balances of all ledger accounts sum up to zero in each currency
+func TestLedgerPostings(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.RateProvider = NewFixedRateProvider(map[string]float64{"BYN/USD": 0.3})
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	dollars, _ := service.OpenAccountInCurrency("USD")
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 30); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.ConvertAndTransfer(sender.Iban, dollars.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.DestructMoney(recipient.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if len(inMemImpl.Journal) != len(inMemImpl.Transactions) {
+		t.Fatalf("Expected %d journal entries, got %d", len(inMemImpl.Transactions), len(inMemImpl.Journal))
+	}
+	if issued := service.RetrieveLedgerBalance(IssuedLedgerAccount, "BYN"); issued != -100 {
+		t.Errorf("Expected issued money of -100, got %v", issued)
+	}
+	if position := service.RetrieveLedgerBalance(FxPositionLedgerAccount, "USD"); position != -15 {
+		t.Errorf("Expected USD position of -15, got %v", position)
+	}
+	totals := map[string]float64{}
+	for _, acc := range inMemImpl.Accounts.All() {
+		totals[acc.Currency] += acc.Balance
+	}
+	for key, balance := range inMemImpl.LedgerBalances {
+		totals[key[strings.Index(key, "|")+1:]] += balance
+	}
+	for currency, total := range totals {
+		if math.Abs(total) > reconciliationTolerance {
+			t.Errorf("Ledger does not balance in %s: %v", currency, total)
+		}
+	}
+
+	res, err := service.RetrieveJournalAsJson()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var entries []struct {
+		TransactionID uint64 `json:"transaction_id"`
+		Lines         []struct {
+			Account string `json:"account"`
+		} `json:"lines"`
+	}
+	if err := json.Unmarshal([]byte(res), &entries); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Emission and transfer of the initial deposit, the transfer, the conversion and the destruction
+	if len(entries) != 5 || len(entries[3].Lines) != 4 || entries[3].TransactionID != 4 {
+		t.Errorf("Unexpected journal: %s", res)
+	}
+}
+
+// Entries whose debits and credits differ are rejected without touching the balances
+func TestLedgerImbalance(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+
+	stored, _ := inMemImpl.Accounts.Get(acc.Iban)
+	tx := &Transaction{Type: TransferTransaction, Sender: acc.Iban, Recipient: inMemImpl.DestructionAccount.Iban, Amount: 10, Currency: acc.Currency}
+	err := inMemImpl.post(tx, debitLine(stored, 10), creditLine(inMemImpl.DestructionAccount, 9.99))
+	if err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[LedgerImbalanceError][locale()]) {
+		t.Errorf("Expected imbalanced entry to be rejected, got %v", err)
+	}
+	if stored.Balance != 100 || inMemImpl.DestructionAccount.Balance != 0 || len(inMemImpl.Transactions) != 2 || len(inMemImpl.Journal) != 2 {
+		t.Errorf("Rejected entry changed the ledger")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining per-account debit limits, zero value of a limit means there is no limit
+type AccountLimits struct {
+	PerTransaction float64 // maximum amount of a single debit
+	Daily          float64 // maximum sum of debits per calendar day (UTC)
+}
+
+// Helper function to check if the debit fits into the limits of the account, the default limits of the repository apply
+// to ordinary accounts which do not have the limit of their own
+func (r *InMemoryAccountRepository) checkDebitLimits(acc *Account, amount float64) error {
+	limits := acc.Limits
+	if acc.Type == Ordinary && limits.PerTransaction == 0 {
+		limits.PerTransaction = r.DefaultLimits.PerTransaction
+	}
+	if acc.Type == Ordinary && limits.Daily == 0 {
+		limits.Daily = r.DefaultLimits.Daily
+	}
+	amount = roundToCurrency(amount, acc.Currency)
+	if limits.PerTransaction > 0 && amount > limits.PerTransaction {
+		return fmt.Errorf(errorCodesToMessagesMap[LimitExceededError][locale()])
+	}
+	if limits.Daily > 0 && roundToCurrency(acc.debitedOn(clock.Now().UTC())+amount, acc.Currency) > limits.Daily {
+		return fmt.Errorf(errorCodesToMessagesMap[LimitExceededError][locale()])
+	}
+	return nil
+}
+
+// Helper function to get the sum of debits made on the day of the given time
+func (acc *Account) debitedOn(t time.Time) float64 {
+	if acc.DailyDebitDate != t.Format(time.DateOnly) {
+		return 0
+	}
+	return acc.DailyDebited
+}
+
+// Helper function to count the debit towards the daily limit, the counter starts over on the next day
+func (acc *Account) recordDebit(amount float64) {
+	now := clock.Now().UTC()
+	acc.DailyDebited = roundToCurrency(acc.debitedOn(now)+amount, acc.Currency)
+	acc.DailyDebitDate = now.Format(time.DateOnly)
+}
+
+// Helper function to take a reverted debit out of the daily counter
+func (acc *Account) revertDebit(amount float64) {
+	acc.DailyDebited = roundToCurrency(acc.DailyDebited-amount, acc.Currency)
+}
+
+// Limits can only be set for ordinary accounts, they apply to transfers and destructions from the account
+func (r *InMemoryAccountRepository) SetAccountLimits(iban string, limits AccountLimits) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is an ordinary one, special accounts are not limited
+	if acc.Type != Ordinary {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if the limits are not negative
+	if limits.PerTransaction < 0 || limits.Daily < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	acc.Limits = AccountLimits{roundToCurrency(limits.PerTransaction, acc.Currency), roundToCurrency(limits.Daily, acc.Currency)}
+	return nil
+}
+
+// Default limits apply to ordinary accounts without limits of their own, i.e. set by the configuration
+func (r *InMemoryAccountRepository) SetDefaultLimits(limits AccountLimits) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the limits are not negative
+	if limits.PerTransaction < 0 || limits.Daily < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	r.DefaultLimits = limits
+	return nil
+}
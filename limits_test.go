@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Enforce per-transaction and daily debit limits of an account
+func TestAccountLimits(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccountWithInitialDeposit(500)
+	other, _ := service.OpenAccount()
+
+	if err := service.SetAccountLimits(emission, AccountLimits{PerTransaction: 10}); err == nil {
+		t.Errorf("Limiting the emission account failed to fail")
+	}
+	if err := service.SetAccountLimits(acc.Iban, AccountLimits{Daily: -1}); err == nil {
+		t.Errorf("Setting negative limits failed to fail")
+	}
+	if err := service.SetAccountLimits(acc.Iban, AccountLimits{PerTransaction: 100, Daily: 150}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 100.01); err == nil {
+		t.Errorf("Transfer exceeding per-transaction limit failed to fail")
+	}
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.DestructMoney(acc.Iban, 50.01); err == nil {
+		t.Errorf("Destruction exceeding daily limit failed to fail")
+	}
+	if err := service.DestructMoney(acc.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Split payments count towards the limits as a whole
+	if _, err := service.SplitTransfer(acc.Iban, 1, []SplitShare{{Recipient: other.Iban, Amount: 1}}); err == nil {
+		t.Errorf("Split exceeding daily limit failed to fail")
+	}
+
+	// The daily counter starts over on the next day
+	inMemImpl := service.accountRepoImpl.(*InMemoryAccountRepository)
+	stored, _ := inMemImpl.Accounts.Get(acc.Iban)
+	stored.DailyDebitDate = time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Lifting the limits
+	if err := service.SetAccountLimits(acc.Iban, AccountLimits{}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 250); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
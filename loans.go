@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining loans: the principal is emitted and credited to a linked current account, installments are collected
+// from the linked account back to the emission account according to an annuity amortization schedule
+// Balance of the loan account mirrors the outstanding principal as a negative amount, so it is not money in circulation
+type InstallmentStatus int8
+
+const (
+	InstallmentScheduled InstallmentStatus = iota
+	InstallmentPaid
+	InstallmentOverdue // due but the linked account could not pay it, it is collected again on the next run
+)
+
+// Mapping installment status codes to installment status names considering locale
+var installmentStatusCodeToNameMap map[InstallmentStatus](map[LanguageCode]string) = map[InstallmentStatus](map[LanguageCode]string){
+	InstallmentScheduled: {
+		English: "Scheduled",
+		Russian: "Запланирован",
+	},
+	InstallmentPaid: {
+		English: "Paid",
+		Russian: "Оплачен",
+	},
+	InstallmentOverdue: {
+		English: "Overdue",
+		Russian: "Просрочен",
+	},
+}
+
+type LoanStatus int8
+
+const (
+	LoanActive LoanStatus = iota
+	LoanRepaid
+)
+
+// Mapping loan status codes to loan status names considering locale
+var loanStatusCodeToNameMap map[LoanStatus](map[LanguageCode]string) = map[LoanStatus](map[LanguageCode]string){
+	LoanActive: {
+		English: "Active",
+		Russian: "Действующий",
+	},
+	LoanRepaid: {
+		English: "Repaid",
+		Russian: "Погашен",
+	},
+}
+
+// Payment is the sum of Principal and Interest, RemainingPrincipal is the principal outstanding after the installment is paid
+type Installment struct {
+	Number             int
+	DueDate            time.Time
+	Payment            float64
+	Principal          float64
+	Interest           float64
+	RemainingPrincipal float64
+	Status             InstallmentStatus
+	TransactionID      string // ULID of the transaction collecting the installment, empty until paid
+}
+
+type Loan struct {
+	Iban        string // IBAN of the loan account
+	LinkedIban  string // IBAN of the current account the principal is credited to and installments are collected from
+	Principal   float64
+	AnnualRate  float64 // in percent
+	Months      int
+	Status      LoanStatus
+	Schedule    []Installment
+	DisbursedAt time.Time
+}
+
+// Longest term of a loan, 50 years
+const maxLoanMonths = 600
+
+func (l Loan) RemainingPrincipal() float64 {
+	remaining := l.Principal
+	for _, installment := range l.Schedule {
+		if installment.Status == InstallmentPaid {
+			remaining = installment.RemainingPrincipal
+		}
+	}
+	return remaining
+}
+
+// Returns the due date of the earliest installment not paid yet, false if the loan is repaid
+func (l Loan) NextDueDate() (time.Time, bool) {
+	for _, installment := range l.Schedule {
+		if installment.Status != InstallmentPaid {
+			return installment.DueDate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (l Loan) copy() Loan {
+	l.Schedule = append([]Installment{}, l.Schedule...)
+	return l
+}
+
+// Generates an annuity schedule with equal monthly payments rounded to the currency, the last payment absorbs the rounding
+func amortizationSchedule(principal, annualRate float64, months int, firstDue time.Time, currency string) []Installment {
+	rate := annualRate / 100 / 12
+	payment := principal / float64(months)
+	if rate > 0 {
+		payment = principal * rate / (1 - math.Pow(1+rate, -float64(months)))
+	}
+	payment = roundToCurrency(payment, currency)
+
+	schedule := make([]Installment, 0, months)
+	remaining := principal
+	for i := 0; i < months; i++ {
+		interest := roundToCurrency(remaining*rate, currency)
+		principalPart := roundToCurrency(payment-interest, currency)
+		if i == months-1 || principalPart > remaining {
+			principalPart = remaining
+		}
+		remaining = roundToCurrency(remaining-principalPart, currency)
+		schedule = append(schedule, Installment{i + 1, firstDue.AddDate(0, i, 0), roundToCurrency(principalPart+interest, currency), principalPart, interest, remaining, InstallmentScheduled, ""})
+	}
+	return schedule
+}
+
+// Opens a loan account next to the linked current account, emits the principal and credits it to the linked account
+// The first installment is due on firstDue, the following ones on the same day of the subsequent months
+func (r *InMemoryAccountRepository) OpenLoan(linkedIban string, principal, annualRate float64, months int, firstDue time.Time) (*Loan, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	linkedIban = NormalizeIban(linkedIban)
+
+	// Checking if the linked account exists and is an active ordinary one
+	linked, exists := r.Accounts.Get(linkedIban)
+	if !exists || linked == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if linked.Type != Ordinary {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	if linked.Status == Blocked {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	if linked.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the principal is emitted in the currency of the linked account
+	if r.EmissionAccount == nil || r.EmissionAccount.Currency != linked.Currency {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+	// Checking if the terms of the loan are valid finite numbers
+	principal = roundToCurrency(principal, linked.Currency)
+	if principal <= 0 || math.IsNaN(principal) || math.IsInf(principal, 0) || annualRate < 0 || math.IsNaN(annualRate) || math.IsInf(annualRate, 0) || months < 1 || months > maxLoanMonths {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidLoanTermsError][locale()])
+	}
+	// Checking if the linked account can receive the principal
+	if err := checkBalanceCeiling(linked, principal); err != nil {
+		return nil, err
+	}
+
+	acc, err := r.openAccount(linked.Currency, "")
+	if err != nil {
+		return nil, err
+	}
+	acc.Type = LoanAccount
+	r.Accounts.Put(acc)
+	acc.Kyc = KycVerified
+	acc.CustomerID = linked.CustomerID
+	txCount := len(r.Transactions)
+	if err := r.emitMoney(principal, "", LoanReason); err != nil {
+		r.Accounts.Delete(acc.Iban)
+		return nil, err
+	}
+	// The loan account mirrors the principal against the loans ledger account while the money itself comes from the emission account
+	tx := &Transaction{Type: LoanDisbursementTransaction, Sender: acc.Iban, Recipient: linked.Iban, Amount: principal, Currency: linked.Currency}
+	if err := r.post(tx, debitLine(r.EmissionAccount, principal), creditLine(linked, principal), debitLine(acc, principal), creditLedgerLine(LoansLedgerAccount, acc.Currency, principal)); err != nil {
+		r.TotalEmitted -= principal
+		r.rollbackTo(txCount)
+		r.Accounts.Delete(acc.Iban)
+		return nil, err
+	}
+
+	loan := &Loan{acc.Iban, linked.Iban, principal, annualRate, months, LoanActive, amortizationSchedule(principal, annualRate, months, firstDue.UTC(), linked.Currency), tx.Timestamp}
+	r.Loans[acc.Iban] = loan
+	copied := loan.copy()
+	return &copied, nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveLoan(iban string) (Loan, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists and is a loan account
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return Loan{}, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	loan, exists := r.Loans[iban]
+	if !exists || loan == nil {
+		return Loan{}, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	return loan.copy(), nil
+}
+
+// Collects installments due at the given time from the linked accounts and returns the number of collected ones
+// Installments of a loan are collected in order, an installment the linked account cannot pay becomes overdue
+// and blocks the following ones until it is paid on a later run
+// Collection is a debit initiated by the bank, so limits and fees of the linked account are not applied
+func (r *InMemoryAccountRepository) CollectDueInstallments(now time.Time) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	ibans := make([]string, 0, len(r.Loans))
+	for iban, loan := range r.Loans {
+		if loan.Status == LoanActive {
+			ibans = append(ibans, iban)
+		}
+	}
+	sort.Strings(ibans)
+
+	collected := 0
+	for _, iban := range ibans {
+		loan := r.Loans[iban]
+		acc, _ := r.Accounts.Get(iban)
+		linked, exists := r.Accounts.Get(loan.LinkedIban)
+		if !exists || linked == nil || acc == nil {
+			continue
+		}
+		for i := range loan.Schedule {
+			installment := &loan.Schedule[i]
+			if installment.Status == InstallmentPaid {
+				continue
+			}
+			if installment.DueDate.After(now) {
+				break
+			}
+			// Checking if the linked account can pay the installment and the loan account can be credited with the principal
+			if linked.Status != Active || linked.AvailableBalance() < installment.Payment || checkBalanceFloor(linked, installment.Payment) != nil ||
+				checkDebitRestriction(linked) != nil || checkCreditRestriction(acc) != nil {
+				installment.Status = InstallmentOverdue
+				break
+			}
+			tx := &Transaction{Type: LoanRepaymentTransaction, Sender: linked.Iban, Recipient: r.EmissionAccount.Iban, Amount: installment.Payment, Currency: linked.Currency, Reference: fmt.Sprintf("%s/%d", iban, installment.Number)}
+			if err := r.post(tx, debitLine(linked, installment.Payment), creditLine(r.EmissionAccount, installment.Payment), debitLedgerLine(LoansLedgerAccount, acc.Currency, installment.Principal), creditLine(acc, installment.Principal)); err != nil {
+				return collected, err
+			}
+			installment.Status = InstallmentPaid
+			installment.TransactionID = tx.Ulid
+			collected++
+		}
+		// Closing the loan account once the whole principal is repaid
+		if _, due := loan.NextDueDate(); !due {
+			loan.Status = LoanRepaid
+			acc.Status = Closed
+			r.Accounts.Put(acc)
+		}
+	}
+	return collected, nil
+}
+
+// Collects due loan installments along with the scheduled transfers, returns the number of collected installments
+func (s *TransferScheduler) RunDueInstallments(now time.Time) int {
+	collected, _ := s.service.CollectDueInstallments(now)
+	return collected
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAmortizationSchedule(t *testing.T) {
+	schedule := amortizationSchedule(1000, 12, 12, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), "BYN")
+	if len(schedule) != 12 || schedule[0].Payment != 88.85 || schedule[0].Interest != 10 || schedule[11].RemainingPrincipal != 0 {
+		t.Fatalf("Unexpected schedule: %+v", schedule)
+	}
+	principal := 0.0
+	for _, installment := range schedule {
+		principal += installment.Principal
+	}
+	if math.Abs(principal-1000) > 1e-9 || !schedule[11].DueDate.Equal(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the whole principal to be repaid by the last installment, got %.2f on %v", principal, schedule[11].DueDate)
+	}
+}
+
+// Principal is disbursed to the linked account and installments are collected from it when due
+func TestLoans(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	scheduler := NewTransferScheduler(service)
+	current, _ := service.OpenAccount()
+	if _, err := service.OpenLoan(current.Iban, 300, 0, 0, time.Now()); err == nil {
+		t.Errorf("Opening loan without a term failed to fail")
+	}
+	for _, terms := range [][2]float64{{math.NaN(), 0}, {math.Inf(1), 0}, {300, math.NaN()}, {300, math.Inf(1)}} {
+		if _, err := service.OpenLoan(current.Iban, terms[0], terms[1], 3, time.Now()); err == nil {
+			t.Errorf("Opening loan of %v at %v%% failed to fail", terms[0], terms[1])
+		}
+	}
+	firstDue := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)
+	loan, err := service.OpenLoan(current.Iban, 300, 0, 3, firstDue)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(loan.Iban); details.Balance != -300 || details.Type != accountTypeCodeToNameMap[LoanAccount][locale()] {
+		t.Errorf("Unexpected loan account: %+v", details)
+	}
+	if details, _ := service.RetrieveAccount(current.Iban); details.Balance != 300 {
+		t.Errorf("Expected principal to be credited to the linked account, got %.2f", details.Balance)
+	}
+	if _, err := service.TransferMoney(current.Iban, loan.Iban, 10); err == nil {
+		t.Errorf("Transfer to loan account failed to fail")
+	}
+
+	if collected := scheduler.RunDueInstallments(firstDue.AddDate(0, 1, 0)); collected != 2 {
+		t.Errorf("Expected two installments to be collected, got %d", collected)
+	}
+	loaned, _ := service.RetrieveLoan(loan.Iban)
+	if next, due := loaned.NextDueDate(); loaned.RemainingPrincipal() != 100 || !due || !next.Equal(firstDue.AddDate(0, 2, 0)) {
+		t.Errorf("Unexpected remaining principal %.2f and next due date %v", loaned.RemainingPrincipal(), next)
+	}
+	// An installment the linked account cannot pay becomes overdue
+	service.DestructMoney(current.Iban, 50)
+	if collected := scheduler.RunDueInstallments(firstDue.AddDate(0, 2, 0)); collected != 0 {
+		t.Errorf("Expected no installments to be collected, got %d", collected)
+	}
+	if loaned, _ := service.RetrieveLoan(loan.Iban); loaned.Schedule[2].Status != InstallmentOverdue {
+		t.Errorf("Expected the last installment to be overdue, got %s", installmentStatusCodeToNameMap[loaned.Schedule[2].Status][locale()])
+	}
+	other, _ := service.OpenAccountWithInitialDeposit(50)
+	if _, err := service.TransferMoney(other.Iban, current.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if collected := scheduler.RunDueInstallments(firstDue.AddDate(0, 2, 1)); collected != 1 {
+		t.Errorf("Expected overdue installment to be collected, got %d", collected)
+	}
+	if loaned, _ := service.RetrieveLoan(loan.Iban); loaned.Status != LoanRepaid {
+		t.Errorf("Expected the loan to be repaid, got %s", loanStatusCodeToNameMap[loaned.Status][locale()])
+	}
+	if details, _ := service.RetrieveAccount(loan.Iban); details.Balance != 0 || details.Status != accountStatusCodeToNameMap[Closed][locale()] {
+		t.Errorf("Expected loan account to be closed, got %+v", details)
+	}
+	if res, err := service.ReconcileFractions(); err != nil || !res.Balanced {
+		t.Errorf("Unexpected reconciliation: %+v (%v)", res, err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// --------------------------------------------------------
+// Defining striped locks of accounts, so that transfers between unrelated accounts do not wait for each other
+// Plain transfers hold the repository mutex shared and lock the stripes of both of their accounts for the checks,
+// the log and the journal are only appended to under the journal mutex, which is held for a short while, split transfers lock
+// all of their accounts and hold the journal mutex for the whole split
+// Reads hold the repository mutex shared as well, reads of balances, the log or the journal hold the journal mutex shared
+// on top, so that they never see a transfer half-recorded, openings of accounts hold it shared as well, see shards.go
+// Everything else holds the repository mutex exclusively, so it never runs along with a transfer in flight or a read
+
+// Number of stripes accounts are hashed to, accounts sharing a stripe wait for each other as if they were the same account
+const accountLockStripes = 1024
+
+type AccountLocks struct {
+	stripes [accountLockStripes]sync.Mutex
+}
+
+// Locking protocol of accounts, to be followed by every repository locking several accounts at once
+// Every account is guarded by a lock identified by a key: the IBAN itself if every account has a lock of its own, the number
+// of the stripe if accounts are hashed to stripes. Locks are acquired in ascending order of their keys, each lock once however
+// many of the accounts it guards, and released in reverse order, so that operations locking overlapping sets of accounts
+// never wait for each other in a cycle
+// Keys of locks shared by several accounts must not be IBANs, since accounts sharing a lock could be ordered differently
+type AccountLocker interface {
+	// Returns the key of the lock guarding the account and the lock itself, IBAN is normalized
+	LockerFor(iban string) (string, sync.Locker)
+}
+
+// Locks the accounts according to the locking protocol, calling the returned function unlocks them
+func LockAccounts(locker AccountLocker, ibans ...string) func() {
+	keys := []string{}
+	lockers := map[string]sync.Locker{}
+	for _, iban := range ibans {
+		if key, l := locker.LockerFor(NormalizeIban(iban)); lockers[key] == nil {
+			lockers[key] = l
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lockers[key].Lock()
+	}
+	return func() {
+		for i := len(keys) - 1; i >= 0; i-- {
+			lockers[keys[i]].Unlock()
+		}
+	}
+}
+
+// Keys of the stripes are their numbers padded with zeros, so that they sort as numbers do
+func (l *AccountLocks) LockerFor(iban string) (string, sync.Locker) {
+	h := fnv.New32a()
+	h.Write([]byte(iban))
+	stripe := h.Sum32() % accountLockStripes
+	return fmt.Sprintf("%04d", stripe), &l.stripes[stripe]
+}
+
+// Locks the stripes of the given accounts according to the locking protocol, calling the returned function unlocks them
+func (l *AccountLocks) Lock(ibans ...string) func() {
+	return LockAccounts(l, ibans...)
+}
+
+// Helper function to check if the transfer can run along with others, expects the repository mutex to be held by the caller
+// Transfers waiting for approval or confirmation have to hold the repository mutex exclusively
+func (r *InMemoryAccountRepository) isPlainTransfer(sender, recipient string, amount float64) bool {
+	if (r.ApprovalThreshold > 0 && amount > r.ApprovalThreshold) || r.transferNeedsConfirmation(sender, amount) {
+		return false
+	}
+	return r.canLockAccounts(sender, recipient)
+}
+
+// Helper function to check if movements between the accounts can run under their locks, expects the repository mutex to be held
+// by the caller
+// Velocity rules observe every transfer and the fee income account is credited by transfers between other accounts, so
+// movements going through velocity rules or moving money of the fee income account have to hold the repository mutex exclusively,
+// unless the fee income account is hot, see hotaccounts.go
+func (r *InMemoryAccountRepository) canLockAccounts(ibans ...string) bool {
+	if r.VelocityEngine != nil {
+		return false
+	}
+	for _, iban := range ibans {
+		if r.FeeAccount != nil && r.FeeAccount.hot == nil && r.FeeAccount.Iban == NormalizeIban(iban) {
+			return false
+		}
+	}
+	return true
+}
+
+// Helper function to execute the plain transfer under the locks of its accounts, expects the repository mutex to be held
+// shared by the caller
+// Fees and acquiring fees are credited to the fee income account under the journal mutex, it is never checked by the transfer
+// Hot accounts are not locked, their balances are checked again under the journal mutex
+func (r *InMemoryAccountRepository) transferConcurrently(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	unlock := r.AccountLocks.Lock(r.lockedAccounts(sender, recipient)...)
+	defer unlock()
+
+	transfer, err := r.prepareTransfer(sender, recipient, amount, details)
+	r.JournalMutex.Lock()
+	defer r.JournalMutex.Unlock()
+	if err == nil {
+		err = r.recheckHotAccounts(transfer)
+	}
+	if err == nil {
+		err = r.commitTransfer(transfer)
+	}
+	return r.trackTransfer(err), err
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Screening provider taking a while to answer, like the ones called over the network
+type slowScreeningProvider struct {
+	delay time.Duration
+}
+
+func (p slowScreeningProvider) Screen(sender, recipient ScreeningParty) (ScreeningOutcome, string) {
+	time.Sleep(p.delay)
+	return ScreeningClear, ""
+}
+
+// Helper function to open the accounts with the same initial deposit each
+func openLockTestAccounts(t testing.TB, service *AccountService, n int, deposit float64) []string {
+	ibans := []string{}
+	for i := 0; i < n; i++ {
+		acc, err := service.OpenAccountWithInitialDeposit(deposit)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		ibans = append(ibans, acc.Iban)
+	}
+	return ibans
+}
+
+// Transfer money back and forth between accounts in parallel, balances and the log have to add up
+func TestConcurrentTransfers(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	ibans := openLockTestAccounts(t, service, 8, 100)
+	transactions := len(inMemImpl.Transactions)
+
+	wg := sync.WaitGroup{}
+	const n int = 200
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every pair of accounts is used in both directions, so that locks are taken the other way round as well
+			sender, recipient := ibans[i%len(ibans)], ibans[(i/len(ibans)+i+1)%len(ibans)]
+			if sender == recipient {
+				recipient = ibans[(i+1)%len(ibans)]
+			}
+			if _, err := service.TransferMoneyWithDetails(sender, recipient, 1, TransferDetails{}); err != nil {
+				t.Errorf("Error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0.0
+	for _, iban := range ibans {
+		details, _ := service.RetrieveAccount(iban)
+		total += details.Balance
+	}
+	if total != 800 {
+		t.Errorf("Expected the total balance to stay 800, got %v", total)
+	}
+	if len(inMemImpl.Transactions) != transactions+n {
+		t.Errorf("Expected %d transactions to be recorded, got %d", n, len(inMemImpl.Transactions)-transactions)
+	}
+	if _, err := service.VerifyInvariants(); err != nil {
+		t.Errorf("Expected invariants to hold, got %v", err)
+	}
+}
+
+// Stripes are locked once however often the accounts are given and unlocked by the returned function
+func TestAccountLocks(t *testing.T) {
+	locks := AccountLocks{}
+	unlock := locks.Lock("BY84 ALFA 1000 0000 0000 0000 0002", "by84alfa10000000000000000002", "BY84 ALFA 1000 0000 0000 0000 0003")
+	done := make(chan bool)
+	go func() {
+		locks.Lock("BY84 ALFA 1000 0000 0000 0000 0003")()
+		done <- true
+	}()
+	select {
+	case <-done:
+		t.Errorf("Locking the locked account failed to wait")
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-done
+}
+
+// Compare transfers between disjoint pairs of accounts run under the account locks with ones run under the repository mutex
+func BenchmarkConcurrentTransfers(b *testing.B) {
+	for _, exclusive := range []bool{false, true} {
+		name := "striped"
+		if exclusive {
+			name = "exclusive"
+		}
+		b.Run(name, func(b *testing.B) {
+			inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+			inMemImpl.ScreeningProvider = slowScreeningProvider{20 * time.Microsecond}
+			service := NewAccountService(inMemImpl)
+			ibans := openLockTestAccounts(b, service, 64, float64(b.N))
+			next := int32(0)
+
+			// Screening keeps goroutines waiting rather than busy, so there are more of them than processors
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// Each goroutine takes a pair of its own unless there are more goroutines than pairs
+				i := int(atomic.AddInt32(&next, 1)-1) % (len(ibans) / 2) * 2
+				for pb.Next() {
+					var err error
+					if exclusive {
+						inMemImpl.Mutex.Lock()
+						_, err = inMemImpl.submitTransfer(ibans[i], ibans[i+1], 1, TransferDetails{})
+						inMemImpl.Mutex.Unlock()
+					} else {
+						_, err = inMemImpl.TransferMoneyWithDetails(ibans[i], ibans[i+1], 1, TransferDetails{})
+					}
+					if err != nil {
+						b.Errorf("Error: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// Reads run along with each other and with transfers, they never see a transfer half-recorded
+func TestConcurrentReads(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	ibans := openLockTestAccounts(t, service, 4, 100)
+
+	// A read holding the repository mutex does not keep others waiting
+	inMemImpl.Mutex.RLock()
+	done := make(chan error)
+	go func() {
+		_, err := service.RetrieveAccount(ibans[0])
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Reading along with another read failed to proceed")
+	}
+	inMemImpl.Mutex.RUnlock()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			service.TransferMoneyWithDetails(ibans[i%2], ibans[2+i%2], 1, TransferDetails{})
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := service.VerifyInvariants(); err != nil {
+				t.Errorf("Expected invariants to hold during transfers, got %v", err)
+			}
+			if _, err := service.RetrieveAllAccountsAsJson(); err != nil {
+				t.Errorf("Error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if details, _ := service.RetrieveAccount(ibans[2]); details.Balance != 125 {
+		t.Errorf("Expected balance 125 after the transfers, got %v", details.Balance)
+	}
+}
+
+// Account locker with a lock of its own for every account which records the order of locking
+type recordingAccountLocker struct {
+	locks  map[string]*sync.Mutex
+	events []string
+	mutex  sync.Mutex
+}
+
+type recordingLock struct {
+	locker *recordingAccountLocker
+	key    string
+}
+
+func (l recordingLock) Lock() {
+	l.locker.locks[l.key].Lock()
+	l.locker.mutex.Lock()
+	l.locker.events = append(l.locker.events, "lock "+l.key)
+	l.locker.mutex.Unlock()
+}
+
+func (l recordingLock) Unlock() {
+	l.locker.mutex.Lock()
+	l.locker.events = append(l.locker.events, "unlock "+l.key)
+	l.locker.mutex.Unlock()
+	l.locker.locks[l.key].Unlock()
+}
+
+func (l *recordingAccountLocker) LockerFor(iban string) (string, sync.Locker) {
+	return iban, recordingLock{l, iban}
+}
+
+// Accounts are locked in ascending order of the keys of their locks, each lock once, and unlocked in reverse order
+func TestLockingProtocol(t *testing.T) {
+	ibans := []string{"BY84 ALFA 1000 0000 0000 0000 0003", "BY84 ALFA 1000 0000 0000 0000 0002", "BY84 ALFA 1000 0000 0000 0000 0004"}
+	locker := &recordingAccountLocker{locks: map[string]*sync.Mutex{}}
+	for _, iban := range ibans {
+		locker.locks[NormalizeIban(iban)] = &sync.Mutex{}
+	}
+	LockAccounts(locker, ibans[0], ibans[1], ibans[2], ibans[0])()
+	expected := []string{"lock BY84ALFA10000000000000000002", "lock BY84ALFA10000000000000000003", "lock BY84ALFA10000000000000000004",
+		"unlock BY84ALFA10000000000000000004", "unlock BY84ALFA10000000000000000003", "unlock BY84ALFA10000000000000000002"}
+	if len(locker.events) != len(expected) {
+		t.Fatalf("Unexpected locking %v", locker.events)
+	}
+	for i := range expected {
+		if locker.events[i] != expected[i] {
+			t.Errorf("Unexpected locking %v", locker.events)
+			break
+		}
+	}
+
+	// Operations locking overlapping accounts in all orders never deadlock
+	done := make(chan bool)
+	go func() {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 300; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				LockAccounts(locker, ibans[i%3], ibans[(i/3)%3])()
+			}(i)
+		}
+		wg.Wait()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Locking overlapping accounts deadlocked")
+	}
+}
+
+// Split transfers from accounts paying each other run along with each other and with plain transfers
+func TestConcurrentSplits(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	ibans := openLockTestAccounts(t, service, 3, 1000)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 60; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			sender := ibans[i%3]
+			shares := []SplitShare{{Recipient: ibans[(i+1)%3], Percentage: 50}, {Recipient: ibans[(i+2)%3], Percentage: 50}}
+			if _, err := service.SplitTransfer(sender, 2, shares); err != nil {
+				t.Errorf("Error: %v", err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			service.TransferMoney(ibans[(i+2)%3], ibans[i%3], 1)
+		}(i)
+	}
+	wg.Wait()
+
+	// Every account sent and received as much as the others
+	for _, iban := range ibans {
+		if details, _ := service.RetrieveAccount(iban); details.Balance != 1000 {
+			t.Errorf("Expected balance 1000 after the transfers, got %v", details.Balance)
+		}
+	}
+	if _, err := service.VerifyInvariants(); err != nil {
+		t.Errorf("Expected invariants to hold, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining structured logging: operations of the repository are logged by the decorator with their duration, operations
+// of the service are logged along with the principal performing them, failures carry the code of the error
+//
+// Logger of the demo and of the program as a whole, replaced according to the configuration at startup
+var logger *slog.Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+// Output format is "text" (key=value pairs) or "json" (one object per line)
+func NewLogger(writer io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(writer, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(writer, options)), nil
+	}
+	return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+}
+
+// Returns the logger writing to the writer in the format and at the level of the configuration
+func (c Config) Logger(writer io.Writer) (*slog.Logger, error) {
+	return NewLogger(writer, c.LogFormat, c.LogLevel)
+}
+
+// Helper function to find the code of the error by its message in any of the locales
+func errorCodeOf(err error) (ErrorCode, bool) {
+	if err == nil {
+		return 0, false
+	}
+	for code, messages := range errorCodesToMessagesMap {
+		for _, message := range messages {
+			if err.Error() == message {
+				return code, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Helper function to turn the error into log attributes, errors which do not come from the error map have no code
+func errorAttrs(err error) []any {
+	if code, ok := errorCodeOf(err); ok {
+		return []any{"error_code", int(code), "error", err.Error()}
+	}
+	return []any{"error", err.Error()}
+}
+
+// Logs the audited operations of the service with the principal performing them, see audit
+func (s *AccountService) WithLogger(logger *slog.Logger) *AccountService {
+	bound := *s
+	bound.logger = logger
+	return &bound
+}
+
+// Helper function to log the audited operation, successful ones are logged at debug level since the repository logs them too
+func (s *AccountService) logAudited(entry AuditEntry, err error) {
+	if s.logger == nil {
+		return
+	}
+	attrs := []any{"operation", auditActionCodeToNameMap[entry.Action][English], "principal", entry.Principal, "iban", entry.Iban}
+	if entry.Counterparty != "" {
+		attrs = append(attrs, "counterparty", entry.Counterparty)
+	}
+	if entry.Amount != 0 {
+		attrs = append(attrs, "amount", entry.Amount)
+	}
+	if err != nil {
+		s.logger.Warn("operation failed", append(attrs, errorAttrs(err)...)...)
+		return
+	}
+	s.logger.Debug("operation audited", attrs...)
+}
+
+// Decorates the repository to log money movements and changes of account statuses, other methods are passed through as is
+type LoggingAccountRepository struct {
+	AccountRepository
+	logger *slog.Logger
+}
+
+func NewLoggingAccountRepository(r AccountRepository, logger *slog.Logger) *LoggingAccountRepository {
+	return &LoggingAccountRepository{r, logger}
+}
+
+// Helper function to log the operation started at the given time, failed operations are logged as warnings
+func (r *LoggingAccountRepository) log(operation string, start time.Time, err error, attrs ...any) {
+	attrs = append([]any{"operation", operation}, attrs...)
+	attrs = append(attrs, "duration", time.Since(start))
+	if err != nil {
+		r.logger.Log(context.Background(), slog.LevelWarn, "operation failed", append(attrs, errorAttrs(err)...)...)
+		return
+	}
+	r.logger.Info("operation succeeded", attrs...)
+}
+
+// Helper function to take the IBAN of the opened account for the log
+func openedIban(acc *Account) string {
+	if acc == nil {
+		return ""
+	}
+	return acc.Iban
+}
+
+func (r *LoggingAccountRepository) EmitMoney(amount float64) error {
+	start := time.Now()
+	err := r.AccountRepository.EmitMoney(amount)
+	r.log("EmitMoney", start, err, "amount", amount)
+	return err
+}
+
+func (r *LoggingAccountRepository) DestructMoney(iban string, amount float64) error {
+	start := time.Now()
+	err := r.AccountRepository.DestructMoney(iban, amount)
+	r.log("DestructMoney", start, err, "iban", iban, "amount", amount)
+	return err
+}
+
+func (r *LoggingAccountRepository) OpenAccount() (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccount()
+	r.log("OpenAccount", start, err, "iban", openedIban(acc))
+	return acc, err
+}
+
+func (r *LoggingAccountRepository) OpenAccountInCurrency(currency string) (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccountInCurrency(currency)
+	r.log("OpenAccountInCurrency", start, err, "iban", openedIban(acc), "currency", currency)
+	return acc, err
+}
+
+func (r *LoggingAccountRepository) OpenAccountWithOptions(opts AccountOptions) (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccountWithOptions(opts)
+	r.log("OpenAccountWithOptions", start, err, "iban", openedIban(acc))
+	return acc, err
+}
+
+func (r *LoggingAccountRepository) OpenAccountWithInitialDeposit(amount float64) (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccountWithInitialDeposit(amount)
+	r.log("OpenAccountWithInitialDeposit", start, err, "iban", openedIban(acc), "amount", amount)
+	return acc, err
+}
+
+func (r *LoggingAccountRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	start := time.Now()
+	id, err := r.AccountRepository.TransferMoney(sender, recipient, amount)
+	r.log("TransferMoney", start, err, "sender", sender, "recipient", recipient, "amount", amount)
+	return id, err
+}
+
+func (r *LoggingAccountRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	start := time.Now()
+	id, err := r.AccountRepository.TransferMoneyWithDetails(sender, recipient, amount, details)
+	r.log("TransferMoneyWithDetails", start, err, "sender", sender, "recipient", recipient, "amount", amount, "transfer_id", id)
+	return id, err
+}
+
+// Only the length of the request is logged, the request is decoded by the repository
+func (r *LoggingAccountRepository) TransferMoneyJson(jsonStr string) (string, error) {
+	start := time.Now()
+	id, err := r.AccountRepository.TransferMoneyJson(jsonStr)
+	r.log("TransferMoneyJson", start, err, "request_bytes", len(jsonStr))
+	return id, err
+}
+
+func (r *LoggingAccountRepository) ConvertAndTransfer(sender, recipient string, amount float64) error {
+	start := time.Now()
+	err := r.AccountRepository.ConvertAndTransfer(sender, recipient, amount)
+	r.log("ConvertAndTransfer", start, err, "sender", sender, "recipient", recipient, "amount", amount)
+	return err
+}
+
+func (r *LoggingAccountRepository) ReverseTransfer(id, reason string) (string, error) {
+	start := time.Now()
+	reversal, err := r.AccountRepository.ReverseTransfer(id, reason)
+	r.log("ReverseTransfer", start, err, "transfer_id", id, "reversal_id", reversal)
+	return reversal, err
+}
+
+func (r *LoggingAccountRepository) BlockAccount(iban string) error {
+	start := time.Now()
+	err := r.AccountRepository.BlockAccount(iban)
+	r.log("BlockAccount", start, err, "iban", iban)
+	return err
+}
+
+func (r *LoggingAccountRepository) ActivateAccount(iban string) error {
+	start := time.Now()
+	err := r.AccountRepository.ActivateAccount(iban)
+	r.log("ActivateAccount", start, err, "iban", iban)
+	return err
+}
+
+func (r *LoggingAccountRepository) CloseAccount(iban, sweepTargetIban string) error {
+	start := time.Now()
+	err := r.AccountRepository.CloseAccount(iban, sweepTargetIban)
+	r.log("CloseAccount", start, err, "iban", iban, "sweep_target", sweepTargetIban)
+	return err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// Log operations of the repository and the service as JSON and make sure failures carry the code of the error
+func TestStructuredLogging(t *testing.T) {
+	var buffer bytes.Buffer
+	logger, err := NewLogger(&buffer, "json", slog.LevelDebug)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(NewLoggingAccountRepository(inMemImpl, logger)).WithLogger(logger)
+
+	acc, _ := service.OpenAccount()
+	service.EmitMoney(100)
+	service.TransferMoney(emission, acc.Iban, 100)
+	service.TransferMoney(acc.Iban, emission, -5)
+	service.As(Principal{ID: "alice", Role: CustomerRole}).BlockAccount(acc.Iban)
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected JSON lines, got %q", line)
+		}
+		entries = append(entries, entry)
+	}
+	find := func(level, operation string) map[string]interface{} {
+		for _, entry := range entries {
+			if entry["level"] == level && entry["operation"] == operation {
+				return entry
+			}
+		}
+		return nil
+	}
+
+	if entry := find("INFO", "TransferMoney"); entry == nil || entry["recipient"] != acc.Iban || entry["amount"] != 100.0 || entry["duration"] == nil {
+		t.Errorf("Expected successful transfer to be logged by the repository, got %v", entry)
+	}
+	if entry := find("INFO", "OpenAccount"); entry == nil || entry["iban"] != acc.Iban {
+		t.Errorf("Expected opening to be logged with the IBAN, got %v", entry)
+	}
+	if entry := find("WARN", "TransferMoney"); entry == nil || entry["error_code"] != float64(NegativeAmountError) {
+		t.Errorf("Expected failed transfer to be logged with the error code, got %v", entry)
+	}
+	if entry := find("DEBUG", "Emit"); entry == nil {
+		t.Errorf("Expected successful emission to be logged by the service at debug level")
+	}
+	// Denials never reach the repository, so they are only logged by the service
+	if entry := find("WARN", "Block"); entry == nil || entry["principal"] != "alice" || entry["error_code"] != float64(AccessDeniedError) {
+		t.Errorf("Expected denied block to be logged with the principal, got %v", entry)
+	}
+	if entry := find("WARN", "BlockAccount"); entry != nil {
+		t.Errorf("Expected denied block not to reach the repository, got %v", entry)
+	}
+}
+
+// Choose the format and the level of the log by the configuration
+func TestLoggingConfig(t *testing.T) {
+	config, err := LoadConfig([]string{"-log-format", "text", "-log-level", "warn"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var buffer bytes.Buffer
+	logger, err := config.Logger(&buffer)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	logger.Info("skipped")
+	logger.Warn("logged", errorAttrs(fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()]))...)
+	if out := buffer.String(); strings.Contains(out, "skipped") || !strings.Contains(out, "level=WARN msg=logged error_code=0") {
+		t.Errorf("Unexpected text log %q", out)
+	}
+	if attrs := errorAttrs(errors.New("unknown")); len(attrs) != 2 {
+		t.Errorf("Expected errors outside of the error map to have no code, got %v", attrs)
+	}
+	for _, args := range [][]string{{"-log-format", "xml"}, {"-log-level", "verbose"}} {
+		if _, err := LoadConfig(args); err == nil {
+			t.Errorf("Loading config with %v failed to fail", args)
+		}
+	}
+	if _, err := NewLogger(&buffer, "xml", slog.LevelInfo); err == nil {
+		t.Errorf("Logger of unknown format failed to fail")
+	}
+}
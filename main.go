@@ -25,19 +25,27 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"math"
-	"math/rand"
+	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 // --------------------------------------------------------
 // Defining necessary enums such as account status and type as well as error message codes
-type ErrorCode int8
+type ErrorCode int16
 
 const (
 	AccountDoesNotExistError = iota
@@ -50,11 +58,150 @@ const (
 	AccountCreationError
 	AccountDetailsJsonError
 	MoneyTransferJsonError
+	FractionsReconciliationError
+	CurrencyMismatchError
+	ExchangeRateUnavailableError
+	TransactionsJsonError
+	UnsupportedCurrencyError
+	InvalidAccountQueryError
+	InvalidMetadataError
+	CustomerDoesNotExistError
+	InvalidCustomerDetailsError
+	AccountCustomerMismatchError
+	KycLimitExceededError
+	KycRejectedError
+	KycTransitionError
+	AccountIsClosedError
+	ProductRuleViolationError
+	ClientReferenceConflictError
+	AccountAlreadyExistsError
+	AccountsImportError
+	AccountsExportError
+	InvalidTransferDetailsError
+	TransferDoesNotExistError
+	InvalidIdempotencyKeyError
+	IdempotencyKeyConflictError
+	InvalidSplitError
+	ScheduledTransferDoesNotExistError
+	ScheduledTransferNotPendingError
+	HoldDoesNotExistError
+	HoldIsNotActiveError
+	AccountHasActiveHoldsError
+	TransferNotReversibleError
+	LimitExceededError
+	InvalidVelocityRuleError
+	VelocityRuleViolationError
+	AlertDoesNotExistError
+	AlertAlreadyResolvedError
+	PrincipalRequiredError
+	InvalidScreeningListError
+	SanctionsMatchError
+	FraudReviewDoesNotExistError
+	FraudReviewIsNotPendingError
+	FraudScoringError
+	InvalidFeeRuleError
+	FeeAccountUnavailableError
+	FeeExceedsAmountError
+	OverdraftLimitExceededError
+	InvalidAccountSettingsError
+	BelowMinimumBalanceError
+	AboveMaximumBalanceError
+	InvalidLoanTermsError
+	InvalidDepositTermsError
+	TermDepositLockedError
+	InvalidInterestRateError
+	ApprovalDoesNotExistError
+	ApprovalIsNotPendingError
+	ApprovalPrincipalError
+	LedgerImbalanceError
+	IntegrityViolationError
+	InvalidChartOfAccountsError
+	TransactionsExportError
+	InvalidPaymentQRError
+	InvalidPaymentRequestError
+	PaymentRequestDoesNotExistError
+	PaymentRequestIsNotPendingError
+	InvalidBeneficiaryError
+	BeneficiaryDoesNotExistError
+	InvalidAliasError
+	AliasAlreadyRegisteredError
+	AliasDoesNotExistError
+	InterbankUnavailableError
+	UnknownPeerBankError
+	InvalidInterbankMessageError
+	InterbankTransferDoesNotExistError
+	InterbankTransferIsNotPendingError
+	InvalidClearingObligationError
+	CorrespondentAccountExistsError
+	CorrespondentAccountDoesNotExistError
+	InterbankDeliveryError
+	EmissionLimitExceededError
+	EmissionApprovalRequiredError
+	EmissionApprovalDoesNotExistError
+	EmissionPrincipalError
+	InvalidSupplyReasonError
+	InvalidBlockError
+	BlockLiftNotAllowedError
+	DebitsFrozenError
+	CreditsFrozenError
+	InvalidFreezeError
+	FreezeDoesNotExistError
+	FreezeCaseMismatchError
+	InvalidAdjustmentError
+	AccessDeniedError
+	InvalidApiKeyError
+	ApiKeyRateLimitExceededError
+	InvalidTokenError
+	InvalidConsentError
+	ConsentLimitExceededError
+	ChallengeDoesNotExistError
+	InvalidOtpCodeError
+	OtpDeliveryError
+	SessionExpiredError
+	InvalidIpPolicyError
+	InvalidSignatureError
+	InvalidWebhookError
+	InvalidTlsConfigError
+	EncryptionError
+	CustomerHasOpenAccountsError
+	InvalidConfigError
+	SecretNotFoundError
+	SecretUnavailableError
+	ConfigRestartRequiredError
+	ShutdownTimeoutError
+	SnapshotWriteError
+	BackendUnavailableError
+	UnsupportedIbanCountryError
+	InvalidBankCodeError
+	UnknownBankCodeError
+	CardDoesNotExistError
+	CardIsBlockedError
+	CardIsExpiredError
+	CardNotAllowedError
+	CardIssuanceError
+	AuthorizationDoesNotExistError
+	AuthorizationIsNotPendingError
+	AuthorizationHoldError
+	InvalidPurchaseError
+	CashVaultNotDeclaredError
+	CashVaultShortageError
+	CashLimitExceededError
+	UntrustedPeerBankError
+	InterbankAuthenticationRequiredError
 )
 
 type LanguageCode int8
 
-var locale LanguageCode
+// Language of messages, stored atomically since reloads of the configuration change it while requests are served
+var currentLocale atomic.Int32
+
+func locale() LanguageCode {
+	return LanguageCode(currentLocale.Load())
+}
+
+func setLocale(l LanguageCode) {
+	currentLocale.Store(int32(l))
+}
 
 const (
 	English = iota
@@ -103,6 +250,526 @@ var errorCodesToMessagesMap map[ErrorCode](map[LanguageCode]string) = map[ErrorC
 		English: fmt.Sprintf("Error code: %d. Message: %s", MoneyTransferJsonError, "Cannot parse JSON"),
 		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", MoneyTransferJsonError, "Невозможно обработать JSON"),
 	},
+	FractionsReconciliationError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FractionsReconciliationError, "Total balances do not match emitted money"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FractionsReconciliationError, "Сумма балансов не совпадает с эмитированными средствами"),
+	},
+	CurrencyMismatchError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CurrencyMismatchError, "Account currencies do not match"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CurrencyMismatchError, "Валюты аккаунтов не совпадают"),
+	},
+	ExchangeRateUnavailableError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ExchangeRateUnavailableError, "Exchange rate is not available"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ExchangeRateUnavailableError, "Курс обмена недоступен"),
+	},
+	TransactionsJsonError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", TransactionsJsonError, "Cannot represent transactions as JSON"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", TransactionsJsonError, "Невозможно преобразить транзакции в JSON"),
+	},
+	UnsupportedCurrencyError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", UnsupportedCurrencyError, "Currency is not supported"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", UnsupportedCurrencyError, "Валюта не поддерживается"),
+	},
+	InvalidAccountQueryError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidAccountQueryError, "Account search criteria are contradictory"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidAccountQueryError, "Критерии поиска аккаунтов противоречивы"),
+	},
+	InvalidMetadataError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidMetadataError, "Account metadata or tags are not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidMetadataError, "Метаданные или теги аккаунта некорректны"),
+	},
+	CustomerDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CustomerDoesNotExistError, "Requested customer does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CustomerDoesNotExistError, "Запрашиваемый клиент не существует"),
+	},
+	InvalidCustomerDetailsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidCustomerDetailsError, "Customer details are not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidCustomerDetailsError, "Данные клиента некорректны"),
+	},
+	AccountCustomerMismatchError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AccountCustomerMismatchError, "Account belongs to another customer"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AccountCustomerMismatchError, "Аккаунт принадлежит другому клиенту"),
+	},
+	KycLimitExceededError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", KycLimitExceededError, "Amount exceeds the limit for accounts pending KYC verification"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", KycLimitExceededError, "Сумма превышает лимит для аккаунтов, ожидающих KYC проверки"),
+	},
+	KycRejectedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", KycRejectedError, "Account failed KYC verification"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", KycRejectedError, "Аккаунт не прошел KYC проверку"),
+	},
+	KycTransitionError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", KycTransitionError, "KYC status cannot be changed in the current state"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", KycTransitionError, "KYC статус не может быть изменен в текущем состоянии"),
+	},
+	AccountIsClosedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AccountIsClosedError, "Account is closed"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AccountIsClosedError, "Аккаунт закрыт"),
+	},
+	ProductRuleViolationError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ProductRuleViolationError, "Operation is not allowed for the account product"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ProductRuleViolationError, "Операция не разрешена для продукта аккаунта"),
+	},
+	ClientReferenceConflictError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ClientReferenceConflictError, "Client reference was already used to open an account with other parameters"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ClientReferenceConflictError, "Клиентская ссылка уже использована для открытия аккаунта с другими параметрами"),
+	},
+	AccountAlreadyExistsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AccountAlreadyExistsError, "Account with the given IBAN already exists"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AccountAlreadyExistsError, "Аккаунт с указанным IBAN уже существует"),
+	},
+	AccountsImportError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AccountsImportError, "Accounts import data is malformed"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AccountsImportError, "Данные для импорта аккаунтов некорректны"),
+	},
+	AccountsExportError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AccountsExportError, "Impossible to export accounts"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AccountsExportError, "Невозможно экспортировать аккаунты"),
+	},
+	InvalidTransferDetailsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidTransferDetailsError, "Transfer reference, memo or purpose code is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidTransferDetailsError, "Ссылка, комментарий или код назначения перевода не являются валидными"),
+	},
+	TransferDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", TransferDoesNotExistError, "Transfer with the given ID does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", TransferDoesNotExistError, "Перевод с указанным идентификатором не существует"),
+	},
+	InvalidIdempotencyKeyError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidIdempotencyKeyError, "Idempotency key is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidIdempotencyKeyError, "Ключ идемпотентности не является валидным"),
+	},
+	IdempotencyKeyConflictError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", IdempotencyKeyConflictError, "Idempotency key was already used for another operation"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", IdempotencyKeyConflictError, "Ключ идемпотентности уже использован для другой операции"),
+	},
+	InvalidSplitError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidSplitError, "Split shares are malformed or do not add up to the amount"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidSplitError, "Доли разделения некорректны или не составляют сумму"),
+	},
+	ScheduledTransferDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ScheduledTransferDoesNotExistError, "Scheduled transfer with the given ID does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ScheduledTransferDoesNotExistError, "Запланированный перевод с указанным идентификатором не существует"),
+	},
+	ScheduledTransferNotPendingError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ScheduledTransferNotPendingError, "Scheduled transfer has already been executed or cancelled"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ScheduledTransferNotPendingError, "Запланированный перевод уже исполнен или отменён"),
+	},
+	HoldDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", HoldDoesNotExistError, "Hold with the given ID does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", HoldDoesNotExistError, "Блокировка средств с указанным идентификатором не существует"),
+	},
+	HoldIsNotActiveError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", HoldIsNotActiveError, "Hold has already been captured or released"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", HoldIsNotActiveError, "Блокировка средств уже списана или снята"),
+	},
+	AccountHasActiveHoldsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AccountHasActiveHoldsError, "Account has money reserved by active holds"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AccountHasActiveHoldsError, "На аккаунте есть средства, зарезервированные активными блокировками"),
+	},
+	TransferNotReversibleError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", TransferNotReversibleError, "Transaction cannot be reversed or has already been reversed"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", TransferNotReversibleError, "Транзакция не может быть сторнирована или уже сторнирована"),
+	},
+	LimitExceededError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", LimitExceededError, "Operation exceeds the debit limits of the account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", LimitExceededError, "Операция превышает лимиты списания аккаунта"),
+	},
+	InvalidVelocityRuleError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidVelocityRuleError, "Velocity rules configuration is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidVelocityRuleError, "Конфигурация правил частоты операций не является валидной"),
+	},
+	VelocityRuleViolationError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", VelocityRuleViolationError, "Transfer is blocked by velocity rules"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", VelocityRuleViolationError, "Перевод заблокирован правилами частоты операций"),
+	},
+	AlertDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AlertDoesNotExistError, "Alert with the given ID does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AlertDoesNotExistError, "Оповещение с указанным идентификатором не существует"),
+	},
+	AlertAlreadyResolvedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AlertAlreadyResolvedError, "Alert has already been resolved"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AlertAlreadyResolvedError, "Оповещение уже обработано"),
+	},
+	PrincipalRequiredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", PrincipalRequiredError, "Principal performing the operation is not specified"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", PrincipalRequiredError, "Не указан субъект, выполняющий операцию"),
+	},
+	InvalidScreeningListError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidScreeningListError, "Screening list configuration is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidScreeningListError, "Конфигурация списка проверки не является валидной"),
+	},
+	SanctionsMatchError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", SanctionsMatchError, "Transfer counterparty matches sanctions lists"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", SanctionsMatchError, "Участник перевода найден в санкционных списках"),
+	},
+	FraudReviewDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FraudReviewDoesNotExistError, "Transfer under fraud review does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FraudReviewDoesNotExistError, "Перевод на проверке на мошенничество не существует"),
+	},
+	FraudReviewIsNotPendingError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FraudReviewIsNotPendingError, "Fraud review of the transfer has already been completed"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FraudReviewIsNotPendingError, "Проверка перевода на мошенничество уже завершена"),
+	},
+	FraudScoringError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FraudScoringError, "Transfer could not be scored for fraud"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FraudScoringError, "Не удалось оценить риск мошенничества для перевода"),
+	},
+	InvalidFeeRuleError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidFeeRuleError, "Fee rule is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidFeeRuleError, "Правило комиссии не является валидным"),
+	},
+	FeeAccountUnavailableError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FeeAccountUnavailableError, "Fee income account is not available"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FeeAccountUnavailableError, "Счёт для зачисления комиссий недоступен"),
+	},
+	FeeExceedsAmountError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FeeExceedsAmountError, "Fee borne by the recipient exceeds the amount"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FeeExceedsAmountError, "Комиссия получателя превышает сумму перевода"),
+	},
+	OverdraftLimitExceededError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", OverdraftLimitExceededError, "Debit exceeds the overdraft limit of the account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", OverdraftLimitExceededError, "Списание превышает лимит овердрафта счёта"),
+	},
+	InvalidAccountSettingsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidAccountSettingsError, "Account settings are not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidAccountSettingsError, "Настройки счёта не являются валидными"),
+	},
+	BelowMinimumBalanceError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", BelowMinimumBalanceError, "Debit would bring the balance below the minimum balance of the account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", BelowMinimumBalanceError, "Списание приведёт к остатку ниже минимального остатка счёта"),
+	},
+	AboveMaximumBalanceError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AboveMaximumBalanceError, "Credit would bring the balance above the maximum balance of the account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AboveMaximumBalanceError, "Зачисление приведёт к остатку выше максимального остатка счёта"),
+	},
+	InvalidLoanTermsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidLoanTermsError, "Loan terms are not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidLoanTermsError, "Условия кредита не являются валидными"),
+	},
+	InvalidDepositTermsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidDepositTermsError, "Term deposit terms are not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidDepositTermsError, "Условия срочного депозита не являются валидными"),
+	},
+	TermDepositLockedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", TermDepositLockedError, "Term deposit cannot be debited before maturity"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", TermDepositLockedError, "Средства срочного депозита недоступны до окончания срока"),
+	},
+	InvalidInterestRateError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidInterestRateError, "Interest rate is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidInterestRateError, "Процентная ставка не является валидной"),
+	},
+	ApprovalDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ApprovalDoesNotExistError, "Transfer awaiting approval with the given ID does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ApprovalDoesNotExistError, "Перевод, ожидающий подтверждения, с указанным идентификатором не существует"),
+	},
+	ApprovalIsNotPendingError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ApprovalIsNotPendingError, "Transfer has already been approved or rejected"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ApprovalIsNotPendingError, "Перевод уже подтверждён или отклонён"),
+	},
+	ApprovalPrincipalError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ApprovalPrincipalError, "Transfer must be approved or rejected by a principal other than its initiator"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ApprovalPrincipalError, "Перевод должен быть подтверждён или отклонён не его инициатором"),
+	},
+	LedgerImbalanceError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", LedgerImbalanceError, "Debits and credits of the journal entry do not balance"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", LedgerImbalanceError, "Дебет и кредит проводки не сбалансированы"),
+	},
+	IntegrityViolationError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", IntegrityViolationError, "Money supply invariants do not hold"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", IntegrityViolationError, "Нарушены инварианты денежной массы"),
+	},
+	InvalidChartOfAccountsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidChartOfAccountsError, "Chart of accounts is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidChartOfAccountsError, "План счетов не является валидным"),
+	},
+	TransactionsExportError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", TransactionsExportError, "Impossible to export transactions"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", TransactionsExportError, "Невозможно выгрузить транзакции"),
+	},
+	InvalidPaymentQRError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidPaymentQRError, "Payment QR code is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidPaymentQRError, "QR-код платежа не является валидным"),
+	},
+	InvalidPaymentRequestError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidPaymentRequestError, "Payment request must be addressed to another account, have positive amount and due date in the future"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidPaymentRequestError, "Запрос на оплату должен быть адресован другому счёту, иметь положительную сумму и срок оплаты в будущем"),
+	},
+	PaymentRequestDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", PaymentRequestDoesNotExistError, "Payment request with the given ID addressed to the account does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", PaymentRequestDoesNotExistError, "Запрос на оплату с указанным идентификатором, адресованный счёту, не существует"),
+	},
+	PaymentRequestIsNotPendingError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", PaymentRequestIsNotPendingError, "Payment request has already been paid, declined or has expired"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", PaymentRequestIsNotPendingError, "Запрос на оплату уже оплачен, отклонён или просрочен"),
+	},
+	InvalidBeneficiaryError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidBeneficiaryError, "Beneficiary alias must be printable, up to 35 characters long and not an IBAN, beneficiary must be another account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidBeneficiaryError, "Псевдоним получателя должен состоять из печатных символов, быть не длиннее 35 символов и не быть IBAN, получатель должен быть другим счётом"),
+	},
+	BeneficiaryDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", BeneficiaryDoesNotExistError, "Beneficiary with the given alias does not exist in the address book of the account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", BeneficiaryDoesNotExistError, "Получатель с указанным псевдонимом не существует в адресной книге счёта"),
+	},
+	InvalidAliasError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidAliasError, "Alias must be a phone number in the international format or an email address"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidAliasError, "Псевдоним должен быть номером телефона в международном формате или адресом электронной почты"),
+	},
+	AliasAlreadyRegisteredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AliasAlreadyRegisteredError, "Alias is already registered for another account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AliasAlreadyRegisteredError, "Псевдоним уже зарегистрирован для другого счёта"),
+	},
+	AliasDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AliasDoesNotExistError, "Alias is not registered for any account"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AliasDoesNotExistError, "Псевдоним не зарегистрирован ни для одного счёта"),
+	},
+	InterbankUnavailableError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InterbankUnavailableError, "Interbank transfers require the suspense account to be declared in the chart of accounts"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InterbankUnavailableError, "Для межбанковских переводов в плане счетов должен быть объявлен счёт невыясненных сумм"),
+	},
+	UnknownPeerBankError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", UnknownPeerBankError, "Recipient account does not belong to a peer bank"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", UnknownPeerBankError, "Счёт получателя не принадлежит банку-участнику"),
+	},
+	InvalidInterbankMessageError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidInterbankMessageError, "Interbank transfer message is not valid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidInterbankMessageError, "Сообщение о межбанковском переводе не является валидным"),
+	},
+	InterbankTransferDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InterbankTransferDoesNotExistError, "Interbank transfer with the given correlation ID does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InterbankTransferDoesNotExistError, "Межбанковский перевод с указанным идентификатором корреляции не существует"),
+	},
+	InterbankTransferIsNotPendingError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InterbankTransferIsNotPendingError, "Interbank transfer has already been settled or rejected"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InterbankTransferIsNotPendingError, "Межбанковский перевод уже исполнен или отклонён"),
+	},
+	InvalidClearingObligationError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidClearingObligationError, "Clearing obligation must be owed by one participant to another one and have positive amount"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidClearingObligationError, "Клиринговое обязательство должно быть обязательством одного участника перед другим и иметь положительную сумму"),
+	},
+	CorrespondentAccountExistsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CorrespondentAccountExistsError, "Correspondent account of this kind has already been opened for the peer bank"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CorrespondentAccountExistsError, "Корреспондентский счёт этого вида уже открыт для банка-участника"),
+	},
+	CorrespondentAccountDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CorrespondentAccountDoesNotExistError, "Correspondent account of the peer bank does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CorrespondentAccountDoesNotExistError, "Корреспондентский счёт банка-участника не существует"),
+	},
+	InterbankDeliveryError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InterbankDeliveryError, "Peer bank could not be reached or responded with an invalid message"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InterbankDeliveryError, "Банк-участник недоступен или ответил невалидным сообщением"),
+	},
+	EmissionLimitExceededError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", EmissionLimitExceededError, "Emission exceeds the limits of the money supply"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", EmissionLimitExceededError, "Эмиссия превышает лимиты денежной массы"),
+	},
+	EmissionApprovalRequiredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", EmissionApprovalRequiredError, "Emission of the amount requires approval"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", EmissionApprovalRequiredError, "Эмиссия данной суммы требует подтверждения"),
+	},
+	EmissionApprovalDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", EmissionApprovalDoesNotExistError, "No emission with the given ID awaits approval"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", EmissionApprovalDoesNotExistError, "Эмиссия с данным идентификатором не ожидает подтверждения"),
+	},
+	EmissionPrincipalError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", EmissionPrincipalError, "Emission must be approved by a principal other than its initiator"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", EmissionPrincipalError, "Эмиссия должна быть подтверждена другим лицом, а не её инициатором"),
+	},
+	InvalidSupplyReasonError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidSupplyReasonError, "Operator and a valid reason of the emission or destruction must be given"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidSupplyReasonError, "Необходимо указать оператора и допустимую причину эмиссии или уничтожения"),
+	},
+	InvalidBlockError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidBlockError, "Invalid reason or expiry of the block"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidBlockError, "Недопустимая причина или срок блокировки"),
+	},
+	BlockLiftNotAllowedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", BlockLiftNotAllowedError, "The block cannot be lifted with the given authority"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", BlockLiftNotAllowedError, "Блокировка не может быть снята с данными полномочиями"),
+	},
+	DebitsFrozenError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", DebitsFrozenError, "Debits from the account are frozen"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", DebitsFrozenError, "Списания со счёта приостановлены"),
+	},
+	CreditsFrozenError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CreditsFrozenError, "Credits to the account are frozen"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CreditsFrozenError, "Зачисления на счёт приостановлены"),
+	},
+	InvalidFreezeError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidFreezeError, "Freeze must name a case not registered for the account yet, an authority and a non-negative limit"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidFreezeError, "Арест должен содержать ещё не зарегистрированное по счёту дело, орган и неотрицательный лимит"),
+	},
+	FreezeDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FreezeDoesNotExistError, "Active freeze with the given ID does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FreezeDoesNotExistError, "Действующий арест с данным идентификатором не существует"),
+	},
+	FreezeCaseMismatchError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", FreezeCaseMismatchError, "Frozen money can only be released referencing the case of the freeze"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", FreezeCaseMismatchError, "Арестованные средства могут быть освобождены только со ссылкой на дело ареста"),
+	},
+	InvalidAdjustmentError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidAdjustmentError, "Adjustment must be made by an operator for a reason and change the balance"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidAdjustmentError, "Корректировка должна выполняться оператором с указанием причины и изменять остаток"),
+	},
+	AccessDeniedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AccessDeniedError, "Principal is not permitted to perform the operation"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AccessDeniedError, "Недостаточно прав для выполнения операции"),
+	},
+	InvalidApiKeyError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidApiKeyError, "API key is invalid, expired or revoked"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidApiKeyError, "API-ключ недействителен, истёк или отозван"),
+	},
+	ApiKeyRateLimitExceededError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ApiKeyRateLimitExceededError, "Rate limit of the API key is exceeded"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ApiKeyRateLimitExceededError, "Превышен лимит запросов для API-ключа"),
+	},
+	InvalidTokenError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidTokenError, "Bearer token is missing, invalid or expired"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidTokenError, "Токен доступа отсутствует, недействителен или истёк"),
+	},
+	InvalidConsentError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidConsentError, "Consent is invalid, expired or revoked"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidConsentError, "Согласие недействительно, истекло или отозвано"),
+	},
+	ConsentLimitExceededError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ConsentLimitExceededError, "Payment exceeds the remaining limit of the consent"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ConsentLimitExceededError, "Платёж превышает оставшийся лимит согласия"),
+	},
+	ChallengeDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ChallengeDoesNotExistError, "Confirmation challenge does not exist or has expired"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ChallengeDoesNotExistError, "Запрос подтверждения не существует или истёк"),
+	},
+	InvalidOtpCodeError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidOtpCodeError, "Confirmation code is invalid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidOtpCodeError, "Неверный код подтверждения"),
+	},
+	OtpDeliveryError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", OtpDeliveryError, "Confirmation code could not be sent"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", OtpDeliveryError, "Не удалось отправить код подтверждения"),
+	},
+	SessionExpiredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", SessionExpiredError, "Session does not exist or has expired"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", SessionExpiredError, "Сессия не существует или истекла"),
+	},
+	InvalidIpPolicyError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidIpPolicyError, "IP address or network of the policy is invalid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidIpPolicyError, "Неверный IP-адрес или сеть в политике доступа"),
+	},
+	InvalidSignatureError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidSignatureError, "Signature of the request is missing, invalid or expired"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidSignatureError, "Подпись запроса отсутствует, неверна или устарела"),
+	},
+	InvalidWebhookError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidWebhookError, "Webhook subscription is invalid or does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidWebhookError, "Подписка на уведомления неверна или не существует"),
+	},
+	InvalidTlsConfigError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidTlsConfigError, "Certificate, key or CA of the TLS config could not be loaded"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidTlsConfigError, "Не удалось загрузить сертификат, ключ или УЦ конфигурации TLS"),
+	},
+	EncryptionError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", EncryptionError, "Value could not be encrypted or decrypted"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", EncryptionError, "Не удалось зашифровать или расшифровать значение"),
+	},
+	CustomerHasOpenAccountsError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CustomerHasOpenAccountsError, "Customer still holds accounts which are not closed"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CustomerHasOpenAccountsError, "У клиента остались незакрытые счета"),
+	},
+	InvalidConfigError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidConfigError, "Configuration is invalid"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidConfigError, "Некорректная конфигурация"),
+	},
+	SecretNotFoundError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", SecretNotFoundError, "Requested secret does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", SecretNotFoundError, "Запрашиваемый секрет не существует"),
+	},
+	SecretUnavailableError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", SecretUnavailableError, "Secret could not be loaded from the store"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", SecretUnavailableError, "Не удалось получить секрет из хранилища"),
+	},
+	ConfigRestartRequiredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ConfigRestartRequiredError, "Changed settings cannot be applied without a restart"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ConfigRestartRequiredError, "Изменённые настройки вступят в силу только после перезапуска"),
+	},
+	ShutdownTimeoutError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", ShutdownTimeoutError, "Shutdown did not complete in time"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", ShutdownTimeoutError, "Завершение работы не уложилось в отведённое время"),
+	},
+	SnapshotWriteError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", SnapshotWriteError, "Snapshot could not be written"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", SnapshotWriteError, "Не удалось записать снимок"),
+	},
+	BackendUnavailableError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", BackendUnavailableError, "Backend is unavailable"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", BackendUnavailableError, "Хранилище недоступно"),
+	},
+	UnsupportedIbanCountryError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", UnsupportedIbanCountryError, "IBANs of the country are not supported"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", UnsupportedIbanCountryError, "IBAN этой страны не поддерживаются"),
+	},
+	InvalidBankCodeError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidBankCodeError, "Bank code does not fit the IBAN format of the country"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidBankCodeError, "Код банка не соответствует формату IBAN страны"),
+	},
+	UnknownBankCodeError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", UnknownBankCodeError, "Bank of the IBAN is not in the bank directory"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", UnknownBankCodeError, "Банк IBAN отсутствует в справочнике банков"),
+	},
+	CardDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CardDoesNotExistError, "Card does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CardDoesNotExistError, "Карта не существует"),
+	},
+	CardIsBlockedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CardIsBlockedError, "Card is blocked"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CardIsBlockedError, "Карта заблокирована"),
+	},
+	CardIsExpiredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CardIsExpiredError, "Card is expired"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CardIsExpiredError, "Срок действия карты истек"),
+	},
+	CardNotAllowedError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CardNotAllowedError, "Cards cannot be issued to accounts of this type"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CardNotAllowedError, "Карты не выпускаются к счетам этого типа"),
+	},
+	CardIssuanceError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CardIssuanceError, "Failed to issue card"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CardIssuanceError, "Не удалось выпустить карту"),
+	},
+	AuthorizationDoesNotExistError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AuthorizationDoesNotExistError, "Authorization does not exist"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AuthorizationDoesNotExistError, "Авторизация не существует"),
+	},
+	AuthorizationIsNotPendingError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AuthorizationIsNotPendingError, "Authorization is already captured, voided or expired"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AuthorizationIsNotPendingError, "Авторизация уже списана, отменена или истекла"),
+	},
+	AuthorizationHoldError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", AuthorizationHoldError, "Hold belongs to card authorization and is settled with it only"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", AuthorizationHoldError, "Блокировка средств принадлежит авторизации по карте и снимается только вместе с ней"),
+	},
+	InvalidPurchaseError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InvalidPurchaseError, "Purchase cannot be paid to the account of the card"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InvalidPurchaseError, "Покупка не может быть оплачена на счет карты"),
+	},
+	CashVaultNotDeclaredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CashVaultNotDeclaredError, "Cash vault account is not declared in the chart of accounts"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CashVaultNotDeclaredError, "Счет кассы не объявлен в плане счетов"),
+	},
+	CashVaultShortageError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CashVaultShortageError, "Cash vault balance is insufficient for the deposit"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CashVaultShortageError, "Остатка кассы недостаточно для взноса наличных"),
+	},
+	CashLimitExceededError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", CashLimitExceededError, "Cash operation exceeds the cash limits"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", CashLimitExceededError, "Операция с наличными превышает лимиты"),
+	},
+	UntrustedPeerBankError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", UntrustedPeerBankError, "Interbank transfer does not come from a known peer bank"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", UntrustedPeerBankError, "Межбанковский перевод поступил не от известного банка-участника"),
+	},
+	InterbankAuthenticationRequiredError: {
+		English: fmt.Sprintf("Error code: %d. Message: %s", InterbankAuthenticationRequiredError, "Interbank transfers are accepted only if signatures, API keys or bearer tokens of the peers are required"),
+		Russian: fmt.Sprintf("Код ошибки: %d. Сообщение: %s", InterbankAuthenticationRequiredError, "Межбанковские переводы принимаются, только если от банков-участников требуются подписи, API-ключи или токены"),
+	},
 }
 
 type AccountStatus int8
@@ -110,6 +777,7 @@ type AccountStatus int8
 const (
 	Active AccountStatus = iota
 	Blocked
+	Closed
 )
 
 // Mapping account status codes to account status names considering locale
@@ -122,6 +790,10 @@ var accountStatusCodeToNameMap map[AccountStatus](map[LanguageCode]string) = map
 		English: "Blocked",
 		Russian: "Заблокированный",
 	},
+	Closed: {
+		English: "Closed",
+		Russian: "Закрытый",
+	},
 }
 
 type AccountType int8
@@ -130,33 +802,166 @@ const (
 	Ordinary AccountType = iota
 	MonetaryEmission
 	MonetaryDestruction
+	MonetaryRemainder
+	LoanAccount
+	TermDepositAccount
+	NostroAccount // mirror of our account at a correspondent bank
+	VostroAccount // account of a correspondent bank with us
 )
 
+// Mapping account type codes to account type names considering locale
+var accountTypeCodeToNameMap map[AccountType](map[LanguageCode]string) = map[AccountType](map[LanguageCode]string){
+	Ordinary: {
+		English: "Ordinary",
+		Russian: "Обычный",
+	},
+	MonetaryEmission: {
+		English: "Monetary emission",
+		Russian: "Эмиссионный",
+	},
+	MonetaryDestruction: {
+		English: "Monetary destruction",
+		Russian: "Для уничтожения",
+	},
+	MonetaryRemainder: {
+		English: "Monetary remainder",
+		Russian: "Для остатков",
+	},
+	LoanAccount: {
+		English: "Loan",
+		Russian: "Кредитный",
+	},
+	TermDepositAccount: {
+		English: "Term deposit",
+		Russian: "Срочный депозит",
+	},
+	NostroAccount: {
+		English: "Nostro",
+		Russian: "Ностро",
+	},
+	VostroAccount: {
+		English: "Vostro",
+		Russian: "Лоро",
+	},
+}
+
+// IBAN of the system account accumulating sub-cent fractions swept from all other accounts
+const RemainderAccountIban = "BY84ALFA10000000000000000002"
+
+// Currency of special accounts and of ordinary accounts opened without specifying one
+const DefaultCurrency = "BYN"
+
 // --------------------------------------------------------
 // Defining account structure properties
 type Account struct {
-	Iban      string
-	Status    AccountStatus
-	Type      AccountType
-	Balance   float64
-	Fractions float64
-	// can be augmented with account holder details
+	Iban       string
+	Status     AccountStatus
+	Type       AccountType
+	Balance    float64
+	Fractions  float64
+	Currency   string
+	Metadata   map[string]string // arbitrary key-value pairs, i.e. branch or product code
+	Tags       []string          // free-form labels, i.e. "test" to mark test accounts
+	CustomerID string            // ID of the account holder, empty for special accounts and accounts opened without a customer
+	Kyc        KycStatus
+	Product    ProductType
+	Held       float64 // part of the balance reserved by active holds, it cannot be debited until the holds are captured or released
+	Limits     AccountLimits
+	// sum of debits made on DailyDebitDate, counted towards the daily limit
+	DailyDebited   float64
+	DailyDebitDate string
+	// sum of cash withdrawals made on DailyCashWithdrawalDate, counted towards the daily cash limit
+	DailyCashWithdrawn      float64
+	DailyCashWithdrawalDate string
+	OpenedAt                time.Time
+	OverdraftLimit          float64 // the balance may go negative down to minus the limit
+	MinimumBalance          float64
+	MaximumBalance          float64
+	BlockReason             BlockReason
+	BlockedUntil            time.Time // the block is lifted automatically at that time, zero means it stays until lifted explicitly
+	Restriction             AccountRestriction
+	OtpThreshold            float64     // transfers of larger amounts need confirmation by a one-time code, zero disables confirmation
+	hot                     *hotBalance // movements of the balance not reconciled yet if the account is hot, see hotaccounts.go
 	// can be augmented with other properties such as the timestamp of last modification and so on
 }
 
+// Rounding helpers for amounts in the default currency, see currency.go for other currencies
 func round(amount float64) float64 {
-	return math.Round(amount*100) / 100
+	return roundToCurrency(amount, DefaultCurrency)
 }
 
 func roundAndExtractFractions(amount float64) (float64, float64) {
-	var rounded float64 = math.Round(amount*100) / 100
-	fractions := amount - rounded
-	return rounded, fractions
+	return roundAndExtractFractionsInCurrency(amount, DefaultCurrency)
 }
 
 func NewAccount(iban string, s AccountStatus, t AccountType, b float64) *Account {
 	r, f := roundAndExtractFractions(b)
-	return &Account{iban, s, t, r, f}
+	return &Account{Iban: iban, Status: s, Type: t, Balance: r, Fractions: f, Currency: DefaultCurrency, Metadata: map[string]string{}, Tags: []string{}, Kyc: KycVerified, OpenedAt: clock.Now().UTC()}
+}
+
+// Representation of account attributes exposed to external callers, status and type are translated considering locale
+type AccountDetails struct {
+	Iban       string            `json:"iban"`
+	Balance    float64           `json:"balance"`
+	Held       float64           `json:"held"`
+	Available  float64           `json:"available"`
+	Fractions  float64           `json:"fractions"`
+	Currency   string            `json:"currency"`
+	Status     string            `json:"status"`
+	Type       string            `json:"type"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	CustomerID string            `json:"customer_id,omitempty"`
+	Kyc        string            `json:"kyc"`
+	Product    string            `json:"product"`
+	// Overdraft facility of the account and the part of it in use
+	OverdraftLimit float64 `json:"overdraft_limit,omitempty"`
+	OverdraftUsed  float64 `json:"overdraft_used,omitempty"`
+	// Reason and automatic expiry of the block of a blocked account
+	BlockReason  string     `json:"block_reason,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+	Restriction  string     `json:"restriction,omitempty"`
+}
+
+func (acc *Account) Details() AccountDetails {
+	details := AccountDetails{acc.Iban, acc.BookedBalance(), acc.Held, acc.AvailableBalance(), acc.Fractions, acc.Currency, accountStatusCodeToNameMap[acc.Status][locale()], accountTypeCodeToNameMap[acc.Type][locale()], copyMetadata(acc.Metadata), append([]string{}, acc.Tags...), acc.CustomerID, kycStatusCodeToNameMap[acc.Kyc][locale()], productTypeCodeToNameMap[acc.Product][locale()], acc.OverdraftLimit, acc.OverdraftUsed(), "", nil, ""}
+	if acc.Status == Blocked {
+		details.BlockReason = blockReasonCodeToNameMap[acc.BlockReason][locale()]
+		if !acc.BlockedUntil.IsZero() {
+			until := acc.BlockedUntil
+			details.BlockedUntil = &until
+		}
+	}
+	if acc.Restriction != NoRestriction {
+		details.Restriction = accountRestrictionCodeToNameMap[acc.Restriction][locale()]
+	}
+	return details
+}
+
+// Returns a value copy of the account detached from the repository, so that modifying it does not affect stored state
+func (acc *Account) Snapshot() Account {
+	snapshot := *acc
+	snapshot.Balance, snapshot.hot = acc.BookedBalance(), nil
+	snapshot.Metadata = copyMetadata(acc.Metadata)
+	snapshot.Tags = append([]string{}, acc.Tags...)
+	return snapshot
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	copied := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (acc *Account) HasTag(tag string) bool {
+	for _, t := range acc.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 func (acc *Account) Block() {
@@ -165,36 +970,55 @@ func (acc *Account) Block() {
 
 func (acc *Account) Activate() {
 	acc.Status = Active
+	acc.BlockReason = UnspecifiedBlock
+	acc.BlockedUntil = time.Time{}
 }
 
 func (acc *Account) Deduct(amount float64) {
-	r, f := roundAndExtractFractions(amount)
-	acc.Balance -= r
+	r, f := roundAndExtractFractionsInCurrency(amount, acc.Currency)
 	acc.Fractions -= f
+	if acc.hot != nil {
+		acc.hot.pending.Add(-toMinorUnits(r, acc.Currency))
+		return
+	}
+	acc.Balance -= r
+
+	acc.Balance = roundToCurrency(acc.Balance, acc.Currency)
+}
 
-	acc.Balance = round(acc.Balance)
+// Booked balance less the amount reserved by holds plus the overdraft facility
+func (acc *Account) AvailableBalance() float64 {
+	return roundToCurrency(acc.BookedBalance()-acc.Held+acc.OverdraftLimit, acc.Currency)
 }
 
 func (acc *Account) Add(amount float64) {
-	r, f := roundAndExtractFractions(amount)
-	acc.Balance += r
+	r, f := roundAndExtractFractionsInCurrency(amount, acc.Currency)
 	acc.Fractions += f
+	if acc.hot != nil {
+		acc.hot.pending.Add(toMinorUnits(r, acc.Currency))
+		return
+	}
+	acc.Balance += r
 
-	acc.Balance = round(acc.Balance)
+	acc.Balance = roundToCurrency(acc.Balance, acc.Currency)
 }
 
 // Helper functions to validate and generate IBAN
 func IsValidIban(iban string) bool {
 	// Stripping spaces since IBANs often contain them to separate characters in blocks of 4 for better readability
-	iban = strings.Replace(iban, " ", "", -1)
+	iban = NormalizeIban(iban)
 
-	// Checking the length
-	if len(iban) != 28 {
+	// Checking the length and the structure of the BBAN by the country
+	if len(iban) < 4 {
+		return false
+	}
+	format, ok := LookupIbanFormat(iban[:2])
+	if !ok || len(iban) != format.Length || !format.MatchesBban(iban[4:]) {
 		return false
 	}
 
-	// Prepare an IBAN for mod-97 verification
-	iban = iban[:4] + iban[4:]
+	// Prepare an IBAN for mod-97 verification by moving the country code and the check digits to the end
+	iban = iban[4:] + iban[:4]
 	ibanConverted, err := ConvertIbanToNumericForm(iban)
 	if err != nil {
 		return false
@@ -219,7 +1043,7 @@ func ConvertIbanToNumericForm(iban string) (string, error) {
 			continue
 		}
 		// Return an error for invalid characters
-		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale])
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale()])
 	}
 	return numericBuilder.String(), nil
 }
@@ -234,53 +1058,46 @@ func Mod97(number string) int {
 	return remainder
 }
 
-// Generates random Belarusian IBAN without ensuring its validity
+// Generates random Belarusian IBAN with the check digits calculated for the random BBAN
 func GenerateBelarusianIban() (string, error) {
 	countryPrefix := "BY"
 
 	const totalLength = 28
-	const checkDigitsPlaceholder = "00" // Placeholder for check digits
-	bbanLength := totalLength - 4       // Length of the Basic Bank Account Number (BBAN)
+	bbanLength := totalLength - 4 // Length of the Basic Bank Account Number (BBAN)
 
-	// Generate a random BBAN with digits
+	// Generate a random BBAN with digits, unpredictable ones if configured
 	bban := GenerateRandomDigits(bbanLength)
+	if secureIbanGeneration {
+		var err error
+		if bban, err = GenerateSecureRandomDigits(bbanLength); err != nil {
+			return "", err
+		}
+	}
 
-	// Construct the IBAN with placeholder check digits
-	iban := countryPrefix + checkDigitsPlaceholder + bban
-
-	// Convert IBAN to numeric string for mod-97 calculation
-	ibanNumeric, err := ConvertIbanToNumericForm(iban)
+	// Calculate check digits of the BBAN, see CalculateCheckDigits
+	checkDigits, err := CalculateCheckDigits(countryPrefix, bban)
 	if err != nil {
 		return "", err
 	}
+	return countryPrefix + checkDigits + bban, nil
+}
 
-	// Calculate check digits
-	checkDigits := CalculateIbanCheckDigits(ibanNumeric)
-
-	// Replace placeholder check digits in the IBAN
-	iban = countryPrefix + checkDigits + bban
+// Attempts to generate an IBAN which is not taken by another account before giving up, see Config
+var maxIbanGenerationAttempts = 1000000
 
-	return iban, nil
-}
+// BBANs are generated with crypto/rand instead of the random source of the package if set, see Config
+var secureIbanGeneration = false
 
-// Generates a random Belarusian IBAN that is valid
+// Generates a random Belarusian IBAN that is valid in one pass, check digits are calculated rather than searched for,
+// so that the only failure is the one of the random source
 func GenerateValidBelarusianIban() (string, error) {
-	var iban string = ""
-	var err error = nil
-	errCount := 0
-	for !IsValidIban(iban) {
-		// Breaking the loop if valid IBAN generation took too many tries
-		// ideally the value to compare to errCount should be parsed from environmental configuration
-		if errCount > 1000000 {
-			return "", fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale])
-		}
-		// Attempting to generate a valid IBAN
-		iban, err = GenerateBelarusianIban()
-		if err != nil {
-			errCount++
-			continue
-		}
-		errCount++
+	iban, err := GenerateBelarusianIban()
+	if err != nil {
+		return "", err
+	}
+	// Checking the generated IBAN once to guard against bugs of the generator rather than retrying until it is valid
+	if !IsValidIban(iban) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale()])
 	}
 	return iban, nil
 }
@@ -289,11 +1106,29 @@ func GenerateValidBelarusianIban() (string, error) {
 func GenerateRandomDigits(length int) string {
 	digits := make([]byte, length)
 	for i := range digits {
-		digits[i] = byte(rand.Intn(10) + '0')
+		digits[i] = byte(random.Intn(10) + '0')
 	}
 	return string(digits)
 }
 
+// Generates a string of random digits of a specified length with crypto/rand, bytes above 249 are discarded so that
+// every digit is equally likely
+func GenerateSecureRandomDigits(length int) (string, error) {
+	digits := make([]byte, 0, length)
+	buffer := make([]byte, length)
+	for len(digits) < length {
+		if _, err := rand.Read(buffer); err != nil {
+			return "", err
+		}
+		for _, b := range buffer {
+			if b < 250 && len(digits) < length {
+				digits = append(digits, b%10+'0')
+			}
+		}
+	}
+	return string(digits), nil
+}
+
 // Calculates the check digits for an IBAN given its numeric string representation.
 func CalculateIbanCheckDigits(ibanNumeric string) string {
 	// Perform mod-97 operation and subtract from 98 to get check digits
@@ -310,311 +1145,2101 @@ type AccountRepository interface {
 	EmitMoney(amount float64) error
 	DestructMoney(iban string, amount float64) error
 	OpenAccount() (*Account, error)
-	TransferMoney(sender, recipient string, amount float64) error
-	TransferMoneyJson(jsonStr string) error
+	TransferMoney(sender, recipient string, amount float64) (string, error)
+	TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error)
+	GetTransferStatus(id string) (TransferStatus, error)
+	RetrieveTransferParties(id string) (string, string, error)
+	ReverseTransfer(id, reason string) (string, error)
+	// Additional methods to approve large transfers by a second principal
+	ApproveTransfer(id, principal string) error
+	RejectTransfer(id, principal, reason string) error
+	ListPendingApprovals() ([]Approval, error)
+	// Additional methods to limit debits per account
+	SetAccountLimits(iban string, limits AccountLimits) error
+	SetOverdraftLimit(iban string, limit float64) error
+	UpdateAccountSettings(iban string, settings AccountSettings) error
+	RetrieveAccountSettings(iban string) (AccountSettings, error)
+	// Additional methods to charge fees
+	SetFeeAccount(iban string) error
+	// Additional methods to accrue and post interest
+	SetInterestRate(product ProductType, rate float64) error
+	AccrueInterest(date time.Time) (int, error)
+	PostInterest(until time.Time) ([]InterestPosting, error)
+	ListInterestAccruals(iban string) ([]InterestAccrual, error)
+	ListInterestPostings(iban string) ([]InterestPosting, error)
+	// Additional methods to lend money
+	OpenLoan(linkedIban string, principal, annualRate float64, months int, firstDue time.Time) (*Loan, error)
+	RetrieveLoan(iban string) (Loan, error)
+	CollectDueInstallments(now time.Time) (int, error)
+	// Additional methods to manage term deposits
+	OpenTermDeposit(linkedIban string, amount, annualRate, penaltyRate float64, maturesAt time.Time) (*TermDeposit, error)
+	RetrieveTermDeposit(iban string) (TermDeposit, error)
+	MatureTermDeposits(now time.Time) (int, error)
+	WithdrawTermDeposit(iban string) (float64, error)
+	// Additional methods to deduplicate money movements retried by clients
+	EmitMoneyIdempotent(key string, amount float64) error
+	DestructMoneyIdempotent(key, iban string, amount float64) error
+	TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error)
+	// Additional methods to pay several recipients at once
+	SplitTransfer(sender string, amount float64, shares []SplitShare) ([]string, error)
+	// Additional methods to reserve money before debiting it
+	HoldFunds(iban string, amount float64) (*Hold, error)
+	CaptureHold(holdID, recipient string, amount float64) (string, error)
+	ReleaseHold(holdID string) error
+	RetrieveHold(holdID string) (Hold, error)
+	// Additional methods to issue payment cards and debit accounts by them
+	IssueCard(iban string) (*Card, error)
+	BlockCard(cardID string) error
+	ActivateCard(cardID string) error
+	RetrieveCard(cardID string) (Card, error)
+	ListCards(iban string) ([]Card, error)
+	DebitCard(cardID, recipient string, amount float64) (string, error)
+	// Additional methods to authorize card purchases and capture them later
+	AuthorizePurchase(cardID string, amount float64, merchant string) (*Authorization, error)
+	CapturePurchase(authorizationID string, amount float64) (string, error)
+	VoidAuthorization(authorizationID string) error
+	RetrieveAuthorization(authorizationID string) (Authorization, error)
+	ExpireAuthorizations(now time.Time) (int, error)
+	// Additional methods to withdraw and deposit cash
+	CashWithdraw(iban string, amount float64) (string, error)
+	CashDeposit(iban string, amount float64) (string, error)
+	SetCashLimits(limits CashLimits) error
+	// Additional methods to request money from other accounts
+	CreatePaymentRequest(requester, payer string, amount float64, dueDate time.Time, memo string) (*PaymentRequest, error)
+	ListIncomingPaymentRequests(payer string) ([]PaymentRequest, error)
+	AcceptPaymentRequest(id, payer string) (string, error)
+	DeclinePaymentRequest(id, payer string) error
+	ExpirePaymentRequests(now time.Time) (int, error)
+	// Additional methods to keep address books of beneficiaries
+	SaveBeneficiary(iban, alias, beneficiaryIban string) (*Beneficiary, error)
+	ListBeneficiaries(iban string) ([]Beneficiary, error)
+	DeleteBeneficiary(iban, alias string) error
+	// Additional methods to pay by phone numbers and email addresses
+	RegisterAlias(alias, iban string) error
+	ResolveAlias(alias string) (string, error)
+	// Additional methods to exchange transfers with peer banks
+	InitiateInterbankTransfer(sender, recipient, peerBank string, amount float64, details TransferDetails) (*InterbankTransfer, error)
+	SettleInterbankTransfer(correlationID string) error
+	RefundInterbankTransfer(correlationID, reason string) error
+	ReceiveInterbankTransfer(msg InterbankMessage) (InterbankAck, error)
+	RetrieveInterbankTransfer(correlationID string) (InterbankTransfer, error)
+	// Additional methods to keep correspondent accounts with peer banks
+	OpenNostroAccount(bank, iban string, balance float64) (*Account, error)
+	OpenVostroAccount(bank, iban string) (*Account, error)
+	RetrieveVostroBalance(bank string) (float64, string, error)
+	ReconcileNostro(bank string, reportedBalance float64) (*NostroReconciliation, error)
+	// Additional methods to limit the money supply
+	SetEmissionPolicy(policy EmissionPolicy) error
+	EmitMoneyWithReason(amount float64, operator string, reason SupplyReason) error
+	DestructMoneyWithReason(iban string, amount float64, operator string, reason SupplyReason) error
+	RetrieveSupplyRegister(from, to time.Time) ([]SupplyRecord, error)
+	RequestEmission(amount float64, initiator string, reason SupplyReason) (string, error)
+	ApproveEmission(id, principal string) error
+	RejectEmission(id, principal, reason string) error
+	ListPendingEmissions() ([]EmissionApproval, error)
+	MoneySupply() (*MoneySupply, error)
+	TransferMoneyJson(jsonStr string) (string, error)
+	OpenAccountWithInitialDeposit(amount float64) (*Account, error)
 	RetrieveAllAccountsAsJson() (string, error)
+	WriteAllAccountsJson(writer io.Writer) error
+	RetrieveAccount(iban string) (*AccountDetails, error)
+	RetrieveAccountAsJson(iban string) (string, error)
+	GetAccount(iban string) (Account, error)
+	ForEachAccount(callback func(Account) bool) error
+	FindAccounts(query AccountQuery) ([]AccountDetails, error)
+	RetrieveSnapshot(ibans ...string) (*RepositorySnapshot, error)
+	ListBlockedAccounts() ([]AccountDetails, error)
+	ListAccountsByType(accType AccountType) ([]AccountDetails, error)
+	ListDormantAccounts(since time.Time) ([]AccountDetails, error)
 	// Additional methods to manipulate the status of the account
 	BlockAccount(iban string) error
 	ActivateAccount(iban string) error
+	BlockAccountWithReason(iban string, reason BlockReason, until time.Time) error
+	ActivateAccountAs(iban string, authority Authority) error
+	ExpireBlocks(now time.Time) (int, error)
+	RestrictAccount(iban string, restriction AccountRestriction) error
+	// Additional methods to freeze money by legal order
+	RegisterFreeze(iban, caseNumber, authority string, limit float64) (*LegalFreeze, error)
+	ReleaseFreeze(id, caseNumber string) error
+	ListFreezes(iban string) ([]LegalFreeze, error)
+	// Additional methods to correct balances manually
+	AdjustBalance(iban string, delta float64, reason, operator string) (string, error)
+	ListAdjustments(iban string) ([]Adjustment, error)
+	// Additional methods to authenticate clients of the HTTP layer
+	IssueApiKey(principal Principal, scopes []ApiKeyScope, rateLimit int) (*ApiKey, string, error)
+	RotateApiKey(id string, grace time.Duration) (*ApiKey, string, error)
+	RevokeApiKey(id string) error
+	ListApiKeys(principalID string) ([]ApiKey, error)
+	AuthenticateApiKey(token string, scope ApiKeyScope, now time.Time) (*ApiKey, error)
+	CheckApiKey(id string, scope ApiKeyScope, now time.Time) (*ApiKey, error)
+	// Additional methods to grant third-party clients access to accounts
+	IssueConsent(clientID string, ibans []string, scopes []ConsentScope, paymentLimit float64, days int) (*Consent, error)
+	IntrospectConsent(id string) (ConsentIntrospection, error)
+	RevokeConsent(id string) error
+	ListConsents(iban string) ([]Consent, error)
+	RetrieveAccountByConsent(id, clientID, iban string) (*AccountDetails, error)
+	TransferMoneyByConsent(id, clientID, sender, recipient string, amount float64, details TransferDetails) (string, error)
+	// Additional methods to confirm transfers by one-time codes
+	SetOtpThreshold(iban string, threshold float64) error
+	ConfirmTransfer(challengeID, code string) error
+	ExpireChallenges(now time.Time) (int, error)
+	ListPendingChallenges(iban string) ([]Challenge, error)
+	// Additional methods to erase personal data of customers
+	AnonymizeCustomerAccounts(customerID string) (int, error)
+	CloseAccount(iban, sweepTargetIban string) error
+	// Additional methods to keep track of sub-cent fractions
+	RetrieveRemainderAccountIban() (string, error)
+	RetrieveSystemAccountIban(role SystemAccountRole) (string, error)
+	ReconcileFractions() (*FractionsReconciliation, error)
+	// Additional methods to support accounts in foreign currencies
+	OpenAccountInCurrency(currency string) (*Account, error)
+	// Additional methods to label accounts
+	OpenAccountWithMetadata(metadata map[string]string, tags []string) (*Account, error)
+	OpenAccountWithOptions(opts AccountOptions) (*Account, error)
+	UpdateAccountMetadata(iban string, metadata map[string]string, tags []string) error
+	// Additional methods to make retried account openings idempotent
+	OpenAccountWithReference(reference string) (*Account, error)
+	// Additional methods to migrate existing account numbers
+	OpenAccountWithIban(iban string) (*Account, error)
+	ImportAccountsCSV(reader io.Reader) (int, error)
+	ExportAccounts(writer io.Writer, format ExportFormat) error
+	// Additional methods to link accounts to their holders
+	OpenAccountForCustomer(customerID string) (*Account, error)
+	AttachAccountToCustomer(iban, customerID string) error
+	InternalMoveMoney(customerID, sender, recipient string, amount float64) error
+	// Additional methods to manage KYC verification of accounts
+	VerifyKyc(iban string) error
+	RejectKyc(iban string) error
+	ConvertAndTransfer(sender, recipient string, amount float64) error
+	RetrieveAllTransactionsAsJson() (string, error)
+	RetrieveAccountTransactions(iban string, limit int) ([]Transaction, error)
+	RetrieveTransaction(id string) (Transaction, error)
+	RetrieveTransactionsBetween(from, to time.Time) ([]Transaction, error)
+	// Additional methods to inspect the general ledger
+	RetrieveLedgerBalance(account, currency string) float64
+	RetrieveJournalAsJson() (string, error)
+	VerifyInvariants() (*InvariantReport, error)
+	// Additional methods to keep balances of high-traffic system accounts in atomic counters
+	SetHotAccount(iban string, hot bool) error
+	ReconcileHotAccounts() (int, error)
 }
 
 type AccountService struct {
-	accountRepoImpl AccountRepository
+	accountRepoImpl  AccountRepository
+	customerRepoImpl CustomerRepository
+	principal        Principal // identity of the caller recorded in the audit log and checked against the permissions, see As
+	auditLog         *AuditLog
+	logger           *slog.Logger    // audited operations are not logged if nil, see WithLogger
+	tracing          *serviceTracing // audited operations are not traced if nil, see WithTracer
 }
 
+// Uses in-memory customer repository, see NewAccountServiceWithCustomers to provide another implementation
 func NewAccountService(r AccountRepository) *AccountService {
-	return &AccountService{r}
+	return NewAccountServiceWithCustomers(r, NewInMemoryCustomerRepository())
+}
+
+func NewAccountServiceWithCustomers(r AccountRepository, c CustomerRepository) *AccountService {
+	return &AccountService{r, c, Principal{}, NewAuditLog(), nil, nil}
 }
 
 func (s *AccountService) RetrieveEmissionAccountIban() (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
 	return s.accountRepoImpl.RetrieveEmissionAccountIban()
 }
 
 func (s *AccountService) RetrieveDestructionAccountIban() (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
 	return s.accountRepoImpl.RetrieveDestructionAccountIban()
 }
 
 func (s *AccountService) EmitMoney(amount float64) error {
-	return s.accountRepoImpl.EmitMoney(amount)
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.audited(EmitAction, "", "", amount, err)
+	}
+	return s.audited(EmitAction, "", "", amount, s.accountRepoImpl.EmitMoney(amount))
 }
 
 func (s *AccountService) DestructMoney(iban string, amount float64) error {
-	return s.accountRepoImpl.DestructMoney(iban, amount)
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.audited(DestructAction, iban, "", amount, err)
+	}
+	return s.audited(DestructAction, iban, "", amount, s.accountRepoImpl.DestructMoney(iban, amount))
 }
 
 // Not passing account type assuming this method opens only ordinary accounts, not special accounts for monetary emmision and destruction
-// Not passing account status assuming a newly opened account should be active immediately (this behavior can be change to comply with KYC)
+// Not passing account status assuming a newly opened account should be active immediately, though it stays pending KYC verification with limited debits until VerifyKyc
 // Not passing initial balance assuming it should only be topped up from the emission account by making a money transfer between accounts
 func (s *AccountService) OpenAccount() (*Account, error) {
-	return s.accountRepoImpl.OpenAccount()
+	if err := s.authorize(OpenPermission); err != nil {
+		return s.auditedOpening(nil, err)
+	}
+	return s.auditedOpening(s.accountRepoImpl.OpenAccount())
 }
 
-func (s *AccountService) TransferMoney(sender, recipient string, amount float64) error {
-	return s.accountRepoImpl.TransferMoney(sender, recipient, amount)
+// Returns the ID of the transfer even if it failed, so that its status can be looked up later with GetTransferStatus
+func (s *AccountService) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	// Transfers of the principal go with details stamped with their initiator
+	if s.principal.ID != "" {
+		return s.TransferMoneyWithDetails(sender, recipient, amount, TransferDetails{})
+	}
+	if err := s.authorize(TransferPermission, sender); err != nil {
+		return s.auditedID(TransferAction, sender, recipient, amount, "", err)
+	}
+	id, err := s.accountRepoImpl.TransferMoney(sender, recipient, amount)
+	return s.auditedID(TransferAction, sender, recipient, amount, id, err)
 }
 
-func (s *AccountService) TransferMoneyJson(jsonStr string) error {
-	return s.accountRepoImpl.TransferMoneyJson(jsonStr)
+// Transfers money with optional reference, memo and purpose code stored on the transaction record
+// Returns the ID of the transfer even if it failed, so that its status can be looked up later with GetTransferStatus
+func (s *AccountService) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	if err := s.authorize(TransferPermission, sender); err != nil {
+		return s.auditedID(TransferAction, sender, recipient, amount, "", err)
+	}
+	id, err := s.accountRepoImpl.TransferMoneyWithDetails(sender, recipient, amount, s.initiated(details))
+	return s.auditedID(TransferAction, sender, recipient, amount, id, err)
 }
 
-func (s *AccountService) RetrieveAllAccountsAsJson() (string, error) {
-	return s.accountRepoImpl.RetrieveAllAccountsAsJson()
+func (s *AccountService) GetTransferStatus(id string) (TransferStatus, error) {
+	if err := s.authorizeTransfer(ReadPermission, id); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.GetTransferStatus(id)
 }
 
-func (s *AccountService) BlockAccount(iban string) error {
-	return s.accountRepoImpl.BlockAccount(iban)
+// Sends the money of a settled transfer back with a compensating transaction linked to the original one
+// Returns the ID of the compensating transaction
+func (s *AccountService) ReverseTransfer(id, reason string) (string, error) {
+	if err := s.authorize(TransferPermission); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.ReverseTransfer(id, reason)
 }
 
-func (s *AccountService) ActivateAccount(iban string) error {
-	return s.accountRepoImpl.ActivateAccount(iban)
+// Executes a transfer awaiting approval, the principal of the service must differ from the initiator of the transfer
+func (s *AccountService) ApproveTransfer(id string) error {
+	if err := s.authorize(TransferPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ApproveTransfer(id, s.principal.ID)
 }
 
-// --------------------------------------------------------
-// Defining in-memory implementation of account repository interface methods
-// Explicitly declaring EmissionAccount and DestructionAccount properties for the ease of access (no need to iterate over a collection to get them)
-type InMemoryAccountRepository struct {
-	EmissionAccount    *Account
-	DestructionAccount *Account
-	Accounts           map[string]*Account // accounts decalred as map for speed and simplicity but array could be used instead
-	Mutex              sync.Mutex
+// Cancels a transfer awaiting approval, the principal of the service must differ from the initiator of the transfer
+func (s *AccountService) RejectTransfer(id, reason string) error {
+	if err := s.authorize(TransferPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RejectTransfer(id, s.principal.ID, reason)
 }
 
-func NewInMemoryAccountRepository(eIban, dIban string) *InMemoryAccountRepository {
-	eIban = strings.Replace(eIban, " ", "", -1)
-	dIban = strings.Replace(dIban, " ", "", -1)
-	emissionAcc := NewAccount(eIban, Active, MonetaryEmission, 0)
-	destructionAcc := NewAccount(dIban, Active, MonetaryDestruction, 0)
-	accounts := map[string]*Account{
-		eIban: emissionAcc,
-		dIban: destructionAcc,
+func (s *AccountService) ListPendingApprovals() ([]Approval, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
 	}
-	return &InMemoryAccountRepository{emissionAcc, destructionAcc, accounts, sync.Mutex{}}
+	return s.accountRepoImpl.ListPendingApprovals()
 }
 
-// Helper function to check if account with the given IBAN exists in the accounts map
-func (r *InMemoryAccountRepository) accountExists(iban string) bool {
-	if r.EmissionAccount != nil && r.EmissionAccount.Iban == iban {
-		return true
+// Sets per-transaction and daily debit limits of the account, zero limits are lifted
+func (s *AccountService) SetAccountLimits(iban string, limits AccountLimits) error {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return err
 	}
-	if r.DestructionAccount != nil && r.DestructionAccount.Iban == iban {
-		return true
+	return s.accountRepoImpl.SetAccountLimits(iban, limits)
+}
+
+// Lets the balance of the account go negative down to minus the limit, zero limit withdraws the facility
+func (s *AccountService) SetOverdraftLimit(iban string, limit float64) error {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return err
 	}
-	_, exists := r.Accounts[iban]
-	return exists
+	return s.accountRepoImpl.SetOverdraftLimit(iban, limit)
 }
 
-func (r *InMemoryAccountRepository) RetrieveEmissionAccountIban() (string, error) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-	// Checking if emission account is set
-	if r.EmissionAccount == nil {
-		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+// Constrains the balance of the account with a floor and a ceiling enforced on every debit and credit
+func (s *AccountService) UpdateAccountSettings(iban string, settings AccountSettings) error {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return err
 	}
-	// Checking if account set as emission account is of the correct type
-	if r.EmissionAccount.Type != MonetaryEmission {
-		return "", fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale])
+	return s.accountRepoImpl.UpdateAccountSettings(iban, settings)
+}
+
+func (s *AccountService) RetrieveAccountSettings(iban string) (AccountSettings, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return AccountSettings{}, err
 	}
-	return r.EmissionAccount.Iban, nil
+	return s.accountRepoImpl.RetrieveAccountSettings(iban)
 }
 
-func (r *InMemoryAccountRepository) RetrieveDestructionAccountIban() (string, error) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-	// Checking if destruction account is set
-	if r.DestructionAccount == nil {
-		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+// Designates the account credited with fees configured in the fee schedule of the repository
+func (s *AccountService) SetFeeAccount(iban string) error {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return err
 	}
-	// Checking if account set as destruction account is of the correct type
-	if r.DestructionAccount.Type != MonetaryDestruction {
-		return "", fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale])
+	return s.accountRepoImpl.SetFeeAccount(iban)
+}
+
+func (s *AccountService) SetInterestRate(product ProductType, rate float64) error {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.SetInterestRate(product, rate)
+}
+
+// Records interest earned for the day without moving money, see PostInterest
+func (s *AccountService) AccrueInterest(date time.Time) (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.AccrueInterest(date)
+}
+
+// Credits accounts with the interest accrued up to the given date
+func (s *AccountService) PostInterest(until time.Time) ([]InterestPosting, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.PostInterest(until)
+}
+
+func (s *AccountService) ListInterestAccruals(iban string) ([]InterestAccrual, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListInterestAccruals(iban)
+}
+
+func (s *AccountService) ListInterestPostings(iban string) ([]InterestPosting, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListInterestPostings(iban)
+}
+
+// Disburses the principal to the linked account and returns the loan with its amortization schedule
+func (s *AccountService) OpenLoan(linkedIban string, principal, annualRate float64, months int, firstDue time.Time) (*Loan, error) {
+	if err := s.authorize(OpenPermission, linkedIban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.OpenLoan(linkedIban, principal, annualRate, months, firstDue)
+}
+
+func (s *AccountService) RetrieveLoan(iban string) (Loan, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return Loan{}, err
+	}
+	return s.accountRepoImpl.RetrieveLoan(iban)
+}
+
+func (s *AccountService) CollectDueInstallments(now time.Time) (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.CollectDueInstallments(now)
+}
+
+// Locks the amount taken from the linked account until maturity
+func (s *AccountService) OpenTermDeposit(linkedIban string, amount, annualRate, penaltyRate float64, maturesAt time.Time) (*TermDeposit, error) {
+	if err := s.authorize(OpenPermission, linkedIban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.OpenTermDeposit(linkedIban, amount, annualRate, penaltyRate, maturesAt)
+}
+
+func (s *AccountService) RetrieveTermDeposit(iban string) (TermDeposit, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return TermDeposit{}, err
+	}
+	return s.accountRepoImpl.RetrieveTermDeposit(iban)
+}
+
+func (s *AccountService) MatureTermDeposits(now time.Time) (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.MatureTermDeposits(now)
+}
+
+// Penalized withdrawal of the deposit before maturity
+func (s *AccountService) WithdrawTermDeposit(iban string) (float64, error) {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.WithdrawTermDeposit(iban)
+}
+
+// Repeating the call with the same idempotency key does not emit the money again
+func (s *AccountService) EmitMoneyIdempotent(key string, amount float64) error {
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.audited(EmitAction, "", "", amount, err)
+	}
+	return s.audited(EmitAction, "", "", amount, s.accountRepoImpl.EmitMoneyIdempotent(key, amount))
+}
+
+// Repeating the call with the same idempotency key does not destruct the money again
+func (s *AccountService) DestructMoneyIdempotent(key, iban string, amount float64) error {
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.audited(DestructAction, iban, "", amount, err)
+	}
+	return s.audited(DestructAction, iban, "", amount, s.accountRepoImpl.DestructMoneyIdempotent(key, iban, amount))
+}
+
+// Repeating the call with the same idempotency key does not transfer the money again and returns the ID of the original transfer
+func (s *AccountService) TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	if err := s.authorize(TransferPermission, sender); err != nil {
+		return s.auditedID(TransferAction, sender, recipient, amount, "", err)
+	}
+	id, err := s.accountRepoImpl.TransferMoneyIdempotent(key, sender, recipient, amount, s.initiated(details))
+	return s.auditedID(TransferAction, sender, recipient, amount, id, err)
+}
+
+// Pays several recipients by fixed amounts or percentages of the amount in one atomic operation
+func (s *AccountService) SplitTransfer(sender string, amount float64, shares []SplitShare) ([]string, error) {
+	if err := s.authorize(TransferPermission, sender); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.SplitTransfer(sender, amount, shares)
+}
+
+// Reserves part of the available balance, i.e. on card authorization, without moving the money
+func (s *AccountService) HoldFunds(iban string, amount float64) (*Hold, error) {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.HoldFunds(iban, amount)
+}
+
+// Transfers the held money or part of it to the recipient, the rest of the hold is released
+func (s *AccountService) CaptureHold(holdID, recipient string, amount float64) (string, error) {
+	if err := s.authorizeHold(TransferPermission, holdID); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.CaptureHold(holdID, recipient, amount)
+}
+
+// Makes the held money available again without moving it
+func (s *AccountService) ReleaseHold(holdID string) error {
+	if err := s.authorizeHold(TransferPermission, holdID); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ReleaseHold(holdID)
+}
+
+func (s *AccountService) RetrieveHold(holdID string) (Hold, error) {
+	if err := s.authorizeHold(ReadPermission, holdID); err != nil {
+		return Hold{}, err
+	}
+	return s.accountRepoImpl.RetrieveHold(holdID)
+}
+
+// Issues a card linked to the account, only the masked card number is kept
+func (s *AccountService) IssueCard(iban string) (*Card, error) {
+	if err := s.authorize(OpenPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.IssueCard(iban)
+}
+
+// Blocks the card without blocking its account, i.e. when the card is lost
+func (s *AccountService) BlockCard(cardID string) error {
+	if err := s.authorizeCard(BlockPermission, cardID); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.BlockCard(cardID)
+}
+
+func (s *AccountService) ActivateCard(cardID string) error {
+	if err := s.authorizeCard(BlockPermission, cardID); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ActivateCard(cardID)
+}
+
+func (s *AccountService) RetrieveCard(cardID string) (Card, error) {
+	if err := s.authorizeCard(ReadPermission, cardID); err != nil {
+		return Card{}, err
+	}
+	return s.accountRepoImpl.RetrieveCard(cardID)
+}
+
+func (s *AccountService) ListCards(iban string) ([]Card, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListCards(iban)
+}
+
+// Debits the account of the card in favor of the recipient if the card is active and not expired
+func (s *AccountService) DebitCard(cardID, recipient string, amount float64) (string, error) {
+	if err := s.authorizeCard(TransferPermission, cardID); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.DebitCard(cardID, recipient, amount)
+}
+
+// Holds the amount of the purchase on the account of the card until the merchant captures it or the authorization expires
+func (s *AccountService) AuthorizePurchase(cardID string, amount float64, merchant string) (*Authorization, error) {
+	if err := s.authorizeCard(TransferPermission, cardID); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.AuthorizePurchase(cardID, amount, merchant)
+}
+
+// Transfers the authorized amount or part of it to the merchant, the rest of the hold is released
+func (s *AccountService) CapturePurchase(authorizationID string, amount float64) (string, error) {
+	if err := s.authorizeAuthorization(TransferPermission, authorizationID); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.CapturePurchase(authorizationID, amount)
+}
+
+func (s *AccountService) VoidAuthorization(authorizationID string) error {
+	if err := s.authorizeAuthorization(TransferPermission, authorizationID); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.VoidAuthorization(authorizationID)
+}
+
+func (s *AccountService) RetrieveAuthorization(authorizationID string) (Authorization, error) {
+	if err := s.authorizeAuthorization(ReadPermission, authorizationID); err != nil {
+		return Authorization{}, err
+	}
+	return s.accountRepoImpl.RetrieveAuthorization(authorizationID)
+}
+
+func (s *AccountService) ExpireAuthorizations(now time.Time) (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.ExpireAuthorizations(now)
+}
+
+// Pays out cash from the account through the vault, i.e. at an ATM
+func (s *AccountService) CashWithdraw(iban string, amount float64) (string, error) {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.CashWithdraw(iban, amount)
+}
+
+func (s *AccountService) CashDeposit(iban string, amount float64) (string, error) {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.CashDeposit(iban, amount)
+}
+
+func (s *AccountService) SetCashLimits(limits CashLimits) error {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.SetCashLimits(limits)
+}
+
+func (s *AccountService) CreatePaymentRequest(requester, payer string, amount float64, dueDate time.Time, memo string) (*PaymentRequest, error) {
+	if err := s.authorize(TransferPermission, requester); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.CreatePaymentRequest(requester, payer, amount, dueDate, memo)
+}
+
+func (s *AccountService) ListIncomingPaymentRequests(payer string) ([]PaymentRequest, error) {
+	if err := s.authorize(ReadPermission, payer); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListIncomingPaymentRequests(payer)
+}
+
+func (s *AccountService) AcceptPaymentRequest(id, payer string) (string, error) {
+	if err := s.authorize(TransferPermission, payer); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.AcceptPaymentRequest(id, payer)
+}
+
+func (s *AccountService) DeclinePaymentRequest(id, payer string) error {
+	if err := s.authorize(TransferPermission, payer); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.DeclinePaymentRequest(id, payer)
+}
+
+func (s *AccountService) ExpirePaymentRequests(now time.Time) (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.ExpirePaymentRequests(now)
+}
+
+// Beneficiaries saved for the account can be used as recipients of its transfers by their aliases
+func (s *AccountService) SaveBeneficiary(iban, alias, beneficiaryIban string) (*Beneficiary, error) {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.SaveBeneficiary(iban, alias, beneficiaryIban)
+}
+
+func (s *AccountService) ListBeneficiaries(iban string) ([]Beneficiary, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListBeneficiaries(iban)
+}
+
+func (s *AccountService) DeleteBeneficiary(iban, alias string) error {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.DeleteBeneficiary(iban, alias)
+}
+
+// Registers the phone number or email address as alias of the account in the proxy directory
+func (s *AccountService) RegisterAlias(alias, iban string) error {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RegisterAlias(alias, iban)
+}
+
+func (s *AccountService) ResolveAlias(alias string) (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.ResolveAlias(alias)
+}
+
+// Debits the sender for the transfer to the peer bank, see InterbankGateway to forward it
+func (s *AccountService) InitiateInterbankTransfer(sender, recipient, peerBank string, amount float64, details TransferDetails) (*InterbankTransfer, error) {
+	if err := s.authorize(TransferPermission, sender); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.InitiateInterbankTransfer(sender, recipient, peerBank, amount, details)
+}
+
+func (s *AccountService) SettleInterbankTransfer(correlationID string) error {
+	if err := s.authorize(TransferPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.SettleInterbankTransfer(correlationID)
+}
+
+func (s *AccountService) RefundInterbankTransfer(correlationID, reason string) error {
+	if err := s.authorize(TransferPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RefundInterbankTransfer(correlationID, reason)
+}
+
+func (s *AccountService) ReceiveInterbankTransfer(msg InterbankMessage) (InterbankAck, error) {
+	if err := s.authorize(TransferPermission); err != nil {
+		return InterbankAck{}, err
+	}
+	return s.accountRepoImpl.ReceiveInterbankTransfer(msg)
+}
+
+func (s *AccountService) RetrieveInterbankTransfer(correlationID string) (InterbankTransfer, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return InterbankTransfer{}, err
+	}
+	return s.accountRepoImpl.RetrieveInterbankTransfer(correlationID)
+}
+
+func (s *AccountService) OpenNostroAccount(bank, iban string, balance float64) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.OpenNostroAccount(bank, iban, balance)
+}
+
+func (s *AccountService) OpenVostroAccount(bank, iban string) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.OpenVostroAccount(bank, iban)
+}
+
+func (s *AccountService) RetrieveVostroBalance(bank string) (float64, string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return 0, "", err
+	}
+	return s.accountRepoImpl.RetrieveVostroBalance(bank)
+}
+
+// Compares our nostro balance with the reported one, see InterbankGateway.ReconcileNostro to fetch the balance from the peer
+func (s *AccountService) ReconcileNostro(bank string, reportedBalance float64) (*NostroReconciliation, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ReconcileNostro(bank, reportedBalance)
+}
+
+func (s *AccountService) SetEmissionPolicy(policy EmissionPolicy) error {
+	if err := s.authorize(EmitPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.SetEmissionPolicy(policy)
+}
+
+// Records the principal of the service as the operator and the reason in the money supply register, unlike EmitMoney
+func (s *AccountService) EmitMoneyWithReason(amount float64, reason SupplyReason) error {
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.audited(EmitAction, "", "", amount, err)
+	}
+	return s.audited(EmitAction, "", "", amount, s.accountRepoImpl.EmitMoneyWithReason(amount, s.principal.ID, reason))
+}
+
+func (s *AccountService) DestructMoneyWithReason(iban string, amount float64, reason SupplyReason) error {
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.audited(DestructAction, iban, "", amount, err)
+	}
+	return s.audited(DestructAction, iban, "", amount, s.accountRepoImpl.DestructMoneyWithReason(iban, amount, s.principal.ID, reason))
+}
+
+func (s *AccountService) RetrieveSupplyRegister(from, to time.Time) ([]SupplyRecord, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.RetrieveSupplyRegister(from, to)
+}
+
+// Emits the amount right away or returns the ID of the emission awaiting approval, the principal of the service is its initiator
+func (s *AccountService) RequestEmission(amount float64, reason SupplyReason) (string, error) {
+	if err := s.authorize(EmitPermission); err != nil {
+		return s.auditedID(EmitAction, "", "", amount, "", err)
+	}
+	id, err := s.accountRepoImpl.RequestEmission(amount, s.principal.ID, reason)
+	return s.auditedID(EmitAction, "", "", amount, id, err)
+}
+
+// The principal of the service must differ from the initiator of the emission
+func (s *AccountService) ApproveEmission(id string) error {
+	if err := s.authorize(EmitPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ApproveEmission(id, s.principal.ID)
+}
+
+func (s *AccountService) RejectEmission(id, reason string) error {
+	if err := s.authorize(EmitPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RejectEmission(id, s.principal.ID, reason)
+}
+
+func (s *AccountService) ListPendingEmissions() ([]EmissionApproval, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListPendingEmissions()
+}
+
+func (s *AccountService) MoneySupply() (*MoneySupply, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.MoneySupply()
+}
+
+// Opens a new account and funds it with newly emitted money in one atomic operation
+func (s *AccountService) OpenAccountWithInitialDeposit(amount float64) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return s.auditedOpening(nil, err)
+	}
+	return s.auditedOpening(s.accountRepoImpl.OpenAccountWithInitialDeposit(amount))
+}
+
+// Returns the ID of the transfer like TransferMoneyWithDetails
+// The initiator given by the request is replaced with the principal of the service
+func (s *AccountService) TransferMoneyJson(jsonStr string) (string, error) {
+	// Recording the request as far as it can be decoded
+	req, decodeErr := decodeMoneyTransferReq(jsonStr)
+	recipient := req.Recipient
+	if recipient == "" {
+		recipient = req.RecipientAlias
+	}
+	if err := s.authorize(TransferPermission, req.Sender); err != nil {
+		return s.auditedID(TransferAction, req.Sender, recipient, req.Amount, "", err)
+	}
+	if decodeErr == nil {
+		req.Initiator = s.principal.ID
+		if stamped, err := json.Marshal(req); err == nil {
+			jsonStr = string(stamped)
+		}
+	}
+	id, err := s.accountRepoImpl.TransferMoneyJson(jsonStr)
+	return s.auditedID(TransferAction, req.Sender, recipient, req.Amount, id, err)
+}
+
+func (s *AccountService) RetrieveAllAccountsAsJson() (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.RetrieveAllAccountsAsJson()
+}
+
+// Writes the same JSON array RetrieveAllAccountsAsJson returns without building the whole output in memory
+func (s *AccountService) WriteAllAccountsJson(writer io.Writer) error {
+	if err := s.authorize(ReadPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.WriteAllAccountsJson(writer)
+}
+
+func (s *AccountService) RetrieveAccount(iban string) (*AccountDetails, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.RetrieveAccount(iban)
+}
+
+func (s *AccountService) RetrieveAccountAsJson(iban string) (string, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.RetrieveAccountAsJson(iban)
+}
+
+func (s *AccountService) GetAccount(iban string) (Account, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return Account{}, err
+	}
+	return s.accountRepoImpl.GetAccount(iban)
+}
+
+// Calls the callback with a snapshot of every account until it returns false
+func (s *AccountService) ForEachAccount(callback func(Account) bool) error {
+	if err := s.authorize(ReadPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ForEachAccount(callback)
+}
+
+// Streams snapshots of all accounts through a channel which is closed once all accounts have been sent
+// Calling the returned function stops streaming early, consumers abandoning the channel must call it to release the producing goroutine
+// Principals not allowed to read all accounts get the channel closed right away
+func (s *AccountService) StreamAccounts() (<-chan Account, func()) {
+	ch := make(chan Account, accountIterationBatchSize)
+	done := make(chan struct{})
+	if err := s.authorize(ReadPermission); err != nil {
+		close(ch)
+		return ch, func() {}
+	}
+	go func() {
+		defer close(ch)
+		s.accountRepoImpl.ForEachAccount(func(acc Account) bool {
+			select {
+			case ch <- acc:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+	once := sync.Once{}
+	return ch, func() { once.Do(func() { close(done) }) }
+}
+
+// Returns details of all accounts matching every criterion set in the query, i.e. all blocked accounts with balance over 1000
+func (s *AccountService) FindAccounts(query AccountQuery) ([]AccountDetails, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.FindAccounts(query)
+}
+
+func (s *AccountService) RetrieveSnapshot(ibans ...string) (*RepositorySnapshot, error) {
+	if err := s.authorize(ReadPermission, ibans...); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.RetrieveSnapshot(ibans...)
+}
+
+func (s *AccountService) ListBlockedAccounts() ([]AccountDetails, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListBlockedAccounts()
+}
+
+func (s *AccountService) ListAccountsByType(accType AccountType) ([]AccountDetails, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListAccountsByType(accType)
+}
+
+// Lists accounts without any transaction since the given time, i.e. to notify their holders or to review them for closure
+func (s *AccountService) ListDormantAccounts(since time.Time) ([]AccountDetails, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListDormantAccounts(since)
+}
+
+func (s *AccountService) BlockAccount(iban string) error {
+	if err := s.authorize(BlockPermission); err != nil {
+		return s.audited(BlockAction, iban, "", 0, err)
+	}
+	return s.audited(BlockAction, iban, "", 0, s.accountRepoImpl.BlockAccount(iban))
+}
+
+func (s *AccountService) ActivateAccount(iban string) error {
+	if err := s.authorize(BlockPermission); err != nil {
+		return s.audited(ActivateAction, iban, "", 0, err)
+	}
+	return s.audited(ActivateAction, iban, "", 0, s.accountRepoImpl.ActivateAccount(iban))
+}
+
+// Zero expiry means the block stays until it is lifted with ActivateAccountAs
+func (s *AccountService) BlockAccountWithReason(iban string, reason BlockReason, until time.Time) error {
+	if err := s.authorize(BlockPermission); err != nil {
+		return s.audited(BlockAction, iban, "", 0, err)
+	}
+	return s.audited(BlockAction, iban, "", 0, s.accountRepoImpl.BlockAccountWithReason(iban, reason, until))
+}
+
+func (s *AccountService) ActivateAccountAs(iban string, authority Authority) error {
+	if err := s.authorize(BlockPermission); err != nil {
+		return s.audited(ActivateAction, iban, "", 0, err)
+	}
+	return s.audited(ActivateAction, iban, "", 0, s.accountRepoImpl.ActivateAccountAs(iban, authority))
+}
+
+func (s *AccountService) ExpireBlocks(now time.Time) (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.ExpireBlocks(now)
+}
+
+// Freezes debits or credits of the account only, NoRestriction lifts the restriction
+func (s *AccountService) RestrictAccount(iban string, restriction AccountRestriction) error {
+	if err := s.authorize(BlockPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.RestrictAccount(iban, restriction)
+}
+
+// Zero limit freezes the whole available balance
+func (s *AccountService) RegisterFreeze(iban, caseNumber, authority string, limit float64) (*LegalFreeze, error) {
+	if err := s.authorize(BlockPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.RegisterFreeze(iban, caseNumber, authority, limit)
+}
+
+func (s *AccountService) ReleaseFreeze(id, caseNumber string) error {
+	if err := s.authorize(BlockPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ReleaseFreeze(id, caseNumber)
+}
+
+func (s *AccountService) ListFreezes(iban string) ([]LegalFreeze, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListFreezes(iban)
+}
+
+// Moves the remaining balance to the sweep target account (or to the destruction account if empty IBAN is passed) and closes the account
+func (s *AccountService) CloseAccount(iban, sweepTargetIban string) error {
+	if err := s.authorize(BlockPermission); err != nil {
+		return s.audited(CloseAction, iban, sweepTargetIban, 0, err)
+	}
+	return s.audited(CloseAction, iban, sweepTargetIban, 0, s.accountRepoImpl.CloseAccount(iban, sweepTargetIban))
+}
+
+func (s *AccountService) RetrieveRemainderAccountIban() (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.RetrieveRemainderAccountIban()
+}
+
+// Returns IBAN of the system account declared in the chart of accounts for the given role
+func (s *AccountService) RetrieveSystemAccountIban(role SystemAccountRole) (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.RetrieveSystemAccountIban(role)
+}
+
+func (s *AccountService) ReconcileFractions() (*FractionsReconciliation, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ReconcileFractions()
+}
+
+func (s *AccountService) OpenAccountInCurrency(currency string) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return s.auditedOpening(nil, err)
+	}
+	return s.auditedOpening(s.accountRepoImpl.OpenAccountInCurrency(currency))
+}
+
+func (s *AccountService) OpenAccountWithMetadata(metadata map[string]string, tags []string) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return s.auditedOpening(nil, err)
+	}
+	return s.auditedOpening(s.accountRepoImpl.OpenAccountWithMetadata(metadata, tags))
+}
+
+// Retrying the call with the same client reference returns the account opened by the first call instead of a new one
+func (s *AccountService) OpenAccountWithReference(reference string) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return s.auditedOpening(nil, err)
+	}
+	return s.auditedOpening(s.accountRepoImpl.OpenAccountWithReference(reference))
+}
+
+// Registers an account under an existing IBAN instead of generating a new one, i.e. when migrating accounts from another system
+func (s *AccountService) OpenAccountWithIban(iban string) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return s.auditedOpening(nil, err)
+	}
+	return s.auditedOpening(s.accountRepoImpl.OpenAccountWithIban(iban))
+}
+
+// Loads accounts with their balances from CSV, i.e. to seed realistic datasets or migrate from another system
+func (s *AccountService) ImportAccountsCSV(reader io.Reader) (int, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.ImportAccountsCSV(reader)
+}
+
+// Dumps all accounts as CSV or newline-delimited JSON without building the whole output in memory
+func (s *AccountService) ExportAccounts(writer io.Writer, format ExportFormat) error {
+	if err := s.authorize(ReadPermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ExportAccounts(writer, format)
+}
+
+func (s *AccountService) UpdateAccountMetadata(iban string, metadata map[string]string, tags []string) error {
+	if err := s.authorize(OpenPermission, iban); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.UpdateAccountMetadata(iban, metadata, tags)
+}
+
+// Debits the sender in its currency and credits the recipient in its own currency at the rate quoted by the rate provider
+func (s *AccountService) ConvertAndTransfer(sender, recipient string, amount float64) error {
+	if err := s.authorize(TransferPermission, sender); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ConvertAndTransfer(sender, recipient, amount)
+}
+
+func (s *AccountService) RetrieveAllTransactionsAsJson() (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.RetrieveAllTransactionsAsJson()
+}
+
+// Returns up to limit most recent transactions debiting or crediting the account, the most recent first
+func (s *AccountService) RetrieveAccountTransactions(iban string, limit int) ([]Transaction, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.RetrieveAccountTransactions(iban, limit)
+}
+
+func (s *AccountService) RetrieveTransaction(id string) (Transaction, error) {
+	if err := s.authorizeTransfer(ReadPermission, id); err != nil {
+		return Transaction{}, err
+	}
+	return s.accountRepoImpl.RetrieveTransaction(id)
+}
+
+func (s *AccountService) RetrieveTransactionsBetween(from, to time.Time) ([]Transaction, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.RetrieveTransactionsBetween(from, to)
+}
+
+// Returns the balance of an off-balance ledger account such as ISSUED, FX-POSITION or LOANS in the given currency
+// Principals not allowed to read the ledger get zero balances
+func (s *AccountService) RetrieveLedgerBalance(account, currency string) float64 {
+	if err := s.authorize(ReadPermission); err != nil {
+		return 0
+	}
+	return s.accountRepoImpl.RetrieveLedgerBalance(account, currency)
+}
+
+// Returns journal entries of all transactions, debit and credit lines of every entry balance in each currency
+func (s *AccountService) RetrieveJournalAsJson() (string, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return "", err
+	}
+	return s.accountRepoImpl.RetrieveJournalAsJson()
+}
+
+// Checks that no money appeared or vanished outside emission and destruction, drift is reported as *IntegrityError
+func (s *AccountService) VerifyInvariants() (*InvariantReport, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.VerifyInvariants()
+}
+
+func (s *AccountService) SetHotAccount(iban string, hot bool) error {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.SetHotAccount(iban, hot)
+}
+
+func (s *AccountService) ReconcileHotAccounts() (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.ReconcileHotAccounts()
+}
+
+// Runs fractions reconciliation every interval in a background goroutine and passes each outcome to the given callback
+// Calling the returned function stops the reconciliation loop
+func (s *AccountService) StartFractionsReconciliation(interval time.Duration, callback func(*FractionsReconciliation, error)) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				res, err := s.ReconcileFractions()
+				if callback != nil {
+					callback(res, err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// --------------------------------------------------------
+// Defining in-memory implementation of account repository interface methods
+// Explicitly declaring EmissionAccount and DestructionAccount properties for the ease of access (no need to iterate over a collection to get them)
+type InMemoryAccountRepository struct {
+	EmissionAccount    *Account
+	DestructionAccount *Account
+	RemainderAccount   *Account
+	Accounts           *AccountShards     // accounts decalred as map sharded by IBAN for speed and simplicity but array could be used instead
+	TotalEmitted       float64            // exact (unrounded) sum of all emitted money, used to prove nothing appears or vanishes
+	ConvertedBalances  map[string]float64 // net amount per currency brought into (positive) or taken out of (negative) circulation by conversions
+	RateProvider       RateProvider       // source of exchange rates for conversions, conversions fail if not set
+	Transactions       []*Transaction
+	OpeningReferences  map[string]string             // client references of account openings mapped to IBANs of the opened accounts
+	TransferStatuses   map[string]TransferStatus     // statuses of transfers by their IDs, including failed ones
+	IdempotencyKeys    map[string]*idempotencyRecord // successful money movements by client idempotency keys
+	Holds              map[string]*Hold
+	Cards              map[string]*Card          // payment cards by their IDs
+	Authorizations     map[string]*Authorization // card purchases by the IDs of their authorizations
+	Freezes            map[string]*LegalFreeze   // legal freezes by their IDs, the frozen money is reserved by holds
+	ApprovalThreshold  float64                   // transfers of larger amounts wait for approval by a second principal, zero disables approvals
+	Approvals          map[string]*Approval
+	EmissionPolicy     *EmissionPolicy // limits of emissions, emissions are unlimited if not set
+	EmissionApprovals  map[string]*EmissionApproval
+	SupplyRegister     []*SupplyRecord       // emissions and destructions with their operators and reasons
+	Adjustments        []*Adjustment         // manual corrections of balances made by operators
+	ApiKeys            map[string]*ApiKey    // API keys by their IDs
+	Consents           map[string]*Consent   // consents granted to third-party clients by their IDs
+	OtpSender          OtpSender             // sends one-time codes confirming transfers, transfers are never challenged if not set
+	Challenges         map[string]*Challenge // transfers awaiting confirmation by their IDs
+	Encryptor          *FieldEncryptor       // encrypts holders and metadata of exported accounts, exports are written in plaintext if not set
+	EncryptBalances    bool                  // balances of exported accounts are encrypted as well, only used with the encryptor
+	PaymentRequests    map[string]*PaymentRequest
+	Beneficiaries      map[string]map[string]*Beneficiary // address books by IBANs of their accounts and aliases
+	Aliases            map[string]string                  // IBANs by phone numbers and email addresses registered in the proxy directory
+	InterbankTransfers map[string]*InterbankTransfer      // transfers exchanged with peer banks by their correlation IDs
+	Correspondents     map[string]*Correspondent          // correspondent accounts by bank codes of the peers
+	VelocityEngine     *VelocityEngine                    // evaluates patterns of debits before transfers, velocity rules are not applied if not set
+	EventBus           EventBus                           // receives notifications about settled transfers, nothing is published if not set
+	ScreeningProvider  ScreeningProvider                  // screens transfer counterparties against sanctions lists, screening is skipped if not set
+	ScreeningHits      []ScreeningHit                     // transfers executed despite a match, i.e. for review by compliance users
+	FeeSchedule        *FeeSchedule                       // fees charged on top of money movements, movements are free of charge if not set
+	DefaultLimits      AccountLimits                      // limits of ordinary accounts which do not have limits of their own
+	CashLimits         CashLimits                         // limits of cash withdrawals and deposits, cash operations are unlimited if not set
+	ApiKeyRateLimit    int                                // requests per minute of API keys issued without a rate limit of their own, zero disables it
+	FeeAccount         *Account                           // ordinary account credited with the fees
+	InterestRates      map[ProductType]float64            // annual interest rates in percent of eligible products
+	InterestAccruals   map[string]*InterestAccrual        // accruals by IBAN and date
+	InterestPostings   []*InterestPosting
+	Loans              map[string]*Loan               // loans by IBANs of their loan accounts
+	TermDeposits       map[string]*TermDeposit        // term deposits by IBANs of their deposit accounts
+	Journal            []*JournalEntry                // double-entry postings of the transactions, one entry per transaction
+	LedgerBalances     map[string]float64             // balances of off-balance ledger accounts by account and currency
+	SystemAccounts     map[SystemAccountRole]*Account // system accounts declared in the chart of accounts by their roles
+	// Held shared by transfers between accounts, which lock the accounts they touch and the journal instead, and by reads,
+	// exclusively by everything else, see locks.go
+	Mutex        sync.RWMutex
+	AccountLocks AccountLocks
+	JournalMutex sync.RWMutex // guards balances, the log and the journal while the repository mutex is held shared
+	// Events of the operation in progress held back until it completes, see holdEvents
+	heldEvents    []heldEvent
+	holdingEvents int
+}
+
+// Opens emission, destruction and remainder accounts only, see NewInMemoryAccountRepositoryWithChart to declare other system accounts
+func NewInMemoryAccountRepository(eIban, dIban string) *InMemoryAccountRepository {
+	r := newInMemoryAccountRepository()
+	r.openSystemAccounts(ChartOfAccounts{EmissionRole: eIban, DestructionRole: dIban, RemainderRole: RemainderAccountIban})
+	return r
+}
+
+// Helper function to create a repository without any accounts
+func newInMemoryAccountRepository() *InMemoryAccountRepository {
+	return &InMemoryAccountRepository{
+		Accounts:           NewAccountShards(),
+		SystemAccounts:     map[SystemAccountRole]*Account{},
+		ConvertedBalances:  map[string]float64{},
+		Transactions:       []*Transaction{},
+		OpeningReferences:  map[string]string{},
+		TransferStatuses:   map[string]TransferStatus{},
+		IdempotencyKeys:    map[string]*idempotencyRecord{},
+		Holds:              map[string]*Hold{},
+		Cards:              map[string]*Card{},
+		Authorizations:     map[string]*Authorization{},
+		Freezes:            map[string]*LegalFreeze{},
+		ApiKeys:            map[string]*ApiKey{},
+		Consents:           map[string]*Consent{},
+		Challenges:         map[string]*Challenge{},
+		Approvals:          map[string]*Approval{},
+		EmissionApprovals:  map[string]*EmissionApproval{},
+		PaymentRequests:    map[string]*PaymentRequest{},
+		Beneficiaries:      map[string]map[string]*Beneficiary{},
+		Aliases:            map[string]string{},
+		InterbankTransfers: map[string]*InterbankTransfer{},
+		Correspondents:     map[string]*Correspondent{},
+		InterestRates:      map[ProductType]float64{},
+		InterestAccruals:   map[string]*InterestAccrual{},
+		Loans:              map[string]*Loan{},
+		TermDeposits:       map[string]*TermDeposit{},
+		Journal:            []*JournalEntry{},
+		LedgerBalances:     map[string]float64{},
+	}
+}
+
+// Helper function to check if account with the given IBAN exists in the accounts map
+func (r *InMemoryAccountRepository) accountExists(iban string) bool {
+	if r.EmissionAccount != nil && r.EmissionAccount.Iban == iban {
+		return true
+	}
+	if r.DestructionAccount != nil && r.DestructionAccount.Iban == iban {
+		return true
+	}
+	if r.RemainderAccount != nil && r.RemainderAccount.Iban == iban {
+		return true
+	}
+	_, exists := r.Accounts.Get(iban)
+	return exists
+}
+
+func (r *InMemoryAccountRepository) RetrieveEmissionAccountIban() (string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	// Checking if emission account is set
+	if r.EmissionAccount == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if account set as emission account is of the correct type
+	if r.EmissionAccount.Type != MonetaryEmission {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	return r.EmissionAccount.Iban, nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveDestructionAccountIban() (string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	// Checking if destruction account is set
+	if r.DestructionAccount == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if account set as destruction account is of the correct type
+	if r.DestructionAccount.Type != MonetaryDestruction {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	return r.DestructionAccount.Iban, nil
+}
+
+func (r *InMemoryAccountRepository) EmitMoney(amount float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if the amount does not need approval, such emissions are requested with RequestEmission
+	if r.emissionNeedsApproval(amount) {
+		return fmt.Errorf(errorCodesToMessagesMap[EmissionApprovalRequiredError][locale()])
+	}
+	return r.emitMoney(amount, "", UnspecifiedReason)
+}
+
+// Expects the repository mutex to be held by the caller, so that the operation can be combined with others atomically
+// The emission is recorded in the money supply register with the operator and the reason
+func (r *InMemoryAccountRepository) emitMoney(amount float64, operator string, reason SupplyReason) error {
+	// Checking if emission account is set
+	if r.EmissionAccount == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if account set as emission account is of the correct type
+	if r.EmissionAccount.Type != MonetaryEmission {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if the account is not blocked
+	if r.EmissionAccount.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if money amount to emit is not negative
+	if amount < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if the emission policy allows the amount
+	if err := r.checkEmissionCaps(amount); err != nil {
+		return err
+	}
+
+	tx := &Transaction{Type: EmissionTransaction, Recipient: r.EmissionAccount.Iban, Amount: amount, Currency: r.EmissionAccount.Currency}
+	if err := r.post(tx, debitLedgerLine(IssuedLedgerAccount, r.EmissionAccount.Currency, amount), creditLine(r.EmissionAccount, amount)); err != nil {
+		return err
+	}
+	r.TotalEmitted += amount
+	r.recordSupplyChange(tx, r.EmissionAccount.Iban, operator, reason)
+
+	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
+	return nil
+}
+
+func (r *InMemoryAccountRepository) DestructMoney(iban string, amount float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.destructMoney(iban, amount, "", UnspecifiedReason)
+}
+
+// Expects the repository mutex to be held by the caller, so that the operation can be combined with others atomically
+// The destruction is recorded in the money supply register with the operator and the reason
+func (r *InMemoryAccountRepository) destructMoney(iban string, amount float64, operator string, reason SupplyReason) error {
+	iban = NormalizeIban(iban)
+
+	// Checking if destruction account is set
+	if r.DestructionAccount == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if account set as destruction account is of the correct type
+	if r.DestructionAccount.Type != MonetaryDestruction {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if destruction account is not blocked
+	if r.DestructionAccount.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if money amount to deduct is not negative
+	if amount < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if account associated with the given IBAN exists
+	if !r.accountExists(iban) {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	acc, _ := r.Accounts.Get(iban)
+	// Ensuring that we indeed got the correct account object
+	if acc.Iban != iban {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if the account is blocked (or is not active)
+	if acc.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if the account is not closed
+	if acc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the account is not a term deposit locked until maturity
+	if acc.Type == TermDepositAccount {
+		return fmt.Errorf(errorCodesToMessagesMap[TermDepositLockedError][locale()])
+	}
+	// Checking if the account holds money in the same currency as the destruction account
+	if acc.Currency != r.DestructionAccount.Currency {
+		return fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+	// Checking if debits from the account are not frozen
+	if err := checkDebitRestriction(acc); err != nil {
+		return err
+	}
+	// Checking if KYC status of the account allows the debit
+	if err := checkKycDebit(acc, amount); err != nil {
+		return err
+	}
+	// Checking if the debit fits into the limits of the account
+	if err := r.checkDebitLimits(acc, amount); err != nil {
+		return err
+	}
+	// Checking if the account balance is sufficient to deduct the given amount
+	if r, _ := roundAndExtractFractionsInCurrency(amount, acc.Currency); acc.AvailableBalance() < r {
+		return insufficientFundsError(acc)
+	}
+	// Checking if the balance stays at or above the floor of the account
+	if err := checkBalanceFloor(acc, roundToCurrency(amount, acc.Currency)); err != nil {
+		return err
+	}
+
+	tx := &Transaction{Type: DestructionTransaction, Sender: acc.Iban, Recipient: r.DestructionAccount.Iban, Amount: amount, Currency: acc.Currency}
+	if err := r.post(tx, transferLines(acc, r.DestructionAccount, amount)...); err != nil {
+		return err
+	}
+	acc.recordDebit(amount)
+	r.recordSupplyChange(tx, acc.Iban, operator, reason)
+
+	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
+	return nil
+}
+
+func (r *InMemoryAccountRepository) OpenAccount() (*Account, error) {
+	return r.OpenAccountInCurrency(DefaultCurrency)
+}
+
+// Either all of opening, emission and transfer happen or none of them, unlike calling the three methods one by one
+func (r *InMemoryAccountRepository) OpenAccountWithInitialDeposit(amount float64) (*Account, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Emitting the deposit first since it validates the emission account and the amount without side effects on failure
+	defer r.holdEvents()()
+	txCount := len(r.Transactions)
+	if err := r.emitMoney(amount, "", InitialDepositReason); err != nil {
+		return nil, err
+	}
+	// Reverting the emission if any of the subsequent steps fail
+	rollback := func() {
+		r.TotalEmitted -= amount
+		r.rollbackTo(txCount)
+	}
+	acc, err := r.openAccount(r.EmissionAccount.Currency, "")
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+	if err := r.transferMoney(r.EmissionAccount.Iban, acc.Iban, amount, TransferDetails{}); err != nil {
+		r.Accounts.Delete(acc.Iban)
+		rollback()
+		return nil, err
+	}
+	snapshot := acc.Snapshot()
+	return &snapshot, nil
+}
+
+func (r *InMemoryAccountRepository) OpenAccountInCurrency(currency string) (*Account, error) {
+	return r.OpenAccountWithOptions(AccountOptions{Currency: currency})
+}
+
+func (r *InMemoryAccountRepository) OpenAccountWithMetadata(metadata map[string]string, tags []string) (*Account, error) {
+	return r.OpenAccountWithOptions(AccountOptions{Metadata: metadata, Tags: tags})
+}
+
+func (r *InMemoryAccountRepository) OpenAccountWithReference(reference string) (*Account, error) {
+	return r.OpenAccountWithOptions(AccountOptions{ClientReference: reference})
+}
+
+func (r *InMemoryAccountRepository) OpenAccountWithIban(iban string) (*Account, error) {
+	// Rejecting an empty IBAN explicitly since options treat it as a request to generate one
+	if strings.TrimSpace(iban) == "" {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale()])
+	}
+	return r.OpenAccountWithOptions(AccountOptions{Iban: iban})
+}
+
+// Helper function to register a new active ordinary account in the given currency under the given IBAN, or a generated unique one if empty
+// Expects the repository mutex to be held by the caller and returns the stored object
+func (r *InMemoryAccountRepository) openAccount(currency, iban string) (*Account, error) {
+	return r.openAccountWith(currency, iban, nil)
+}
+
+// Helper function to register a new account like openAccount, the account is set up by the given function before it is stored,
+// expects the repository mutex to be held by the caller, at least shared, since nothing but the shard of the account is changed
+func (r *InMemoryAccountRepository) openAccountWith(currency, iban string, setup func(acc *Account)) (*Account, error) {
+	// Checking if the currency is known to the currency registry
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !IsValidCurrency(currency) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[UnsupportedCurrencyError][locale()])
+	}
+
+	iban = NormalizeIban(iban)
+	if iban != "" {
+		// Checking if the supplied IBAN is valid
+		if !IsValidIban(iban) {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidIbanError][locale()])
+		}
+		// Checking if the supplied IBAN is not taken by another account, including special ones
+		if r.accountExists(iban) {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[AccountAlreadyExistsError][locale()])
+		}
+	}
+	// Generating IBANs until the one not taken by another account comes up, generated IBANs are valid by construction
+	// Accounts may be opened along with each other, so the IBAN may be taken between the check and storing the account
+	supplied := iban != ""
+	for attempts := 0; ; attempts++ {
+		if !supplied {
+			if attempts >= maxIbanGenerationAttempts {
+				return nil, fmt.Errorf(errorCodesToMessagesMap[AccountCreationError][locale()])
+			}
+			generated, err := GenerateValidBelarusianIban()
+			if err != nil {
+				return nil, fmt.Errorf(errorCodesToMessagesMap[AccountCreationError][locale()])
+			}
+			if iban = generated; r.accountExists(iban) {
+				continue
+			}
+		}
+
+		// Creating a new account pending KYC verification and adding it to the account storage
+		acc := NewAccount(iban, Active, Ordinary, 0)
+		acc.Currency = currency
+		acc.Kyc = KycPending
+		if setup != nil {
+			setup(acc)
+		}
+		if r.Accounts.Insert(acc) {
+			return acc, nil
+		}
+		if supplied {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[AccountAlreadyExistsError][locale()])
+		}
+	}
+}
+
+// Limits protecting listings from oversized metadata
+const (
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 256
+	maxTagLength           = 64
+)
+
+func isValidMetadata(metadata map[string]string, tags []string) bool {
+	for k, v := range metadata {
+		if strings.TrimSpace(k) == "" || len(k) > maxMetadataKeyLength || len(v) > maxMetadataValueLength {
+			return false
+		}
+	}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" || len(tag) > maxTagLength {
+			return false
+		}
+	}
+	return true
+}
+
+// Helper function to trim tags and drop duplicates while preserving their order
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// Merges the given metadata into the account metadata, entries with empty values are removed
+// Tags are replaced unless nil is passed
+func (r *InMemoryAccountRepository) UpdateAccountMetadata(iban string, metadata map[string]string, tags []string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Ensuring that we indeed got the correct account object
+	if acc.Iban != iban {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if metadata and tags are well-formed
+	if !isValidMetadata(metadata, tags) {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidMetadataError][locale()])
+	}
+
+	for k, v := range metadata {
+		if v == "" {
+			delete(acc.Metadata, k)
+			continue
+		}
+		acc.Metadata[k] = v
+	}
+	if tags != nil {
+		acc.Tags = normalizeTags(tags)
+	}
+	return nil
+}
+
+func (r *InMemoryAccountRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	return r.TransferMoneyWithDetails(sender, recipient, amount, TransferDetails{})
+}
+
+func (r *InMemoryAccountRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	// Transfers which neither wait for approval or confirmation nor go through velocity rules run in parallel
+	r.Mutex.RLock()
+	if sender, recipient := NormalizeIban(sender), NormalizeIban(r.resolveBeneficiary(sender, recipient)); r.isPlainTransfer(sender, recipient, amount) {
+		defer r.Mutex.RUnlock()
+		return r.transferConcurrently(sender, recipient, amount, details)
+	}
+	r.Mutex.RUnlock()
+
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.submitTransfer(sender, recipient, amount, details)
+}
+
+// Expects the repository mutex to be held by the caller, so that the operation can be combined with others atomically
+func (r *InMemoryAccountRepository) transferMoney(sender, recipient string, amount float64, details TransferDetails) error {
+	transfer, err := r.prepareTransfer(sender, recipient, amount, details)
+	if err != nil {
+		return err
+	}
+	return r.commitTransfer(transfer)
+}
+
+// Transfer which passed the checks and is ready to be recorded
+type preparedTransfer struct {
+	sAcc         *Account
+	rAcc         *Account
+	amount       float64
+	details      TransferDetails
+	fee          float64 // fee charged to the sender and the part of it deducted from the credited amount
+	deducted     float64
+	acquiringFee float64 // fee charged to the recipient if it is a merchant
+	outcome      ScreeningOutcome
+	entry        string // matched entry of the sanctions list if the transfer is flagged
+}
+
+// Helper function to run the checks of the transfer without changing anything, expects the repository mutex to be held by
+// the caller, either exclusively or shared along with the locks of the accounts of the transfer, see locks.go
+func (r *InMemoryAccountRepository) prepareTransfer(sender, recipient string, amount float64, details TransferDetails) (*preparedTransfer, error) {
+	sender = NormalizeIban(sender)
+	recipient = NormalizeIban(recipient)
+
+	// Checking if reference, memo and purpose code are well-formed
+	details, ok := normalizeTransferDetails(details)
+	if !ok {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTransferDetailsError][locale()])
+	}
+
+	// Checking if sender account exists
+	sAcc, sExists := r.Accounts.Get(sender)
+	if !sExists || sAcc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Ensuring that we indeed got the correct account object
+	if sAcc.Iban != sender {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if sender account is not blocked
+	if sAcc.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if sender account is not closed
+	if sAcc.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if money amount to transfer is not negative
+	if amount < 0 {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	// Checking if debits from sender account are not frozen
+	if err := checkDebitRestriction(sAcc); err != nil {
+		return nil, err
+	}
+	// Checking if KYC status of sender account allows the debit
+	if err := checkKycDebit(sAcc, amount); err != nil {
+		return nil, err
+	}
+	// Checking if the debit fits into the limits of sender account
+	if err := r.checkDebitLimits(sAcc, amount); err != nil {
+		return nil, err
+	}
+	//Checking if sender has sufficient balance to transfer the amount to recipient
+	if r, _ := roundAndExtractFractionsInCurrency(amount, sAcc.Currency); sAcc.AvailableBalance() < r {
+		return nil, insufficientFundsError(sAcc)
+	}
+	// Checking if recipient account exists
+	rAcc, rExists := r.Accounts.Get(recipient)
+	if !rExists {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Ensuring that we indeed got the correct account object
+	if rAcc.Iban != recipient {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if recipient account is not blocked
+	if rAcc.Status == Blocked {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if recipient account is not closed
+	if rAcc.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if credits to recipient account are not frozen
+	if err := checkCreditRestriction(rAcc); err != nil {
+		return nil, err
+	}
+	// Checking if both accounts hold money in the same currency, ConvertAndTransfer should be used otherwise
+	if sAcc.Currency != rAcc.Currency {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+	// Checking if product rules of sender account allow the transfer
+	if err := checkProductTransfer(sAcc, rAcc); err != nil {
+		return nil, err
+	}
+	// Checking if neither of the accounts is a term deposit, deposits are only moved on opening, maturity and early withdrawal
+	if sAcc.Type == TermDepositAccount {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[TermDepositLockedError][locale()])
+	}
+	if rAcc.Type == TermDepositAccount {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if neither of the accounts is a loan or a nostro account, loans are only moved by disbursements and installments,
+	// nostro accounts by transfers to their banks
+	if sAcc.Type == LoanAccount || rAcc.Type == LoanAccount || sAcc.Type == NostroAccount || rAcc.Type == NostroAccount {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if neither of the counterparties matches sanctions lists
+	outcome, entry := r.screenTransfer(sAcc, rAcc)
+	if outcome == ScreeningReject {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[SanctionsMatchError][locale()])
+	}
+	// Checking if the fee for the transfer can be paid by the bearer
+	fee, deducted, err := r.feeFor(sAcc, TransferTransaction, amount, details.FeeBearer)
+	if err != nil {
+		return nil, err
+	}
+	// Checking if the acquiring fee can be deducted from the payment if the recipient is a merchant
+	acquiringFee, err := r.acquiringFeeFor(rAcc, amount-deducted)
+	if err != nil {
+		return nil, err
+	}
+	// Checking if both balances stay within the floor and the ceiling of the accounts
+	rounded := roundToCurrency(amount, sAcc.Currency)
+	if err := checkBalanceFloor(sAcc, rounded+fee-deducted); err != nil {
+		return nil, err
+	}
+	if err := checkBalanceCeiling(rAcc, rounded-deducted); err != nil {
+		return nil, err
+	}
+	// TODO: prohibit transfer for certain account types if it makes sense (i.e., cannot send from ordinary account to monetary emission account)
+
+	return &preparedTransfer{sAcc, rAcc, amount, details, fee, deducted, acquiringFee, outcome, entry}, nil
+}
+
+// Helper function to record the prepared transfer, expects the repository mutex to be held by the caller, either
+// exclusively or shared along with the locks of the accounts of the transfer and the journal mutex
+func (r *InMemoryAccountRepository) commitTransfer(t *preparedTransfer) error {
+	sAcc, rAcc, amount, details := t.sAcc, t.rAcc, t.amount, t.details
+	sender, recipient := sAcc.Iban, rAcc.Iban
+
+	txCount := len(r.Transactions)
+	feeTx, err := r.chargeFee(sAcc, t.fee)
+	if err != nil {
+		return err
+	}
+	acquiringTx, err := r.chargeAcquiringFee(rAcc, t.acquiringFee)
+	if err != nil {
+		r.rollbackTo(txCount)
+		return err
+	}
+	amount -= t.deducted
+	tx := &Transaction{Type: TransferTransaction, Sender: sender, Recipient: recipient, Amount: amount, Currency: sAcc.Currency, Reference: details.Reference, Memo: details.Memo, PurposeCode: details.PurposeCode, FeeBearer: details.FeeBearer}
+	if err := r.post(tx, transferLines(sAcc, rAcc, amount)...); err != nil {
+		r.rollbackTo(txCount)
+		return err
+	}
+	sAcc.recordDebit(amount)
+	if r.VelocityEngine != nil {
+		r.VelocityEngine.Observe(sender, recipient, clock.Now().UTC())
+	}
+	linkFee(feeTx, tx)
+	if acquiringTx != nil {
+		acquiringTx.FeeOf = tx.Ulid
+	}
+	r.publish(TransferSettledEvent, TransferEventPayload{tx.Ulid, sender, recipient, amount, sAcc.Currency, details.TraceParent})
+	if t.outcome == ScreeningFlag {
+		hit := ScreeningHit{tx.Ulid, sender, recipient, t.entry, tx.Timestamp}
+		r.ScreeningHits = append(r.ScreeningHits, hit)
+		r.publish(ScreeningHitEvent, hit)
+	}
+
+	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
+	return nil
+}
+
+// Reference, memo, purpose code and idempotency key are optional, i.e. {"sender": "...", "recipient": "...", "amount": 10, "purpose_code": "SALA"}
+// Money transfer request accepted by TransferMoneyJson
+type moneyTransferReq struct {
+	Sender         string  `json:"sender"`
+	Recipient      string  `json:"recipient"`
+	RecipientAlias string  `json:"recipient_alias"` // phone number or email address registered for the recipient, instead of its IBAN
+	Amount         float64 `json:"amount"`
+	Reference      string  `json:"reference"`
+	Memo           string  `json:"memo"`
+	PurposeCode    string  `json:"purpose_code"`
+	IdempotencyKey string  `json:"idempotency_key"`
+	Initiator      string  `json:"initiator"`
+	FeeBearer      string  `json:"fee_bearer"`
+}
+
+func decodeMoneyTransferReq(jsonStr string) (moneyTransferReq, error) {
+	var req moneyTransferReq
+	if err := json.Unmarshal([]byte(jsonStr), &req); err != nil {
+		return req, fmt.Errorf(errorCodesToMessagesMap[MoneyTransferJsonError][locale()])
+	}
+	return req, nil
+}
+
+func (req moneyTransferReq) details() TransferDetails {
+	return TransferDetails{Reference: req.Reference, Memo: req.Memo, PurposeCode: req.PurposeCode, Initiator: req.Initiator, FeeBearer: parseFeeBearer(req.FeeBearer)}
+}
+
+func (r *InMemoryAccountRepository) TransferMoneyJson(jsonStr string) (string, error) {
+	req, err := decodeMoneyTransferReq(jsonStr)
+	if err != nil {
+		return "", err
 	}
-	return r.DestructionAccount.Iban, nil
+	// Resolving the recipient alias through the proxy directory, the request cannot name both the recipient and its alias
+	if req.RecipientAlias != "" {
+		if req.Recipient != "" {
+			return "", fmt.Errorf(errorCodesToMessagesMap[MoneyTransferJsonError][locale()])
+		}
+		if req.Recipient, err = r.ResolveAlias(req.RecipientAlias); err != nil {
+			return "", err
+		}
+	}
+	return r.TransferMoneyIdempotent(req.IdempotencyKey, req.Sender, req.Recipient, req.Amount, req.details())
 }
 
-func (r *InMemoryAccountRepository) EmitMoney(amount float64) error {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-
-	// Checking if emission account is set
-	if r.EmissionAccount == nil {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+// Accounts are serialized from a snapshot, so that transfers do not wait for the serialization, see snapshots.go
+func (r *InMemoryAccountRepository) RetrieveAllAccountsAsJson() (string, error) {
+	output := strings.Builder{}
+	if err := r.WriteAllAccountsJson(&output); err != nil {
+		return "", err
 	}
-	// Checking if account set as emission account is of the correct type
-	if r.EmissionAccount.Type != MonetaryEmission {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale])
+	return output.String(), nil
+}
+
+// Writes all accounts to the writer as one JSON array of AccountDetails, system accounts first
+// Details are encoded and written one account at a time rather than collected into a slice and marshalled at once, only the
+// snapshot of the accounts is held in memory
+func (r *InMemoryAccountRepository) WriteAllAccountsJson(writer io.Writer) error {
+	snapshot, err := r.RetrieveSnapshot()
+	if err != nil {
+		return err
 	}
-	// Checking if the account is not blocked
-	if r.EmissionAccount.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale])
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteByte('[')
+	for i := range snapshot.Accounts {
+		if i > 0 {
+			buffered.WriteByte(',')
+		}
+		output, err := json.Marshal(snapshot.Accounts[i].Details())
+		if err != nil {
+			return fmt.Errorf(errorCodesToMessagesMap[AccountDetailsJsonError][locale()])
+		}
+		buffered.Write(output)
 	}
-	// Checking if money amount to emit is not negative
-	if amount < 0 {
-		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale])
+	buffered.WriteByte(']')
+	// Errors of writing are sticky, so checking the flush is enough
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountsExportError][locale()])
 	}
-
-	r.EmissionAccount.Add(amount)
-
-	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
 	return nil
 }
 
-func (r *InMemoryAccountRepository) DestructMoney(iban string, amount float64) error {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-
-	iban = strings.Replace(iban, " ", "", -1)
+// Criteria to search accounts by, nil (or empty) criteria are not applied
+// Balance boundaries are inclusive
+type AccountQuery struct {
+	Status     *AccountStatus
+	Type       *AccountType
+	MinBalance *float64
+	MaxBalance *float64
+	IbanPrefix string
+	Tags       []string          // accounts must have all of the tags
+	Metadata   map[string]string // accounts must have all of the key-value pairs
+	CustomerID string
+}
 
-	// Checking if destruction account is set
-	if r.DestructionAccount == nil {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+func (q AccountQuery) matches(acc *Account) bool {
+	if q.Status != nil && acc.Status != *q.Status {
+		return false
 	}
-	// Checking if account set as destruction account is of the correct type
-	if r.DestructionAccount.Type != MonetaryDestruction {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale])
+	if q.Type != nil && acc.Type != *q.Type {
+		return false
 	}
-	// Checking if destruction account is not blocked
-	if r.DestructionAccount.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale])
+	if q.MinBalance != nil && acc.Balance < *q.MinBalance {
+		return false
 	}
-	// Checking if money amount to deduct is not negative
-	if amount < 0 {
-		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale])
+	if q.MaxBalance != nil && acc.Balance > *q.MaxBalance {
+		return false
 	}
-	// Checking if account associated with the given IBAN exists
-	if !r.accountExists(iban) {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+	if q.IbanPrefix != "" && !strings.HasPrefix(acc.Iban, NormalizeIban(q.IbanPrefix)) {
+		return false
 	}
-	acc := r.Accounts[iban]
-	// Ensuring that we indeed got the correct account object
-	if acc.Iban != iban {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale])
+	if q.CustomerID != "" && acc.CustomerID != q.CustomerID {
+		return false
 	}
-	// Checking if the account is blocked (or is not active)
-	if acc.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale])
+	for _, tag := range q.Tags {
+		if !acc.HasTag(tag) {
+			return false
+		}
 	}
-	// Checking if the account balance is sufficient to deduct the given amount
-	if r, _ := roundAndExtractFractions(amount); acc.Balance < r {
-		return fmt.Errorf(errorCodesToMessagesMap[InsufficientAccountBalanceError][locale])
+	for k, v := range q.Metadata {
+		if acc.Metadata[k] != v {
+			return false
+		}
 	}
+	return true
+}
 
-	acc.Deduct(amount)
-	r.Accounts[acc.Iban] = acc
-	r.DestructionAccount.Add(amount)
+// Accounts are matched against a snapshot, so that transfers do not wait for the search, see snapshots.go
+func (r *InMemoryAccountRepository) FindAccounts(query AccountQuery) ([]AccountDetails, error) {
+	// Checking if balance boundaries make sense
+	if query.MinBalance != nil && query.MaxBalance != nil && *query.MinBalance > *query.MaxBalance {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidAccountQueryError][locale()])
+	}
 
-	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
-	return nil
-}
+	// Looking only at the accounts with the given status or of the given type if any, see indexes.go
+	if query.Status != nil || query.Type != nil {
+		return r.findIndexed(query), nil
+	}
 
-func (r *InMemoryAccountRepository) OpenAccount() (*Account, error) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+	snapshot, err := r.RetrieveSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	found := []AccountDetails{}
+	for i := range snapshot.Accounts {
+		if acc := &snapshot.Accounts[i]; query.matches(acc) {
+			found = append(found, acc.Details())
+		}
+	}
+	return found, nil
+}
 
-	iban := ""
-	var err error = nil
-	// Performing one or more attempts to generate a valid and unique Belarusian IBAN
-	for iban == "" || (iban != "" && r.accountExists(iban)) {
-		iban, err = GenerateValidBelarusianIban()
-		if err != nil {
-			return nil, fmt.Errorf(errorCodesToMessagesMap[AccountCreationError][locale])
+// Number of accounts copied per lock acquisition while iterating over accounts
+const accountIterationBatchSize = 1000
+
+// Iterates over special accounts first and ordinary accounts afterwards in the same order as RetrieveAllAccountsAsJson
+// Only IBANs are collected upfront, accounts are copied in batches and the callback is called without holding the mutex,
+// so that the callback may use the repository and other operations are not blocked for the whole iteration
+func (r *InMemoryAccountRepository) ForEachAccount(callback func(Account) bool) error {
+	r.Mutex.RLock()
+	ibans := make([]string, 0, r.Accounts.Len())
+	for _, acc := range []*Account{r.EmissionAccount, r.DestructionAccount, r.RemainderAccount} {
+		if acc != nil {
+			ibans = append(ibans, acc.Iban)
+		}
+	}
+	for _, acc := range r.Accounts.All() {
+		if acc != r.EmissionAccount && acc != r.DestructionAccount && acc != r.RemainderAccount {
+			ibans = append(ibans, acc.Iban)
 		}
 	}
+	r.Mutex.RUnlock()
 
-	// Creating a new account and adding it to the account storage
-	acc := NewAccount(iban, Active, Ordinary, 0)
-	r.Accounts[iban] = acc
-	return acc, nil
+	batch := make([]Account, 0, accountIterationBatchSize)
+	for start := 0; start < len(ibans); start += accountIterationBatchSize {
+		end := start + accountIterationBatchSize
+		if end > len(ibans) {
+			end = len(ibans)
+		}
+		batch = batch[:0]
+		r.Mutex.RLock()
+		r.JournalMutex.RLock()
+		for _, iban := range ibans[start:end] {
+			if acc, exists := r.Accounts.Get(iban); exists && acc != nil {
+				batch = append(batch, acc.Snapshot())
+			}
+		}
+		r.JournalMutex.RUnlock()
+		r.Mutex.RUnlock()
+		for _, acc := range batch {
+			if !callback(acc) {
+				return nil
+			}
+		}
+	}
+	return nil
 }
 
-func (r *InMemoryAccountRepository) TransferMoney(sender, recipient string, amount float64) error {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+func (r *InMemoryAccountRepository) RetrieveAccount(iban string) (*AccountDetails, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
 
-	sender = strings.Replace(sender, " ", "", -1)
-	recipient = strings.Replace(recipient, " ", "", -1)
+	iban = NormalizeIban(iban)
 
-	// Checking if sender account exists
-	sAcc, sExists := r.Accounts[sender]
-	if !sExists || sAcc == nil {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
-	}
-	// Ensuring that we indeed got the correct account object
-	if sAcc.Iban != sender {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale])
-	}
-	// Checking if sender account is not blocked
-	if sAcc.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale])
-	}
-	// Checking if money amount to transfer is not negative
-	if amount < 0 {
-		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale])
-	}
-	//Checking if sender has sufficient balance to transfer the amount to recipient
-	if r, _ := roundAndExtractFractions(amount); sAcc.Balance < r {
-		return fmt.Errorf(errorCodesToMessagesMap[InsufficientAccountBalanceError][locale])
+	// Checking if account associated with the given IBAN exists
+	if !r.accountExists(iban) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
 	}
-	// Checking if recipient account exists
-	rAcc, rExists := r.Accounts[recipient]
-	if !rExists {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+	acc, _ := r.Accounts.Get(iban)
+	// Ensuring that account object is not nil
+	if acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
 	}
 	// Ensuring that we indeed got the correct account object
-	if rAcc.Iban != recipient {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale])
+	if acc.Iban != iban {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
 	}
-	// Checking if recipient account is not blocked
-	if rAcc.Status == Blocked {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale])
+	// Checking if the account is blocked (or is not active)
+	if acc.Status == Blocked { // alternatively can be "if acc.Status != Active" depending on expected behavior
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
 	}
-	// TODO: prohibit transfer for certain account types if it makes sense (i.e., cannot send from ordinary account to monetary emission account)
-
-	sAcc.Deduct(amount)
-	r.Accounts[sender] = sAcc
-	rAcc.Add(amount)
-	r.Accounts[recipient] = rAcc
 
-	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
-	return nil
+	details := acc.Details()
+	return &details, nil
 }
 
-func (r *InMemoryAccountRepository) TransferMoneyJson(jsonStr string) error {
-	type moneyTransferReq struct {
-		Sender    string  `json:"sender"`
-		Recipient string  `json:"recipient"`
-		Amount    float64 `json:"amount"`
+// Unlike RetrieveAccount, returns the account regardless of its status since it is meant for internal consumers
+func (r *InMemoryAccountRepository) GetAccount(iban string) (Account, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return Account{}, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
 	}
-	var req moneyTransferReq
-	if err := json.Unmarshal([]byte(jsonStr), &req); err != nil {
-		return fmt.Errorf(errorCodesToMessagesMap[MoneyTransferJsonError][locale])
+	// Ensuring that we indeed got the correct account object
+	if acc.Iban != iban {
+		return Account{}, fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
 	}
-	return r.TransferMoney(req.Sender, req.Recipient, req.Amount)
+	return acc.Snapshot(), nil
 }
 
-func (r *InMemoryAccountRepository) RetrieveAllAccountsAsJson() (string, error) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-	type accountDetails struct {
-		Iban      string  `json:"iban"`
-		Balance   float64 `json:"balance"`
-		Fractions float64 `json:"fractions"`
-		Status    string  `json:"status"`
-	}
-	allAccountDetails := []accountDetails{}
-	if r.EmissionAccount != nil {
-		allAccountDetails = append(allAccountDetails, accountDetails{r.EmissionAccount.Iban, r.EmissionAccount.Balance, r.EmissionAccount.Fractions, accountStatusCodeToNameMap[r.EmissionAccount.Status][locale]})
-	}
-	if r.DestructionAccount != nil {
-		allAccountDetails = append(allAccountDetails, accountDetails{r.DestructionAccount.Iban, r.DestructionAccount.Balance, r.DestructionAccount.Fractions, accountStatusCodeToNameMap[r.DestructionAccount.Status][locale]})
-	}
-	for _, acc := range r.Accounts {
-		if acc != r.EmissionAccount && acc != r.DestructionAccount {
-			allAccountDetails = append(allAccountDetails, accountDetails{acc.Iban, acc.Balance, acc.Fractions, accountStatusCodeToNameMap[acc.Status][locale]})
-		}
+func (r *InMemoryAccountRepository) RetrieveAccountAsJson(iban string) (string, error) {
+	details, err := r.RetrieveAccount(iban)
+	if err != nil {
+		return "", err
 	}
-	output, err := json.Marshal(allAccountDetails)
+	output, err := json.Marshal(details)
 	if err != nil {
-		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDetailsJsonError][locale])
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDetailsJsonError][locale()])
 	}
 	return string(output), nil
 }
@@ -622,64 +3247,324 @@ func (r *InMemoryAccountRepository) RetrieveAllAccountsAsJson() (string, error)
 func (r *InMemoryAccountRepository) BlockAccount(iban string) error {
 	r.Mutex.Lock()
 	defer r.Mutex.Unlock()
+	return r.blockAccount(iban, UnspecifiedBlock, time.Time{})
+}
 
-	iban = strings.Replace(iban, " ", "", -1)
+// Expects the repository mutex to be held by the caller, zero expiry means the block stays until it is lifted
+func (r *InMemoryAccountRepository) blockAccount(iban string, reason BlockReason, until time.Time) error {
+	iban = NormalizeIban(iban)
 
 	// Checking if account associated with the given IBAN exists
 	if !r.accountExists(iban) {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
 	}
-	acc := r.Accounts[iban]
+	acc, _ := r.Accounts.Get(iban)
 	// Ensuring that account object is not nil
 	if acc == nil {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
 	}
 	// Ensuring that we indeed got the correct account object
 	if acc.Iban != iban {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale])
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if the account is not closed, closed accounts cannot be reopened
+	if acc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the account is not blocked for another reason, the existing block has to be lifted first
+	if acc.Status == Blocked && acc.BlockReason != reason {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
 	}
 
 	acc.Block()
-	r.Accounts[acc.Iban] = acc
+	acc.BlockReason = reason
+	acc.BlockedUntil = until
+	r.Accounts.Put(acc)
 	return nil
 }
 
+// Lifts the block acting as an operator, blocks for fraud or by court order cannot be lifted this way, see ActivateAccountAs
 func (r *InMemoryAccountRepository) ActivateAccount(iban string) error {
 	r.Mutex.Lock()
 	defer r.Mutex.Unlock()
+	return r.activateAccount(iban, OperatorAuthority)
+}
 
-	iban = strings.Replace(iban, " ", "", -1)
+// Expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) activateAccount(iban string, authority Authority) error {
+	iban = NormalizeIban(iban)
 
 	// Checking if account associated with the given IBAN exists
 	if !r.accountExists(iban) {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
 	}
-	acc := r.Accounts[iban]
+	acc, _ := r.Accounts.Get(iban)
 	// Ensuring that account object is not nil
 	if acc == nil {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale])
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
 	}
 	// Ensuring that we indeed got the correct account object
 	if acc.Iban != iban {
-		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale])
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if the account is not closed, closed accounts cannot be reopened
+	if acc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the authority may lift the block
+	if acc.Status == Blocked && !blockLiftAuthorities[acc.BlockReason][authority] {
+		return fmt.Errorf(errorCodesToMessagesMap[BlockLiftNotAllowedError][locale()])
 	}
 
 	acc.Activate()
-	r.Accounts[acc.Iban] = acc
+	r.Accounts.Put(acc)
+	return nil
+}
+
+// Closed accounts stay in the repository, so that they remain visible in listings and transaction history
+// KYC limits are not applied to the sweep since money of a rejected or pending account has to be moved somewhere anyway
+func (r *InMemoryAccountRepository) CloseAccount(iban, sweepTargetIban string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	sweepTargetIban = NormalizeIban(sweepTargetIban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Ensuring that we indeed got the correct account object
+	if acc.Iban != iban {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	// Checking if the account is an ordinary one, special accounts cannot be closed
+	if acc.Type != Ordinary {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if the account is not blocked, blocked accounts have to be activated first
+	if acc.Status == Blocked {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	// Checking if the account is not closed already
+	if acc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if no money is reserved by holds, they have to be captured or released first
+	if acc.Held != 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountHasActiveHoldsError][locale()])
+	}
+	// Checking if debits from the account are not frozen, sweeping the balance is a debit as well
+	if err := checkDebitRestriction(acc); err != nil {
+		return err
+	}
+	// Checking if the account is not overdrawn, the overdraft has to be repaid first
+	if acc.Balance < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[InsufficientAccountBalanceError][locale()])
+	}
+
+	// Resolving the account to sweep the remaining balance to, destruction account is used by default
+	target := r.DestructionAccount
+	txType := DestructionTransaction
+	if sweepTargetIban != "" {
+		target, exists = r.Accounts.Get(sweepTargetIban)
+		if !exists || target == nil {
+			return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+		}
+		txType = TransferTransaction
+	}
+	if target == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the sweep target is a different account able to receive the balance
+	if target == acc {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIbanMismatchError][locale()])
+	}
+	if target.Status == Blocked {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	if target.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	if target.Currency != acc.Currency {
+		return fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+	if target.Type == LoanAccount || target.Type == TermDepositAccount || target.Type == NostroAccount {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	if err := checkBalanceCeiling(target, acc.Balance); err != nil {
+		return err
+	}
+	// Sweeping the balance to another account is a transfer, so it goes through the checks of transfers except the floor
+	// of the balance, which the closing account is allowed to leave
+	outcome, entry := ScreeningClear, ""
+	if txType == TransferTransaction && acc.Balance != 0 {
+		if err := checkKycDebit(acc, acc.Balance); err != nil {
+			return err
+		}
+		if err := r.checkDebitLimits(acc, acc.Balance); err != nil {
+			return err
+		}
+		if err := checkCreditRestriction(target); err != nil {
+			return err
+		}
+		if outcome, entry = r.screenTransfer(acc, target); outcome == ScreeningReject {
+			return fmt.Errorf(errorCodesToMessagesMap[SanctionsMatchError][locale()])
+		}
+	}
+
+	if balance := acc.Balance; balance != 0 {
+		tx := &Transaction{Type: txType, Sender: acc.Iban, Recipient: target.Iban, Amount: balance, Currency: acc.Currency}
+		if err := r.post(tx, transferLines(acc, target, balance)...); err != nil {
+			return err
+		}
+		if txType == TransferTransaction {
+			acc.recordDebit(balance)
+		}
+		if outcome == ScreeningFlag {
+			hit := ScreeningHit{tx.Ulid, acc.Iban, target.Iban, entry, tx.Timestamp}
+			r.ScreeningHits = append(r.ScreeningHits, hit)
+			r.publish(ScreeningHitEvent, hit)
+		}
+	}
+	acc.Status = Closed
+	r.Accounts.Put(acc)
 	return nil
 }
 
+func (r *InMemoryAccountRepository) RetrieveRemainderAccountIban() (string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	// Checking if remainder account is set
+	if r.RemainderAccount == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if account set as remainder account is of the correct type
+	if r.RemainderAccount.Type != MonetaryRemainder {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	return r.RemainderAccount.Iban, nil
+}
+
+// Result of sweeping sub-cent fractions into the remainder account
+// Only accounts in the remainder account currency take part in the reconciliation
+// Remainder can be negative since rounding half away from zero credits accounts slightly more than the exact amount
+type FractionsReconciliation struct {
+	Swept           float64 `json:"swept"`            // fractions moved to the remainder account during this run
+	RoundedBalances float64 `json:"rounded_balances"` // sum of rounded balances of all accounts except the remainder one
+	Remainder       float64 `json:"remainder"`        // remainder account balance plus its own sub-cent fractions
+	TotalEmitted    float64 `json:"total_emitted"`
+	Converted       float64 `json:"converted"`   // net amount brought into circulation by currency conversions
+	Adjusted        float64 `json:"adjusted"`    // net amount brought into circulation by manual adjustments
+	Discrepancy     float64 `json:"discrepancy"` // rounded balances plus remainder minus emitted, converted and adjusted money, expected to be zero
+	Balanced        bool    `json:"balanced"`
+}
+
+// Maximum float drift tolerated when comparing sums of balances with the emitted money
+const reconciliationTolerance = 1e-6
+
+func (r *InMemoryAccountRepository) ReconcileFractions() (*FractionsReconciliation, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	// Checking if remainder account is set
+	if r.RemainderAccount == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if account set as remainder account is of the correct type
+	if r.RemainderAccount.Type != MonetaryRemainder {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+
+	// Sweeping fractions of every other account in the same currency into the remainder account
+	res := &FractionsReconciliation{}
+	for _, acc := range r.Accounts.All() {
+		// Loan and nostro accounts mirror outstanding principal and money held by peers rather than hold money, the principal itself is emitted
+		if acc == r.RemainderAccount || acc.Type == LoanAccount || acc.Type == NostroAccount || acc.Currency != r.RemainderAccount.Currency {
+			continue
+		}
+		res.Swept += acc.Fractions
+		acc.Fractions = 0
+		res.RoundedBalances += acc.BookedBalance()
+	}
+	r.RemainderAccount.Fractions += res.Swept
+
+	// Moving whole minor units (i.e. kopecks) accumulated in remainder fractions to its balance
+	minor := truncToCurrency(r.RemainderAccount.Fractions, r.RemainderAccount.Currency)
+	r.RemainderAccount.Balance = roundToCurrency(r.RemainderAccount.Balance+minor, r.RemainderAccount.Currency)
+	r.RemainderAccount.Fractions -= minor
+
+	res.Remainder = r.RemainderAccount.BookedBalance() + r.RemainderAccount.Fractions
+	res.TotalEmitted = r.TotalEmitted
+	res.Converted = r.ConvertedBalances[r.RemainderAccount.Currency]
+	res.Adjusted = r.adjustedMoney(r.RemainderAccount.Currency)
+	res.Discrepancy = res.RoundedBalances + res.Remainder - res.TotalEmitted - res.Converted - res.Adjusted
+	res.Balanced = math.Abs(res.Discrepancy) < reconciliationTolerance
+	if !res.Balanced {
+		return res, fmt.Errorf(errorCodesToMessagesMap[FractionsReconciliationError][locale()])
+	}
+
+	//TODO: send some kind of notification to message queue to be processed by transaction log microservice
+	return res, nil
+}
+
 // --------------------------------------------------------
 // Initializing the app and assigning values to certain parameters
 // Ideally, those should be parsed from the environment configuration or vault
 func init() {
-	rand.Seed(time.Now().UnixNano())
-	locale = English
+	setLocale(English)
 }
 
 func main() {
-	inMemRepoImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
-	service := NewAccountService(inMemRepoImpl)
+	// Taking the configuration from the file, the environment and the flags
+	config, err := LoadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	config.Apply()
+	if logger, err = config.Logger(os.Stdout); err != nil {
+		fmt.Println(err)
+		return
+	}
+	chart, err := config.Chart()
+	if err != nil {
+		logger.Error("loading chart of accounts", errorAttrs(err)...)
+		return
+	}
+	inMemRepoImpl, err := NewInMemoryAccountRepositoryWithChart(chart)
+	if err != nil {
+		logger.Error("opening repository", errorAttrs(err)...)
+		return
+	}
+	inMemRepoImpl.RateProvider = NewFixedRateProvider(map[string]float64{"USD/BYN": 3.27, "EUR/BYN": 3.55})
+	bus := NewInMemoryEventBus()
+	inMemRepoImpl.EventBus = bus
+	if err := config.ApplyTo(inMemRepoImpl); err != nil {
+		logger.Error("applying configuration", errorAttrs(err)...)
+		return
+	}
+	// Logging operations of the repository with their duration and operations of the service with the principal,
+	// counting operations of the repository for the metrics
+	var backend AccountRepository = inMemRepoImpl
+	if config.Chaos.Enabled() {
+		// Injecting faults innermost, so that the log and the metrics show them as failures of the backend
+		logger.Warn("injecting faults into the repository", "latency", config.Chaos.Latency, "error_rate", config.Chaos.ErrorRate, "partial_failure_rate", config.Chaos.PartialFailureRate)
+		backend = NewFaultInjectingAccountRepository(inMemRepoImpl, config.Chaos)
+	}
+	metrics := NewMetricsAccountRepository(NewLoggingAccountRepository(backend, logger))
+	var repo AccountRepository = metrics
+	var tracer *Tracer
+	if config.Tracing {
+		// Tracing the repository outermost, so that the service can bind it to the trace of the request
+		tracer = NewTracer(NewJsonSpanExporter(os.Stderr))
+		repo = NewTracingAccountRepository(metrics, tracer)
+	}
+	service := NewAccountService(repo).WithLogger(logger)
+	if tracer != nil {
+		service = service.WithTracer(tracer)
+	}
 
 	wg := sync.WaitGroup{}
 
@@ -732,200 +3617,221 @@ func main() {
 	}
 	wg.Wait()
 
+	// Convert money between accounts in different currencies
+	testCurrencyConversion(service)
+
+	// Retrieve details of a single account
+	testSingleAccountRetrieval(service)
+
+	// Search for accounts matching given criteria
+	testAccountSearch(service)
+
+	// Open a new account funded with an initial deposit in one step
+	testAccountOpeningWithInitialDeposit(service)
+
+	// Sweep sub-cent fractions into the remainder account and prove the books are balanced
+	testFractionsReconciliation(service)
+
 	// Print all accounts details
 	testAllAccountDetailsPrinting(service)
+
+	// Serve the interbank API if the address is configured until the program is interrupted or terminated
+	// Components are stopped in the order they are registered: the API first, background jobs next, events last
+	shutdown := NewShutdownCoordinator(config.ShutdownTimeout)
+	if config.HttpAddr != "" {
+		gateway, err := config.InterbankGateway(service)
+		if err != nil {
+			logger.Error("serving interbank API", errorAttrs(err)...)
+			return
+		}
+		var handler http.Handler = gateway.Handler()
+		if tracer != nil {
+			handler = TracingMiddleware(tracer, handler)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/", handler)
+		mux.Handle(MetricsPath, metrics.Handler())
+		server, addr, err := config.StartServer(mux)
+		if err != nil {
+			logger.Error("serving interbank API", errorAttrs(err)...)
+			return
+		}
+		shutdown.Register("http server", server.Shutdown)
+		shutdown.RegisterStop("scheduler", NewTransferScheduler(service).Start(time.Minute))
+		shutdown.RegisterStop("interbank retries", gateway.Start(time.Minute))
+		// Reloading the configuration while serving, the file is checked for changes every few seconds
+		shutdown.RegisterStop("config reloader", NewConfigReloader(os.Args[1:], config, inMemRepoImpl).Start(5*time.Second))
+		logger.Info("serving interbank API", "addr", addr.String())
+		logger.Info("shutting down", "signal", shutdown.WaitForSignal(os.Interrupt, syscall.SIGTERM).String())
+	}
+	shutdown.Register("event bus", func(ctx context.Context) error {
+		bus.Close()
+		return nil
+	})
+	if config.SnapshotDir != "" {
+		shutdown.Register("snapshot", func(ctx context.Context) error {
+			return inMemRepoImpl.FlushSnapshot(config.SnapshotDir)
+		})
+	}
+	if err := shutdown.Shutdown(); err != nil {
+		os.Exit(1)
+	}
 }
 
 // Get IBAN of emission account
 func testGettingEmissionIBAN(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 1: getting emission account IBAN\n")
+	useCase := "Use Case 1: getting emission account IBAN"
 	iban, err := service.RetrieveEmissionAccountIban()
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("IBAN: %s", iban))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "iban", FormatIban(iban))
 }
 
 // Get IBAN of destruction account
 func testGettingDestructionIBAN(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 2: getting destruction account IBAN\n")
+	useCase := "Use Case 2: getting destruction account IBAN"
 	iban, err := service.RetrieveDestructionAccountIban()
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("IBAN: %s", iban))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "iban", FormatIban(iban))
 }
 
 // Open a new ordinary account and topping up the balance (failure)
 func testAccountOpeningAndTopupFailure(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 3: failing to open a new account and top up its balance\n")
+	useCase := "Use Case 3: failing to open a new account and top up its balance"
 	acc, err := service.OpenAccount()
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	err = service.TransferMoney("BY84 ALFA 1000 0000 0000 0000 0000", acc.Iban, -23.48)
+	_, err = service.TransferMoney("BY84 ALFA 1000 0000 0000 0000 0000", acc.Iban, -23.48)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, append([]any{"iban", acc.Iban}, errorAttrs(err)...)...)
 	}
 }
 
 // Open a new ordinary account and topping up the balance (success)
 func testAccountOpeningAndTopupSuccess(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 4: presumably successfully opening a new account and topping up its balance\n")
+	useCase := "Use Case 4: presumably successfully opening a new account and topping up its balance"
 	acc, err := service.OpenAccount()
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	var amount float64 = rand.Float64() * float64(rand.Intn(1000))
+	var amount float64 = random.Float64() * float64(random.Intn(1000))
 	err = service.EmitMoney(amount)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	err = service.TransferMoney("BY84 ALFA 1000 0000 0000 0000 0000", acc.Iban, amount)
+	_, err = service.TransferMoney("BY84 ALFA 1000 0000 0000 0000 0000", acc.Iban, amount)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("IBAN %s: %.2f", acc.Iban, round(amount)))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "iban", acc.Iban, "amount", round(amount))
 }
 
 // Open a new ordinary account with zero balance (success)
 func testZeroBalanceAccountOpening(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 5: presumably successfully opening an account with zero balance\n")
+	useCase := "Use Case 5: presumably successfully opening an account with zero balance"
 	acc, err := service.OpenAccount()
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("IBAN %s: %.2f", acc.Iban, acc.Balance))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "iban", acc.Iban, "balance", acc.Balance)
 }
 
 // Destruct money (failure)
 func testMoneyDestructionFailure(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 6: failing to destruct money\n")
+	useCase := "Use Case 6: failing to destruct money"
 	err := service.DestructMoney("BY84 ALFA 1000 0000 0000 0000 0000", -10000)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 	}
 }
 
 // Emit money (success)
 func testMoneyEmissionSuccess(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 7: presumably successfully emitting money\n")
+	useCase := "Use Case 7: presumably successfully emitting money"
 	var amount float64 = 250
 	err := service.EmitMoney(amount)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("Money emitted: %.2f\n", round(amount)))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "amount", round(amount))
 }
 
 // Destruct money (success)
 func testMoneyDestructionSuccess(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 8: presumably successfully destructing money\n")
+	useCase := "Use Case 8: presumably successfully destructing money"
 	var amount float64 = 10
 	iban := "BY84 ALFA 1000 0000 0000 0000 0000"
 	err := service.DestructMoney(iban, amount)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("Money destructed to %s: %.2f\n", iban, round(amount)))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "iban", iban, "amount", round(amount))
 }
 
 // Print all accounts details
 func testAllAccountDetailsPrinting(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 9: printing IBAN, balance and status of all existing accounts including special and ordinary\n")
+	useCase := "Use Case 9: printing IBAN, balance and status of all existing accounts including special and ordinary"
 	res, err := service.RetrieveAllAccountsAsJson()
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, res)
-	fmt.Println(builder.String())
+	logger.Info(useCase, "accounts", res)
 }
 
 // Transfer money between accounts (success)
 func testSuccessfulMoneyTransfer(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 10: presumably successfully transferring money between accounts\n")
+	useCase := "Use Case 10: presumably successfully transferring money between accounts"
 	sender := "BY84 ALFA 1000 0000 0000 0000 0000"
 	recipient := "BY84 ALFA 1000 0000 0000 0000 0001"
 	var amount float64 = 50
-	err := service.TransferMoney(sender, recipient, amount)
+	_, err := service.TransferMoney(sender, recipient, amount)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("Money transfer from %s to %s: %.2f\n", sender, recipient, round(amount)))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "sender", sender, "recipient", recipient, "amount", round(amount))
 }
 
 // Transfer money between accounts (failure)
 func testFailedMoneyTransfer(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 11: failing to transfer money between accounts\n")
+	useCase := "Use Case 11: failing to transfer money between accounts"
 	// Blocking an account to fail the subsequent money transfer attempt
 	err := service.BlockAccount("BY84 ALFA 1000 0000 0000 0000 0000")
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	err = service.TransferMoney("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001", 50)
+	_, err = service.TransferMoney("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001", 50)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		logger.Error(useCase, errorAttrs(err)...)
 	}
 	// Activating account again to remove the block set earlier, so that future operations won't be affected by this use case
 	err = service.ActivateAccount("BY84 ALFA 1000 0000 0000 0000 0000")
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		logger.Error(useCase, errorAttrs(err)...)
 	}
-	fmt.Println(builder.String())
 }
 
 // Picking two random accounts and transferring money between them via JSON request
 func testMoneyTransferViaJson(service *AccountService) {
-	var builder strings.Builder
-	fmt.Fprintf(&builder, "Use Case 12: picking two random accounts and transferring money between them\n")
+	useCase := "Use Case 12: picking two random accounts and transferring money between them"
 	str, err := service.RetrieveAllAccountsAsJson()
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
 	type accountDetails struct {
@@ -935,19 +3841,17 @@ func testMoneyTransferViaJson(service *AccountService) {
 	}
 	var accounts []accountDetails
 	if err := json.Unmarshal([]byte(str), &accounts); err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
 
 	// Excluding special accounts from consideration and shuffling remaining ordinary accounts
-	if len(accounts) < 4 {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", "Not enough accounts to execute use case 12"))
-		fmt.Println(builder.String())
+	if len(accounts) < 5 {
+		logger.Error(useCase, "error", "Not enough accounts to execute use case 12")
 		return
 	}
-	accounts = accounts[2:]
-	rand.Shuffle(len(accounts), func(i, j int) { accounts[i], accounts[j] = accounts[j], accounts[i] })
+	accounts = accounts[3:]
+	random.Shuffle(len(accounts), func(i, j int) { accounts[i], accounts[j] = accounts[j], accounts[i] })
 
 	type moneyTransfer struct {
 		Sender    string  `json:"sender"`
@@ -955,22 +3859,88 @@ func testMoneyTransferViaJson(service *AccountService) {
 		Amount    float64 `json:"amount"`
 	}
 
-	mt := moneyTransfer{accounts[0].Iban, accounts[1].Iban, rand.Float64() + float64(rand.Intn(100))}
+	mt := moneyTransfer{accounts[0].Iban, accounts[1].Iban, random.Float64() + float64(random.Intn(100))}
 
 	jsonStr, err := json.Marshal(mt)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", "Unable to execute use case 12 due to JSON related error"))
-		fmt.Println(builder.String())
+		logger.Error(useCase, "error", "Unable to execute use case 12 due to JSON related error")
+		return
+	}
+
+	_, err = service.TransferMoneyJson(string(jsonStr))
+	if err != nil {
+		logger.Error(useCase, append([]any{"json", string(jsonStr)}, errorAttrs(err)...)...)
+		return
+	}
+	logger.Info(useCase, "json", string(jsonStr), "sender", mt.Sender, "recipient", mt.Recipient, "amount", round(mt.Amount))
+}
+
+// Sweep sub-cent fractions into the remainder account and prove the books are balanced
+func testFractionsReconciliation(service *AccountService) {
+	useCase := "Use Case 13: sweeping accumulated fractions into the remainder account"
+	res, err := service.ReconcileFractions()
+	if err != nil {
+		logger.Error(useCase, errorAttrs(err)...)
+		return
+	}
+	logger.Info(useCase, "swept", res.Swept, "rounded_balances", res.RoundedBalances, "remainder", res.Remainder, "total_emitted", res.TotalEmitted, "balanced", res.Balanced)
+}
+
+// Convert money between accounts in different currencies
+func testCurrencyConversion(service *AccountService) {
+	useCase := "Use Case 14: converting money between accounts in different currencies"
+	acc, err := service.OpenAccountInCurrency("USD")
+	if err != nil {
+		logger.Error(useCase, errorAttrs(err)...)
+		return
+	}
+	var amount float64 = 100
+	sender := "BY84 ALFA 1000 0000 0000 0000 0000"
+	err = service.ConvertAndTransfer(sender, acc.Iban, amount)
+	if err != nil {
+		logger.Error(useCase, errorAttrs(err)...)
+		return
+	}
+	converted, err := service.GetAccount(acc.Iban)
+	if err != nil {
+		logger.Error(useCase, errorAttrs(err)...)
+		return
+	}
+	logger.Info(useCase, "sender", sender, "recipient", acc.Iban, "amount", FormatAmount(amount, DefaultCurrency), "converted", FormatAmount(converted.Balance, converted.Currency))
+}
+
+// Retrieve details of a single account
+func testSingleAccountRetrieval(service *AccountService) {
+	useCase := "Use Case 15: retrieving IBAN, balance, status and type of a single account"
+	res, err := service.RetrieveAccountAsJson("BY84 ALFA 1000 0000 0000 0000 0000")
+	if err != nil {
+		logger.Error(useCase, errorAttrs(err)...)
+		return
+	}
+	logger.Info(useCase, "account", res)
+}
+
+// Search for accounts matching given criteria
+func testAccountSearch(service *AccountService) {
+	useCase := "Use Case 16: searching for active ordinary accounts with balance over 100"
+	status, accType, minBalance := Active, Ordinary, 100.0
+	res, err := service.FindAccounts(AccountQuery{Status: &status, Type: &accType, MinBalance: &minBalance})
+	if err != nil {
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("JSON: %s\n", string(jsonStr)))
+	for _, acc := range res {
+		logger.Info(useCase, "iban", acc.Iban, "balance", acc.Balance)
+	}
+}
 
-	err = service.TransferMoneyJson(string(jsonStr))
+// Open a new account funded with an initial deposit in one step
+func testAccountOpeningWithInitialDeposit(service *AccountService) {
+	useCase := "Use Case 17: opening a new account with an initial deposit in one atomic operation"
+	acc, err := service.OpenAccountWithInitialDeposit(150.5)
 	if err != nil {
-		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
-		fmt.Println(builder.String())
+		logger.Error(useCase, errorAttrs(err)...)
 		return
 	}
-	fmt.Fprintf(&builder, fmt.Sprintf("Money transfer from %s to %s: %.2f\n", mt.Sender, mt.Recipient, round(mt.Amount)))
-	fmt.Println(builder.String())
+	logger.Info(useCase, "iban", acc.Iban, "balance", acc.Balance)
 }
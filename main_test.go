@@ -17,6 +17,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"strings"
 	"sync"
@@ -87,7 +88,7 @@ func TestAccountOpeningAndTopupFailure(t *testing.T) {
 		fmt.Println(builder.String())
 		return
 	}
-	err = service.TransferMoney(emission, acc.Iban, -23.48)
+	_, err = service.TransferMoney(emission, acc.Iban, -23.48)
 	if err != nil {
 		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
 		fmt.Println(builder.String())
@@ -118,7 +119,7 @@ func TestAccountOpeningAndTopupSuccess(t *testing.T) {
 		t.Errorf(builder.String())
 		return
 	}
-	err = service.TransferMoney(emission, acc.Iban, amount)
+	_, err = service.TransferMoney(emission, acc.Iban, amount)
 	if err != nil {
 		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
 		t.Errorf(builder.String())
@@ -231,6 +232,46 @@ func TestAllAccountDetailsPrinting(t *testing.T) {
 	fmt.Println(builder.String())
 }
 
+// Writer failing after the given number of bytes
+type failingWriter struct {
+	left int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.left {
+		n := w.left
+		w.left = 0
+		return n, fmt.Errorf("disk full")
+	}
+	w.left -= len(p)
+	return len(p), nil
+}
+
+// Stream all accounts details as one JSON array
+func TestWritingAllAccountsJson(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	for i := 0; i < 300; i++ {
+		service.OpenAccountWithInitialDeposit(float64(i))
+	}
+
+	var output strings.Builder
+	if err := service.WriteAllAccountsJson(&output); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var details []AccountDetails
+	if err := json.Unmarshal([]byte(output.String()), &details); err != nil || len(details) != inMemImpl.Accounts.Len() || details[0].Iban != inMemImpl.EmissionAccount.Iban {
+		t.Errorf("Expected details of all %d accounts, got %d, %v", inMemImpl.Accounts.Len(), len(details), err)
+	}
+	// Accounts other than the system ones come in no particular order
+	if res, _ := service.RetrieveAllAccountsAsJson(); len(res) != output.Len() || !strings.HasPrefix(res, "[{") || !strings.HasSuffix(res, "}]") {
+		t.Errorf("Expected the same JSON as returned by RetrieveAllAccountsAsJson")
+	}
+	if err := service.WriteAllAccountsJson(&failingWriter{left: 10000}); err == nil {
+		t.Errorf("Writing to failing writer failed to fail")
+	}
+}
+
 // Transfer money between accounts (success)
 func TestSuccessfulMoneyTransfer(t *testing.T) {
 	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
@@ -250,7 +291,7 @@ func TestSuccessfulMoneyTransfer(t *testing.T) {
 	sender := "BY84 ALFA 1000 0000 0000 0000 0000"
 	recipient := "BY84 ALFA 1000 0000 0000 0000 0001"
 	amount = 50
-	err = service.TransferMoney(sender, recipient, amount)
+	_, err = service.TransferMoney(sender, recipient, amount)
 	if err != nil {
 		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
 		t.Errorf(builder.String())
@@ -276,7 +317,7 @@ func TestFailedMoneyTransfer(t *testing.T) {
 		fmt.Println(builder.String())
 		return
 	}
-	err = service.TransferMoney(emission, destruction, 50)
+	_, err = service.TransferMoney(emission, destruction, 50)
 	if err != nil {
 		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
 	}
@@ -323,7 +364,7 @@ func TestMoneyTransferViaJson(t *testing.T) {
 				t.Errorf(builder.String())
 				return
 			}
-			err = service.TransferMoney(emission, acc.Iban, amount)
+			_, err = service.TransferMoney(emission, acc.Iban, amount)
 			if err != nil {
 				fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
 				t.Errorf(builder.String())
@@ -352,12 +393,12 @@ func TestMoneyTransferViaJson(t *testing.T) {
 	}
 
 	// Excluding special accounts from consideration and shuffling remaining ordinary accounts
-	if len(accounts) < 4 {
+	if len(accounts) < 5 {
 		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", "Not enough accounts to execute use case 12"))
 		t.Errorf(builder.String())
 		return
 	}
-	accounts = accounts[2:]
+	accounts = accounts[3:]
 
 	const m int = 1000
 	for i := 0; i < m; i++ {
@@ -382,7 +423,7 @@ func TestMoneyTransferViaJson(t *testing.T) {
 			}
 			fmt.Fprintf(&builder, fmt.Sprintf("JSON: %s\n", string(jsonStr)))
 
-			err = service.TransferMoneyJson(string(jsonStr))
+			_, err = service.TransferMoneyJson(string(jsonStr))
 			if err != nil {
 				fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
 				fmt.Println(builder.String())
@@ -402,3 +443,430 @@ func TestMoneyTransferViaJson(t *testing.T) {
 	fmt.Fprintf(&builder, res)
 	fmt.Println(builder.String())
 }
+
+// Sweep sub-cent fractions into the remainder account and prove the books are balanced
+func TestFractionsReconciliation(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Use Case 13: sweeping accumulated fractions into the remainder account\n")
+	for _, amount := range []float64{10.004, 20.006, 0.3333, 7.0049} {
+		acc, err := service.OpenAccount()
+		if err != nil {
+			fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+			t.Errorf(builder.String())
+			return
+		}
+		if err := service.EmitMoney(amount); err != nil {
+			fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+			t.Errorf(builder.String())
+			return
+		}
+		if _, err := service.TransferMoney(emission, acc.Iban, amount); err != nil {
+			fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+			t.Errorf(builder.String())
+			return
+		}
+	}
+	if err := service.DestructMoney(emission, 0); err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	res, err := service.ReconcileFractions()
+	if err != nil || !res.Balanced {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v (%+v)\n", err, res))
+		t.Errorf(builder.String())
+		return
+	}
+	for _, acc := range inMemImpl.Accounts.All() {
+		if acc != inMemImpl.RemainderAccount && acc.Fractions != 0 {
+			fmt.Fprintf(&builder, fmt.Sprintf("Error: fractions of %s were not swept (%f)\n", acc.Iban, acc.Fractions))
+			t.Errorf(builder.String())
+			return
+		}
+	}
+	if math.Abs(res.Remainder-(0.004-0.004+0.0033+0.0049)) > reconciliationTolerance {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: unexpected remainder %f\n", res.Remainder))
+		t.Errorf(builder.String())
+		return
+	}
+	fmt.Fprintf(&builder, fmt.Sprintf("Remainder: %.6f", res.Remainder))
+	fmt.Println(builder.String())
+}
+
+// Retrieve details of a single account
+func TestSingleAccountRetrieval(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Use Case 15: retrieving IBAN, balance, status and type of a single account\n")
+	if err := service.EmitMoney(12.345); err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	res, err := service.RetrieveAccountAsJson(emission)
+	if err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	var details AccountDetails
+	if err := json.Unmarshal([]byte(res), &details); err != nil || details.Iban != "BY84ALFA10000000000000000000" || details.Balance != 12.35 || details.Type != "Monetary emission" || details.Status != "Active" {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: unexpected account details %s\n", res))
+		t.Errorf(builder.String())
+		return
+	}
+	// Unknown and blocked accounts cannot be retrieved
+	if _, err := service.RetrieveAccount("BY84 ALFA 1000 0000 0000 0000 0009"); err == nil {
+		fmt.Fprintf(&builder, "Error: retrieving unknown account failed to fail\n")
+		t.Errorf(builder.String())
+		return
+	}
+	if err := service.BlockAccount(destruction); err != nil {
+		fmt.Fprintf(&builder, fmt.Sprintf("Error: %v\n", err))
+		t.Errorf(builder.String())
+		return
+	}
+	if _, err := service.RetrieveAccount(destruction); err == nil {
+		fmt.Fprintf(&builder, "Error: retrieving blocked account failed to fail\n")
+		t.Errorf(builder.String())
+		return
+	}
+	fmt.Fprintf(&builder, res)
+	fmt.Println(builder.String())
+}
+
+// Get a snapshot of an account that cannot be used to modify repository state
+func TestGetAccountReturnsCopy(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	opened, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	opened.Balance = 1000
+	opened.Block()
+	acc, err := service.GetAccount(opened.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if acc.Balance != 0 || acc.Status != Active {
+		t.Errorf("Account returned by OpenAccount modified repository state: %+v", acc)
+	}
+	acc.Balance = 1000
+	if stored, _ := service.GetAccount(opened.Iban); stored.Balance != 0 {
+		t.Errorf("Account returned by GetAccount modified repository state: %+v", stored)
+	}
+	if _, err := service.GetAccount("BY84 ALFA 1000 0000 0000 0000 0009"); err == nil {
+		t.Errorf("Getting unknown account failed to fail")
+	}
+}
+
+// Iterate over accounts without materializing all of them at once
+func TestAccountIteration(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	const n int = accountIterationBatchSize + 10
+	for i := 0; i < n; i++ {
+		if _, err := service.OpenAccount(); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	err := service.ForEachAccount(func(acc Account) bool {
+		seen[acc.Iban] = true
+		// The callback is called without holding the mutex, so calling the repository must not deadlock
+		if _, err := service.GetAccount(acc.Iban); err != nil {
+			t.Errorf("Error: %v", err)
+		}
+		return true
+	})
+	if err != nil || len(seen) != n+3 {
+		t.Errorf("Expected %d accounts, iterated over %d (%v)", n+3, len(seen), err)
+	}
+
+	ch, stop := service.StreamAccounts()
+	first := <-ch
+	if first.Type != MonetaryEmission {
+		t.Errorf("Expected emission account to be streamed first, got %+v", first)
+	}
+	count := 1
+	for range ch {
+		count++
+		if count == 10 {
+			stop()
+			break
+		}
+	}
+	stop()
+	if count != 10 {
+		t.Errorf("Expected streaming to stop after 10 accounts, got %d", count)
+	}
+}
+
+// Search for accounts matching given criteria
+func TestAccountSearch(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	if err := service.EmitMoney(3000); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ibans := []string{}
+	for _, amount := range []float64{500, 1500, 1000} {
+		acc, err := service.OpenAccount()
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if _, err := service.TransferMoney(emission, acc.Iban, amount); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if err := service.BlockAccount(acc.Iban); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		ibans = append(ibans, acc.Iban)
+	}
+
+	// All blocked accounts with balance over 1000
+	blocked, minBalance := Blocked, 1000.01
+	res, err := service.FindAccounts(AccountQuery{Status: &blocked, MinBalance: &minBalance})
+	if err != nil || len(res) != 1 || res[0].Iban != ibans[1] {
+		t.Errorf("Unexpected search results %+v (%v)", res, err)
+	}
+
+	// All special accounts by IBAN prefix
+	ordinary := Ordinary
+	res, err = service.FindAccounts(AccountQuery{IbanPrefix: "BY84 ALFA"})
+	if err != nil || len(res) != 3 {
+		t.Errorf("Unexpected search results %+v (%v)", res, err)
+	}
+	res, err = service.FindAccounts(AccountQuery{Type: &ordinary})
+	if err != nil || len(res) != 3 {
+		t.Errorf("Unexpected search results %+v (%v)", res, err)
+	}
+
+	maxBalance := 10.0
+	if _, err := service.FindAccounts(AccountQuery{MinBalance: &minBalance, MaxBalance: &maxBalance}); err == nil {
+		t.Errorf("Contradictory search criteria failed to fail")
+	}
+}
+
+// Label accounts with metadata and tags and search by them
+func TestAccountMetadata(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	acc, err := service.OpenAccountWithMetadata(map[string]string{"branch": "minsk-01"}, []string{"test", " test", "demo"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.OpenAccount(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(acc.Tags) != 2 || acc.Metadata["branch"] != "minsk-01" {
+		t.Errorf("Unexpected metadata %v and tags %v", acc.Metadata, acc.Tags)
+	}
+	if _, err := service.OpenAccountWithMetadata(map[string]string{"": "empty"}, nil); err == nil {
+		t.Errorf("Opening an account with invalid metadata failed to fail")
+	}
+
+	if err := service.UpdateAccountMetadata(acc.Iban, map[string]string{"branch": "", "product": "P-100"}, nil); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	details, err := service.RetrieveAccount(acc.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, exists := details.Metadata["branch"]; exists || details.Metadata["product"] != "P-100" || len(details.Tags) != 2 {
+		t.Errorf("Unexpected metadata %v and tags %v", details.Metadata, details.Tags)
+	}
+
+	res, err := service.FindAccounts(AccountQuery{Tags: []string{"test"}, Metadata: map[string]string{"product": "P-100"}})
+	if err != nil || len(res) != 1 || res[0].Iban != acc.Iban {
+		t.Errorf("Unexpected search results %+v (%v)", res, err)
+	}
+}
+
+// Close an account sweeping its balance to another account and to the destruction account
+func TestAccountClosing(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	first, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	second, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.EmitMoney(100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(emission, first.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.CloseAccount(emission, ""); err == nil {
+		t.Errorf("Closing a special account failed to fail")
+	}
+	if err := service.CloseAccount(first.Iban, first.Iban); err == nil {
+		t.Errorf("Sweeping balance of closed account to itself failed to fail")
+	}
+	if err := service.CloseAccount(first.Iban, second.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if acc, _ := service.GetAccount(second.Iban); acc.Balance != 100 {
+		t.Errorf("Expected balance to be swept to the target account, got %.2f", acc.Balance)
+	}
+
+	// Closed account rejects any further operations but remains visible
+	if _, err := service.TransferMoney(second.Iban, first.Iban, 10); err == nil {
+		t.Errorf("Crediting closed account failed to fail")
+	}
+	if err := service.ActivateAccount(first.Iban); err == nil {
+		t.Errorf("Activating closed account failed to fail")
+	}
+	if err := service.CloseAccount(first.Iban, ""); err == nil {
+		t.Errorf("Closing closed account failed to fail")
+	}
+	if details, err := service.RetrieveAccount(first.Iban); err != nil || details.Status != "Closed" || details.Balance != 0 {
+		t.Errorf("Unexpected closed account details %+v (%v)", details, err)
+	}
+
+	if err := service.CloseAccount(second.Iban, ""); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if inMemImpl.DestructionAccount.Balance != 100 {
+		t.Errorf("Expected balance to be swept to the destruction account, got %.2f", inMemImpl.DestructionAccount.Balance)
+	}
+}
+
+// Screening provider rejecting every counterparty
+type rejectingScreeningProvider struct{}
+
+func (rejectingScreeningProvider) Screen(sender, recipient ScreeningParty) (ScreeningOutcome, string) {
+	return ScreeningReject, "SDN-1"
+}
+
+// Sweeping the balance of the closed account to another account goes through the checks of transfers
+func TestAccountClosingChecks(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	target, _ := service.OpenAccount()
+
+	rejected, _ := service.OpenAccountWithInitialDeposit(100)
+	service.RejectKyc(rejected.Iban)
+	if err := service.CloseAccount(rejected.Iban, target.Iban); err == nil {
+		t.Errorf("Sweeping account with rejected KYC failed to fail")
+	}
+	limited, _ := service.OpenAccountWithInitialDeposit(100)
+	if err := service.SetAccountLimits(limited.Iban, AccountLimits{PerTransaction: 10}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.CloseAccount(limited.Iban, target.Iban); err == nil {
+		t.Errorf("Sweeping more than the transaction limit failed to fail")
+	}
+	screened, _ := service.OpenAccountWithInitialDeposit(100)
+	inMemImpl.ScreeningProvider = rejectingScreeningProvider{}
+	if err := service.CloseAccount(screened.Iban, target.Iban); err == nil {
+		t.Errorf("Sweeping to sanctioned counterparty failed to fail")
+	}
+	inMemImpl.ScreeningProvider = nil
+	if details, _ := service.RetrieveAccount(target.Iban); details.Balance != 0 {
+		t.Errorf("Expected nothing to be swept, got %v", details.Balance)
+	}
+
+	// Limits do not keep the balance from being destroyed on closing
+	if err := service.CloseAccount(limited.Iban, ""); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if err := service.CloseAccount(screened.Iban, target.Iban); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(screened.Iban); details.Status != "Closed" {
+		t.Errorf("Expected account to be closed, got %+v", details)
+	}
+}
+
+// Open a new account funded with an initial deposit in one step
+func TestAccountOpeningWithInitialDeposit(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	destruction := "BY84 ALFA 1000 0000 0000 0000 0001"
+	inMemImpl := NewInMemoryAccountRepository(emission, destruction)
+	service := NewAccountService(inMemImpl)
+
+	acc, err := service.OpenAccountWithInitialDeposit(150.5)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if acc.Balance != 150.5 || inMemImpl.EmissionAccount.Balance != 0 || inMemImpl.TotalEmitted != 150.5 {
+		t.Errorf("Unexpected balances after opening: %.2f on the account, %.2f on the emission account", acc.Balance, inMemImpl.EmissionAccount.Balance)
+	}
+
+	// Failing operation must not leave an opened account or emitted money behind
+	if _, err := service.OpenAccountWithInitialDeposit(-1); err == nil {
+		t.Errorf("Opening an account with negative deposit failed to fail")
+	}
+	if err := service.BlockAccount(emission); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.OpenAccountWithInitialDeposit(10); err == nil {
+		t.Errorf("Opening an account while the emission account is blocked failed to fail")
+	}
+	if inMemImpl.Accounts.Len() != 4 || inMemImpl.TotalEmitted != 150.5 || len(inMemImpl.Transactions) != 2 {
+		t.Errorf("Failed opening left side effects: %d accounts, %.2f emitted, %d transactions", inMemImpl.Accounts.Len(), inMemImpl.TotalEmitted, len(inMemImpl.Transactions))
+	}
+}
+
+// Register accounts under existing IBANs when migrating them from another system
+func TestAccountOpeningWithIban(t *testing.T) {
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+
+	iban, err := GenerateValidBelarusianIban()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	spaced := iban[:4] + " " + iban[4:8] + " " + iban[8:]
+	acc, err := service.OpenAccountWithIban(spaced)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if acc.Iban != iban || acc.Status != Active || acc.Type != Ordinary {
+		t.Errorf("Unexpected account opened: %+v", acc)
+	}
+	if details, err := service.RetrieveAccount(iban); err != nil || details.Iban != iban {
+		t.Errorf("Expected to retrieve migrated account %s, got %+v (%v)", iban, details, err)
+	}
+	// The same IBAN cannot be registered twice
+	if _, err := service.OpenAccountWithIban(iban); err == nil {
+		t.Errorf("Opening account with already registered IBAN failed to fail")
+	}
+	// Invalid and empty IBANs are rejected
+	broken := iban[:27] + string('0'+(iban[27]-'0'+1)%10)
+	for _, invalid := range []string{"", "  ", broken, "BY12"} {
+		if _, err := service.OpenAccountWithIban(invalid); err == nil {
+			t.Errorf("Opening account with invalid IBAN %q failed to fail", invalid)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining acquiring of merchant accounts: payments credited to accounts of the merchant product are charged with the
+// acquiring fee of the fee schedule, which is deducted from the credited amount and moved to the fee income account
+// Acquiring fees are kept when payments are reversed, like card schemes keep the interchange of refunded purchases
+
+// Helper function to find out the acquiring fee of the payment credited to the recipient, expects the repository mutex to
+// be held by the caller
+func (r *InMemoryAccountRepository) acquiringFeeFor(rAcc *Account, amount float64) (float64, error) {
+	if r.FeeSchedule == nil || rAcc.Type != Ordinary || rAcc.Product != MerchantProduct {
+		return 0, nil
+	}
+	fee := r.FeeSchedule.Quote(AcquiringFeeTransaction, amount, rAcc.Currency)
+	if fee == 0 {
+		return 0, nil
+	}
+	// Checking if there is an account to credit the fee to in the currency of the merchant
+	if r.FeeAccount == nil || r.FeeAccount.Status != Active || r.FeeAccount.Currency != rAcc.Currency || r.FeeAccount == rAcc {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[FeeAccountUnavailableError][locale()])
+	}
+	// Checking if the payment covers the fee
+	if fee > roundToCurrency(amount, rAcc.Currency) {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[FeeExceedsAmountError][locale()])
+	}
+	return fee, nil
+}
+
+// Helper function to move the acquiring fee from the merchant to the fee income account, expects the repository mutex to be
+// held by the caller
+// Like other fees the fee is recorded before the payment it is charged on, the payment credits the merchant right after
+func (r *InMemoryAccountRepository) chargeAcquiringFee(rAcc *Account, fee float64) (*Transaction, error) {
+	if fee == 0 {
+		return nil, nil
+	}
+	feeTx := &Transaction{Type: AcquiringFeeTransaction, Sender: rAcc.Iban, Recipient: r.FeeAccount.Iban, Amount: fee, Currency: rAcc.Currency}
+	if err := r.post(feeTx, transferLines(rAcc, r.FeeAccount, fee)...); err != nil {
+		return nil, err
+	}
+	return feeTx, nil
+}
+
+// Payments credited to the merchant on the day (UTC) and acquiring fees charged on them
+type MerchantSettlementDay struct {
+	Date     string  `json:"date"`
+	Payments int     `json:"payments"`
+	Gross    float64 `json:"gross"`
+	Fees     float64 `json:"fees"`
+	Net      float64 `json:"net"`
+}
+
+type MerchantSettlementReport struct {
+	Iban        string                  `json:"iban"`
+	Currency    string                  `json:"currency"`
+	From        time.Time               `json:"from"`
+	To          time.Time               `json:"to"`
+	GeneratedAt time.Time               `json:"generated_at"`
+	Days        []MerchantSettlementDay `json:"days"` // days without payments are left out
+	Total       MerchantSettlementDay   `json:"total"`
+}
+
+// Builds the settlement report of the merchant account for payments made in [from, to), one entry per day
+func (s *AccountService) MerchantSettlementReport(iban string, from, to time.Time) (*MerchantSettlementReport, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	iban = NormalizeIban(iban)
+	var merchant *Account
+	if err := s.accountRepoImpl.ForEachAccount(func(acc Account) bool {
+		if acc.Iban == iban {
+			merchant = &acc
+			return false
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	// Checking if the account exists and is a merchant account
+	if merchant == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if merchant.Product != MerchantProduct {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+
+	transactions, err := s.accountRepoImpl.RetrieveTransactionsBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	report := &MerchantSettlementReport{Iban: iban, Currency: merchant.Currency, From: from, To: to, GeneratedAt: clock.Now().UTC(), Days: []MerchantSettlementDay{}}
+	days := map[string]*MerchantSettlementDay{}
+	day := func(tx Transaction) *MerchantSettlementDay {
+		date := tx.Timestamp.UTC().Format(time.DateOnly)
+		if days[date] == nil {
+			days[date] = &MerchantSettlementDay{Date: date}
+		}
+		return days[date]
+	}
+	for _, tx := range transactions {
+		switch {
+		case tx.Type == TransferTransaction && tx.Recipient == iban:
+			entry := day(tx)
+			entry.Payments++
+			entry.Gross = roundToCurrency(entry.Gross+tx.Amount, merchant.Currency)
+		case tx.Type == AcquiringFeeTransaction && tx.Sender == iban:
+			entry := day(tx)
+			entry.Fees = roundToCurrency(entry.Fees+tx.Amount, merchant.Currency)
+		}
+	}
+	for _, entry := range days {
+		entry.Net = roundToCurrency(entry.Gross-entry.Fees, merchant.Currency)
+		report.Days = append(report.Days, *entry)
+		report.Total.Payments += entry.Payments
+		report.Total.Gross = roundToCurrency(report.Total.Gross+entry.Gross, merchant.Currency)
+		report.Total.Fees = roundToCurrency(report.Total.Fees+entry.Fees, merchant.Currency)
+	}
+	report.Total.Net = roundToCurrency(report.Total.Gross-report.Total.Fees, merchant.Currency)
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+	return report, nil
+}
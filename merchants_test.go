@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Deduct acquiring fees from payments to merchant accounts and summarize them by day
+func TestMerchantAcquiring(t *testing.T) {
+	fake := NewFakeClock(time.Date(2024, time.April, 1, 9, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+
+	chart := DefaultChartOfAccounts()
+	inMemImpl, _ := NewInMemoryAccountRepositoryWithChart(chart)
+	inMemImpl.FeeSchedule = NewFeeSchedule()
+	if err := inMemImpl.FeeSchedule.SetFee(AcquiringFeeTransaction, FeeRule{Kind: PercentageFee, Value: 2, Minimum: 0.1}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service := NewAccountService(inMemImpl)
+	c, _ := service.CreateCustomer("Shop", "", "", "")
+	merchant, err := service.OpenAccountWithOptions(AccountOptions{Product: MerchantProduct, CustomerID: c.ID})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	acc, _ := service.OpenAccountWithInitialDeposit(1000)
+	other, _ := service.OpenAccount()
+	card, _ := service.IssueCard(acc.Iban)
+
+	// Transfers, card debits and captured purchases are charged alike
+	if _, err := service.TransferMoney(acc.Iban, merchant.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.DebitCard(card.ID, merchant.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	fake.Advance(24 * time.Hour)
+	authorization, _ := service.AuthorizePurchase(card.ID, 200, merchant.Iban)
+	if _, err := service.CapturePurchase(authorization.ID, 0); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(acc.Iban, merchant.Iban, 0.01); err == nil {
+		t.Errorf("Payment not covering the acquiring fee failed to fail")
+	}
+	if _, err := service.TransferMoney(acc.Iban, other.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if details, _ := service.RetrieveAccount(merchant.Iban); details.Balance != 343 {
+		t.Errorf("Expected merchant to be credited net of the fees, got %v", details.Balance)
+	}
+	if details, _ := service.RetrieveAccount(other.Iban); details.Balance != 100 {
+		t.Errorf("Expected ordinary account not to be charged, got %v", details.Balance)
+	}
+	if details, _ := service.RetrieveAccount(chart[FeeIncomeRole]); details.Balance != 7 {
+		t.Errorf("Expected fee income of 7, got %v", details.Balance)
+	}
+	transactions, _ := service.RetrieveAccountTransactions(merchant.Iban, 2)
+	if len(transactions) != 2 || transactions[1].Type != AcquiringFeeTransaction || transactions[1].FeeOf != transactions[0].Ulid {
+		t.Errorf("Expected the fee to be linked to the payment, got %+v", transactions)
+	}
+	if _, err := service.VerifyInvariants(); err != nil {
+		t.Errorf("Expected invariants to hold, got %v", err)
+	}
+
+	report, err := service.MerchantSettlementReport(merchant.Iban, time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.April, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expected := []MerchantSettlementDay{{"2024-04-01", 2, 150, 3, 147}, {"2024-04-02", 1, 200, 4, 196}}
+	if len(report.Days) != len(expected) || report.Days[0] != expected[0] || report.Days[1] != expected[1] {
+		t.Errorf("Unexpected days %+v", report.Days)
+	}
+	if report.Total != (MerchantSettlementDay{Payments: 3, Gross: 350, Fees: 7, Net: 343}) {
+		t.Errorf("Unexpected total %+v", report.Total)
+	}
+	if _, err := service.MerchantSettlementReport(other.Iban, time.Time{}, fake.Now()); err == nil {
+		t.Errorf("Reporting on ordinary account failed to fail")
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining metrics of the repository exposed in the text format of Prometheus: money movements are counted by the decorator
+// along with their amounts and latencies, failures are counted by the code of the error, the number of accounts and
+// the money supply are taken from the repository at the time of scraping
+const (
+	MetricsPath   = "/metrics"
+	metricsPrefix = "payments_"
+)
+
+// Upper bounds of the buckets of the latency histograms (seconds) and of the amount histograms (units of the currency)
+var (
+	latencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+	amountBuckets  = []float64{1, 10, 50, 100, 500, 1000, 5000, 10000, 50000, 100000}
+)
+
+// Cumulative histogram, counts[i] is the number of observations not greater than buckets[i]
+type metricHistogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newMetricHistogram(buckets []float64) *metricHistogram {
+	return &metricHistogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *metricHistogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// Decorates the repository to count money movements and openings of accounts, other methods are passed through as is
+type MetricsAccountRepository struct {
+	AccountRepository
+	operations map[string]uint64           // operations by "operation,status" labels
+	amounts    map[string]float64          // money moved by successful operations by the operation
+	latencies  map[string]*metricHistogram // latencies by the operation
+	sizes      map[string]*metricHistogram // amounts of successful operations by the operation
+	errors     map[string]uint64           // failures by "operation,code" labels
+	Mutex      sync.Mutex
+}
+
+func NewMetricsAccountRepository(r AccountRepository) *MetricsAccountRepository {
+	return &MetricsAccountRepository{AccountRepository: r, operations: map[string]uint64{}, amounts: map[string]float64{}, latencies: map[string]*metricHistogram{}, sizes: map[string]*metricHistogram{}, errors: map[string]uint64{}}
+}
+
+// Helper function to record the operation started at the given time, amounts of failed operations are not counted
+func (r *MetricsAccountRepository) observe(operation string, start time.Time, amount float64, err error) {
+	elapsed := time.Since(start).Seconds()
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	if r.latencies[operation] == nil {
+		r.latencies[operation], r.sizes[operation] = newMetricHistogram(latencyBuckets), newMetricHistogram(amountBuckets)
+	}
+	r.latencies[operation].observe(elapsed)
+	if err != nil {
+		r.operations[operation+",failure"]++
+		code := "unknown"
+		if errorCode, ok := errorCodeOf(err); ok {
+			code = strconv.Itoa(int(errorCode))
+		}
+		r.errors[operation+","+code]++
+		return
+	}
+	r.operations[operation+",success"]++
+	r.amounts[operation] += amount
+	r.sizes[operation].observe(amount)
+}
+
+// Helper function to take the amount of the opened account, that is its initial deposit
+func openedBalance(acc *Account) float64 {
+	if acc == nil {
+		return 0
+	}
+	return acc.Balance
+}
+
+func (r *MetricsAccountRepository) EmitMoney(amount float64) error {
+	start := time.Now()
+	err := r.AccountRepository.EmitMoney(amount)
+	r.observe("emission", start, amount, err)
+	return err
+}
+
+func (r *MetricsAccountRepository) DestructMoney(iban string, amount float64) error {
+	start := time.Now()
+	err := r.AccountRepository.DestructMoney(iban, amount)
+	r.observe("destruction", start, amount, err)
+	return err
+}
+
+func (r *MetricsAccountRepository) OpenAccount() (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccount()
+	r.observe("account_open", start, openedBalance(acc), err)
+	return acc, err
+}
+
+func (r *MetricsAccountRepository) OpenAccountInCurrency(currency string) (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccountInCurrency(currency)
+	r.observe("account_open", start, openedBalance(acc), err)
+	return acc, err
+}
+
+func (r *MetricsAccountRepository) OpenAccountWithOptions(opts AccountOptions) (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccountWithOptions(opts)
+	r.observe("account_open", start, openedBalance(acc), err)
+	return acc, err
+}
+
+func (r *MetricsAccountRepository) OpenAccountWithInitialDeposit(amount float64) (*Account, error) {
+	start := time.Now()
+	acc, err := r.AccountRepository.OpenAccountWithInitialDeposit(amount)
+	r.observe("account_open", start, openedBalance(acc), err)
+	return acc, err
+}
+
+func (r *MetricsAccountRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	start := time.Now()
+	id, err := r.AccountRepository.TransferMoney(sender, recipient, amount)
+	r.observe("transfer", start, amount, err)
+	return id, err
+}
+
+func (r *MetricsAccountRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	start := time.Now()
+	id, err := r.AccountRepository.TransferMoneyWithDetails(sender, recipient, amount, details)
+	r.observe("transfer", start, amount, err)
+	return id, err
+}
+
+// The amount is taken from the request, requests which cannot be decoded are counted as failed by the repository anyway
+func (r *MetricsAccountRepository) TransferMoneyJson(jsonStr string) (string, error) {
+	start := time.Now()
+	id, err := r.AccountRepository.TransferMoneyJson(jsonStr)
+	var request struct {
+		Amount float64 `json:"amount"`
+	}
+	json.Unmarshal([]byte(jsonStr), &request)
+	r.observe("transfer", start, request.Amount, err)
+	return id, err
+}
+
+func (r *MetricsAccountRepository) ConvertAndTransfer(sender, recipient string, amount float64) error {
+	start := time.Now()
+	err := r.AccountRepository.ConvertAndTransfer(sender, recipient, amount)
+	r.observe("transfer", start, amount, err)
+	return err
+}
+
+// Helper function to write the sample of the metric, labels are given as name-value pairs
+func writeMetricSample(w io.Writer, name string, value float64, labels ...string) {
+	pairs := []string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	if len(pairs) > 0 {
+		name += "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s%s %s\n", metricsPrefix, name, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// Helper function to write the header of the metric
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+}
+
+// Helper function to write the histograms by the operation
+func writeMetricHistograms(w io.Writer, name string, histograms map[string]*metricHistogram, operations []string) {
+	for _, operation := range operations {
+		h := histograms[operation]
+		for i, bound := range h.buckets {
+			writeMetricSample(w, name+"_bucket", float64(h.counts[i]), "operation", operation, "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		}
+		writeMetricSample(w, name+"_bucket", float64(h.count), "operation", operation, "le", "+Inf")
+		writeMetricSample(w, name+"_sum", h.sum, "operation", operation)
+		writeMetricSample(w, name+"_count", float64(h.count), "operation", operation)
+	}
+}
+
+// Helper function to sort the labels of the counters, so that the metrics are written in a stable order
+func sortedMetricLabels(counters map[string]uint64) []string {
+	labels := make([]string, 0, len(counters))
+	for key := range counters {
+		labels = append(labels, key)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Writes all metrics in the text format of Prometheus, failing reads of the current totals leave them out
+func (r *MetricsAccountRepository) WriteMetrics(w io.Writer) error {
+	// Taking the current totals first, since the repository is locked by them
+	accounts := map[string]uint64{}
+	for status, names := range accountStatusCodeToNameMap {
+		status := status
+		found, err := r.AccountRepository.FindAccounts(AccountQuery{Status: &status})
+		if err == nil {
+			accounts[strings.ToLower(names[English])] = uint64(len(found))
+		}
+	}
+	supply, supplyErr := r.AccountRepository.MoneySupply()
+
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	writeMetricHeader(w, "operations_total", "counter", "Operations of the repository by the outcome.")
+	for _, key := range sortedMetricLabels(r.operations) {
+		labels := strings.SplitN(key, ",", 2)
+		writeMetricSample(w, "operations_total", float64(r.operations[key]), "operation", labels[0], "status", labels[1])
+	}
+	writeMetricHeader(w, "operation_amount_total", "counter", "Money moved by successful operations.")
+	operations := []string{}
+	for operation := range r.latencies {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		writeMetricSample(w, "operation_amount_total", r.amounts[operation], "operation", operation)
+	}
+	writeMetricHeader(w, "operation_duration_seconds", "histogram", "Latency of operations of the repository.")
+	writeMetricHistograms(w, "operation_duration_seconds", r.latencies, operations)
+	writeMetricHeader(w, "operation_amount", "histogram", "Amounts of successful operations.")
+	writeMetricHistograms(w, "operation_amount", r.sizes, operations)
+	writeMetricHeader(w, "errors_total", "counter", "Failed operations by the error code.")
+	for _, key := range sortedMetricLabels(r.errors) {
+		labels := strings.SplitN(key, ",", 2)
+		writeMetricSample(w, "errors_total", float64(r.errors[key]), "operation", labels[0], "code", labels[1])
+	}
+	writeMetricHeader(w, "accounts", "gauge", "Accounts by the status.")
+	for _, status := range sortedMetricLabels(accounts) {
+		writeMetricSample(w, "accounts", float64(accounts[status]), "status", status)
+	}
+	if supplyErr == nil {
+		writeMetricHeader(w, "money_supply", "gauge", "Money supply by the kind.")
+		for _, kind := range []struct {
+			name  string
+			value float64
+		}{{"emitted", supply.Emitted}, {"destroyed", supply.Destroyed}, {"undistributed", supply.Undistributed}, {"circulating", supply.Circulating}} {
+			writeMetricSample(w, "money_supply", math.Round(kind.value*1e6)/1e6, "kind", kind.name, "currency", supply.Currency)
+		}
+	}
+	return nil
+}
+
+// Serves the metrics to the scrapers at MetricsPath
+func (r *MetricsAccountRepository) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteMetrics(w)
+	})
+}
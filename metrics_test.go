@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Count money movements through the decorator and scrape them along with the current totals
+func TestMetrics(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	metrics := NewMetricsAccountRepository(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	service := NewAccountService(metrics)
+
+	acc, _ := service.OpenAccount()
+	service.EmitMoney(1000)
+	service.TransferMoney(emission, acc.Iban, 300)
+	service.TransferMoneyJson(`{"sender":"` + acc.Iban + `","recipient":"` + emission + `","amount":50}`)
+	service.TransferMoney(acc.Iban, emission, -5)
+	service.DestructMoney(acc.Iban, 20)
+
+	server := httptest.NewServer(metrics.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + MetricsPath)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer resp.Body.Close()
+	var body strings.Builder
+	buffer := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buffer)
+		body.Write(buffer[:n])
+		if err != nil {
+			break
+		}
+	}
+	out := body.String()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	expected := []string{
+		`payments_operations_total{operation="transfer",status="success"} 2`,
+		`payments_operations_total{operation="transfer",status="failure"} 1`,
+		`payments_operations_total{operation="emission",status="success"} 1`,
+		`payments_operations_total{operation="destruction",status="success"} 1`,
+		`payments_operations_total{operation="account_open",status="success"} 1`,
+		`payments_operation_amount_total{operation="transfer"} 350`,
+		`payments_operation_amount_bucket{operation="transfer",le="100"} 1`,
+		`payments_operation_amount_bucket{operation="transfer",le="+Inf"} 2`,
+		`payments_operation_duration_seconds_count{operation="transfer"} 3`,
+		`payments_errors_total{operation="transfer",code="5"} 1`,
+		`payments_accounts{status="active"} 4`,
+		`payments_money_supply{kind="emitted",currency="BYN"} 1000`,
+		`payments_money_supply{kind="circulating",currency="BYN"} 230`,
+		"# TYPE payments_operation_duration_seconds histogram",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, out)
+		}
+	}
+
+	if resp, err := http.Post(server.URL+MetricsPath, "text/plain", nil); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected scraping with POST to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining two-factor confirmation: transfers above the threshold of the sender account are not executed right away,
+// a one-time code is sent to the account holder and the transfer is executed once ConfirmTransfer is called with it
+type OtpSender interface {
+	SendOtp(iban, challengeID, code string) error
+}
+
+type ChallengeStatus int8
+
+const (
+	ChallengePending ChallengeStatus = iota
+	ChallengeConfirmed
+	ChallengeExpired
+	ChallengeFailed // too many wrong codes, or the transfer could not be executed once confirmed
+)
+
+// Codes are valid for five minutes, three wrong codes fail the challenge
+const (
+	otpChallengeTTL      = 5 * time.Minute
+	otpChallengeAttempts = 3
+)
+
+// ID of the challenge is the ID of the transfer handed out to the initiator, it is kept by the transfer once executed
+type Challenge struct {
+	ID        string
+	Sender    string
+	Recipient string
+	Amount    float64
+	Details   TransferDetails
+	CodeHash  string
+	Attempts  int // wrong codes entered so far
+	Status    ChallengeStatus
+	Reason    string // reason of the failure
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Helper function to hash the code along with the challenge, the codes themselves are never stored
+func hashOtpCode(challengeID, code string) string {
+	sum := sha256.Sum256([]byte(challengeID + ":" + strings.TrimSpace(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Helper function to generate a six digit code
+func newOtpCode() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint64(b[:])%1000000)
+}
+
+func (r *InMemoryAccountRepository) SetOtpThreshold(iban string, threshold float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the threshold is valid
+	if threshold < 0 || math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	acc.OtpThreshold = threshold
+	return nil
+}
+
+// Helper function to check if the transfer needs confirmation, expects the repository mutex to be held by the caller
+// Transfers are never challenged if no OTP sender is set
+func (r *InMemoryAccountRepository) transferNeedsConfirmation(sender string, amount float64) bool {
+	acc, exists := r.Accounts.Get(sender)
+	return r.OtpSender != nil && exists && acc != nil && acc.OtpThreshold > 0 && amount > acc.OtpThreshold
+}
+
+// Helper function to put the transfer aside and send the code to the holder of the sender account,
+// expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) challengeTransfer(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	// Checking what can be checked upfront, the rest is checked by the transfer itself once confirmed
+	details, ok := normalizeTransferDetails(details)
+	if !ok {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidTransferDetailsError][locale()])
+	}
+	if rAcc, exists := r.Accounts.Get(recipient); !exists || rAcc == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+
+	now := clock.Now().UTC()
+	code := newOtpCode()
+	challenge := &Challenge{ID: NewUlid(now), Sender: sender, Recipient: recipient, Amount: amount, Details: details, Status: ChallengePending, CreatedAt: now, ExpiresAt: now.Add(otpChallengeTTL)}
+	challenge.CodeHash = hashOtpCode(challenge.ID, code)
+	if err := r.OtpSender.SendOtp(sender, challenge.ID, code); err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[OtpDeliveryError][locale()])
+	}
+	r.Challenges[challenge.ID] = challenge
+	r.TransferStatuses[challenge.ID] = TransferPending
+	return challenge.ID, nil
+}
+
+// If the transfer cannot be executed at the time of confirmation, the challenge fails and the transfer has to be made again
+func (r *InMemoryAccountRepository) ConfirmTransfer(challengeID, code string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	now := clock.Now().UTC()
+	challenge, exists := r.Challenges[strings.ToUpper(strings.TrimSpace(challengeID))]
+	if !exists || challenge == nil || challenge.Status != ChallengePending {
+		return fmt.Errorf(errorCodesToMessagesMap[ChallengeDoesNotExistError][locale()])
+	}
+	if !now.Before(challenge.ExpiresAt) {
+		r.expireChallenge(challenge)
+		return fmt.Errorf(errorCodesToMessagesMap[ChallengeDoesNotExistError][locale()])
+	}
+	// Checking if the code matches, the challenge fails after too many wrong codes
+	if subtle.ConstantTimeCompare([]byte(hashOtpCode(challenge.ID, code)), []byte(challenge.CodeHash)) != 1 {
+		challenge.Attempts++
+		if challenge.Attempts >= otpChallengeAttempts {
+			challenge.Status = ChallengeFailed
+			challenge.Reason = errorCodesToMessagesMap[InvalidOtpCodeError][locale()]
+			r.TransferStatuses[challenge.ID] = TransferFailed
+		}
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidOtpCodeError][locale()])
+	}
+	if err := r.transferMoney(challenge.Sender, challenge.Recipient, challenge.Amount, challenge.Details); err != nil {
+		challenge.Status = ChallengeFailed
+		challenge.Reason = err.Error()
+		r.TransferStatuses[challenge.ID] = TransferFailed
+		return err
+	}
+	r.keepTransferID(challenge.ID)
+	challenge.Status = ChallengeConfirmed
+	r.TransferStatuses[challenge.ID] = TransferSettled
+	return nil
+}
+
+// Helper function to expire the challenge, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) expireChallenge(challenge *Challenge) {
+	challenge.Status = ChallengeExpired
+	r.TransferStatuses[challenge.ID] = TransferFailed
+}
+
+// Expires challenges which have not been confirmed in time, returns the number of expired challenges
+func (r *InMemoryAccountRepository) ExpireChallenges(now time.Time) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	expired := 0
+	for _, challenge := range r.Challenges {
+		if challenge.Status == ChallengePending && !now.Before(challenge.ExpiresAt) {
+			r.expireChallenge(challenge)
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// Lists challenges of transfers from the account awaiting confirmation, the oldest first
+func (r *InMemoryAccountRepository) ListPendingChallenges(iban string) ([]Challenge, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	challenges := []Challenge{}
+	for _, challenge := range r.Challenges {
+		if challenge.Status == ChallengePending && challenge.Sender == iban {
+			challenges = append(challenges, *challenge)
+		}
+	}
+	sort.Slice(challenges, func(i, j int) bool { return challenges[i].CreatedAt.Before(challenges[j].CreatedAt) })
+	return challenges, nil
+}
+
+// Zero threshold disables confirmation of transfers from the account
+func (s *AccountService) SetOtpThreshold(iban string, threshold float64) error {
+	if err := s.authorize(TransferPermission, iban); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.SetOtpThreshold(iban, threshold)
+}
+
+func (s *AccountService) ConfirmTransfer(challengeID, code string) error {
+	if err := s.authorizeTransfer(TransferPermission, challengeID); err != nil {
+		return err
+	}
+	return s.accountRepoImpl.ConfirmTransfer(challengeID, code)
+}
+
+func (s *AccountService) ExpireChallenges(now time.Time) (int, error) {
+	if err := s.authorize(ConfigurePermission); err != nil {
+		return 0, err
+	}
+	return s.accountRepoImpl.ExpireChallenges(now)
+}
+
+func (s *AccountService) ListPendingChallenges(iban string) ([]Challenge, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return nil, err
+	}
+	return s.accountRepoImpl.ListPendingChallenges(iban)
+}
+
+func (s *TransferScheduler) RunChallengeExpiry(now time.Time) int {
+	expired, _ := s.service.ExpireChallenges(now)
+	return expired
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Captures the codes instead of delivering them to the account holders
+type recordingOtpSender struct {
+	codes map[string]string
+	fail  bool
+}
+
+func (s *recordingOtpSender) SendOtp(iban, challengeID, code string) error {
+	if s.fail {
+		return fmt.Errorf("unavailable")
+	}
+	s.codes[challengeID] = code
+	return nil
+}
+
+// Transfers above the threshold wait for the code sent to the holder, wrong codes fail the challenge eventually
+func TestTransferConfirmation(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender := &recordingOtpSender{codes: map[string]string{}}
+	inMemImpl.OtpSender = sender
+	acc, _ := service.OpenAccountWithInitialDeposit(500)
+	recipient, _ := service.OpenAccount()
+	service.VerifyKyc(acc.Iban)
+
+	if err := service.SetOtpThreshold(acc.Iban, -1); err == nil {
+		t.Errorf("Negative threshold failed to fail")
+	}
+	if err := service.SetOtpThreshold(acc.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Transfers up to the threshold are executed right away
+	if _, err := service.TransferMoneyWithDetails(acc.Iban, recipient.Iban, 100, TransferDetails{}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	id, err := service.TransferMoneyWithDetails(acc.Iban, recipient.Iban, 150, TransferDetails{Reference: "INV-1"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferPending {
+		t.Errorf("Expected transfer to wait for confirmation, got %v", status)
+	}
+	if challenges, _ := service.ListPendingChallenges(acc.Iban); len(challenges) != 1 || challenges[0].ID != id || challenges[0].CodeHash == sender.codes[id] {
+		t.Errorf("Unexpected challenges: %+v", challenges)
+	}
+	if err := service.ConfirmTransfer(id, "wrong"); err == nil {
+		t.Errorf("Confirmation with a wrong code failed to fail")
+	}
+	if err := service.ConfirmTransfer(id, sender.codes[id]); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.ConfirmTransfer(id, sender.codes[id]); err == nil {
+		t.Errorf("Confirming the transfer twice failed to fail")
+	}
+	if tx, _ := service.RetrieveTransaction(id); tx.Amount != 150 || tx.Reference != "INV-1" {
+		t.Errorf("Expected executed transfer to keep the ID of the challenge, got %+v", tx)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferSettled {
+		t.Errorf("Expected confirmed transfer to be settled, got %v", status)
+	}
+
+	failed, _ := service.TransferMoneyWithDetails(acc.Iban, recipient.Iban, 120, TransferDetails{})
+	for i := 0; i < otpChallengeAttempts; i++ {
+		service.ConfirmTransfer(failed, "wrong")
+	}
+	if err := service.ConfirmTransfer(failed, sender.codes[failed]); err == nil {
+		t.Errorf("Confirmation after too many wrong codes failed to fail")
+	}
+	expiring, _ := service.TransferMoneyWithDetails(acc.Iban, recipient.Iban, 110, TransferDetails{})
+	if expired, _ := service.ExpireChallenges(time.Now().Add(otpChallengeTTL)); expired != 1 {
+		t.Errorf("Expected one challenge to expire, got %d", expired)
+	}
+	if status, _ := service.GetTransferStatus(expiring); status != TransferFailed {
+		t.Errorf("Expected expired challenge to fail the transfer, got %v", status)
+	}
+
+	sender.fail = true
+	if _, err := service.TransferMoneyWithDetails(acc.Iban, recipient.Iban, 130, TransferDetails{}); err == nil {
+		t.Errorf("Transfer with undelivered code failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 250 {
+		t.Errorf("Expected recipient balance of 250, got %v", details.Balance)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// --------------------------------------------------------
+// Defining overdraft facility letting balances of ordinary accounts go negative down to the granted limit
+func (acc *Account) OverdraftUsed() float64 {
+	return roundToCurrency(math.Max(0, -acc.BookedBalance()), acc.Currency)
+}
+
+// Helper function to report a debit not covered by the available balance, accounts with an overdraft facility
+// report exceeding the facility rather than insufficient balance
+func insufficientFundsError(acc *Account) error {
+	if acc.OverdraftLimit > 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[OverdraftLimitExceededError][locale()])
+	}
+	return fmt.Errorf(errorCodesToMessagesMap[InsufficientAccountBalanceError][locale()])
+}
+
+// Lowering the limit below the overdraft in use is allowed, the account just cannot be debited until it is repaid
+func (r *InMemoryAccountRepository) SetOverdraftLimit(iban string, limit float64) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is an ordinary one, special accounts cannot be overdrawn
+	if acc.Type != Ordinary {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if the limit is not negative
+	if limit < 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	acc.OverdraftLimit = roundToCurrency(limit, acc.Currency)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Balance goes negative down to the overdraft limit
+func TestOverdraft(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	if err := service.SetOverdraftLimit(emission, 10); err == nil {
+		t.Errorf("Granting overdraft to emission account failed to fail")
+	}
+	if err := service.SetOverdraftLimit(sender.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 130); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.DestructMoney(sender.Iban, 20); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	details, _ := service.RetrieveAccount(sender.Iban)
+	if details.Balance != -50 || details.Available != 0 || details.OverdraftUsed != 50 || details.OverdraftLimit != 50 {
+		t.Errorf("Unexpected account details: %+v", details)
+	}
+	_, err := service.TransferMoney(sender.Iban, recipient.Iban, 0.01)
+	if err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[OverdraftLimitExceededError][locale()]) {
+		t.Errorf("Expected overdraft limit to be exceeded, got %v", err)
+	}
+	if err := service.CloseAccount(sender.Iban, ""); err == nil {
+		t.Errorf("Closing overdrawn account failed to fail")
+	}
+
+	// Accounts without the facility still report insufficient balance
+	_, err = service.TransferMoney(recipient.Iban, sender.Iban, 130.01)
+	if err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[InsufficientAccountBalanceError][locale()]) {
+		t.Errorf("Expected insufficient balance, got %v", err)
+	}
+	if _, err := service.TransferMoney(recipient.Iban, sender.Iban, 60); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(sender.Iban); details.Balance != 10 || details.OverdraftUsed != 0 {
+		t.Errorf("Expected repaid overdraft, got %+v", details)
+	}
+}
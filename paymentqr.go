@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// --------------------------------------------------------
+// Defining scan-to-pay codes following the EPC QR code guidelines (EPC069-12), version 002 with UTF-8 character set
+// EPC codes are meant for euro credit transfers, codes in other currencies follow the same layout with their currency codes
+type PaymentQR struct {
+	Name        string // beneficiary name, up to 70 characters
+	Iban        string
+	Amount      float64 // zero if the payer is to enter the amount
+	Currency    string
+	PurposeCode string
+	Reference   string // structured creditor reference, up to 35 characters
+	Memo        string // unstructured remittance information, up to 140 characters, mutually exclusive with the reference
+}
+
+const (
+	maxPaymentQRNameLength = 70
+	maxPaymentQRAmount     = 999999999.99
+	paymentQRPixelsPerCell = 8
+)
+
+// Renders the payload, trailing empty elements are left out as the guidelines allow
+func (p PaymentQR) Payload() string {
+	amount := ""
+	if p.Amount > 0 {
+		amount = strings.ToUpper(p.Currency) + strconv.FormatFloat(roundToCurrency(p.Amount, p.Currency), 'f', -1, 64)
+	}
+	elements := []string{"BCD", "002", "1", "SCT", "", p.Name, p.Iban, amount, p.PurposeCode, p.Reference, p.Memo}
+	for len(elements) > 0 && elements[len(elements)-1] == "" {
+		elements = elements[:len(elements)-1]
+	}
+	return strings.Join(elements, "\n")
+}
+
+// Parses the payload of an EPC QR code, the BIC is ignored since all accounts belong to the prototype
+func ParsePaymentQR(payload string) (PaymentQR, error) {
+	invalid := fmt.Errorf(errorCodesToMessagesMap[InvalidPaymentQRError][locale()])
+	elements := strings.Split(strings.Replace(strings.TrimRight(payload, "\r\n"), "\r\n", "\n", -1), "\n")
+	// Checking the service tag, version, character set and identification code
+	if len(elements) < 7 || len(elements) > 12 || elements[0] != "BCD" || (elements[1] != "001" && elements[1] != "002") || elements[2] != "1" || elements[3] != "SCT" {
+		return PaymentQR{}, invalid
+	}
+	for len(elements) < 12 {
+		elements = append(elements, "")
+	}
+	p := PaymentQR{Name: strings.TrimSpace(elements[5]), Iban: NormalizeIban(elements[6]), PurposeCode: strings.TrimSpace(elements[8]), Reference: strings.TrimSpace(elements[9]), Memo: strings.TrimSpace(elements[10])}
+	if p.Name == "" || utf8.RuneCountInString(p.Name) > maxPaymentQRNameLength || !IsValidIban(p.Iban) {
+		return PaymentQR{}, invalid
+	}
+	if p.Reference != "" && p.Memo != "" {
+		return PaymentQR{}, invalid
+	}
+	if amount := strings.TrimSpace(elements[7]); amount != "" {
+		if len(amount) < 4 {
+			return PaymentQR{}, invalid
+		}
+		value, err := strconv.ParseFloat(amount[3:], 64)
+		if err != nil || value <= 0 || value > maxPaymentQRAmount || roundToCurrency(value, amount[:3]) != value {
+			return PaymentQR{}, invalid
+		}
+		p.Currency, p.Amount = strings.ToUpper(amount[:3]), value
+	}
+	if _, ok := normalizeTransferDetails(p.transferDetails()); !ok {
+		return PaymentQR{}, invalid
+	}
+	return p, nil
+}
+
+// Helper function to carry the remittance information of the code over to the transfer
+func (p PaymentQR) transferDetails() TransferDetails {
+	return TransferDetails{Reference: p.Reference, Memo: p.Memo, PurposeCode: p.PurposeCode}
+}
+
+// Generates the payload and the PNG image of a code requesting the amount to be paid to the account,
+// zero amount leaves it up to the payer, the beneficiary name is the name of the account holder
+func (s *AccountService) GeneratePaymentQR(iban string, amount float64, memo string) (string, []byte, error) {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return "", nil, err
+	}
+	acc, err := s.accountRepoImpl.GetAccount(iban)
+	if err != nil {
+		return "", nil, err
+	}
+	// Checking if the account can receive payments
+	if acc.Type != Ordinary {
+		return "", nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	if acc.Status == Closed {
+		return "", nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	if amount < 0 || amount > maxPaymentQRAmount {
+		return "", nil, fmt.Errorf(errorCodesToMessagesMap[InvalidPaymentQRError][locale()])
+	}
+	name, _ := s.accountHolder(acc.Iban)
+	if utf8.RuneCountInString(name) > maxPaymentQRNameLength {
+		name = string([]rune(name)[:maxPaymentQRNameLength])
+	}
+	p := PaymentQR{Name: name, Iban: acc.Iban, Amount: roundToCurrency(amount, acc.Currency), Currency: acc.Currency, Memo: strings.TrimSpace(memo)}
+	if _, ok := normalizeTransferDetails(p.transferDetails()); !ok || strings.Contains(p.Memo, "\n") {
+		return "", nil, fmt.Errorf(errorCodesToMessagesMap[InvalidPaymentQRError][locale()])
+	}
+
+	payload := p.Payload()
+	qr, ok := encodeQR([]byte(payload))
+	if !ok {
+		return "", nil, fmt.Errorf(errorCodesToMessagesMap[InvalidPaymentQRError][locale()])
+	}
+	img, err := qr.png(paymentQRPixelsPerCell)
+	if err != nil {
+		return "", nil, fmt.Errorf(errorCodesToMessagesMap[InvalidPaymentQRError][locale()])
+	}
+	return payload, img, nil
+}
+
+// Pays the scanned code from the sender account, codes without an amount are paid with the given one,
+// returns the ID of the transfer like TransferMoneyWithDetails does
+func (s *AccountService) PayPaymentQR(sender, payload string, amount float64) (string, error) {
+	if err := s.authorize(TransferPermission, sender); err != nil {
+		return "", err
+	}
+	p, err := ParsePaymentQR(payload)
+	if err != nil {
+		return "", err
+	}
+	if p.Amount > 0 {
+		amount = p.Amount
+		// Checking if the code requests money in the currency of the beneficiary account
+		acc, err := s.accountRepoImpl.GetAccount(p.Iban)
+		if err != nil {
+			return "", err
+		}
+		if acc.Currency != p.Currency {
+			return "", fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+		}
+	}
+	return s.TransferMoneyWithDetails(sender, p.Iban, amount, p.transferDetails())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// Generated codes carry the holder name and the amount, paying a scanned code transfers the requested amount
+func TestPaymentQR(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	shop, _ := service.CreateCustomer("Coffee Shop", "", "", "")
+	merchant, _ := service.OpenAccountForCustomer(shop.ID)
+	payer, _ := service.OpenAccountWithInitialDeposit(100)
+
+	payload, img, err := service.GeneratePaymentQR(merchant.Iban, 4.5, "Order 42")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if expected := "BCD\n002\n1\nSCT\n\nCoffee Shop\n" + merchant.Iban + "\nBYN4.5\n\n\nOrder 42"; payload != expected {
+		t.Errorf("Unexpected payload %q", payload)
+	}
+	if _, err := png.Decode(bytes.NewReader(img)); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	p, err := ParsePaymentQR(payload)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if p.Name != "Coffee Shop" || p.Iban != merchant.Iban || p.Amount != 4.5 || p.Currency != "BYN" || p.Memo != "Order 42" {
+		t.Errorf("Unexpected code: %+v", p)
+	}
+	id, err := service.PayPaymentQR(payer.Iban, payload, 0)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if tx, _ := service.RetrieveTransaction(id); tx.Amount != 4.5 || tx.Recipient != merchant.Iban || tx.Memo != "Order 42" {
+		t.Errorf("Unexpected transfer: %+v", tx)
+	}
+
+	// Codes without an amount are paid with the amount entered by the payer
+	payload, _, _ = service.GeneratePaymentQR(merchant.Iban, 0, "")
+	if !strings.HasSuffix(payload, merchant.Iban) {
+		t.Errorf("Expected trailing elements to be left out, got %q", payload)
+	}
+	if _, err := service.PayPaymentQR(payer.Iban, payload, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(merchant.Iban); details.Balance != 14.5 {
+		t.Errorf("Expected balance of 14.5, got %v", details.Balance)
+	}
+
+	invalid := []string{
+		"",
+		"BCD\n002\n1\nSCT\n\nCoffee Shop\nBY00INVALID",
+		"BCD\n002\n1\nINST\n\nCoffee Shop\n" + merchant.Iban,
+		"BCD\n002\n1\nSCT\n\nCoffee Shop\n" + merchant.Iban + "\nBYN4.555",
+		"BCD\n002\n1\nSCT\n\nCoffee Shop\n" + merchant.Iban + "\nBYN4.5\n\nRF18539007547034\nOrder 42",
+	}
+	for _, payload := range invalid {
+		if _, err := ParsePaymentQR(payload); err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[InvalidPaymentQRError][locale()]) {
+			t.Errorf("Expected invalid code for %q, got %v", payload, err)
+		}
+	}
+	emission, _ := service.RetrieveEmissionAccountIban()
+	if _, _, err := service.GeneratePaymentQR(emission, 1, ""); err == nil {
+		t.Errorf("Generating code for emission account failed to fail")
+	}
+}
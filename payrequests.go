@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining payment requests (invoices): an account asks another one to pay the amount until the due date,
+// the payer either accepts the request, which executes the transfer, or declines it, unanswered requests expire
+type PaymentRequestStatus int8
+
+const (
+	PaymentRequestPending PaymentRequestStatus = iota
+	PaymentRequestPaid
+	PaymentRequestDeclined
+	PaymentRequestExpired
+)
+
+// Mapping payment request status codes to payment request status names considering locale
+var paymentRequestStatusCodeToNameMap map[PaymentRequestStatus](map[LanguageCode]string) = map[PaymentRequestStatus](map[LanguageCode]string){
+	PaymentRequestPending: {
+		English: "Pending",
+		Russian: "Ожидает оплаты",
+	},
+	PaymentRequestPaid: {
+		English: "Paid",
+		Russian: "Оплачен",
+	},
+	PaymentRequestDeclined: {
+		English: "Declined",
+		Russian: "Отклонён",
+	},
+	PaymentRequestExpired: {
+		English: "Expired",
+		Russian: "Просрочен",
+	},
+}
+
+type PaymentRequest struct {
+	ID         string
+	Requester  string // IBAN of the account the money is requested to
+	Payer      string // IBAN of the account asked to pay
+	Amount     float64
+	Currency   string
+	Memo       string
+	DueDate    time.Time // the request expires once the due date passes
+	Status     PaymentRequestStatus
+	TransferID string // ID of the transfer (including failed attempts) made on acceptance
+	CreatedAt  time.Time
+	SettledAt  time.Time // time of payment, decline or expiry
+}
+
+func (r *InMemoryAccountRepository) CreatePaymentRequest(requester, payer string, amount float64, dueDate time.Time, memo string) (*PaymentRequest, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	requester = NormalizeIban(requester)
+	payer = NormalizeIban(payer)
+	// Checking if both accounts exist
+	rAcc, rExists := r.Accounts.Get(requester)
+	pAcc, pExists := r.Accounts.Get(payer)
+	if !rExists || rAcc == nil || !pExists || pAcc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the requester is an open ordinary account, the payer is checked by the transfer on acceptance
+	if rAcc.Type != Ordinary {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	if rAcc.Status == Closed || pAcc.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	if rAcc.Currency != pAcc.Currency {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+	// Checking if the request makes sense
+	now := clock.Now().UTC()
+	if requester == payer || amount <= 0 || !dueDate.After(now) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidPaymentRequestError][locale()])
+	}
+	details, ok := normalizeTransferDetails(TransferDetails{Memo: memo})
+	if !ok {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTransferDetailsError][locale()])
+	}
+
+	request := &PaymentRequest{ID: NewUlid(now), Requester: requester, Payer: payer, Amount: roundToCurrency(amount, rAcc.Currency), Currency: rAcc.Currency, Memo: details.Memo, DueDate: dueDate.UTC(), Status: PaymentRequestPending, CreatedAt: now}
+	r.PaymentRequests[request.ID] = request
+	copied := *request
+	return &copied, nil
+}
+
+// Helper function to mark overdue requests as expired, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) expirePaymentRequests(now time.Time) int {
+	expired := 0
+	for _, request := range r.PaymentRequests {
+		if request.Status == PaymentRequestPending && !now.Before(request.DueDate) {
+			request.Status = PaymentRequestExpired
+			request.SettledAt = now
+			expired++
+		}
+	}
+	return expired
+}
+
+// Expires pending requests whose due date has passed by the given time, returns the number of expired requests
+func (r *InMemoryAccountRepository) ExpirePaymentRequests(now time.Time) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	return r.expirePaymentRequests(now.UTC()), nil
+}
+
+// Lists pending requests addressed to the payer, the earliest due first
+func (r *InMemoryAccountRepository) ListIncomingPaymentRequests(payer string) ([]PaymentRequest, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	payer = NormalizeIban(payer)
+	// Checking if account associated with the given IBAN exists
+	if acc, exists := r.Accounts.Get(payer); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	r.expirePaymentRequests(clock.Now().UTC())
+	requests := []PaymentRequest{}
+	for _, request := range r.PaymentRequests {
+		if request.Payer == payer && request.Status == PaymentRequestPending {
+			requests = append(requests, *request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].DueDate.Before(requests[j].DueDate) })
+	return requests, nil
+}
+
+// Helper function to find a pending request addressed to the payer, expects the repository mutex to be held by the caller
+func (r *InMemoryAccountRepository) pendingPaymentRequest(id, payer string) (*PaymentRequest, error) {
+	request, exists := r.PaymentRequests[strings.ToUpper(strings.TrimSpace(id))]
+	// Requests addressed to other payers are not disclosed
+	if !exists || request == nil || request.Payer != NormalizeIban(payer) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[PaymentRequestDoesNotExistError][locale()])
+	}
+	r.expirePaymentRequests(clock.Now().UTC())
+	if request.Status != PaymentRequestPending {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[PaymentRequestIsNotPendingError][locale()])
+	}
+	return request, nil
+}
+
+// Pays the request from the payer account, the request stays pending if the transfer fails so that it can be accepted again
+// Returns the ID of the transfer even if it failed, like TransferMoneyWithDetails does
+func (r *InMemoryAccountRepository) AcceptPaymentRequest(id, payer string) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	request, err := r.pendingPaymentRequest(id, payer)
+	if err != nil {
+		return "", err
+	}
+	err = r.transferMoney(request.Payer, request.Requester, request.Amount, TransferDetails{Reference: request.ID, Memo: request.Memo})
+	request.TransferID = r.trackTransfer(err)
+	if err != nil {
+		return request.TransferID, err
+	}
+	request.Status = PaymentRequestPaid
+	request.SettledAt = clock.Now().UTC()
+	return request.TransferID, nil
+}
+
+func (r *InMemoryAccountRepository) DeclinePaymentRequest(id, payer string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	request, err := r.pendingPaymentRequest(id, payer)
+	if err != nil {
+		return err
+	}
+	request.Status = PaymentRequestDeclined
+	request.SettledAt = clock.Now().UTC()
+	return nil
+}
+
+// Expires overdue payment requests along with the scheduled transfers, returns the number of expired requests
+func (s *TransferScheduler) RunPaymentRequestExpiry(now time.Time) int {
+	expired, _ := s.service.ExpirePaymentRequests(now)
+	return expired
+}
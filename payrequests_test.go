@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Request money from another account, the payer accepts or declines it, unanswered requests expire
+func TestPaymentRequests(t *testing.T) {
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	payer, _ := service.OpenAccountWithInitialDeposit(100)
+	requester, _ := service.OpenAccount()
+	now := time.Now().UTC()
+
+	if _, err := service.CreatePaymentRequest(requester.Iban, requester.Iban, 10, now.Add(time.Hour), ""); err == nil {
+		t.Errorf("Requesting money from the same account failed to fail")
+	}
+	if _, err := service.CreatePaymentRequest(requester.Iban, payer.Iban, 10, now.Add(-time.Hour), ""); err == nil {
+		t.Errorf("Request due in the past failed to fail")
+	}
+	if _, err := service.CreatePaymentRequest(requester.Iban, payer.Iban, -10, now.Add(time.Hour), ""); err == nil {
+		t.Errorf("Request of negative amount failed to fail")
+	}
+
+	later, err := service.CreatePaymentRequest(requester.Iban, payer.Iban, 80, now.Add(2*time.Hour), "Invoice 2")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	sooner, err := service.CreatePaymentRequest(requester.Iban, payer.Iban, 30, now.Add(time.Hour), "Invoice 1")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	incoming, err := service.ListIncomingPaymentRequests(payer.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(incoming) != 2 || incoming[0].ID != sooner.ID || incoming[1].ID != later.ID {
+		t.Errorf("Expected both requests, the earliest due first, got %+v", incoming)
+	}
+	if incoming, _ := service.ListIncomingPaymentRequests(requester.Iban); len(incoming) != 0 {
+		t.Errorf("Expected no requests addressed to the requester, got %d", len(incoming))
+	}
+
+	// Only the payer can accept the request
+	if _, err := service.AcceptPaymentRequest(sooner.ID, requester.Iban); err == nil {
+		t.Errorf("Accepting request addressed to another account failed to fail")
+	}
+	id, err := service.AcceptPaymentRequest(sooner.ID, payer.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferSettled {
+		t.Errorf("Expected transfer of the request to be settled")
+	}
+	if tx, _ := service.RetrieveTransaction(id); tx.Reference != sooner.ID || tx.Memo != "Invoice 1" {
+		t.Errorf("Expected request ID and memo to be carried over to the transfer, got %q and %q", tx.Reference, tx.Memo)
+	}
+	if details, _ := service.RetrieveAccount(requester.Iban); details.Balance != 30 {
+		t.Errorf("Expected requester balance of 30, got %v", details.Balance)
+	}
+	if _, err := service.AcceptPaymentRequest(sooner.ID, payer.Iban); err == nil {
+		t.Errorf("Paying already paid request failed to fail")
+	}
+
+	// Failed payment leaves the request pending
+	if _, err := service.AcceptPaymentRequest(later.ID, payer.Iban); err == nil {
+		t.Errorf("Paying more than available balance failed to fail")
+	}
+	if incoming, _ := service.ListIncomingPaymentRequests(payer.Iban); len(incoming) != 1 || incoming[0].ID != later.ID {
+		t.Errorf("Expected request to stay pending after failed payment, got %+v", incoming)
+	}
+	if err := service.DeclinePaymentRequest(later.ID, payer.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.DeclinePaymentRequest(later.ID, payer.Iban); err == nil {
+		t.Errorf("Declining already declined request failed to fail")
+	}
+
+	overdue, _ := service.CreatePaymentRequest(requester.Iban, payer.Iban, 5, now.Add(time.Hour), "")
+	scheduler := NewTransferScheduler(service)
+	if expired := scheduler.RunPaymentRequestExpiry(now.Add(2 * time.Hour)); expired != 1 {
+		t.Errorf("Expected one request to expire, got %d", expired)
+	}
+	if _, err := service.AcceptPaymentRequest(overdue.ID, payer.Iban); err == nil {
+		t.Errorf("Paying expired request failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(payer.Iban); details.Balance != 70 {
+		t.Errorf("Expected payer balance of 70, got %v", details.Balance)
+	}
+}
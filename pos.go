@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining card purchases at points of sale: the purchase is authorized first, which holds the amount on the account of
+// the card, and captured later by the merchant, i.e. once the goods are shipped, or voided, i.e. when the order is canceled
+// Authorizations the merchant neither captures nor voids expire and their holds are released
+type AuthorizationStatus int8
+
+const (
+	AuthorizationPending AuthorizationStatus = iota
+	AuthorizationCaptured
+	AuthorizationVoided
+	AuthorizationExpired
+)
+
+// Mapping authorization status codes to authorization status names considering locale
+var authorizationStatusCodeToNameMap map[AuthorizationStatus](map[LanguageCode]string) = map[AuthorizationStatus](map[LanguageCode]string){
+	AuthorizationPending: {
+		English: "Pending",
+		Russian: "Ожидает списания",
+	},
+	AuthorizationCaptured: {
+		English: "Captured",
+		Russian: "Списана",
+	},
+	AuthorizationVoided: {
+		English: "Voided",
+		Russian: "Отменена",
+	},
+	AuthorizationExpired: {
+		English: "Expired",
+		Russian: "Истекла",
+	},
+}
+
+// Time the merchant has to capture the authorized purchase, card schemes commonly release uncaptured holds after a week
+const authorizationValidity = 7 * 24 * time.Hour
+
+type Authorization struct {
+	ID             string
+	CardID         string
+	Iban           string // IBAN of the account of the card
+	Merchant       string // IBAN of the account of the merchant credited on capture
+	Amount         float64
+	CapturedAmount float64
+	HoldID         string
+	Status         AuthorizationStatus
+	TransferID     string    // ID of the transfer made on capture
+	ExpiresAt      time.Time // the authorization expires unless it is captured or voided by then
+	CreatedAt      time.Time
+	SettledAt      time.Time // time of capture, void or expiry
+}
+
+// Authorizes the purchase by the active card, the amount is held on the account of the card until the merchant captures it
+func (r *InMemoryAccountRepository) AuthorizePurchase(cardID string, amount float64, merchant string) (*Authorization, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	card, err := r.usableCard(cardID)
+	if err != nil {
+		return nil, err
+	}
+	merchant = NormalizeIban(merchant)
+	// Checking if the merchant account exists and is an ordinary account which can be credited on capture
+	mAcc, exists := r.Accounts.Get(merchant)
+	if !exists || mAcc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	if mAcc.Type != Ordinary {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	if mAcc.Status == Blocked {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	if mAcc.Status == Closed {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the card does not pay to its own account and the merchant is paid in the currency of the card
+	if merchant == card.Iban {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidPurchaseError][locale()])
+	}
+	if acc, _ := r.Accounts.Get(card.Iban); acc == nil || acc.Currency != mAcc.Currency {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[CurrencyMismatchError][locale()])
+	}
+
+	now := clock.Now().UTC()
+	hold, err := r.holdFunds(card.Iban, amount)
+	if err != nil {
+		return nil, err
+	}
+	authorization := &Authorization{ID: NewUlid(now), CardID: card.ID, Iban: card.Iban, Merchant: merchant, Amount: hold.Amount, HoldID: hold.ID, Status: AuthorizationPending, ExpiresAt: now.Add(authorizationValidity), CreatedAt: now}
+	hold.AuthorizationID = authorization.ID
+	r.Authorizations[authorization.ID] = authorization
+	copied := *authorization
+	return &copied, nil
+}
+
+// Helper function to release holds of authorizations which have expired by the given time, expects the repository mutex to
+// be held by the caller
+func (r *InMemoryAccountRepository) expireAuthorizations(now time.Time) int {
+	expired := 0
+	for _, authorization := range r.Authorizations {
+		if authorization.Status != AuthorizationPending || now.Before(authorization.ExpiresAt) {
+			continue
+		}
+		hold := r.Holds[authorization.HoldID]
+		if acc, _ := r.Accounts.Get(authorization.Iban); hold != nil && acc != nil && hold.Status == HoldActive {
+			r.releaseHold(hold, acc)
+		}
+		authorization.Status = AuthorizationExpired
+		authorization.SettledAt = now
+		expired++
+	}
+	return expired
+}
+
+// Expires pending authorizations by the given time releasing their holds, returns the number of expired authorizations
+func (r *InMemoryAccountRepository) ExpireAuthorizations(now time.Time) (int, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	return r.expireAuthorizations(now.UTC()), nil
+}
+
+// Helper function to find a pending authorization and its hold, expects the repository mutex to be held by the caller
+// Overdue authorizations are expired first, so that they are never captured
+func (r *InMemoryAccountRepository) pendingAuthorization(id string) (*Authorization, *Hold, *Account, error) {
+	r.expireAuthorizations(clock.Now().UTC())
+	authorization, exists := r.Authorizations[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || authorization == nil {
+		return nil, nil, nil, fmt.Errorf(errorCodesToMessagesMap[AuthorizationDoesNotExistError][locale()])
+	}
+	if authorization.Status != AuthorizationPending {
+		return nil, nil, nil, fmt.Errorf(errorCodesToMessagesMap[AuthorizationIsNotPendingError][locale()])
+	}
+	hold := r.Holds[authorization.HoldID]
+	acc, _ := r.Accounts.Get(authorization.Iban)
+	if hold == nil || acc == nil || hold.Status != HoldActive {
+		return nil, nil, nil, fmt.Errorf(errorCodesToMessagesMap[HoldIsNotActiveError][locale()])
+	}
+	return authorization, hold, acc, nil
+}
+
+// Captures the amount (the whole authorized amount if zero) in favor of the merchant and releases the rest of the hold
+// The card is not checked again: purchases authorized before the card was blocked or expired are still paid
+// Returns the ID of the transfer even if it failed, the authorization stays pending then
+func (r *InMemoryAccountRepository) CapturePurchase(authorizationID string, amount float64) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	authorization, hold, acc, err := r.pendingAuthorization(authorizationID)
+	if err != nil {
+		return "", err
+	}
+	id, err := r.captureHold(hold, acc, authorization.Merchant, amount)
+	if err != nil {
+		return id, err
+	}
+	authorization.Status = AuthorizationCaptured
+	authorization.CapturedAmount = hold.CapturedAmount
+	authorization.TransferID = id
+	authorization.SettledAt = clock.Now().UTC()
+	return id, nil
+}
+
+// Cancels the authorization making the held money available again
+func (r *InMemoryAccountRepository) VoidAuthorization(authorizationID string) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	authorization, hold, acc, err := r.pendingAuthorization(authorizationID)
+	if err != nil {
+		return err
+	}
+	r.releaseHold(hold, acc)
+	authorization.Status = AuthorizationVoided
+	authorization.SettledAt = clock.Now().UTC()
+	return nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveAuthorization(authorizationID string) (Authorization, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	r.expireAuthorizations(clock.Now().UTC())
+	authorization, exists := r.Authorizations[strings.ToUpper(strings.TrimSpace(authorizationID))]
+	if !exists || authorization == nil {
+		return Authorization{}, fmt.Errorf(errorCodesToMessagesMap[AuthorizationDoesNotExistError][locale()])
+	}
+	return *authorization, nil
+}
+
+// Expires authorizations the merchants have not captured in time along with the scheduled transfers, returns the number of
+// expired authorizations
+func (s *TransferScheduler) RunAuthorizationExpiry(now time.Time) int {
+	expired, _ := s.service.ExpireAuthorizations(now)
+	return expired
+}
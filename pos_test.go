@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Authorize purchases by card, then capture, void or let them expire
+func TestPurchaseAuthorizations(t *testing.T) {
+	fake := NewFakeClock(time.Date(2024, time.June, 1, 10, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	merchant, _ := service.OpenAccount()
+	card, _ := service.IssueCard(acc.Iban)
+
+	first, err := service.AuthorizePurchase(card.ID, 60, merchant.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Held != 60 || details.Available != 40 {
+		t.Errorf("Unexpected balances with pending authorization: %+v", details)
+	}
+	if _, err := service.AuthorizePurchase(card.ID, 50, merchant.Iban); err == nil {
+		t.Errorf("Authorizing more than available balance failed to fail")
+	}
+	if _, err := service.AuthorizePurchase(card.ID, 10, acc.Iban); err == nil {
+		t.Errorf("Paying the purchase to the account of the card failed to fail")
+	}
+	if _, err := service.CaptureHold(first.HoldID, merchant.Iban, 0); err == nil {
+		t.Errorf("Capturing the hold of the authorization directly failed to fail")
+	}
+
+	// Captures are paid even if the card was blocked after the authorization
+	service.BlockCard(card.ID)
+	id, err := service.CapturePurchase(first.ID, 45)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if authorization, _ := service.RetrieveAuthorization(first.ID); authorization.Status != AuthorizationCaptured || authorization.CapturedAmount != 45 || authorization.TransferID != id {
+		t.Errorf("Unexpected captured authorization %+v", authorization)
+	}
+	if _, err := service.CapturePurchase(first.ID, 0); err == nil {
+		t.Errorf("Capturing authorization twice failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Balance != 55 || details.Held != 0 {
+		t.Errorf("Unexpected balances after capture: %+v", details)
+	}
+	if details, _ := service.RetrieveAccount(merchant.Iban); details.Balance != 45 {
+		t.Errorf("Expected merchant to be credited, got %v", details.Balance)
+	}
+	if _, err := service.AuthorizePurchase(card.ID, 10, merchant.Iban); err == nil {
+		t.Errorf("Authorizing purchase by blocked card failed to fail")
+	}
+	service.ActivateCard(card.ID)
+
+	// Voided authorization releases the hold
+	second, _ := service.AuthorizePurchase(card.ID, 20, merchant.Iban)
+	if err := service.VoidAuthorization(second.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.CapturePurchase(second.ID, 0); err == nil {
+		t.Errorf("Capturing voided authorization failed to fail")
+	}
+
+	// Uncaptured authorization expires once its validity passes
+	third, _ := service.AuthorizePurchase(card.ID, 30, merchant.Iban)
+	fake.Advance(authorizationValidity)
+	if _, err := service.CapturePurchase(third.ID, 0); err == nil {
+		t.Errorf("Capturing expired authorization failed to fail")
+	}
+	if authorization, _ := service.RetrieveAuthorization(third.ID); authorization.Status != AuthorizationExpired {
+		t.Errorf("Expected authorization to expire, got %v", authorizationStatusCodeToNameMap[authorization.Status][English])
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Balance != 55 || details.Held != 0 {
+		t.Errorf("Unexpected balances after void and expiry: %+v", details)
+	}
+	if _, err := service.RetrieveAuthorization("unknown"); err == nil {
+		t.Errorf("Retrieving unknown authorization failed to fail")
+	}
+}
+
+// Release holds of authorizations the merchant has not captured by the scheduler
+func TestAuthorizationExpiry(t *testing.T) {
+	service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	merchant, _ := service.OpenAccount()
+	card, _ := service.IssueCard(acc.Iban)
+	authorization, err := service.AuthorizePurchase(card.ID, 70, merchant.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	scheduler := NewTransferScheduler(service)
+	if expired := scheduler.RunAuthorizationExpiry(authorization.ExpiresAt.Add(-time.Second)); expired != 0 {
+		t.Errorf("Expected authorization to be valid until it expires, got %d expired", expired)
+	}
+	if expired := scheduler.RunAuthorizationExpiry(authorization.ExpiresAt); expired != 1 {
+		t.Errorf("Expected 1 expired authorization, got %d", expired)
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Held != 0 || details.Available != 100 {
+		t.Errorf("Expected the hold to be released, got %+v", details)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining account products offered to customers, all of them are ordinary accounts from the system point of view
+type ProductType int8
+
+const (
+	CurrentProduct ProductType = iota
+	SavingsProduct
+	MerchantProduct
+)
+
+// Mapping product type codes to product type names considering locale
+var productTypeCodeToNameMap map[ProductType](map[LanguageCode]string) = map[ProductType](map[LanguageCode]string){
+	CurrentProduct: {
+		English: "Current",
+		Russian: "Текущий",
+	},
+	SavingsProduct: {
+		English: "Savings",
+		Russian: "Сберегательный",
+	},
+	MerchantProduct: {
+		English: "Merchant",
+		Russian: "Торговый",
+	},
+}
+
+// Parameters of a new account, zero values open a current account in the default currency without a holder
+type AccountOptions struct {
+	Product    ProductType
+	Currency   string
+	CustomerID string
+	Metadata   map[string]string
+	Tags       []string
+	// Optional reference supplied by the client, repeating an opening with the same reference returns the same account
+	ClientReference string
+	// Optional existing IBAN to register the account under, a new one is generated if empty
+	Iban string
+}
+
+// Per-product rules applied on opening:
+// - merchant accounts must be held by a customer (the merchant)
+func checkProductOpening(opts AccountOptions) error {
+	if _, ok := productTypeCodeToNameMap[opts.Product]; !ok {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountCreationError][locale()])
+	}
+	if opts.Product == MerchantProduct && opts.CustomerID == "" {
+		return fmt.Errorf(errorCodesToMessagesMap[ProductRuleViolationError][locale()])
+	}
+	return nil
+}
+
+// Per-product rules applied on transfers:
+// - savings accounts can only send money to other accounts of the same customer
+func checkProductTransfer(sAcc, rAcc *Account) error {
+	if sAcc.Product == SavingsProduct && (sAcc.CustomerID == "" || sAcc.CustomerID != rAcc.CustomerID) {
+		return fmt.Errorf(errorCodesToMessagesMap[ProductRuleViolationError][locale()])
+	}
+	return nil
+}
+
+// Accounts opened without a client reference only change the shard of their IBAN, so they are opened along with each other
+func (r *InMemoryAccountRepository) OpenAccountWithOptions(opts AccountOptions) (*Account, error) {
+	opts.CustomerID = strings.TrimSpace(opts.CustomerID)
+	opts.ClientReference = strings.TrimSpace(opts.ClientReference)
+	if opts.ClientReference == "" {
+		r.Mutex.RLock()
+		defer r.Mutex.RUnlock()
+	} else {
+		r.Mutex.Lock()
+		defer r.Mutex.Unlock()
+	}
+
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+	// Checking if the account has already been opened with the same client reference, e.g. by a retried request
+	if iban, ok := r.OpeningReferences[opts.ClientReference]; ok && opts.ClientReference != "" {
+		acc, exists := r.Accounts.Get(iban)
+		if !exists || acc == nil {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+		}
+		// Reusing the reference for an opening with different parameters is most likely a client bug
+		if acc.Product != opts.Product || acc.CustomerID != opts.CustomerID || !strings.EqualFold(acc.Currency, strings.TrimSpace(opts.Currency)) ||
+			(opts.Iban != "" && !strings.EqualFold(acc.Iban, NormalizeIban(opts.Iban))) {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[ClientReferenceConflictError][locale()])
+		}
+		snapshot := acc.Snapshot()
+		return &snapshot, nil
+	}
+	// Checking if product rules allow opening the account, existence of the customer is checked by the service layer
+	if err := checkProductOpening(opts); err != nil {
+		return nil, err
+	}
+	// Checking if metadata and tags are well-formed before generating an IBAN
+	if !isValidMetadata(opts.Metadata, opts.Tags) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidMetadataError][locale()])
+	}
+
+	acc, err := r.openAccountWith(opts.Currency, opts.Iban, func(acc *Account) {
+		acc.Product = opts.Product
+		acc.CustomerID = opts.CustomerID
+		acc.Metadata = copyMetadata(opts.Metadata)
+		acc.Tags = normalizeTags(opts.Tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.ClientReference != "" {
+		r.OpeningReferences[opts.ClientReference] = acc.Iban
+	}
+	// Returning a snapshot rather than the stored object, so that the caller cannot modify it bypassing the mutex
+	snapshot := acc.Snapshot()
+	return &snapshot, nil
+}
+
+// The first account of the customer becomes their primary account
+func (s *AccountService) OpenAccountWithOptions(opts AccountOptions) (*Account, error) {
+	if err := s.authorize(OpenPermission); err != nil {
+		return s.auditedOpening(nil, err)
+	}
+	var c Customer
+	if opts.CustomerID != "" {
+		// Checking if the customer exists before opening an account on their behalf
+		var err error
+		if c, err = s.customerRepoImpl.RetrieveCustomer(opts.CustomerID); err != nil {
+			return nil, err
+		}
+		opts.CustomerID = c.ID
+	}
+	acc, err := s.auditedOpening(s.accountRepoImpl.OpenAccountWithOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	if c.ID != "" && c.PrimaryIban == "" {
+		if err := s.customerRepoImpl.SetPrimaryAccount(c.ID, acc.Iban); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
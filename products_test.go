@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+)
+
+// Open accounts of different products and enforce per-product rules
+func TestAccountProducts(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+
+	c, err := service.CreateCustomer("Ivan Ivanov", "", "", "")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.OpenAccountWithOptions(AccountOptions{Product: MerchantProduct}); err == nil {
+		t.Errorf("Opening merchant account without a customer failed to fail")
+	}
+	savings, err := service.OpenAccountWithOptions(AccountOptions{Product: SavingsProduct, CustomerID: c.ID, Tags: []string{"deposit"}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	current, err := service.OpenAccountWithOptions(AccountOptions{CustomerID: c.ID})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	stranger, err := service.OpenAccount()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if primary, _ := service.RetrievePrimaryAccount(c.ID); primary == nil || primary.Iban != savings.Iban || primary.Product != "Savings" {
+		t.Errorf("Expected savings account to be primary, got %+v", primary)
+	}
+
+	if err := service.EmitMoney(100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(emission, savings.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Savings accounts can only send money to accounts of the same customer
+	if _, err := service.TransferMoney(savings.Iban, stranger.Iban, 10); err == nil {
+		t.Errorf("Transfer from savings account to another customer failed to fail")
+	}
+	if _, err := service.TransferMoney(savings.Iban, current.Iban, 10); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
+
+// Retry account opening with the same client reference and get the same account back
+func TestIdempotentAccountOpening(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+
+	first, err := service.OpenAccountWithReference("req-42")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	retried, err := service.OpenAccountWithReference(" req-42 ")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if retried.Iban != first.Iban || inMemImpl.Accounts.Len() != 4 {
+		t.Errorf("Expected retried opening to return %s, got %s with %d accounts", first.Iban, retried.Iban, inMemImpl.Accounts.Len())
+	}
+	other, err := service.OpenAccountWithReference("req-43")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if other.Iban == first.Iban {
+		t.Errorf("Expected another reference to open another account")
+	}
+	// Reusing the reference with different parameters must be rejected
+	if _, err := service.OpenAccountWithOptions(AccountOptions{Currency: "USD", ClientReference: "req-42"}); err == nil {
+		t.Errorf("Reusing client reference with another currency failed to fail")
+	}
+	// Openings without a reference are never deduplicated
+	a, _ := service.OpenAccount()
+	b, _ := service.OpenAccount()
+	if a == nil || b == nil || a.Iban == b.Iban {
+		t.Errorf("Expected openings without a reference to open distinct accounts")
+	}
+}
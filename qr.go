@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// --------------------------------------------------------
+// Defining minimal QR code encoder (ISO/IEC 18004) supporting byte mode with medium error correction only,
+// which is what EPC QR codes require, so that payment codes can be rendered without third-party libraries
+type qrCode struct {
+	size       int
+	modules    [][]bool // dark modules by row and column
+	isFunction [][]bool // modules of finder, timing, alignment, format and version patterns
+}
+
+// Error correction codewords per block and number of blocks by version for medium error correction level
+var qrEccCodewordsPerBlock = [41]int{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28}
+var qrEccBlocks = [41]int{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49}
+
+// Bits of medium error correction level in format information
+const qrEccMediumFormatBits = 0
+
+// Number of modules available for data and error correction codewords in the given version
+func qrRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+func qrDataCodewords(version int) int {
+	return qrRawDataModules(version)/8 - qrEccCodewordsPerBlock[version]*qrEccBlocks[version]
+}
+
+// Encodes the data in byte mode choosing the smallest version it fits into, returns false if it does not fit into any
+func encodeQR(data []byte) (*qrCode, bool) {
+	version := 1
+	for ; version <= 40; version++ {
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrDataCodewords(version)*8 {
+			break
+		}
+	}
+	if version > 40 {
+		return nil, false
+	}
+
+	// Mode indicator, character count, data, terminator and padding
+	bits := []bool{}
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 != 0)
+		}
+	}
+	appendBits(0x4, 4)
+	if version >= 10 {
+		appendBits(len(data), 16)
+	} else {
+		appendBits(len(data), 8)
+	}
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	capacity := qrDataCodewords(version) * 8
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	codewords := make([]byte, 0, capacity/8)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity/8; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+
+	qr := newQRCode(version)
+	qr.drawCodewords(qrAddEccAndInterleave(codewords, version))
+	// Choosing the mask with the lowest penalty, masks are applied by XOR and so are undone by applying them again
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		qr.applyMask(mask)
+		qr.drawFormatBits(mask)
+		if penalty := qr.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		qr.applyMask(mask)
+	}
+	qr.applyMask(bestMask)
+	qr.drawFormatBits(bestMask)
+	return qr, true
+}
+
+func newQRCode(version int) *qrCode {
+	size := version*4 + 17
+	qr := &qrCode{size: size, modules: make([][]bool, size), isFunction: make([][]bool, size)}
+	for i := range qr.modules {
+		qr.modules[i] = make([]bool, size)
+		qr.isFunction[i] = make([]bool, size)
+	}
+
+	// Timing patterns
+	for i := 0; i < size; i++ {
+		qr.setFunction(6, i, i%2 == 0)
+		qr.setFunction(i, 6, i%2 == 0)
+	}
+	// Finder patterns along with their separators
+	for _, center := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := center[0]+dx, center[1]+dy
+				if x >= 0 && x < size && y >= 0 && y < size {
+					dist := qrMax(qrAbs(dx), qrAbs(dy))
+					qr.setFunction(x, y, dist != 2 && dist != 4)
+				}
+			}
+		}
+	}
+	// Alignment patterns except the ones overlapping finder patterns
+	positions := qrAlignmentPositions(version)
+	for i := range positions {
+		for j := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == len(positions)-1) || (i == len(positions)-1 && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					qr.setFunction(positions[i]+dx, positions[j]+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+				}
+			}
+		}
+	}
+	// Reserving format information modules, the actual bits are drawn once the mask is chosen
+	qr.drawFormatBits(0)
+	// Version information
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 != 0
+			a, b := size-11+i%3, i/3
+			qr.setFunction(a, b, dark)
+			qr.setFunction(b, a, dark)
+		}
+	}
+	return qr
+}
+
+// Coordinates are given as column and row
+func (qr *qrCode) setFunction(x, y int, dark bool) {
+	qr.modules[y][x] = dark
+	qr.isFunction[y][x] = true
+}
+
+func qrAlignmentPositions(version int) []int {
+	if version == 1 {
+		return []int{}
+	}
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	positions := make([]int, numAlign)
+	positions[0] = 6
+	for i, pos := numAlign-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func (qr *qrCode) drawFormatBits(mask int) {
+	data := qrEccMediumFormatBits<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	// First copy around the top left finder pattern
+	for i := 0; i <= 5; i++ {
+		qr.setFunction(8, i, bit(i))
+	}
+	qr.setFunction(8, 7, bit(6))
+	qr.setFunction(8, 8, bit(7))
+	qr.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.setFunction(14-i, 8, bit(i))
+	}
+	// Second copy split between the other two finder patterns, along with the module that is always dark
+	for i := 0; i < 8; i++ {
+		qr.setFunction(qr.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.setFunction(8, qr.size-15+i, bit(i))
+	}
+	qr.setFunction(8, qr.size-8, true)
+}
+
+// Places the codewords in the zigzag order starting from the bottom right corner and skipping function modules
+func (qr *qrCode) drawCodewords(data []byte) {
+	i := 0
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < qr.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = qr.size - 1 - vert
+				}
+				if !qr.isFunction[y][x] && i < len(data)*8 {
+					qr.modules[y][x] = (data[i>>3]>>(7-(i&7)))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (qr *qrCode) applyMask(mask int) {
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !qr.isFunction[y][x] {
+				qr.modules[y][x] = !qr.modules[y][x]
+			}
+		}
+	}
+}
+
+// Penalty of the current pattern according to the four rules of the standard, lower is better
+func (qr *qrCode) penalty() int {
+	result := 0
+	at := func(x, y int, transposed bool) bool {
+		if transposed {
+			return qr.modules[x][y]
+		}
+		return qr.modules[y][x]
+	}
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for _, transposed := range []bool{false, true} {
+		for y := 0; y < qr.size; y++ {
+			// Runs of five or more modules of the same color
+			run := 1
+			for x := 1; x <= qr.size; x++ {
+				if x < qr.size && at(x, y, transposed) == at(x-1, y, transposed) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += run - 2
+				}
+				run = 1
+			}
+			// Patterns looking like finder patterns
+			for x := 0; x+11 <= qr.size; x++ {
+				for _, pattern := range finderLike {
+					matches := true
+					for k, dark := range pattern {
+						if at(x+k, y, transposed) != dark {
+							matches = false
+							break
+						}
+					}
+					if matches {
+						result += 40
+					}
+				}
+			}
+		}
+	}
+	// Blocks of 2x2 modules of the same color
+	dark := 0
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			if qr.modules[y][x] {
+				dark++
+			}
+			if x+1 < qr.size && y+1 < qr.size {
+				c := qr.modules[y][x]
+				if c == qr.modules[y][x+1] && c == qr.modules[y+1][x] && c == qr.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+	// Imbalance of dark and light modules, 10 points per every 5% of deviation from the half
+	total := qr.size * qr.size
+	k := (qrAbs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		result += k * 10
+	}
+	return result
+}
+
+// Splits the data codewords into blocks, appends error correction codewords to every block and interleaves the blocks
+func qrAddEccAndInterleave(data []byte, version int) []byte {
+	numBlocks := qrEccBlocks[version]
+	blockEccLen := qrEccCodewordsPerBlock[version]
+	rawCodewords := qrRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := qrReedSolomonDivisor(blockEccLen)
+	blocks := [][]byte{}
+	for i, k := 0, 0; i < numBlocks; i++ {
+		length := shortBlockLen - blockEccLen
+		if i >= numShortBlocks {
+			length++
+		}
+		block := append([]byte{}, data[k:k+length]...)
+		k += length
+		ecc := qrReedSolomonRemainder(block, divisor)
+		// Short blocks get a placeholder so that all blocks have the same length while interleaving
+		if i < numShortBlocks {
+			block = append(block, 0)
+		}
+		blocks = append(blocks, append(block, ecc...))
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortBlockLen-blockEccLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+func qrReedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = qrGfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrGfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func qrReedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= qrGfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// Multiplication in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func qrGfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Renders the code as a grayscale PNG with the given number of pixels per module and the mandatory quiet zone of 4 modules
+func (qr *qrCode) png(scale int) ([]byte, error) {
+	const quietZone = 4
+	side := (qr.size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			mx, my := x/scale-quietZone, y/scale-quietZone
+			c := color.Gray{Y: 255}
+			if mx >= 0 && mx < qr.size && my >= 0 && my < qr.size && qr.modules[my][mx] {
+				c = color.Gray{Y: 0}
+			}
+			img.SetGray(x, y, c)
+		}
+	}
+	buffer := bytes.Buffer{}
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+// Error correction codewords of the "HELLO WORLD" example of the standard (version 1, medium level)
+func TestQRReedSolomon(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if ecc := qrReedSolomonRemainder(data, qrReedSolomonDivisor(10)); !bytes.Equal(ecc, expected) {
+		t.Errorf("Expected %v, got %v", expected, ecc)
+	}
+}
+
+// Codewords read back from the unmasked symbol match the encoded ones, format information is present in both copies
+func TestEncodeQR(t *testing.T) {
+	data := bytes.Repeat([]byte("BCD\n002\n1\nSCT\n"), 10)
+	qr, ok := encodeQR(data)
+	if !ok {
+		t.Fatalf("Encoding failed")
+	}
+	version := (qr.size - 17) / 4
+	if version != 8 {
+		t.Errorf("Expected version 8 for %d bytes, got %d", len(data), version)
+	}
+
+	// Finding the mask from the first copy of format information and undoing it
+	bits := 0
+	for i := 0; i <= 5; i++ {
+		if qr.modules[i][8] {
+			bits |= 1 << i
+		}
+	}
+	for i, module := range []bool{qr.modules[7][8], qr.modules[8][8], qr.modules[8][7]} {
+		if module {
+			bits |= 1 << (6 + i)
+		}
+	}
+	for i := 9; i < 15; i++ {
+		if qr.modules[8][14-i] {
+			bits |= 1 << i
+		}
+	}
+	// Checking the second copy next to the top right finder pattern
+	for i := 0; i < 8; i++ {
+		if qr.modules[8][qr.size-1-i] != (bits>>i&1 != 0) {
+			t.Errorf("Format information copies differ at bit %d", i)
+		}
+	}
+	bits ^= 0x5412
+	if level := bits >> 13; level != qrEccMediumFormatBits {
+		t.Fatalf("Unexpected error correction level %d", level)
+	}
+	mask := (bits >> 10) & 7
+	qr.applyMask(mask)
+
+	read := []byte{}
+	count := 0
+	var current byte
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < qr.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = qr.size - 1 - vert
+				}
+				if qr.isFunction[y][x] {
+					continue
+				}
+				current <<= 1
+				if qr.modules[y][x] {
+					current |= 1
+				}
+				if count++; count%8 == 0 {
+					read = append(read, current)
+				}
+			}
+		}
+	}
+	// Byte mode indicator, 8 bits of length and the data, all of them in the first (short) block
+	blocks := qrEccBlocks[version]
+	first := []byte{}
+	for i := 0; len(first) < len(data)/blocks && i*blocks < len(read); i++ {
+		first = append(first, read[i*blocks])
+	}
+	if first[0]>>4 != 0x4 || int(first[0]&0x0F)<<4|int(first[1]>>4) != len(data) || first[1]&0x0F != data[0]>>4 {
+		t.Errorf("Unexpected header %08b %08b", first[0], first[1])
+	}
+
+	img, err := qr.png(2)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(img))
+	if err != nil || decoded.Bounds().Dx() != (qr.size+8)*2 {
+		t.Errorf("Unexpected image: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining the random source of the package: generated IBANs, amounts and shuffles of the demo scenarios, jitter of the
+// retries and faults of the chaos repository all draw from it, so that the run seeded with the same value opens the same
+// accounts and makes the same transfers, see the random.seed setting
+// The source is seeded by the start time unless the seed is configured
+type Random struct {
+	rand  *rand.Rand
+	Mutex sync.Mutex
+}
+
+func NewRandom(source rand.Source) *Random {
+	return &Random{rand: rand.New(source)}
+}
+
+var random *Random = NewRandom(rand.NewSource(time.Now().UnixNano()))
+
+// Replaces the random source of the package, returns the function restoring the previous source
+func UseRandomSource(source rand.Source) func() {
+	previous := random
+	random = NewRandom(source)
+	return func() { random = previous }
+}
+
+func (r *Random) Intn(n int) int {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.rand.Intn(n)
+}
+
+func (r *Random) Int63() int64 {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.rand.Int63()
+}
+
+func (r *Random) Int63n(n int64) int64 {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.rand.Int63n(n)
+}
+
+func (r *Random) Float64() float64 {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.rand.Float64()
+}
+
+func (r *Random) Shuffle(n int, swap func(i, j int)) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	r.rand.Shuffle(n, swap)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Open the same accounts in runs seeded with the same value
+func TestReproducibleRandom(t *testing.T) {
+	open := func(seed int64) []string {
+		defer UseRandomSource(rand.NewSource(seed))()
+		service := NewAccountService(NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+		ibans := []string{}
+		for i := 0; i < 5; i++ {
+			acc, err := service.OpenAccount()
+			if err != nil {
+				t.Fatalf("Error: %v", err)
+			}
+			ibans = append(ibans, acc.Iban)
+		}
+		return ibans
+	}
+	first, second, other := open(42), open(42), open(43)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("Expected account %d to be the same in runs with the same seed, got %s and %s", i, first[i], second[i])
+		}
+	}
+	if first[0] == other[0] {
+		t.Errorf("Expected runs with different seeds to open different accounts")
+	}
+
+	config, err := LoadConfig([]string{"-random-seed", "42"})
+	if err != nil || config.RandomSeed != 42 {
+		t.Errorf("Expected the seed to be configured, got %d and %v", config.RandomSeed, err)
+	}
+	if _, err := LoadConfig([]string{"-random-seed", "forty-two"}); err == nil {
+		t.Errorf("Loading config with invalid seed failed to fail")
+	}
+}
+
+// Generate valid account numbers with crypto/rand which do not follow the seed of the random source
+func TestSecureIbanGeneration(t *testing.T) {
+	defer func(secure bool) { secureIbanGeneration = secure }(secureIbanGeneration)
+	secureIbanGeneration = true
+	generate := func() string {
+		defer UseRandomSource(rand.NewSource(42))()
+		iban, err := GenerateValidBelarusianIban()
+		if err != nil || !IsValidIban(iban) {
+			t.Fatalf("Expected valid IBAN, got %q and %v", iban, err)
+		}
+		return iban
+	}
+	if first, second := generate(), generate(); first == second {
+		t.Errorf("Expected secure IBANs not to be reproduced by the seed, got %s twice", first)
+	}
+	if digits, err := GenerateSecureRandomDigits(1000); err != nil || len(digits) != 1000 || strings.Trim(digits, "0123456789") != "" {
+		t.Errorf("Expected 1000 digits, got %d and %v", len(digits), err)
+	}
+
+	config, err := LoadConfig([]string{"-secure-iban-generation", "true"})
+	if err != nil || !config.SecureIbanGeneration {
+		t.Errorf("Expected secure generation to be configured, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining role-based access control: the service bound to a principal with As checks the permissions of its role
+// before openings, money movements, blocks, reads of accounts and changes of their limits, customers can only act on accounts
+// they hold
+// The service which is not bound to any principal acts on behalf of the system itself and is not restricted
+type Role int8
+
+const (
+	CustomerRole Role = iota // the least privileged role is the zero value
+	AuditorRole
+	TellerRole
+	AdminRole
+)
+
+// Mapping role codes to role names considering locale
+var roleCodeToNameMap map[Role](map[LanguageCode]string) = map[Role](map[LanguageCode]string){
+	CustomerRole: {
+		English: "Customer",
+		Russian: "Клиент",
+	},
+	AuditorRole: {
+		English: "Auditor",
+		Russian: "Аудитор",
+	},
+	TellerRole: {
+		English: "Teller",
+		Russian: "Операционист",
+	},
+	AdminRole: {
+		English: "Admin",
+		Russian: "Администратор",
+	},
+}
+
+type Principal struct {
+	ID         string
+	Role       Role
+	CustomerID string // customer the principal acts for, only used for the customer role
+}
+
+// Principal of requests passed by the middleware without credentials, a customer acting for no customer is denied every
+// operation checked by the service
+var anonymousPrincipal = Principal{ID: "anonymous", Role: CustomerRole}
+
+type Permission int8
+
+const (
+	ReadPermission      Permission = iota // reading accounts, transactions and the audit log
+	OpenPermission                        // opening accounts
+	TransferPermission                    // moving money between accounts
+	BlockPermission                       // blocking, activating, restricting and closing accounts
+	EmitPermission                        // emitting, destructing and adjusting money, managing the emission policy
+	KeyPermission                         // issuing, rotating and revoking API keys
+	ErasePermission                       // erasing personal data of customers
+	ConfigurePermission                   // changing limits, settings, fees and rates, running periodic jobs of the bank
+)
+
+// Permission matrix of the roles
+var rolePermissions map[Role]map[Permission]bool = map[Role]map[Permission]bool{
+	CustomerRole: {ReadPermission: true, TransferPermission: true},
+	AuditorRole:  {ReadPermission: true},
+	TellerRole:   {ReadPermission: true, OpenPermission: true, TransferPermission: true, BlockPermission: true},
+	AdminRole:    {ReadPermission: true, OpenPermission: true, TransferPermission: true, BlockPermission: true, EmitPermission: true, KeyPermission: true, ErasePermission: true, ConfigurePermission: true},
+}
+
+// Helper function to check if the principal of the service may perform the operation on the given accounts
+// Customers have to name accounts they hold, operations not naming any account are denied to them
+func (s *AccountService) authorize(permission Permission, ibans ...string) error {
+	if s.principal.ID == "" {
+		return nil
+	}
+	if !rolePermissions[s.principal.Role][permission] {
+		return fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	if s.principal.Role != CustomerRole {
+		return nil
+	}
+	if s.principal.CustomerID == "" || len(ibans) == 0 {
+		return fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	for _, iban := range ibans {
+		acc, err := s.accountRepoImpl.GetAccount(NormalizeIban(iban))
+		if err != nil || acc.CustomerID != s.principal.CustomerID {
+			return fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+		}
+	}
+	return nil
+}
+
+// Helper function to check if the principal of the service may perform the operation for the customer,
+// customers can only act for themselves
+func (s *AccountService) authorizeCustomer(permission Permission, customerID string) error {
+	if s.principal.ID == "" {
+		return nil
+	}
+	if !rolePermissions[s.principal.Role][permission] {
+		return fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	if s.principal.Role == CustomerRole && (s.principal.CustomerID == "" || !strings.EqualFold(strings.TrimSpace(customerID), s.principal.CustomerID)) {
+		return fmt.Errorf(errorCodesToMessagesMap[AccessDeniedError][locale()])
+	}
+	return nil
+}
+
+// Helper function to check if the principal of the service may perform the operation on either party of a transfer,
+// customers reach transfers both from and to accounts they hold
+func (s *AccountService) authorizeParty(permission Permission, sender, recipient string) error {
+	if err := s.authorize(permission, sender); err == nil {
+		return nil
+	}
+	return s.authorize(permission, recipient)
+}
+
+// Helper functions to check if the principal of the service may perform the operation on the account a hold, a card,
+// a card authorization or a transfer belongs to
+// Unknown IDs are left to the operation to report, customers are denied them as operations not naming any account
+func (s *AccountService) authorizeHold(permission Permission, holdID string) error {
+	if s.principal.ID == "" {
+		return nil
+	}
+	hold, err := s.accountRepoImpl.RetrieveHold(holdID)
+	if err != nil {
+		return s.authorize(permission)
+	}
+	return s.authorize(permission, hold.Iban)
+}
+
+func (s *AccountService) authorizeCard(permission Permission, cardID string) error {
+	if s.principal.ID == "" {
+		return nil
+	}
+	card, err := s.accountRepoImpl.RetrieveCard(cardID)
+	if err != nil {
+		return s.authorize(permission)
+	}
+	return s.authorize(permission, card.Iban)
+}
+
+// Both the cardholder and the merchant reach the authorization
+func (s *AccountService) authorizeAuthorization(permission Permission, authorizationID string) error {
+	if s.principal.ID == "" {
+		return nil
+	}
+	authorization, err := s.accountRepoImpl.RetrieveAuthorization(authorizationID)
+	if err != nil {
+		return s.authorize(permission)
+	}
+	return s.authorizeParty(permission, authorization.Iban, authorization.Merchant)
+}
+
+func (s *AccountService) authorizeTransfer(permission Permission, id string) error {
+	if s.principal.ID == "" {
+		return nil
+	}
+	sender, recipient, err := s.accountRepoImpl.RetrieveTransferParties(id)
+	if err != nil {
+		return s.authorize(permission)
+	}
+	return s.authorizeParty(permission, sender, recipient)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Principals are restricted by the permission matrix of their roles, customers only reach their own accounts
+func TestRoleBasedAccessControl(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	admin := service.As(Principal{ID: "admin", Role: AdminRole})
+	teller := service.As(Principal{ID: "teller", Role: TellerRole})
+	auditor := service.As(Principal{ID: "auditor", Role: AuditorRole})
+
+	customer, _ := service.CreateCustomer("Ivan Ivanov", "ivan@example.com", "+375291234567", "Minsk")
+	own, err := teller.OpenAccountForCustomer(customer.ID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	other, _ := teller.OpenAccount()
+	holder := service.As(Principal{ID: "ivan", Role: CustomerRole, CustomerID: customer.ID})
+
+	// Only admin can emit and destruct money
+	if err := teller.EmitMoney(100); err == nil {
+		t.Errorf("Emission by the teller failed to fail")
+	}
+	if err := admin.EmitMoney(100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := admin.TransferMoney(emission, own.Iban, 100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := teller.DestructMoney(own.Iban, 10); err == nil {
+		t.Errorf("Destruction by the teller failed to fail")
+	}
+
+	// Auditors only read
+	if _, err := auditor.RetrieveAccount(own.Iban); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if _, err := auditor.QueryAuditLog(AuditFilter{}); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if _, err := auditor.OpenAccount(); err == nil {
+		t.Errorf("Opening by the auditor failed to fail")
+	}
+	if _, err := auditor.TransferMoney(own.Iban, other.Iban, 10); err == nil {
+		t.Errorf("Transfer by the auditor failed to fail")
+	}
+
+	// Customers only act on their own accounts
+	if _, err := holder.TransferMoney(own.Iban, other.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := holder.TransferMoney(other.Iban, own.Iban, 10); err == nil {
+		t.Errorf("Transfer from another's account failed to fail")
+	}
+	if _, err := holder.RetrieveAccount(own.Iban); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if _, err := holder.RetrieveAccount(other.Iban); err == nil {
+		t.Errorf("Reading another's account failed to fail")
+	}
+	if _, err := holder.RetrieveAllAccountsAsJson(); err == nil {
+		t.Errorf("Reading all accounts by the customer failed to fail")
+	}
+	if _, err := holder.ListAccountsByCustomer(customer.ID); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if err := holder.BlockAccount(own.Iban); err == nil {
+		t.Errorf("Blocking by the customer failed to fail")
+	}
+
+	// Denied operations are audited too
+	denied, _ := service.QueryAuditLog(AuditFilter{Principal: "teller", Actions: []AuditAction{EmitAction}})
+	if len(denied) != 1 || denied[0].Error != errorCodesToMessagesMap[AccessDeniedError][locale()] {
+		t.Errorf("Expected denied emission to be audited, got %+v", denied)
+	}
+	if details, _ := service.RetrieveAccount(own.Iban); details.Balance != 90 {
+		t.Errorf("Expected balance of 90, got %v", details.Balance)
+	}
+}
+
+// Helper function to open an account funded with the deposit for a new customer and return the principal of the customer
+func openCustomerAccount(t *testing.T, service *AccountService, name string, deposit float64) (*Account, *AccountService) {
+	customer, err := service.CreateCustomer(name, strings.ToLower(name)+"@example.com", "+375291234567", "Minsk")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	acc, err := service.OpenAccountForCustomer(customer.ID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if deposit > 0 {
+		emission, _ := service.RetrieveEmissionAccountIban()
+		service.EmitMoney(deposit)
+		if _, err := service.TransferMoney(emission, acc.Iban, deposit); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	return acc, service.As(Principal{ID: name, Role: CustomerRole, CustomerID: customer.ID})
+}
+
+// Money movements beyond plain transfers are restricted like transfers, customers only move money of their own accounts
+func TestAuthorizationOfMoneyMovements(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	auditor := service.As(Principal{ID: "auditor", Role: AuditorRole})
+	own, holder := openCustomerAccount(t, service, "Ivan", 100)
+	other, stranger := openCustomerAccount(t, service, "Petr", 100)
+
+	if _, err := auditor.SplitTransfer(own.Iban, 10, []SplitShare{{Recipient: other.Iban, Percentage: 100}}); err == nil {
+		t.Errorf("Split transfer by the auditor failed to fail")
+	}
+	if _, err := stranger.SplitTransfer(own.Iban, 10, []SplitShare{{Recipient: other.Iban, Percentage: 100}}); err == nil {
+		t.Errorf("Split transfer from another's account failed to fail")
+	}
+	if _, err := holder.SplitTransfer(own.Iban, 10, []SplitShare{{Recipient: other.Iban, Percentage: 100}}); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if err := stranger.ConvertAndTransfer(own.Iban, other.Iban, 10); err == nil {
+		t.Errorf("Conversion from another's account failed to fail")
+	}
+	if _, err := stranger.CashWithdraw(own.Iban, 10); err == nil {
+		t.Errorf("Cash withdrawal from another's account failed to fail")
+	}
+
+	// Holds are reached through the accounts they are placed on
+	if _, err := stranger.HoldFunds(own.Iban, 10); err == nil {
+		t.Errorf("Hold on another's account failed to fail")
+	}
+	hold, err := holder.HoldFunds(own.Iban, 10)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := stranger.CaptureHold(hold.ID, other.Iban, 10); err == nil {
+		t.Errorf("Capture of another's hold failed to fail")
+	}
+	if _, err := stranger.RetrieveHold(hold.ID); err == nil {
+		t.Errorf("Reading another's hold failed to fail")
+	}
+	if _, err := stranger.CaptureHold("01ARZ3NDEKTSV4RRFFQ69G5FAV", other.Iban, 10); err == nil {
+		t.Errorf("Capture of an unknown hold failed to fail")
+	}
+	if _, err := holder.CaptureHold(hold.ID, other.Iban, 10); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	// Cards and their authorizations are reached through the accounts of the cardholder and the merchant
+	card, _ := service.IssueCard(own.Iban)
+	if _, err := holder.IssueCard(own.Iban); err == nil {
+		t.Errorf("Card issued by the customer failed to fail")
+	}
+	if _, err := stranger.AuthorizePurchase(card.ID, 10, other.Iban); err == nil {
+		t.Errorf("Purchase by another's card failed to fail")
+	}
+	authorization, err := holder.AuthorizePurchase(card.ID, 10, other.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := stranger.CapturePurchase(authorization.ID, 10); err != nil {
+		t.Errorf("Expected the merchant to capture the purchase, got %v", err)
+	}
+	if err := holder.BlockCard(card.ID); err == nil {
+		t.Errorf("Blocking the card by the customer failed to fail")
+	}
+
+	// Approvals and reversals are left to the staff
+	if err := auditor.ApproveTransfer("01ARZ3NDEKTSV4RRFFQ69G5FAV"); err == nil || err.Error() != errorCodesToMessagesMap[AccessDeniedError][locale()] {
+		t.Errorf("Expected approval by the auditor to be denied, got %v", err)
+	}
+	if _, err := holder.ReverseTransfer(hold.TransferID, "mistake"); err == nil {
+		t.Errorf("Reversal by the customer failed to fail")
+	}
+}
+
+// Limits, settings and rates of the bank and its periodic jobs are left to admins
+func TestAuthorizationOfConfiguration(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	admin := service.As(Principal{ID: "admin", Role: AdminRole})
+	teller := service.As(Principal{ID: "teller", Role: TellerRole})
+	auditor := service.As(Principal{ID: "auditor", Role: AuditorRole})
+	own, holder := openCustomerAccount(t, service, "Ivan", 100)
+
+	if err := auditor.SetOverdraftLimit(own.Iban, 100); err == nil {
+		t.Errorf("Overdraft set by the auditor failed to fail")
+	}
+	if err := holder.SetAccountLimits(own.Iban, AccountLimits{}); err == nil {
+		t.Errorf("Limits set by the customer failed to fail")
+	}
+	if err := teller.UpdateAccountSettings(own.Iban, AccountSettings{MinimumBalance: 10}); err == nil {
+		t.Errorf("Settings updated by the teller failed to fail")
+	}
+	if err := teller.SetFeeAccount(own.Iban); err == nil {
+		t.Errorf("Fee account set by the teller failed to fail")
+	}
+	if _, err := teller.ReconcileFractions(); err == nil {
+		t.Errorf("Reconciliation run by the teller failed to fail")
+	}
+	if _, err := teller.ExpireBlocks(time.Now()); err == nil {
+		t.Errorf("Expiry of blocks run by the teller failed to fail")
+	}
+	if err := admin.SetOverdraftLimit(own.Iban, 100); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if settings, err := holder.RetrieveAccountSettings(own.Iban); err != nil || settings.MinimumBalance != 0 {
+		t.Errorf("Expected the customer to read own settings, got %+v, %v", settings, err)
+	}
+}
+
+// Products, freezes and bulk imports open or restrict accounts, the staff only
+func TestAuthorizationOfAccountMaintenance(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	teller := service.As(Principal{ID: "teller", Role: TellerRole})
+	auditor := service.As(Principal{ID: "auditor", Role: AuditorRole})
+	own, holder := openCustomerAccount(t, service, "Ivan", 100)
+
+	if _, err := auditor.OpenLoan(own.Iban, 100, 0.1, 12, time.Now().AddDate(0, 1, 0)); err == nil {
+		t.Errorf("Loan opened by the auditor failed to fail")
+	}
+	if _, err := holder.OpenTermDeposit(own.Iban, 10, 0.05, 0.01, time.Now().AddDate(1, 0, 0)); err == nil {
+		t.Errorf("Term deposit opened by the customer failed to fail")
+	}
+	if err := holder.VerifyKyc(own.Iban); err == nil {
+		t.Errorf("KYC verified by the customer failed to fail")
+	}
+	if _, err := auditor.ImportAccountsCSV(strings.NewReader("")); err == nil {
+		t.Errorf("Import by the auditor failed to fail")
+	}
+	if _, err := holder.RegisterFreeze(own.Iban, "2024-1", "Court", 10); err == nil {
+		t.Errorf("Freeze registered by the customer failed to fail")
+	}
+	freeze, err := teller.RegisterFreeze(own.Iban, "2024-1", "Court", 10)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := holder.ReleaseFreeze(freeze.ID, "2024-1"); err == nil {
+		t.Errorf("Freeze released by the customer failed to fail")
+	}
+	if err := teller.ReleaseFreeze(freeze.ID, "2024-1"); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
+
+// Customers read their own accounts and transfers only, bank-wide reads are left to the staff
+func TestAuthorizationOfReads(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	auditor := service.As(Principal{ID: "auditor", Role: AuditorRole})
+	own, holder := openCustomerAccount(t, service, "Ivan", 100)
+	other, stranger := openCustomerAccount(t, service, "Petr", 100)
+
+	id, err := holder.TransferMoney(own.Iban, other.Iban, 10)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Both parties read the transfer
+	for _, principal := range []*AccountService{holder, stranger, auditor} {
+		if _, err := principal.GetTransferStatus(id); err != nil {
+			t.Errorf("Error: %v", err)
+		}
+		if _, err := principal.RetrieveTransaction(id); err != nil {
+			t.Errorf("Error: %v", err)
+		}
+	}
+	third, outsider := openCustomerAccount(t, service, "Anna", 0)
+	if _, err := outsider.GetTransferStatus(id); err == nil {
+		t.Errorf("Reading another's transfer status failed to fail")
+	}
+	if _, err := outsider.RetrieveTransaction(id); err == nil {
+		t.Errorf("Reading another's transaction failed to fail")
+	}
+	if _, err := outsider.GetAccount(own.Iban); err == nil {
+		t.Errorf("Reading another's account failed to fail")
+	}
+	if _, err := outsider.ListBeneficiaries(own.Iban); err == nil {
+		t.Errorf("Reading another's beneficiaries failed to fail")
+	}
+	if _, err := outsider.GetAccount(third.Iban); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	var output strings.Builder
+	if err := holder.ExportAccounts(&output, CsvExportFormat); err == nil {
+		t.Errorf("Export by the customer failed to fail")
+	}
+	if _, err := holder.FindAccounts(AccountQuery{}); err == nil {
+		t.Errorf("Search by the customer failed to fail")
+	}
+	if _, err := holder.RetrieveJournalAsJson(); err == nil {
+		t.Errorf("Reading the journal by the customer failed to fail")
+	}
+	if accounts, _ := holder.StreamAccounts(); len(accounts) != 0 {
+		t.Errorf("Expected nothing streamed to the customer")
+	}
+	if err := auditor.ExportAccounts(&output, CsvExportFormat); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if _, err := auditor.VerifyInvariants(); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining hot reload of the configuration: the file is watched for changes and reloaded on SIGHUP as well, the settings
+// which can be changed on the fly (locale, fees, default limits and rate limits) are applied to the running program
+// A configuration which is not valid or changes settings requiring a restart is rejected as a whole, the running
+// configuration stays in effect
+type ConfigReloader struct {
+	args      []string // command-line arguments the configuration was loaded with, flags keep overriding the file on reload
+	repo      *InMemoryAccountRepository
+	current   Config
+	modified  time.Time // modification time of the file when it was last loaded
+	Reloads   uint64    // configurations applied since the start
+	Failures  uint64    // configurations rejected since the start
+	lastError error
+	Mutex     sync.Mutex
+}
+
+// Applies the reloadable settings to the repository, package-wide settings are applied by ApplyReloadable
+func (c Config) ApplyTo(r *InMemoryAccountRepository) error {
+	if c.Fees != nil {
+		r.Mutex.Lock()
+		if r.FeeSchedule == nil {
+			r.FeeSchedule = NewFeeSchedule()
+		}
+		schedule := r.FeeSchedule
+		r.Mutex.Unlock()
+		if err := schedule.ReplaceFees(c.Fees); err != nil {
+			return err
+		}
+	}
+	if err := r.SetDefaultLimits(c.DefaultLimits); err != nil {
+		return err
+	}
+	if err := r.SetCashLimits(c.CashLimits); err != nil {
+		return err
+	}
+	return r.SetApiKeyRateLimit(c.ApiKeyRateLimit)
+}
+
+// Helper function to clear the reloadable settings, so that configurations can be compared by the rest of the settings
+func (c Config) withoutReloadable() Config {
+	c.Locale, c.Fees, c.DefaultLimits, c.CashLimits, c.ApiKeyRateLimit = English, nil, AccountLimits{}, CashLimits{}, 0
+	return c
+}
+
+// The configuration is expected to be applied already, see Apply and ApplyTo
+func NewConfigReloader(args []string, current Config, repo *InMemoryAccountRepository) *ConfigReloader {
+	reloader := &ConfigReloader{args: append([]string{}, args...), repo: repo, current: current}
+	if info, err := os.Stat(current.File); current.File != "" && err == nil {
+		reloader.modified = info.ModTime()
+	}
+	return reloader
+}
+
+func (r *ConfigReloader) Current() Config {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.current
+}
+
+// Returns the reason the last configuration was rejected for, nil if it was applied
+func (r *ConfigReloader) LastError() error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.lastError
+}
+
+// Loads the configuration again and applies its reloadable settings, settings applied before a failure are rolled back
+func (r *ConfigReloader) Reload() error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.record(r.reload())
+}
+
+// Helper function to count the outcome of the reload, expects the reloader mutex to be held by the caller
+func (r *ConfigReloader) record(err error) error {
+	r.lastError = err
+	if err != nil {
+		r.Failures++
+		return err
+	}
+	r.Reloads++
+	return nil
+}
+
+// Helper function to reload the configuration, expects the reloader mutex to be held by the caller
+func (r *ConfigReloader) reload() error {
+	next, err := LoadConfig(r.args)
+	if err != nil {
+		return err
+	}
+	// Checking if only reloadable settings have changed
+	if !reflect.DeepEqual(next.withoutReloadable(), r.current.withoutReloadable()) {
+		return fmt.Errorf(errorCodesToMessagesMap[ConfigRestartRequiredError][locale()])
+	}
+	// Fees removed from the configuration are removed from the schedule as well
+	if next.Fees == nil && r.current.Fees != nil {
+		next.Fees = map[TransactionType]FeeRule{}
+	}
+	// Taking the fees in effect, so that they can be restored even if the schedule was not set by the configuration
+	rollback := r.current
+	r.repo.Mutex.Lock()
+	if schedule := r.repo.FeeSchedule; schedule != nil {
+		rollback.Fees = schedule.Fees()
+	}
+	r.repo.Mutex.Unlock()
+	if err := next.ApplyTo(r.repo); err != nil {
+		rollback.ApplyTo(r.repo)
+		return err
+	}
+	next.ApplyReloadable()
+	r.current = next
+	return nil
+}
+
+// Helper function to reload the configuration if the file has been modified since it was last loaded
+func (r *ConfigReloader) reloadIfModified() {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	if r.current.File == "" {
+		return
+	}
+	info, err := os.Stat(r.current.File)
+	if err != nil || !info.ModTime().After(r.modified) {
+		return
+	}
+	r.modified = info.ModTime()
+	r.record(r.reload())
+}
+
+// Starts watching the file for changes every interval and reloading on SIGHUP, returns the function stopping it
+// once the reload in progress, if any, has finished
+func (r *ConfigReloader) Start(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				r.reloadIfModified()
+			case <-hangups:
+				r.Reload()
+			case <-done:
+				ticker.Stop()
+				signal.Stop(hangups)
+				return
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Change reloadable settings in the file and make sure they are applied while invalid files and restarts are rejected
+func TestConfigReload(t *testing.T) {
+	defer setLocale(locale())
+	path := filepath.Join(t.TempDir(), "payments.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	write("fees:\n  transfer: flat:1\nlimits:\n  per_transaction: 100\napi_keys:\n  rate_limit: 1\n")
+	args := []string{"-config", path}
+	config, err := LoadConfig(args)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl, _ := NewInMemoryAccountRepositoryWithChart(DefaultChartOfAccounts())
+	service := NewAccountService(inMemImpl)
+	if err := config.ApplyTo(inMemImpl); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// Default limits and rate limits apply to accounts and keys without their own
+	acc, _ := service.OpenAccount()
+	service.EmitMoney(500)
+	service.TransferMoney(emission, acc.Iban, 500)
+	if _, err := service.TransferMoney(acc.Iban, emission, 150); err == nil {
+		t.Errorf("Transfer above the default limit failed to fail")
+	}
+	if _, err := service.TransferMoney(acc.Iban, emission, 50); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if details, _ := service.RetrieveAccount(acc.Iban); details.Balance != 449 {
+		t.Errorf("Expected the fee of the configuration to be charged, got balance %.2f", details.Balance)
+	}
+	_, token, _ := service.IssueApiKey(Principal{ID: "client"}, []ApiKeyScope{ReadScope}, 0)
+	now := time.Now()
+	service.AuthenticateApiKey(token, ReadScope, now)
+	if _, err := service.AuthenticateApiKey(token, ReadScope, now); err == nil {
+		t.Errorf("Request above the default rate limit failed to fail")
+	}
+
+	reloader := NewConfigReloader(args, config, inMemImpl)
+	write("locale: ru\nfees:\n  transfer: percentage:1:0.5:10\n  internal_move: flat:0.1\nlimits:\n  per_transaction: 200\n")
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if locale() != Russian || inMemImpl.DefaultLimits.PerTransaction != 200 || inMemImpl.ApiKeyRateLimit != 0 {
+		t.Errorf("Expected reloaded settings, got locale %d, limits %+v and rate limit %d", locale(), inMemImpl.DefaultLimits, inMemImpl.ApiKeyRateLimit)
+	}
+	if fees := inMemImpl.FeeSchedule.Fees(); len(fees) != 2 || fees[TransferTransaction].Kind != PercentageFee || fees[TransferTransaction].Maximum != 10 {
+		t.Errorf("Expected reloaded fees, got %+v", fees)
+	}
+	if _, err := service.TransferMoney(acc.Iban, emission, 150); err != nil {
+		t.Errorf("Expected reloaded limit to allow the transfer, got %v", err)
+	}
+
+	// Invalid configurations and ones requiring a restart leave the running configuration in effect
+	for _, content := range []string{"fees:\n  transfer: percentage:1:10:5\n", "locale: de\n", "http_addr: \":8080\"\n", "broken"} {
+		write(content)
+		if err := reloader.Reload(); err == nil {
+			t.Errorf("Reload of %q failed to fail", content)
+		}
+		if reloader.Current().DefaultLimits.PerTransaction != 200 || inMemImpl.DefaultLimits.PerTransaction != 200 || len(inMemImpl.FeeSchedule.Fees()) != 2 {
+			t.Errorf("Expected the running configuration to stay in effect after reload of %q", content)
+		}
+	}
+	if reloader.Reloads != 1 || reloader.Failures != 4 || reloader.LastError() == nil {
+		t.Errorf("Unexpected reload counters %d and %d (%v)", reloader.Reloads, reloader.Failures, reloader.LastError())
+	}
+
+	// Fees removed from the file are removed from the schedule, changes of the file are picked up by the watcher
+	write("locale: en\n")
+	os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	reloader.reloadIfModified()
+	if fees := inMemImpl.FeeSchedule.Fees(); len(fees) != 0 || locale() != English {
+		t.Errorf("Expected fees to be removed by the modified file, got %+v", fees)
+	}
+	reloader.reloadIfModified()
+	if reloader.Reloads != 2 {
+		t.Errorf("Expected unmodified file not to be reloaded, got %d reloads", reloader.Reloads)
+	}
+
+	// SIGHUP reloads the configuration as well
+	write("limits:\n  daily: 1000\n")
+	stop := reloader.Start(time.Hour)
+	defer stop()
+	process, _ := os.FindProcess(os.Getpid())
+	process.Signal(syscall.SIGHUP)
+	for deadline := time.Now().Add(2 * time.Second); reloader.Current().DefaultLimits.Daily != 1000 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reloader.Current().DefaultLimits.Daily != 1000 {
+		t.Errorf("Expected the configuration to be reloaded on SIGHUP")
+	}
+}
+
+// Reloads change only the reloadable settings while messages are translated, the bank directory and the random source of
+// the start stay in effect
+func TestConfigReloadKeepsStartupSettings(t *testing.T) {
+	defer setLocale(locale())
+	defer func(directory BankDirectory, previous *Random) { bankDirectory, random = directory, previous }(bankDirectory, random)
+	dir := t.TempDir()
+	banks, path := filepath.Join(dir, "banks.json"), filepath.Join(dir, "payments.yaml")
+	os.WriteFile(banks, []byte(`{"de": {"37040044": "Commerzbank"}}`), 0600)
+	content := "bank_directory_file: " + banks + "\nrandom:\n  seed: 7\n"
+	os.WriteFile(path, []byte(content), 0600)
+	args := []string{"-config", path}
+	config, err := LoadConfig(args)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	config.Apply()
+	seeded := random
+	reloader := NewConfigReloader(args, config, NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001"))
+
+	os.WriteFile(banks, []byte(`{"de": {"10010010": "Postbank"}}`), 0600)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_ = errorCodesToMessagesMap[InvalidConfigError][locale()]
+		}
+	}()
+	for _, code := range []string{"ru", "en", "ru"} {
+		os.WriteFile(path, []byte(content+"locale: "+code+"\n"), 0600)
+		if err := reloader.Reload(); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	<-done
+	if locale() != Russian {
+		t.Errorf("Expected the locale to be reloaded, got %d", locale())
+	}
+	if random != seeded {
+		t.Errorf("Expected the random source not to be seeded again by reloads")
+	}
+	if err := ValidateIban("DE89 3704 0044 0532 0130 00"); err != nil {
+		t.Errorf("Expected the bank directory of the start to stay in effect, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining periodic regulatory reports: balances by account type at the time of the report, transactions of the
+// period above the reporting threshold and blocked accounts, built from the accounts and the transaction log
+type ReportFormat int8
+
+const (
+	JsonReportFormat ReportFormat = iota
+	CsvReportFormat
+)
+
+type TypeBalance struct {
+	Type     string  `json:"type"`
+	Currency string  `json:"currency"`
+	Accounts int     `json:"accounts"`
+	Balance  float64 `json:"balance"`
+}
+
+type LargeTransaction struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Sender    string    `json:"sender,omitempty"`
+	Recipient string    `json:"recipient,omitempty"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type BlockedAccount struct {
+	Iban     string  `json:"iban"`
+	Type     string  `json:"type"`
+	Balance  float64 `json:"balance"`
+	Currency string  `json:"currency"`
+}
+
+type RegulatoryReport struct {
+	From              time.Time          `json:"from"`
+	To                time.Time          `json:"to"`
+	Threshold         float64            `json:"threshold"` // transactions of larger amounts in any currency are reported
+	GeneratedAt       time.Time          `json:"generated_at"`
+	Balances          []TypeBalance      `json:"balances"`
+	LargeTransactions []LargeTransaction `json:"large_transactions"`
+	BlockedAccounts   []BlockedAccount   `json:"blocked_accounts"`
+}
+
+// Builds the report for transactions made in [from, to), balances and statuses are reported as of now
+func (s *AccountService) RegulatoryReport(from, to time.Time, threshold float64) (*RegulatoryReport, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	// Checking if the threshold is valid
+	if threshold < 0 || math.IsNaN(threshold) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	report := &RegulatoryReport{From: from, To: to, Threshold: threshold, GeneratedAt: clock.Now().UTC(), Balances: []TypeBalance{}, LargeTransactions: []LargeTransaction{}, BlockedAccounts: []BlockedAccount{}}
+
+	balances := map[string]*TypeBalance{}
+	if err := s.accountRepoImpl.ForEachAccount(func(acc Account) bool {
+		typeName := accountTypeCodeToNameMap[acc.Type][locale()]
+		key := typeName + "|" + acc.Currency
+		if balances[key] == nil {
+			balances[key] = &TypeBalance{Type: typeName, Currency: acc.Currency}
+		}
+		balances[key].Accounts++
+		balances[key].Balance = roundToCurrency(balances[key].Balance+acc.Balance, acc.Currency)
+		if acc.Status == Blocked {
+			report.BlockedAccounts = append(report.BlockedAccounts, BlockedAccount{acc.Iban, typeName, acc.Balance, acc.Currency})
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	for _, balance := range balances {
+		report.Balances = append(report.Balances, *balance)
+	}
+	sort.Slice(report.Balances, func(i, j int) bool {
+		if report.Balances[i].Type != report.Balances[j].Type {
+			return report.Balances[i].Type < report.Balances[j].Type
+		}
+		return report.Balances[i].Currency < report.Balances[j].Currency
+	})
+	sort.Slice(report.BlockedAccounts, func(i, j int) bool { return report.BlockedAccounts[i].Iban < report.BlockedAccounts[j].Iban })
+
+	transactions, err := s.accountRepoImpl.RetrieveTransactionsBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range transactions {
+		if tx.Amount > threshold {
+			report.LargeTransactions = append(report.LargeTransactions, LargeTransaction{tx.Ulid, transactionTypeCodeToNameMap[tx.Type][locale()], tx.Sender, tx.Recipient, tx.Amount, tx.Currency, tx.Timestamp})
+		}
+	}
+	return report, nil
+}
+
+// Column names of the CSV report, every row belongs to one of the sections: balance, large_transaction or blocked_account
+var regulatoryReportCsvHeader = []string{"section", "id", "type", "iban", "counterparty", "currency", "accounts", "amount", "timestamp"}
+
+// Writes the report as one JSON document or as CSV with a header row
+func (report *RegulatoryReport) Write(writer io.Writer, format ReportFormat) error {
+	switch format {
+	case JsonReportFormat:
+		if err := json.NewEncoder(writer).Encode(report); err != nil {
+			return fmt.Errorf(errorCodesToMessagesMap[AccountsExportError][locale()])
+		}
+		return nil
+	case CsvReportFormat:
+	default:
+		return fmt.Errorf(errorCodesToMessagesMap[AccountsExportError][locale()])
+	}
+
+	amount := func(amount float64, currency string) string {
+		return strconv.FormatFloat(roundToCurrency(amount, currency), 'f', minorUnitsOf(currency), 64)
+	}
+	rows := [][]string{regulatoryReportCsvHeader}
+	for _, b := range report.Balances {
+		rows = append(rows, []string{"balance", "", b.Type, "", "", b.Currency, strconv.Itoa(b.Accounts), amount(b.Balance, b.Currency), ""})
+	}
+	for _, tx := range report.LargeTransactions {
+		rows = append(rows, []string{"large_transaction", tx.ID, tx.Type, tx.Sender, tx.Recipient, tx.Currency, "", amount(tx.Amount, tx.Currency), tx.Timestamp.Format(time.RFC3339)})
+	}
+	for _, acc := range report.BlockedAccounts {
+		rows = append(rows, []string{"blocked_account", "", acc.Type, acc.Iban, "", acc.Currency, "", amount(acc.Balance, acc.Currency), ""})
+	}
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountsExportError][locale()])
+	}
+	return nil
+}
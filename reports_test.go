@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Report lists balances by account type, transactions above the threshold and blocked accounts
+func TestRegulatoryReport(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	from := time.Now().Add(-time.Minute)
+	sender, _ := service.OpenAccountWithInitialDeposit(5000)
+	recipient, _ := service.OpenAccount()
+	service.VerifyKyc(sender.Iban)
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 1500); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(sender.Iban, recipient.Iban, 20); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.BlockAccount(recipient.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err := service.RegulatoryReport(from, time.Now().Add(time.Minute), -1); err == nil {
+		t.Errorf("Negative threshold failed to fail")
+	}
+	report, err := service.RegulatoryReport(from, time.Now().Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// Emission of the initial deposit, its move to the account and the large transfer
+	if len(report.LargeTransactions) != 3 || report.LargeTransactions[2].Amount != 1500 || report.LargeTransactions[2].Recipient != recipient.Iban {
+		t.Errorf("Unexpected large transactions: %+v", report.LargeTransactions)
+	}
+	if len(report.BlockedAccounts) != 1 || report.BlockedAccounts[0].Iban != recipient.Iban || report.BlockedAccounts[0].Balance != 1520 {
+		t.Errorf("Unexpected blocked accounts: %+v", report.BlockedAccounts)
+	}
+	ordinary := accountTypeCodeToNameMap[Ordinary][locale()]
+	for _, b := range report.Balances {
+		if b.Type == ordinary && (b.Accounts != 2 || b.Balance != 5000) {
+			t.Errorf("Expected 2 ordinary accounts holding 5000, got %+v", b)
+		}
+	}
+	if earlier, _ := service.RegulatoryReport(from.Add(-time.Hour), from, 1000); len(earlier.LargeTransactions) != 0 {
+		t.Errorf("Expected no transactions before the period, got %+v", earlier.LargeTransactions)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, JsonReportFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var decoded RegulatoryReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.LargeTransactions) != 3 {
+		t.Errorf("Unexpected JSON report: %s (%v)", buf.String(), err)
+	}
+	buf.Reset()
+	if err := report.Write(&buf, CsvReportFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(rows) != 1+len(report.Balances)+3+1 || rows[0][0] != "section" || rows[len(rows)-1][0] != "blocked_account" || rows[len(rows)-1][7] != "1520.00" {
+		t.Errorf("Unexpected CSV report: %v", rows)
+	}
+}
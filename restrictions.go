@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+)
+
+// --------------------------------------------------------
+// Defining partial blocks: unlike a block, a restriction freezes money movements in one direction only,
+// i.e. an account with frozen debits still receives credits, restrictions apply on top of the account status
+type AccountRestriction int8
+
+const (
+	NoRestriction AccountRestriction = iota
+	DebitsFrozen
+	CreditsFrozen
+)
+
+// Mapping restriction codes to restriction names considering locale
+var accountRestrictionCodeToNameMap map[AccountRestriction](map[LanguageCode]string) = map[AccountRestriction](map[LanguageCode]string){
+	NoRestriction: {
+		English: "None",
+		Russian: "Нет",
+	},
+	DebitsFrozen: {
+		English: "Debits frozen",
+		Russian: "Списания приостановлены",
+	},
+	CreditsFrozen: {
+		English: "Credits frozen",
+		Russian: "Зачисления приостановлены",
+	},
+}
+
+// Helper functions to check if the restriction of the account allows money to leave or to enter it
+func checkDebitRestriction(acc *Account) error {
+	if acc.Restriction == DebitsFrozen {
+		return fmt.Errorf(errorCodesToMessagesMap[DebitsFrozenError][locale()])
+	}
+	return nil
+}
+
+func checkCreditRestriction(acc *Account) error {
+	if acc.Restriction == CreditsFrozen {
+		return fmt.Errorf(errorCodesToMessagesMap[CreditsFrozenError][locale()])
+	}
+	return nil
+}
+
+// NoRestriction lifts the restriction of the account
+func (r *InMemoryAccountRepository) RestrictAccount(iban string, restriction AccountRestriction) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the restriction is known and the account is not closed
+	if _, ok := accountRestrictionCodeToNameMap[restriction]; !ok {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidBlockError][locale()])
+	}
+	if acc.Status == Closed {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	acc.Restriction = restriction
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Account with frozen debits still receives credits and vice versa
+func TestAccountRestrictions(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	first, _ := service.OpenAccountWithInitialDeposit(100)
+	second, _ := service.OpenAccountWithInitialDeposit(100)
+
+	if err := service.RestrictAccount(first.Iban, AccountRestriction(42)); err == nil {
+		t.Errorf("Unknown restriction failed to fail")
+	}
+	if err := service.RestrictAccount(first.Iban, DebitsFrozen); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(first.Iban, second.Iban, 10); err == nil {
+		t.Errorf("Debit of account with frozen debits failed to fail")
+	}
+	if err := service.DestructMoney(first.Iban, 10); err == nil {
+		t.Errorf("Destruction from account with frozen debits failed to fail")
+	}
+	if _, err := service.HoldFunds(first.Iban, 10); err == nil {
+		t.Errorf("Hold on account with frozen debits failed to fail")
+	}
+	if err := service.CloseAccount(first.Iban, second.Iban); err == nil {
+		t.Errorf("Closing account with frozen debits failed to fail")
+	}
+	if err := service.CloseAccount(first.Iban, ""); err == nil {
+		t.Errorf("Sweeping account with frozen debits to the destruction account failed to fail")
+	}
+	if _, err := service.TransferMoney(second.Iban, first.Iban, 10); err != nil {
+		t.Errorf("Expected credit of account with frozen debits to succeed, got %v", err)
+	}
+	if details, _ := service.RetrieveAccount(first.Iban); details.Restriction != accountRestrictionCodeToNameMap[DebitsFrozen][locale()] || details.Balance != 110 {
+		t.Errorf("Unexpected account details: %+v", details)
+	}
+
+	if err := service.RestrictAccount(first.Iban, CreditsFrozen); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(second.Iban, first.Iban, 10); err == nil {
+		t.Errorf("Credit of account with frozen credits failed to fail")
+	}
+	if _, err := service.TransferMoney(first.Iban, second.Iban, 10); err != nil {
+		t.Errorf("Expected debit of account with frozen credits to succeed, got %v", err)
+	}
+	if err := service.DestructMoney(first.Iban, 10); err != nil {
+		t.Errorf("Expected destruction from account with frozen credits to succeed, got %v", err)
+	}
+
+	if err := service.RestrictAccount(first.Iban, NoRestriction); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.TransferMoney(second.Iban, first.Iban, 10); err != nil {
+		t.Errorf("Expected credit after the restriction is lifted to succeed, got %v", err)
+	}
+	if details, _ := service.RetrieveAccount(first.Iban); details.Restriction != "" || details.Balance != 100 {
+		t.Errorf("Unexpected account details: %+v", details)
+	}
+}
+
+// Reversals and loan installments move money like transfers do, so they respect the restrictions as well
+func TestRestrictionsOfReversalsAndInstallments(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccountWithInitialDeposit(0)
+	id, err := service.TransferMoney(sender.Iban, recipient.Iban, 100)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service.RestrictAccount(recipient.Iban, DebitsFrozen)
+	if _, err := service.ReverseTransfer(id, "disputed"); err == nil {
+		t.Errorf("Reversal out of account with frozen debits failed to fail")
+	}
+	service.RestrictAccount(recipient.Iban, NoRestriction)
+	service.RestrictAccount(sender.Iban, CreditsFrozen)
+	if _, err := service.ReverseTransfer(id, "disputed"); err == nil {
+		t.Errorf("Reversal into account with frozen credits failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 100 {
+		t.Errorf("Expected the money to stay with the recipient, got balance %v", details.Balance)
+	}
+
+	firstDue := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)
+	loan, err := service.OpenLoan(recipient.Iban, 300, 0, 3, firstDue)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service.RestrictAccount(recipient.Iban, DebitsFrozen)
+	if collected, _ := service.CollectDueInstallments(firstDue); collected != 0 {
+		t.Errorf("Expected no installment to be collected from account with frozen debits, got %d", collected)
+	}
+	if details, _ := service.RetrieveLoan(loan.Iban); details.Schedule[0].Status != InstallmentOverdue {
+		t.Errorf("Expected the installment to be overdue, got %+v", details.Schedule[0])
+	}
+	service.RestrictAccount(recipient.Iban, NoRestriction)
+	if collected, _ := service.CollectDueInstallments(firstDue); collected != 1 {
+		t.Errorf("Expected the installment to be collected once the restriction is lifted, got %d", collected)
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining retries of repository operations against flaky backends: operations failing with transient errors of the
+// backend are attempted again after the exponentially growing delay with jitter, so that clients retrying at once do not
+// hit the recovering backend together
+// Only operations which are safe to repeat are retried: reads, operations deduplicated by the idempotency key of the
+// client, and emissions, destructions and transfers, which are made idempotent by the key generated per call, so that the
+// attempt which reached the backend before the connection broke is never executed twice, other operations are attempted
+// once
+type RetryPolicy struct {
+	MaxAttempts int                  // attempts including the first one, operations are not retried if 1 or less
+	BaseDelay   time.Duration        // delay before the second attempt, doubled for each next attempt
+	MaxDelay    time.Duration        // upper bound of the delay, not bounded if 0
+	Retryable   func(err error) bool // classifies errors as transient, isRetryableError is used if nil
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: 2 * time.Second}
+}
+
+// Errors of the error map are answers of the backend which will be the same next time, the open circuit of the breaker
+// included, any other error, i.e. the broken connection or the timeout, is treated as transient
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := errorCodeOf(err)
+	return !ok
+}
+
+// Returns the delay before the given attempt, a random value between the half and the whole of the exponential delay
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 2; i < attempt && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(random.Int63n(int64(delay/2)+1))
+}
+
+type RetryingAccountRepository struct {
+	AccountRepository
+	policy RetryPolicy
+	sleep  func(time.Duration)
+	newKey func() string // generates keys making plain money movements idempotent
+}
+
+func NewRetryingAccountRepository(r AccountRepository, policy RetryPolicy) *RetryingAccountRepository {
+	return &RetryingAccountRepository{r, policy, time.Sleep, func() string { return "retry-" + NewUlid(clock.Now()) }}
+}
+
+// Helper function to call the operation until it succeeds, fails with the error which is not transient or runs out
+// of attempts, the error of the last attempt is returned
+func (r *RetryingAccountRepository) retry(call func() error) error {
+	retryable := r.policy.Retryable
+	if retryable == nil {
+		retryable = isRetryableError
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = call(); err == nil || !retryable(err) || attempt >= r.policy.MaxAttempts {
+			return err
+		}
+		r.sleep(r.policy.Backoff(attempt + 1))
+	}
+}
+
+// Retrying the money movements by idempotent counterparts, every attempt carries the same key generated for the call
+func (r *RetryingAccountRepository) EmitMoney(amount float64) error {
+	key := r.newKey()
+	return r.retry(func() error { return r.AccountRepository.EmitMoneyIdempotent(key, amount) })
+}
+
+func (r *RetryingAccountRepository) DestructMoney(iban string, amount float64) error {
+	key := r.newKey()
+	return r.retry(func() error { return r.AccountRepository.DestructMoneyIdempotent(key, iban, amount) })
+}
+
+func (r *RetryingAccountRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	return r.TransferMoneyWithDetails(sender, recipient, amount, TransferDetails{})
+}
+
+func (r *RetryingAccountRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	return r.TransferMoneyIdempotent(r.newKey(), sender, recipient, amount, details)
+}
+
+// Operations keyed by the client are retried as they are, the operation without the key is not deduplicated by the
+// backend and is attempted once
+func (r *RetryingAccountRepository) EmitMoneyIdempotent(key string, amount float64) error {
+	if strings.TrimSpace(key) == "" {
+		return r.AccountRepository.EmitMoneyIdempotent(key, amount)
+	}
+	return r.retry(func() error { return r.AccountRepository.EmitMoneyIdempotent(key, amount) })
+}
+
+func (r *RetryingAccountRepository) DestructMoneyIdempotent(key, iban string, amount float64) error {
+	if strings.TrimSpace(key) == "" {
+		return r.AccountRepository.DestructMoneyIdempotent(key, iban, amount)
+	}
+	return r.retry(func() error { return r.AccountRepository.DestructMoneyIdempotent(key, iban, amount) })
+}
+
+func (r *RetryingAccountRepository) TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	if strings.TrimSpace(key) == "" {
+		return r.AccountRepository.TransferMoneyIdempotent(key, sender, recipient, amount, details)
+	}
+	var transferID string
+	err := r.retry(func() (err error) {
+		transferID, err = r.AccountRepository.TransferMoneyIdempotent(key, sender, recipient, amount, details)
+		return err
+	})
+	return transferID, err
+}
+
+// Reads
+func (r *RetryingAccountRepository) RetrieveEmissionAccountIban() (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveEmissionAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveDestructionAccountIban() (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveDestructionAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) GetTransferStatus(id string) (TransferStatus, error) {
+	var result TransferStatus
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.GetTransferStatus(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveTransferParties(id string) (string, string, error) {
+	var result string
+	var second string
+	err := r.retry(func() (err error) {
+		result, second, err = r.AccountRepository.RetrieveTransferParties(id)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *RetryingAccountRepository) ListPendingApprovals() ([]Approval, error) {
+	var result []Approval
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListPendingApprovals()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveAccountSettings(iban string) (AccountSettings, error) {
+	var result AccountSettings
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountSettings(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListInterestAccruals(iban string) ([]InterestAccrual, error) {
+	var result []InterestAccrual
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListInterestAccruals(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListInterestPostings(iban string) ([]InterestPosting, error) {
+	var result []InterestPosting
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListInterestPostings(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveLoan(iban string) (Loan, error) {
+	var result Loan
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveLoan(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveTermDeposit(iban string) (TermDeposit, error) {
+	var result TermDeposit
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveTermDeposit(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveHold(holdID string) (Hold, error) {
+	var result Hold
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveHold(holdID)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveCard(cardID string) (Card, error) {
+	var result Card
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveCard(cardID)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListCards(iban string) ([]Card, error) {
+	var result []Card
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListCards(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveAuthorization(authorizationID string) (Authorization, error) {
+	var result Authorization
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAuthorization(authorizationID)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListIncomingPaymentRequests(payer string) ([]PaymentRequest, error) {
+	var result []PaymentRequest
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListIncomingPaymentRequests(payer)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListBeneficiaries(iban string) ([]Beneficiary, error) {
+	var result []Beneficiary
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListBeneficiaries(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ResolveAlias(alias string) (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ResolveAlias(alias)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveInterbankTransfer(correlationID string) (InterbankTransfer, error) {
+	var result InterbankTransfer
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveInterbankTransfer(correlationID)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveVostroBalance(bank string) (float64, string, error) {
+	var result float64
+	var second string
+	err := r.retry(func() (err error) {
+		result, second, err = r.AccountRepository.RetrieveVostroBalance(bank)
+		return err
+	})
+	return result, second, err
+}
+
+func (r *RetryingAccountRepository) RetrieveSupplyRegister(from, to time.Time) ([]SupplyRecord, error) {
+	var result []SupplyRecord
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveSupplyRegister(from, to)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListPendingEmissions() ([]EmissionApproval, error) {
+	var result []EmissionApproval
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListPendingEmissions()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) MoneySupply() (*MoneySupply, error) {
+	var result *MoneySupply
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.MoneySupply()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveAllAccountsAsJson() (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAllAccountsAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveAccount(iban string) (*AccountDetails, error) {
+	var result *AccountDetails
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccount(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveAccountAsJson(iban string) (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountAsJson(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) GetAccount(iban string) (Account, error) {
+	var result Account
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.GetAccount(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) FindAccounts(query AccountQuery) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.FindAccounts(query)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveSnapshot(ibans ...string) (*RepositorySnapshot, error) {
+	var result *RepositorySnapshot
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveSnapshot(ibans...)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListBlockedAccounts() ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListBlockedAccounts()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListAccountsByType(accType AccountType) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListAccountsByType(accType)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListDormantAccounts(since time.Time) ([]AccountDetails, error) {
+	var result []AccountDetails
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListDormantAccounts(since)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListFreezes(iban string) ([]LegalFreeze, error) {
+	var result []LegalFreeze
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListFreezes(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListAdjustments(iban string) ([]Adjustment, error) {
+	var result []Adjustment
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListAdjustments(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListApiKeys(principalID string) ([]ApiKey, error) {
+	var result []ApiKey
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListApiKeys(principalID)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) CheckApiKey(id string, scope ApiKeyScope, now time.Time) (*ApiKey, error) {
+	var result *ApiKey
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.CheckApiKey(id, scope, now)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListConsents(iban string) ([]Consent, error) {
+	var result []Consent
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListConsents(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) ListPendingChallenges(iban string) ([]Challenge, error) {
+	var result []Challenge
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.ListPendingChallenges(iban)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveRemainderAccountIban() (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveRemainderAccountIban()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveSystemAccountIban(role SystemAccountRole) (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveSystemAccountIban(role)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveAllTransactionsAsJson() (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAllTransactionsAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveAccountTransactions(iban string, limit int) ([]Transaction, error) {
+	var result []Transaction
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveAccountTransactions(iban, limit)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveTransaction(id string) (Transaction, error) {
+	var result Transaction
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveTransaction(id)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveTransactionsBetween(from, to time.Time) ([]Transaction, error) {
+	var result []Transaction
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveTransactionsBetween(from, to)
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) RetrieveJournalAsJson() (string, error) {
+	var result string
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.RetrieveJournalAsJson()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingAccountRepository) VerifyInvariants() (*InvariantReport, error) {
+	var result *InvariantReport
+	err := r.retry(func() (err error) {
+		result, err = r.AccountRepository.VerifyInvariants()
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Backend losing the connection after the operation has been executed, so that the client does not know the outcome
+type lossyAccountRepository struct {
+	AccountRepository
+	failures int
+	calls    int
+}
+
+func (r *lossyAccountRepository) lose(err error) error {
+	r.calls++
+	if err == nil && r.failures > 0 {
+		r.failures--
+		return errors.New("connection reset by peer")
+	}
+	return err
+}
+
+func (r *lossyAccountRepository) TransferMoneyIdempotent(key, sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	id, err := r.AccountRepository.TransferMoneyIdempotent(key, sender, recipient, amount, details)
+	return id, r.lose(err)
+}
+
+func (r *lossyAccountRepository) BlockAccount(iban string) error {
+	return r.lose(r.AccountRepository.BlockAccount(iban))
+}
+
+// Retry transfers lost on the way back without moving money twice and leave unsafe and business failures alone
+func TestRetryingRepository(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	backend := &lossyAccountRepository{AccountRepository: inMemImpl}
+	delays := []time.Duration{}
+	repo := NewRetryingAccountRepository(backend, RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second})
+	repo.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+	service := NewAccountService(repo)
+	acc, _ := service.OpenAccount()
+	service.EmitMoney(100)
+
+	backend.failures = 2
+	if _, err := service.TransferMoney(emission, acc.Iban, 30); err != nil {
+		t.Fatalf("Expected the transfer to succeed on the third attempt, got %v", err)
+	}
+	if account, _ := inMemImpl.RetrieveAccount(acc.Iban); account.Balance != 30 || backend.calls != 3 || len(delays) != 2 {
+		t.Errorf("Expected the transfer to be executed once in 3 attempts, got balance %v and %d calls", account.Balance, backend.calls)
+	}
+	if delays[0] < 5*time.Millisecond || delays[0] > 10*time.Millisecond || delays[1] < 10*time.Millisecond || delays[1] > 20*time.Millisecond {
+		t.Errorf("Unexpected delays between the attempts %v", delays)
+	}
+
+	backend.calls, backend.failures = 0, 5
+	if _, err := service.TransferMoney(emission, acc.Iban, 30); err == nil || backend.calls != 3 {
+		t.Errorf("Expected the transfer to fail after 3 attempts, got %v and %d calls", err, backend.calls)
+	}
+	backend.calls, backend.failures = 0, 0
+	if _, err := service.TransferMoney(acc.Iban, emission, 1000); err == nil || backend.calls != 1 {
+		t.Errorf("Expected the business error not to be retried, got %v and %d calls", err, backend.calls)
+	}
+	backend.calls, backend.failures = 0, 1
+	if err := service.BlockAccount(acc.Iban); err == nil || backend.calls != 1 {
+		t.Errorf("Expected the operation without the key not to be retried, got %v and %d calls", err, backend.calls)
+	}
+
+	// Generated keys carry the time of the package clock like any other ULID
+	fake := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+	if key := repo.newKey(); !strings.HasPrefix(key, "retry-"+NewUlid(fake.Now())[:10]) {
+		t.Errorf("Expected the key to be generated at the time of the clock, got %s", key)
+	}
+}
+
+// Keep the jittered delay between the half and the whole of the exponential delay bounded by the maximum
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	bounds := map[int]time.Duration{2: 100 * time.Millisecond, 3: 200 * time.Millisecond, 4: 400 * time.Millisecond, 5: 800 * time.Millisecond, 6: time.Second, 60: time.Second}
+	for attempt, bound := range bounds {
+		for i := 0; i < 100; i++ {
+			if delay := policy.Backoff(attempt); delay < bound/2 || delay > bound {
+				t.Fatalf("Expected the delay before attempt %d to be within %v and %v, got %v", attempt, bound/2, bound, delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining future-dated transfers executed by a background scheduler
+type ScheduledTransferStatus int8
+
+const (
+	ScheduledTransferPending ScheduledTransferStatus = iota
+	ScheduledTransferExecuted
+	ScheduledTransferFailed
+	ScheduledTransferCancelled
+)
+
+// Mapping scheduled transfer status codes to scheduled transfer status names considering locale
+var scheduledTransferStatusCodeToNameMap map[ScheduledTransferStatus](map[LanguageCode]string) = map[ScheduledTransferStatus](map[LanguageCode]string){
+	ScheduledTransferPending: {
+		English: "Pending",
+		Russian: "Ожидает исполнения",
+	},
+	ScheduledTransferExecuted: {
+		English: "Executed",
+		Russian: "Исполнен",
+	},
+	ScheduledTransferFailed: {
+		English: "Failed",
+		Russian: "Отклонён",
+	},
+	ScheduledTransferCancelled: {
+		English: "Cancelled",
+		Russian: "Отменён",
+	},
+}
+
+type ScheduledTransfer struct {
+	ID         string
+	Sender     string
+	Recipient  string
+	Amount     float64
+	ExecuteAt  time.Time
+	Status     ScheduledTransferStatus
+	TransferID string // ID of the transfer made on execution, set for executed and failed transfers
+	Error      string // reason of the failure, set for failed transfers
+	CreatedAt  time.Time
+}
+
+// Scheduler keeps scheduled transfers and executes the due ones through the account service,
+// so that they are subject to exactly the same checks as transfers made directly
+type TransferScheduler struct {
+	service   *AccountService
+	Transfers map[string]*ScheduledTransfer
+	Mutex     sync.Mutex
+}
+
+func NewTransferScheduler(service *AccountService) *TransferScheduler {
+	return &TransferScheduler{service: service, Transfers: map[string]*ScheduledTransfer{}}
+}
+
+// Accounts are checked to exist at scheduling time, balance and status only at execution time
+// Transfers scheduled in the past are executed on the next run
+func (s *TransferScheduler) ScheduleTransfer(sender, recipient string, amount float64, executeAt time.Time) (*ScheduledTransfer, error) {
+	sender = NormalizeIban(sender)
+	recipient = NormalizeIban(recipient)
+
+	// Checking if both accounts exist
+	if _, err := s.service.GetAccount(sender); err != nil {
+		return nil, err
+	}
+	if _, err := s.service.GetAccount(recipient); err != nil {
+		return nil, err
+	}
+	// Checking if money amount to transfer is not negative
+	if amount < 0 {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	now := clock.Now().UTC()
+	st := &ScheduledTransfer{ID: NewUlid(now), Sender: sender, Recipient: recipient, Amount: amount, ExecuteAt: executeAt.UTC(), Status: ScheduledTransferPending, CreatedAt: now}
+	s.Transfers[st.ID] = st
+	copied := *st
+	return &copied, nil
+}
+
+// Only pending transfers can be cancelled
+func (s *TransferScheduler) CancelScheduledTransfer(id string) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	st, exists := s.Transfers[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists || st == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[ScheduledTransferDoesNotExistError][locale()])
+	}
+	if st.Status != ScheduledTransferPending {
+		return fmt.Errorf(errorCodesToMessagesMap[ScheduledTransferNotPendingError][locale()])
+	}
+	st.Status = ScheduledTransferCancelled
+	return nil
+}
+
+// Lists pending transfers ordered by execution time
+func (s *TransferScheduler) ListPendingScheduledTransfers() []ScheduledTransfer {
+	return s.list(func(st *ScheduledTransfer) bool { return st.Status == ScheduledTransferPending })
+}
+
+// Lists transfers in all statuses ordered by execution time
+func (s *TransferScheduler) ListScheduledTransfers() []ScheduledTransfer {
+	return s.list(func(st *ScheduledTransfer) bool { return true })
+}
+
+func (s *TransferScheduler) list(filter func(*ScheduledTransfer) bool) []ScheduledTransfer {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	transfers := []ScheduledTransfer{}
+	for _, st := range s.Transfers {
+		if filter(st) {
+			transfers = append(transfers, *st)
+		}
+	}
+	sortScheduledTransfers(transfers)
+	return transfers
+}
+
+// Helper function to order transfers by execution time, transfers due at the same time are ordered by creation time
+func sortScheduledTransfers(transfers []ScheduledTransfer) {
+	sort.Slice(transfers, func(i, j int) bool {
+		if !transfers[i].ExecuteAt.Equal(transfers[j].ExecuteAt) {
+			return transfers[i].ExecuteAt.Before(transfers[j].ExecuteAt)
+		}
+		return transfers[i].CreatedAt.Before(transfers[j].CreatedAt)
+	})
+}
+
+// Executes pending transfers due at the given time in the order of their execution time and returns the number of executed ones
+// A failed transfer is not retried, it stays in the failed status with the reason of the failure
+func (s *TransferScheduler) RunDueTransfers(now time.Time) int {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	due := []ScheduledTransfer{}
+	for _, st := range s.Transfers {
+		if st.Status == ScheduledTransferPending && !st.ExecuteAt.After(now) {
+			due = append(due, *st)
+		}
+	}
+	sortScheduledTransfers(due)
+
+	executed := 0
+	for _, d := range due {
+		st := s.Transfers[d.ID]
+		id, err := s.service.TransferMoneyWithDetails(st.Sender, st.Recipient, st.Amount, TransferDetails{Reference: st.ID})
+		st.TransferID = id
+		if err != nil {
+			st.Status = ScheduledTransferFailed
+			st.Error = err.Error()
+			continue
+		}
+		st.Status = ScheduledTransferExecuted
+		executed++
+	}
+	return executed
+}
+
+// Checks for due transfers, loan installments, matured deposits, overdue payment requests and card authorizations every interval in a background goroutine,
+// reconciling hot accounts afterwards
+// Calling the returned function stops the scheduler and waits for the run in progress, if any, to finish
+func (s *TransferScheduler) Start(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				now := clock.Now()
+				s.RunDueTransfers(now)
+				s.RunDueInstallments(now)
+				s.RunMaturedDeposits(now)
+				s.RunPaymentRequestExpiry(now)
+				s.RunAuthorizationExpiry(now)
+				s.RunBlockExpiry(now)
+				s.RunChallengeExpiry(now)
+				s.RunHotAccountReconciliation()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	once := sync.Once{}
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
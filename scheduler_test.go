@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Schedule transfers, cancel one of them and execute the due ones
+func TestScheduledTransfers(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	scheduler := NewTransferScheduler(service)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+
+	now := time.Now()
+	due, err := scheduler.ScheduleTransfer(sender.Iban, recipient.Iban, 30, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	tooLarge, _ := scheduler.ScheduleTransfer(sender.Iban, recipient.Iban, 500, now)
+	cancelled, _ := scheduler.ScheduleTransfer(sender.Iban, recipient.Iban, 10, now.Add(time.Hour))
+	later, _ := scheduler.ScheduleTransfer(sender.Iban, recipient.Iban, 20, now.Add(2*time.Hour))
+	if _, err := scheduler.ScheduleTransfer(sender.Iban, "BY00 0000 0000 0000 0000 0000 0000", 10, now); err == nil {
+		t.Errorf("Scheduling transfer to unknown account failed to fail")
+	}
+	if _, err := scheduler.ScheduleTransfer(sender.Iban, recipient.Iban, -10, now); err == nil {
+		t.Errorf("Scheduling transfer of negative amount failed to fail")
+	}
+
+	if err := scheduler.CancelScheduledTransfer(cancelled.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := scheduler.CancelScheduledTransfer(cancelled.ID); err == nil {
+		t.Errorf("Cancelling already cancelled transfer failed to fail")
+	}
+	if pending := scheduler.ListPendingScheduledTransfers(); len(pending) != 3 || pending[0].ID != due.ID || pending[2].ID != later.ID {
+		t.Errorf("Unexpected pending transfers: %+v", pending)
+	}
+
+	if executed := scheduler.RunDueTransfers(now.Add(90 * time.Minute)); executed != 1 {
+		t.Errorf("Expected 1 executed transfer, got %d", executed)
+	}
+	for _, st := range scheduler.ListScheduledTransfers() {
+		expected := map[string]ScheduledTransferStatus{due.ID: ScheduledTransferExecuted, tooLarge.ID: ScheduledTransferFailed, cancelled.ID: ScheduledTransferCancelled, later.ID: ScheduledTransferPending}[st.ID]
+		if st.Status != expected {
+			t.Errorf("Expected transfer %s to be %s, got %s", st.ID, scheduledTransferStatusCodeToNameMap[expected][locale()], scheduledTransferStatusCodeToNameMap[st.Status][locale()])
+		}
+		if st.ID == due.ID {
+			if status, err := service.GetTransferStatus(st.TransferID); err != nil || status != TransferSettled {
+				t.Errorf("Expected executed transfer %s to be settled, got %v (%v)", st.TransferID, status, err)
+			}
+		}
+		if st.ID == tooLarge.ID && st.Error == "" {
+			t.Errorf("Expected failed transfer to keep the reason of the failure")
+		}
+	}
+	if err := scheduler.CancelScheduledTransfer(due.ID); err == nil {
+		t.Errorf("Cancelling executed transfer failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 30 {
+		t.Errorf("Expected recipient balance 30, got %.2f", details.Balance)
+	}
+
+	// Background goroutine picks up the remaining transfer once it is due
+	scheduler.Transfers[later.ID].ExecuteAt = time.Now()
+	stop := scheduler.Start(10 * time.Millisecond)
+	defer stop()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(scheduler.ListPendingScheduledTransfers()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 50 {
+		t.Errorf("Expected recipient balance 50 after background execution, got %.2f", details.Balance)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining sanctions screening of transfer counterparties
+type ScreeningOutcome int8
+
+const (
+	ScreeningClear  ScreeningOutcome = iota
+	ScreeningFlag                    // transfer is executed and the match is reported for review
+	ScreeningReject                  // transfer is rejected
+)
+
+// Mapping screening outcome codes to screening outcome names considering locale
+var screeningOutcomeCodeToNameMap map[ScreeningOutcome](map[LanguageCode]string) = map[ScreeningOutcome](map[LanguageCode]string){
+	ScreeningClear: {
+		English: "Clear",
+		Russian: "Нет совпадений",
+	},
+	ScreeningFlag: {
+		English: "Flag",
+		Russian: "Отметить",
+	},
+	ScreeningReject: {
+		English: "Reject",
+		Russian: "Отклонить",
+	},
+}
+
+// Counterparty of a transfer, Name is the name of the holder when it is known
+type ScreeningParty struct {
+	Iban string
+	Name string
+}
+
+// Screening is called under the repository mutex before the money is moved, so implementations backed by remote
+// services are expected to answer from a local copy of the lists rather than call the service on every transfer
+type ScreeningProvider interface {
+	// Returns the outcome for the pair of counterparties and the matched list entry if any
+	Screen(sender, recipient ScreeningParty) (ScreeningOutcome, string)
+}
+
+// Match reported for a transfer executed despite it
+type ScreeningHit struct {
+	TransferID string
+	Sender     string
+	Recipient  string
+	Entry      string
+	Timestamp  time.Time
+}
+
+const ScreeningHitEvent = "screening.hit" // payload is ScreeningHit
+
+// Screening provider checking counterparties against a configurable blocklist of IBANs and holder names, i.e.
+// {"action": "reject", "ibans": ["BY04 CBDC 3602 9110 1000 4000 0000"], "names": ["John Doe"]}
+type ListScreeningProvider struct {
+	Action ScreeningOutcome
+	ibans  map[string]bool
+	names  map[string]bool
+	mutex  sync.Mutex
+}
+
+// Helper function to bring names to a comparable form, i.e. " john  DOE " and "John Doe" are the same name
+func normalizeScreeningName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func NewListScreeningProvider(action ScreeningOutcome, ibans, names []string) *ListScreeningProvider {
+	p := &ListScreeningProvider{Action: action}
+	p.SetLists(ibans, names)
+	return p
+}
+
+// Loads the blocklist from a JSON file in the format described at ListScreeningProvider
+func LoadListScreeningProvider(path string) (*ListScreeningProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidScreeningListError][locale()])
+	}
+	var config struct {
+		Action string   `json:"action"`
+		Ibans  []string `json:"ibans"`
+		Names  []string `json:"names"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidScreeningListError][locale()])
+	}
+	actions := map[string]ScreeningOutcome{"flag": ScreeningFlag, "reject": ScreeningReject}
+	action, ok := actions[strings.ToLower(config.Action)]
+	if !ok {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidScreeningListError][locale()])
+	}
+	return NewListScreeningProvider(action, config.Ibans, config.Names), nil
+}
+
+// Replaces the lists, i.e. when a new version of the sanctions list is published
+func (p *ListScreeningProvider) SetLists(ibans, names []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.ibans = map[string]bool{}
+	for _, iban := range ibans {
+		p.ibans[NormalizeIban(iban)] = true
+	}
+	p.names = map[string]bool{}
+	for _, name := range names {
+		if n := normalizeScreeningName(name); n != "" {
+			p.names[n] = true
+		}
+	}
+}
+
+func (p *ListScreeningProvider) Screen(sender, recipient ScreeningParty) (ScreeningOutcome, string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, party := range []ScreeningParty{sender, recipient} {
+		if iban := NormalizeIban(party.Iban); p.ibans[iban] {
+			return p.Action, iban
+		}
+		if name := normalizeScreeningName(party.Name); name != "" && p.names[name] {
+			return p.Action, name
+		}
+	}
+	return ScreeningClear, ""
+}
+
+// Helper function to screen the counterparties of a transfer, expects the repository mutex to be held by the caller
+// Holder names are not known to the repository yet, so only IBANs are screened for now
+func (r *InMemoryAccountRepository) screenTransfer(sAcc, rAcc *Account) (ScreeningOutcome, string) {
+	if r.ScreeningProvider == nil {
+		return ScreeningClear, ""
+	}
+	return r.ScreeningProvider.Screen(ScreeningParty{Iban: sAcc.Iban}, ScreeningParty{Iban: rAcc.Iban})
+}
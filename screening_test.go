@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Reject or flag transfers to counterparties on the blocklist
+func TestSanctionsScreening(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	sanctioned, _ := service.OpenAccount()
+	other, _ := service.OpenAccount()
+
+	path := filepath.Join(t.TempDir(), "blocklist.json")
+	config := `{"action": "reject", "ibans": ["` + sanctioned.Iban[:4] + " " + sanctioned.Iban[4:] + `"], "names": [" John  DOE "]}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	provider, err := LoadListScreeningProvider(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	inMemImpl.ScreeningProvider = provider
+
+	if _, err := service.TransferMoney(sender.Iban, sanctioned.Iban, 10); err == nil {
+		t.Errorf("Transfer to sanctioned account failed to fail")
+	}
+	if _, err := service.TransferMoney(sender.Iban, other.Iban, 10); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if outcome, entry := provider.Screen(ScreeningParty{Iban: other.Iban}, ScreeningParty{Name: "john doe"}); outcome != ScreeningReject || entry != "john doe" {
+		t.Errorf("Expected holder name to match, got %s (%s)", screeningOutcomeCodeToNameMap[outcome][locale()], entry)
+	}
+
+	// In flag mode the transfer goes through and the match is reported
+	bus := NewInMemoryEventBus()
+	inMemImpl.EventBus = bus
+	hits, unsubscribe := bus.Subscribe(ScreeningHitEvent)
+	defer unsubscribe()
+	provider.Action = ScreeningFlag
+	id, err := service.TransferMoneyWithDetails(sender.Iban, sanctioned.Iban, 10, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if hit := waitForEvent(t, hits).Payload.(ScreeningHit); hit.TransferID != id || hit.Entry != sanctioned.Iban {
+		t.Errorf("Unexpected screening hit: %+v", hit)
+	}
+	if len(inMemImpl.ScreeningHits) != 1 {
+		t.Errorf("Expected one recorded screening hit, got %+v", inMemImpl.ScreeningHits)
+	}
+
+	// Lists can be replaced at runtime
+	provider.SetLists(nil, nil)
+	provider.Action = ScreeningReject
+	if _, err := service.TransferMoney(sender.Iban, sanctioned.Iban, 10); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"action": "ignore"}`), 0o600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := LoadListScreeningProvider(path); err == nil {
+		t.Errorf("Loading blocklist with unknown action failed to fail")
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining secret stores: credentials of the backends, keys of the bearer tokens and secrets of the webhooks and the peers
+// are looked up by their names in the store instead of being written to the configuration file
+type SecretProvider interface {
+	Secret(name string) (string, error) // SecretNotFoundError is returned if the store has no secret with the name
+}
+
+// Names of the secrets looked up by the features using them, names of the peer secrets are suffixed by the bank code
+const (
+	JwtHmacSecretName    = "jwt_hmac_secret"
+	JwtRsaPublicKeyName  = "jwt_rsa_public_key"
+	PeerSecretNamePrefix = "interbank_secret_"
+	PeerApiKeyNamePrefix = "interbank_api_key_"
+	DatabasePasswordName = "database_password" // reserved for persistent backends
+)
+
+// Helper function to check if the secret is missing from the store rather than failed to be loaded
+func isSecretNotFound(err error) bool {
+	return err != nil && err.Error() == errorCodesToMessagesMap[SecretNotFoundError][locale()]
+}
+
+// Helper function to check if the name of the secret is safe to use as a file or environment variable name
+func isValidSecretName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, char := range name {
+		if !(char >= 'a' && char <= 'z') && !(char >= 'A' && char <= 'Z') && !(char >= '0' && char <= '9') && char != '_' && char != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Takes secrets from environment variables named after the secrets in upper case with the prefix, i.e. PAYMENTS_SECRET_JWT_HMAC_SECRET
+type EnvSecretProvider struct {
+	Prefix string
+}
+
+func NewEnvSecretProvider(prefix string) *EnvSecretProvider {
+	return &EnvSecretProvider{prefix}
+}
+
+func (p *EnvSecretProvider) Secret(name string) (string, error) {
+	if !isValidSecretName(name) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	value, set := os.LookupEnv(p.Prefix + strings.ToUpper(strings.Replace(name, "-", "_", -1)))
+	if !set {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	return value, nil
+}
+
+// Takes secrets from files named after the secrets in the directory, i.e. secrets mounted by Docker or Kubernetes to /run/secrets,
+// trailing newlines are trimmed
+type FileSecretProvider struct {
+	Dir string
+}
+
+func NewFileSecretProvider(dir string) *FileSecretProvider {
+	return &FileSecretProvider{dir}
+}
+
+func (p *FileSecretProvider) Secret(name string) (string, error) {
+	if !isValidSecretName(name) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	content, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretUnavailableError][locale()])
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// Takes secrets from the key-value (version 2) engine of HashiCorp Vault, all secrets are the keys of one Vault secret
+// at the path, i.e. "payments" for the "secret" mount reads GET /v1/secret/data/payments
+type VaultSecretProvider struct {
+	Address string // i.e. "https://vault.example.com:8200"
+	Token   string // taken from the VAULT_TOKEN variable if empty
+	Mount   string
+	Path    string
+	Client  *http.Client
+}
+
+func NewVaultSecretProvider(address, mount, path string) *VaultSecretProvider {
+	return &VaultSecretProvider{Address: strings.TrimRight(address, "/"), Mount: strings.Trim(mount, "/"), Path: strings.Trim(path, "/"), Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (p *VaultSecretProvider) Secret(name string) (string, error) {
+	if !isValidSecretName(name) {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	req, err := http.NewRequest(http.MethodGet, p.Address+"/v1/"+p.Mount+"/data/"+(&url.URL{Path: p.Path}).EscapedPath(), nil)
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretUnavailableError][locale()])
+	}
+	token := p.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	req.Header.Set("X-Vault-Token", token)
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretUnavailableError][locale()])
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretUnavailableError][locale()])
+	}
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretUnavailableError][locale()])
+	}
+	value, exists := body.Data.Data[name]
+	if !exists {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf(errorCodesToMessagesMap[SecretUnavailableError][locale()])
+	}
+	return secret, nil
+}
+
+// Store the secrets are taken from, the Vault token is never taken from the configuration but from VAULT_TOKEN
+type SecretsConfig struct {
+	Provider   string // "env", "file" or "vault"
+	Dir        string // directory of the file store
+	VaultAddr  string
+	VaultMount string
+	VaultPath  string
+}
+
+func (c SecretsConfig) Validate() error {
+	switch c.Provider {
+	case "env":
+		return nil
+	case "file":
+		if c.Dir != "" {
+			return nil
+		}
+	case "vault":
+		if u, err := url.Parse(c.VaultAddr); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && c.VaultMount != "" && c.VaultPath != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf(errorCodesToMessagesMap[InvalidConfigError][locale()])
+}
+
+// Returns the store of the config, secrets of the environment store are prefixed with PAYMENTS_SECRET_
+func (c SecretsConfig) SecretProvider() (SecretProvider, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.Provider {
+	case "file":
+		return NewFileSecretProvider(c.Dir), nil
+	case "vault":
+		return NewVaultSecretProvider(c.VaultAddr, c.VaultMount, c.VaultPath), nil
+	}
+	return NewEnvSecretProvider(configEnvPrefix + "SECRET_"), nil
+}
+
+// Returns the config with the keys taken from the store, keys missing from the store are left as set in the config
+func (c JwtConfig) WithSecrets(p SecretProvider) (JwtConfig, error) {
+	for name, target := range map[string]*string{JwtHmacSecretName: &c.HmacSecret, JwtRsaPublicKeyName: &c.RsaPublicKeyPem} {
+		secret, err := p.Secret(name)
+		if isSecretNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return JwtConfig{}, err
+		}
+		*target = secret
+	}
+	return c, nil
+}
+
+// Takes the secrets and the API keys shared with the peers from the store, peers without them in the store are skipped
+func (g *InterbankGateway) LoadPeerSecrets(p SecretProvider, bankCodes ...string) error {
+	for _, bankCode := range bankCodes {
+		name := strings.ToLower(strings.TrimSpace(bankCode))
+		secret, err := p.Secret(PeerSecretNamePrefix + name)
+		if err != nil && !isSecretNotFound(err) {
+			return err
+		}
+		if err == nil {
+			g.SetPeerSecret(bankCode, secret)
+		}
+		token, err := p.Secret(PeerApiKeyNamePrefix + name)
+		if err != nil && !isSecretNotFound(err) {
+			return err
+		}
+		if err == nil {
+			g.SetPeerApiKey(bankCode, token)
+		}
+	}
+	return nil
+}
+
+// Subscribes the URL with the secret taken from the store, so that the secret is shared with the receiver out of band
+func (d *WebhookDispatcher) SubscribeWithSecret(url string, p SecretProvider, name string, eventTypes ...string) (*WebhookSubscription, error) {
+	secret, err := p.Secret(name)
+	if err != nil {
+		return nil, err
+	}
+	// Checking if the secret is set, empty secrets would be replaced by generated ones the receiver does not know
+	if secret == "" {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[SecretNotFoundError][locale()])
+	}
+	return d.Subscribe(url, secret, eventTypes...)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Take secrets from the environment, files and Vault and tell missing secrets from unavailable stores
+func TestSecretProviders(t *testing.T) {
+	t.Setenv("PAYMENTS_SECRET_JWT_HMAC_SECRET", "from-env")
+	env := NewEnvSecretProvider("PAYMENTS_SECRET_")
+	if secret, err := env.Secret(JwtHmacSecretName); err != nil || secret != "from-env" {
+		t.Errorf("Expected secret of the environment, got %q (%v)", secret, err)
+	}
+	if _, err := env.Secret("missing"); !isSecretNotFound(err) {
+		t.Errorf("Expected missing secret, got %v", err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "database_password"), []byte("from-file\n"), 0600)
+	files := NewFileSecretProvider(dir)
+	if secret, err := files.Secret(DatabasePasswordName); err != nil || secret != "from-file" {
+		t.Errorf("Expected secret of the file, got %q (%v)", secret, err)
+	}
+	for _, name := range []string{"missing", "../database_password", ""} {
+		if _, err := files.Secret(name); !isSecretNotFound(err) {
+			t.Errorf("Expected secret %q to be missing, got %v", name, err)
+		}
+	}
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		if req.URL.Path != "/v1/secret/data/payments" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"interbank_secret_beta":"from-vault","webhook_acme":"acme"},"metadata":{"version":3}}}`))
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_TOKEN", "root")
+	store := NewVaultSecretProvider(vault.URL+"/", "secret", "payments")
+	if secret, err := store.Secret("interbank_secret_beta"); err != nil || secret != "from-vault" {
+		t.Errorf("Expected secret of Vault, got %q (%v)", secret, err)
+	}
+	if _, err := store.Secret("missing"); !isSecretNotFound(err) {
+		t.Errorf("Expected missing secret, got %v", err)
+	}
+	if _, err := NewVaultSecretProvider(vault.URL, "secret", "other").Secret("missing"); !isSecretNotFound(err) {
+		t.Errorf("Expected missing secret at unknown path, got %v", err)
+	}
+	store.Token = "wrong"
+	if _, err := store.Secret("interbank_secret_beta"); err == nil || isSecretNotFound(err) {
+		t.Errorf("Expected Vault to be unavailable with wrong token, got %v", err)
+	}
+	store.Token = ""
+
+	// Features take their secrets from the store
+	jwt, err := JwtConfig{RsaPublicKeyPem: "kept"}.WithSecrets(env)
+	if err != nil || jwt.HmacSecret != "from-env" || jwt.RsaPublicKeyPem != "kept" {
+		t.Errorf("Unexpected JWT config %+v (%v)", jwt, err)
+	}
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	gateway := NewInterbankGateway(NewAccountService(inMemImpl), "ALFA")
+	if err := gateway.LoadPeerSecrets(store, "BETA", "GAMA"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if secret, known := gateway.peerSecret("BETA"); !known || secret != "from-vault" {
+		t.Errorf("Expected peer secret from Vault, got %q", secret)
+	}
+	if _, known := gateway.peerSecret("GAMA"); known {
+		t.Errorf("Expected peer without secret to be skipped")
+	}
+	dispatcher := NewWebhookDispatcher(NewInMemoryEventBus())
+	defer dispatcher.Close()
+	if sub, err := dispatcher.SubscribeWithSecret("https://acme.example.com/hooks", store, "webhook_acme"); err != nil || sub.Secret != "acme" {
+		t.Errorf("Unexpected subscription %+v (%v)", sub, err)
+	}
+	if _, err := dispatcher.SubscribeWithSecret("https://acme.example.com/hooks", store, "missing"); err == nil {
+		t.Errorf("Subscription with missing secret failed to fail")
+	}
+}
+
+// Choose the store of the secrets by the configuration
+func TestSecretsConfig(t *testing.T) {
+	config, err := LoadConfig([]string{"-secrets-provider", "file", "-secrets-dir", t.TempDir()})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if provider, err := config.Secrets.SecretProvider(); err != nil {
+		t.Errorf("Error: %v", err)
+	} else if _, ok := provider.(*FileSecretProvider); !ok {
+		t.Errorf("Expected file store, got %T", provider)
+	}
+	invalid := [][]string{
+		{"-secrets-provider", "keychain"},
+		{"-secrets-provider", "file", "-secrets-dir", ""},
+		{"-secrets-provider", "vault"},
+		{"-secrets-provider", "vault", "-secrets-vault-addr", "vault:8200"},
+	}
+	for _, args := range invalid {
+		if _, err := LoadConfig(args); err == nil {
+			t.Errorf("Loading config with %v failed to fail", args)
+		}
+	}
+}
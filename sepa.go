@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining SEPA-style batch settlement files: transfers of a day are grouped by the bank code of the recipient IBAN
+// into ISO 20022 credit transfer initiations (pain.001.001.03), one batch per bank for simulated clearing
+const sepaNamespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+// Name of the initiating party of the batches, can be augmented with an identifier of the bank
+const sepaInitiatingParty = "PAYMENT SYSTEM PROTOTYPE"
+
+type sepaDocument struct {
+	XMLName    xml.Name       `xml:"Document"`
+	Xmlns      string         `xml:"xmlns,attr"`
+	Initiation sepaInitiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type sepaInitiation struct {
+	GroupHeader sepaGroupHeader `xml:"GrpHdr"`
+	Payments    []sepaPayment   `xml:"PmtInf"`
+}
+
+type sepaGroupHeader struct {
+	MessageID       string    `xml:"MsgId"`
+	CreatedAt       string    `xml:"CreDtTm"`
+	NumberOfTxs     int       `xml:"NbOfTxs"`
+	ControlSum      string    `xml:"CtrlSum"`
+	InitiatingParty sepaParty `xml:"InitgPty"`
+}
+
+type sepaParty struct {
+	Name string `xml:"Nm,omitempty"`
+}
+
+type sepaAccount struct {
+	Iban string `xml:"Id>IBAN"`
+}
+
+type sepaAgent struct {
+	BankCode string `xml:"FinInstnId>Othr>Id"`
+}
+
+// Payment information block groups the transfers of a single debtor account as SEPA requires
+type sepaPayment struct {
+	PaymentID     string            `xml:"PmtInfId"`
+	Method        string            `xml:"PmtMtd"`
+	NumberOfTxs   int               `xml:"NbOfTxs"`
+	ControlSum    string            `xml:"CtrlSum"`
+	ServiceLevel  string            `xml:"PmtTpInf>SvcLvl>Cd"`
+	ExecutionDate string            `xml:"ReqdExctnDt"`
+	Debtor        sepaParty         `xml:"Dbtr"`
+	DebtorAccount sepaAccount       `xml:"DbtrAcct"`
+	DebtorAgent   sepaAgent         `xml:"DbtrAgt"`
+	ChargeBearer  string            `xml:"ChrgBr"`
+	Transfers     []sepaTransaction `xml:"CdtTrfTxInf"`
+}
+
+type sepaAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type sepaTransaction struct {
+	EndToEndID      string      `xml:"PmtId>EndToEndId"`
+	Amount          sepaAmount  `xml:"Amt>InstdAmt"`
+	CreditorAgent   sepaAgent   `xml:"CdtrAgt"`
+	Creditor        sepaParty   `xml:"Cdtr"`
+	CreditorAccount sepaAccount `xml:"CdtrAcct"`
+	Remittance      string      `xml:"RmtInf>Ustrd,omitempty"`
+}
+
+// Batch of transfers addressed to accounts of a single bank
+type SepaBatch struct {
+	BankCode   string
+	MessageID  string
+	Transfers  int
+	ControlSum float64 // sum of the amounts regardless of their currencies, as CtrlSum of pain.001 is
+	document   sepaDocument
+}
+
+// Writes the batch as an XML document
+func (b SepaBatch) WriteXml(writer io.Writer) error {
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[TransactionsExportError][locale()])
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(b.document); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[TransactionsExportError][locale()])
+	}
+	return nil
+}
+
+// Bank code is the leading part of the BBAN by the format of the country, i.e. ALFA in BY84 ALFA 1000 0000 0000 0000 0000
+// or 37040044 in DE89 3704 0044 0532 0130 00, see ParseIban
+func bankCodeOfIban(iban string) string {
+	if components, err := ParseIban(iban); err == nil {
+		return components.BankCode
+	}
+	// Falling back to the four characters following the check digits for IBANs which cannot be parsed, i.e. the system
+	// accounts of the chart
+	iban = NormalizeIban(iban)
+	if len(iban) < 8 {
+		return ""
+	}
+	return strings.ToUpper(iban[4:8])
+}
+
+// Control sums add up amounts of different currencies, so they are rounded to the largest number of minor units in use
+func sepaControlSum(sum float64) string {
+	return strconv.FormatFloat(math.Round(sum*1000)/1000, 'f', -1, 64)
+}
+
+// Helper function to format the amount with a decimal point and as many decimals as the currency has minor units
+func sepaAmountString(amount float64, currency string) string {
+	return strconv.FormatFloat(roundToCurrency(amount, currency), 'f', minorUnitsOf(currency), 64)
+}
+
+// Groups transfers between ordinary accounts settled on the given day (in UTC) into batches by recipient bank codes,
+// batches are sorted by bank codes and reversed transfers are left out since they are not to be cleared
+func (s *AccountService) ExportSepaBatches(day time.Time) ([]SepaBatch, error) {
+	if err := s.authorize(ReadPermission); err != nil {
+		return nil, err
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	transactions, err := s.accountRepoImpl.RetrieveTransactionsBetween(from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	// Grouping transfers by bank codes of the recipients and then by senders
+	isOrdinary := map[string]bool{}
+	ordinary := func(iban string) bool {
+		if known, ok := isOrdinary[iban]; ok {
+			return known
+		}
+		acc, err := s.accountRepoImpl.GetAccount(iban)
+		isOrdinary[iban] = err == nil && acc.Type == Ordinary
+		return isOrdinary[iban]
+	}
+	grouped := map[string]map[string][]Transaction{}
+	for _, tx := range transactions {
+		if tx.Type != TransferTransaction || tx.ReversedBy != "" || !ordinary(tx.Sender) || !ordinary(tx.Recipient) {
+			continue
+		}
+		bankCode := bankCodeOfIban(tx.Recipient)
+		if grouped[bankCode] == nil {
+			grouped[bankCode] = map[string][]Transaction{}
+		}
+		grouped[bankCode][tx.Sender] = append(grouped[bankCode][tx.Sender], tx)
+	}
+
+	bankCodes := []string{}
+	for bankCode := range grouped {
+		bankCodes = append(bankCodes, bankCode)
+	}
+	sort.Strings(bankCodes)
+	createdAt := clock.Now().UTC()
+	batches := []SepaBatch{}
+	for _, bankCode := range bankCodes {
+		batch := SepaBatch{BankCode: bankCode, MessageID: from.Format("20060102") + "-" + bankCode}
+		senders := []string{}
+		for sender := range grouped[bankCode] {
+			senders = append(senders, sender)
+		}
+		sort.Strings(senders)
+		payments := []sepaPayment{}
+		for i, sender := range senders {
+			name, _ := s.accountHolder(sender)
+			payment := sepaPayment{
+				PaymentID:     fmt.Sprintf("%s-%d", batch.MessageID, i+1),
+				Method:        "TRF",
+				ServiceLevel:  "SEPA",
+				ExecutionDate: from.Format(time.DateOnly),
+				Debtor:        sepaParty{name},
+				DebtorAccount: sepaAccount{sender},
+				DebtorAgent:   sepaAgent{bankCodeOfIban(sender)},
+				ChargeBearer:  "SLEV",
+			}
+			sum := 0.0
+			for _, tx := range grouped[bankCode][sender] {
+				name, _ := s.accountHolder(tx.Recipient)
+				remittance := tx.Reference
+				if remittance == "" {
+					remittance = tx.Memo
+				}
+				payment.Transfers = append(payment.Transfers, sepaTransaction{tx.Ulid, sepaAmount{tx.Currency, sepaAmountString(tx.Amount, tx.Currency)}, sepaAgent{bankCode}, sepaParty{name}, sepaAccount{tx.Recipient}, remittance})
+				sum += roundToCurrency(tx.Amount, tx.Currency)
+			}
+			payment.NumberOfTxs = len(payment.Transfers)
+			payment.ControlSum = sepaControlSum(sum)
+			payments = append(payments, payment)
+			batch.Transfers += payment.NumberOfTxs
+			batch.ControlSum += sum
+		}
+		batch.ControlSum = math.Round(batch.ControlSum*1000) / 1000
+		header := sepaGroupHeader{batch.MessageID, createdAt.Format(time.RFC3339), batch.Transfers, sepaControlSum(batch.ControlSum), sepaParty{sepaInitiatingParty}}
+		batch.document = sepaDocument{Xmlns: sepaNamespace, Initiation: sepaInitiation{header, payments}}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper function to build a valid IBAN with the given BBAN by finding its check digits
+func ibanWithBban(bban string) string {
+	for checkDigits := 0; checkDigits < 100; checkDigits++ {
+		if iban := fmt.Sprintf("BY%02d%s", checkDigits, bban); IsValidIban(iban) {
+			return iban
+		}
+	}
+	return ""
+}
+
+// Transfers of the day are grouped by the bank codes of the recipients and by the senders within a batch
+func TestExportSepaBatches(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	other, _ := service.OpenAccountWithInitialDeposit(100)
+	alfa, err := service.OpenAccountWithOptions(AccountOptions{Iban: ibanWithBban("ALFA30120000000000000001")})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	beta, err := service.OpenAccountWithOptions(AccountOptions{Iban: ibanWithBban("BETA30120000000000000002")})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service.TransferMoneyWithDetails(sender.Iban, alfa.Iban, 10.1, TransferDetails{Reference: "INV-1"})
+	service.TransferMoney(other.Iban, alfa.Iban, 20.2)
+	service.TransferMoney(sender.Iban, beta.Iban, 5)
+	reversed, _ := service.TransferMoneyWithDetails(sender.Iban, beta.Iban, 7, TransferDetails{})
+	if _, err := service.ReverseTransfer(reversed, "Sent by mistake"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	batches, err := service.ExportSepaBatches(time.Now())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(batches) != 2 || batches[0].BankCode != "ALFA" || batches[1].BankCode != "BETA" {
+		t.Fatalf("Unexpected batches: %+v", batches)
+	}
+	if batches[0].Transfers != 2 || batches[0].ControlSum != 30.3 || batches[1].Transfers != 1 || batches[1].ControlSum != 5 {
+		t.Errorf("Unexpected batches: %+v", batches)
+	}
+
+	buffer := bytes.Buffer{}
+	if err := batches[0].WriteXml(&buffer); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var document struct {
+		Initiation struct {
+			Header struct {
+				NumberOfTxs int    `xml:"NbOfTxs"`
+				ControlSum  string `xml:"CtrlSum"`
+			} `xml:"GrpHdr"`
+			Payments []struct {
+				Debtor    string `xml:"DbtrAcct>Id>IBAN"`
+				Transfers []struct {
+					Amount     string `xml:"Amt>InstdAmt"`
+					Creditor   string `xml:"CdtrAcct>Id>IBAN"`
+					Remittance string `xml:"RmtInf>Ustrd"`
+				} `xml:"CdtTrfTxInf"`
+			} `xml:"PmtInf"`
+		} `xml:"CstmrCdtTrfInitn"`
+	}
+	if err := xml.Unmarshal(buffer.Bytes(), &document); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if document.Initiation.Header.NumberOfTxs != 2 || document.Initiation.Header.ControlSum != "30.3" || len(document.Initiation.Payments) != 2 {
+		t.Fatalf("Unexpected document:\n%s", buffer.String())
+	}
+	for _, payment := range document.Initiation.Payments {
+		transfer := payment.Transfers[0]
+		if transfer.Creditor != alfa.Iban || (payment.Debtor == sender.Iban) != (transfer.Remittance == "INV-1" && transfer.Amount == "10.10") {
+			t.Errorf("Unexpected payment: %+v", payment)
+		}
+	}
+	if !strings.Contains(buffer.String(), sepaNamespace) {
+		t.Errorf("Expected pain.001 namespace in:\n%s", buffer.String())
+	}
+
+	// Other days have nothing to clear
+	if batches, _ := service.ExportSepaBatches(time.Now().AddDate(0, 0, -1)); len(batches) != 0 {
+		t.Errorf("Expected no batches, got %+v", batches)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining server-side sessions of interactive clients: clients log in once with their API key or bearer token and pass
+// the session ID afterwards, sessions end on logout, after the idle timeout or after the absolute timeout, whichever is first
+// Sessions opened with an API key are limited to the scopes of the key and end once the key is revoked or expires
+type Session struct {
+	ID         string        `json:"session_id"`
+	Principal  Principal     `json:"-"`
+	KeyID      string        `json:"-"`                // API key the session was opened with, empty for bearer tokens
+	Scopes     []ApiKeyScope `json:"scopes,omitempty"` // scopes of the API key, bearer tokens are limited by their role only
+	CreatedAt  time.Time     `json:"created_at"`
+	LastSeenAt time.Time     `json:"last_seen_at"`
+	ExpiresAt  time.Time     `json:"expires_at"` // absolute expiry, the session may end earlier if idle
+}
+
+type SessionManager struct {
+	IdleTimeout     time.Duration // zero disables the idle timeout
+	AbsoluteTimeout time.Duration // zero disables the absolute timeout
+	sessions        map[string]*Session
+	Mutex           sync.Mutex
+}
+
+// Path of the login (POST) and logout (DELETE) endpoint
+const sessionsPath = "/sessions"
+
+// Name of the cookie carrying the session ID, clients which do not keep cookies pass it in the X-Session-ID header
+const sessionCookie = "session"
+
+func NewSessionManager(idleTimeout, absoluteTimeout time.Duration) *SessionManager {
+	return &SessionManager{IdleTimeout: idleTimeout, AbsoluteTimeout: absoluteTimeout, sessions: map[string]*Session{}}
+}
+
+// Helper function to check if the session has ended by the given time
+func (m *SessionManager) expired(session *Session, now time.Time) bool {
+	return (m.IdleTimeout > 0 && !now.Before(session.LastSeenAt.Add(m.IdleTimeout))) || (!session.ExpiresAt.IsZero() && !now.Before(session.ExpiresAt))
+}
+
+func (m *SessionManager) Open(principal Principal, now time.Time) Session {
+	return m.open(&Session{Principal: principal}, now)
+}
+
+// Opens the session limited to the scopes of the key, the session ends with the key at the latest
+func (m *SessionManager) OpenWithApiKey(key *ApiKey, now time.Time) Session {
+	session := &Session{Principal: key.Principal, KeyID: key.ID, Scopes: append([]ApiKeyScope{}, key.Scopes...)}
+	if m.AbsoluteTimeout > 0 {
+		session.ExpiresAt = now.Add(m.AbsoluteTimeout)
+	}
+	if !key.ExpiresAt.IsZero() && (session.ExpiresAt.IsZero() || key.ExpiresAt.Before(session.ExpiresAt)) {
+		session.ExpiresAt = key.ExpiresAt
+	}
+	return m.open(session, now)
+}
+
+// Helper function to register the session under a new random ID
+func (m *SessionManager) open(session *Session, now time.Time) Session {
+	var id [32]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	session.ID, session.CreatedAt, session.LastSeenAt = hex.EncodeToString(id[:]), now, now
+	if m.AbsoluteTimeout > 0 && session.ExpiresAt.IsZero() {
+		session.ExpiresAt = now.Add(m.AbsoluteTimeout)
+	}
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	m.sessions[session.ID] = session
+	return *session
+}
+
+// Returns the principal of the session and extends its idle timeout
+func (m *SessionManager) Touch(id string, now time.Time) (Principal, error) {
+	session, err := m.touch(id, now)
+	if err != nil {
+		return Principal{}, err
+	}
+	return session.Principal, nil
+}
+
+// Helper function to find the live session and extend its idle timeout
+func (m *SessionManager) touch(id string, now time.Time) (Session, error) {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	session, exists := m.sessions[strings.TrimSpace(id)]
+	if !exists || session == nil {
+		return Session{}, fmt.Errorf(errorCodesToMessagesMap[SessionExpiredError][locale()])
+	}
+	if m.expired(session, now) {
+		delete(m.sessions, session.ID)
+		return Session{}, fmt.Errorf(errorCodesToMessagesMap[SessionExpiredError][locale()])
+	}
+	session.LastSeenAt = now
+	return *session, nil
+}
+
+func (m *SessionManager) Close(id string) error {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	id = strings.TrimSpace(id)
+	if _, exists := m.sessions[id]; !exists {
+		return fmt.Errorf(errorCodesToMessagesMap[SessionExpiredError][locale()])
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+// Ends the sessions opened with the key, i.e. once the key is revoked, returns the number of ended sessions
+func (m *SessionManager) CloseKeySessions(keyID string) int {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	closed := 0
+	for id, session := range m.sessions {
+		if session.KeyID != "" && strings.EqualFold(session.KeyID, strings.TrimSpace(keyID)) {
+			delete(m.sessions, id)
+			closed++
+		}
+	}
+	return closed
+}
+
+// Removes sessions which have ended by the given time, returns the number of removed sessions
+func (m *SessionManager) Sweep(now time.Time) int {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	removed := 0
+	for id, session := range m.sessions {
+		if m.expired(session, now) {
+			delete(m.sessions, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Lists sessions of the principal which have not ended yet, i.e. to show them to the user
+func (m *SessionManager) ListSessions(principalID string, now time.Time) []Session {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	sessions := []Session{}
+	for _, session := range m.sessions {
+		if strings.EqualFold(session.Principal.ID, strings.TrimSpace(principalID)) && !m.expired(session, now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions
+}
+
+// Helper function to take the session ID from the header or the cookie of the request
+func sessionIDOf(req *http.Request) string {
+	if id := req.Header.Get("X-Session-ID"); id != "" {
+		return id
+	}
+	if cookie, err := req.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Serves logins authenticated by the API key (X-API-Key header) or by the bearer token if the verifier is given,
+// and logouts of the current session
+func (m *SessionManager) Handler(service *AccountService, verifier *JwtVerifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost:
+			now := clock.Now().UTC()
+			var session Session
+			if authorization := req.Header.Get("Authorization"); verifier != nil && strings.HasPrefix(authorization, "Bearer ") {
+				principal, err := verifier.Verify(strings.TrimPrefix(authorization, "Bearer "), now)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				session = m.Open(principal, now)
+			} else {
+				key, err := service.AuthenticateApiKey(req.Header.Get(apiKeyHeader), ReadScope, now)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				session = m.OpenWithApiKey(key, now)
+			}
+			http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: session.ID, Path: "/", Expires: session.ExpiresAt, HttpOnly: true, Secure: req.TLS != nil, SameSite: http.SameSiteStrictMode})
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(session)
+		case http.MethodDelete:
+			if err := m.Close(sessionIDOf(req)); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// Wraps the handler to reject requests without a live session, the principal of the session is passed to the handler
+// in the request context, see PrincipalFromContext
+// Requests of sessions opened with an API key need the scope of the request granted by the key, sessions of keys which
+// have been revoked or have expired are ended
+func SessionMiddleware(m *SessionManager, service *AccountService, scopeOf func(*http.Request) ApiKeyScope, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		now := clock.Now().UTC()
+		session, err := m.touch(sessionIDOf(req), now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if session.KeyID != "" {
+			scope := scopeOf(req)
+			if !(&ApiKey{Scopes: session.Scopes}).hasScope(scope) {
+				http.Error(w, errorCodesToMessagesMap[AccessDeniedError][locale()], http.StatusForbidden)
+				return
+			}
+			if _, err := service.CheckApiKey(session.KeyID, scope, now); err != nil {
+				if err.Error() == errorCodesToMessagesMap[InvalidApiKeyError][locale()] {
+					m.CloseKeySessions(session.KeyID)
+				}
+				http.Error(w, errorCodesToMessagesMap[SessionExpiredError][locale()], http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), principalContextKey{}, session.Principal)))
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Sessions end after the idle or the absolute timeout, whichever is first
+func TestSessionTimeouts(t *testing.T) {
+	manager := NewSessionManager(10*time.Minute, time.Hour)
+	now := time.Now()
+	session := manager.Open(Principal{ID: "teller", Role: TellerRole}, now)
+
+	if principal, err := manager.Touch(session.ID, now.Add(9*time.Minute)); err != nil || principal.ID != "teller" {
+		t.Errorf("Expected the session of the teller, got %+v (%v)", principal, err)
+	}
+	// Every call extends the idle timeout but not the absolute one
+	for elapsed := 18 * time.Minute; elapsed < time.Hour; elapsed += 9 * time.Minute {
+		if _, err := manager.Touch(session.ID, now.Add(elapsed)); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if _, err := manager.Touch(session.ID, now.Add(time.Hour)); err == nil {
+		t.Errorf("Session past the absolute timeout failed to fail")
+	}
+
+	idle := manager.Open(Principal{ID: "teller", Role: TellerRole}, now)
+	active := manager.Open(Principal{ID: "teller", Role: TellerRole}, now)
+	if sessions := manager.ListSessions("TELLER", now); len(sessions) != 2 {
+		t.Errorf("Expected 2 sessions, got %+v", sessions)
+	}
+	manager.Touch(active.ID, now.Add(5*time.Minute))
+	if removed := manager.Sweep(now.Add(11 * time.Minute)); removed != 1 {
+		t.Errorf("Expected the idle session to be removed, got %d", removed)
+	}
+	if _, err := manager.Touch(idle.ID, now.Add(11*time.Minute)); err == nil {
+		t.Errorf("Idle session failed to fail")
+	}
+	if err := manager.Close(active.ID); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := manager.Touch(active.ID, now.Add(6*time.Minute)); err == nil {
+		t.Errorf("Closed session failed to fail")
+	}
+}
+
+// Helper function to require the transfer scope for requests changing accounts
+func sessionScopeOf(req *http.Request) ApiKeyScope {
+	if req.Method == http.MethodPost {
+		return TransferScope
+	}
+	return ReadScope
+}
+
+// Clients log in with their API key once and pass the session afterwards
+func TestSessionLogin(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	_, token, _ := service.IssueApiKey(Principal{ID: "teller", Role: TellerRole}, []ApiKeyScope{ReadScope, TransferScope}, 0)
+	manager := NewSessionManager(10*time.Minute, time.Hour)
+	mux := http.NewServeMux()
+	mux.Handle(sessionsPath, manager.Handler(service, nil))
+	mux.Handle("/accounts", SessionMiddleware(manager, service, sessionScopeOf, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := service.ForRequest(req).OpenAccount(); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		}
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	do := func(method, path, header, value string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		return resp
+	}
+
+	if resp := do(http.MethodPost, sessionsPath, apiKeyHeader, "wrong.token"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected login with a wrong key to be rejected, got %d", resp.StatusCode)
+	}
+	resp := do(http.MethodPost, sessionsPath, apiKeyHeader, token)
+	var session Session
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil || session.ID == "" || len(resp.Cookies()) != 1 {
+		t.Fatalf("Expected session to be opened, got %+v (%v)", session, err)
+	}
+	resp.Body.Close()
+	if resp := do(http.MethodPost, "/accounts", "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected request without a session to be rejected, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, "/accounts", "X-Session-ID", session.ID); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected request within the session to succeed, got %d", resp.StatusCode)
+	}
+	if entries, _ := service.QueryAuditLog(AuditFilter{Principal: "teller"}); len(entries) != 1 {
+		t.Errorf("Expected the opening to be audited as the teller, got %+v", entries)
+	}
+	if resp := do(http.MethodDelete, sessionsPath, "X-Session-ID", session.ID); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected logout to succeed, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, "/accounts", "X-Session-ID", session.ID); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected request after logout to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+// Sessions opened with an API key are limited to its scopes and end with the key
+func TestSessionApiKeys(t *testing.T) {
+	fake := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer UseClock(fake)()
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	readKey, readToken, _ := service.IssueApiKey(Principal{ID: "auditor", Role: AuditorRole}, []ApiKeyScope{ReadScope}, 0)
+	transferKey, transferToken, _ := service.IssueApiKey(Principal{ID: "teller", Role: TellerRole}, []ApiKeyScope{ReadScope, TransferScope}, 0)
+	manager := NewSessionManager(0, 0)
+	mux := http.NewServeMux()
+	mux.Handle(sessionsPath, manager.Handler(service, nil))
+	mux.Handle("/accounts", SessionMiddleware(manager, service, sessionScopeOf, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	login := func(token string) Session {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+sessionsPath, nil)
+		req.Header.Set(apiKeyHeader, token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		defer resp.Body.Close()
+		var session Session
+		json.NewDecoder(resp.Body).Decode(&session)
+		return session
+	}
+	do := func(method string, session Session) int {
+		req, _ := http.NewRequest(method, server.URL+"/accounts", nil)
+		req.Header.Set("X-Session-ID", session.ID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	read := login(readToken)
+	if len(read.Scopes) != 1 || read.Scopes[0] != ReadScope {
+		t.Errorf("Expected session to carry the scopes of the key, got %+v", read)
+	}
+	if status := do(http.MethodGet, read); status != http.StatusOK {
+		t.Errorf("Expected read within the scope to succeed, got %d", status)
+	}
+	if status := do(http.MethodPost, read); status != http.StatusForbidden {
+		t.Errorf("Expected request beyond the scopes of the key to be forbidden, got %d", status)
+	}
+
+	// Revoking the key ends its sessions
+	read2 := login(readToken)
+	service.RevokeApiKey(readKey.ID)
+	if status := do(http.MethodGet, read); status != http.StatusUnauthorized {
+		t.Errorf("Expected session of the revoked key to end, got %d", status)
+	}
+	if _, err := manager.Touch(read2.ID, fake.Now()); err == nil {
+		t.Errorf("Expected every session of the revoked key to end")
+	}
+
+	// Sessions of the rotated key end with the grace period of the key
+	transfer := login(transferToken)
+	if status := do(http.MethodPost, transfer); status != http.StatusOK {
+		t.Errorf("Expected transfer within the scope to succeed, got %d", status)
+	}
+	service.RotateApiKey(transferKey.ID, time.Hour)
+	if status := do(http.MethodPost, transfer); status != http.StatusOK {
+		t.Errorf("Expected session to last for the grace period, got %d", status)
+	}
+	fake.Advance(time.Hour)
+	if status := do(http.MethodPost, transfer); status != http.StatusUnauthorized {
+		t.Errorf("Expected session of the expired key to end, got %d", status)
+	}
+}
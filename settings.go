@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+)
+
+// --------------------------------------------------------
+// Defining per-account settings constraining the balance, zero values mean no constraint
+// Limits of debits and the overdraft facility have their own setters, see SetAccountLimits and SetOverdraftLimit
+type AccountSettings struct {
+	MinimumBalance float64 // floor the balance less holds cannot be debited below, i.e. savings accounts keeping 10.00
+	MaximumBalance float64 // ceiling the balance cannot be credited above, i.e. merchant accounts capped at 1,000,000
+}
+
+// Helper function to check if the debit keeps the balance less holds at or above the floor of the account
+func checkBalanceFloor(acc *Account, debit float64) error {
+	if acc.MinimumBalance > 0 && roundToCurrency(acc.BookedBalance()-acc.Held-debit, acc.Currency) < acc.MinimumBalance {
+		return fmt.Errorf(errorCodesToMessagesMap[BelowMinimumBalanceError][locale()])
+	}
+	return nil
+}
+
+// Helper function to check if the credit keeps the balance at or below the ceiling of the account
+func checkBalanceCeiling(acc *Account, credit float64) error {
+	if acc.MaximumBalance > 0 && roundToCurrency(acc.BookedBalance()+credit, acc.Currency) > acc.MaximumBalance {
+		return fmt.Errorf(errorCodesToMessagesMap[AboveMaximumBalanceError][locale()])
+	}
+	return nil
+}
+
+// Settings apply to subsequent money movements, an account already violating them is not corrected
+func (r *InMemoryAccountRepository) UpdateAccountSettings(iban string, settings AccountSettings) error {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if the account is an ordinary one, balances of special accounts are not constrained
+	if acc.Type != Ordinary {
+		return fmt.Errorf(errorCodesToMessagesMap[AccountTypeMismatchError][locale()])
+	}
+	// Checking if the floor and the ceiling are not negative and do not contradict each other
+	if settings.MinimumBalance < 0 || settings.MaximumBalance < 0 || (settings.MaximumBalance > 0 && settings.MaximumBalance < settings.MinimumBalance) {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidAccountSettingsError][locale()])
+	}
+	acc.MinimumBalance = roundToCurrency(settings.MinimumBalance, acc.Currency)
+	acc.MaximumBalance = roundToCurrency(settings.MaximumBalance, acc.Currency)
+	return nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveAccountSettings(iban string) (AccountSettings, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+
+	// Checking if account associated with the given IBAN exists
+	acc, exists := r.Accounts.Get(iban)
+	if !exists || acc == nil {
+		return AccountSettings{}, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	return AccountSettings{acc.MinimumBalance, acc.MaximumBalance}, nil
+}
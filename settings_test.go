@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Debits cannot go below the floor and credits cannot go above the ceiling
+func TestAccountSettings(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	current, _ := service.OpenAccountWithInitialDeposit(100)
+	capped, _ := service.OpenAccount()
+	if err := service.UpdateAccountSettings(current.Iban, AccountSettings{MinimumBalance: 20, MaximumBalance: 10}); err == nil {
+		t.Errorf("Setting ceiling below floor failed to fail")
+	}
+	if err := service.UpdateAccountSettings(current.Iban, AccountSettings{MinimumBalance: 10}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.UpdateAccountSettings(capped.Iban, AccountSettings{MaximumBalance: 50}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if settings, _ := service.RetrieveAccountSettings(current.Iban); settings.MinimumBalance != 10 || settings.MaximumBalance != 0 {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+
+	_, err := service.TransferMoney(current.Iban, capped.Iban, 60)
+	if err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[AboveMaximumBalanceError][locale()]) {
+		t.Errorf("Expected ceiling to be enforced, got %v", err)
+	}
+	if _, err := service.TransferMoney(current.Iban, capped.Iban, 50); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := service.DestructMoney(current.Iban, 40); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	err = service.DestructMoney(current.Iban, 0.01)
+	if err == nil || !strings.Contains(err.Error(), errorCodesToMessagesMap[BelowMinimumBalanceError][locale()]) {
+		t.Errorf("Expected floor to be enforced, got %v", err)
+	}
+	if _, err := service.HoldFunds(current.Iban, 1); err == nil {
+		t.Errorf("Holding funds below the floor failed to fail")
+	}
+	// Closing the account withdraws the whole balance regardless of the floor
+	if err := service.CloseAccount(current.Iban, ""); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+)
+
+// --------------------------------------------------------
+// Defining account storage partitioned into shards by the hash of the IBAN, every shard has its own lock, so that accounts
+// can be looked up and opened along with each other without waiting for a single lock
+// Shards only guard the maps themselves, accounts stored in them are guarded by the repository mutex, see locks.go
+// Stored accounts are indexed by status, type and activity as well, see indexes.go
+
+// Number of shards accounts are partitioned into
+const accountShardCount = 64
+
+type accountShard struct {
+	accounts map[string]*Account
+	mutex    sync.RWMutex
+}
+
+type AccountShards struct {
+	shards  [accountShardCount]accountShard
+	indexes accountIndexes
+}
+
+func NewAccountShards() *AccountShards {
+	m := &AccountShards{indexes: accountIndexes{byStatus: map[AccountStatus]map[string]bool{}, byType: map[AccountType]map[string]bool{}, indexed: map[string]indexedAccount{}, activity: list.New()}}
+	for i := range m.shards {
+		m.shards[i].accounts = map[string]*Account{}
+	}
+	return m
+}
+
+// Helper function to find the shard of the account, IBAN is expected to be normalized
+func (m *AccountShards) shard(iban string) *accountShard {
+	h := fnv.New32a()
+	h.Write([]byte(iban))
+	return &m.shards[h.Sum32()%accountShardCount]
+}
+
+func (m *AccountShards) Get(iban string) (*Account, bool) {
+	shard := m.shard(iban)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	acc, exists := shard.accounts[iban]
+	return acc, exists
+}
+
+// Stores the account under its IBAN, replacing the account stored under the same IBAN if any, and indexes it again
+func (m *AccountShards) Put(acc *Account) {
+	shard := m.shard(acc.Iban)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	shard.accounts[acc.Iban] = acc
+	m.indexes.update(acc)
+}
+
+// Stores the account under its IBAN unless the IBAN is taken, returns whether the account has been stored
+func (m *AccountShards) Insert(acc *Account) bool {
+	shard := m.shard(acc.Iban)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if _, exists := shard.accounts[acc.Iban]; exists {
+		return false
+	}
+	shard.accounts[acc.Iban] = acc
+	m.indexes.update(acc)
+	return true
+}
+
+func (m *AccountShards) Delete(iban string) {
+	shard := m.shard(iban)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	delete(shard.accounts, iban)
+	m.indexes.remove(iban)
+}
+
+func (m *AccountShards) Len() int {
+	n := 0
+	for i := range m.shards {
+		m.shards[i].mutex.RLock()
+		n += len(m.shards[i].accounts)
+		m.shards[i].mutex.RUnlock()
+	}
+	return n
+}
+
+// Returns all stored accounts, shards are locked one by one, so accounts opened meanwhile may or may not be returned
+func (m *AccountShards) All() []*Account {
+	accounts := []*Account{}
+	for i := range m.shards {
+		m.shards[i].mutex.RLock()
+		for _, acc := range m.shards[i].accounts {
+			accounts = append(accounts, acc)
+		}
+		m.shards[i].mutex.RUnlock()
+	}
+	return accounts
+}
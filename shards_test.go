@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// Store, look up and remove accounts spread over the shards
+func TestAccountShards(t *testing.T) {
+	shards := NewAccountShards()
+	for i := 0; i < 3*accountShardCount; i++ {
+		iban, _ := GenerateValidBelarusianIban()
+		if !shards.Insert(NewAccount(iban, Active, Ordinary, 0)) {
+			t.Fatalf("Expected account %s to be stored", iban)
+		}
+	}
+	if shards.Len() != 3*accountShardCount || len(shards.All()) != 3*accountShardCount {
+		t.Errorf("Expected %d accounts, got %d", 3*accountShardCount, shards.Len())
+	}
+
+	acc := shards.All()[0]
+	if shards.Insert(NewAccount(acc.Iban, Active, Ordinary, 0)) {
+		t.Errorf("Storing account under taken IBAN failed to fail")
+	}
+	if stored, exists := shards.Get(acc.Iban); !exists || stored != acc {
+		t.Errorf("Expected the account stored first to be kept")
+	}
+	shards.Delete(acc.Iban)
+	if _, exists := shards.Get(acc.Iban); exists || shards.Len() != 3*accountShardCount-1 {
+		t.Errorf("Expected account to be removed")
+	}
+}
+
+// Open accounts in parallel along with transfers and listings, every opening gets an IBAN of its own
+func TestConcurrentOpenings(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	accounts := inMemImpl.Accounts.Len()
+
+	wg := sync.WaitGroup{}
+	ibans := make(chan string, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			acc, err := service.OpenAccountWithOptions(AccountOptions{Metadata: map[string]string{"channel": "mobile"}})
+			if err != nil {
+				t.Errorf("Error: %v", err)
+				return
+			}
+			ibans <- acc.Iban
+		}()
+		go func() {
+			defer wg.Done()
+			service.TransferMoney(sender.Iban, recipient.Iban, 1)
+			service.FindAccounts(AccountQuery{})
+		}()
+	}
+	wg.Wait()
+	close(ibans)
+
+	seen := map[string]bool{}
+	for iban := range ibans {
+		if seen[iban] {
+			t.Errorf("IBAN %s was given to two accounts", iban)
+		}
+		seen[iban] = true
+		if acc, _ := service.GetAccount(iban); acc.Metadata["channel"] != "mobile" {
+			t.Errorf("Expected account to be opened with its metadata, got %+v", acc.Metadata)
+		}
+	}
+	if inMemImpl.Accounts.Len() != accounts+100 {
+		t.Errorf("Expected 100 accounts to be opened, got %d", inMemImpl.Accounts.Len()-accounts)
+	}
+	if details, _ := service.RetrieveAccount(recipient.Iban); details.Balance != 100 {
+		t.Errorf("Expected balance 100 after the transfers, got %v", details.Balance)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining graceful shutdown: components are stopped one by one in the order they were registered within the common
+// timeout, so that the API stops accepting requests first, background jobs finish the runs in progress next, published
+// events are delivered after that, and only then the books are flushed to disk
+type ShutdownCoordinator struct {
+	Timeout time.Duration
+	steps   []shutdownStep
+	started bool
+	Mutex   sync.Mutex
+}
+
+type shutdownStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func NewShutdownCoordinator(timeout time.Duration) *ShutdownCoordinator {
+	return &ShutdownCoordinator{Timeout: timeout}
+}
+
+// Registers the step stopping the component, i.e. Shutdown of the HTTP server, steps are run in the order of registration
+func (c *ShutdownCoordinator) Register(name string, run func(ctx context.Context) error) {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	c.steps = append(c.steps, shutdownStep{name, run})
+}
+
+// Registers the function stopping the component which does not take a deadline, i.e. the one returned by Start of the
+// scheduler, the function keeps running in the background if it does not return in time
+func (c *ShutdownCoordinator) RegisterStop(name string, stop func()) {
+	c.Register(name, func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			stop()
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf(errorCodesToMessagesMap[ShutdownTimeoutError][locale()])
+		}
+	})
+}
+
+// Blocks until one of the signals is received, i.e. os.Interrupt and syscall.SIGTERM, and returns it
+func (c *ShutdownCoordinator) WaitForSignal(signals ...os.Signal) os.Signal {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	defer signal.Stop(received)
+	return <-received
+}
+
+// Runs the steps once, steps failing or running out of time do not prevent the rest from running, so that as much as
+// possible is drained and flushed, the first failure is returned
+func (c *ShutdownCoordinator) Shutdown() error {
+	c.Mutex.Lock()
+	if c.started {
+		c.Mutex.Unlock()
+		return nil
+	}
+	c.started = true
+	steps := append([]shutdownStep{}, c.steps...)
+	c.Mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	var first error
+	for _, step := range steps {
+		start := time.Now()
+		err := step.run(ctx)
+		// Deadlines passed to the components are reported as the timeout of the shutdown
+		if err != nil && ctx.Err() != nil {
+			err = fmt.Errorf(errorCodesToMessagesMap[ShutdownTimeoutError][locale()])
+		}
+		if err != nil {
+			logger.Error("shutdown step failed", append([]any{"step", step.name, "duration", time.Since(start)}, errorAttrs(err)...)...)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		logger.Info("shutdown step completed", "step", step.name, "duration", time.Since(start))
+	}
+	return first
+}
+
+// Writes all accounts as newline-delimited JSON and the journal as JSON to the directory, files are replaced atomically,
+// so that a crash during the flush leaves the previous snapshot intact
+// Accounts and the journal are taken under the same locks, so that balances of the accounts are the ones the journal adds up to
+func (r *InMemoryAccountRepository) FlushSnapshot(dir string) error {
+	r.Mutex.RLock()
+	r.JournalMutex.RLock()
+	journal, err := r.journalAsJson()
+	accounts := r.accountSnapshots()
+	r.JournalMutex.RUnlock()
+	r.Mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := writeSnapshotFile(filepath.Join(dir, "journal.json"), func(file *os.File) error {
+		_, err := file.WriteString(journal)
+		return err
+	}); err != nil {
+		return err
+	}
+	return writeSnapshotFile(filepath.Join(dir, "accounts.ndjson"), func(file *os.File) error {
+		return r.exportAccounts(file, NdjsonExportFormat, func(callback func(Account) bool) error {
+			for _, acc := range accounts {
+				if !callback(acc) {
+					break
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Helper function to write the file next to the target first and then rename it to the target
+func writeSnapshotFile(path string, write func(file *os.File) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[SnapshotWriteError][locale()])
+	}
+	defer os.Remove(file.Name())
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf(errorCodesToMessagesMap[SnapshotWriteError][locale()])
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[SnapshotWriteError][locale()])
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[SnapshotWriteError][locale()])
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Drain the request in flight, stop the rest in order and keep going when a step runs out of time
+func TestGracefulShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	config := DefaultConfig()
+	config.HttpAddr = "127.0.0.1:0"
+	server, addr, err := config.StartServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	}))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	inFlight := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr.String())
+		if err == nil {
+			resp.Body.Close()
+		}
+		inFlight <- err
+	}()
+	<-started
+
+	order := []string{}
+	shutdown := NewShutdownCoordinator(500 * time.Millisecond)
+	shutdown.Register("http server", func(ctx context.Context) error {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		err := server.Shutdown(ctx)
+		order = append(order, "http server")
+		return err
+	})
+	shutdown.RegisterStop("stuck", func() { time.Sleep(time.Hour) })
+	shutdown.Register("events", func(ctx context.Context) error {
+		order = append(order, "events")
+		return nil
+	})
+	err = shutdown.Shutdown()
+	if err == nil || err.Error() != errorCodesToMessagesMap[ShutdownTimeoutError][locale()] {
+		t.Errorf("Expected the stuck step to time out, got %v", err)
+	}
+	if strings.Join(order, ",") != "http server,events" {
+		t.Errorf("Unexpected order of the steps %v", order)
+	}
+	if err := <-inFlight; err != nil {
+		t.Errorf("Expected the request in flight to be completed, got %v", err)
+	}
+	if _, err := http.Get("http://" + addr.String()); err == nil {
+		t.Errorf("Request after the shutdown failed to fail")
+	}
+	if err := shutdown.Shutdown(); err != nil {
+		t.Errorf("Expected the second shutdown to do nothing, got %v", err)
+	}
+}
+
+// Deliver the events published before the bus was closed and drop the ones published after
+func TestEventBusDraining(t *testing.T) {
+	bus := NewInMemoryEventBus()
+	events, unsubscribe := bus.Subscribe()
+	for i := 0; i < 3; i++ {
+		bus.Publish(TransferSettledEvent, TransferEventPayload{TransferID: fmt.Sprint(i)})
+	}
+	bus.Close()
+	bus.Publish(TransferSettledEvent, TransferEventPayload{})
+	received := 0
+	for range events {
+		received++
+	}
+	unsubscribe()
+	if received != 3 || bus.Dropped != 1 {
+		t.Errorf("Expected 3 events to be drained and 1 dropped, got %d and %d", received, bus.Dropped)
+	}
+	late, _ := bus.Subscribe()
+	if _, open := <-late; open {
+		t.Errorf("Expected subscription to the closed bus to be closed")
+	}
+}
+
+// Flush accounts and the journal to the directory
+func TestSnapshotFlush(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccount()
+	service.EmitMoney(100)
+	service.TransferMoney(emission, acc.Iban, 40)
+
+	dir := t.TempDir()
+	if err := inMemImpl.FlushSnapshot(dir); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	accounts, _ := os.ReadFile(filepath.Join(dir, "accounts.ndjson"))
+	if !strings.Contains(string(accounts), acc.Iban) || strings.Count(string(accounts), "\n") != 4 {
+		t.Errorf("Unexpected accounts snapshot %s", accounts)
+	}
+	journal, _ := os.ReadFile(filepath.Join(dir, "journal.json"))
+	if !strings.HasPrefix(string(journal), "[") || !strings.Contains(string(journal), `"debit":40`) {
+		t.Errorf("Unexpected journal snapshot %s", journal)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files to be left, got %d files", len(entries))
+	}
+	if err := inMemImpl.FlushSnapshot(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Flush to a missing directory failed to fail")
+	}
+}
+
+// Flush while transfers are running, the accounts written are always the ones the journal written along with them adds up to
+func TestSnapshotFlushConsistency(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccount()
+	service.EmitMoney(1000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 300; i++ {
+			service.TransferMoney(emission, acc.Iban, 1)
+		}
+	}()
+
+	dir := t.TempDir()
+	for flushed := false; !flushed; {
+		select {
+		case <-done:
+			flushed = true
+		default:
+		}
+		if err := inMemImpl.FlushSnapshot(dir); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		var balance float64
+		accounts, _ := os.ReadFile(filepath.Join(dir, "accounts.ndjson"))
+		for _, line := range strings.Split(strings.TrimSpace(string(accounts)), "\n") {
+			var details AccountDetails
+			if err := json.Unmarshal([]byte(line), &details); err == nil && details.Iban == acc.Iban {
+				balance = details.Balance
+			}
+		}
+		journal, _ := os.ReadFile(filepath.Join(dir, "journal.json"))
+		if credited := strings.Count(string(journal), `"account":"`+acc.Iban+`","currency":"BYN","credit":1`); float64(credited) != balance {
+			t.Fatalf("Expected balance %d the journal adds up to, got %.2f", credited, balance)
+		}
+	}
+}
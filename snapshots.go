@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining point-in-time snapshots of the repository: accounts and transactions are copied at once under the locks and
+// read afterwards without them, so that listings and statements reflect a state no transfer was half-recorded in, while
+// transfers wait only for the copying rather than for the whole serialization
+type RepositorySnapshot struct {
+	Sequence     uint64        // number of journal entries recorded when the snapshot was taken, the state is as of the last of them
+	TakenAt      time.Time     // time of the snapshot
+	Accounts     []Account     // emission, destruction and remainder accounts first, the rest in no particular order
+	Transactions []Transaction // transactions of the accounts the snapshot was taken for, the oldest first
+}
+
+// Helper function to copy all accounts, emission, destruction and remainder accounts first, expects the repository mutex and
+// the journal mutex to be held shared by the caller
+func (r *InMemoryAccountRepository) accountSnapshots() []Account {
+	accounts := []Account{}
+	for _, acc := range []*Account{r.EmissionAccount, r.DestructionAccount, r.RemainderAccount} {
+		if acc != nil {
+			accounts = append(accounts, acc.Snapshot())
+		}
+	}
+	for _, acc := range r.Accounts.All() {
+		if acc != r.EmissionAccount && acc != r.DestructionAccount && acc != r.RemainderAccount {
+			accounts = append(accounts, acc.Snapshot())
+		}
+	}
+	return accounts
+}
+
+// Takes the snapshot of the given accounts along with their transactions, or of all accounts without transactions if none
+// are given
+func (r *InMemoryAccountRepository) RetrieveSnapshot(ibans ...string) (*RepositorySnapshot, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	snapshot := &RepositorySnapshot{Sequence: uint64(len(r.Journal)), TakenAt: clock.Now().UTC(), Accounts: []Account{}, Transactions: []Transaction{}}
+	if len(ibans) == 0 {
+		snapshot.Accounts = r.accountSnapshots()
+		return snapshot, nil
+	}
+
+	included := map[string]bool{}
+	for _, iban := range ibans {
+		iban = NormalizeIban(iban)
+		// Checking if account associated with the given IBAN exists
+		acc, exists := r.Accounts.Get(iban)
+		if !exists || acc == nil {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+		}
+		if !included[iban] {
+			included[iban] = true
+			snapshot.Accounts = append(snapshot.Accounts, acc.Snapshot())
+		}
+	}
+	for _, tx := range r.Transactions {
+		if included[tx.Sender] || included[tx.Recipient] {
+			snapshot.Transactions = append(snapshot.Transactions, *tx)
+		}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// Take snapshots of all accounts and of single accounts with their transactions, later changes do not show up in them
+func TestRepositorySnapshot(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	other, _ := service.OpenAccount()
+	service.TransferMoney(acc.Iban, other.Iban, 30)
+
+	snapshot, err := service.RetrieveSnapshot()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(snapshot.Accounts) != inMemImpl.Accounts.Len() || snapshot.Accounts[0].Iban != inMemImpl.EmissionAccount.Iban || len(snapshot.Transactions) != 0 {
+		t.Errorf("Unexpected snapshot of all accounts %+v", snapshot)
+	}
+	if snapshot.Sequence != uint64(len(inMemImpl.Journal)) {
+		t.Errorf("Expected the snapshot to be as of journal entry %d, got %d", len(inMemImpl.Journal), snapshot.Sequence)
+	}
+
+	snapshot, err = service.RetrieveSnapshot(other.Iban, acc.Iban, other.Iban)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service.TransferMoney(acc.Iban, other.Iban, 20)
+	if len(snapshot.Accounts) != 2 || snapshot.Accounts[0].Iban != other.Iban || snapshot.Accounts[0].Balance != 30 {
+		t.Errorf("Unexpected accounts in the snapshot %+v", snapshot.Accounts)
+	}
+	if len(snapshot.Transactions) != 2 || snapshot.Transactions[0].Sender != inMemImpl.EmissionAccount.Iban || snapshot.Transactions[1].Amount != 30 {
+		t.Errorf("Unexpected transactions in the snapshot %+v", snapshot.Transactions)
+	}
+	if _, err := service.RetrieveSnapshot("BY00 UNKNOWN"); err == nil {
+		t.Errorf("Taking snapshot of unknown account failed to fail")
+	}
+}
+
+// Snapshots taken along with transfers never see money in flight
+func TestSnapshotConsistency(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	ibans := openLockTestAccounts(t, service, 4, 100)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			service.TransferMoney(ibans[i%4], ibans[(i+1)%4], 1)
+		}(i)
+		go func() {
+			defer wg.Done()
+			snapshot, err := service.RetrieveSnapshot(ibans...)
+			if err != nil {
+				t.Errorf("Error: %v", err)
+				return
+			}
+			total := 0.0
+			for _, acc := range snapshot.Accounts {
+				total += acc.Balance
+			}
+			if total != 400 {
+				t.Errorf("Expected the snapshot to hold 400 in total, got %v", total)
+			}
+		}()
+	}
+	wg.Wait()
+}
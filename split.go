@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// --------------------------------------------------------
+// Defining one-to-many split payments, i.e. payroll disbursement from a merchant account
+
+// Either Amount or Percentage of the total amount should be set for each share
+type SplitShare struct {
+	Recipient  string
+	Amount     float64
+	Percentage float64
+	Details    TransferDetails
+}
+
+// Precision of the check that shares add up to the total amount, in minor units of the currency
+const splitTolerance = 1e-6
+
+// Helper function to allocate the amount between the shares in minor units of the currency
+// Fixed amounts are allocated as is, percentage shares are rounded down and the residue is handed out one minor unit at a time
+// to the shares with the largest dropped fractions (the earlier share wins a tie), so that the allocation is deterministic
+func allocateSplit(amount float64, currency string, shares []SplitShare) ([]float64, bool) {
+	factor := math.Pow10(minorUnitsOf(currency))
+	totalUnits := math.Round(amount * factor)
+	units := make([]float64, len(shares))
+	dropped := make([]float64, len(shares))
+	percentageShares := []int{}
+	exactSum := 0.0
+	for i, share := range shares {
+		// Checking if exactly one of amount and percentage is set and it is positive
+		if (share.Amount > 0) == (share.Percentage > 0) || share.Amount < 0 || share.Percentage < 0 {
+			return nil, false
+		}
+		if share.Amount > 0 {
+			// Checking if fixed amount does not have more decimals than the currency allows
+			units[i] = math.Round(share.Amount * factor)
+			if math.Abs(units[i]-share.Amount*factor) > splitTolerance {
+				return nil, false
+			}
+			exactSum += units[i]
+			continue
+		}
+		exact := share.Percentage / 100 * totalUnits
+		units[i] = math.Floor(exact + splitTolerance)
+		dropped[i] = exact - units[i]
+		exactSum += exact
+		percentageShares = append(percentageShares, i)
+	}
+	// Checking if the shares add up to the total amount
+	if math.Abs(exactSum-totalUnits) > splitTolerance {
+		return nil, false
+	}
+
+	allocated := 0.0
+	for _, u := range units {
+		allocated += u
+	}
+	sort.SliceStable(percentageShares, func(a, b int) bool {
+		return dropped[percentageShares[a]] > dropped[percentageShares[b]]
+	})
+	for i := 0; allocated < totalUnits && i < len(percentageShares); i++ {
+		units[percentageShares[i]]++
+		allocated++
+	}
+
+	amounts := make([]float64, len(shares))
+	for i, u := range units {
+		amounts[i] = u / factor
+	}
+	return amounts, true
+}
+
+// Transfers the amount from the sender to several recipients in one atomic operation, either all shares are transferred or none of them
+// Returns IDs of the transfers in the order of the shares
+func (r *InMemoryAccountRepository) SplitTransfer(sender string, amount float64, shares []SplitShare) ([]string, error) {
+	// Splits run along with other transfers under the locks of all of their accounts, see locks.go
+	r.Mutex.RLock()
+	ibans := []string{sender}
+	for _, share := range shares {
+		ibans = append(ibans, share.Recipient)
+	}
+	if r.canLockAccounts(ibans...) {
+		defer r.Mutex.RUnlock()
+		unlock := r.AccountLocks.Lock(r.lockedAccounts(ibans...)...)
+		defer unlock()
+		// Shares are rolled back by truncating the log, so no other transfer may be recorded meanwhile
+		r.JournalMutex.Lock()
+		defer r.JournalMutex.Unlock()
+		return r.splitTransfer(sender, amount, shares)
+	}
+	r.Mutex.RUnlock()
+
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	return r.splitTransfer(sender, amount, shares)
+}
+
+// Helper function to execute the split, expects the repository mutex to be held by the caller, either exclusively or shared
+// along with the locks of the accounts of the split and the journal mutex
+func (r *InMemoryAccountRepository) splitTransfer(sender string, amount float64, shares []SplitShare) ([]string, error) {
+	sender = NormalizeIban(sender)
+	sAcc, sExists := r.Accounts.Get(sender)
+	if !sExists || sAcc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if money amount to split is not negative
+	if amount < 0 {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[NegativeAmountError][locale()])
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidSplitError][locale()])
+	}
+	// Checking KYC and per-transaction limits against the whole amount, so that splitting cannot be used to bypass them
+	if err := checkKycDebit(sAcc, amount); err != nil {
+		return nil, err
+	}
+	if err := r.checkDebitLimits(sAcc, amount); err != nil {
+		return nil, err
+	}
+	amounts, ok := allocateSplit(amount, sAcc.Currency, shares)
+	if !ok {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidSplitError][locale()])
+	}
+
+	// Settlements of the shares are only published once all shares are transferred
+	defer r.holdEvents()()
+	txCount := len(r.Transactions)
+	for i, share := range shares {
+		if err := r.transferMoney(sender, share.Recipient, amounts[i], share.Details); err != nil {
+			// Reverting debits of the shares transferred so far, fees charged on them are not counted as debits
+			for _, tx := range r.Transactions[txCount:] {
+				if tx.FeeOf == "" {
+					acc, _ := r.Accounts.Get(tx.Sender)
+					acc.revertDebit(tx.Amount)
+				}
+			}
+			r.rollbackTo(txCount)
+			return nil, err
+		}
+	}
+	ids := make([]string, 0, len(shares))
+	for _, tx := range r.Transactions[txCount:] {
+		// Skipping fees charged to the sender and acquiring fees charged to merchants among the recipients
+		if tx.FeeOf != "" {
+			continue
+		}
+		r.TransferStatuses[tx.Ulid] = TransferSettled
+		ids = append(ids, tx.Ulid)
+	}
+	return ids, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+)
+
+// Split a payment between several recipients and allocate the rounding residue deterministically
+func TestSplitTransfer(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	merchant, _ := service.OpenAccountWithInitialDeposit(200)
+	a, _ := service.OpenAccount()
+	b, _ := service.OpenAccount()
+	c, _ := service.OpenAccount()
+
+	// 100 split into thirds leaves one kopeck of residue that goes to the first share
+	ids, err := service.SplitTransfer(merchant.Iban, 100, []SplitShare{
+		{Recipient: a.Iban, Percentage: 100.0 / 3},
+		{Recipient: b.Iban, Percentage: 100.0 / 3},
+		{Recipient: c.Iban, Percentage: 100.0 / 3, Details: TransferDetails{PurposeCode: "SALA"}},
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(ids) != 3 {
+		t.Errorf("Expected 3 transfer IDs, got %v", ids)
+	}
+	for i, expected := range map[string]float64{a.Iban: 33.34, b.Iban: 33.33, c.Iban: 33.33} {
+		if details, _ := service.RetrieveAccount(i); details.Balance != expected {
+			t.Errorf("Expected balance %.2f on %s, got %.2f", expected, i, details.Balance)
+		}
+	}
+
+	// Fixed amounts and percentages can be mixed
+	if _, err := service.SplitTransfer(merchant.Iban, 50, []SplitShare{
+		{Recipient: a.Iban, Amount: 10},
+		{Recipient: b.Iban, Percentage: 80},
+	}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	invalid := [][]SplitShare{
+		{},
+		{{Recipient: a.Iban, Amount: 5}},
+		{{Recipient: a.Iban, Percentage: 50}, {Recipient: b.Iban, Percentage: 40}},
+		{{Recipient: a.Iban, Amount: 5, Percentage: 50}, {Recipient: b.Iban, Percentage: 50}},
+		{{Recipient: a.Iban, Amount: 9.999}, {Recipient: b.Iban, Amount: 0.001}},
+		{{Recipient: a.Iban}, {Recipient: b.Iban, Amount: 10}},
+	}
+	for _, shares := range invalid {
+		if _, err := service.SplitTransfer(merchant.Iban, 10, shares); err == nil {
+			t.Errorf("Split %+v failed to fail", shares)
+		}
+	}
+
+	// A failing share reverts the shares transferred before it
+	if err := service.BlockAccount(c.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.SplitTransfer(merchant.Iban, 20, []SplitShare{
+		{Recipient: a.Iban, Amount: 10},
+		{Recipient: c.Iban, Amount: 10},
+	}); err == nil {
+		t.Errorf("Split to a blocked account failed to fail")
+	}
+	if details, _ := service.RetrieveAccount(merchant.Iban); details.Balance != 50 {
+		t.Errorf("Expected merchant balance 50 after reverted split, got %.2f", details.Balance)
+	}
+	if details, _ := service.RetrieveAccount(a.Iban); details.Balance != 43.34 {
+		t.Errorf("Expected balance 43.34 after reverted split, got %.2f", details.Balance)
+	}
+	if rec, err := service.ReconcileFractions(); err != nil || !rec.Balanced {
+		t.Errorf("Expected books to be balanced, got %+v (%v)", rec, err)
+	}
+}
+
+// Settlements of the shares are published once the whole split is transferred, a reverted split publishes nothing
+func TestSplitTransferEvents(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	a, _ := service.OpenAccount()
+	b, _ := service.OpenAccount()
+	bus := NewInMemoryEventBus()
+	inMemImpl.EventBus = bus
+	events, unsubscribe := bus.Subscribe(TransferSettledEvent)
+	defer unsubscribe()
+
+	service.BlockAccount(b.Iban)
+	if _, err := service.SplitTransfer(sender.Iban, 20, []SplitShare{{Recipient: a.Iban, Amount: 10}, {Recipient: b.Iban, Amount: 10}}); err == nil {
+		t.Fatalf("Split to a blocked account failed to fail")
+	}
+	service.ActivateAccount(b.Iban)
+	ids, err := service.SplitTransfer(sender.Iban, 20, []SplitShare{{Recipient: a.Iban, Amount: 10}, {Recipient: b.Iban, Amount: 10}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	bus.Close()
+	published := []string{}
+	for event := range events {
+		published = append(published, event.Payload.(TransferEventPayload).TransferID)
+	}
+	if len(published) != 2 || published[0] != ids[0] || published[1] != ids[1] {
+		t.Errorf("Expected settlements of %v only, got %v", ids, published)
+	}
+}
+
+// Shares paid to merchants are charged acquiring fees, which are neither returned as shares nor reverted as debits
+func TestSplitTransferToMerchant(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.FeeSchedule = NewFeeSchedule()
+	inMemImpl.FeeSchedule.SetFee(TransferTransaction, FeeRule{Kind: FlatFee, Value: 1})
+	inMemImpl.FeeSchedule.SetFee(AcquiringFeeTransaction, FeeRule{Kind: PercentageFee, Value: 2})
+	service := NewAccountService(inMemImpl)
+	feeIncome, _ := service.OpenAccount()
+	if err := service.SetFeeAccount(feeIncome.Iban); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	c, _ := service.CreateCustomer("Shop", "", "", "")
+	merchant, err := service.OpenAccountWithOptions(AccountOptions{Product: MerchantProduct, CustomerID: c.ID})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	other, _ := service.OpenAccount()
+
+	ids, err := service.SplitTransfer(sender.Iban, 20, []SplitShare{{Recipient: merchant.Iban, Amount: 10}, {Recipient: other.Iban, Amount: 10}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 transfer IDs, got %v", ids)
+	}
+	for _, id := range ids {
+		if tx, _ := service.RetrieveTransaction(id); tx.Type != TransferTransaction {
+			t.Errorf("Expected transfers only, got %+v", tx)
+		}
+	}
+
+	// Reverting a share paid to the merchant keeps its daily debits as they were
+	service.BlockAccount(other.Iban)
+	if _, err := service.SplitTransfer(sender.Iban, 20, []SplitShare{{Recipient: merchant.Iban, Amount: 10}, {Recipient: other.Iban, Amount: 10}}); err == nil {
+		t.Fatalf("Split to a blocked account failed to fail")
+	}
+	if acc, _ := service.GetAccount(merchant.Iban); acc.DailyDebited != 0 {
+		t.Errorf("Expected no daily debits of the merchant, got %v", acc.DailyDebited)
+	}
+	if acc, _ := service.GetAccount(sender.Iban); acc.DailyDebited != 20 {
+		t.Errorf("Expected daily debits of the sender of 20, got %v", acc.DailyDebited)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining export of account activity for personal finance tools: OFX 1.0.2 (SGML) and QIF bank statements
+type StatementFormat int8
+
+const (
+	OfxStatementFormat StatementFormat = iota
+	QifStatementFormat
+)
+
+// Movement of money on the account as seen by its holder, debits are negative
+type statementLine struct {
+	ID           string
+	Posted       time.Time
+	Amount       float64
+	Counterparty string
+	Reference    string
+	Memo         string
+}
+
+// Helper function to find out the signed amount of the transaction for the account, conversions credit the converted amount
+func signedAmountFor(tx Transaction, iban string) float64 {
+	amount := 0.0
+	if tx.Recipient == iban {
+		if tx.Type == ConversionTransaction {
+			amount += tx.CreditedAmount
+		} else {
+			amount += tx.Amount
+		}
+	}
+	if tx.Sender == iban {
+		amount -= tx.Amount
+	}
+	return amount
+}
+
+// Helper function to remove characters with special meaning in the formats from free text
+func statementText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.NewReplacer("<", "", ">", "", "&", "", "^", "").Replace(text)
+}
+
+// Writes the whole history of the account in the chosen format, the oldest transactions first
+func (s *AccountService) ExportAccountActivity(writer io.Writer, iban string, format StatementFormat) error {
+	if err := s.authorize(ReadPermission, iban); err != nil {
+		return err
+	}
+	// Taking the account along with its transactions at once, so that the statement adds up to the balance
+	snapshot, err := s.accountRepoImpl.RetrieveSnapshot(iban)
+	if err != nil {
+		return err
+	}
+	acc := snapshot.Accounts[0]
+	lines := []statementLine{}
+	for _, tx := range snapshot.Transactions {
+		counterparty := tx.Sender
+		if tx.Sender == acc.Iban {
+			counterparty = tx.Recipient
+		}
+		memo := tx.Memo
+		if memo == "" {
+			memo = transactionTypeCodeToNameMap[tx.Type][locale()]
+		}
+		lines = append(lines, statementLine{tx.Ulid, tx.Timestamp, signedAmountFor(tx, acc.Iban), statementText(counterparty), statementText(tx.Reference), statementText(memo)})
+	}
+
+	buffered := bufio.NewWriter(writer)
+	switch format {
+	case OfxStatementFormat:
+		writeOfxStatement(buffered, acc, lines)
+	case QifStatementFormat:
+		writeQifStatement(buffered, acc, lines)
+	default:
+		return fmt.Errorf(errorCodesToMessagesMap[TransactionsExportError][locale()])
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf(errorCodesToMessagesMap[TransactionsExportError][locale()])
+	}
+	return nil
+}
+
+// Helper function to format the amount with a decimal point and as many decimals as the currency has minor units
+func statementAmount(amount float64, currency string) string {
+	return strconv.FormatFloat(roundToCurrency(amount, currency), 'f', minorUnitsOf(currency), 64)
+}
+
+// OFX 1.0.2 is SGML, so elements holding values are not closed
+func writeOfxStatement(writer *bufio.Writer, acc Account, lines []statementLine) {
+	const ofxTime = "20060102150405"
+	now := clock.Now().UTC()
+	start, end := now, now
+	if len(lines) > 0 {
+		start, end = lines[0].Posted, lines[len(lines)-1].Posted
+	}
+	header := []string{"OFXHEADER:100", "DATA:OFXSGML", "VERSION:102", "SECURITY:NONE", "ENCODING:USASCII", "CHARSET:1252", "COMPRESSION:NONE", "OLDFILEUID:NONE", "NEWFILEUID:NONE", ""}
+	for _, line := range header {
+		writer.WriteString(line + "\r\n")
+	}
+	element := func(name, value string) {
+		writer.WriteString("<" + name + ">" + value + "\r\n")
+	}
+	tag := func(name string) {
+		writer.WriteString("<" + name + ">\r\n")
+	}
+	tag("OFX")
+	tag("SIGNONMSGSRSV1")
+	tag("SONRS")
+	tag("STATUS")
+	element("CODE", "0")
+	element("SEVERITY", "INFO")
+	tag("/STATUS")
+	element("DTSERVER", now.Format(ofxTime))
+	element("LANGUAGE", "ENG")
+	tag("/SONRS")
+	tag("/SIGNONMSGSRSV1")
+	tag("BANKMSGSRSV1")
+	tag("STMTTRNRS")
+	element("TRNUID", "0")
+	tag("STATUS")
+	element("CODE", "0")
+	element("SEVERITY", "INFO")
+	tag("/STATUS")
+	tag("STMTRS")
+	element("CURDEF", acc.Currency)
+	tag("BANKACCTFROM")
+	element("BANKID", bankCodeOfIban(acc.Iban))
+	element("ACCTID", acc.Iban)
+	element("ACCTTYPE", "CHECKING")
+	tag("/BANKACCTFROM")
+	tag("BANKTRANLIST")
+	element("DTSTART", start.UTC().Format(ofxTime))
+	element("DTEND", end.UTC().Format(ofxTime))
+	for _, line := range lines {
+		trnType := "CREDIT"
+		if line.Amount < 0 {
+			trnType = "DEBIT"
+		}
+		tag("STMTTRN")
+		element("TRNTYPE", trnType)
+		element("DTPOSTED", line.Posted.UTC().Format(ofxTime))
+		element("TRNAMT", statementAmount(line.Amount, acc.Currency))
+		element("FITID", line.ID)
+		if line.Reference != "" {
+			element("REFNUM", line.Reference)
+		}
+		if line.Counterparty != "" {
+			element("NAME", line.Counterparty)
+		}
+		element("MEMO", line.Memo)
+		tag("/STMTTRN")
+	}
+	tag("/BANKTRANLIST")
+	tag("LEDGERBAL")
+	element("BALAMT", statementAmount(acc.Balance, acc.Currency))
+	element("DTASOF", now.Format(ofxTime))
+	tag("/LEDGERBAL")
+	tag("AVAILBAL")
+	element("BALAMT", statementAmount(acc.AvailableBalance(), acc.Currency))
+	element("DTASOF", now.Format(ofxTime))
+	tag("/AVAILBAL")
+	tag("/STMTRS")
+	tag("/STMTTRNRS")
+	tag("/BANKMSGSRSV1")
+	tag("/OFX")
+}
+
+// QIF has no notion of currency or balance, every record is terminated by a caret
+func writeQifStatement(writer *bufio.Writer, acc Account, lines []statementLine) {
+	writer.WriteString("!Type:Bank\n")
+	for _, line := range lines {
+		writer.WriteString("D" + line.Posted.UTC().Format("01/02/2006") + "\n")
+		writer.WriteString("T" + statementAmount(line.Amount, acc.Currency) + "\n")
+		if line.Reference != "" {
+			writer.WriteString("N" + line.Reference + "\n")
+		}
+		if line.Counterparty != "" {
+			writer.WriteString("P" + line.Counterparty + "\n")
+		}
+		writer.WriteString("M" + line.Memo + "\n")
+		writer.WriteString("^\n")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Activity is exported with signed amounts, the oldest transactions first
+func TestExportAccountActivity(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	inMemImpl.RateProvider = NewFixedRateProvider(map[string]float64{"BYN/USD": 0.3})
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	dollars, _ := service.OpenAccountInCurrency("USD")
+	if _, err := service.TransferMoneyWithDetails(acc.Iban, recipient.Iban, 12.5, TransferDetails{Reference: "INV-7", Memo: "Rent <May>"}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	service.ConvertAndTransfer(acc.Iban, dollars.Iban, 10)
+
+	buffer := bytes.Buffer{}
+	if err := service.ExportAccountActivity(&buffer, acc.Iban, QifStatementFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	records := strings.Split(strings.TrimSuffix(buffer.String(), "^\n"), "^\n")
+	if !strings.HasPrefix(records[0], "!Type:Bank\n") || len(records) != 3 {
+		t.Fatalf("Unexpected QIF:\n%s", buffer.String())
+	}
+	if !strings.Contains(records[0], "T100.00\n") || !strings.Contains(records[1], "T-12.50\nNINV-7\nP"+recipient.Iban+"\nMRent May\n") || !strings.Contains(records[2], "T-10.00\n") {
+		t.Errorf("Unexpected QIF:\n%s", buffer.String())
+	}
+
+	buffer.Reset()
+	if err := service.ExportAccountActivity(&buffer, dollars.Iban, OfxStatementFormat); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ofx := buffer.String()
+	for _, expected := range []string{"OFXHEADER:100\r\n", "<CURDEF>USD\r\n", "<ACCTID>" + dollars.Iban + "\r\n", "<TRNTYPE>CREDIT\r\n<DTPOSTED>", "<TRNAMT>3.00\r\n", "<LEDGERBAL>\r\n<BALAMT>3.00\r\n", "</OFX>\r\n"} {
+		if !strings.Contains(ofx, expected) {
+			t.Errorf("Expected %q in OFX:\n%s", expected, ofx)
+		}
+	}
+
+	if err := service.ExportAccountActivity(&buffer, acc.Iban, StatementFormat(7)); err == nil {
+		t.Errorf("Exporting in unknown format failed to fail")
+	}
+	if err := service.ExportAccountActivity(&buffer, "BY00 0000", QifStatementFormat); err == nil {
+		t.Errorf("Exporting unknown account failed to fail")
+	}
+}
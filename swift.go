@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// --------------------------------------------------------
+// Defining SWIFT MT103 (single customer credit transfer) rendering of settled transfers for SWIFT simulation tooling
+// Only the text block (block 4) is rendered, headers are up to the tooling since the prototype has no BIC of its own
+type MT103Party struct {
+	Iban    string
+	Name    string
+	Address string
+}
+
+type MT103Message struct {
+	Reference   string // field 20, up to 16 characters
+	ValueDate   string // YYMMDD
+	Currency    string
+	Amount      float64
+	Ordering    MT103Party // field 50K
+	Beneficiary MT103Party // field 59
+	Charges     string     // field 71A, OUR, SHA or BEN
+}
+
+// Maximum lengths of the reference and of a line of party fields
+const (
+	mt103ReferenceLength = 16
+	mt103LineLength      = 35
+)
+
+// Helper function to fit the text into a line of a party field, SWIFT lines cannot start with a slash or a hyphen
+func mt103Line(text string) string {
+	text = strings.TrimLeft(strings.Join(strings.Fields(text), " "), "/-")
+	if utf8.RuneCountInString(text) > mt103LineLength {
+		text = string([]rune(text)[:mt103LineLength])
+	}
+	return text
+}
+
+// Renders fields 20, 23B, 32A, 50K, 59 and 71A, amounts are written with a decimal comma as SWIFT requires
+func FormatMT103(msg MT103Message) string {
+	amount := strings.Replace(fmt.Sprintf("%.*f", minorUnitsOf(msg.Currency), roundToCurrency(msg.Amount, msg.Currency)), ".", ",", 1)
+	if !strings.Contains(amount, ",") {
+		amount += ","
+	}
+	party := func(tag string, p MT103Party) string {
+		lines := []string{":" + tag + ":/" + p.Iban}
+		if name := mt103Line(p.Name); name != "" {
+			lines = append(lines, name)
+		}
+		if address := mt103Line(p.Address); address != "" {
+			lines = append(lines, address)
+		}
+		return strings.Join(lines, "\r\n")
+	}
+	fields := []string{
+		"{4:",
+		":20:" + msg.Reference,
+		":23B:CRED",
+		":32A:" + msg.ValueDate + strings.ToUpper(msg.Currency) + amount,
+		party("50K", msg.Ordering),
+		party("59", msg.Beneficiary),
+		":71A:" + msg.Charges,
+		"-}",
+	}
+	return strings.Join(fields, "\r\n")
+}
+
+func (s *AccountService) mt103Party(iban string) MT103Party {
+	name, address := s.accountHolder(iban)
+	return MT103Party{iban, name, address}
+}
+
+// Renders the settled transfer with the given ID as an MT103 message, holders are looked up in the customer repository
+func (s *AccountService) RenderMT103(transferID string) (string, error) {
+	if err := s.authorizeTransfer(ReadPermission, transferID); err != nil {
+		return "", err
+	}
+	tx, err := s.accountRepoImpl.RetrieveTransaction(transferID)
+	if err != nil {
+		return "", err
+	}
+	// Checking if the transaction is a transfer between accounts
+	if tx.Type != TransferTransaction && tx.Type != InternalMoveTransaction {
+		return "", fmt.Errorf(errorCodesToMessagesMap[TransferDoesNotExistError][locale()])
+	}
+	reference := mt103Line(tx.Reference)
+	if reference == "" || strings.Contains(reference, "//") || strings.HasSuffix(reference, "/") {
+		reference = tx.Ulid[len(tx.Ulid)-mt103ReferenceLength:]
+	}
+	if len(reference) > mt103ReferenceLength {
+		reference = reference[:mt103ReferenceLength]
+	}
+	msg := MT103Message{
+		Reference:   reference,
+		ValueDate:   tx.Timestamp.UTC().Format("060102"),
+		Currency:    tx.Currency,
+		Amount:      tx.Amount,
+		Ordering:    s.mt103Party(tx.Sender),
+		Beneficiary: s.mt103Party(tx.Recipient),
+		Charges:     feeBearerCodeToNameMap[tx.FeeBearer],
+	}
+	return FormatMT103(msg), nil
+}
+
+// Renders every transfer settled after the call as an MT103 message to the writer until the returned function is called,
+// messages are separated by blank lines, the callback (if any) receives rendering and writing errors
+func (s *AccountService) StartMT103Rendering(bus EventBus, writer io.Writer, callback func(transferID string, err error)) func() {
+	events, unsubscribe := bus.Subscribe(TransferSettledEvent)
+	go func() {
+		for event := range events {
+			payload, ok := event.Payload.(TransferEventPayload)
+			if !ok {
+				continue
+			}
+			msg, err := s.RenderMT103(payload.TransferID)
+			if err == nil {
+				_, err = io.WriteString(writer, msg+"\r\n\r\n")
+			}
+			if err != nil && callback != nil {
+				callback(payload.TransferID, err)
+			}
+		}
+	}()
+	return unsubscribe
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Settled transfers are rendered with the holders of both accounts and the fee bearer
+func TestRenderMT103(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	alice, _ := service.CreateCustomer("Alice Smith", "alice@example.com", "", "1 Main Street, Minsk")
+	sender, _ := service.OpenAccountForCustomer(alice.ID)
+	service.VerifyKyc(sender.Iban)
+	service.EmitMoney(2000)
+	emission, _ := service.RetrieveEmissionAccountIban()
+	if _, err := service.TransferMoney(emission, sender.Iban, 2000); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	recipient, _ := service.OpenAccount()
+	id, err := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 1234.5, TransferDetails{Reference: "INV-2024-001", FeeBearer: FeeBearerSha})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	tx, _ := service.RetrieveTransaction(id)
+
+	msg, err := service.RenderMT103(id)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"{4:",
+		":20:INV-2024-001",
+		":23B:CRED",
+		":32A:" + tx.Timestamp.Format("060102") + "BYN1234,50",
+		":50K:/" + sender.Iban,
+		"Alice Smith",
+		"1 Main Street, Minsk",
+		":59:/" + recipient.Iban,
+		"Ordinary",
+		":71A:SHA",
+		"-}",
+	}, "\r\n")
+	if msg != expected {
+		t.Errorf("Unexpected message:\n%s", msg)
+	}
+
+	// Emissions are not customer transfers
+	all, _ := service.RetrieveAccountTransactions(emission, 0)
+	if _, err := service.RenderMT103(all[len(all)-1].Ulid); err == nil {
+		t.Errorf("Rendering emission failed to fail")
+	}
+	if _, err := service.RenderMT103("01ARZ3NDEKTSV4RRFFQ69G5FAV"); err == nil {
+		t.Errorf("Rendering unknown transfer failed to fail")
+	}
+}
+
+type syncBuffer struct {
+	buffer bytes.Buffer
+	mutex  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+// Transfers settled while rendering is started are written to the writer
+func TestStartMT103Rendering(t *testing.T) {
+	inMemImpl := NewInMemoryAccountRepository("BY84 ALFA 1000 0000 0000 0000 0000", "BY84 ALFA 1000 0000 0000 0000 0001")
+	bus := NewInMemoryEventBus()
+	inMemImpl.EventBus = bus
+	service := NewAccountService(inMemImpl)
+	writer := &syncBuffer{}
+	stop := service.StartMT103Rendering(bus, writer, nil)
+	defer stop()
+
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	service.TransferMoney(sender.Iban, recipient.Iban, 10)
+
+	deadline := time.Now().Add(time.Second)
+	for strings.Count(writer.String(), ":20:") < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if output := writer.String(); strings.Count(output, ":20:") != 2 || !strings.Contains(output, ":59:/"+recipient.Iban) {
+		t.Errorf("Unexpected output:\n%s", output)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// --------------------------------------------------------
+// Defining TLS of the servers and of the clients talking to the peers: certificates are loaded from PEM files,
+// servers may require client certificates issued by the client CA and map their subjects to principals
+type TlsConfig struct {
+	CertFile          string // PEM encoded certificate chain of the server, or of the client when talking to the peers
+	KeyFile           string // PEM encoded private key of the certificate
+	ClientCAFile      string // CA the client certificates are verified by, client certificates are not requested if empty
+	RequireClientCert bool   // connections without a valid client certificate are refused, only used with ClientCAFile
+	RootCAFile        string // CA the certificates of the peers are verified by, system roots are used if empty
+}
+
+// Helper function to load the certificate pool from the PEM file
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTlsConfigError][locale()])
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTlsConfigError][locale()])
+	}
+	return pool, nil
+}
+
+// Returns the config of the HTTP, gRPC or TCP server, TLS 1.2 is the oldest version accepted
+func (c TlsConfig) ServerConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTlsConfigError][locale()])
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if strings.TrimSpace(c.ClientCAFile) != "" {
+		pool, err := loadCertPool(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.RequireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return config, nil
+}
+
+// Returns the config of the client talking to the peers, the certificate is presented to the peers if given
+func (c TlsConfig) ClientConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if strings.TrimSpace(c.CertFile) != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidTlsConfigError][locale()])
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if strings.TrimSpace(c.RootCAFile) != "" {
+		pool, err := loadCertPool(c.RootCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// Wraps the listener of a TCP server to accept TLS connections only
+func (c TlsConfig) Listener(listener net.Listener) (net.Listener, error) {
+	config, err := c.ServerConfig()
+	if err != nil {
+		return nil, err
+	}
+	return tls.NewListener(listener, config), nil
+}
+
+// Makes the gateway talk to the peers over TLS with the certificate of the config
+func (g *InterbankGateway) UseTls(c TlsConfig) error {
+	config, err := c.ClientConfig()
+	if err != nil {
+		return err
+	}
+	g.Client.Transport = &http.Transport{TLSClientConfig: config}
+	return nil
+}
+
+// Wraps the handler to authenticate requests by verified client certificates, the principal derived from the certificate
+// is passed to the handler in the request context, see PrincipalFromContext
+// Requests without a certificate or with one not mapped to a principal are rejected
+func ClientCertMiddleware(principalOf func(cert *x509.Certificate) (Principal, bool), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+			http.Error(w, errorCodesToMessagesMap[AccessDeniedError][locale()], http.StatusUnauthorized)
+			return
+		}
+		principal, ok := principalOf(req.TLS.VerifiedChains[0][0])
+		if !ok {
+			http.Error(w, errorCodesToMessagesMap[AccessDeniedError][locale()], http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), principalContextKey{}, principal)))
+	})
+}
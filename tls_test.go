@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Helper function to issue the certificate signed by the CA, the certificate is self-signed if no CA is given,
+// writes the PEM files of the certificate and its key to the directory and returns their paths
+func issueTestCert(t *testing.T, dir, name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (string, string, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(time.Now().UnixNano()), Subject: pkix.Name{CommonName: name}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour),
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, KeyUsage: x509.KeyUsageDigitalSignature}
+	if ca == nil {
+		template.IsCA, template.BasicConstraintsValid, template.KeyUsage = true, true, x509.KeyUsageCertSign
+		ca, caKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	cert, _ := x509.ParseCertificate(der)
+	return certPath, keyPath, cert, key
+}
+
+// Servers requiring client certificates refuse clients without them and map the subjects of the certificates to principals
+func TestMutualTls(t *testing.T) {
+	dir := t.TempDir()
+	caPath, _, ca, caKey := issueTestCert(t, dir, "ca", nil, nil)
+	serverCert, serverKey, _, _ := issueTestCert(t, dir, "server", ca, caKey)
+	clientCert, clientKey, _, _ := issueTestCert(t, dir, "ALFA", ca, caKey)
+
+	if _, err := (TlsConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: serverKey}).ServerConfig(); err == nil {
+		t.Errorf("Missing certificate failed to fail")
+	}
+	serverConfig, err := TlsConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: caPath, RequireClientCert: true}.ServerConfig()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	server := httptest.NewUnstartedServer(ClientCertMiddleware(func(cert *x509.Certificate) (Principal, bool) {
+		return Principal{ID: cert.Subject.CommonName, Role: TellerRole}, cert.Subject.CommonName == "ALFA"
+	}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if principal, ok := PrincipalFromContext(req.Context()); !ok || principal.ID != "ALFA" {
+			http.Error(w, "unexpected principal", http.StatusInternalServerError)
+		}
+	})))
+	server.TLS = serverConfig
+	server.StartTLS()
+	defer server.Close()
+
+	anonymous, err := TlsConfig{RootCAFile: caPath}.ClientConfig()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: anonymous}}
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("Request without a client certificate failed to fail")
+	}
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	gateway := NewInterbankGateway(NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")), "ALFA")
+	if err := gateway.UseTls(TlsConfig{CertFile: clientCert, KeyFile: clientKey, RootCAFile: caPath}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	resp, err := gateway.Client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected request with the client certificate to succeed, got %d", resp.StatusCode)
+	}
+}
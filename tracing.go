@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining distributed tracing in the manner of OpenTelemetry: operations of the service and the repository are recorded
+// as spans of the trace, the trace context is taken from and passed on in the W3C traceparent header, so that a transfer
+// can be followed from the request through the repository to the events, the webhooks and the peer banks
+const traceParentHeader = "traceparent"
+
+// Trace IDs are 16 bytes and span IDs are 8 bytes long, both in lower-case hex
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+func (c SpanContext) IsValid() bool {
+	return isTraceHex(c.TraceID, 32) && isTraceHex(c.SpanID, 16)
+}
+
+// Returns the context in the format of the traceparent header, i.e. "00-<trace ID>-<span ID>-01", all spans are sampled
+func (c SpanContext) TraceParent() string {
+	if !c.IsValid() {
+		return ""
+	}
+	return "00-" + c.TraceID + "-" + c.SpanID + "-01"
+}
+
+// Helper function to check if the ID is the hex of the given length which is not all zeros, as the W3C format demands
+func isTraceHex(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && strings.ToLower(id) == id
+}
+
+// Parses the traceparent header, headers of unknown versions are accepted as long as they start with the known fields
+func ParseTraceParent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	c := SpanContext{parts[1], parts[2]}
+	return c, c.IsValid()
+}
+
+// Helper function to generate a random ID of the given number of bytes
+func newTraceID(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Kinds of the spans as in OpenTelemetry
+const (
+	InternalSpan = "internal"
+	ServerSpan   = "server"
+)
+
+type Span struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"` // empty for the root span of the trace
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	StartedAt  time.Time         `json:"start"`
+	EndedAt    time.Time         `json:"end"`
+	Error      string            `json:"error,omitempty"` // empty if the operation succeeded
+	tracer     *Tracer
+}
+
+func (s *Span) Context() SpanContext {
+	return SpanContext{s.TraceID, s.SpanID}
+}
+
+// Sets the attribute of the span, attributes are given as name-value pairs
+func (s *Span) SetAttributes(attrs ...string) {
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if s.Attributes == nil {
+			s.Attributes = map[string]string{}
+		}
+		s.Attributes[attrs[i]] = attrs[i+1]
+	}
+}
+
+// Ends the span with the outcome of the operation and exports it, error codes are recorded as the attribute
+func (s *Span) End(err error) {
+	s.EndedAt = time.Now().UTC()
+	if err != nil {
+		s.Error = err.Error()
+		if code, ok := errorCodeOf(err); ok {
+			s.SetAttributes("error.code", strconv.Itoa(int(code)))
+		}
+	}
+	s.tracer.exporter.ExportSpan(*s)
+}
+
+// Receives the ended spans, i.e. to send them to a collector
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+// Keeps the spans in memory, mostly useful in tests
+type InMemorySpanExporter struct {
+	spans []Span
+	Mutex sync.Mutex
+}
+
+func NewInMemorySpanExporter() *InMemorySpanExporter {
+	return &InMemorySpanExporter{}
+}
+
+func (e *InMemorySpanExporter) ExportSpan(span Span) {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Returns the spans of the trace in the order they ended, spans of all traces if the trace ID is empty
+func (e *InMemorySpanExporter) Spans(traceID string) []Span {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	found := []Span{}
+	for _, span := range e.spans {
+		if traceID == "" || span.TraceID == traceID {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+// Writes the spans as JSON lines, i.e. to be picked up by the collector from the standard output
+type JsonSpanExporter struct {
+	writer io.Writer
+	Mutex  sync.Mutex
+}
+
+func NewJsonSpanExporter(writer io.Writer) *JsonSpanExporter {
+	return &JsonSpanExporter{writer: writer}
+}
+
+func (e *JsonSpanExporter) ExportSpan(span Span) {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	json.NewEncoder(e.writer).Encode(span)
+}
+
+type Tracer struct {
+	exporter SpanExporter
+}
+
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{exporter}
+}
+
+// Starts the span as a child of the parent, a new trace is started if the parent is not valid
+func (t *Tracer) Start(parent SpanContext, name, kind string, attrs ...string) *Span {
+	span := &Span{TraceID: parent.TraceID, SpanID: newTraceID(8), ParentID: parent.SpanID, Name: name, Kind: kind, StartedAt: time.Now().UTC(), tracer: t}
+	if !parent.IsValid() {
+		span.TraceID, span.ParentID = newTraceID(16), ""
+	}
+	span.SetAttributes(attrs...)
+	return span
+}
+
+type spanContextKey struct{}
+
+func ContextWithSpan(ctx context.Context, span SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// Returns the span the request is handled in, see TracingMiddleware
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	span, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return span, ok && span.IsValid()
+}
+
+// Helper type to capture the status code of the response for the span
+type tracedResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *tracedResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Handles every request in a server span continuing the trace of the traceparent header, if any, the span is passed to
+// the handler in the request context and returned to the caller in the traceparent header of the response
+func TracingMiddleware(tracer *Tracer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		parent, _ := ParseTraceParent(req.Header.Get(traceParentHeader))
+		span := tracer.Start(parent, req.Method+" "+req.URL.Path, ServerSpan, "http.method", req.Method, "http.target", req.URL.Path)
+		w.Header().Set(traceParentHeader, span.Context().TraceParent())
+		recorder := &tracedResponseWriter{w, http.StatusOK}
+		next.ServeHTTP(recorder, req.WithContext(ContextWithSpan(req.Context(), span.Context())))
+		span.SetAttributes("http.status_code", strconv.Itoa(recorder.status))
+		span.End(nil)
+	})
+}
+
+// Span of the operations of the service bound to the trace, the spans of the repository are its children
+type serviceTracing struct {
+	tracer   *Tracer
+	span     SpanContext // empty until the service is bound to the trace by WithTrace
+	parentID string
+	start    time.Time
+	ended    bool
+	mutex    sync.Mutex
+}
+
+// Traces the audited operations of the service, the service is bound to the trace of the request by ForRequest
+func (s *AccountService) WithTracer(tracer *Tracer) *AccountService {
+	bound := *s
+	bound.tracing = &serviceTracing{tracer: tracer}
+	return &bound
+}
+
+// Binds the service to the trace, so that its operations are traced as children of the span, the service is returned
+// as it is if it has no tracer
+func (s *AccountService) WithTrace(parent SpanContext) *AccountService {
+	if s.tracing == nil || !parent.IsValid() {
+		return s
+	}
+	bound := *s
+	bound.tracing = &serviceTracing{tracer: s.tracing.tracer, span: SpanContext{parent.TraceID, newTraceID(8)}, parentID: parent.SpanID, start: time.Now().UTC()}
+	if repo, ok := s.accountRepoImpl.(*TracingAccountRepository); ok {
+		bound.accountRepoImpl = repo.WithParent(bound.tracing.span)
+	}
+	return &bound
+}
+
+// Helper function to export the span of the audited operation, the span starts when the service was bound to the trace
+// Further operations of the same bound service get spans of their own starting where the previous one ended
+func (s *AccountService) traceAudited(entry AuditEntry, err error) {
+	if s.tracing == nil || !s.tracing.span.IsValid() {
+		return
+	}
+	t := s.tracing
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	span := &Span{TraceID: t.span.TraceID, SpanID: t.span.SpanID, ParentID: t.parentID, Name: "AccountService." + auditActionCodeToNameMap[entry.Action][English], Kind: InternalSpan, StartedAt: t.start, tracer: t.tracer}
+	if t.ended {
+		span.SpanID = newTraceID(8)
+	}
+	span.SetAttributes("principal", entry.Principal, "iban", entry.Iban)
+	if entry.Counterparty != "" {
+		span.SetAttributes("counterparty", entry.Counterparty)
+	}
+	if entry.Amount != 0 {
+		span.SetAttributes("amount", formatSpanAmount(entry.Amount))
+	}
+	span.End(err)
+	t.start, t.ended = span.EndedAt, true
+}
+
+// Helper function to format the amount for the attribute
+func formatSpanAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// Decorates the repository to record a span for every money movement and change of account status, transfers are made
+// in the trace context, so that it reaches the events and the peer banks
+type TracingAccountRepository struct {
+	AccountRepository
+	tracer *Tracer
+	parent SpanContext // spans are roots of new traces if empty
+}
+
+func NewTracingAccountRepository(r AccountRepository, tracer *Tracer) *TracingAccountRepository {
+	return &TracingAccountRepository{AccountRepository: r, tracer: tracer}
+}
+
+// Returns the repository recording spans as children of the parent
+func (r *TracingAccountRepository) WithParent(parent SpanContext) *TracingAccountRepository {
+	return &TracingAccountRepository{r.AccountRepository, r.tracer, parent}
+}
+
+// Helper function to start the span of the operation
+func (r *TracingAccountRepository) start(operation string, attrs ...string) *Span {
+	return r.tracer.Start(r.parent, "AccountRepository."+operation, InternalSpan, attrs...)
+}
+
+// Helper function to pass the trace context on with the transfer, the context given by the caller is kept
+func tracedDetails(details TransferDetails, span *Span) TransferDetails {
+	if details.TraceParent == "" {
+		details.TraceParent = span.Context().TraceParent()
+	}
+	return details
+}
+
+// Helper function to record the IBAN of the opened account
+func endOpening(span *Span, acc *Account, err error) (*Account, error) {
+	if acc != nil {
+		span.SetAttributes("iban", acc.Iban)
+	}
+	span.End(err)
+	return acc, err
+}
+
+func (r *TracingAccountRepository) EmitMoney(amount float64) error {
+	span := r.start("EmitMoney", "amount", formatSpanAmount(amount))
+	err := r.AccountRepository.EmitMoney(amount)
+	span.End(err)
+	return err
+}
+
+func (r *TracingAccountRepository) DestructMoney(iban string, amount float64) error {
+	span := r.start("DestructMoney", "iban", iban, "amount", formatSpanAmount(amount))
+	err := r.AccountRepository.DestructMoney(iban, amount)
+	span.End(err)
+	return err
+}
+
+func (r *TracingAccountRepository) OpenAccount() (*Account, error) {
+	span := r.start("OpenAccount")
+	acc, err := r.AccountRepository.OpenAccount()
+	return endOpening(span, acc, err)
+}
+
+func (r *TracingAccountRepository) OpenAccountInCurrency(currency string) (*Account, error) {
+	span := r.start("OpenAccountInCurrency", "currency", currency)
+	acc, err := r.AccountRepository.OpenAccountInCurrency(currency)
+	return endOpening(span, acc, err)
+}
+
+func (r *TracingAccountRepository) OpenAccountWithOptions(opts AccountOptions) (*Account, error) {
+	span := r.start("OpenAccountWithOptions")
+	acc, err := r.AccountRepository.OpenAccountWithOptions(opts)
+	return endOpening(span, acc, err)
+}
+
+func (r *TracingAccountRepository) OpenAccountWithInitialDeposit(amount float64) (*Account, error) {
+	span := r.start("OpenAccountWithInitialDeposit", "amount", formatSpanAmount(amount))
+	acc, err := r.AccountRepository.OpenAccountWithInitialDeposit(amount)
+	return endOpening(span, acc, err)
+}
+
+// Made as the transfer with details to pass the trace context on, see tracedDetails
+func (r *TracingAccountRepository) TransferMoney(sender, recipient string, amount float64) (string, error) {
+	span := r.start("TransferMoney", "sender", sender, "recipient", recipient, "amount", formatSpanAmount(amount))
+	id, err := r.AccountRepository.TransferMoneyWithDetails(sender, recipient, amount, tracedDetails(TransferDetails{}, span))
+	span.SetAttributes("transfer.id", id)
+	span.End(err)
+	return id, err
+}
+
+func (r *TracingAccountRepository) TransferMoneyWithDetails(sender, recipient string, amount float64, details TransferDetails) (string, error) {
+	span := r.start("TransferMoneyWithDetails", "sender", sender, "recipient", recipient, "amount", formatSpanAmount(amount))
+	id, err := r.AccountRepository.TransferMoneyWithDetails(sender, recipient, amount, tracedDetails(details, span))
+	span.SetAttributes("transfer.id", id)
+	span.End(err)
+	return id, err
+}
+
+func (r *TracingAccountRepository) TransferMoneyJson(jsonStr string) (string, error) {
+	span := r.start("TransferMoneyJson")
+	id, err := r.AccountRepository.TransferMoneyJson(jsonStr)
+	span.SetAttributes("transfer.id", id)
+	span.End(err)
+	return id, err
+}
+
+func (r *TracingAccountRepository) ConvertAndTransfer(sender, recipient string, amount float64) error {
+	span := r.start("ConvertAndTransfer", "sender", sender, "recipient", recipient, "amount", formatSpanAmount(amount))
+	err := r.AccountRepository.ConvertAndTransfer(sender, recipient, amount)
+	span.End(err)
+	return err
+}
+
+func (r *TracingAccountRepository) ReverseTransfer(id, reason string) (string, error) {
+	span := r.start("ReverseTransfer", "transfer.id", id)
+	reversal, err := r.AccountRepository.ReverseTransfer(id, reason)
+	span.End(err)
+	return reversal, err
+}
+
+func (r *TracingAccountRepository) InitiateInterbankTransfer(sender, recipient, peerBank string, amount float64, details TransferDetails) (*InterbankTransfer, error) {
+	span := r.start("InitiateInterbankTransfer", "sender", sender, "recipient", recipient, "peer_bank", peerBank, "amount", formatSpanAmount(amount))
+	transfer, err := r.AccountRepository.InitiateInterbankTransfer(sender, recipient, peerBank, amount, tracedDetails(details, span))
+	span.End(err)
+	return transfer, err
+}
+
+func (r *TracingAccountRepository) ReceiveInterbankTransfer(msg InterbankMessage) (InterbankAck, error) {
+	span := r.start("ReceiveInterbankTransfer", "correlation.id", msg.CorrelationID, "sender", msg.Sender, "recipient", msg.Recipient, "amount", formatSpanAmount(msg.Amount))
+	msg.TraceParent = span.Context().TraceParent()
+	ack, err := r.AccountRepository.ReceiveInterbankTransfer(msg)
+	span.End(err)
+	return ack, err
+}
+
+func (r *TracingAccountRepository) BlockAccount(iban string) error {
+	span := r.start("BlockAccount", "iban", iban)
+	err := r.AccountRepository.BlockAccount(iban)
+	span.End(err)
+	return err
+}
+
+func (r *TracingAccountRepository) ActivateAccount(iban string) error {
+	span := r.start("ActivateAccount", "iban", iban)
+	err := r.AccountRepository.ActivateAccount(iban)
+	span.End(err)
+	return err
+}
+
+func (r *TracingAccountRepository) CloseAccount(iban, sweepTargetIban string) error {
+	span := r.start("CloseAccount", "iban", iban, "sweep_target", sweepTargetIban)
+	err := r.AccountRepository.CloseAccount(iban, sweepTargetIban)
+	span.End(err)
+	return err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Accept only well-formed traceparent headers
+func TestTraceParent(t *testing.T) {
+	valid := map[string]SpanContext{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       {"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra": {"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+	}
+	for header, expected := range valid {
+		if c, ok := ParseTraceParent(header); !ok || c != expected {
+			t.Errorf("Expected %q to be parsed, got %+v", header, c)
+		}
+	}
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	}
+	for _, header := range invalid {
+		if _, ok := ParseTraceParent(header); ok {
+			t.Errorf("Parsing %q failed to fail", header)
+		}
+	}
+}
+
+// Follow the transfer from the request through the service and the repository to the webhook of the settled event
+func TestTracingEndToEnd(t *testing.T) {
+	hooks := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hooks <- req.Header.Get(traceParentHeader)
+	}))
+	defer receiver.Close()
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	bus := NewInMemoryEventBus()
+	inMemImpl.EventBus = bus
+	dispatcher := NewWebhookDispatcher(bus)
+	defer dispatcher.Close()
+	dispatcher.Subscribe(receiver.URL, "secret", TransferSettledEvent)
+	exporter := NewInMemorySpanExporter()
+	tracer := NewTracer(exporter)
+	service := NewAccountService(NewTracingAccountRepository(inMemImpl, tracer)).WithTracer(tracer)
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	<-hooks
+
+	server := httptest.NewServer(TracingMiddleware(tracer, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := service.ForRequest(req).TransferMoney(acc.Iban, recipient.Iban, 25); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	})))
+	defer server.Close()
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/transfers", nil)
+	req.Header.Set(traceParentHeader, "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	resp.Body.Close()
+	if c, ok := ParseTraceParent(resp.Header.Get(traceParentHeader)); !ok || c.TraceID != traceID {
+		t.Errorf("Expected the trace to be returned in the response, got %q", resp.Header.Get(traceParentHeader))
+	}
+
+	spans := map[string]Span{}
+	for _, span := range exporter.Spans(traceID) {
+		spans[span.Name] = span
+	}
+	serverSpan, serviceSpan, repoSpan := spans["POST /transfers"], spans["AccountService.Transfer"], spans["AccountRepository.TransferMoney"]
+	if serverSpan.ParentID != "00f067aa0ba902b7" || serverSpan.Kind != ServerSpan || serverSpan.Attributes["http.status_code"] != "200" {
+		t.Errorf("Unexpected span of the request %+v", serverSpan)
+	}
+	if serviceSpan.ParentID != serverSpan.SpanID || serviceSpan.Attributes["amount"] != "25" {
+		t.Errorf("Expected the service span to be the child of the request span, got %+v", serviceSpan)
+	}
+	if repoSpan.ParentID != serviceSpan.SpanID || repoSpan.Attributes["recipient"] != recipient.Iban || repoSpan.Attributes["transfer.id"] == "" {
+		t.Errorf("Expected the repository span to be the child of the service span, got %+v", repoSpan)
+	}
+	select {
+	case header := <-hooks:
+		if c, ok := ParseTraceParent(header); !ok || c != repoSpan.Context() {
+			t.Errorf("Expected the webhook to continue the trace of the transfer, got %q", header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the settled transfer to be delivered")
+	}
+
+	// Failures are recorded with the error code, operations outside of requests start traces of their own
+	if _, err := service.TransferMoney(acc.Iban, recipient.Iban, -1); err == nil {
+		t.Fatalf("Transfer of negative amount failed to fail")
+	}
+	all := exporter.Spans("")
+	failed := all[len(all)-1]
+	if failed.TraceID == traceID || failed.ParentID != "" || failed.Error == "" || failed.Attributes["error.code"] != "5" {
+		t.Errorf("Unexpected span of the failed transfer %+v", failed)
+	}
+}
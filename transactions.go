@@ -0,0 +1,479 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --------------------------------------------------------
+// Defining transaction log entries recorded for every money movement
+type TransactionType int8
+
+const (
+	EmissionTransaction TransactionType = iota
+	DestructionTransaction
+	TransferTransaction
+	ConversionTransaction
+	InternalMoveTransaction
+	ImportTransaction
+	ReversalTransaction
+	FeeTransaction
+	InterestTransaction
+	LoanDisbursementTransaction
+	LoanRepaymentTransaction
+	InterbankTransaction
+	AdjustmentTransaction
+	CashWithdrawalTransaction
+	CashDepositTransaction
+	AcquiringFeeTransaction
+)
+
+// Mapping transaction type codes to transaction type names considering locale
+var transactionTypeCodeToNameMap map[TransactionType](map[LanguageCode]string) = map[TransactionType](map[LanguageCode]string){
+	EmissionTransaction: {
+		English: "Emission",
+		Russian: "Эмиссия",
+	},
+	DestructionTransaction: {
+		English: "Destruction",
+		Russian: "Уничтожение",
+	},
+	TransferTransaction: {
+		English: "Transfer",
+		Russian: "Перевод",
+	},
+	ConversionTransaction: {
+		English: "Conversion",
+		Russian: "Конвертация",
+	},
+	InternalMoveTransaction: {
+		English: "Internal move",
+		Russian: "Перевод между своими счетами",
+	},
+	ImportTransaction: {
+		English: "Import",
+		Russian: "Импорт",
+	},
+	ReversalTransaction: {
+		English: "Reversal",
+		Russian: "Сторнирование",
+	},
+	FeeTransaction: {
+		English: "Fee",
+		Russian: "Комиссия",
+	},
+	InterestTransaction: {
+		English: "Interest",
+		Russian: "Проценты",
+	},
+	LoanDisbursementTransaction: {
+		English: "Loan disbursement",
+		Russian: "Выдача кредита",
+	},
+	LoanRepaymentTransaction: {
+		English: "Loan repayment",
+		Russian: "Погашение кредита",
+	},
+	InterbankTransaction: {
+		English: "Interbank transfer",
+		Russian: "Межбанковский перевод",
+	},
+	AdjustmentTransaction: {
+		English: "Adjustment",
+		Russian: "Корректировка",
+	},
+	CashWithdrawalTransaction: {
+		English: "Cash withdrawal",
+		Russian: "Снятие наличных",
+	},
+	CashDepositTransaction: {
+		English: "Cash deposit",
+		Russian: "Взнос наличных",
+	},
+	AcquiringFeeTransaction: {
+		English: "Acquiring fee",
+		Russian: "Комиссия за эквайринг",
+	},
+}
+
+// Lifecycle of a transfer as seen by the callers of asynchronous layers
+type TransferStatus int8
+
+const (
+	TransferPending TransferStatus = iota
+	TransferSettled
+	TransferFailed
+	TransferReversed
+)
+
+// Mapping transfer status codes to transfer status names considering locale
+var transferStatusCodeToNameMap map[TransferStatus](map[LanguageCode]string) = map[TransferStatus](map[LanguageCode]string){
+	TransferPending: {
+		English: "Pending",
+		Russian: "В обработке",
+	},
+	TransferSettled: {
+		English: "Settled",
+		Russian: "Исполнен",
+	},
+	TransferFailed: {
+		English: "Failed",
+		Russian: "Отклонён",
+	},
+	TransferReversed: {
+		English: "Reversed",
+		Russian: "Сторнирован",
+	},
+}
+
+// Amount and Currency always describe the debited side of the transaction
+// CreditedAmount, CreditedCurrency and Rate are only filled in for conversions
+// Reference, Memo and PurposeCode are only filled in for transfers if supplied by the client
+// ReversalOf and ReversedBy link a reversed transfer and its compensating transaction to each other by their ULIDs
+// Fee is the whole fee charged for the transaction, FeeOf links the fee transaction to the charged one by its ULID
+// Amount of a transfer is net of the part of the fee borne by the recipient according to FeeBearer
+// Ulid is a globally unique identifier of the transaction that can be handed out to clients, unlike the sequential ID
+type Transaction struct {
+	ID               uint64
+	Ulid             string
+	Type             TransactionType
+	Sender           string
+	Recipient        string
+	Amount           float64
+	Currency         string
+	CreditedAmount   float64
+	CreditedCurrency string
+	Rate             float64
+	Reference        string
+	Memo             string
+	PurposeCode      string
+	ReversalOf       string
+	ReversedBy       string
+	Fee              float64
+	FeeOf            string
+	FeeBearer        FeeBearer
+	Timestamp        time.Time
+}
+
+// Optional details of a transfer supplied by the client
+type TransferDetails struct {
+	Reference   string // end-to-end reference of the payer, up to 35 characters
+	Memo        string // free-text remittance information, up to 140 characters
+	PurposeCode string // ISO 20022 external purpose code, i.e. SALA for salary payments
+	Initiator   string // principal who initiated the transfer, stamped by the service, transfers awaiting approval cannot be approved by them
+	FeeBearer   FeeBearer
+	TraceParent string // W3C trace context the transfer is made in, passed on to the events and the peer banks, see tracing.go
+}
+
+// Limits follow the SEPA credit transfer rules for end-to-end identification and unstructured remittance information
+const (
+	maxTransferReferenceLength = 35
+	maxTransferMemoLength      = 140
+)
+
+// Subset of ISO 20022 external purpose codes, can be augmented with other codes from the official list
+var purposeCodes map[string]bool = map[string]bool{
+	"BONU": true, // bonus payment
+	"CBFF": true, // capital building
+	"CHAR": true, // charity payment
+	"DIVD": true, // dividend
+	"GDDS": true, // purchase or sale of goods
+	"GOVT": true, // government payment
+	"INSU": true, // insurance premium
+	"INTC": true, // intra-company payment
+	"LOAN": true, // loan
+	"OTHR": true, // other
+	"PENS": true, // pension payment
+	"RENT": true, // rent
+	"SALA": true, // salary payment
+	"SCVE": true, // purchase or sale of services
+	"SUPP": true, // supplier payment
+	"TAXS": true, // tax payment
+	"TRAD": true, // trade services
+	"UBIL": true, // utility bill
+}
+
+// Helper function to trim transfer details and check them against the limits, purpose code is matched case-insensitively
+func normalizeTransferDetails(details TransferDetails) (TransferDetails, bool) {
+	details.Reference = strings.TrimSpace(details.Reference)
+	details.Memo = strings.TrimSpace(details.Memo)
+	details.PurposeCode = strings.ToUpper(strings.TrimSpace(details.PurposeCode))
+	details.Initiator = strings.TrimSpace(details.Initiator)
+	if _, ok := feeBearerCodeToNameMap[details.FeeBearer]; !ok {
+		return details, false
+	}
+	if utf8.RuneCountInString(details.Reference) > maxTransferReferenceLength || utf8.RuneCountInString(details.Memo) > maxTransferMemoLength {
+		return details, false
+	}
+	// Reference is meant to be passed along to other systems, so only printable ASCII characters are allowed
+	for _, c := range details.Reference {
+		if c < ' ' || c > '~' {
+			return details, false
+		}
+	}
+	for _, c := range details.Memo {
+		if c < ' ' {
+			return details, false
+		}
+	}
+	if details.PurposeCode != "" && !purposeCodes[details.PurposeCode] {
+		return details, false
+	}
+	return details, true
+}
+
+// Helper function to append a transaction to the log, expects the repository mutex to be held by the caller
+// Transactions moving money are recorded through post along with their journal entries
+func (r *InMemoryAccountRepository) recordTransaction(tx *Transaction) {
+	tx.ID = uint64(len(r.Transactions) + 1)
+	tx.Timestamp = clock.Now().UTC()
+	tx.Ulid = NewUlid(tx.Timestamp)
+	r.Transactions = append(r.Transactions, tx)
+}
+
+// Crockford's base32 alphabet used by ULIDs, it excludes I, L, O and U to avoid confusion
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Generates a ULID: 48 bits of millisecond timestamp followed by 80 random bits encoded as 26 characters,
+// so that IDs are unique without coordination and sort lexicographically by creation time
+func NewUlid(t time.Time) string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(t.UnixMilli())<<16)
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(err)
+	}
+	// Encoding 128 bits as 26 characters of 5 bits each, the first character only holds 3 bits
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	encoded := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		encoded[i] = ulidAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(encoded)
+}
+
+// Helper function to remember the outcome of a transfer that has just been attempted, expects the repository mutex to be held by the caller
+// Settled transfers get the ID of their transaction, failed ones get a fresh ID
+func (r *InMemoryAccountRepository) trackTransfer(err error) string {
+	if err != nil {
+		id := NewUlid(clock.Now().UTC())
+		r.TransferStatuses[id] = TransferFailed
+		return id
+	}
+	id := r.Transactions[len(r.Transactions)-1].Ulid
+	r.TransferStatuses[id] = TransferSettled
+	return id
+}
+
+// Reverses a settled transfer by sending the money back from the recipient to the sender, the reason is stored as the memo
+// Only transfers between ordinary accounts can be reversed and only once, the recipient must still have the money available
+// Fees charged for the transfer are not refunded
+// Product rules and KYC limits are not applied since the money returns to where it came from
+func (r *InMemoryAccountRepository) ReverseTransfer(id, reason string) (string, error) {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	id = strings.ToUpper(strings.TrimSpace(id))
+	reason = strings.TrimSpace(reason)
+	// Checking if the reason is given and fits into the memo
+	if details, ok := normalizeTransferDetails(TransferDetails{Memo: reason}); !ok || details.Memo == "" {
+		return "", fmt.Errorf(errorCodesToMessagesMap[InvalidTransferDetailsError][locale()])
+	}
+
+	// Looking the transaction up by scanning the log, reversals are rare enough for that
+	var original *Transaction
+	for _, tx := range r.Transactions {
+		if tx.Ulid == id {
+			original = tx
+			break
+		}
+	}
+	if original == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[TransferDoesNotExistError][locale()])
+	}
+	// Checking if the transaction is a transfer that has not been reversed yet
+	if original.Type != TransferTransaction && original.Type != InternalMoveTransaction {
+		return "", fmt.Errorf(errorCodesToMessagesMap[TransferNotReversibleError][locale()])
+	}
+	if original.ReversedBy != "" {
+		return "", fmt.Errorf(errorCodesToMessagesMap[TransferNotReversibleError][locale()])
+	}
+	sAcc, sExists := r.Accounts.Get(original.Recipient)
+	rAcc, rExists := r.Accounts.Get(original.Sender)
+	if !sExists || sAcc == nil || !rExists || rAcc == nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	// Checking if both accounts are ordinary ones, money movements of special accounts are reverted by emission and destruction
+	if sAcc.Type != Ordinary || rAcc.Type != Ordinary {
+		return "", fmt.Errorf(errorCodesToMessagesMap[TransferNotReversibleError][locale()])
+	}
+	// Checking if neither of the accounts is blocked or closed
+	if sAcc.Status == Blocked || rAcc.Status == Blocked {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountIsBlockedError][locale()])
+	}
+	if sAcc.Status == Closed || rAcc.Status == Closed {
+		return "", fmt.Errorf(errorCodesToMessagesMap[AccountIsClosedError][locale()])
+	}
+	// Checking if the money may leave the recipient of the original transfer and return to its sender
+	if err := checkDebitRestriction(sAcc); err != nil {
+		return "", err
+	}
+	if err := checkCreditRestriction(rAcc); err != nil {
+		return "", err
+	}
+	// Checking if the recipient of the original transfer still has the money
+	if r, _ := roundAndExtractFractionsInCurrency(original.Amount, sAcc.Currency); sAcc.AvailableBalance() < r {
+		return "", insufficientFundsError(sAcc)
+	}
+	// Checking if both balances stay within the floor and the ceiling of the accounts
+	if err := checkBalanceFloor(sAcc, roundToCurrency(original.Amount, sAcc.Currency)); err != nil {
+		return "", err
+	}
+	if err := checkBalanceCeiling(rAcc, roundToCurrency(original.Amount, rAcc.Currency)); err != nil {
+		return "", err
+	}
+
+	reversal := &Transaction{Type: ReversalTransaction, Sender: sAcc.Iban, Recipient: rAcc.Iban, Amount: original.Amount, Currency: original.Currency, Reference: original.Reference, Memo: reason, ReversalOf: original.Ulid}
+	if err := r.post(reversal, transferLines(sAcc, rAcc, original.Amount)...); err != nil {
+		return "", err
+	}
+	original.ReversedBy = reversal.Ulid
+	r.TransferStatuses[original.Ulid] = TransferReversed
+	r.TransferStatuses[reversal.Ulid] = TransferSettled
+	return reversal.Ulid, nil
+}
+
+func (r *InMemoryAccountRepository) GetTransferStatus(id string) (TransferStatus, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+	status, exists := r.TransferStatuses[strings.ToUpper(strings.TrimSpace(id))]
+	if !exists {
+		return 0, fmt.Errorf(errorCodesToMessagesMap[TransferDoesNotExistError][locale()])
+	}
+	return status, nil
+}
+
+// Returns IBANs of the sender and the recipient of the transfer, whether it is executed or awaits approval or confirmation
+func (r *InMemoryAccountRepository) RetrieveTransferParties(id string) (string, string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if approval, exists := r.Approvals[id]; exists && approval != nil {
+		return approval.Sender, approval.Recipient, nil
+	}
+	if challenge, exists := r.Challenges[id]; exists && challenge != nil {
+		return challenge.Sender, challenge.Recipient, nil
+	}
+	for _, tx := range r.Transactions {
+		if tx.Ulid == id {
+			return tx.Sender, tx.Recipient, nil
+		}
+	}
+	return "", "", fmt.Errorf(errorCodesToMessagesMap[TransferDoesNotExistError][locale()])
+}
+
+func (r *InMemoryAccountRepository) RetrieveTransaction(id string) (Transaction, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	id = strings.ToUpper(strings.TrimSpace(id))
+	for _, tx := range r.Transactions {
+		if tx.Ulid == id {
+			return *tx, nil
+		}
+	}
+	return Transaction{}, fmt.Errorf(errorCodesToMessagesMap[TransferDoesNotExistError][locale()])
+}
+
+// Returns transactions recorded at or after from and before to, in the order of recording, inverted periods contain nothing
+func (r *InMemoryAccountRepository) RetrieveTransactionsBetween(from, to time.Time) ([]Transaction, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	transactions := []Transaction{}
+	for _, tx := range r.Transactions {
+		if !tx.Timestamp.Before(from) && tx.Timestamp.Before(to) {
+			transactions = append(transactions, *tx)
+		}
+	}
+	return transactions, nil
+}
+
+// Non-positive limit means no limit
+func (r *InMemoryAccountRepository) RetrieveAccountTransactions(iban string, limit int) ([]Transaction, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+
+	iban = NormalizeIban(iban)
+	// Checking if account associated with the given IBAN exists
+	if acc, exists := r.Accounts.Get(iban); !exists || acc == nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[AccountDoesNotExistError][locale()])
+	}
+	transactions := []Transaction{}
+	for i := len(r.Transactions) - 1; i >= 0 && (limit <= 0 || len(transactions) < limit); i-- {
+		if tx := r.Transactions[i]; tx.Sender == iban || tx.Recipient == iban {
+			transactions = append(transactions, *tx)
+		}
+	}
+	return transactions, nil
+}
+
+func (r *InMemoryAccountRepository) RetrieveAllTransactionsAsJson() (string, error) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.JournalMutex.RLock()
+	defer r.JournalMutex.RUnlock()
+	type transactionDetails struct {
+		ID               uint64    `json:"id"`
+		Ulid             string    `json:"ulid"`
+		Type             string    `json:"type"`
+		Sender           string    `json:"sender,omitempty"`
+		Recipient        string    `json:"recipient,omitempty"`
+		Amount           float64   `json:"amount"`
+		Currency         string    `json:"currency"`
+		CreditedAmount   float64   `json:"credited_amount,omitempty"`
+		CreditedCurrency string    `json:"credited_currency,omitempty"`
+		Rate             float64   `json:"rate,omitempty"`
+		Reference        string    `json:"reference,omitempty"`
+		Memo             string    `json:"memo,omitempty"`
+		PurposeCode      string    `json:"purpose_code,omitempty"`
+		ReversalOf       string    `json:"reversal_of,omitempty"`
+		ReversedBy       string    `json:"reversed_by,omitempty"`
+		Fee              float64   `json:"fee,omitempty"`
+		FeeOf            string    `json:"fee_of,omitempty"`
+		FeeBearer        string    `json:"fee_bearer,omitempty"`
+		Timestamp        time.Time `json:"timestamp"`
+	}
+	allTransactionDetails := []transactionDetails{}
+	for _, tx := range r.Transactions {
+		// Fee bearer is only meaningful for transactions charged with a fee
+		feeBearer := ""
+		if tx.Fee != 0 {
+			feeBearer = feeBearerCodeToNameMap[tx.FeeBearer]
+		}
+		allTransactionDetails = append(allTransactionDetails, transactionDetails{tx.ID, tx.Ulid, transactionTypeCodeToNameMap[tx.Type][locale()], tx.Sender, tx.Recipient, roundToCurrency(tx.Amount, tx.Currency), tx.Currency, roundToCurrency(tx.CreditedAmount, tx.CreditedCurrency), tx.CreditedCurrency, tx.Rate, tx.Reference, tx.Memo, tx.PurposeCode, tx.ReversalOf, tx.ReversedBy, roundToCurrency(tx.Fee, tx.Currency), tx.FeeOf, feeBearer, tx.Timestamp})
+	}
+	output, err := json.Marshal(allTransactionDetails)
+	if err != nil {
+		return "", fmt.Errorf(errorCodesToMessagesMap[TransactionsJsonError][locale()])
+	}
+	return string(output), nil
+}
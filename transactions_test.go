@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Transfer money with reference, memo and purpose code and find them in the transaction log
+func TestTransferDetails(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccount()
+	if err := service.EmitMoney(100); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err := service.TransferMoneyWithDetails(emission, acc.Iban, 10, TransferDetails{Reference: " INV-2024/001 ", Memo: "Salary for March", PurposeCode: "sala"}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	req := `{"sender": "` + emission + `", "recipient": "` + acc.Iban + `", "amount": 5, "reference": "REF-2", "purpose_code": "RENT"}`
+	if _, err := service.TransferMoneyJson(req); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	invalid := []TransferDetails{
+		{Reference: strings.Repeat("R", 36)},
+		{Reference: "Счёт 1"},
+		{Memo: strings.Repeat("m", 141)},
+		{Memo: "line\nbreak"},
+		{PurposeCode: "XXXX"},
+	}
+	for _, details := range invalid {
+		if _, err := service.TransferMoneyWithDetails(emission, acc.Iban, 1, details); err == nil {
+			t.Errorf("Transfer with invalid details %+v failed to fail", details)
+		}
+	}
+
+	jsonStr, err := service.RetrieveAllTransactionsAsJson()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	var txs []map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &txs); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(txs))
+	}
+	if txs[1]["reference"] != "INV-2024/001" || txs[1]["memo"] != "Salary for March" || txs[1]["purpose_code"] != "SALA" {
+		t.Errorf("Unexpected transfer details in %v", txs[1])
+	}
+	if txs[2]["reference"] != "REF-2" || txs[2]["purpose_code"] != "RENT" {
+		t.Errorf("Unexpected transfer details in %v", txs[2])
+	}
+	if _, ok := txs[0]["reference"]; ok {
+		t.Errorf("Expected emission to have no reference, got %v", txs[0])
+	}
+}
+
+// Look up statuses of settled and failed transfers by their IDs
+func TestTransferStatus(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	acc, _ := service.OpenAccount()
+	if err := service.EmitMoney(10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	settled, err := service.TransferMoneyWithDetails(emission, acc.Iban, 10, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	failed, err := service.TransferMoneyWithDetails(emission, acc.Iban, 10, TransferDetails{})
+	if err == nil {
+		t.Fatalf("Transfer exceeding the balance failed to fail")
+	}
+	if len(settled) != 26 || len(failed) != 26 || settled == failed {
+		t.Errorf("Expected two distinct ULIDs, got %q and %q", settled, failed)
+	}
+	if status, err := service.GetTransferStatus(settled); err != nil || status != TransferSettled {
+		t.Errorf("Expected transfer %s to be settled, got %s (%v)", settled, transferStatusCodeToNameMap[status][locale()], err)
+	}
+	if status, err := service.GetTransferStatus(strings.ToLower(failed)); err != nil || status != TransferFailed {
+		t.Errorf("Expected transfer %s to be failed, got %s (%v)", failed, transferStatusCodeToNameMap[status][locale()], err)
+	}
+	// Plain and JSON transfers return their IDs as well
+	service.EmitMoney(10)
+	plain, err := service.TransferMoney(emission, acc.Iban, 5)
+	if status, _ := service.GetTransferStatus(plain); err != nil || status != TransferSettled {
+		t.Errorf("Expected transfer %q to be settled, got %s (%v)", plain, transferStatusCodeToNameMap[status][locale()], err)
+	}
+	viaJson, err := service.TransferMoneyJson(`{"sender":"` + emission + `","recipient":"` + acc.Iban + `","amount":50}`)
+	if status, _ := service.GetTransferStatus(viaJson); err == nil || status != TransferFailed {
+		t.Errorf("Expected transfer %q to be failed, got %s (%v)", viaJson, transferStatusCodeToNameMap[status][locale()], err)
+	}
+	if _, err := service.GetTransferStatus("01ARZ3NDEKTSV4RRFFQ69G5FAV"); err == nil {
+		t.Errorf("Looking up an unknown transfer failed to fail")
+	}
+	// The transfer ID is the ULID of its transaction
+	jsonStr, _ := service.RetrieveAllTransactionsAsJson()
+	if !strings.Contains(jsonStr, `"ulid":"`+settled+`"`) {
+		t.Errorf("Expected transaction log to contain transfer %s: %s", settled, jsonStr)
+	}
+
+	// ULIDs encode the timestamp in the first 10 characters and sort by creation time
+	if id := NewUlid(time.UnixMilli(1469918176385)); id[:10] != "01ARYZ6S41" {
+		t.Errorf("Unexpected timestamp part of ULID %s", id)
+	}
+	if earlier, later := NewUlid(time.Unix(1, 0)), NewUlid(time.Unix(2, 0)); earlier >= later {
+		t.Errorf("Expected %s to sort before %s", earlier, later)
+	}
+}
+
+// Reverse a transfer and find both transactions linked in the transaction log
+func TestReverseTransfer(t *testing.T) {
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	service := NewAccountService(NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001"))
+	sender, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+
+	id, err := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 40, TransferDetails{Reference: "INV-1"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.ReverseTransfer(id, " "); err == nil {
+		t.Errorf("Reversal without a reason failed to fail")
+	}
+	reversal, err := service.ReverseTransfer(id, "Sent by mistake")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.ReverseTransfer(id, "Sent by mistake"); err == nil {
+		t.Errorf("Reversing a transfer twice failed to fail")
+	}
+	if _, err := service.ReverseTransfer(reversal, "Reversal of reversal"); err == nil {
+		t.Errorf("Reversing a reversal failed to fail")
+	}
+	if status, _ := service.GetTransferStatus(id); status != TransferReversed {
+		t.Errorf("Expected transfer to be reversed, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+	if details, _ := service.RetrieveAccount(sender.Iban); details.Balance != 100 {
+		t.Errorf("Expected sender balance 100 after reversal, got %.2f", details.Balance)
+	}
+
+	jsonStr, _ := service.RetrieveAllTransactionsAsJson()
+	var txs []map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &txs); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	last, original := txs[len(txs)-1], txs[len(txs)-2]
+	if original["reversed_by"] != reversal || last["reversal_of"] != id || last["memo"] != "Sent by mistake" || last["reference"] != "INV-1" {
+		t.Errorf("Expected transactions to be linked, got %v and %v", original, last)
+	}
+
+	// The recipient must still have the money
+	second, _ := service.TransferMoneyWithDetails(sender.Iban, recipient.Iban, 40, TransferDetails{})
+	if _, err := service.TransferMoney(recipient.Iban, emission, 40); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := service.ReverseTransfer(second, "Sent by mistake"); err == nil {
+		t.Errorf("Reversal exceeding recipient balance failed to fail")
+	}
+	// Emissions are not transfers between ordinary accounts
+	if _, err := service.ReverseTransfer(txs[0]["ulid"].(string), "Sent by mistake"); err == nil {
+		t.Errorf("Reversing an emission failed to fail")
+	}
+	if _, err := service.ReverseTransfer("unknown", "Sent by mistake"); err == nil {
+		t.Errorf("Reversing an unknown transfer failed to fail")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining velocity rules engine evaluating patterns of debits from an account before a transfer is executed
+type VelocityAction int8
+
+const (
+	VelocityAllow VelocityAction = iota
+	VelocityFlag                 // transfer is executed and recorded for review
+	VelocityDelay                // transfer waits for approval by a second principal, see approvals.go
+	VelocityBlock                // transfer is rejected
+)
+
+// Mapping velocity action codes to velocity action names considering locale
+var velocityActionCodeToNameMap map[VelocityAction](map[LanguageCode]string) = map[VelocityAction](map[LanguageCode]string){
+	VelocityAllow: {
+		English: "Allow",
+		Russian: "Разрешить",
+	},
+	VelocityFlag: {
+		English: "Flag",
+		Russian: "Отметить",
+	},
+	VelocityDelay: {
+		English: "Delay",
+		Russian: "Отложить",
+	},
+	VelocityBlock: {
+		English: "Block",
+		Russian: "Заблокировать",
+	},
+}
+
+// Names of actions and rule types used in the configuration file
+var velocityActionsByConfigName map[string]VelocityAction = map[string]VelocityAction{
+	"flag":  VelocityFlag,
+	"delay": VelocityDelay,
+	"block": VelocityBlock,
+}
+
+const (
+	// More than MaxDebits debits from the account within WindowMinutes, the evaluated transfer included
+	FrequencyVelocityRule = "frequency"
+	// Debit of at least MinAmount after no activity on the account for DormantDays
+	DormancyVelocityRule = "dormancy"
+)
+
+// Rule as defined in the configuration file, i.e.
+// [{"name": "burst", "type": "frequency", "max_debits": 5, "window_minutes": 10, "action": "delay"},
+// {"name": "sleeper", "type": "dormancy", "dormant_days": 180, "min_amount": 1000, "action": "flag"}]
+type VelocityRule struct {
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	MaxDebits     int     `json:"max_debits,omitempty"`
+	WindowMinutes int     `json:"window_minutes,omitempty"`
+	DormantDays   int     `json:"dormant_days,omitempty"`
+	MinAmount     float64 `json:"min_amount,omitempty"`
+	Action        string  `json:"action"`
+}
+
+func (rule VelocityRule) isValid() bool {
+	if strings.TrimSpace(rule.Name) == "" {
+		return false
+	}
+	if _, ok := velocityActionsByConfigName[strings.ToLower(rule.Action)]; !ok {
+		return false
+	}
+	switch strings.ToLower(rule.Type) {
+	case FrequencyVelocityRule:
+		return rule.MaxDebits > 0 && rule.WindowMinutes > 0
+	case DormancyVelocityRule:
+		return rule.DormantDays > 0 && rule.MinAmount >= 0
+	}
+	return false
+}
+
+// Transfer executed despite matching a rule with the flag action
+type VelocityFlagRecord struct {
+	TransferID string
+	Iban       string
+	Amount     float64
+	Rule       string
+	Timestamp  time.Time
+}
+
+type velocityHistory struct {
+	debits       []time.Time // times of recent debits, older than the longest window are dropped
+	lastActivity time.Time   // time of the last debit or credit
+}
+
+// Engine keeps its own account history, so that rules do not need to scan the transaction log
+type VelocityEngine struct {
+	Rules   []VelocityRule
+	Flags   []VelocityFlagRecord
+	history map[string]*velocityHistory
+	mutex   sync.Mutex
+}
+
+func NewVelocityEngine(rules []VelocityRule) (*VelocityEngine, error) {
+	for _, rule := range rules {
+		if !rule.isValid() {
+			return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidVelocityRuleError][locale()])
+		}
+	}
+	return &VelocityEngine{Rules: rules, Flags: []VelocityFlagRecord{}, history: map[string]*velocityHistory{}}, nil
+}
+
+// Loads rules from a JSON file in the format described at VelocityRule
+func LoadVelocityEngine(path string) (*VelocityEngine, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidVelocityRuleError][locale()])
+	}
+	rules := []VelocityRule{}
+	if err := json.Unmarshal(content, &rules); err != nil {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidVelocityRuleError][locale()])
+	}
+	return NewVelocityEngine(rules)
+}
+
+// Evaluates all rules against the debit and returns the strictest action along with the name of the rule demanding it
+func (e *VelocityEngine) Evaluate(iban string, amount float64, now time.Time) (VelocityAction, string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	h := e.history[iban]
+	action, matched := VelocityAllow, ""
+	for _, rule := range e.Rules {
+		hit := false
+		switch strings.ToLower(rule.Type) {
+		case FrequencyVelocityRule:
+			count := 1
+			if h != nil {
+				since := now.Add(-time.Duration(rule.WindowMinutes) * time.Minute)
+				for _, t := range h.debits {
+					if t.After(since) {
+						count++
+					}
+				}
+			}
+			hit = count > rule.MaxDebits
+		case DormancyVelocityRule:
+			// Accounts never seen by the engine are new rather than dormant
+			hit = h != nil && !h.lastActivity.IsZero() && amount >= rule.MinAmount && now.Sub(h.lastActivity) >= time.Duration(rule.DormantDays)*24*time.Hour
+		}
+		if ruleAction := velocityActionsByConfigName[strings.ToLower(rule.Action)]; hit && ruleAction > action {
+			action, matched = ruleAction, rule.Name
+		}
+	}
+	return action, matched
+}
+
+// Records an executed transfer in the history of both accounts
+func (e *VelocityEngine) Observe(sender, recipient string, now time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	longest := time.Duration(0)
+	for _, rule := range e.Rules {
+		if window := time.Duration(rule.WindowMinutes) * time.Minute; window > longest {
+			longest = window
+		}
+	}
+	for _, iban := range []string{sender, recipient} {
+		if e.history[iban] == nil {
+			e.history[iban] = &velocityHistory{}
+		}
+		e.history[iban].lastActivity = now
+	}
+	h := e.history[sender]
+	kept := h.debits[:0]
+	for _, t := range h.debits {
+		if now.Sub(t) < longest {
+			kept = append(kept, t)
+		}
+	}
+	h.debits = append(kept, now)
+}
+
+func (e *VelocityEngine) flag(record VelocityFlagRecord) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.Flags = append(e.Flags, record)
+}
+
+// Lists transfers executed despite matching a rule with the flag action
+func (e *VelocityEngine) ListFlags() []VelocityFlagRecord {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]VelocityFlagRecord{}, e.Flags...)
+}
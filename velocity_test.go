@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Load velocity rules from configuration and let them block, delay and flag transfers
+func TestVelocityRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	config := `[
+		{"name": "burst", "type": "frequency", "max_debits": 2, "window_minutes": 10, "action": "block"},
+		{"name": "busy", "type": "frequency", "max_debits": 1, "window_minutes": 10, "action": "flag"},
+		{"name": "sleeper", "type": "dormancy", "dormant_days": 30, "min_amount": 15, "action": "delay"}
+	]`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	engine, err := LoadVelocityEngine(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := NewVelocityEngine([]VelocityRule{{Name: "broken", Type: "frequency", Action: "block"}}); err == nil {
+		t.Errorf("Loading invalid rule failed to fail")
+	}
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	service := NewAccountService(inMemImpl)
+	acc, _ := service.OpenAccountWithInitialDeposit(500)
+	other, _ := service.OpenAccount()
+	inMemImpl.VelocityEngine = engine
+
+	first, err := service.TransferMoneyWithDetails(acc.Iban, other.Iban, 10, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	second, err := service.TransferMoneyWithDetails(acc.Iban, other.Iban, 10, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if flags := engine.ListFlags(); len(flags) != 1 || flags[0].TransferID != second || flags[0].Rule != "busy" {
+		t.Errorf("Expected the second transfer to be flagged, got %+v (first is %s)", flags, first)
+	}
+	if _, err := service.TransferMoneyWithDetails(acc.Iban, other.Iban, 10, TransferDetails{}); err == nil {
+		t.Errorf("Third transfer within the window failed to fail")
+	}
+
+	// A large transfer after a long period of inactivity waits for approval
+	engine.history[other.Iban].lastActivity = time.Now().AddDate(0, 0, -31)
+	delayed, err := service.TransferMoneyWithDetails(other.Iban, acc.Iban, 20, TransferDetails{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if status, _ := service.GetTransferStatus(delayed); status != TransferPending {
+		t.Errorf("Expected transfer after dormancy to be pending, got %s", transferStatusCodeToNameMap[status][locale()])
+	}
+	// Small amounts are not affected by the dormancy rule
+	if _, err := service.TransferMoneyWithDetails(other.Iban, acc.Iban, 5, TransferDetails{}); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
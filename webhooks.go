@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --------------------------------------------------------
+// Defining webhooks: events of the event bus are posted to the URLs of the subscriptions, every delivery is signed with
+// the secret of its subscription, so that receivers can tell it from forged ones, the same scheme signs incoming requests
+//
+// Signature is "v1=" followed by hex encoded HMAC-SHA256 of "<timestamp>.<body>" where timestamp is in Unix seconds
+const (
+	signatureHeader          = "X-Signature"
+	signatureTimestampHeader = "X-Signature-Timestamp"
+	signatureKeyHeader       = "X-Signature-Key" // identifies the secret of the signature, i.e. the bank code of the peer
+)
+
+// Signed requests older or newer than that are rejected
+const signatureTolerance = 5 * time.Minute
+
+func SignPayload(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checks the signature and the timestamp of the payload, the timestamp is given in Unix seconds as sent in the header
+func VerifySignature(secret, timestamp string, body []byte, signature string, now time.Time) error {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil || secret == "" {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidSignatureError][locale()])
+	}
+	signedAt := time.Unix(seconds, 0)
+	if signedAt.Before(now.Add(-signatureTolerance)) || signedAt.After(now.Add(signatureTolerance)) {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidSignatureError][locale()])
+	}
+	if !hmac.Equal([]byte(SignPayload(secret, signedAt, body)), []byte(strings.TrimSpace(signature))) {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidSignatureError][locale()])
+	}
+	return nil
+}
+
+// Helper function to sign the request with the secret, the body has to be the body of the request
+func signRequest(req *http.Request, keyID, secret string, body []byte, now time.Time) {
+	req.Header.Set(signatureTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(signatureHeader, SignPayload(secret, now, body))
+	if keyID != "" {
+		req.Header.Set(signatureKeyHeader, keyID)
+	}
+}
+
+// Wraps the handler to reject requests which are not signed by the secret of their key, requests are accepted once,
+// replayed requests are rejected even within the tolerance
+func SignatureMiddleware(secretOf func(keyID string) (string, bool), next http.Handler) http.Handler {
+	seen := map[string]time.Time{}
+	mutex := sync.Mutex{}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, errorCodesToMessagesMap[InvalidSignatureError][locale()], http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		now := clock.Now()
+		secret, known := secretOf(req.Header.Get(signatureKeyHeader))
+		signature := req.Header.Get(signatureHeader)
+		if !known {
+			http.Error(w, errorCodesToMessagesMap[InvalidSignatureError][locale()], http.StatusUnauthorized)
+			return
+		}
+		if err := VerifySignature(secret, req.Header.Get(signatureTimestampHeader), body, signature, now); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		// Checking if the signature has not been seen within the tolerance, older ones are rejected by their timestamps anyway
+		mutex.Lock()
+		for s, at := range seen {
+			if now.Sub(at) > 2*signatureTolerance {
+				delete(seen, s)
+			}
+		}
+		_, replayed := seen[signature]
+		seen[signature] = now
+		mutex.Unlock()
+		if replayed {
+			http.Error(w, errorCodesToMessagesMap[InvalidSignatureError][locale()], http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+type WebhookSubscription struct {
+	ID         string
+	Url        string
+	Secret     string   // HMAC secret the deliveries are signed with
+	EventTypes []string // all events are delivered if empty
+	CreatedAt  time.Time
+}
+
+// Body of the delivery, the ID of the delivery is passed in the X-Webhook-ID header as well
+type webhookDelivery struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Payload   interface{} `json:"payload"`
+}
+
+// Posts events of the bus to the subscriptions, deliveries are made once, failed ones are only counted
+type WebhookDispatcher struct {
+	Client        *http.Client
+	Delivered     uint64
+	Failed        uint64 // deliveries which could not be made or were not acknowledged with 2xx status
+	subscriptions map[string]*WebhookSubscription
+	unsubscribe   func()
+	done          chan struct{}
+	Mutex         sync.Mutex
+}
+
+func NewWebhookDispatcher(bus EventBus) *WebhookDispatcher {
+	events, unsubscribe := bus.Subscribe()
+	d := &WebhookDispatcher{Client: &http.Client{Timeout: 5 * time.Second}, subscriptions: map[string]*WebhookSubscription{}, unsubscribe: unsubscribe, done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+		for event := range events {
+			d.dispatch(event)
+		}
+	}()
+	return d
+}
+
+// Returns the subscription along with its secret, the secret is generated if empty
+func (d *WebhookDispatcher) Subscribe(url, secret string, eventTypes ...string) (*WebhookSubscription, error) {
+	url = strings.TrimSpace(url)
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf(errorCodesToMessagesMap[InvalidWebhookError][locale()])
+	}
+	if secret == "" {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(err)
+		}
+		secret = hex.EncodeToString(b[:])
+	}
+	now := clock.Now().UTC()
+	sub := &WebhookSubscription{NewUlid(now), url, secret, append([]string{}, eventTypes...), now}
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	d.subscriptions[sub.ID] = sub
+	copied := *sub
+	return &copied, nil
+}
+
+func (d *WebhookDispatcher) Unsubscribe(id string) error {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if _, exists := d.subscriptions[id]; !exists {
+		return fmt.Errorf(errorCodesToMessagesMap[InvalidWebhookError][locale()])
+	}
+	delete(d.subscriptions, id)
+	return nil
+}
+
+// Stops delivering events, waits for the delivery in progress to finish
+func (d *WebhookDispatcher) Close() {
+	d.unsubscribe()
+	<-d.done
+}
+
+// Helper function to deliver the event to the subscriptions of its type, oldest subscriptions first
+func (d *WebhookDispatcher) dispatch(event Event) {
+	d.Mutex.Lock()
+	subs := []*WebhookSubscription{}
+	for _, sub := range d.subscriptions {
+		matches := len(sub.EventTypes) == 0
+		for _, t := range sub.EventTypes {
+			matches = matches || t == event.Type
+		}
+		if matches {
+			subs = append(subs, sub)
+		}
+	}
+	d.Mutex.Unlock()
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+
+	delivery := webhookDelivery{NewUlid(event.Timestamp), event.Type, event.Timestamp, event.Payload}
+	traceParent := ""
+	if payload, ok := event.Payload.(TransferEventPayload); ok {
+		traceParent = payload.TraceParent
+	}
+	body, err := json.Marshal(delivery)
+	for _, sub := range subs {
+		if err != nil || !d.post(sub, delivery.ID, traceParent, body) {
+			d.Mutex.Lock()
+			d.Failed++
+			d.Mutex.Unlock()
+			continue
+		}
+		d.Mutex.Lock()
+		d.Delivered++
+		d.Mutex.Unlock()
+	}
+}
+
+// Helper function to post the signed delivery, returns true once the receiver acknowledges it
+func (d *WebhookDispatcher) post(sub *WebhookSubscription, id, traceParent string, body []byte) bool {
+	req, err := http.NewRequest(http.MethodPost, sub.Url, bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", id)
+	if traceParent != "" {
+		req.Header.Set(traceParentHeader, traceParent)
+	}
+	signRequest(req, "", sub.Secret, body, clock.Now())
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Deliveries are signed with the secret of the subscription and can be verified by the receiver
+func TestWebhookDeliveries(t *testing.T) {
+	received := make(chan error, 10)
+	var payload webhookDelivery
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		err := VerifySignature("secret", req.Header.Get(signatureTimestampHeader), body, req.Header.Get(signatureHeader), time.Now())
+		if err == nil {
+			err = json.Unmarshal(body, &payload)
+		}
+		received <- err
+	}))
+	defer receiver.Close()
+
+	emission := "BY84 ALFA 1000 0000 0000 0000 0000"
+	inMemImpl := NewInMemoryAccountRepository(emission, "BY84 ALFA 1000 0000 0000 0000 0001")
+	bus := NewInMemoryEventBus()
+	inMemImpl.EventBus = bus
+	service := NewAccountService(inMemImpl)
+	dispatcher := NewWebhookDispatcher(bus)
+	if _, err := dispatcher.Subscribe("ftp://example.com", ""); err == nil {
+		t.Errorf("Subscription with an unsupported URL failed to fail")
+	}
+	if _, err := dispatcher.Subscribe(receiver.URL, "secret", TransferSettledEvent); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	acc, _ := service.OpenAccountWithInitialDeposit(100)
+	recipient, _ := service.OpenAccount()
+	if _, err := service.TransferMoney(acc.Iban, recipient.Iban, 10); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	select {
+	case err := <-received:
+		if err != nil {
+			t.Errorf("Error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the settled transfer to be delivered")
+	}
+	dispatcher.Close()
+	if payload.Type != TransferSettledEvent || payload.ID == "" {
+		t.Errorf("Unexpected delivery: %+v", payload)
+	}
+	if dispatcher.Failed != 0 {
+		t.Errorf("Expected no failed deliveries, got %d", dispatcher.Failed)
+	}
+}
+
+// Signed requests are accepted once within the tolerance only
+func TestSignatureMiddleware(t *testing.T) {
+	handler := SignatureMiddleware(func(keyID string) (string, bool) { return "secret", keyID == "client" }, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if body, _ := io.ReadAll(req.Body); string(body) != `{"amount":10}` {
+			http.Error(w, "body is not restored", http.StatusInternalServerError)
+		}
+	}))
+	body := []byte(`{"amount":10}`)
+	serve := func(keyID string, signedAt time.Time, secret string, body []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/payments", bytes.NewReader(body))
+		signRequest(req, keyID, secret, body, signedAt)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	now := time.Now()
+	if status := serve("client", now, "secret", body); status != http.StatusOK {
+		t.Errorf("Expected signed request to be accepted, got %d", status)
+	}
+	if status := serve("client", now, "secret", body); status != http.StatusUnauthorized {
+		t.Errorf("Expected replayed request to be rejected, got %d", status)
+	}
+	if status := serve("client", now.Add(-time.Hour), "secret", body); status != http.StatusUnauthorized {
+		t.Errorf("Expected outdated request to be rejected, got %d", status)
+	}
+	if status := serve("client", now.Add(time.Second), "other", body); status != http.StatusUnauthorized {
+		t.Errorf("Expected request signed by another secret to be rejected, got %d", status)
+	}
+	if status := serve("unknown", now.Add(time.Second), "secret", body); status != http.StatusUnauthorized {
+		t.Errorf("Expected request of an unknown key to be rejected, got %d", status)
+	}
+	if err := VerifySignature("secret", strconv.FormatInt(now.Unix(), 10), []byte(`{"amount":99}`), SignPayload("secret", now, body), now); err == nil {
+		t.Errorf("Signature of another body failed to fail")
+	}
+}
+
+// Gateways sign transfers with the secrets shared with the peers
+func TestSignedInterbankTransfers(t *testing.T) {
+	alfa, alfaGateway, alfaServer, sender := startInterbankInstance(t, "ALFA", 100)
+	defer alfaServer.Close()
+	beta, betaGateway, _, _ := startInterbankInstance(t, "BETA", 0)
+	betaGateway.RequireSignatures = true
+	betaServer := httptest.NewServer(betaGateway.Handler())
+	defer betaServer.Close()
+	alfaGateway.AddPeer("BETA", betaServer.URL)
+	recipient, _ := beta.OpenAccountWithIban(ibanWithBban("BETA00000000000000000042"))
+
+	unsigned, _ := alfaGateway.SendTransfer(sender.Iban, recipient.Iban, 10, TransferDetails{})
+	if status, _ := alfa.GetTransferStatus(unsigned); status != TransferPending {
+		t.Errorf("Expected unsigned transfer to wait for delivery, got %v", status)
+	}
+	alfaGateway.SetPeerSecret("BETA", "shared")
+	betaGateway.SetPeerSecret("ALFA", "shared")
+	if delivered := alfaGateway.RunRetries(time.Now().Add(time.Hour)); delivered != 1 {
+		t.Fatalf("Expected the signed transfer to be delivered, got %d", delivered)
+	}
+	if details, _ := beta.RetrieveAccount(recipient.Iban); details.Balance != 10 {
+		t.Errorf("Expected recipient balance of 10, got %v", details.Balance)
+	}
+}